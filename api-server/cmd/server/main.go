@@ -107,8 +107,14 @@ func main() {
 	// Get the pgxpool from timescaleDB for the plan service
 	timescalePool := timescaleDB.GetTimescalePool().(*pgxpool.Pool)
 	planSvc := services.NewPlanService(postgresDB.GetPostgresDB(), timescalePool)
-
-	apiServer := api.NewServer(cfg, postgresService, timescaleService, redisService, apiKeySvc, planSvc)
+	// Pricing lookups are cached in Redis and invalidated across all replicas
+	pricingCache := services.NewPricingCache(rdb, 1*time.Hour)
+	jobsCtx, stopJobs := context.WithCancel(ctx)
+	defer stopJobs()
+	go pricingCache.Subscribe(jobsCtx)
+
+	apiServer := api.NewServer(cfg, postgresService, timescaleService, redisService, apiKeySvc, planSvc, pricingCache)
+	apiServer.StartJobs(jobsCtx)
 	go func() {
 		if err := apiServer.Run(); err != nil {
 			log.Fatalf("server start err: %v", err)
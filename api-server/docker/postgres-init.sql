@@ -31,6 +31,7 @@ CREATE TABLE IF NOT EXISTS tenants (
   id BIGSERIAL PRIMARY KEY,
   name TEXT NOT NULL,
   pricing_plan TEXT DEFAULT 'Starter',  -- References pricing_plans.name: 'Starter', 'Premium', 'Business'
+  display_currency CHAR(3) DEFAULT 'USD',  -- Default currency for cost reporting (costs are stored in USD)
   created_at timestamptz NOT NULL DEFAULT now()
 );
 
@@ -88,9 +89,11 @@ CREATE TABLE IF NOT EXISTS pricing_configs (
   provider VARCHAR(20) NOT NULL,  -- aws, gcp, azure, oci, custom
   region VARCHAR(50),
   is_default BOOLEAN DEFAULT false,
+  cpu_ram_cost_ratio DECIMAL(10,4),  -- core vs GB price for splitting node hourly costs (NULL = from rates)
   created_at timestamptz DEFAULT now(),
   updated_at timestamptz DEFAULT now(),
-  UNIQUE(tenant_id, name)
+  UNIQUE(tenant_id, name),
+  CONSTRAINT pricing_configs_cpu_ram_cost_ratio_check CHECK (cpu_ram_cost_ratio IS NULL OR cpu_ram_cost_ratio > 0)
 );
 
 -- Ensure only one default per tenant
@@ -139,6 +142,505 @@ CREATE TABLE IF NOT EXISTS node_pricing (
 
 CREATE INDEX IF NOT EXISTS idx_node_pricing_cluster ON node_pricing(cluster_name, tenant_id);
 
+-- Negotiated discounts (EDP, private pricing) applied on top of a configuration's rates
+CREATE TABLE IF NOT EXISTS pricing_discounts (
+  id BIGSERIAL PRIMARY KEY,
+  config_id BIGINT NOT NULL REFERENCES pricing_configs(id) ON DELETE CASCADE,
+  name VARCHAR(100) NOT NULL,
+  scope VARCHAR(20) NOT NULL,           -- global, resource, instance_family
+  resource_type VARCHAR(20),            -- cpu, memory, gpu, storage (NULL = all resources)
+  instance_family VARCHAR(50),          -- for instance_family scope: m5, n2, Standard_D*
+  discount_percent DECIMAL(5,2) NOT NULL,
+  effective_from DATE DEFAULT CURRENT_DATE,
+  effective_to DATE,                    -- NULL = currently active
+  created_at timestamptz DEFAULT now(),
+  CONSTRAINT pricing_discounts_scope_check CHECK (scope IN ('global', 'resource', 'instance_family')),
+  CONSTRAINT pricing_discounts_percent_check CHECK (discount_percent >= 0 AND discount_percent < 100)
+);
+
+CREATE INDEX IF NOT EXISTS idx_pricing_discounts_effective ON pricing_discounts(config_id, effective_from, effective_to);
+
+-- Pricing change history: one immutable row per change to a config, its rates or its discounts.
+-- config_id has no foreign key so the history of deleted configs is kept.
+CREATE TABLE IF NOT EXISTS pricing_versions (
+  id BIGSERIAL PRIMARY KEY,
+  tenant_id BIGINT NOT NULL REFERENCES tenants(id) ON DELETE CASCADE,
+  config_id BIGINT NOT NULL,
+  version INT NOT NULL,                 -- per config, starting at 1
+  action VARCHAR(30) NOT NULL,          -- config.create, rate.update, rates.import, discount.delete, ...
+  entity_type VARCHAR(20) NOT NULL,     -- config, rate, discount
+  entity_id BIGINT,
+  actor VARCHAR(255),                   -- user email or API key
+  before JSONB,
+  after JSONB,
+  snapshot JSONB,                       -- config with rates and discounts after the change (NULL = deleted)
+  created_at timestamptz DEFAULT now(),
+  UNIQUE(config_id, version)
+);
+
+CREATE INDEX IF NOT EXISTS idx_pricing_versions_tenant ON pricing_versions(tenant_id, created_at DESC);
+CREATE INDEX IF NOT EXISTS idx_pricing_versions_config ON pricing_versions(config_id, created_at);
+
+-- ============================
+-- Allocation Sharing Tables
+-- ============================
+
+-- Shared cost rules: overhead workloads whose cost is spread over other allocations
+CREATE TABLE IF NOT EXISTS shared_cost_rules (
+  id BIGSERIAL PRIMARY KEY,
+  tenant_id BIGINT NOT NULL REFERENCES tenants(id) ON DELETE CASCADE,
+  name VARCHAR(100) NOT NULL,
+  namespaces TEXT[] DEFAULT ARRAY[]::TEXT[],  -- namespaces whose cost is shared
+  labels TEXT[] DEFAULT ARRAY[]::TEXT[],      -- 'key=value' or 'key' pod label selectors
+  split VARCHAR(20) NOT NULL DEFAULT 'weighted',  -- even, weighted
+  enabled BOOLEAN NOT NULL DEFAULT true,
+  created_at timestamptz DEFAULT now(),
+  updated_at timestamptz DEFAULT now(),
+  UNIQUE(tenant_id, name),
+  CONSTRAINT shared_cost_rules_split_check CHECK (split IN ('even', 'weighted'))
+);
+
+CREATE INDEX IF NOT EXISTS idx_shared_cost_rules_tenant ON shared_cost_rules(tenant_id) WHERE enabled = true;
+
+-- ============================
+-- External Cost Tables
+-- ============================
+
+-- External cost line items (managed services, licences) merged into allocations by tags
+CREATE TABLE IF NOT EXISTS external_costs (
+  id BIGSERIAL PRIMARY KEY,
+  tenant_id BIGINT NOT NULL REFERENCES tenants(id) ON DELETE CASCADE,
+  start_time timestamptz NOT NULL,
+  end_time timestamptz NOT NULL,
+  amount DOUBLE PRECISION NOT NULL,
+  currency VARCHAR(3) NOT NULL DEFAULT 'USD',
+  provider VARCHAR(50),           -- aws, gcp, azure, datadog, ...
+  service VARCHAR(255),           -- e.g. 'Amazon RDS', 'S3'
+  description TEXT,
+  tags JSONB NOT NULL DEFAULT '{}'::jsonb,
+  source VARCHAR(255),            -- upload batch / file name
+  created_at timestamptz DEFAULT now(),
+  CONSTRAINT external_costs_window_check CHECK (end_time >= start_time)
+);
+
+CREATE INDEX IF NOT EXISTS idx_external_costs_tenant_time ON external_costs(tenant_id, start_time, end_time);
+CREATE INDEX IF NOT EXISTS idx_external_costs_tags ON external_costs USING GIN (tags);
+
+-- ============================
+-- Billing Reconciliation Tables
+-- ============================
+
+-- Uploaded cloud billing exports (AWS CUR, GCP billing export, Azure cost export)
+CREATE TABLE IF NOT EXISTS billing_imports (
+  id BIGSERIAL PRIMARY KEY,
+  tenant_id BIGINT NOT NULL REFERENCES tenants(id) ON DELETE CASCADE,
+  provider VARCHAR(20) NOT NULL,  -- aws, gcp, azure
+  file_name VARCHAR(255),
+  period_start timestamptz,
+  period_end timestamptz,
+  line_items INTEGER NOT NULL DEFAULT 0,
+  matched_items INTEGER NOT NULL DEFAULT 0,
+  total_cost DOUBLE PRECISION NOT NULL DEFAULT 0,      -- net cost of all line items
+  matched_cost DOUBLE PRECISION NOT NULL DEFAULT 0,    -- net cost attributed to nodes
+  unmatched_cost DOUBLE PRECISION NOT NULL DEFAULT 0,  -- net cost of non-node resources
+  nodes_matched INTEGER NOT NULL DEFAULT 0,
+  status VARCHAR(20) NOT NULL,    -- completed, failed
+  error TEXT,
+  created_at timestamptz DEFAULT now()
+);
+
+CREATE INDEX IF NOT EXISTS idx_billing_imports_tenant ON billing_imports(tenant_id, created_at DESC);
+
+-- Actual daily node cost after reservations, savings plans, discounts and credits.
+-- A later import for the same node and day replaces the earlier figure.
+CREATE TABLE IF NOT EXISTS reconciled_node_costs (
+  id BIGSERIAL PRIMARY KEY,
+  tenant_id BIGINT NOT NULL REFERENCES tenants(id) ON DELETE CASCADE,
+  import_id BIGINT NOT NULL REFERENCES billing_imports(id) ON DELETE CASCADE,
+  cluster_name TEXT NOT NULL,
+  node_name TEXT NOT NULL,
+  resource_id TEXT,               -- instance ID / resource name from the bill
+  period_start timestamptz NOT NULL,
+  period_end timestamptz NOT NULL,
+  usage_hours DOUBLE PRECISION NOT NULL DEFAULT 0,
+  list_cost DOUBLE PRECISION NOT NULL DEFAULT 0,  -- on-demand equivalent
+  net_cost DOUBLE PRECISION NOT NULL DEFAULT 0,   -- after discounts and credits
+  created_at timestamptz DEFAULT now(),
+  UNIQUE(tenant_id, cluster_name, node_name, period_start)
+);
+
+CREATE INDEX IF NOT EXISTS idx_reconciled_node_costs_period ON reconciled_node_costs(tenant_id, period_start, period_end);
+
+-- ============================
+-- Commitment Tables
+-- ============================
+
+-- Reserved instances, savings plans and committed use discounts. The upfront cost is
+-- amortized over the term and the discount applied to the covered nodes in allocation.
+CREATE TABLE IF NOT EXISTS commitments (
+  id BIGSERIAL PRIMARY KEY,
+  tenant_id BIGINT NOT NULL REFERENCES tenants(id) ON DELETE CASCADE,
+  name VARCHAR(100) NOT NULL,
+  provider VARCHAR(20) NOT NULL,
+  type VARCHAR(30) NOT NULL,             -- reserved_instance, savings_plan, committed_use
+  term VARCHAR(20) NOT NULL,             -- reserved_1yr, reserved_3yr
+  upfront_cost DECIMAL(14,4) NOT NULL DEFAULT 0,
+  hourly_commitment DECIMAL(12,6) NOT NULL DEFAULT 0,  -- recurring $/hour
+  discount_percent DECIMAL(5,2) NOT NULL DEFAULT 0,    -- vs on-demand; 0 = provider default
+  instance_families TEXT[] DEFAULT ARRAY[]::TEXT[],    -- e.g. 'm5', 'n2'; empty = any
+  clusters TEXT[] DEFAULT ARRAY[]::TEXT[],             -- empty = all clusters
+  start_date timestamptz NOT NULL,
+  end_date timestamptz NOT NULL,
+  created_at timestamptz DEFAULT now(),
+  updated_at timestamptz DEFAULT now(),
+  CONSTRAINT commitments_type_check CHECK (type IN ('reserved_instance', 'savings_plan', 'committed_use')),
+  CONSTRAINT commitments_term_check CHECK (term IN ('reserved_1yr', 'reserved_3yr'))
+);
+
+CREATE INDEX IF NOT EXISTS idx_commitments_tenant_period ON commitments(tenant_id, start_date, end_date);
+
+-- ============================
+-- Currency Tables
+-- ============================
+
+-- Exchange rates from USD (all stored costs) to a display currency, effective from a date
+-- until superseded by a later rate for the same currency
+CREATE TABLE IF NOT EXISTS exchange_rates (
+  id BIGSERIAL PRIMARY KEY,
+  tenant_id BIGINT NOT NULL REFERENCES tenants(id) ON DELETE CASCADE,
+  currency CHAR(3) NOT NULL,                 -- ISO 4217 code, e.g. EUR, GBP
+  rate DECIMAL(18,8) NOT NULL,               -- units of currency per 1 USD
+  effective_from DATE NOT NULL,
+  created_at timestamptz DEFAULT now(),
+  UNIQUE(tenant_id, currency, effective_from),
+  CONSTRAINT exchange_rates_rate_check CHECK (rate > 0)
+);
+
+CREATE INDEX IF NOT EXISTS idx_exchange_rates_lookup ON exchange_rates(tenant_id, currency, effective_from DESC);
+
+-- ============================
+-- Anomaly Detection Tables
+-- ============================
+
+-- Per-tenant detection settings; tenants without a row use the defaults
+CREATE TABLE IF NOT EXISTS anomaly_settings (
+  tenant_id BIGINT PRIMARY KEY REFERENCES tenants(id) ON DELETE CASCADE,
+  enabled BOOLEAN NOT NULL DEFAULT TRUE,
+  sensitivity VARCHAR(10) NOT NULL DEFAULT 'medium',  -- low, medium, high
+  min_change_usd DECIMAL(12,4) NOT NULL DEFAULT 1,    -- ignore smaller changes
+  baseline_days INT NOT NULL DEFAULT 14,              -- daily baseline; hourly uses the previous 24 hours
+  dimensions TEXT[] DEFAULT ARRAY['cluster', 'namespace']::TEXT[],
+  granularities TEXT[] DEFAULT ARRAY['hour', 'day']::TEXT[],
+  updated_at timestamptz DEFAULT now(),
+  CONSTRAINT anomaly_settings_sensitivity_check CHECK (sensitivity IN ('low', 'medium', 'high'))
+);
+
+-- Detected spikes and drops of the cost of a cluster, namespace or label value
+CREATE TABLE IF NOT EXISTS cost_anomalies (
+  id BIGSERIAL PRIMARY KEY,
+  tenant_id BIGINT NOT NULL REFERENCES tenants(id) ON DELETE CASCADE,
+  granularity VARCHAR(10) NOT NULL,      -- hour, day
+  dimension VARCHAR(100) NOT NULL,       -- cluster, namespace, label:<key>
+  name VARCHAR(255) NOT NULL,
+  window_start timestamptz NOT NULL,
+  window_end timestamptz NOT NULL,
+  cost_usd DECIMAL(14,4) NOT NULL,
+  baseline_cost_usd DECIMAL(14,4) NOT NULL,
+  stddev_usd DECIMAL(14,4) NOT NULL,
+  z_score DECIMAL(10,2) NOT NULL,
+  direction VARCHAR(10) NOT NULL,        -- spike, drop
+  severity VARCHAR(10) NOT NULL,         -- low, medium, high
+  drivers JSONB DEFAULT '[]'::JSONB,     -- namespace/controller cost changes (root-cause hints)
+  status VARCHAR(20) NOT NULL DEFAULT 'open',
+  acknowledged_by VARCHAR(255),
+  acknowledged_at timestamptz,
+  resolved_by VARCHAR(255),
+  resolved_at timestamptz,
+  note TEXT,
+  created_at timestamptz DEFAULT now(),
+  updated_at timestamptz DEFAULT now(),
+  UNIQUE(tenant_id, granularity, dimension, name, window_start),
+  CONSTRAINT cost_anomalies_status_check CHECK (status IN ('open', 'acknowledged', 'resolved'))
+);
+
+CREATE INDEX IF NOT EXISTS idx_cost_anomalies_tenant ON cost_anomalies(tenant_id, status, window_start DESC);
+
+-- Periods already evaluated, so each period is evaluated once across API server replicas
+CREATE TABLE IF NOT EXISTS anomaly_detection_runs (
+  id BIGSERIAL PRIMARY KEY,
+  tenant_id BIGINT NOT NULL REFERENCES tenants(id) ON DELETE CASCADE,
+  granularity VARCHAR(10) NOT NULL,
+  window_start timestamptz NOT NULL,
+  anomalies INT NOT NULL DEFAULT 0,
+  created_at timestamptz DEFAULT now(),
+  UNIQUE(tenant_id, granularity, window_start)
+);
+
+-- ============================
+-- Budget Tables
+-- ============================
+
+-- Monthly or quarterly spend limits on the allocations matching the filters
+CREATE TABLE IF NOT EXISTS budgets (
+  id BIGSERIAL PRIMARY KEY,
+  tenant_id BIGINT NOT NULL REFERENCES tenants(id) ON DELETE CASCADE,
+  name VARCHAR(100) NOT NULL,
+  period VARCHAR(20) NOT NULL,                         -- monthly, quarterly
+  amount DECIMAL(14,2) NOT NULL,
+  currency CHAR(3) NOT NULL DEFAULT 'USD',
+  filters TEXT[] DEFAULT ARRAY[]::TEXT[],              -- allocation filters; empty = whole tenant
+  thresholds FLOAT8[] DEFAULT ARRAY[50, 80, 100]::FLOAT8[],  -- percent of the amount
+  alert_on_forecast BOOLEAN NOT NULL DEFAULT TRUE,
+  last_spent DECIMAL(14,2) DEFAULT 0,
+  last_forecast DECIMAL(14,2) DEFAULT 0,
+  last_evaluated_at timestamptz,
+  created_at timestamptz DEFAULT now(),
+  updated_at timestamptz DEFAULT now(),
+  UNIQUE(tenant_id, name),
+  CONSTRAINT budgets_period_check CHECK (period IN ('monthly', 'quarterly')),
+  CONSTRAINT budgets_amount_check CHECK (amount > 0)
+);
+
+-- Thresholds crossed by a budget, once per threshold and period
+CREATE TABLE IF NOT EXISTS budget_alerts (
+  id BIGSERIAL PRIMARY KEY,
+  tenant_id BIGINT NOT NULL REFERENCES tenants(id) ON DELETE CASCADE,
+  budget_id BIGINT NOT NULL REFERENCES budgets(id) ON DELETE CASCADE,
+  period_start timestamptz NOT NULL,
+  type VARCHAR(20) NOT NULL,                           -- actual, forecast
+  threshold DECIMAL(6,2) NOT NULL,
+  amount DECIMAL(14,2) NOT NULL,
+  spent DECIMAL(14,2) NOT NULL,
+  forecast DECIMAL(14,2) NOT NULL,
+  currency CHAR(3) NOT NULL,
+  created_at timestamptz DEFAULT now(),
+  UNIQUE(budget_id, period_start, type, threshold)
+);
+
+CREATE INDEX IF NOT EXISTS idx_budget_alerts_tenant ON budget_alerts(tenant_id, created_at DESC);
+
+-- ============================
+-- Notification Tables
+-- ============================
+
+-- Destinations for alert notifications (Slack, Teams, signed webhook, SMTP email)
+CREATE TABLE IF NOT EXISTS notification_channels (
+  id BIGSERIAL PRIMARY KEY,
+  tenant_id BIGINT NOT NULL REFERENCES tenants(id) ON DELETE CASCADE,
+  name VARCHAR(100) NOT NULL,
+  type VARCHAR(20) NOT NULL,                           -- slack, teams, webhook, email
+  config JSONB NOT NULL DEFAULT '{}'::JSONB,
+  events TEXT[] DEFAULT ARRAY[]::TEXT[],               -- anomaly, budget, recommendation; empty = all
+  enabled BOOLEAN NOT NULL DEFAULT TRUE,
+  created_at timestamptz DEFAULT now(),
+  updated_at timestamptz DEFAULT now(),
+  UNIQUE(tenant_id, name),
+  CONSTRAINT notification_channels_type_check CHECK (type IN ('slack', 'teams', 'webhook', 'email'))
+);
+
+-- Delivery log: one row per notification sent to a channel
+CREATE TABLE IF NOT EXISTS notification_deliveries (
+  id BIGSERIAL PRIMARY KEY,
+  tenant_id BIGINT NOT NULL REFERENCES tenants(id) ON DELETE CASCADE,
+  channel_id BIGINT NOT NULL REFERENCES notification_channels(id) ON DELETE CASCADE,
+  event VARCHAR(50) NOT NULL,
+  title VARCHAR(500) NOT NULL,
+  status VARCHAR(20) NOT NULL,                         -- sent, failed
+  attempts INT NOT NULL DEFAULT 0,
+  error TEXT,
+  created_at timestamptz DEFAULT now()
+);
+
+CREATE INDEX IF NOT EXISTS idx_notification_deliveries_tenant ON notification_deliveries(tenant_id, created_at DESC);
+CREATE INDEX IF NOT EXISTS idx_notification_deliveries_channel ON notification_deliveries(channel_id, created_at DESC);
+
+-- ============================
+-- Report Tables
+-- ============================
+
+-- Saved allocation queries rendered on a cron schedule and delivered through notification channels
+CREATE TABLE IF NOT EXISTS reports (
+  id BIGSERIAL PRIMARY KEY,
+  tenant_id BIGINT NOT NULL REFERENCES tenants(id) ON DELETE CASCADE,
+  name VARCHAR(100) NOT NULL,
+  query JSONB NOT NULL DEFAULT '{}'::JSONB,            -- window, aggregate, filters, ...
+  format VARCHAR(10) NOT NULL,                         -- csv, html
+  schedule VARCHAR(100) NOT NULL,                      -- cron expression
+  timezone VARCHAR(64) NOT NULL DEFAULT 'UTC',
+  channel_ids BIGINT[] DEFAULT ARRAY[]::BIGINT[],      -- notification_channels
+  recipients TEXT[] DEFAULT ARRAY[]::TEXT[],           -- override email channel recipients
+  enabled BOOLEAN NOT NULL DEFAULT TRUE,
+  next_run_at timestamptz,
+  last_run_at timestamptz,
+  created_at timestamptz DEFAULT now(),
+  updated_at timestamptz DEFAULT now(),
+  UNIQUE(tenant_id, name),
+  CONSTRAINT reports_format_check CHECK (format IN ('csv', 'html'))
+);
+
+CREATE INDEX IF NOT EXISTS idx_reports_next_run ON reports(next_run_at) WHERE enabled;
+
+-- Run history; scheduled runs are unique per report and time so only one replica runs them
+CREATE TABLE IF NOT EXISTS report_runs (
+  id BIGSERIAL PRIMARY KEY,
+  tenant_id BIGINT NOT NULL REFERENCES tenants(id) ON DELETE CASCADE,
+  report_id BIGINT NOT NULL REFERENCES reports(id) ON DELETE CASCADE,
+  scheduled_for timestamptz NOT NULL,
+  manual BOOLEAN NOT NULL DEFAULT FALSE,
+  status VARCHAR(20) NOT NULL,                         -- running, succeeded, failed
+  format VARCHAR(10) NOT NULL,
+  rows INT NOT NULL DEFAULT 0,
+  size INT NOT NULL DEFAULT 0,
+  deliveries INT NOT NULL DEFAULT 0,
+  error TEXT,
+  output BYTEA,
+  started_at timestamptz NOT NULL DEFAULT now(),
+  finished_at timestamptz
+);
+
+CREATE UNIQUE INDEX IF NOT EXISTS idx_report_runs_scheduled ON report_runs(report_id, scheduled_for) WHERE NOT manual;
+CREATE INDEX IF NOT EXISTS idx_report_runs_report ON report_runs(report_id, started_at DESC);
+
+-- ============================
+-- Statement Tables
+-- ============================
+
+-- Cost center hierarchy (org -> department -> team -> service); pods are mapped to nodes
+-- by cluster, namespace or pod labels
+CREATE TABLE IF NOT EXISTS cost_centers (
+  id BIGSERIAL PRIMARY KEY,
+  tenant_id BIGINT NOT NULL REFERENCES tenants(id) ON DELETE CASCADE,
+  parent_id BIGINT REFERENCES cost_centers(id),
+  name VARCHAR(100) NOT NULL,
+  level VARCHAR(50),                                   -- org, department, team, service, ...
+  description TEXT,
+  clusters TEXT[] DEFAULT ARRAY[]::TEXT[],             -- restrict the rules to these clusters
+  namespaces TEXT[] DEFAULT ARRAY[]::TEXT[],
+  labels TEXT[] DEFAULT ARRAY[]::TEXT[],               -- pod label selectors: key=value or key
+  priority INT NOT NULL DEFAULT 0,                     -- lowest matching priority wins
+  created_at timestamptz DEFAULT now(),
+  updated_at timestamptz DEFAULT now(),
+  UNIQUE(tenant_id, name)
+);
+
+CREATE INDEX IF NOT EXISTS idx_cost_centers_parent ON cost_centers(parent_id);
+
+-- Monthly chargeback statements: allocation results frozen per cost center
+CREATE TABLE IF NOT EXISTS statements (
+  id BIGSERIAL PRIMARY KEY,
+  tenant_id BIGINT NOT NULL REFERENCES tenants(id) ON DELETE CASCADE,
+  period VARCHAR(7) NOT NULL,                          -- YYYY-MM
+  period_start timestamptz NOT NULL,
+  period_end timestamptz NOT NULL,
+  currency VARCHAR(3) NOT NULL,
+  share_idle VARCHAR(20) NOT NULL,
+  status VARCHAR(20) NOT NULL DEFAULT 'draft',
+  allocated_cost DECIMAL(14,2) NOT NULL DEFAULT 0,
+  adjustments_total DECIMAL(14,2) NOT NULL DEFAULT 0,
+  total DECIMAL(14,2) NOT NULL DEFAULT 0,
+  generated_at timestamptz NOT NULL DEFAULT now(),
+  created_by VARCHAR(255),
+  reviewed_by VARCHAR(255),
+  reviewed_at timestamptz,
+  finalized_by VARCHAR(255),
+  finalized_at timestamptz,
+  created_at timestamptz DEFAULT now(),
+  updated_at timestamptz DEFAULT now(),
+  UNIQUE(tenant_id, period),
+  CONSTRAINT statements_status_check CHECK (status IN ('draft', 'reviewed', 'finalized'))
+);
+
+CREATE TABLE IF NOT EXISTS statement_lines (
+  id BIGSERIAL PRIMARY KEY,
+  statement_id BIGINT NOT NULL REFERENCES statements(id) ON DELETE CASCADE,
+  cost_center_id BIGINT REFERENCES cost_centers(id) ON DELETE SET NULL,
+  cost_center VARCHAR(100) NOT NULL,                   -- name when generated, __unallocated__ or __idle__
+  namespaces TEXT[] DEFAULT ARRAY[]::TEXT[],
+  pod_count INT NOT NULL DEFAULT 0,
+  cpu_cost DECIMAL(14,4) NOT NULL DEFAULT 0,
+  ram_cost DECIMAL(14,4) NOT NULL DEFAULT 0,
+  shared_idle_cost DECIMAL(14,4) NOT NULL DEFAULT 0,
+  shared_cost DECIMAL(14,4) NOT NULL DEFAULT 0,
+  external_cost DECIMAL(14,4) NOT NULL DEFAULT 0,
+  allocated_cost DECIMAL(14,4) NOT NULL DEFAULT 0,
+  adjustments DECIMAL(14,4) NOT NULL DEFAULT 0,
+  total DECIMAL(14,4) NOT NULL DEFAULT 0,
+  UNIQUE(statement_id, cost_center)
+);
+
+CREATE TABLE IF NOT EXISTS statement_adjustments (
+  id BIGSERIAL PRIMARY KEY,
+  statement_id BIGINT NOT NULL REFERENCES statements(id) ON DELETE CASCADE,
+  cost_center VARCHAR(100) NOT NULL,
+  kind VARCHAR(20) NOT NULL,                           -- adjustment, credit
+  amount DECIMAL(14,4) NOT NULL,
+  comment TEXT NOT NULL,
+  created_by VARCHAR(255),
+  created_at timestamptz DEFAULT now(),
+  CONSTRAINT statement_adjustments_kind_check CHECK (kind IN ('adjustment', 'credit'))
+);
+
+CREATE INDEX IF NOT EXISTS idx_statement_adjustments_statement ON statement_adjustments(statement_id);
+
+-- Finalized statements are immutable: they cannot be updated and their lines and
+-- adjustments cannot be added to or changed. Deletes are left to the application so that
+-- deleting a tenant still cascades.
+CREATE OR REPLACE FUNCTION protect_finalized_statement()
+RETURNS TRIGGER AS $$
+BEGIN
+  IF TG_TABLE_NAME = 'statements' THEN
+    IF OLD.status = 'finalized' THEN
+      RAISE EXCEPTION 'statement % is finalized', OLD.id;
+    END IF;
+  ELSIF EXISTS (SELECT 1 FROM statements WHERE id = NEW.statement_id AND status = 'finalized') THEN
+    RAISE EXCEPTION 'statement % is finalized', NEW.statement_id;
+  END IF;
+  RETURN NEW;
+END;
+$$ LANGUAGE plpgsql;
+
+DROP TRIGGER IF EXISTS protect_finalized_statement ON statements;
+CREATE TRIGGER protect_finalized_statement
+  BEFORE UPDATE ON statements
+  FOR EACH ROW
+  EXECUTE FUNCTION protect_finalized_statement();
+
+DROP TRIGGER IF EXISTS protect_finalized_statement_lines ON statement_lines;
+CREATE TRIGGER protect_finalized_statement_lines
+  BEFORE INSERT OR UPDATE ON statement_lines
+  FOR EACH ROW
+  EXECUTE FUNCTION protect_finalized_statement();
+
+DROP TRIGGER IF EXISTS protect_finalized_statement_adjustments ON statement_adjustments;
+CREATE TRIGGER protect_finalized_statement_adjustments
+  BEFORE INSERT OR UPDATE ON statement_adjustments
+  FOR EACH ROW
+  EXECUTE FUNCTION protect_finalized_statement();
+
+-- ============================
+-- Virtual Label Tables
+-- ============================
+
+-- Virtual label rules: derive a pod label at query time from other labels so that
+-- allocation by label is consistent without relabelling workloads
+CREATE TABLE IF NOT EXISTS virtual_label_rules (
+  id BIGSERIAL PRIMARY KEY,
+  tenant_id BIGINT NOT NULL REFERENCES tenants(id) ON DELETE CASCADE,
+  key VARCHAR(100) NOT NULL,                           -- virtual label key
+  sources TEXT[] DEFAULT ARRAY[]::TEXT[],              -- label keys coalesced in order
+  lowercase BOOLEAN NOT NULL DEFAULT false,
+  rewrites JSONB NOT NULL DEFAULT '[]'::JSONB,         -- [{pattern, replacement}], applied in order
+  lookup JSONB NOT NULL DEFAULT '{}'::JSONB,           -- value -> value
+  namespace_defaults JSONB NOT NULL DEFAULT '{}'::JSONB, -- namespace -> value
+  default_value VARCHAR(255),
+  enabled BOOLEAN NOT NULL DEFAULT true,
+  created_at timestamptz DEFAULT now(),
+  updated_at timestamptz DEFAULT now(),
+  UNIQUE(tenant_id, key)
+);
+
+CREATE INDEX IF NOT EXISTS idx_virtual_label_rules_tenant ON virtual_label_rules(tenant_id) WHERE enabled = true;
+
 \echo "k8s_cost database initialized."
 
 -- -- ============================
@@ -28,9 +28,11 @@ CREATE TABLE IF NOT EXISTS node_metrics (
   cluster_name TEXT,
   node_name TEXT,
   instance_type TEXT,
+  provider_id TEXT,
   cpu_capacity BIGINT,
   memory_capacity BIGINT,
-  hourly_cost_usd NUMERIC(10,6)
+  hourly_cost_usd NUMERIC(10,6),
+  labels JSONB
 );
 SELECT create_hypertable('node_metrics','time', if_not_exists => TRUE);
 
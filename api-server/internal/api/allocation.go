@@ -1,14 +1,15 @@
 package api
 
 import (
+	"errors"
 	"net/http"
 	"strconv"
 	"strings"
 
+	"github.com/bugfreev587/k8s-cost-api-server/internal/export"
 	"github.com/bugfreev587/k8s-cost-api-server/internal/middleware"
 	"github.com/bugfreev587/k8s-cost-api-server/internal/services"
 	"github.com/gin-gonic/gin"
-	"github.com/jackc/pgx/v5/pgxpool"
 )
 
 // GET /v1/allocation
@@ -16,21 +17,52 @@ import (
 //
 // Query Parameters:
 //   - window: Time window (required). Formats: "24h", "7d", "today", "lastweek", "2024-01-01,2024-01-07"
-//   - aggregate: Grouping dimension(s). Values: "namespace", "cluster", "node", "pod", "controller", "label:<key>"
+//   - aggregate: Grouping dimension(s). Values: "namespace", "cluster", "node", "pod", "controller", "container"
+//     (costed from each container's own requests and usage), "label:<key>",
+//     "costCenter" (the cost center pods map to) or "costCenter:<level>" (rolled up to a level name such as
+//     "department", or a depth, 1 = root); pods no mapping rule matches are reported as "__unallocated__"
 //     Multiple aggregations can be comma-separated: "namespace,label:app"
 //   - step: Time bucket size for time-series results: "1h", "1d", "1w"
 //   - accumulate: How to accumulate results: "true" (single result), "false", "hour", "day", "week"
 //   - idle: Include idle cost allocation: "true" or "false"
-//   - shareIdle: Distribute idle costs: "true", "false", "weighted"
-//   - filter: Filter expressions (can be repeated). Formats: "namespace:value", "cluster:value", "label:key=value"
+//   - shareIdle: Distribute idle costs: "true"/"even" (equal split), "false", "weighted" (CPU idle by CPU cost, RAM idle by RAM cost)
+//   - idleBy: Scope within which idle is computed and shared: "tenant" (default), "cluster", "node"
+//     (OpenCost alias: idleByNode=true)
+//   - shareNamespaces: Comma-separated namespaces whose cost is shared across all other allocations
+//   - shareLabels: Comma-separated pod label selectors whose cost is shared: "key=value" or "key"
+//   - shareSplit: How shared costs are split: "weighted" (default, by cost) or "even"
+//   - applySharingRules: Apply the tenant's stored sharing rules when no share parameters are given (default "true")
+//   - rawLabels: Aggregate and filter on raw pod labels, ignoring the tenant's virtual label rules: "true" or "false" (default)
+//   - includeExternal: Merge uploaded external cost line items into allocations by matching their tags
+//     (namespace, cluster, label keys) to the aggregation: "true" or "false" (default)
+//   - reconcile: Use actual node costs from imported billing exports where available: "true" (default) or "false"
+//   - applyCommitments: Apply amortized reserved instance / savings plan discounts to covered nodes: "true" (default) or "false"
+//   - currency: ISO 4217 currency to report costs in (default: the tenant's display currency). Each step is
+//     converted at the exchange rate effective at its start
+//   - pricingAsOf: Price with the pricing configs as they stood at this time (RFC3339, or YYYY-MM-DD for the
+//     end of that day), reproducing reports from before later rate edits
+//   - pricingVersion: Like pricingAsOf, at the time the given pricing version was recorded
+//   - filter: Filter expressions (can be repeated, all must match). OpenCost v2 syntax: a field (cluster, node,
+//     namespace, pod, controller, container, label[key]), an operator (":" equals, "~:" contains, "<~:" starts with,
+//     ">~:" ends with, "!" before any of them negates) and quoted values; "+" is and, "|" is or, parentheses
+//     group: `namespace:"a","b"+label[app]!:"x"`. The legacy "namespace:value", "cluster:value" and
+//     "label:key=value" forms are still accepted. Invalid filters are rejected with 400
 //   - offset: Pagination offset
-//   - limit: Pagination limit (default 1000)
+//   - limit: Pagination limit (default 1000; file exports are only paginated when offset or
+//     limit is given, and then per step)
+//   - format: Response format: "json" (default), or "csv", "xlsx" or "parquet" to stream one flattened row
+//     per allocation and step as a file download
 //
 // Example requests:
 //   GET /v1/allocation?window=7d&aggregate=namespace
 //   GET /v1/allocation?window=24h&aggregate=namespace,label:app&idle=true&shareIdle=weighted
+//   GET /v1/allocation?window=7d&aggregate=namespace&idle=true&shareIdle=weighted&idleBy=cluster
+//   GET /v1/allocation?window=7d&aggregate=namespace&shareNamespaces=kube-system,monitoring&shareSplit=even
+//   GET /v1/allocation?window=30d&aggregate=label:team&includeExternal=true
+//   GET /v1/allocation?window=30d&aggregate=costCenter:department&idle=true&shareIdle=weighted
 //   GET /v1/allocation?window=lastweek&aggregate=cluster&step=1d&accumulate=false
 //   GET /v1/allocation?window=30d&aggregate=pod&filter=namespace:production&filter=cluster:prod-east
+//   GET /v1/allocation?window=30d&aggregate=namespace&step=1d&accumulate=false&format=parquet
 func (s *Server) getAllocation(c *gin.Context) {
 	tenantID, ok := middleware.GetTenantIDFromContext(c)
 	if !ok {
@@ -41,6 +73,15 @@ func (s *Server) getAllocation(c *gin.Context) {
 		})
 		return
 	}
+	format, err := exportFormat(c)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"code":    400,
+			"status":  "error",
+			"message": err.Error(),
+		})
+		return
+	}
 
 	// Parse query parameters
 	params := services.AllocationParams{
@@ -49,6 +90,31 @@ func (s *Server) getAllocation(c *gin.Context) {
 		Step:       c.Query("step"),
 		Accumulate: c.DefaultQuery("accumulate", "true"),
 		ShareIdle:  c.Query("shareIdle"),
+		IdleBy:     c.Query("idleBy"),
+	}
+	parseSharingParams(c, &params)
+	params.RawLabels = c.Query("rawLabels") == "true"
+	params.IncludeExternal = c.Query("includeExternal") == "true"
+	params.Reconcile = c.DefaultQuery("reconcile", "true") == "true"
+	params.ApplyCommitments = c.DefaultQuery("applyCommitments", "true") == "true"
+
+	currency, err := s.resolveRequestCurrency(c, tenantID)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"code":    400,
+			"status":  "error",
+			"message": err.Error(),
+		})
+		return
+	}
+	params.Currency = currency
+	if !s.parsePricingPin(c, tenantID, &params) {
+		return
+	}
+
+	// Parse idleByNode (OpenCost alias)
+	if c.Query("idleByNode") == "true" {
+		params.IdleBy = services.IdleByNode
 	}
 
 	// Parse idle parameter
@@ -81,6 +147,9 @@ func (s *Server) getAllocation(c *gin.Context) {
 				filters = append(filters, "node:"+strings.TrimSpace(n))
 			}
 		}
+		if ct := c.Query("filterContainers"); ct != "" {
+			filters = append(filters, "container:"+ct)
+		}
 		if lb := c.Query("filterLabels"); lb != "" {
 			filters = append(filters, "label:"+lb)
 		}
@@ -100,12 +169,31 @@ func (s *Server) getAllocation(c *gin.Context) {
 	}
 
 	// Get allocations with dynamic pricing
-	pool := s.timescaleDB.GetTimescalePool().(*pgxpool.Pool)
-	allocSvc := services.NewAllocationServiceWithPricing(pool, s.postgresDB.GetPostgresDB())
+	allocSvc := s.allocSvc
+	if format != "" {
+		// File exports are not paginated unless a limit is given explicitly, and are written
+		// step by step, so memory is bounded by one step's allocations rather than the window
+		err := streamExport(c, "allocation", format, services.AllocationExportColumns, func(w export.Writer) error {
+			return allocSvc.EachAllocationSet(c.Request.Context(), int64(tenantID), params, func(set services.AllocationSet, currency string) error {
+				return services.WriteAllocationSetRows(w, set, currency)
+			})
+		})
+		if err != nil {
+			status := allocationErrorStatus(err)
+			c.JSON(status, gin.H{
+				"code":    status,
+				"status":  "error",
+				"message": err.Error(),
+			})
+		}
+		return
+	}
+
 	response, err := allocSvc.GetAllocations(c.Request.Context(), int64(tenantID), params)
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{
-			"code":    500,
+		status := allocationErrorStatus(err)
+		c.JSON(status, gin.H{
+			"code":    status,
 			"status":  "error",
 			"message": err.Error(),
 		})
@@ -125,7 +213,8 @@ func (s *Server) getAllocationCompute(c *gin.Context) {
 }
 
 // GET /v1/allocation/summary
-// Returns condensed allocation summary with key metrics
+// Returns condensed allocation summary with key metrics. With format=csv|xlsx|parquet the
+// accumulated allocations are streamed as a file in the same flattened layout as /v1/allocation
 func (s *Server) getAllocationSummary(c *gin.Context) {
 	tenantID, ok := middleware.GetTenantIDFromContext(c)
 	if !ok {
@@ -136,6 +225,15 @@ func (s *Server) getAllocationSummary(c *gin.Context) {
 		})
 		return
 	}
+	format, err := exportFormat(c)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"code":    400,
+			"status":  "error",
+			"message": err.Error(),
+		})
+		return
+	}
 
 	// Parse query parameters - simpler than full allocation
 	params := services.AllocationParams{
@@ -144,18 +242,56 @@ func (s *Server) getAllocationSummary(c *gin.Context) {
 		Accumulate: "true", // Summary always accumulates
 		Idle:       c.Query("idle") == "true",
 		ShareIdle:  c.Query("shareIdle"),
+		IdleBy:     c.Query("idleBy"),
+	}
+	parseSharingParams(c, &params)
+	params.RawLabels = c.Query("rawLabels") == "true"
+	params.IncludeExternal = c.Query("includeExternal") == "true"
+	params.Reconcile = c.DefaultQuery("reconcile", "true") == "true"
+	params.ApplyCommitments = c.DefaultQuery("applyCommitments", "true") == "true"
+
+	currency, err := s.resolveRequestCurrency(c, tenantID)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"code":    400,
+			"status":  "error",
+			"message": err.Error(),
+		})
+		return
+	}
+	params.Currency = currency
+	if !s.parsePricingPin(c, tenantID, &params) {
+		return
 	}
 
 	// Parse filters
 	params.Filters = c.QueryArray("filter")
 
 	// Get allocations with dynamic pricing
-	pool := s.timescaleDB.GetTimescalePool().(*pgxpool.Pool)
-	allocSvc := services.NewAllocationServiceWithPricing(pool, s.postgresDB.GetPostgresDB())
+	allocSvc := s.allocSvc
+	if format != "" {
+		// The summary accumulates the window into a single set, which is streamed unpaginated
+		err := streamExport(c, "allocation-summary", format, services.AllocationExportColumns, func(w export.Writer) error {
+			return allocSvc.EachAllocationSet(c.Request.Context(), int64(tenantID), params, func(set services.AllocationSet, currency string) error {
+				return services.WriteAllocationSetRows(w, set, currency)
+			})
+		})
+		if err != nil {
+			status := allocationErrorStatus(err)
+			c.JSON(status, gin.H{
+				"code":    status,
+				"status":  "error",
+				"message": err.Error(),
+			})
+		}
+		return
+	}
+
 	response, err := allocSvc.GetAllocations(c.Request.Context(), int64(tenantID), params)
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{
-			"code":    500,
+		status := allocationErrorStatus(err)
+		c.JSON(status, gin.H{
+			"code":    status,
 			"status":  "error",
 			"message": err.Error(),
 		})
@@ -170,11 +306,15 @@ func (s *Server) getAllocationSummary(c *gin.Context) {
 		RAMByteHours    float64 `json:"ramByteHours"`
 		RAMCost         float64 `json:"ramCost"`
 		TotalCost       float64 `json:"totalCost"`
+		SharedIdleCost  float64 `json:"sharedIdleCost"`
+		SharedCost      float64 `json:"sharedCost"`
+		ExternalCost    float64 `json:"externalCost"`
+		ListCost        float64 `json:"listCost"`
 		TotalEfficiency float64 `json:"totalEfficiency"`
 	}
 
 	var summaryItems []AllocationSummaryItem
-	var totalCost, totalCPUCost, totalRAMCost float64
+	var totalCost, totalListCost, totalCPUCost, totalRAMCost float64
 
 	if len(response.Data) > 0 {
 		for name, alloc := range response.Data[0].Allocations {
@@ -185,9 +325,14 @@ func (s *Server) getAllocationSummary(c *gin.Context) {
 				RAMByteHours:    alloc.RAMByteHours,
 				RAMCost:         alloc.RAMCost,
 				TotalCost:       alloc.TotalCost,
+				SharedIdleCost:  alloc.SharedIdleCost,
+				SharedCost:      alloc.SharedCost,
+				ExternalCost:    alloc.ExternalCost,
+				ListCost:        alloc.ListCost,
 				TotalEfficiency: alloc.TotalEfficiency,
 			})
 			totalCost += alloc.TotalCost
+			totalListCost += alloc.ListCost
 			totalCPUCost += alloc.CPUCost
 			totalRAMCost += alloc.RAMCost
 		}
@@ -197,12 +342,14 @@ func (s *Server) getAllocationSummary(c *gin.Context) {
 		"code":   200,
 		"status": "success",
 		"data": gin.H{
-			"items":        summaryItems,
-			"totalCost":    totalCost,
-			"totalCPUCost": totalCPUCost,
-			"totalRAMCost": totalRAMCost,
-			"window":       params.Window,
-			"aggregate":    params.Aggregate,
+			"items":         summaryItems,
+			"totalCost":     totalCost,
+			"totalListCost": totalListCost,
+			"totalCPUCost":  totalCPUCost,
+			"currency":      response.Currency,
+			"totalRAMCost":  totalRAMCost,
+			"window":        params.Window,
+			"aggregate":     params.Aggregate,
 		},
 	})
 }
@@ -227,15 +374,35 @@ func (s *Server) getAllocationTopline(c *gin.Context) {
 		Accumulate: "true",
 		Idle:       c.Query("idle") == "true",
 		ShareIdle:  c.Query("shareIdle"),
+		IdleBy:     c.Query("idleBy"),
+	}
+	parseSharingParams(c, &params)
+	params.RawLabels = c.Query("rawLabels") == "true"
+	params.IncludeExternal = c.Query("includeExternal") == "true"
+	params.Reconcile = c.DefaultQuery("reconcile", "true") == "true"
+	params.ApplyCommitments = c.DefaultQuery("applyCommitments", "true") == "true"
+
+	currency, err := s.resolveRequestCurrency(c, tenantID)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"code":    400,
+			"status":  "error",
+			"message": err.Error(),
+		})
+		return
+	}
+	params.Currency = currency
+	if !s.parsePricingPin(c, tenantID, &params) {
+		return
 	}
 
 	// Get allocations with dynamic pricing
-	pool := s.timescaleDB.GetTimescalePool().(*pgxpool.Pool)
-	allocSvc := services.NewAllocationServiceWithPricing(pool, s.postgresDB.GetPostgresDB())
+	allocSvc := s.allocSvc
 	response, err := allocSvc.GetAllocations(c.Request.Context(), int64(tenantID), params)
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{
-			"code":    500,
+		status := allocationErrorStatus(err)
+		c.JSON(status, gin.H{
+			"code":    status,
 			"status":  "error",
 			"message": err.Error(),
 		})
@@ -250,6 +417,8 @@ func (s *Server) getAllocationTopline(c *gin.Context) {
 		totalCPUCoreHours   float64
 		totalRAMByteHours   float64
 		totalIdleCost       float64
+		totalExternalCost   float64
+		totalListCost       float64
 		avgEfficiency       float64
 		allocationCount     int
 		efficiencySum       float64
@@ -257,8 +426,10 @@ func (s *Server) getAllocationTopline(c *gin.Context) {
 
 	if len(response.Data) > 0 {
 		totalIdleCost = response.Data[0].IdleCost
+		totalExternalCost = response.Data[0].ExternalCost
+		totalListCost = response.Data[0].ListCost
 		for _, alloc := range response.Data[0].Allocations {
-			if alloc.Name == "__idle__" {
+			if strings.HasSuffix(alloc.Name, "__idle__") {
 				continue
 			}
 			totalCost += alloc.TotalCost
@@ -282,6 +453,9 @@ func (s *Server) getAllocationTopline(c *gin.Context) {
 			"totalCPUCost":      totalCPUCost,
 			"totalRAMCost":      totalRAMCost,
 			"totalIdleCost":     totalIdleCost,
+			"totalExternalCost": totalExternalCost,
+			"totalListCost":     totalListCost,
+			"currency":          response.Currency,
 			"totalCPUCoreHours": totalCPUCoreHours,
 			"totalRAMByteHours": totalRAMByteHours,
 			"avgEfficiency":     avgEfficiency,
@@ -290,3 +464,34 @@ func (s *Server) getAllocationTopline(c *gin.Context) {
 		},
 	})
 }
+
+// parseSharingParams reads the shared cost parameters (shareNamespaces, shareLabels,
+// shareSplit, applySharingRules) into params
+func parseSharingParams(c *gin.Context, params *services.AllocationParams) {
+	params.ShareNamespaces = splitQueryList(c.Query("shareNamespaces"))
+	params.ShareLabels = splitQueryList(c.Query("shareLabels"))
+	params.ShareSplit = c.Query("shareSplit")
+	params.ApplySharingRules = c.DefaultQuery("applySharingRules", "true") == "true"
+}
+
+// allocationErrorStatus returns the HTTP status for an allocation query error: 400 for an
+// invalid filter or a currency without an exchange rate for the window, 500 otherwise
+func allocationErrorStatus(err error) int {
+	var filterErr *services.FilterError
+	var rateErr *services.MissingRateError
+	if errors.As(err, &filterErr) || errors.As(err, &rateErr) {
+		return http.StatusBadRequest
+	}
+	return http.StatusInternalServerError
+}
+
+// splitQueryList splits a comma-separated query value, dropping empty entries
+func splitQueryList(value string) []string {
+	var items []string
+	for _, item := range strings.Split(value, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			items = append(items, item)
+		}
+	}
+	return items
+}
@@ -0,0 +1,262 @@
+package api
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/bugfreev587/k8s-cost-api-server/internal/middleware"
+	"github.com/bugfreev587/k8s-cost-api-server/internal/models"
+	"github.com/bugfreev587/k8s-cost-api-server/internal/services"
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+// GET /v1/anomalies
+// List detected cost anomalies, most recent first
+//
+// Query Parameters:
+//   - status: open, acknowledged or resolved
+//   - granularity: hour or day
+//   - dimension: cluster, namespace or label:<key>
+//   - since: Only anomalies of periods starting at or after this time (RFC3339 or YYYY-MM-DD)
+//   - limit: Maximum number of anomalies (default 100)
+func (s *Server) listAnomalies(c *gin.Context) {
+	tenantID, ok := middleware.GetTenantIDFromContext(c)
+	if !ok {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "no tenant context"})
+		return
+	}
+
+	filter := services.AnomalyFilter{
+		Status:      c.Query("status"),
+		Granularity: c.Query("granularity"),
+		Dimension:   c.Query("dimension"),
+	}
+	if v := c.Query("since"); v != "" {
+		t, err := parseExternalCostQueryTime(v)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "invalid since: " + v})
+			return
+		}
+		filter.Since = t
+	}
+	filter.Limit, _ = strconv.Atoi(c.Query("limit"))
+
+	anomalies, err := s.anomalySvc.ListAnomalies(c.Request.Context(), tenantID, filter)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"anomalies": anomalies,
+		"count":     len(anomalies),
+	})
+}
+
+// GET /v1/anomalies/:id
+// Get an anomaly with its root-cause hints
+func (s *Server) getAnomaly(c *gin.Context) {
+	anomaly, ok := s.loadTenantAnomaly(c)
+	if !ok {
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"anomaly": anomaly,
+	})
+}
+
+// anomalyStateRequest is the optional note recorded when an anomaly is acknowledged or resolved
+type anomalyStateRequest struct {
+	Note string `json:"note"`
+}
+
+// POST /v1/anomalies/:id/acknowledge
+// Acknowledge an open anomaly
+func (s *Server) acknowledgeAnomaly(c *gin.Context) {
+	anomaly, ok := s.loadTenantAnomaly(c)
+	if !ok {
+		return
+	}
+
+	var req anomalyStateRequest
+	if c.Request.ContentLength > 0 {
+		if err := c.ShouldBindJSON(&req); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+	}
+
+	if err := s.anomalySvc.Acknowledge(c.Request.Context(), anomaly, requestActor(c), req.Note); err != nil {
+		c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"anomaly": anomaly,
+	})
+}
+
+// POST /v1/anomalies/:id/resolve
+// Resolve an open or acknowledged anomaly
+func (s *Server) resolveAnomaly(c *gin.Context) {
+	anomaly, ok := s.loadTenantAnomaly(c)
+	if !ok {
+		return
+	}
+
+	var req anomalyStateRequest
+	if c.Request.ContentLength > 0 {
+		if err := c.ShouldBindJSON(&req); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+	}
+
+	if err := s.anomalySvc.Resolve(c.Request.Context(), anomaly, requestActor(c), req.Note); err != nil {
+		c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"anomaly": anomaly,
+	})
+}
+
+// POST /v1/anomalies/detect
+// Evaluate the last complete hour and day now instead of waiting for the background detector
+func (s *Server) detectAnomalies(c *gin.Context) {
+	tenantID, ok := middleware.GetTenantIDFromContext(c)
+	if !ok {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "no tenant context"})
+		return
+	}
+
+	anomalies, err := s.anomalySvc.DetectTenant(c.Request.Context(), tenantID, time.Now())
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"anomalies": anomalies,
+		"count":     len(anomalies),
+	})
+}
+
+// GET /v1/anomalies/settings
+// Get the tenant's anomaly detection settings (defaults when not configured)
+func (s *Server) getAnomalySettings(c *gin.Context) {
+	tenantID, ok := middleware.GetTenantIDFromContext(c)
+	if !ok {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "no tenant context"})
+		return
+	}
+
+	settings, err := s.anomalySvc.GetSettings(c.Request.Context(), tenantID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"settings":   settings,
+		"thresholds": models.AnomalyThresholds,
+	})
+}
+
+// PUT /v1/admin/anomalies/settings
+// Configure anomaly detection: sensitivity (low, medium, high: 4, 3 or 2 standard deviations
+// from the baseline), minimum cost change, daily baseline length, dimensions (cluster,
+// namespace, label:<key>) and granularities (hour, day). Omitted fields keep their value.
+func (s *Server) updateAnomalySettings(c *gin.Context) {
+	tenantID, ok := middleware.GetTenantIDFromContext(c)
+	if !ok {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "no tenant context"})
+		return
+	}
+
+	var req struct {
+		Enabled       *bool    `json:"enabled"`
+		Sensitivity   string   `json:"sensitivity"`
+		MinChangeUSD  *float64 `json:"min_change_usd"`
+		BaselineDays  int      `json:"baseline_days"`
+		Dimensions    []string `json:"dimensions"`
+		Granularities []string `json:"granularities"`
+	}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	settings, err := s.anomalySvc.GetSettings(c.Request.Context(), tenantID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	if req.Enabled != nil {
+		settings.Enabled = *req.Enabled
+	}
+	if req.Sensitivity != "" {
+		settings.Sensitivity = req.Sensitivity
+	}
+	if req.MinChangeUSD != nil {
+		settings.MinChangeUSD = *req.MinChangeUSD
+	}
+	if req.BaselineDays != 0 {
+		settings.BaselineDays = req.BaselineDays
+	}
+	if req.Dimensions != nil {
+		settings.Dimensions = req.Dimensions
+	}
+	if req.Granularities != nil {
+		settings.Granularities = req.Granularities
+	}
+	if err := services.ValidateAnomalySettings(settings); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	if err := s.anomalySvc.SaveSettings(c.Request.Context(), settings); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"settings": settings,
+	})
+}
+
+// loadTenantAnomaly loads the anomaly in the :id parameter and verifies tenant ownership,
+// writing the error response on failure
+func (s *Server) loadTenantAnomaly(c *gin.Context) (*models.CostAnomaly, bool) {
+	tenantID, ok := middleware.GetTenantIDFromContext(c)
+	if !ok {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "no tenant context"})
+		return nil, false
+	}
+
+	id, err := strconv.ParseUint(c.Param("id"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid anomaly ID"})
+		return nil, false
+	}
+
+	anomaly, err := s.anomalySvc.GetAnomaly(c.Request.Context(), uint(id))
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		c.JSON(http.StatusNotFound, gin.H{"error": "anomaly not found"})
+		return nil, false
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return nil, false
+	}
+	if anomaly.TenantID != tenantID {
+		c.JSON(http.StatusForbidden, gin.H{"error": "access denied"})
+		return nil, false
+	}
+	return anomaly, true
+}
@@ -0,0 +1,201 @@
+package api
+
+import (
+	"io"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/bugfreev587/k8s-cost-api-server/internal/middleware"
+	"github.com/bugfreev587/k8s-cost-api-server/internal/models"
+	"github.com/bugfreev587/k8s-cost-api-server/internal/services"
+	"github.com/gin-gonic/gin"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+// maxBillingExportUpload limits the size of an uploaded billing export
+const maxBillingExportUpload = 1 << 30
+
+// getBillingReconciliationService returns a billing reconciliation service instance
+func (s *Server) getBillingReconciliationService() *services.BillingReconciliationService {
+	pool, _ := s.timescaleDB.GetTimescalePool().(*pgxpool.Pool)
+	return services.NewBillingReconciliationService(pool, s.postgresDB.GetPostgresDB())
+}
+
+// POST /v1/admin/billing/imports/:provider
+// Upload a billing export (aws: Cost and Usage Report CSV or Parquet, gcp: billing export
+// CSV, azure: cost export CSV or Parquet; gzip accepted) as the request body or a multipart
+// "file" field. Costs in a billing currency other than USD need the tenant's exchange rates.
+// Line items are matched to nodes by instance ID / name and the actual node costs
+// replace modeled pricing in allocations for the days covered.
+func (s *Server) importBillingExport(c *gin.Context) {
+	tenantID, ok := middleware.GetTenantIDFromContext(c)
+	if !ok {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "no tenant context"})
+		return
+	}
+
+	provider := models.CloudProvider(c.Param("provider"))
+	switch provider {
+	case models.ProviderAWS, models.ProviderGCP, models.ProviderAzure:
+	default:
+		c.JSON(http.StatusBadRequest, gin.H{"error": "unsupported provider: " + string(provider) + " (expected aws, gcp or azure)"})
+		return
+	}
+
+	var body io.Reader = c.Request.Body
+	fileName := c.Query("file_name")
+	if file, header, err := c.Request.FormFile("file"); err == nil {
+		defer file.Close()
+		body = file
+		if fileName == "" {
+			fileName = header.Filename
+		}
+	}
+
+	billingSvc := s.getBillingReconciliationService()
+	record, err := billingSvc.ImportBillingExport(c.Request.Context(), tenantID, provider, fileName, io.LimitReader(body, maxBillingExportUpload))
+	if err != nil {
+		if record != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error(), "import": record})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusCreated, gin.H{
+		"import": record,
+	})
+}
+
+// GET /v1/billing/imports
+// List billing imports for the tenant
+func (s *Server) listBillingImports(c *gin.Context) {
+	tenantID, ok := middleware.GetTenantIDFromContext(c)
+	if !ok {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "no tenant context"})
+		return
+	}
+
+	imports, err := s.getBillingReconciliationService().ListImports(c.Request.Context(), tenantID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"imports": imports,
+		"count":   len(imports),
+	})
+}
+
+// GET /v1/billing/imports/:id
+// Get a billing import
+func (s *Server) getBillingImport(c *gin.Context) {
+	record, ok := s.loadTenantBillingImport(c)
+	if !ok {
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"import": record,
+	})
+}
+
+// DELETE /v1/admin/billing/imports/:id
+// Delete a billing import and the node costs it reconciled
+func (s *Server) deleteBillingImport(c *gin.Context) {
+	record, ok := s.loadTenantBillingImport(c)
+	if !ok {
+		return
+	}
+
+	if err := s.getBillingReconciliationService().DeleteImport(c.Request.Context(), record.ID); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "billing import deleted"})
+}
+
+// GET /v1/billing/reconciled-costs
+// List reconciled daily node costs
+//
+// Query Parameters:
+//   - start, end: RFC3339 timestamps or YYYY-MM-DD dates (default: last 30 days)
+func (s *Server) listReconciledNodeCosts(c *gin.Context) {
+	tenantID, ok := middleware.GetTenantIDFromContext(c)
+	if !ok {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "no tenant context"})
+		return
+	}
+
+	end := time.Now().UTC()
+	start := end.AddDate(0, 0, -30)
+	if v := c.Query("start"); v != "" {
+		t, err := parseExternalCostQueryTime(v)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "invalid start: " + err.Error()})
+			return
+		}
+		start = t
+	}
+	if v := c.Query("end"); v != "" {
+		t, err := parseExternalCostQueryTime(v)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "invalid end: " + err.Error()})
+			return
+		}
+		end = t
+	}
+
+	costs, err := s.getBillingReconciliationService().ListReconciledNodeCosts(c.Request.Context(), tenantID, start, end)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	var listCost, netCost float64
+	for _, cost := range costs {
+		listCost += cost.ListCost
+		netCost += cost.NetCost
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"costs":     costs,
+		"count":     len(costs),
+		"list_cost": listCost,
+		"net_cost":  netCost,
+		"start":     start,
+		"end":       end,
+	})
+}
+
+// loadTenantBillingImport loads the billing import in the :id parameter and verifies
+// that it belongs to the tenant, writing the error response otherwise
+func (s *Server) loadTenantBillingImport(c *gin.Context) (*models.BillingImport, bool) {
+	tenantID, ok := middleware.GetTenantIDFromContext(c)
+	if !ok {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "no tenant context"})
+		return nil, false
+	}
+
+	importID, err := strconv.ParseUint(c.Param("id"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid import ID"})
+		return nil, false
+	}
+
+	record, err := s.getBillingReconciliationService().GetImport(c.Request.Context(), uint(importID))
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "billing import not found"})
+		return nil, false
+	}
+
+	if record.TenantID != tenantID {
+		c.JSON(http.StatusForbidden, gin.H{"error": "access denied"})
+		return nil, false
+	}
+	return record, true
+}
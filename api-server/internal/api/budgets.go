@@ -0,0 +1,237 @@
+package api
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/bugfreev587/k8s-cost-api-server/internal/middleware"
+	"github.com/bugfreev587/k8s-cost-api-server/internal/models"
+	"github.com/bugfreev587/k8s-cost-api-server/internal/services"
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+// budgetRequest is a budget's definition; scoped by allocation filters ("cluster:prod",
+// "namespace:web", "label:team=data"), none for the whole tenant
+type budgetRequest struct {
+	Name            string    `json:"name" binding:"required"`
+	Period          string    `json:"period" binding:"required"` // monthly, quarterly
+	Amount          float64   `json:"amount" binding:"required"`
+	Currency        string    `json:"currency"` // default: the tenant's display currency
+	Filters         []string  `json:"filters"`
+	Thresholds      []float64 `json:"thresholds"` // percent of the amount (default 50, 80, 100)
+	AlertOnForecast *bool     `json:"alert_on_forecast"`
+}
+
+// GET /v1/budgets
+// List budgets with their spend and projected period-end spend as of the last evaluation
+func (s *Server) listBudgets(c *gin.Context) {
+	tenantID, ok := middleware.GetTenantIDFromContext(c)
+	if !ok {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "no tenant context"})
+		return
+	}
+
+	budgets, err := s.budgetSvc.ListBudgets(c.Request.Context(), tenantID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"budgets": budgets,
+		"count":   len(budgets),
+	})
+}
+
+// GET /v1/budgets/:id
+// Get a budget with its current spend, projected period-end spend and daily burn-down
+func (s *Server) getBudget(c *gin.Context) {
+	budget, ok := s.loadTenantBudget(c)
+	if !ok {
+		return
+	}
+
+	status, err := s.budgetSvc.Status(c.Request.Context(), budget, time.Now())
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"budget": budget,
+		"status": status,
+	})
+}
+
+// GET /v1/budgets/:id/alerts
+// List the alerts fired by a budget, newest first
+func (s *Server) listBudgetAlerts(c *gin.Context) {
+	budget, ok := s.loadTenantBudget(c)
+	if !ok {
+		return
+	}
+
+	limit, _ := strconv.Atoi(c.Query("limit"))
+	alerts, err := s.budgetSvc.ListAlerts(c.Request.Context(), budget.TenantID, budget.ID, limit)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"alerts": alerts,
+		"count":  len(alerts),
+	})
+}
+
+// POST /v1/admin/budgets
+// Create a budget
+func (s *Server) createBudget(c *gin.Context) {
+	tenantID, ok := middleware.GetTenantIDFromContext(c)
+	if !ok {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "no tenant context"})
+		return
+	}
+
+	var req budgetRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	budget := &models.Budget{TenantID: tenantID, AlertOnForecast: true}
+	if !s.applyBudgetRequest(c, budget, &req) {
+		return
+	}
+
+	if err := s.budgetSvc.SaveBudget(c.Request.Context(), budget); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusCreated, gin.H{
+		"budget": budget,
+	})
+}
+
+// PUT /v1/admin/budgets/:id
+// Update a budget; alerts already fired in the current period are kept
+func (s *Server) updateBudget(c *gin.Context) {
+	budget, ok := s.loadTenantBudget(c)
+	if !ok {
+		return
+	}
+
+	var req budgetRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if !s.applyBudgetRequest(c, budget, &req) {
+		return
+	}
+
+	if err := s.budgetSvc.SaveBudget(c.Request.Context(), budget); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"budget": budget,
+	})
+}
+
+// DELETE /v1/admin/budgets/:id
+// Delete a budget and its alerts
+func (s *Server) deleteBudget(c *gin.Context) {
+	budget, ok := s.loadTenantBudget(c)
+	if !ok {
+		return
+	}
+
+	if err := s.budgetSvc.DeleteBudget(c.Request.Context(), budget.ID); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "budget deleted"})
+}
+
+// POST /v1/admin/budgets/:id/evaluate
+// Evaluate a budget now instead of waiting for the scheduled evaluation, firing any alerts due
+func (s *Server) evaluateBudget(c *gin.Context) {
+	budget, ok := s.loadTenantBudget(c)
+	if !ok {
+		return
+	}
+
+	fired, err := s.budgetSvc.Evaluate(c.Request.Context(), budget, time.Now())
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"budget": budget,
+		"alerts": fired,
+	})
+}
+
+// applyBudgetRequest copies a budget request onto a budget and validates it, writing a 400
+// response on failure
+func (s *Server) applyBudgetRequest(c *gin.Context, budget *models.Budget, req *budgetRequest) bool {
+	currency, err := s.getCurrencyService().ResolveCurrency(c.Request.Context(), budget.TenantID, req.Currency)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return false
+	}
+
+	budget.Name = req.Name
+	budget.Period = req.Period
+	budget.Amount = req.Amount
+	budget.Currency = currency
+	budget.Filters = req.Filters
+	budget.Thresholds = req.Thresholds
+	if req.AlertOnForecast != nil {
+		budget.AlertOnForecast = *req.AlertOnForecast
+	}
+	if err := services.ValidateBudget(budget); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return false
+	}
+	return true
+}
+
+// loadTenantBudget loads the budget in the :id parameter and verifies tenant ownership,
+// writing the error response on failure
+func (s *Server) loadTenantBudget(c *gin.Context) (*models.Budget, bool) {
+	tenantID, ok := middleware.GetTenantIDFromContext(c)
+	if !ok {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "no tenant context"})
+		return nil, false
+	}
+
+	id, err := strconv.ParseUint(c.Param("id"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid budget ID"})
+		return nil, false
+	}
+
+	budget, err := s.budgetSvc.GetBudget(c.Request.Context(), uint(id))
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		c.JSON(http.StatusNotFound, gin.H{"error": "budget not found"})
+		return nil, false
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return nil, false
+	}
+	if budget.TenantID != tenantID {
+		c.JSON(http.StatusForbidden, gin.H{"error": "access denied"})
+		return nil, false
+	}
+	return budget, true
+}
@@ -0,0 +1,195 @@
+package api
+
+import (
+	"net/http"
+	"strconv"
+
+	"github.com/bugfreev587/k8s-cost-api-server/internal/middleware"
+	"github.com/bugfreev587/k8s-cost-api-server/internal/models"
+	"github.com/bugfreev587/k8s-cost-api-server/internal/services"
+	"github.com/gin-gonic/gin"
+)
+
+// getCommitmentService returns the shared commitment service
+func (s *Server) getCommitmentService() *services.CommitmentService {
+	return s.commitmentSvc
+}
+
+type commitmentRequest struct {
+	Name             string               `json:"name" binding:"required"`
+	Provider         models.CloudProvider `json:"provider" binding:"required"`
+	Type             string               `json:"type" binding:"required"`
+	Term             models.PricingTier   `json:"term" binding:"required"`
+	UpfrontCost      float64              `json:"upfront_cost"`
+	HourlyCommitment float64              `json:"hourly_commitment"`
+	DiscountPercent  float64              `json:"discount_percent"`
+	InstanceFamilies []string             `json:"instance_families"`
+	Clusters         []string             `json:"clusters"`
+	StartDate        string               `json:"start_date" binding:"required"` // YYYY-MM-DD or RFC3339
+}
+
+// apply copies the request onto a commitment and validates it
+func (req *commitmentRequest) apply(commitment *models.Commitment) error {
+	startDate, err := services.ParseCommitmentDate(req.StartDate)
+	if err != nil {
+		return err
+	}
+	commitment.Name = req.Name
+	commitment.Provider = req.Provider
+	commitment.Type = req.Type
+	commitment.Term = req.Term
+	commitment.UpfrontCost = req.UpfrontCost
+	commitment.HourlyCommitment = req.HourlyCommitment
+	commitment.DiscountPercent = req.DiscountPercent
+	commitment.InstanceFamilies = req.InstanceFamilies
+	commitment.Clusters = req.Clusters
+	commitment.StartDate = startDate
+	return services.ValidateCommitment(commitment)
+}
+
+// GET /v1/commitments
+// List the tenant's commitments
+func (s *Server) listCommitments(c *gin.Context) {
+	tenantID, ok := middleware.GetTenantIDFromContext(c)
+	if !ok {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "no tenant context"})
+		return
+	}
+
+	commitments, err := s.getCommitmentService().ListCommitments(c.Request.Context(), tenantID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"commitments": commitments,
+		"count":       len(commitments),
+	})
+}
+
+// GET /v1/commitments/utilization
+// Report commitment utilization, savings and wasted commitment
+//
+// Query Parameters:
+//   - window: Time window (default "30d"). Same formats as /v1/allocation
+func (s *Server) getCommitmentUtilization(c *gin.Context) {
+	tenantID, ok := middleware.GetTenantIDFromContext(c)
+	if !ok {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "no tenant context"})
+		return
+	}
+
+	report, err := s.getCommitmentService().GetUtilization(c.Request.Context(), tenantID, c.DefaultQuery("window", "30d"))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"utilization": report,
+	})
+}
+
+// POST /v1/admin/commitments
+// Declare a commitment
+func (s *Server) createCommitment(c *gin.Context) {
+	tenantID, ok := middleware.GetTenantIDFromContext(c)
+	if !ok {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "no tenant context"})
+		return
+	}
+
+	var req commitmentRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	commitment := &models.Commitment{TenantID: tenantID}
+	if err := req.apply(commitment); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	if err := s.getCommitmentService().SaveCommitment(c.Request.Context(), commitment); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusCreated, gin.H{
+		"commitment": commitment,
+	})
+}
+
+// PUT /v1/admin/commitments/:id
+// Update a commitment
+func (s *Server) updateCommitment(c *gin.Context) {
+	commitment, ok := s.loadTenantCommitment(c)
+	if !ok {
+		return
+	}
+
+	var req commitmentRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if err := req.apply(commitment); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	if err := s.getCommitmentService().SaveCommitment(c.Request.Context(), commitment); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"commitment": commitment,
+	})
+}
+
+// DELETE /v1/admin/commitments/:id
+// Delete a commitment
+func (s *Server) deleteCommitment(c *gin.Context) {
+	commitment, ok := s.loadTenantCommitment(c)
+	if !ok {
+		return
+	}
+
+	if err := s.getCommitmentService().DeleteCommitment(c.Request.Context(), commitment.ID); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "commitment deleted"})
+}
+
+// loadTenantCommitment loads the commitment in the :id parameter and verifies that it
+// belongs to the tenant, writing the error response otherwise
+func (s *Server) loadTenantCommitment(c *gin.Context) (*models.Commitment, bool) {
+	tenantID, ok := middleware.GetTenantIDFromContext(c)
+	if !ok {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "no tenant context"})
+		return nil, false
+	}
+
+	commitmentID, err := strconv.ParseUint(c.Param("id"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid commitment ID"})
+		return nil, false
+	}
+
+	commitment, err := s.getCommitmentService().GetCommitment(c.Request.Context(), uint(commitmentID))
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "commitment not found"})
+		return nil, false
+	}
+
+	if commitment.TenantID != tenantID {
+		c.JSON(http.StatusForbidden, gin.H{"error": "access denied"})
+		return nil, false
+	}
+	return commitment, true
+}
@@ -1,9 +1,12 @@
 package api
 
 import (
+	"fmt"
 	"net/http"
+	"strconv"
 	"time"
 
+	"github.com/bugfreev587/k8s-cost-api-server/internal/export"
 	"github.com/bugfreev587/k8s-cost-api-server/internal/middleware"
 	"github.com/bugfreev587/k8s-cost-api-server/internal/services"
 	"github.com/gin-gonic/gin"
@@ -11,19 +14,27 @@ import (
 )
 
 // GET /v1/costs/namespaces
+// Costs are also reported as estimated_cost in the "currency" query parameter (default: the
+// tenant's display currency), converted at the rate effective at start_time. format=csv|xlsx|parquet
+// streams the rows as a file download instead of JSON, writing each row as it is read from
+// the database; the other /v1/costs endpoints accept it too
 func (s *Server) getCostsByNamespace(c *gin.Context) {
 	tenantID, ok := middleware.GetTenantIDFromContext(c)
 	if !ok {
 		c.JSON(http.StatusUnauthorized, gin.H{"error": "no tenant context"})
 		return
 	}
+	format, err := exportFormat(c)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
 
 	// Parse query parameters
 	startTimeStr := c.DefaultQuery("start_time", "")
 	endTimeStr := c.DefaultQuery("end_time", "")
 
 	var startTime, endTime time.Time
-	var err error
 
 	if startTimeStr == "" {
 		startTime = time.Now().AddDate(0, 0, -7) // Default: 7 days ago
@@ -45,35 +56,62 @@ func (s *Server) getCostsByNamespace(c *gin.Context) {
 		}
 	}
 
+	converter, ok := s.currencyConverter(c, tenantID, startTime, endTime)
+	if !ok {
+		return
+	}
+
 	pool := s.timescaleDB.GetTimescalePool().(*pgxpool.Pool)
 	costSvc := services.NewCostService(pool)
+	if format != "" {
+		err := streamExport(c, "costs-namespaces", format, namespaceCostColumns, func(w export.Writer) error {
+			return costSvc.EachNamespaceCost(c.Request.Context(), int64(tenantID), startTime, endTime, func(r services.NamespaceCost) error {
+				r.EstimatedCost = converter.Convert(r.EstimatedCostUSD, startTime)
+				return writeNamespaceCost(w, r, converter.Currency)
+			})
+		})
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		}
+		return
+	}
+
 	results, err := costSvc.CostByNamespace(c.Request.Context(), int64(tenantID), startTime, endTime)
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
 		return
 	}
+	for i := range results {
+		results[i].EstimatedCost = converter.Convert(results[i].EstimatedCostUSD, startTime)
+	}
 
 	c.JSON(http.StatusOK, gin.H{
 		"start_time": startTime,
 		"end_time":   endTime,
+		"currency":   converter.Currency,
 		"costs":      results,
 	})
 }
 
 // GET /v1/costs/clusters
+// Same currency handling as /v1/costs/namespaces
 func (s *Server) getCostsByCluster(c *gin.Context) {
 	tenantID, ok := middleware.GetTenantIDFromContext(c)
 	if !ok {
 		c.JSON(http.StatusUnauthorized, gin.H{"error": "no tenant context"})
 		return
 	}
+	format, err := exportFormat(c)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
 
 	// Parse query parameters
 	startTimeStr := c.DefaultQuery("start_time", "")
 	endTimeStr := c.DefaultQuery("end_time", "")
 
 	var startTime, endTime time.Time
-	var err error
 
 	if startTimeStr == "" {
 		startTime = time.Now().AddDate(0, 0, -7)
@@ -95,17 +133,39 @@ func (s *Server) getCostsByCluster(c *gin.Context) {
 		}
 	}
 
+	converter, ok := s.currencyConverter(c, tenantID, startTime, endTime)
+	if !ok {
+		return
+	}
+
 	pool := s.timescaleDB.GetTimescalePool().(*pgxpool.Pool)
 	costSvc := services.NewCostService(pool)
+	if format != "" {
+		err := streamExport(c, "costs-clusters", format, clusterCostColumns, func(w export.Writer) error {
+			return costSvc.EachClusterCost(c.Request.Context(), int64(tenantID), startTime, endTime, func(r services.ClusterCost) error {
+				r.EstimatedCost = converter.Convert(r.EstimatedCostUSD, startTime)
+				return writeClusterCost(w, r, converter.Currency)
+			})
+		})
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		}
+		return
+	}
+
 	results, err := costSvc.CostByCluster(c.Request.Context(), int64(tenantID), startTime, endTime)
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
 		return
 	}
+	for i := range results {
+		results[i].EstimatedCost = converter.Convert(results[i].EstimatedCostUSD, startTime)
+	}
 
 	c.JSON(http.StatusOK, gin.H{
 		"start_time": startTime,
 		"end_time":   endTime,
+		"currency":   converter.Currency,
 		"costs":      results,
 	})
 }
@@ -117,6 +177,11 @@ func (s *Server) getUtilizationVsRequests(c *gin.Context) {
 		c.JSON(http.StatusUnauthorized, gin.H{"error": "no tenant context"})
 		return
 	}
+	format, err := exportFormat(c)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
 
 	// Parse query parameters
 	startTimeStr := c.DefaultQuery("start_time", "")
@@ -125,7 +190,6 @@ func (s *Server) getUtilizationVsRequests(c *gin.Context) {
 	cluster := c.Query("cluster")
 
 	var startTime, endTime time.Time
-	var err error
 
 	if startTimeStr == "" {
 		startTime = time.Now().AddDate(0, 0, -7)
@@ -149,6 +213,18 @@ func (s *Server) getUtilizationVsRequests(c *gin.Context) {
 
 	pool := s.timescaleDB.GetTimescalePool().(*pgxpool.Pool)
 	costSvc := services.NewCostService(pool)
+	if format != "" {
+		err := streamExport(c, "costs-utilization", format, utilizationColumns, func(w export.Writer) error {
+			return costSvc.EachUtilizationMetric(c.Request.Context(), int64(tenantID), startTime, endTime, namespace, cluster, func(r services.UtilizationMetric) error {
+				return writeUtilizationMetric(w, r)
+			})
+		})
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		}
+		return
+	}
+
 	results, err := costSvc.UtilizationVsRequests(c.Request.Context(), int64(tenantID), startTime, endTime, namespace, cluster)
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
@@ -163,12 +239,18 @@ func (s *Server) getUtilizationVsRequests(c *gin.Context) {
 }
 
 // GET /v1/costs/trends
+// Each bucket's estimated_cost is converted at the exchange rate effective at the bucket time
 func (s *Server) getCostTrends(c *gin.Context) {
 	tenantID, ok := middleware.GetTenantIDFromContext(c)
 	if !ok {
 		c.JSON(http.StatusUnauthorized, gin.H{"error": "no tenant context"})
 		return
 	}
+	format, err := exportFormat(c)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
 
 	// Parse query parameters
 	startTimeStr := c.DefaultQuery("start_time", "")
@@ -176,7 +258,6 @@ func (s *Server) getCostTrends(c *gin.Context) {
 	interval := c.DefaultQuery("interval", "daily") // daily, weekly, hourly
 
 	var startTime, endTime time.Time
-	var err error
 
 	if startTimeStr == "" {
 		startTime = time.Now().AddDate(0, 0, -30) // Default: 30 days ago
@@ -198,18 +279,99 @@ func (s *Server) getCostTrends(c *gin.Context) {
 		}
 	}
 
+	converter, ok := s.currencyConverter(c, tenantID, startTime, endTime)
+	if !ok {
+		return
+	}
+
 	pool := s.timescaleDB.GetTimescalePool().(*pgxpool.Pool)
 	costSvc := services.NewCostService(pool)
+	if format != "" {
+		err := streamExport(c, "costs-trends", format, costTrendColumns, func(w export.Writer) error {
+			return costSvc.EachCostTrend(c.Request.Context(), int64(tenantID), startTime, endTime, interval, func(r services.CostTrend) error {
+				r.EstimatedCost = converter.Convert(r.EstimatedCostUSD, r.Time)
+				return writeCostTrend(w, r, converter.Currency)
+			})
+		})
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		}
+		return
+	}
+
 	results, err := costSvc.CostTrends(c.Request.Context(), int64(tenantID), startTime, endTime, interval)
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
 		return
 	}
+	for i := range results {
+		results[i].EstimatedCost = converter.Convert(results[i].EstimatedCostUSD, results[i].Time)
+	}
 
 	c.JSON(http.StatusOK, gin.H{
 		"start_time": startTime,
 		"end_time":   endTime,
+		"currency":   converter.Currency,
 		"interval":   interval,
 		"trends":     results,
 	})
 }
+
+// GET /v1/costs/forecast
+// Forecast daily cost per allocation from its daily allocation history (Holt-Winters with
+// weekly seasonality, or a linear trend for short histories), with confidence bands and
+// month-to-date / quarter-to-date costs plus projected month-end and quarter-end totals
+//
+// Query Parameters:
+//   - aggregate: cluster, namespace, label:<key>, ... (default namespace)
+//   - filter: Allocation filters, as for /v1/allocation
+//   - history: Days of history to fit on (default 56, max 365)
+//   - horizon: Days to forecast from today (default 30, max 366)
+//   - confidence: Confidence level of the bands (default 0.95)
+//   - idle, shareIdle: Idle cost handling, as for /v1/allocation
+//   - currency: Currency to report costs in (default: the tenant's display currency)
+func (s *Server) getCostForecast(c *gin.Context) {
+	tenantID, ok := middleware.GetTenantIDFromContext(c)
+	if !ok {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "no tenant context"})
+		return
+	}
+
+	params := services.ForecastParams{
+		Aggregate: c.DefaultQuery("aggregate", "namespace"),
+		Filters:   c.QueryArray("filter"),
+		Idle:      c.Query("idle") == "true",
+		ShareIdle: c.Query("shareIdle"),
+	}
+	var err error
+	if v := c.Query("history"); v != "" {
+		if params.HistoryDays, err = strconv.Atoi(v); err != nil || params.HistoryDays < 1 || params.HistoryDays > services.MaxForecastHistoryDays {
+			c.JSON(http.StatusBadRequest, gin.H{"error": fmt.Sprintf("history must be between 1 and %d days", services.MaxForecastHistoryDays)})
+			return
+		}
+	}
+	if v := c.Query("horizon"); v != "" {
+		if params.Horizon, err = strconv.Atoi(v); err != nil || params.Horizon < 1 || params.Horizon > services.MaxForecastHorizon {
+			c.JSON(http.StatusBadRequest, gin.H{"error": fmt.Sprintf("horizon must be between 1 and %d days", services.MaxForecastHorizon)})
+			return
+		}
+	}
+	if v := c.Query("confidence"); v != "" {
+		if params.Confidence, err = strconv.ParseFloat(v, 64); err != nil || params.Confidence <= 0 || params.Confidence >= 1 {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "confidence must be between 0 and 1"})
+			return
+		}
+	}
+	if params.Currency, err = s.resolveRequestCurrency(c, tenantID); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	report, err := s.allocSvc.Forecast(c.Request.Context(), int64(tenantID), params)
+	if err != nil {
+		c.JSON(allocationErrorStatus(err), gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, report)
+}
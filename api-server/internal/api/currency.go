@@ -0,0 +1,206 @@
+package api
+
+import (
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/bugfreev587/k8s-cost-api-server/internal/middleware"
+	"github.com/bugfreev587/k8s-cost-api-server/internal/models"
+	"github.com/bugfreev587/k8s-cost-api-server/internal/services"
+	"github.com/gin-gonic/gin"
+)
+
+// getCurrencyService returns a currency service instance
+func (s *Server) getCurrencyService() *services.CurrencyService {
+	return services.NewCurrencyService(s.postgresDB.GetPostgresDB())
+}
+
+// resolveRequestCurrency returns the currency requested with the "currency" query
+// parameter, defaulting to the tenant's display currency
+func (s *Server) resolveRequestCurrency(c *gin.Context, tenantID uint) (string, error) {
+	return s.getCurrencyService().ResolveCurrency(c.Request.Context(), tenantID, c.Query("currency"))
+}
+
+// currencyConverter resolves the request currency and returns a converter for costs between
+// start and end, writing a 400 response if the currency or its exchange rates are missing
+func (s *Server) currencyConverter(c *gin.Context, tenantID uint, start, end time.Time) (*services.CurrencyConverter, bool) {
+	currency, err := s.resolveRequestCurrency(c, tenantID)
+	if err == nil {
+		var converter *services.CurrencyConverter
+		if converter, err = s.getCurrencyService().Converter(c.Request.Context(), tenantID, currency, start, end); err == nil {
+			return converter, true
+		}
+	}
+	c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+	return nil, false
+}
+
+// GET /v1/currency
+// Returns the tenant's display currency and exchange rates
+//
+// Query Parameters:
+//   - currency: Only list rates for this currency
+func (s *Server) getCurrencySettings(c *gin.Context) {
+	tenantID, ok := middleware.GetTenantIDFromContext(c)
+	if !ok {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "no tenant context"})
+		return
+	}
+
+	currencySvc := s.getCurrencyService()
+	displayCurrency, err := currencySvc.ResolveCurrency(c.Request.Context(), tenantID, "")
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	filter := c.Query("currency")
+	if filter != "" {
+		if filter, err = services.NormalizeCurrency(filter); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+	}
+	rates, err := currencySvc.ListExchangeRates(c.Request.Context(), tenantID, filter)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"base_currency":    models.BaseCurrency,
+		"display_currency": displayCurrency,
+		"exchange_rates":   rates,
+	})
+}
+
+// PUT /v1/admin/currency
+// Set the tenant's display currency
+func (s *Server) setDisplayCurrency(c *gin.Context) {
+	tenantID, ok := middleware.GetTenantIDFromContext(c)
+	if !ok {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "no tenant context"})
+		return
+	}
+
+	var req struct {
+		DisplayCurrency string `json:"display_currency" binding:"required"`
+	}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	currency, err := services.NormalizeCurrency(req.DisplayCurrency)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	currencySvc := s.getCurrencyService()
+	if currency != models.BaseCurrency {
+		// Require at least one rate so that reports in the new currency can be converted
+		rates, err := currencySvc.ListExchangeRates(c.Request.Context(), tenantID, currency)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return
+		}
+		if len(rates) == 0 {
+			c.JSON(http.StatusBadRequest, gin.H{"error": fmt.Sprintf("add a %s exchange rate before using it as display currency", currency)})
+			return
+		}
+	}
+
+	if err := currencySvc.SetDisplayCurrency(c.Request.Context(), tenantID, currency); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"display_currency": currency,
+		"message":          "display currency updated",
+	})
+}
+
+// POST /v1/admin/exchange-rates
+// Add an exchange rate from USD, replacing any rate for the same currency and date
+func (s *Server) setExchangeRate(c *gin.Context) {
+	tenantID, ok := middleware.GetTenantIDFromContext(c)
+	if !ok {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "no tenant context"})
+		return
+	}
+
+	var req struct {
+		Currency      string  `json:"currency" binding:"required"`
+		Rate          float64 `json:"rate" binding:"required"`           // units of currency per 1 USD
+		EffectiveFrom string  `json:"effective_from" binding:"required"` // YYYY-MM-DD or RFC3339
+	}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	currency, err := services.NormalizeCurrency(req.Currency)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if currency == models.BaseCurrency {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "costs are stored in USD; no exchange rate is needed"})
+		return
+	}
+	if req.Rate <= 0 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "rate must be positive"})
+		return
+	}
+	effectiveFrom, err := parseExternalCostQueryTime(req.EffectiveFrom)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid effective_from, use YYYY-MM-DD or RFC3339"})
+		return
+	}
+
+	rate := &models.ExchangeRate{
+		TenantID:      tenantID,
+		Currency:      currency,
+		Rate:          req.Rate,
+		EffectiveFrom: effectiveFrom,
+	}
+	if err := s.getCurrencyService().SetExchangeRate(c.Request.Context(), rate); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusCreated, gin.H{
+		"exchange_rate": rate,
+	})
+}
+
+// DELETE /v1/admin/exchange-rates/:id
+// Delete an exchange rate
+func (s *Server) deleteExchangeRate(c *gin.Context) {
+	tenantID, ok := middleware.GetTenantIDFromContext(c)
+	if !ok {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "no tenant context"})
+		return
+	}
+
+	rateID, err := strconv.ParseUint(c.Param("id"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid exchange rate ID"})
+		return
+	}
+
+	deleted, err := s.getCurrencyService().DeleteExchangeRate(c.Request.Context(), tenantID, uint(rateID))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	if deleted == 0 {
+		c.JSON(http.StatusNotFound, gin.H{"error": "exchange rate not found"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "exchange rate deleted"})
+}
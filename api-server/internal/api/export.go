@@ -0,0 +1,239 @@
+package api
+
+import (
+	"fmt"
+	"log"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/bugfreev587/k8s-cost-api-server/internal/export"
+	"github.com/bugfreev587/k8s-cost-api-server/internal/middleware"
+	"github.com/bugfreev587/k8s-cost-api-server/internal/services"
+	"github.com/gin-gonic/gin"
+)
+
+// exportFormat returns the export format requested by the "format" query parameter: ""
+// for the default JSON response, or csv, xlsx or parquet
+func exportFormat(c *gin.Context) (string, error) {
+	format := strings.ToLower(c.Query("format"))
+	if format == "" || format == "json" {
+		return "", nil
+	}
+	if !export.ValidFormat(format) {
+		return "", fmt.Errorf("invalid format: %s (expected json, csv, xlsx or parquet)", format)
+	}
+	return format, nil
+}
+
+// streamExport streams rows as an attachment in the given format. Rows are encoded as they
+// are written, and the attachment headers are only sent with the first row: an error before
+// then is returned so the caller can still answer with a JSON error. Once streaming has
+// started an error can only truncate the output, so it is logged and nil is returned.
+func streamExport(c *gin.Context, name, format string, columns []export.Column, write func(export.Writer) error) error {
+	w := &exportStream{c: c, name: name, format: format, columns: columns}
+	err := write(w)
+	if err != nil && !w.started {
+		return err
+	}
+	if closeErr := w.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		log.Printf("%s export failed: %v", name, err)
+	}
+	return nil
+}
+
+// exportStream is the export.Writer handed to streamExport's write func. It sends the
+// attachment headers and creates the format writer on the first row, or on Close for an
+// export without rows
+type exportStream struct {
+	c       *gin.Context
+	name    string
+	format  string
+	columns []export.Column
+	started bool
+	w       export.Writer
+	err     error
+}
+
+func (s *exportStream) start() error {
+	if !s.started {
+		s.started = true
+		filename := fmt.Sprintf("%s-%s.%s", s.name, time.Now().UTC().Format("20060102T150405Z"), s.format)
+		s.c.Header("Content-Type", export.ContentType(s.format))
+		s.c.Header("Content-Disposition", fmt.Sprintf("attachment; filename=%q", filename))
+		s.c.Status(http.StatusOK)
+		s.w, s.err = export.NewWriter(s.format, s.c.Writer, s.columns)
+	}
+	return s.err
+}
+
+func (s *exportStream) WriteRow(values ...interface{}) error {
+	if err := s.start(); err != nil {
+		return err
+	}
+	return s.w.WriteRow(values...)
+}
+
+func (s *exportStream) Close() error {
+	if err := s.start(); err != nil {
+		return err
+	}
+	return s.w.Close()
+}
+
+var namespaceCostColumns = []export.Column{
+	{Name: "namespace", Type: export.String},
+	{Name: "total_cpu_request_millicores", Type: export.Int},
+	{Name: "total_memory_request_bytes", Type: export.Int},
+	{Name: "avg_cpu_usage_millicores", Type: export.Float},
+	{Name: "avg_memory_usage_bytes", Type: export.Float},
+	{Name: "pod_count", Type: export.Int},
+	{Name: "estimated_cost_usd", Type: export.Float},
+	{Name: "estimated_cost", Type: export.Float},
+	{Name: "currency", Type: export.String},
+}
+
+func writeNamespaceCost(w export.Writer, r services.NamespaceCost, currency string) error {
+	return w.WriteRow(r.Namespace, r.TotalCPURequest, r.TotalMemoryRequest, r.AvgCPUUsage, r.AvgMemoryUsage,
+		r.PodCount, r.EstimatedCostUSD, r.EstimatedCost, currency)
+}
+
+var clusterCostColumns = []export.Column{
+	{Name: "cluster_name", Type: export.String},
+	{Name: "total_cpu_request_millicores", Type: export.Int},
+	{Name: "total_memory_request_bytes", Type: export.Int},
+	{Name: "avg_cpu_usage_millicores", Type: export.Float},
+	{Name: "avg_memory_usage_bytes", Type: export.Float},
+	{Name: "pod_count", Type: export.Int},
+	{Name: "namespace_count", Type: export.Int},
+	{Name: "estimated_cost_usd", Type: export.Float},
+	{Name: "estimated_cost", Type: export.Float},
+	{Name: "currency", Type: export.String},
+}
+
+func writeClusterCost(w export.Writer, r services.ClusterCost, currency string) error {
+	return w.WriteRow(r.ClusterName, r.TotalCPURequest, r.TotalMemoryRequest, r.AvgCPUUsage, r.AvgMemoryUsage,
+		r.PodCount, r.NamespaceCount, r.EstimatedCostUSD, r.EstimatedCost, currency)
+}
+
+var utilizationColumns = []export.Column{
+	{Name: "cluster_name", Type: export.String},
+	{Name: "namespace", Type: export.String},
+	{Name: "pod_name", Type: export.String},
+	{Name: "avg_cpu_usage_millicores", Type: export.Float},
+	{Name: "avg_cpu_request_millicores", Type: export.Float},
+	{Name: "avg_memory_usage_bytes", Type: export.Float},
+	{Name: "avg_memory_request_bytes", Type: export.Float},
+	{Name: "cpu_utilization_percent", Type: export.Float},
+	{Name: "memory_utilization_percent", Type: export.Float},
+}
+
+func writeUtilizationMetric(w export.Writer, r services.UtilizationMetric) error {
+	return w.WriteRow(r.ClusterName, r.Namespace, r.PodName, r.AvgCPUUsage, r.AvgCPURequest,
+		r.AvgMemoryUsage, r.AvgMemoryRequest, r.CPUUtilizationPercent, r.MemoryUtilizationPercent)
+}
+
+var costTrendColumns = []export.Column{
+	{Name: "time", Type: export.Time},
+	{Name: "total_cpu_request_millicores", Type: export.Int},
+	{Name: "total_memory_request_bytes", Type: export.Int},
+	{Name: "avg_cpu_usage_millicores", Type: export.Float},
+	{Name: "avg_memory_usage_bytes", Type: export.Float},
+	{Name: "pod_count", Type: export.Int},
+	{Name: "estimated_cost_usd", Type: export.Float},
+	{Name: "estimated_cost", Type: export.Float},
+	{Name: "currency", Type: export.String},
+}
+
+func writeCostTrend(w export.Writer, r services.CostTrend, currency string) error {
+	return w.WriteRow(r.Time, r.TotalCPURequest, r.TotalMemoryRequest, r.AvgCPUUsage, r.AvgMemoryUsage,
+		r.PodCount, r.EstimatedCostUSD, r.EstimatedCost, currency)
+}
+
+// GET /v1/allocation/focus
+// Exports allocations in the FinOps Open Cost and Usage Specification (FOCUS) schema: one
+// charge per pod, day and cost component (CPU, memory, shared), plus per-cluster idle cost.
+// Clusters map to sub accounts with the provider and region of their pricing config, pods to
+// resources, and pod labels plus the cluster and namespace to Tags.
+//
+// Query Parameters:
+//   - window: Time window, as for /v1/allocation (default "30d")
+//   - format: "csv" (default) or "parquet"
+//   - filter: Allocation filters, as for /v1/allocation
+//   - idle: Report idle capacity as separate charges (default "true")
+//   - shareIdle, idleBy: Idle cost handling, as for /v1/allocation (idle scope defaults to cluster)
+//   - shareNamespaces, shareLabels, shareSplit, applySharingRules: Shared costs, as for /v1/allocation
+//   - rawLabels: Ignore the tenant's virtual label rules, as for /v1/allocation
+//   - reconcile, applyCommitments: As for /v1/allocation (default "true")
+//   - currency: BillingCurrency of the export (default: the tenant's display currency)
+//   - pricingAsOf, pricingVersion: Pricing pin, as for /v1/allocation
+func (s *Server) getAllocationFocus(c *gin.Context) {
+	tenantID, ok := middleware.GetTenantIDFromContext(c)
+	if !ok {
+		c.JSON(http.StatusUnauthorized, gin.H{
+			"code":    401,
+			"status":  "error",
+			"message": "no tenant context",
+		})
+		return
+	}
+
+	format := strings.ToLower(c.DefaultQuery("format", export.FormatCSV))
+	if format != export.FormatCSV && format != export.FormatParquet {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"code":    400,
+			"status":  "error",
+			"message": fmt.Sprintf("invalid format: %s (expected csv or parquet)", format),
+		})
+		return
+	}
+
+	params := services.AllocationParams{
+		Window:    c.DefaultQuery("window", "30d"),
+		Idle:      c.DefaultQuery("idle", "true") == "true",
+		ShareIdle: c.Query("shareIdle"),
+		IdleBy:    c.Query("idleBy"),
+		Filters:   c.QueryArray("filter"),
+	}
+	parseSharingParams(c, &params)
+	params.RawLabels = c.Query("rawLabels") == "true"
+	params.Reconcile = c.DefaultQuery("reconcile", "true") == "true"
+	params.ApplyCommitments = c.DefaultQuery("applyCommitments", "true") == "true"
+
+	currency, err := s.resolveRequestCurrency(c, tenantID)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"code":    400,
+			"status":  "error",
+			"message": err.Error(),
+		})
+		return
+	}
+	params.Currency = currency
+	if !s.parsePricingPin(c, tenantID, &params) {
+		return
+	}
+
+	focus, err := s.allocSvc.Focus(c.Request.Context(), int64(tenantID), params)
+	if err != nil {
+		status := allocationErrorStatus(err)
+		c.JSON(status, gin.H{
+			"code":    status,
+			"status":  "error",
+			"message": err.Error(),
+		})
+		return
+	}
+
+	c.Header("X-FOCUS-Version", services.FocusVersion)
+	if err := streamExport(c, "focus", format, services.FocusColumns, focus.Write); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"code":    500,
+			"status":  "error",
+			"message": err.Error(),
+		})
+	}
+}
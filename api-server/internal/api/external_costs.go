@@ -0,0 +1,221 @@
+package api
+
+import (
+	"errors"
+	"io"
+	"net/http"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/bugfreev587/k8s-cost-api-server/internal/middleware"
+	"github.com/bugfreev587/k8s-cost-api-server/internal/models"
+	"github.com/bugfreev587/k8s-cost-api-server/internal/services"
+	"github.com/gin-gonic/gin"
+)
+
+// maxExternalCostUpload limits the size of an uploaded external cost file
+const maxExternalCostUpload = 32 << 20
+
+// getExternalCostService returns an external cost service instance
+func (s *Server) getExternalCostService() *services.ExternalCostService {
+	return services.NewExternalCostService(s.postgresDB.GetPostgresDB())
+}
+
+// GET /v1/external-costs
+// List external cost line items overlapping a time range
+//
+// Query Parameters:
+//   - start, end: RFC3339 timestamps or YYYY-MM-DD dates (default: last 30 days)
+func (s *Server) listExternalCosts(c *gin.Context) {
+	tenantID, ok := middleware.GetTenantIDFromContext(c)
+	if !ok {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "no tenant context"})
+		return
+	}
+
+	end := time.Now().UTC()
+	start := end.AddDate(0, 0, -30)
+	if v := c.Query("start"); v != "" {
+		t, err := parseExternalCostQueryTime(v)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "invalid start: " + err.Error()})
+			return
+		}
+		start = t
+	}
+	if v := c.Query("end"); v != "" {
+		t, err := parseExternalCostQueryTime(v)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "invalid end: " + err.Error()})
+			return
+		}
+		end = t
+	}
+
+	costs, err := s.getExternalCostService().ListExternalCosts(c.Request.Context(), tenantID, start, end)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	var total float64
+	for _, cost := range costs {
+		total += cost.Amount
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"costs":        costs,
+		"count":        len(costs),
+		"total_amount": total,
+		"start":        start,
+		"end":          end,
+	})
+}
+
+// POST /v1/admin/external-costs
+// Upload external cost line items as CSV or JSON, either as the request body
+// (Content-Type text/csv or application/json) or as a multipart "file" field.
+// The optional "source" query parameter labels the upload (defaults to the file name).
+// Uploads with items in a currency without an exchange rate at their start are rejected.
+func (s *Server) uploadExternalCosts(c *gin.Context) {
+	tenantID, ok := middleware.GetTenantIDFromContext(c)
+	if !ok {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "no tenant context"})
+		return
+	}
+
+	source := c.Query("source")
+	format := c.Query("format")
+	var body io.Reader
+
+	if file, header, err := c.Request.FormFile("file"); err == nil {
+		defer file.Close()
+		body = file
+		if source == "" {
+			source = header.Filename
+		}
+		if format == "" {
+			format = strings.TrimPrefix(strings.ToLower(filepath.Ext(header.Filename)), ".")
+		}
+	} else {
+		body = c.Request.Body
+		if format == "" {
+			if strings.Contains(c.ContentType(), "csv") {
+				format = "csv"
+			} else {
+				format = "json"
+			}
+		}
+	}
+	body = io.LimitReader(body, maxExternalCostUpload)
+
+	var costs []models.ExternalCost
+	var err error
+	switch format {
+	case "csv":
+		costs, err = services.ParseExternalCostsCSV(body)
+	case "json":
+		costs, err = services.ParseExternalCostsJSON(body)
+	default:
+		c.JSON(http.StatusBadRequest, gin.H{"error": "unsupported format: " + format + " (expected csv or json)"})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if len(costs) == 0 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "no line items found"})
+		return
+	}
+
+	if source == "" {
+		source = "upload-" + time.Now().UTC().Format("20060102T150405Z")
+	}
+
+	if err := s.getExternalCostService().CreateExternalCosts(c.Request.Context(), tenantID, source, costs); err != nil {
+		var rateErr *services.MissingRateError
+		if errors.As(err, &rateErr) {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	var total float64
+	for _, cost := range costs {
+		total += cost.Amount
+	}
+
+	c.JSON(http.StatusCreated, gin.H{
+		"source":       source,
+		"count":        len(costs),
+		"total_amount": total,
+	})
+}
+
+// DELETE /v1/admin/external-costs/:id
+// Delete a single external cost line item
+func (s *Server) deleteExternalCost(c *gin.Context) {
+	tenantID, ok := middleware.GetTenantIDFromContext(c)
+	if !ok {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "no tenant context"})
+		return
+	}
+
+	costID, err := strconv.ParseUint(c.Param("id"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid external cost ID"})
+		return
+	}
+
+	deleted, err := s.getExternalCostService().DeleteExternalCost(c.Request.Context(), tenantID, uint(costID))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	if deleted == 0 {
+		c.JSON(http.StatusNotFound, gin.H{"error": "external cost not found"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "external cost deleted"})
+}
+
+// DELETE /v1/admin/external-costs?source=<upload>
+// Delete all line items of an upload
+func (s *Server) deleteExternalCostSource(c *gin.Context) {
+	tenantID, ok := middleware.GetTenantIDFromContext(c)
+	if !ok {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "no tenant context"})
+		return
+	}
+
+	source := c.Query("source")
+	if source == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "source required"})
+		return
+	}
+
+	deleted, err := s.getExternalCostService().DeleteExternalCostsBySource(c.Request.Context(), tenantID, source)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"message": "external costs deleted",
+		"deleted": deleted,
+	})
+}
+
+// parseExternalCostQueryTime parses an RFC3339 timestamp or a YYYY-MM-DD date
+func parseExternalCostQueryTime(value string) (time.Time, error) {
+	if t, err := time.Parse(time.RFC3339, value); err == nil {
+		return t, nil
+	}
+	return time.Parse("2006-01-02", value)
+}
@@ -54,11 +54,13 @@ type NamespaceCostData struct {
 	EstimatedCostUSD   float64 `json:"estimated_cost_usd"`
 }
 type NodeMetricData struct {
-	NodeName       string  `json:"node_name"`
-	InstanceType   string  `json:"instance_type"`
-	CPUCapacity    int64   `json:"cpu_capacity"`
-	MemoryCapacity int64   `json:"memory_capacity"`
-	HourlyCostUSD  float64 `json:"hourly_cost_usd"`
+	NodeName       string            `json:"node_name"`
+	InstanceType   string            `json:"instance_type"`
+	ProviderID     string            `json:"provider_id"`
+	CPUCapacity    int64             `json:"cpu_capacity"`
+	MemoryCapacity int64             `json:"memory_capacity"`
+	HourlyCostUSD  float64           `json:"hourly_cost_usd"`
+	Labels         map[string]string `json:"labels,omitempty"`
 }
 
 func (s *Server) makeIngestHandler() gin.HandlerFunc {
@@ -112,7 +114,11 @@ func (s *Server) makeIngestHandler() gin.HandlerFunc {
 
 		// insert node metrics
 		for _, nm := range p.NodeMetrics {
-			_ = s.timescaleDB.InsertNodeMetric(ctx, ts, tenantID, p.ClusterName, nm.NodeName, nm.InstanceType, nm.CPUCapacity, nm.MemoryCapacity, nm.HourlyCostUSD)
+			if nm.Labels != nil {
+				_ = s.timescaleDB.InsertNodeMetricWithLabels(ctx, ts, tenantID, p.ClusterName, nm.NodeName, nm.InstanceType, nm.ProviderID, nm.CPUCapacity, nm.MemoryCapacity, nm.HourlyCostUSD, nm.Labels)
+			} else {
+				_ = s.timescaleDB.InsertNodeMetric(ctx, ts, tenantID, p.ClusterName, nm.NodeName, nm.InstanceType, nm.ProviderID, nm.CPUCapacity, nm.MemoryCapacity, nm.HourlyCostUSD)
+			}
 		}
 		// insert individual pod metrics
 		for _, pm := range p.PodMetrics {
@@ -0,0 +1,182 @@
+package api
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+
+	"github.com/bugfreev587/k8s-cost-api-server/internal/middleware"
+	"github.com/bugfreev587/k8s-cost-api-server/internal/models"
+	"github.com/bugfreev587/k8s-cost-api-server/internal/services"
+	"github.com/gin-gonic/gin"
+)
+
+type labelRuleRequest struct {
+	Key               string               `json:"key" binding:"required"`
+	Sources           []string             `json:"sources"`
+	Lowercase         bool                 `json:"lowercase"`
+	Rewrites          models.LabelRewrites `json:"rewrites"`
+	Lookup            models.CostTags      `json:"lookup"`
+	NamespaceDefaults models.CostTags      `json:"namespace_defaults"`
+	Default           string               `json:"default"`
+	Enabled           *bool                `json:"enabled"`
+}
+
+// apply copies the request onto a rule
+func (req *labelRuleRequest) apply(rule *models.VirtualLabelRule) {
+	rule.Key = req.Key
+	rule.Sources = req.Sources
+	rule.Lowercase = req.Lowercase
+	rule.Rewrites = req.Rewrites
+	rule.Lookup = req.Lookup
+	rule.NamespaceDefaults = req.NamespaceDefaults
+	rule.Default = req.Default
+	if req.Enabled != nil {
+		rule.Enabled = *req.Enabled
+	}
+}
+
+// GET /v1/allocation/label-rules
+// List the tenant's virtual label rules
+func (s *Server) listLabelRules(c *gin.Context) {
+	tenantID, ok := middleware.GetTenantIDFromContext(c)
+	if !ok {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "no tenant context"})
+		return
+	}
+
+	rules, err := s.allocSvc.ListVirtualLabelRules(c.Request.Context(), tenantID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"rules": rules,
+		"count": len(rules),
+	})
+}
+
+// POST /v1/admin/allocation/label-rules
+// Create a virtual label rule
+func (s *Server) createLabelRule(c *gin.Context) {
+	tenantID, ok := middleware.GetTenantIDFromContext(c)
+	if !ok {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "no tenant context"})
+		return
+	}
+
+	var req labelRuleRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	rule := &models.VirtualLabelRule{TenantID: tenantID, Enabled: true}
+	req.apply(rule)
+	if !s.validateLabelRule(c, rule) {
+		return
+	}
+
+	if err := s.allocSvc.SaveVirtualLabelRule(c.Request.Context(), rule); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusCreated, gin.H{
+		"rule": rule,
+	})
+}
+
+// PUT /v1/admin/allocation/label-rules/:id
+// Update a virtual label rule
+func (s *Server) updateLabelRule(c *gin.Context) {
+	rule, ok := s.loadTenantLabelRule(c)
+	if !ok {
+		return
+	}
+
+	var req labelRuleRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	req.apply(rule)
+	if !s.validateLabelRule(c, rule) {
+		return
+	}
+
+	if err := s.allocSvc.SaveVirtualLabelRule(c.Request.Context(), rule); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"rule": rule,
+	})
+}
+
+// DELETE /v1/admin/allocation/label-rules/:id
+// Delete a virtual label rule
+func (s *Server) deleteLabelRule(c *gin.Context) {
+	rule, ok := s.loadTenantLabelRule(c)
+	if !ok {
+		return
+	}
+
+	if err := s.allocSvc.DeleteVirtualLabelRule(c.Request.Context(), rule.ID); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "label rule deleted"})
+}
+
+// loadTenantLabelRule loads the virtual label rule named by the :id parameter and checks
+// that it belongs to the caller's tenant, writing the error response when it does not
+func (s *Server) loadTenantLabelRule(c *gin.Context) (*models.VirtualLabelRule, bool) {
+	tenantID, ok := middleware.GetTenantIDFromContext(c)
+	if !ok {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "no tenant context"})
+		return nil, false
+	}
+
+	ruleID, err := strconv.ParseUint(c.Param("id"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid rule ID"})
+		return nil, false
+	}
+
+	rule, err := s.allocSvc.GetVirtualLabelRule(c.Request.Context(), uint(ruleID))
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "label rule not found"})
+		return nil, false
+	}
+
+	if rule.TenantID != tenantID {
+		c.JSON(http.StatusForbidden, gin.H{"error": "access denied"})
+		return nil, false
+	}
+	return rule, true
+}
+
+// validateLabelRule validates a rule before it is saved, compiling its rewrite patterns in
+// the database that evaluates them. It writes the error response and returns false if the
+// rule is invalid.
+func (s *Server) validateLabelRule(c *gin.Context, rule *models.VirtualLabelRule) bool {
+	if err := services.ValidateVirtualLabelRule(rule); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return false
+	}
+	if err := s.allocSvc.CheckRewritePatterns(c.Request.Context(), rule); err != nil {
+		status := http.StatusInternalServerError
+		var patternErr *services.RewritePatternError
+		if errors.As(err, &patternErr) {
+			status = http.StatusBadRequest
+		}
+		c.JSON(status, gin.H{"error": err.Error()})
+		return false
+	}
+	return true
+}
@@ -0,0 +1,213 @@
+package api
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/bugfreev587/k8s-cost-api-server/internal/middleware"
+	"github.com/bugfreev587/k8s-cost-api-server/internal/models"
+	"github.com/bugfreev587/k8s-cost-api-server/internal/services"
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+// nodePricingRequest is the pricing override of a node: its instance type (priced from the
+// cluster config's instance rates) and/or a whole-node hourly cost
+type nodePricingRequest struct {
+	InstanceType       string             `json:"instance_type"`
+	PricingTier        models.PricingTier `json:"pricing_tier"`
+	HourlyCostOverride *float64           `json:"hourly_cost_override"`
+}
+
+// PUT /v1/admin/clusters/:name/nodes/:node/pricing
+// Set the pricing override of a node
+func (s *Server) setNodePricing(c *gin.Context) {
+	tenantID, ok := middleware.GetTenantIDFromContext(c)
+	if !ok {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "no tenant context"})
+		return
+	}
+
+	var req nodePricingRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	np := &models.NodePricing{
+		TenantID:           tenantID,
+		ClusterName:        c.Param("name"),
+		NodeName:           c.Param("node"),
+		InstanceType:       req.InstanceType,
+		PricingTier:        req.PricingTier,
+		HourlyCostOverride: req.HourlyCostOverride,
+	}
+	if err := services.ValidateNodePricing(np); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	if err := s.getPricingServiceFor(c).SetNodePricing(c.Request.Context(), np); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"node_pricing": np,
+	})
+}
+
+// GET /v1/clusters/:name/nodes/:node/pricing
+// Get the pricing override of a node
+func (s *Server) getNodePricing(c *gin.Context) {
+	tenantID, ok := middleware.GetTenantIDFromContext(c)
+	if !ok {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "no tenant context"})
+		return
+	}
+
+	np, err := s.getPricingService().GetNodePricing(c.Request.Context(), tenantID, c.Param("name"), c.Param("node"))
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "no pricing override for node"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"node_pricing": np,
+	})
+}
+
+// DELETE /v1/admin/clusters/:name/nodes/:node/pricing
+// Remove the pricing override of a node; it is then priced from its cluster's config
+func (s *Server) deleteNodePricing(c *gin.Context) {
+	tenantID, ok := middleware.GetTenantIDFromContext(c)
+	if !ok {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "no tenant context"})
+		return
+	}
+
+	err := s.getPricingServiceFor(c).DeleteNodePricing(c.Request.Context(), tenantID, c.Param("name"), c.Param("node"))
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		c.JSON(http.StatusNotFound, gin.H{"error": "no pricing override for node"})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "node pricing override deleted"})
+}
+
+// POST /v1/admin/clusters/:name/nodes/pricing
+// Apply the same pricing override to several nodes of a cluster, listed by name or
+// selected from the nodes reporting metrics in the window by label selectors ("key=value"
+// or "key", all must match) and/or instance type. With dry_run, only the selected nodes
+// are returned.
+//
+// Example request:
+//
+//	POST /v1/admin/clusters/prod/nodes/pricing
+//	{"selector": {"labels": ["karpenter.sh/capacity-type=spot"], "instance_type": "m5.xlarge"},
+//	 "pricing_tier": "spot", "hourly_cost_override": 0.072}
+func (s *Server) bulkSetNodePricing(c *gin.Context) {
+	tenantID, ok := middleware.GetTenantIDFromContext(c)
+	if !ok {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "no tenant context"})
+		return
+	}
+	clusterName := c.Param("name")
+
+	var req struct {
+		nodePricingRequest
+		Nodes    []string               `json:"nodes"`
+		Selector *services.NodeSelector `json:"selector"`
+		Window   string                 `json:"window"` // nodes reporting metrics in this window (default 24h)
+		DryRun   bool                   `json:"dry_run"`
+	}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	override := models.NodePricing{
+		InstanceType:       req.InstanceType,
+		PricingTier:        req.PricingTier,
+		HourlyCostOverride: req.HourlyCostOverride,
+	}
+	if err := services.ValidateNodePricing(&override); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	nodes := req.Nodes
+	switch {
+	case req.Selector != nil && len(req.Nodes) > 0:
+		c.JSON(http.StatusBadRequest, gin.H{"error": "nodes and selector are mutually exclusive"})
+		return
+	case req.Selector != nil:
+		if err := req.Selector.Validate(); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+		allocSvc := s.allocSvc
+		selected, err := allocSvc.SelectNodes(c.Request.Context(), int64(tenantID), clusterName, req.Window, *req.Selector)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return
+		}
+		nodes = selected
+	case len(req.Nodes) == 0:
+		c.JSON(http.StatusBadRequest, gin.H{"error": "nodes or selector required"})
+		return
+	}
+
+	if req.DryRun || len(nodes) == 0 {
+		c.JSON(http.StatusOK, gin.H{
+			"nodes":   nodes,
+			"count":   len(nodes),
+			"dry_run": req.DryRun,
+		})
+		return
+	}
+
+	saved, err := s.getPricingServiceFor(c).SetNodePricingBulk(c.Request.Context(), tenantID, clusterName, nodes, override)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"node_pricing": saved,
+		"count":        len(saved),
+	})
+}
+
+// GET /v1/pricing/nodes
+// List nodes with their resolved hourly cost and where the price came from: the node's
+// override (node_override, node_instance), its instance type or family rates
+// (instance_type, instance_family), or the default rates of the cluster's config
+// (cluster_config, tenant_default, system_default)
+//
+// Query Parameters:
+//   - cluster: Only nodes of this cluster
+//   - window: Nodes reporting metrics in this window (default 24h)
+func (s *Server) listNodePrices(c *gin.Context) {
+	tenantID, ok := middleware.GetTenantIDFromContext(c)
+	if !ok {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "no tenant context"})
+		return
+	}
+
+	allocSvc := s.allocSvc
+	nodes, err := allocSvc.ListNodePrices(c.Request.Context(), int64(tenantID), c.Query("cluster"), c.Query("window"))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"nodes": nodes,
+		"count": len(nodes),
+	})
+}
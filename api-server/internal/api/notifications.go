@@ -0,0 +1,240 @@
+package api
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+
+	"github.com/bugfreev587/k8s-cost-api-server/internal/middleware"
+	"github.com/bugfreev587/k8s-cost-api-server/internal/models"
+	"github.com/bugfreev587/k8s-cost-api-server/internal/services"
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+// redactedSecret replaces secrets in responses; sending it back in an update keeps the
+// stored secret
+const redactedSecret = "********"
+
+// notificationChannelRequest is a notification channel's definition
+type notificationChannelRequest struct {
+	Name    string                           `json:"name" binding:"required"`
+	Type    string                           `json:"type" binding:"required"` // slack, teams, webhook, email
+	Config  models.NotificationChannelConfig `json:"config"`
+	Events  []string                         `json:"events"`  // anomaly, budget, recommendation; empty = all
+	Enabled *bool                            `json:"enabled"` // default true
+}
+
+// GET /v1/admin/notifications/channels
+// List notification channels; secrets are redacted
+func (s *Server) listNotificationChannels(c *gin.Context) {
+	tenantID, ok := middleware.GetTenantIDFromContext(c)
+	if !ok {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "no tenant context"})
+		return
+	}
+
+	channels, err := s.notifySvc.ListChannels(c.Request.Context(), tenantID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	for i := range channels {
+		redactChannel(&channels[i])
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"channels": channels,
+		"count":    len(channels),
+	})
+}
+
+// POST /v1/admin/notifications/channels
+// Create a notification channel
+func (s *Server) createNotificationChannel(c *gin.Context) {
+	tenantID, ok := middleware.GetTenantIDFromContext(c)
+	if !ok {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "no tenant context"})
+		return
+	}
+
+	var req notificationChannelRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	channel := &models.NotificationChannel{TenantID: tenantID, Enabled: true}
+	if !applyNotificationChannelRequest(c, channel, &req) {
+		return
+	}
+	if err := s.notifySvc.SaveChannel(c.Request.Context(), channel); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	redactChannel(channel)
+	c.JSON(http.StatusCreated, gin.H{
+		"channel": channel,
+	})
+}
+
+// PUT /v1/admin/notifications/channels/:id
+// Update a notification channel
+func (s *Server) updateNotificationChannel(c *gin.Context) {
+	channel, ok := s.loadTenantNotificationChannel(c)
+	if !ok {
+		return
+	}
+
+	var req notificationChannelRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if req.Config.Secret == redactedSecret {
+		req.Config.Secret = channel.Config.Secret
+	}
+	if req.Config.Password == redactedSecret {
+		req.Config.Password = channel.Config.Password
+	}
+	if !applyNotificationChannelRequest(c, channel, &req) {
+		return
+	}
+	if err := s.notifySvc.SaveChannel(c.Request.Context(), channel); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	redactChannel(channel)
+	c.JSON(http.StatusOK, gin.H{
+		"channel": channel,
+	})
+}
+
+// DELETE /v1/admin/notifications/channels/:id
+// Delete a notification channel and its delivery log
+func (s *Server) deleteNotificationChannel(c *gin.Context) {
+	channel, ok := s.loadTenantNotificationChannel(c)
+	if !ok {
+		return
+	}
+
+	if err := s.notifySvc.DeleteChannel(c.Request.Context(), channel.ID); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "notification channel deleted"})
+}
+
+// POST /v1/admin/notifications/channels/:id/test
+// Send a test message to a channel (enabled or not) and return its delivery
+func (s *Server) testNotificationChannel(c *gin.Context) {
+	channel, ok := s.loadTenantNotificationChannel(c)
+	if !ok {
+		return
+	}
+
+	delivery := s.notifySvc.SendTest(c.Request.Context(), channel)
+	status := http.StatusOK
+	if delivery.Status == models.DeliveryFailed {
+		status = http.StatusBadGateway
+	}
+	c.JSON(status, gin.H{
+		"delivery": delivery,
+	})
+}
+
+// GET /v1/admin/notifications/deliveries
+// List notification deliveries, newest first
+//
+// Query Parameters:
+//   - channel_id: Only deliveries to this channel
+//   - limit: Maximum number of deliveries (default 100)
+func (s *Server) listNotificationDeliveries(c *gin.Context) {
+	tenantID, ok := middleware.GetTenantIDFromContext(c)
+	if !ok {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "no tenant context"})
+		return
+	}
+
+	var channelID uint64
+	if v := c.Query("channel_id"); v != "" {
+		var err error
+		if channelID, err = strconv.ParseUint(v, 10, 64); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "invalid channel_id: " + v})
+			return
+		}
+	}
+	limit, _ := strconv.Atoi(c.Query("limit"))
+
+	deliveries, err := s.notifySvc.ListDeliveries(c.Request.Context(), tenantID, uint(channelID), limit)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"deliveries": deliveries,
+		"count":      len(deliveries),
+	})
+}
+
+// applyNotificationChannelRequest copies a channel request onto a channel and validates it,
+// writing a 400 response on failure
+func applyNotificationChannelRequest(c *gin.Context, channel *models.NotificationChannel, req *notificationChannelRequest) bool {
+	channel.Name = req.Name
+	channel.Type = req.Type
+	channel.Config = req.Config
+	channel.Events = req.Events
+	if req.Enabled != nil {
+		channel.Enabled = *req.Enabled
+	}
+	if err := services.ValidateChannel(channel); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return false
+	}
+	return true
+}
+
+// redactChannel hides a channel's secrets before it is returned
+func redactChannel(channel *models.NotificationChannel) {
+	if channel.Config.Secret != "" {
+		channel.Config.Secret = redactedSecret
+	}
+	if channel.Config.Password != "" {
+		channel.Config.Password = redactedSecret
+	}
+}
+
+// loadTenantNotificationChannel loads the channel in the :id parameter and verifies tenant
+// ownership, writing the error response on failure
+func (s *Server) loadTenantNotificationChannel(c *gin.Context) (*models.NotificationChannel, bool) {
+	tenantID, ok := middleware.GetTenantIDFromContext(c)
+	if !ok {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "no tenant context"})
+		return nil, false
+	}
+
+	id, err := strconv.ParseUint(c.Param("id"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid channel ID"})
+		return nil, false
+	}
+
+	channel, err := s.notifySvc.GetChannel(c.Request.Context(), uint(id))
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		c.JSON(http.StatusNotFound, gin.H{"error": "notification channel not found"})
+		return nil, false
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return nil, false
+	}
+	if channel.TenantID != tenantID {
+		c.JSON(http.StatusForbidden, gin.H{"error": "access denied"})
+		return nil, false
+	}
+	return channel, true
+}
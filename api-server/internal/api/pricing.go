@@ -1,6 +1,9 @@
 package api
 
 import (
+	"context"
+	"errors"
+	"io"
 	"net/http"
 	"strconv"
 	"time"
@@ -11,9 +14,31 @@ import (
 	"github.com/gin-gonic/gin"
 )
 
-// getPricingService returns a pricing service instance
+// getPricingService returns the shared pricing service
 func (s *Server) getPricingService() *services.PricingService {
-	return services.NewPricingService(s.postgresDB.GetPostgresDB())
+	return s.pricingSvc
+}
+
+// getPricingServiceFor returns a pricing service that records the request's user or API
+// key as the author of pricing changes
+func (s *Server) getPricingServiceFor(c *gin.Context) *services.PricingService {
+	return s.getPricingService().WithActor(requestActor(c))
+}
+
+// requestActor identifies the user or API key making a request
+func requestActor(c *gin.Context) string {
+	if user, ok := middleware.GetUserFromContext(c); ok {
+		if user.Email != "" {
+			return user.Email
+		}
+		return user.ID
+	}
+	if akI, exists := c.Get("api_key"); exists {
+		if ak, ok := akI.(*models.APIKey); ok {
+			return "api-key:" + ak.KeyID
+		}
+	}
+	return ""
 }
 
 // GET /v1/pricing/configs
@@ -47,26 +72,32 @@ func (s *Server) createPricingConfig(c *gin.Context) {
 	}
 
 	var req struct {
-		Name      string               `json:"name" binding:"required"`
-		Provider  models.CloudProvider `json:"provider" binding:"required"`
-		Region    string               `json:"region"`
-		IsDefault bool                 `json:"is_default"`
+		Name            string               `json:"name" binding:"required"`
+		Provider        models.CloudProvider `json:"provider" binding:"required"`
+		Region          string               `json:"region"`
+		IsDefault       bool                 `json:"is_default"`
+		CPURAMCostRatio *float64             `json:"cpu_ram_cost_ratio"`
 	}
 
 	if err := c.ShouldBindJSON(&req); err != nil {
 		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
 		return
 	}
+	if req.CPURAMCostRatio != nil && *req.CPURAMCostRatio <= 0 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "cpu_ram_cost_ratio must be positive"})
+		return
+	}
 
 	config := &models.PricingConfig{
-		TenantID:  tenantID,
-		Name:      req.Name,
-		Provider:  req.Provider,
-		Region:    req.Region,
-		IsDefault: req.IsDefault,
+		TenantID:        tenantID,
+		Name:            req.Name,
+		Provider:        req.Provider,
+		Region:          req.Region,
+		IsDefault:       req.IsDefault,
+		CPURAMCostRatio: req.CPURAMCostRatio,
 	}
 
-	pricingSvc := s.getPricingService()
+	pricingSvc := s.getPricingServiceFor(c)
 	if err := pricingSvc.CreateConfig(c.Request.Context(), config); err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
 		return
@@ -125,7 +156,7 @@ func (s *Server) updatePricingConfig(c *gin.Context) {
 		return
 	}
 
-	pricingSvc := s.getPricingService()
+	pricingSvc := s.getPricingServiceFor(c)
 
 	// Get existing config
 	config, err := pricingSvc.GetConfig(c.Request.Context(), uint(configID))
@@ -141,10 +172,11 @@ func (s *Server) updatePricingConfig(c *gin.Context) {
 	}
 
 	var req struct {
-		Name      string               `json:"name"`
-		Provider  models.CloudProvider `json:"provider"`
-		Region    string               `json:"region"`
-		IsDefault *bool                `json:"is_default"`
+		Name            string               `json:"name"`
+		Provider        models.CloudProvider `json:"provider"`
+		Region          string               `json:"region"`
+		IsDefault       *bool                `json:"is_default"`
+		CPURAMCostRatio *float64             `json:"cpu_ram_cost_ratio"` // 0 = derive from the rates
 	}
 
 	if err := c.ShouldBindJSON(&req); err != nil {
@@ -165,6 +197,17 @@ func (s *Server) updatePricingConfig(c *gin.Context) {
 	if req.IsDefault != nil {
 		config.IsDefault = *req.IsDefault
 	}
+	if req.CPURAMCostRatio != nil {
+		switch {
+		case *req.CPURAMCostRatio < 0:
+			c.JSON(http.StatusBadRequest, gin.H{"error": "cpu_ram_cost_ratio must not be negative"})
+			return
+		case *req.CPURAMCostRatio == 0:
+			config.CPURAMCostRatio = nil
+		default:
+			config.CPURAMCostRatio = req.CPURAMCostRatio
+		}
+	}
 
 	if err := pricingSvc.UpdateConfig(c.Request.Context(), config); err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
@@ -191,7 +234,7 @@ func (s *Server) deletePricingConfig(c *gin.Context) {
 		return
 	}
 
-	pricingSvc := s.getPricingService()
+	pricingSvc := s.getPricingServiceFor(c)
 
 	// Get config to verify ownership
 	config, err := pricingSvc.GetConfig(c.Request.Context(), uint(configID))
@@ -215,6 +258,12 @@ func (s *Server) deletePricingConfig(c *gin.Context) {
 
 // POST /v1/pricing/configs/:id/rates
 // Add a pricing rate to a configuration
+//
+// The unit must match the resource type (cpu: core-hour, memory: gb-hour, gpu: gpu-hour,
+// storage: gb-month, network: gb) and defaults to it. A rate whose effective range
+// overlaps an existing rate of the same resource, tier and instance family is rejected
+// with 409, unless "supersede" is set: the existing rates are then end-dated, moved or
+// removed so the new rate alone covers its range.
 func (s *Server) addPricingRate(c *gin.Context) {
 	tenantID, ok := middleware.GetTenantIDFromContext(c)
 	if !ok {
@@ -228,7 +277,7 @@ func (s *Server) addPricingRate(c *gin.Context) {
 		return
 	}
 
-	pricingSvc := s.getPricingService()
+	pricingSvc := s.getPricingServiceFor(c)
 
 	// Verify config ownership
 	config, err := pricingSvc.GetConfig(c.Request.Context(), uint(configID))
@@ -245,10 +294,11 @@ func (s *Server) addPricingRate(c *gin.Context) {
 		ResourceType   models.ResourceType `json:"resource_type" binding:"required"`
 		PricingTier    models.PricingTier  `json:"pricing_tier"`
 		InstanceFamily string              `json:"instance_family"`
-		Unit           string              `json:"unit" binding:"required"`
+		Unit           string              `json:"unit"`
 		CostPerUnit    float64             `json:"cost_per_unit" binding:"required"`
 		EffectiveFrom  *time.Time          `json:"effective_from"`
 		EffectiveTo    *time.Time          `json:"effective_to"`
+		Supersede      bool                `json:"supersede"`
 	}
 
 	if err := c.ShouldBindJSON(&req); err != nil {
@@ -275,9 +325,13 @@ func (s *Server) addPricingRate(c *gin.Context) {
 	if rate.PricingTier == "" {
 		rate.PricingTier = models.TierOnDemand
 	}
+	if err := services.ValidatePricingRate(rate); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
 
-	if err := pricingSvc.AddRate(c.Request.Context(), rate); err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+	if err := pricingSvc.AddRate(c.Request.Context(), rate, req.Supersede); err != nil {
+		respondRateError(c, err)
 		return
 	}
 
@@ -287,7 +341,7 @@ func (s *Server) addPricingRate(c *gin.Context) {
 }
 
 // PUT /v1/pricing/rates/:id
-// Update a pricing rate
+// Update a pricing rate; overlapping effective ranges are handled as when adding a rate
 func (s *Server) updatePricingRate(c *gin.Context) {
 	tenantID, ok := middleware.GetTenantIDFromContext(c)
 	if !ok {
@@ -301,7 +355,7 @@ func (s *Server) updatePricingRate(c *gin.Context) {
 		return
 	}
 
-	pricingSvc := s.getPricingService()
+	pricingSvc := s.getPricingServiceFor(c)
 
 	// Get rate and verify ownership
 	var rate models.PricingRate
@@ -320,6 +374,7 @@ func (s *Server) updatePricingRate(c *gin.Context) {
 		CostPerUnit   *float64   `json:"cost_per_unit"`
 		EffectiveFrom *time.Time `json:"effective_from"`
 		EffectiveTo   *time.Time `json:"effective_to"`
+		Supersede     bool       `json:"supersede"`
 	}
 
 	if err := c.ShouldBindJSON(&req); err != nil {
@@ -336,9 +391,13 @@ func (s *Server) updatePricingRate(c *gin.Context) {
 	if req.EffectiveTo != nil {
 		rate.EffectiveTo = req.EffectiveTo
 	}
+	if err := services.ValidatePricingRate(&rate); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
 
-	if err := pricingSvc.UpdateRate(c.Request.Context(), &rate); err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+	if err := pricingSvc.UpdateRate(c.Request.Context(), &rate, req.Supersede); err != nil {
+		respondRateError(c, err)
 		return
 	}
 
@@ -347,6 +406,72 @@ func (s *Server) updatePricingRate(c *gin.Context) {
 	})
 }
 
+// respondRateError writes the response for a failed rate save: 409 with the conflicting
+// rates for an overlap, 500 otherwise
+func respondRateError(c *gin.Context, err error) {
+	var overlap *services.RateOverlapError
+	if errors.As(err, &overlap) {
+		c.JSON(http.StatusConflict, gin.H{
+			"error":     err.Error(),
+			"conflicts": overlap.Conflicts,
+		})
+		return
+	}
+	c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+}
+
+// GET /v1/pricing/configs/:id/validate
+// Check a configuration's rates (units, costs, date ranges, overlaps) and show which rate
+// wins for each resource/tier/family over time. Overlapping rates resolve to the one with
+// the latest effective_from, then the most recently created.
+//
+// Query Parameters:
+//   - at: Only show the winning rates on this date (YYYY-MM-DD)
+func (s *Server) validatePricingConfig(c *gin.Context) {
+	tenantID, ok := middleware.GetTenantIDFromContext(c)
+	if !ok {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "no tenant context"})
+		return
+	}
+
+	configID, err := strconv.ParseUint(c.Param("id"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid config ID"})
+		return
+	}
+
+	var at *time.Time
+	if v := c.Query("at"); v != "" {
+		t, err := parseExternalCostQueryTime(v)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "invalid at: " + v})
+			return
+		}
+		at = &t
+	}
+
+	pricingSvc := s.getPricingService()
+	config, err := pricingSvc.GetConfig(c.Request.Context(), uint(configID))
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "config not found"})
+		return
+	}
+	if config.TenantID != tenantID {
+		c.JSON(http.StatusForbidden, gin.H{"error": "access denied"})
+		return
+	}
+
+	report, err := pricingSvc.ValidateConfig(c.Request.Context(), config.ID, at)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"report": report,
+	})
+}
+
 // DELETE /v1/pricing/rates/:id
 // Delete a pricing rate
 func (s *Server) deletePricingRate(c *gin.Context) {
@@ -362,7 +487,7 @@ func (s *Server) deletePricingRate(c *gin.Context) {
 		return
 	}
 
-	pricingSvc := s.getPricingService()
+	pricingSvc := s.getPricingServiceFor(c)
 
 	// Get rate and verify ownership
 	var rate models.PricingRate
@@ -385,6 +510,157 @@ func (s *Server) deletePricingRate(c *gin.Context) {
 	c.JSON(http.StatusOK, gin.H{"message": "rate deleted"})
 }
 
+// pricingDiscountRequest is the body for creating or updating a negotiated discount
+type pricingDiscountRequest struct {
+	Name            string               `json:"name" binding:"required"`
+	Scope           models.DiscountScope `json:"scope" binding:"required"`
+	ResourceType    models.ResourceType  `json:"resource_type"`
+	InstanceFamily  string               `json:"instance_family"`
+	DiscountPercent float64              `json:"discount_percent" binding:"required"`
+	EffectiveFrom   *time.Time           `json:"effective_from"`
+	EffectiveTo     *time.Time           `json:"effective_to"`
+}
+
+// apply copies the request onto a discount and validates it
+func (req *pricingDiscountRequest) apply(discount *models.PricingDiscount) error {
+	discount.Name = req.Name
+	discount.Scope = req.Scope
+	discount.ResourceType = req.ResourceType
+	discount.InstanceFamily = req.InstanceFamily
+	discount.DiscountPercent = req.DiscountPercent
+	discount.EffectiveTo = req.EffectiveTo
+	if req.EffectiveFrom != nil {
+		discount.EffectiveFrom = *req.EffectiveFrom
+	} else if discount.EffectiveFrom.IsZero() {
+		discount.EffectiveFrom = time.Now()
+	}
+	return services.ValidatePricingDiscount(discount)
+}
+
+// POST /v1/pricing/configs/:id/discounts
+// Add a negotiated discount (EDP, private pricing) to a configuration
+func (s *Server) addPricingDiscount(c *gin.Context) {
+	tenantID, ok := middleware.GetTenantIDFromContext(c)
+	if !ok {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "no tenant context"})
+		return
+	}
+
+	configID, err := strconv.ParseUint(c.Param("id"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid config ID"})
+		return
+	}
+
+	pricingSvc := s.getPricingServiceFor(c)
+
+	// Verify config ownership
+	config, err := pricingSvc.GetConfig(c.Request.Context(), uint(configID))
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "config not found"})
+		return
+	}
+	if config.TenantID != tenantID {
+		c.JSON(http.StatusForbidden, gin.H{"error": "access denied"})
+		return
+	}
+
+	var req pricingDiscountRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	discount := &models.PricingDiscount{ConfigID: uint(configID)}
+	if err := req.apply(discount); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	if err := pricingSvc.SaveDiscount(c.Request.Context(), discount); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusCreated, gin.H{
+		"discount": discount,
+	})
+}
+
+// PUT /v1/pricing/discounts/:id
+// Update a negotiated discount
+func (s *Server) updatePricingDiscount(c *gin.Context) {
+	discount, ok := s.loadTenantPricingDiscount(c)
+	if !ok {
+		return
+	}
+
+	var req pricingDiscountRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if err := req.apply(discount); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	if err := s.getPricingServiceFor(c).SaveDiscount(c.Request.Context(), discount); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"discount": discount,
+	})
+}
+
+// DELETE /v1/pricing/discounts/:id
+// Delete a negotiated discount
+func (s *Server) deletePricingDiscount(c *gin.Context) {
+	discount, ok := s.loadTenantPricingDiscount(c)
+	if !ok {
+		return
+	}
+
+	if err := s.getPricingServiceFor(c).DeleteDiscount(c.Request.Context(), discount.ID); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "discount deleted"})
+}
+
+// loadTenantPricingDiscount loads the discount in the :id parameter and verifies that its
+// config belongs to the tenant, writing the error response otherwise
+func (s *Server) loadTenantPricingDiscount(c *gin.Context) (*models.PricingDiscount, bool) {
+	tenantID, ok := middleware.GetTenantIDFromContext(c)
+	if !ok {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "no tenant context"})
+		return nil, false
+	}
+
+	discountID, err := strconv.ParseUint(c.Param("id"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid discount ID"})
+		return nil, false
+	}
+
+	pricingSvc := s.getPricingService()
+	discount, err := pricingSvc.GetDiscount(c.Request.Context(), uint(discountID))
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "discount not found"})
+		return nil, false
+	}
+
+	config, err := pricingSvc.GetConfig(c.Request.Context(), discount.ConfigID)
+	if err != nil || config.TenantID != tenantID {
+		c.JSON(http.StatusForbidden, gin.H{"error": "access denied"})
+		return nil, false
+	}
+	return discount, true
+}
+
 // PUT /v1/clusters/:name/pricing
 // Assign a pricing config to a cluster
 func (s *Server) setClusterPricing(c *gin.Context) {
@@ -409,7 +685,7 @@ func (s *Server) setClusterPricing(c *gin.Context) {
 		return
 	}
 
-	pricingSvc := s.getPricingService()
+	pricingSvc := s.getPricingServiceFor(c)
 
 	// Verify config ownership
 	config, err := pricingSvc.GetConfig(c.Request.Context(), req.ConfigID)
@@ -507,7 +783,7 @@ func (s *Server) deleteClusterPricing(c *gin.Context) {
 		return
 	}
 
-	pricingSvc := s.getPricingService()
+	pricingSvc := s.getPricingServiceFor(c)
 	if err := pricingSvc.DeleteClusterPricing(c.Request.Context(), tenantID, clusterName); err != nil {
 		c.JSON(http.StatusNotFound, gin.H{"error": "cluster pricing not found"})
 		return
@@ -544,8 +820,24 @@ func (s *Server) getPricingPresets(c *gin.Context) {
 	})
 }
 
+// maxPriceListUpload limits the size of an uploaded provider price list
+const maxPriceListUpload = 2 << 30
+
+// importPricingRequest holds the import options, sent as JSON or as multipart form fields
+// alongside an uploaded price list ("file")
+type importPricingRequest struct {
+	Name      string `json:"name" form:"name"`
+	Region    string `json:"region" form:"region"`
+	Tier      string `json:"tier" form:"tier"`
+	IsDefault bool   `json:"is_default" form:"is_default"`
+	ConfigID  uint   `json:"config_id" form:"config_id"`
+}
+
 // POST /v1/pricing/import/:provider
-// Import default pricing for a provider
+// Import per-instance-family rates from an uploaded provider price list (AWS Price List
+// offer file, GCP Cloud Billing Catalog SKUs or Azure Retail Prices JSON), or from the
+// bundled offline snapshot when no file is uploaded. Rates are added to config_id, or to
+// a new config seeded with the provider's default rates.
 func (s *Server) importProviderPricing(c *gin.Context) {
 	tenantID, ok := middleware.GetTenantIDFromContext(c)
 	if !ok {
@@ -559,95 +851,161 @@ func (s *Server) importProviderPricing(c *gin.Context) {
 		return
 	}
 
-	var req struct {
-		Name      string `json:"name" binding:"required"`
-		Region    string `json:"region"`
-		IsDefault bool   `json:"is_default"`
+	var req importPricingRequest
+	file, header, fileErr := c.Request.FormFile("file")
+	if fileErr == nil {
+		defer file.Close()
+		if err := c.ShouldBind(&req); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+	} else if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
 	}
 
-	if err := c.ShouldBindJSON(&req); err != nil {
+	tier := models.TierOnDemand
+	if req.Tier != "" {
+		tier = models.PricingTier(req.Tier)
+	}
+
+	pricingSvc := s.getPricingServiceFor(c)
+	ctx := c.Request.Context()
+
+	var config *models.PricingConfig
+	if req.ConfigID != 0 {
+		existing, err := pricingSvc.GetConfig(ctx, req.ConfigID)
+		if err != nil {
+			c.JSON(http.StatusNotFound, gin.H{"error": "config not found"})
+			return
+		}
+		if existing.TenantID != tenantID {
+			c.JSON(http.StatusForbidden, gin.H{"error": "access denied"})
+			return
+		}
+		if existing.Provider != provider {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "config " + existing.Name + " is for provider " + string(existing.Provider)})
+			return
+		}
+		config = existing
+		if req.Region == "" {
+			req.Region = config.Region
+		}
+	} else if req.Name == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "name required"})
+		return
+	}
+
+	// Parse the price list before creating anything
+	var entries []services.CatalogEntry
+	var err error
+	source := "snapshot"
+	capturedAt := ""
+	if fileErr == nil {
+		if req.Region == "" {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "region required"})
+			return
+		}
+		source = header.Filename
+		entries, err = services.ParsePriceList(provider, io.LimitReader(file, maxPriceListUpload), req.Region, tier)
+	} else {
+		info, infoErr := services.GetSnapshotCatalogInfo(provider)
+		if infoErr != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": infoErr.Error()})
+			return
+		}
+		if req.Region == "" && len(info.Regions) > 0 {
+			req.Region = info.Regions[0]
+		}
+		capturedAt = info.CapturedAt
+		entries, err = services.SnapshotCatalogEntries(provider, req.Region, tier)
+	}
+	if err != nil {
 		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
 		return
 	}
 
-	pricingSvc := s.getPricingService()
+	now := time.Now()
+	effectiveFrom := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, time.UTC)
+	familyRates := services.CatalogRates(provider, entries, tier, effectiveFrom)
 
-	// Create config
-	config := &models.PricingConfig{
-		TenantID:  tenantID,
-		Name:      req.Name,
-		Provider:  provider,
-		Region:    req.Region,
-		IsDefault: req.IsDefault,
+	status := http.StatusOK
+	if config == nil {
+		config = &models.PricingConfig{
+			TenantID:  tenantID,
+			Name:      req.Name,
+			Provider:  provider,
+			Region:    req.Region,
+			IsDefault: req.IsDefault,
+		}
+		if err := pricingSvc.CreateConfig(ctx, config); err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return
+		}
+		addPresetRates(ctx, pricingSvc, config.ID, provider)
+		status = http.StatusCreated
 	}
 
-	if err := pricingSvc.CreateConfig(c.Request.Context(), config); err != nil {
+	if err := pricingSvc.ImportCatalogRates(ctx, config.ID, tier, familyRates); err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
 		return
 	}
 
-	// Add default rates from presets
-	presets := pricingSvc.GetProviderPresets(provider)
-	var rates []models.PricingRate
-
-	// Add CPU rates
-	if cpuOnDemand, ok := presets["cpu_on_demand"]; ok {
-		rate := models.PricingRate{
-			ConfigID:      config.ID,
-			ResourceType:  models.ResourceCPU,
-			PricingTier:   models.TierOnDemand,
-			Unit:          "core-hour",
-			CostPerUnit:   cpuOnDemand,
-			EffectiveFrom: time.Now(),
-		}
-		pricingSvc.AddRate(c.Request.Context(), &rate)
-		rates = append(rates, rate)
+	if updated, err := pricingSvc.GetConfig(ctx, config.ID); err == nil {
+		config = updated
 	}
 
-	if cpuSpot, ok := presets["cpu_spot"]; ok {
-		rate := models.PricingRate{
-			ConfigID:      config.ID,
-			ResourceType:  models.ResourceCPU,
-			PricingTier:   models.TierSpot,
-			Unit:          "core-hour",
-			CostPerUnit:   cpuSpot,
-			EffectiveFrom: time.Now(),
+	c.JSON(status, gin.H{
+		"config":         config,
+		"region":         req.Region,
+		"tier":           tier,
+		"source":         source,
+		"captured_at":    capturedAt,
+		"instance_types": len(entries),
+		"rates_imported": len(familyRates),
+		"message":        "imported " + string(tier) + " pricing for " + string(provider),
+	})
+}
+
+// addPresetRates seeds a new config with the provider's default generic rates
+func addPresetRates(ctx context.Context, pricingSvc *services.PricingService, configID uint, provider models.CloudProvider) {
+	presets := pricingSvc.GetProviderPresets(provider)
+	for _, preset := range []struct {
+		key      string
+		resource models.ResourceType
+		tier     models.PricingTier
+		unit     string
+	}{
+		{"cpu_on_demand", models.ResourceCPU, models.TierOnDemand, "core-hour"},
+		{"cpu_spot", models.ResourceCPU, models.TierSpot, "core-hour"},
+		{"memory_on_demand", models.ResourceMemory, models.TierOnDemand, "gb-hour"},
+		{"memory_spot", models.ResourceMemory, models.TierSpot, "gb-hour"},
+	} {
+		cost, ok := presets[preset.key]
+		if !ok {
+			continue
 		}
-		pricingSvc.AddRate(c.Request.Context(), &rate)
-		rates = append(rates, rate)
-	}
-
-	// Add Memory rates
-	if memOnDemand, ok := presets["memory_on_demand"]; ok {
-		rate := models.PricingRate{
-			ConfigID:      config.ID,
-			ResourceType:  models.ResourceMemory,
-			PricingTier:   models.TierOnDemand,
-			Unit:          "gb-hour",
-			CostPerUnit:   memOnDemand,
+		pricingSvc.AddRate(ctx, &models.PricingRate{
+			ConfigID:      configID,
+			ResourceType:  preset.resource,
+			PricingTier:   preset.tier,
+			Unit:          preset.unit,
+			CostPerUnit:   cost,
 			EffectiveFrom: time.Now(),
-		}
-		pricingSvc.AddRate(c.Request.Context(), &rate)
-		rates = append(rates, rate)
+		}, false)
 	}
+}
 
-	if memSpot, ok := presets["memory_spot"]; ok {
-		rate := models.PricingRate{
-			ConfigID:      config.ID,
-			ResourceType:  models.ResourceMemory,
-			PricingTier:   models.TierSpot,
-			Unit:          "gb-hour",
-			CostPerUnit:   memSpot,
-			EffectiveFrom: time.Now(),
-		}
-		pricingSvc.AddRate(c.Request.Context(), &rate)
-		rates = append(rates, rate)
+// GET /v1/pricing/catalogs/:provider
+// Describe the bundled offline price list snapshot of a provider
+func (s *Server) getPricingCatalog(c *gin.Context) {
+	info, err := services.GetSnapshotCatalogInfo(models.CloudProvider(c.Param("provider")))
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+		return
 	}
 
-	config.Rates = rates
-
-	c.JSON(http.StatusCreated, gin.H{
-		"config": config,
-		"message": "imported default pricing for " + string(provider),
+	c.JSON(http.StatusOK, gin.H{
+		"catalog": info,
 	})
 }
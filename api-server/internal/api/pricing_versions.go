@@ -0,0 +1,317 @@
+package api
+
+import (
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/bugfreev587/k8s-cost-api-server/internal/middleware"
+	"github.com/bugfreev587/k8s-cost-api-server/internal/models"
+	"github.com/bugfreev587/k8s-cost-api-server/internal/services"
+	"github.com/gin-gonic/gin"
+)
+
+// GET /v1/pricing/configs/:id/versions
+// List the recorded versions of a pricing configuration, newest first
+func (s *Server) listPricingVersions(c *gin.Context) {
+	tenantID, ok := middleware.GetTenantIDFromContext(c)
+	if !ok {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "no tenant context"})
+		return
+	}
+
+	configID, err := strconv.ParseUint(c.Param("id"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid config ID"})
+		return
+	}
+
+	pricingSvc := s.getPricingService()
+	versions, err := pricingSvc.ListVersions(c.Request.Context(), uint(configID))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	// Versions outlive deleted configs, so ownership is checked on the versions themselves
+	if len(versions) > 0 && versions[0].TenantID != tenantID {
+		c.JSON(http.StatusForbidden, gin.H{"error": "access denied"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"versions": versions,
+		"count":    len(versions),
+	})
+}
+
+// GET /v1/pricing/versions/:id
+// Get a pricing version with the snapshot of its config
+func (s *Server) getPricingVersion(c *gin.Context) {
+	version, ok := s.loadTenantPricingVersion(c, c.Param("id"))
+	if !ok {
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"version": version,
+	})
+}
+
+// GET /v1/pricing/audit
+// Audit trail of pricing changes: who changed which config, rate or discount, and when
+//
+// Query Parameters:
+//   - config_id: Only changes to this config
+//   - since: Only changes at or after this time (RFC3339 or YYYY-MM-DD)
+//   - limit: Maximum number of entries (default 100)
+func (s *Server) listPricingAuditLog(c *gin.Context) {
+	tenantID, ok := middleware.GetTenantIDFromContext(c)
+	if !ok {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "no tenant context"})
+		return
+	}
+
+	var configID uint64
+	if v := c.Query("config_id"); v != "" {
+		id, err := strconv.ParseUint(v, 10, 64)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "invalid config_id"})
+			return
+		}
+		configID = id
+	}
+	var since time.Time
+	if v := c.Query("since"); v != "" {
+		t, err := parseExternalCostQueryTime(v)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "invalid since: " + v})
+			return
+		}
+		since = t
+	}
+	limit, _ := strconv.Atoi(c.Query("limit"))
+
+	entries, err := s.getPricingService().ListAuditLog(c.Request.Context(), tenantID, uint(configID), since, limit)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"entries": entries,
+		"count":   len(entries),
+	})
+}
+
+// loadTenantPricingVersion loads a pricing version by ID and verifies tenant ownership,
+// writing the error response on failure
+func (s *Server) loadTenantPricingVersion(c *gin.Context, id string) (*models.PricingVersion, bool) {
+	tenantID, ok := middleware.GetTenantIDFromContext(c)
+	if !ok {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "no tenant context"})
+		return nil, false
+	}
+
+	versionID, err := strconv.ParseUint(id, 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid version ID"})
+		return nil, false
+	}
+
+	version, err := s.getPricingService().GetVersion(c.Request.Context(), uint(versionID))
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "version not found"})
+		return nil, false
+	}
+	if version.TenantID != tenantID {
+		c.JSON(http.StatusForbidden, gin.H{"error": "access denied"})
+		return nil, false
+	}
+	return version, true
+}
+
+// resolvePricingAsOf returns the time allocation pricing is pinned to: pricingAsOf (RFC3339,
+// or YYYY-MM-DD for the end of that day) or the creation time of pricingVersion. It
+// returns nil when neither is given.
+func (s *Server) resolvePricingAsOf(c *gin.Context, tenantID uint, pricingAsOf, pricingVersion string) (*time.Time, error) {
+	if pricingVersion != "" {
+		versionID, err := strconv.ParseUint(pricingVersion, 10, 64)
+		if err != nil {
+			return nil, fmt.Errorf("invalid pricingVersion: %s", pricingVersion)
+		}
+		version, err := s.getPricingService().GetVersion(c.Request.Context(), uint(versionID))
+		if err != nil || version.TenantID != tenantID {
+			return nil, fmt.Errorf("pricing version %s not found", pricingVersion)
+		}
+		return &version.CreatedAt, nil
+	}
+	if pricingAsOf == "" {
+		return nil, nil
+	}
+	if t, err := time.Parse(time.RFC3339, pricingAsOf); err == nil {
+		return &t, nil
+	}
+	day, err := time.Parse("2006-01-02", pricingAsOf)
+	if err != nil {
+		return nil, fmt.Errorf("invalid pricingAsOf: %s (expected RFC3339 or YYYY-MM-DD)", pricingAsOf)
+	}
+	endOfDay := day.AddDate(0, 0, 1).Add(-time.Nanosecond)
+	return &endOfDay, nil
+}
+
+// parsePricingPin reads the pricingAsOf / pricingVersion query parameters into params,
+// writing a 400 response on failure
+func (s *Server) parsePricingPin(c *gin.Context, tenantID uint, params *services.AllocationParams) bool {
+	asOf, err := s.resolvePricingAsOf(c, tenantID, c.Query("pricingAsOf"), c.Query("pricingVersion"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"code":    400,
+			"status":  "error",
+			"message": err.Error(),
+		})
+		return false
+	}
+	params.PricingAsOf = asOf
+	return true
+}
+
+// whatIfRequest is the body of a what-if repricing request: the window and aggregation to
+// recompute, and the proposed pricing as an existing config or an inline one
+type whatIfRequest struct {
+	Window           string                `json:"window"`
+	Aggregate        string                `json:"aggregate"`
+	Filters          []string              `json:"filter"`
+	Idle             bool                  `json:"idle"`
+	ShareIdle        string                `json:"shareIdle"`
+	IdleBy           string                `json:"idleBy"`
+	Reconcile        bool                  `json:"reconcile"`
+	ApplyCommitments *bool                 `json:"applyCommitments"`
+	Clusters         []string              `json:"clusters"`
+	PricingAsOf      string                `json:"pricingAsOf"`
+	PricingVersion   string                `json:"pricingVersion"`
+	ConfigID         uint                  `json:"configId"`
+	Config           *models.PricingConfig `json:"config"`
+}
+
+// POST /v1/allocation/whatif
+// Recompute a past window under a proposed pricing config and return the cost difference
+// per allocation (default aggregation: cluster,namespace).
+//
+// The proposed pricing is an existing config ("configId") or an inline config ("config":
+// provider, cpu_ram_cost_ratio, rates, discounts) and replaces the assigned config of the
+// listed "clusters" (default: all clusters). The baseline uses the current pricing, or the
+// pricing as of "pricingAsOf" / "pricingVersion". Billing reconciliation is off by default
+// since reconciled nodes keep their billed cost under any pricing.
+//
+// Example request:
+//
+//	POST /v1/allocation/whatif
+//	{"window": "lastmonth", "clusters": ["prod"], "config": {"provider": "aws",
+//	  "rates": [{"resource_type": "cpu", "unit": "core-hour", "cost_per_unit": 0.03}]}}
+func (s *Server) whatIfAllocation(c *gin.Context) {
+	tenantID, ok := middleware.GetTenantIDFromContext(c)
+	if !ok {
+		c.JSON(http.StatusUnauthorized, gin.H{
+			"code":    401,
+			"status":  "error",
+			"message": "no tenant context",
+		})
+		return
+	}
+	badRequest := func(message string) {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"code":    400,
+			"status":  "error",
+			"message": message,
+		})
+	}
+
+	var req whatIfRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		badRequest(err.Error())
+		return
+	}
+
+	proposed := req.Config
+	if req.ConfigID != 0 {
+		config, err := s.getPricingService().GetConfig(c.Request.Context(), req.ConfigID)
+		if err != nil || config.TenantID != tenantID {
+			badRequest(fmt.Sprintf("pricing config %d not found", req.ConfigID))
+			return
+		}
+		proposed = config
+	}
+	if proposed == nil {
+		badRequest("configId or config required")
+		return
+	}
+	if proposed.Provider == "" {
+		proposed.Provider = models.ProviderCustom
+	}
+	for i := range proposed.Rates {
+		if proposed.Rates[i].PricingTier == "" {
+			proposed.Rates[i].PricingTier = models.TierOnDemand
+		}
+		if req.ConfigID != 0 {
+			continue // stored rates are reported by /pricing/configs/:id/validate
+		}
+		if err := services.ValidatePricingRate(&proposed.Rates[i]); err != nil {
+			badRequest(err.Error())
+			return
+		}
+	}
+	for i := range proposed.Discounts {
+		if err := services.ValidatePricingDiscount(&proposed.Discounts[i]); err != nil {
+			badRequest(err.Error())
+			return
+		}
+	}
+
+	params := services.AllocationParams{
+		Window:           req.Window,
+		Aggregate:        req.Aggregate,
+		Filters:          req.Filters,
+		Idle:             req.Idle,
+		ShareIdle:        req.ShareIdle,
+		IdleBy:           req.IdleBy,
+		Reconcile:        req.Reconcile,
+		ApplyCommitments: req.ApplyCommitments == nil || *req.ApplyCommitments,
+	}
+	if params.Window == "" {
+		params.Window = "7d"
+	}
+	if params.Aggregate == "" {
+		params.Aggregate = "cluster,namespace"
+	}
+	parseSharingParams(c, &params)
+
+	currency, err := s.resolveRequestCurrency(c, tenantID)
+	if err != nil {
+		badRequest(err.Error())
+		return
+	}
+	params.Currency = currency
+	if params.PricingAsOf, err = s.resolvePricingAsOf(c, tenantID, req.PricingAsOf, req.PricingVersion); err != nil {
+		badRequest(err.Error())
+		return
+	}
+
+	allocSvc := s.allocSvc
+	result, err := allocSvc.WhatIf(c.Request.Context(), int64(tenantID), params, proposed, req.Clusters)
+	if err != nil {
+		status := allocationErrorStatus(err)
+		c.JSON(status, gin.H{
+			"code":    status,
+			"status":  "error",
+			"message": err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"code":   200,
+		"status": "success",
+		"data":   result,
+	})
+}
@@ -4,6 +4,7 @@ import (
 	"net/http"
 	"strconv"
 	"strings"
+	"time"
 
 	"github.com/bugfreev587/k8s-cost-api-server/internal/middleware"
 	"github.com/bugfreev587/k8s-cost-api-server/internal/models"
@@ -13,6 +14,8 @@ import (
 )
 
 // GET /v1/recommendations
+// estimated_savings is reported in the "currency" query parameter (default: the tenant's
+// display currency) at the current exchange rate, as savings are forward-looking
 func (s *Server) getRecommendations(c *gin.Context) {
 	tenantID, ok := middleware.GetTenantIDFromContext(c)
 	if !ok {
@@ -20,6 +23,12 @@ func (s *Server) getRecommendations(c *gin.Context) {
 		return
 	}
 
+	now := time.Now()
+	converter, ok := s.currencyConverter(c, tenantID, now, now)
+	if !ok {
+		return
+	}
+
 	var recs []models.Recommendation
 	if err := s.postgresDB.GetPostgresDB().Where("tenant_id = ?", tenantID).Find(&recs).Error; err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
@@ -50,7 +59,7 @@ func (s *Server) getRecommendations(c *gin.Context) {
 			Namespace:        r.Namespace,
 			Cluster:          r.ClusterName,
 			Reason:           r.Reason,
-			EstimatedSavings: r.PotentialSavingsUSD,
+			EstimatedSavings: converter.Convert(r.PotentialSavingsUSD, now),
 			Status:           r.Status,
 			CreatedAt:        r.CreatedAt.Format("2006-01-02T15:04:05Z"),
 		}
@@ -64,7 +73,7 @@ func (s *Server) getRecommendations(c *gin.Context) {
 		result = append(result, resp)
 	}
 
-	c.JSON(http.StatusOK, gin.H{"recommendations": result})
+	c.JSON(http.StatusOK, gin.H{"recommendations": result, "currency": converter.Currency})
 }
 
 // POST /v1/recommendations/generate - Generate right-sizing recommendations
@@ -86,11 +95,25 @@ func (s *Server) generateRecommendations(c *gin.Context) {
 	pool := s.timescaleDB.GetTimescalePool().(*pgxpool.Pool)
 	recSvc := services.NewRecommendationService(s.postgresDB.GetPostgresDB(), pool)
 	
+	runStart := time.Now()
 	if err := recSvc.GenerateRightSizingRecommendations(c.Request.Context(), tenantID, lookbackHours); err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
 		return
 	}
 
+	// Notify about the recommendations this run created; updated ones were already announced
+	var created struct {
+		Count   int
+		Savings float64
+	}
+	err := s.postgresDB.GetPostgresDB().Model(&models.Recommendation{}).
+		Select("COUNT(*) AS count, COALESCE(SUM(potential_savings_usd), 0) AS savings").
+		Where("tenant_id = ? AND status = ? AND created_at >= ?", tid, "open", runStart).
+		Scan(&created).Error
+	if err == nil && created.Count > 0 {
+		s.notifySvc.Notify(tid, s.notifySvc.RecommendationMessage(created.Count, created.Savings, "USD"))
+	}
+
 	c.JSON(http.StatusOK, gin.H{"status": "recommendations generated", "lookback_hours": lookbackHours})
 }
 
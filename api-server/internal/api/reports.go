@@ -0,0 +1,271 @@
+package api
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/bugfreev587/k8s-cost-api-server/internal/middleware"
+	"github.com/bugfreev587/k8s-cost-api-server/internal/models"
+	"github.com/bugfreev587/k8s-cost-api-server/internal/services"
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+// reportRequest is a scheduled report's definition
+type reportRequest struct {
+	Name       string             `json:"name" binding:"required"`
+	Query      models.ReportQuery `json:"query"`
+	Format     string             `json:"format"`                      // csv (default), html
+	Schedule   string             `json:"schedule" binding:"required"` // cron expression, e.g. "0 8 * * mon"
+	Timezone   string             `json:"timezone"`                    // IANA zone the schedule runs in (default UTC)
+	ChannelIDs []int64            `json:"channel_ids"`
+	Recipients []string           `json:"recipients"` // replace the recipients of email channels
+	Enabled    *bool              `json:"enabled"`
+}
+
+// GET /v1/reports
+// List scheduled reports with their next and last run
+func (s *Server) listReports(c *gin.Context) {
+	tenantID, ok := middleware.GetTenantIDFromContext(c)
+	if !ok {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "no tenant context"})
+		return
+	}
+
+	reports, err := s.reportSvc.ListReports(c.Request.Context(), tenantID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"reports": reports,
+		"count":   len(reports),
+	})
+}
+
+// GET /v1/reports/:id
+// Get a scheduled report
+func (s *Server) getReport(c *gin.Context) {
+	report, ok := s.loadTenantReport(c)
+	if !ok {
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"report": report,
+	})
+}
+
+// GET /v1/reports/:id/runs
+// List a report's runs, newest first
+//
+// Query Parameters:
+//   - limit: Maximum number of runs (default 50)
+func (s *Server) listReportRuns(c *gin.Context) {
+	report, ok := s.loadTenantReport(c)
+	if !ok {
+		return
+	}
+
+	limit, _ := strconv.Atoi(c.Query("limit"))
+	runs, err := s.reportSvc.ListRuns(c.Request.Context(), report.ID, limit)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"runs":  runs,
+		"count": len(runs),
+	})
+}
+
+// GET /v1/reports/:id/runs/:run_id/download
+// Download the rendered output of a report run
+func (s *Server) downloadReportRun(c *gin.Context) {
+	report, ok := s.loadTenantReport(c)
+	if !ok {
+		return
+	}
+
+	runID, err := strconv.ParseUint(c.Param("run_id"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid run ID"})
+		return
+	}
+	run, err := s.reportSvc.GetRun(c.Request.Context(), report.ID, uint(runID))
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		c.JSON(http.StatusNotFound, gin.H{"error": "report run not found"})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	if len(run.Output) == 0 {
+		c.JSON(http.StatusNotFound, gin.H{"error": "report run has no output"})
+		return
+	}
+
+	contentType := "text/csv; charset=utf-8"
+	if run.Format == models.ReportFormatHTML {
+		contentType = "text/html; charset=utf-8"
+	}
+	c.Header("Content-Disposition", fmt.Sprintf("attachment; filename=%q", services.ReportFilename(report, run)))
+	c.Data(http.StatusOK, contentType, run.Output)
+}
+
+// POST /v1/reports/:id/run
+// Run a report now, outside its schedule, and deliver it
+func (s *Server) runReport(c *gin.Context) {
+	report, ok := s.loadTenantReport(c)
+	if !ok {
+		return
+	}
+
+	run, err := s.reportSvc.RunNow(c.Request.Context(), report)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"run": run,
+	})
+}
+
+// POST /v1/admin/reports
+// Create a scheduled report
+func (s *Server) createReport(c *gin.Context) {
+	tenantID, ok := middleware.GetTenantIDFromContext(c)
+	if !ok {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "no tenant context"})
+		return
+	}
+
+	var req reportRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	report := &models.Report{TenantID: tenantID, Enabled: true}
+	if !s.applyReportRequest(c, report, &req) {
+		return
+	}
+	if err := s.reportSvc.SaveReport(c.Request.Context(), report); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusCreated, gin.H{
+		"report": report,
+	})
+}
+
+// PUT /v1/admin/reports/:id
+// Update a scheduled report; its next run is recomputed from the schedule
+func (s *Server) updateReport(c *gin.Context) {
+	report, ok := s.loadTenantReport(c)
+	if !ok {
+		return
+	}
+
+	var req reportRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if !s.applyReportRequest(c, report, &req) {
+		return
+	}
+	if err := s.reportSvc.SaveReport(c.Request.Context(), report); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"report": report,
+	})
+}
+
+// DELETE /v1/admin/reports/:id
+// Delete a scheduled report and its run history
+func (s *Server) deleteReport(c *gin.Context) {
+	report, ok := s.loadTenantReport(c)
+	if !ok {
+		return
+	}
+
+	if err := s.reportSvc.DeleteReport(c.Request.Context(), report.ID); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "report deleted"})
+}
+
+// applyReportRequest copies a report request onto a report and validates it, writing a 400
+// response on failure
+func (s *Server) applyReportRequest(c *gin.Context, report *models.Report, req *reportRequest) bool {
+	report.Name = req.Name
+	report.Query = req.Query
+	report.Format = req.Format
+	report.Schedule = req.Schedule
+	report.Timezone = req.Timezone
+	report.ChannelIDs = req.ChannelIDs
+	report.Recipients = req.Recipients
+	if req.Enabled != nil {
+		report.Enabled = *req.Enabled
+	}
+	if err := services.ValidateReport(report, time.Now()); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return false
+	}
+
+	unknown, err := s.reportSvc.UnknownChannels(c.Request.Context(), report.TenantID, report.ChannelIDs)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return false
+	}
+	if len(unknown) > 0 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": fmt.Sprintf("unknown notification channels: %v", unknown)})
+		return false
+	}
+	return true
+}
+
+// loadTenantReport loads the report in the :id parameter and verifies tenant ownership,
+// writing the error response on failure
+func (s *Server) loadTenantReport(c *gin.Context) (*models.Report, bool) {
+	tenantID, ok := middleware.GetTenantIDFromContext(c)
+	if !ok {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "no tenant context"})
+		return nil, false
+	}
+
+	id, err := strconv.ParseUint(c.Param("id"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid report ID"})
+		return nil, false
+	}
+
+	report, err := s.reportSvc.GetReport(c.Request.Context(), uint(id))
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		c.JSON(http.StatusNotFound, gin.H{"error": "report not found"})
+		return nil, false
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return nil, false
+	}
+	if report.TenantID != tenantID {
+		c.JSON(http.StatusForbidden, gin.H{"error": "access denied"})
+		return nil, false
+	}
+	return report, true
+}
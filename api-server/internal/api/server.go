@@ -14,6 +14,7 @@ import (
 
 	// New import for HealthCheckResponse
 	"github.com/gin-gonic/gin"
+	"github.com/jackc/pgx/v5/pgxpool"
 )
 
 type Server struct {
@@ -23,6 +24,17 @@ type Server struct {
 	redisClient         app_interfaces.RedisService
 	apiKeySvc           *services.APIKeyService
 	planSvc             *services.PlanService
+	pricingSvc          *services.PricingService
+	allocSvc            *services.AllocationService
+	commitmentSvc       *services.CommitmentService
+	anomalySvc          *services.AnomalyService
+	anomalyCfg          config.AnomalyCfg
+	budgetSvc           *services.BudgetService
+	budgetCfg           config.BudgetCfg
+	notifySvc           *services.NotificationService
+	reportSvc           *services.ReportService
+	reportsCfg          config.ReportsCfg
+	statementSvc        *services.StatementService
 	clerkSvc            *services.ClerkService
 	grafanaSvc          *services.GrafanaService
 	clerkWebhookHandler *ClerkWebhookHandler
@@ -30,7 +42,7 @@ type Server struct {
 	router              *gin.Engine
 }
 
-func NewServer(cfg *config.Config, postgresDB app_interfaces.PostgresService, timescaleDB app_interfaces.TimescaleService, redisClient app_interfaces.RedisService, apiKeySvc *services.APIKeyService, planSvc *services.PlanService) *Server {
+func NewServer(cfg *config.Config, postgresDB app_interfaces.PostgresService, timescaleDB app_interfaces.TimescaleService, redisClient app_interfaces.RedisService, apiKeySvc *services.APIKeyService, planSvc *services.PlanService, pricingCache *services.PricingCache) *Server {
 	if cfg.Environment == "production" || cfg.Environment == "prod" {
 		gin.SetMode(gin.ReleaseMode)
 	}
@@ -60,6 +72,16 @@ func NewServer(cfg *config.Config, postgresDB app_interfaces.PostgresService, ti
 	// Initialize RBAC middleware
 	rbacMiddleware := middleware.NewRBACMiddleware(postgresDB.GetPostgresDB())
 
+	// Pricing and allocation services are shared by all requests so their pricing cache
+	// survives between requests; without a shared cache the cache is local to this instance
+	if pricingCache == nil {
+		pricingCache = services.NewPricingCache(nil, 1*time.Hour)
+	}
+	pool, _ := timescaleDB.GetTimescalePool().(*pgxpool.Pool)
+	pricingSvc := services.NewPricingServiceWithCache(postgresDB.GetPostgresDB(), pricingCache)
+	allocSvc := services.NewAllocationServiceWithPricingService(pool, postgresDB.GetPostgresDB(), pricingSvc)
+	notifySvc := services.NewNotificationService(postgresDB.GetPostgresDB(), cfg.Clerk.FrontendURL)
+
 	server := &Server{
 		serverConfig:        &cfg.Server,
 		postgresDB:          postgresDB,
@@ -67,6 +89,17 @@ func NewServer(cfg *config.Config, postgresDB app_interfaces.PostgresService, ti
 		redisClient:         redisClient,
 		apiKeySvc:           apiKeySvc,
 		planSvc:             planSvc,
+		pricingSvc:          pricingSvc,
+		allocSvc:            allocSvc,
+		commitmentSvc:       services.NewCommitmentServiceWithAllocation(postgresDB.GetPostgresDB(), allocSvc),
+		anomalySvc:          services.NewAnomalyService(postgresDB.GetPostgresDB(), allocSvc, notifySvc),
+		budgetSvc:           services.NewBudgetService(postgresDB.GetPostgresDB(), allocSvc, notifySvc),
+		anomalyCfg:          cfg.Anomaly,
+		budgetCfg:           cfg.Budget,
+		notifySvc:           notifySvc,
+		reportSvc:           services.NewReportService(postgresDB.GetPostgresDB(), allocSvc, notifySvc),
+		reportsCfg:          cfg.Reports,
+		statementSvc:        services.NewStatementService(postgresDB.GetPostgresDB(), allocSvc),
 		clerkSvc:            clerkSvc,
 		grafanaSvc:          grafanaSvc,
 		clerkWebhookHandler: clerkWebhookHandler,
@@ -134,12 +167,55 @@ func (s *Server) setupRoutes() {
 		dashboard.GET("/costs/clusters", s.getCostsByCluster)
 		dashboard.GET("/costs/utilization", s.getUtilizationVsRequests)
 		dashboard.GET("/costs/trends", s.getCostTrends)
+		dashboard.GET("/costs/forecast", s.getCostForecast)
+
+		// Cost anomalies - read only
+		dashboard.GET("/anomalies", s.listAnomalies)
+		dashboard.GET("/anomalies/settings", s.getAnomalySettings)
+		dashboard.GET("/anomalies/:id", s.getAnomaly)
+
+		// Budgets - read only
+		dashboard.GET("/budgets", s.listBudgets)
+		dashboard.GET("/budgets/:id", s.getBudget)
+		dashboard.GET("/budgets/:id/alerts", s.listBudgetAlerts)
+
+		// Scheduled reports - read only
+		dashboard.GET("/reports", s.listReports)
+		dashboard.GET("/reports/:id", s.getReport)
+		dashboard.GET("/reports/:id/runs", s.listReportRuns)
+		dashboard.GET("/reports/:id/runs/:run_id/download", s.downloadReportRun)
+
+		// Cost centers and chargeback statements - read only
+		dashboard.GET("/cost-centers", s.listCostCenters)
+		dashboard.GET("/cost-centers/tree", s.getCostCenterTree)
+		dashboard.GET("/statements", s.listStatements)
+		dashboard.GET("/statements/:id", s.getStatement)
+		dashboard.GET("/statements/:id/export", s.exportStatement)
 
 		// Allocation data - read only
 		dashboard.GET("/allocation", s.getAllocation)
 		dashboard.GET("/allocation/compute", s.getAllocationCompute)
 		dashboard.GET("/allocation/summary", s.getAllocationSummary)
 		dashboard.GET("/allocation/summary/topline", s.getAllocationTopline)
+		dashboard.GET("/allocation/focus", s.getAllocationFocus)
+		dashboard.GET("/allocation/sharing-rules", s.listSharingRules)
+		dashboard.GET("/allocation/label-rules", s.listLabelRules)
+		dashboard.POST("/allocation/whatif", s.whatIfAllocation)
+
+		// External (out-of-cluster) costs - read only
+		dashboard.GET("/external-costs", s.listExternalCosts)
+
+		// Billing reconciliation - read only
+		dashboard.GET("/billing/imports", s.listBillingImports)
+		dashboard.GET("/billing/imports/:id", s.getBillingImport)
+		dashboard.GET("/billing/reconciled-costs", s.listReconciledNodeCosts)
+
+		// Commitments (reserved instances, savings plans) - read only
+		dashboard.GET("/commitments", s.listCommitments)
+		dashboard.GET("/commitments/utilization", s.getCommitmentUtilization)
+
+		// Currency settings and exchange rates - read only
+		dashboard.GET("/currency", s.getCurrencySettings)
 
 		// Recommendations - read only
 		dashboard.GET("/recommendations", s.getRecommendations)
@@ -152,7 +228,14 @@ func (s *Server) setupRoutes() {
 		dashboard.GET("/pricing/configs", s.listPricingConfigs)
 		dashboard.GET("/pricing/configs/:id", s.getPricingConfig)
 		dashboard.GET("/pricing/presets", s.getPricingPresets)
+		dashboard.GET("/pricing/catalogs/:provider", s.getPricingCatalog)
+		dashboard.GET("/pricing/configs/:id/validate", s.validatePricingConfig)
+		dashboard.GET("/pricing/configs/:id/versions", s.listPricingVersions)
+		dashboard.GET("/pricing/versions/:id", s.getPricingVersion)
+		dashboard.GET("/pricing/audit", s.listPricingAuditLog)
 		dashboard.GET("/clusters/:name/pricing", s.getClusterPricing)
+		dashboard.GET("/clusters/:name/nodes/:node/pricing", s.getNodePricing)
+		dashboard.GET("/pricing/nodes", s.listNodePrices)
 		dashboard.GET("/pricing/cluster-assignments", s.listClusterPricings)
 	}
 
@@ -167,6 +250,14 @@ func (s *Server) setupRoutes() {
 		editor.POST("/recommendations/generate", s.generateRecommendations)
 		editor.POST("/recommendations/:id/apply", s.applyRecommendation)
 		editor.POST("/recommendations/:id/dismiss", s.dismissRecommendation)
+
+		// Cost anomalies - can detect on demand, acknowledge, resolve
+		editor.POST("/anomalies/detect", s.detectAnomalies)
+		editor.POST("/anomalies/:id/acknowledge", s.acknowledgeAnomaly)
+		editor.POST("/anomalies/:id/resolve", s.resolveAnomaly)
+
+		// Scheduled reports - can run on demand
+		editor.POST("/reports/:id/run", s.runReport)
 	}
 
 	// ===========================================
@@ -200,9 +291,78 @@ func (s *Server) setupRoutes() {
 		admin.POST("/pricing/configs/:id/rates", s.addPricingRate)
 		admin.PUT("/pricing/rates/:id", s.updatePricingRate)
 		admin.DELETE("/pricing/rates/:id", s.deletePricingRate)
+		admin.POST("/pricing/configs/:id/discounts", s.addPricingDiscount)
+		admin.PUT("/pricing/discounts/:id", s.updatePricingDiscount)
+		admin.DELETE("/pricing/discounts/:id", s.deletePricingDiscount)
 		admin.PUT("/clusters/:name/pricing", s.setClusterPricing)
 		admin.DELETE("/clusters/:name/pricing", s.deleteClusterPricing)
+		admin.PUT("/clusters/:name/nodes/:node/pricing", s.setNodePricing)
+		admin.DELETE("/clusters/:name/nodes/:node/pricing", s.deleteNodePricing)
+		admin.POST("/clusters/:name/nodes/pricing", s.bulkSetNodePricing)
 		admin.POST("/pricing/import/:provider", s.importProviderPricing)
+
+		// Shared cost rules
+		admin.POST("/allocation/sharing-rules", s.createSharingRule)
+		admin.PUT("/allocation/sharing-rules/:id", s.updateSharingRule)
+		admin.DELETE("/allocation/sharing-rules/:id", s.deleteSharingRule)
+		admin.POST("/allocation/label-rules", s.createLabelRule)
+		admin.PUT("/allocation/label-rules/:id", s.updateLabelRule)
+		admin.DELETE("/allocation/label-rules/:id", s.deleteLabelRule)
+
+		// External cost uploads
+		admin.POST("/external-costs", s.uploadExternalCosts)
+		admin.DELETE("/external-costs", s.deleteExternalCostSource)
+		admin.DELETE("/external-costs/:id", s.deleteExternalCost)
+
+		// Billing export imports (AWS CUR, GCP billing export, Azure cost export)
+		admin.POST("/billing/imports/:provider", s.importBillingExport)
+		admin.DELETE("/billing/imports/:id", s.deleteBillingImport)
+
+		// Commitment management
+		admin.POST("/commitments", s.createCommitment)
+		admin.PUT("/commitments/:id", s.updateCommitment)
+		admin.DELETE("/commitments/:id", s.deleteCommitment)
+
+		// Currency management
+		admin.PUT("/currency", s.setDisplayCurrency)
+		admin.POST("/exchange-rates", s.setExchangeRate)
+		admin.DELETE("/exchange-rates/:id", s.deleteExchangeRate)
+
+		// Anomaly detection settings
+		admin.PUT("/anomalies/settings", s.updateAnomalySettings)
+
+		// Budgets
+		admin.POST("/budgets", s.createBudget)
+		admin.PUT("/budgets/:id", s.updateBudget)
+		admin.DELETE("/budgets/:id", s.deleteBudget)
+		admin.POST("/budgets/:id/evaluate", s.evaluateBudget)
+
+		// Notification channels
+		admin.GET("/notifications/channels", s.listNotificationChannels)
+		admin.POST("/notifications/channels", s.createNotificationChannel)
+		admin.PUT("/notifications/channels/:id", s.updateNotificationChannel)
+		admin.DELETE("/notifications/channels/:id", s.deleteNotificationChannel)
+		admin.POST("/notifications/channels/:id/test", s.testNotificationChannel)
+		admin.GET("/notifications/deliveries", s.listNotificationDeliveries)
+
+		// Scheduled reports
+		admin.POST("/reports", s.createReport)
+		admin.PUT("/reports/:id", s.updateReport)
+		admin.DELETE("/reports/:id", s.deleteReport)
+
+		// Cost centers
+		admin.POST("/cost-centers", s.createCostCenter)
+		admin.PUT("/cost-centers/:id", s.updateCostCenter)
+		admin.DELETE("/cost-centers/:id", s.deleteCostCenter)
+
+		// Chargeback statements: generate, adjust and review drafts (owners finalize)
+		admin.POST("/statements", s.createStatement)
+		admin.POST("/statements/:id/refresh", s.refreshStatement)
+		admin.DELETE("/statements/:id", s.deleteStatement)
+		admin.POST("/statements/:id/adjustments", s.createStatementAdjustment)
+		admin.DELETE("/statements/:id/adjustments/:adjustment_id", s.deleteStatementAdjustment)
+		admin.POST("/statements/:id/review", s.reviewStatement)
+		admin.POST("/statements/:id/reopen", s.reopenStatement)
 	}
 
 	// ===========================================
@@ -222,6 +382,9 @@ func (s *Server) setupRoutes() {
 		// Transfer ownership
 		owner.POST("/transfer-ownership", s.transferOwnershipHandler())
 
+		// Finalize a reviewed chargeback statement
+		owner.POST("/statements/:id/finalize", s.finalizeStatement)
+
 		// Delete tenant (danger zone)
 		owner.DELETE("/tenants/:tenant_id", s.rbac.RequireTenantAccess("tenant_id"), s.deleteTenantHandler())
 	}
@@ -240,6 +403,42 @@ func (s *Server) Run() error {
 	return srv.ListenAndServe()
 }
 
+// StartJobs starts the background jobs (anomaly detection, budget evaluation, scheduled
+// reports) until ctx is cancelled
+func (s *Server) StartJobs(ctx context.Context) {
+	if s.anomalyCfg.Disabled {
+		log.Printf("Anomaly detector disabled")
+	} else {
+		interval := jobInterval(s.anomalyCfg.IntervalMinutes, 15*time.Minute)
+		go s.anomalySvc.Run(ctx, interval)
+		log.Printf("Anomaly detector started (every %s)", interval)
+	}
+
+	if s.budgetCfg.Disabled {
+		log.Printf("Budget evaluation disabled")
+	} else {
+		interval := jobInterval(s.budgetCfg.IntervalMinutes, time.Hour)
+		go s.budgetSvc.Run(ctx, interval)
+		log.Printf("Budget evaluation started (every %s)", interval)
+	}
+
+	if s.reportsCfg.Disabled {
+		log.Printf("Report scheduler disabled")
+	} else {
+		interval := jobInterval(s.reportsCfg.IntervalMinutes, time.Minute)
+		go s.reportSvc.Run(ctx, interval)
+		log.Printf("Report scheduler started (every %s)", interval)
+	}
+}
+
+// jobInterval returns a job's configured interval, or the default when unset
+func jobInterval(minutes int, def time.Duration) time.Duration {
+	if minutes <= 0 {
+		return def
+	}
+	return time.Duration(minutes) * time.Minute
+}
+
 func (s *Server) Shutdown(ctx context.Context) error {
 	return nil
 }
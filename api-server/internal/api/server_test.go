@@ -12,6 +12,7 @@ import (
 	"github.com/bugfreev587/k8s-cost-api-server/internal/api_types"      // New import for HealthCheckResponse
 	"github.com/bugfreev587/k8s-cost-api-server/internal/app_interfaces" // New import for app_interfaces
 	"github.com/bugfreev587/k8s-cost-api-server/internal/config"         // Needed for models.Recommendation
+	"github.com/bugfreev587/k8s-cost-api-server/internal/export"
 	"github.com/bugfreev587/k8s-cost-api-server/internal/models"
 
 	"github.com/gin-gonic/gin"
@@ -52,7 +53,10 @@ func (m *mockTimescaleDB) InsertPodMetric(ctx context.Context, timeStamp time.Ti
 func (m *mockTimescaleDB) InsertPodMetricWithExtras(ctx context.Context, timeStamp time.Time, tenantID int64, cluster, namespace, pod, node string, cpuMilli, memBytes, cpuRequest, memRequest, cpuLimit, memLimit int64, labels map[string]string, phase, qosClass string, containers interface{}) error {
 	return nil
 }
-func (m *mockTimescaleDB) InsertNodeMetric(ctx context.Context, t time.Time, tenantID int64, cluster, node, instanceType string, cpuCap, memCap int64, hourlyCost float64) error {
+func (m *mockTimescaleDB) InsertNodeMetric(ctx context.Context, t time.Time, tenantID int64, cluster, node, instanceType, providerID string, cpuCap, memCap int64, hourlyCost float64) error {
+	return nil
+}
+func (m *mockTimescaleDB) InsertNodeMetricWithLabels(ctx context.Context, t time.Time, tenantID int64, cluster, node, instanceType, providerID string, cpuCap, memCap int64, hourlyCost float64, labels map[string]string) error {
 	return nil
 }
 func (m *mockTimescaleDB) GetTimescalePool() interface{} {
@@ -88,7 +92,7 @@ func TestHealthCheckHandler_AllHealthy(t *testing.T) {
 	mockTsDB := &mockTimescaleDB{healthErr: nil}
 	mockRdb := &mockRedisClient{pingErr: nil}
 
-	testServer := NewServer(&config.Config{}, mockPgDB, mockTsDB, mockRdb, nil, nil)
+	testServer := NewServer(&config.Config{}, mockPgDB, mockTsDB, mockRdb, nil, nil, nil)
 
 	// Call the handler
 	testServer.healthCheckHandler()(c)
@@ -117,7 +121,7 @@ func TestHealthCheckHandler_PostgresUnhealthy(t *testing.T) {
 	mockTsDB := &mockTimescaleDB{healthErr: nil}
 	mockRdb := &mockRedisClient{pingErr: nil}
 
-	testServer := NewServer(&config.Config{}, mockPgDB, mockTsDB, mockRdb, nil, nil)
+	testServer := NewServer(&config.Config{}, mockPgDB, mockTsDB, mockRdb, nil, nil, nil)
 
 	// Call the handler
 	testServer.healthCheckHandler()(c)
@@ -147,7 +151,7 @@ func TestHealthCheckHandler_TimescaleUnhealthy(t *testing.T) {
 	mockTsDB := &mockTimescaleDB{healthErr: errors.New("ts error")}
 	mockRdb := &mockRedisClient{pingErr: nil}
 
-	testServer := NewServer(&config.Config{}, mockPgDB, mockTsDB, mockRdb, nil, nil)
+	testServer := NewServer(&config.Config{}, mockPgDB, mockTsDB, mockRdb, nil, nil, nil)
 
 	// Call the handler
 	testServer.healthCheckHandler()(c)
@@ -177,7 +181,7 @@ func TestHealthCheckHandler_RedisUnhealthy(t *testing.T) {
 	mockTsDB := &mockTimescaleDB{healthErr: nil}
 	mockRdb := &mockRedisClient{pingErr: errors.New("redis error")}
 
-	testServer := NewServer(&config.Config{}, mockPgDB, mockTsDB, mockRdb, nil, nil)
+	testServer := NewServer(&config.Config{}, mockPgDB, mockTsDB, mockRdb, nil, nil, nil)
 
 	// Call the handler
 	testServer.healthCheckHandler()(c)
@@ -207,7 +211,7 @@ func TestHealthCheckHandler_AllUnhealthy(t *testing.T) {
 	mockTsDB := &mockTimescaleDB{healthErr: errors.New("ts error")}
 	mockRdb := &mockRedisClient{pingErr: errors.New("redis error")}
 
-	testServer := NewServer(&config.Config{}, mockPgDB, mockTsDB, mockRdb, nil, nil)
+	testServer := NewServer(&config.Config{}, mockPgDB, mockTsDB, mockRdb, nil, nil, nil)
 
 	// Call the handler
 	testServer.healthCheckHandler()(c)
@@ -225,3 +229,39 @@ func TestHealthCheckHandler_AllUnhealthy(t *testing.T) {
 	assert.NoError(t, err)
 	assert.JSONEq(t, string(expectedJSON), w.Body.String())
 }
+
+func TestStreamExport(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	columns := []export.Column{{Name: "name", Type: export.String}}
+
+	// An error before the first row leaves the response to the caller
+	w := httptest.NewRecorder()
+	c, _ := gin.CreateTestContext(w)
+	err := streamExport(c, "test", export.FormatCSV, columns, func(export.Writer) error {
+		return errors.New("query failed")
+	})
+	assert.EqualError(t, err, "query failed")
+	assert.Empty(t, w.Header().Get("Content-Disposition"))
+	assert.Empty(t, w.Body.String())
+
+	// Once rows are written, a later error only truncates the file
+	w = httptest.NewRecorder()
+	c, _ = gin.CreateTestContext(w)
+	err = streamExport(c, "test", export.FormatCSV, columns, func(ew export.Writer) error {
+		if err := ew.WriteRow("a"); err != nil {
+			return err
+		}
+		return errors.New("query failed")
+	})
+	assert.NoError(t, err)
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Contains(t, w.Header().Get("Content-Disposition"), "attachment")
+	assert.Equal(t, "name\na\n", w.Body.String())
+
+	// An export without rows still gets its header row
+	w = httptest.NewRecorder()
+	c, _ = gin.CreateTestContext(w)
+	err = streamExport(c, "test", export.FormatCSV, columns, func(export.Writer) error { return nil })
+	assert.NoError(t, err)
+	assert.Equal(t, "name\n", w.Body.String())
+}
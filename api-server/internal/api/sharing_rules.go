@@ -0,0 +1,177 @@
+package api
+
+import (
+	"net/http"
+	"strconv"
+
+	"github.com/bugfreev587/k8s-cost-api-server/internal/middleware"
+	"github.com/bugfreev587/k8s-cost-api-server/internal/models"
+	"github.com/bugfreev587/k8s-cost-api-server/internal/services"
+	"github.com/gin-gonic/gin"
+)
+
+// getSharingRuleService returns the allocation service, which manages sharing rules
+func (s *Server) getSharingRuleService() *services.AllocationService {
+	return s.allocSvc
+}
+
+type sharingRuleRequest struct {
+	Name       string   `json:"name" binding:"required"`
+	Namespaces []string `json:"namespaces"`
+	Labels     []string `json:"labels"`
+	Split      string   `json:"split"`
+	Enabled    *bool    `json:"enabled"`
+}
+
+// GET /v1/allocation/sharing-rules
+// List the tenant's shared cost rules
+func (s *Server) listSharingRules(c *gin.Context) {
+	tenantID, ok := middleware.GetTenantIDFromContext(c)
+	if !ok {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "no tenant context"})
+		return
+	}
+
+	rules, err := s.getSharingRuleService().ListSharingRules(c.Request.Context(), tenantID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"rules": rules,
+		"count": len(rules),
+	})
+}
+
+// POST /v1/admin/allocation/sharing-rules
+// Create a shared cost rule
+func (s *Server) createSharingRule(c *gin.Context) {
+	tenantID, ok := middleware.GetTenantIDFromContext(c)
+	if !ok {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "no tenant context"})
+		return
+	}
+
+	var req sharingRuleRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	rule := &models.SharedCostRule{
+		TenantID:   tenantID,
+		Name:       req.Name,
+		Namespaces: req.Namespaces,
+		Labels:     req.Labels,
+		Split:      req.Split,
+		Enabled:    req.Enabled == nil || *req.Enabled,
+	}
+	if err := services.ValidateSharedCostRule(rule); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	if err := s.getSharingRuleService().SaveSharingRule(c.Request.Context(), rule); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusCreated, gin.H{
+		"rule": rule,
+	})
+}
+
+// PUT /v1/admin/allocation/sharing-rules/:id
+// Update a shared cost rule
+func (s *Server) updateSharingRule(c *gin.Context) {
+	tenantID, ok := middleware.GetTenantIDFromContext(c)
+	if !ok {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "no tenant context"})
+		return
+	}
+
+	ruleID, err := strconv.ParseUint(c.Param("id"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid rule ID"})
+		return
+	}
+
+	var req sharingRuleRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	allocSvc := s.getSharingRuleService()
+
+	// Get rule to verify ownership
+	rule, err := allocSvc.GetSharingRule(c.Request.Context(), uint(ruleID))
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "sharing rule not found"})
+		return
+	}
+
+	if rule.TenantID != tenantID {
+		c.JSON(http.StatusForbidden, gin.H{"error": "access denied"})
+		return
+	}
+
+	rule.Name = req.Name
+	rule.Namespaces = req.Namespaces
+	rule.Labels = req.Labels
+	rule.Split = req.Split
+	if req.Enabled != nil {
+		rule.Enabled = *req.Enabled
+	}
+	if err := services.ValidateSharedCostRule(rule); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	if err := allocSvc.SaveSharingRule(c.Request.Context(), rule); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"rule": rule,
+	})
+}
+
+// DELETE /v1/admin/allocation/sharing-rules/:id
+// Delete a shared cost rule
+func (s *Server) deleteSharingRule(c *gin.Context) {
+	tenantID, ok := middleware.GetTenantIDFromContext(c)
+	if !ok {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "no tenant context"})
+		return
+	}
+
+	ruleID, err := strconv.ParseUint(c.Param("id"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid rule ID"})
+		return
+	}
+
+	allocSvc := s.getSharingRuleService()
+
+	// Get rule to verify ownership
+	rule, err := allocSvc.GetSharingRule(c.Request.Context(), uint(ruleID))
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "sharing rule not found"})
+		return
+	}
+
+	if rule.TenantID != tenantID {
+		c.JSON(http.StatusForbidden, gin.H{"error": "access denied"})
+		return
+	}
+
+	if err := allocSvc.DeleteSharingRule(c.Request.Context(), uint(ruleID)); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "sharing rule deleted"})
+}
@@ -0,0 +1,528 @@
+package api
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+
+	"github.com/bugfreev587/k8s-cost-api-server/internal/export"
+	"github.com/bugfreev587/k8s-cost-api-server/internal/middleware"
+	"github.com/bugfreev587/k8s-cost-api-server/internal/models"
+	"github.com/bugfreev587/k8s-cost-api-server/internal/services"
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+// costCenterRequest is a node of the cost center hierarchy; pods map to it when they run in
+// one of its clusters (if set) and are in one of its namespaces or match one of its pod
+// label selectors (if set)
+type costCenterRequest struct {
+	Name        string   `json:"name" binding:"required"`
+	ParentID    *uint    `json:"parent_id"`
+	Level       string   `json:"level"` // e.g. org, department, team, service
+	Description string   `json:"description"`
+	Clusters    []string `json:"clusters"`
+	Namespaces  []string `json:"namespaces"`
+	Labels      []string `json:"labels"`   // "key=value" or "key"
+	Priority    int      `json:"priority"` // lowest matching priority wins (default 0)
+}
+
+// statementRequest asks for a draft statement for a month
+type statementRequest struct {
+	Period    string `json:"period" binding:"required"` // YYYY-MM
+	Currency  string `json:"currency"`                  // default: the tenant's display currency
+	ShareIdle string `json:"share_idle"`                // weighted (default), even, false
+}
+
+// adjustmentRequest is a manual adjustment or credit to a cost center on a statement
+type adjustmentRequest struct {
+	CostCenter string  `json:"cost_center" binding:"required"`
+	Kind       string  `json:"kind"` // adjustment (default), credit
+	Amount     float64 `json:"amount"`
+	Comment    string  `json:"comment" binding:"required"`
+}
+
+// GET /v1/cost-centers
+// List cost centers by name
+func (s *Server) listCostCenters(c *gin.Context) {
+	tenantID, ok := middleware.GetTenantIDFromContext(c)
+	if !ok {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "no tenant context"})
+		return
+	}
+
+	centers, err := s.statementSvc.ListCostCenters(c.Request.Context(), tenantID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"cost_centers": centers,
+		"count":        len(centers),
+	})
+}
+
+// GET /v1/cost-centers/tree
+// Get the cost center hierarchy as nested nodes with their paths
+func (s *Server) getCostCenterTree(c *gin.Context) {
+	tenantID, ok := middleware.GetTenantIDFromContext(c)
+	if !ok {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "no tenant context"})
+		return
+	}
+
+	tree, err := s.statementSvc.CostCenterTree(c.Request.Context(), tenantID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"cost_centers": tree.Roots(),
+	})
+}
+
+// POST /v1/admin/cost-centers
+// Create a cost center
+func (s *Server) createCostCenter(c *gin.Context) {
+	tenantID, ok := middleware.GetTenantIDFromContext(c)
+	if !ok {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "no tenant context"})
+		return
+	}
+
+	var req costCenterRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	cc := &models.CostCenter{TenantID: tenantID}
+	if !s.applyCostCenterRequest(c, cc, &req) {
+		return
+	}
+
+	if err := s.statementSvc.SaveCostCenter(c.Request.Context(), cc); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusCreated, gin.H{
+		"cost_center": cc,
+	})
+}
+
+// PUT /v1/admin/cost-centers/:id
+// Update a cost center; existing statements keep the results they were generated with
+func (s *Server) updateCostCenter(c *gin.Context) {
+	cc, ok := s.loadTenantCostCenter(c)
+	if !ok {
+		return
+	}
+
+	var req costCenterRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if !s.applyCostCenterRequest(c, cc, &req) {
+		return
+	}
+
+	if err := s.statementSvc.SaveCostCenter(c.Request.Context(), cc); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"cost_center": cc,
+	})
+}
+
+// DELETE /v1/admin/cost-centers/:id
+// Delete a cost center without children; statement lines keep its name
+func (s *Server) deleteCostCenter(c *gin.Context) {
+	cc, ok := s.loadTenantCostCenter(c)
+	if !ok {
+		return
+	}
+
+	hasChildren, err := s.statementSvc.HasChildCostCenters(c.Request.Context(), cc.ID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	if hasChildren {
+		c.JSON(http.StatusConflict, gin.H{"error": "cost center has child cost centers; move or delete them first"})
+		return
+	}
+
+	if err := s.statementSvc.DeleteCostCenter(c.Request.Context(), cc.ID); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "cost center deleted"})
+}
+
+// GET /v1/statements
+// List chargeback statements, newest period first
+func (s *Server) listStatements(c *gin.Context) {
+	tenantID, ok := middleware.GetTenantIDFromContext(c)
+	if !ok {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "no tenant context"})
+		return
+	}
+
+	statements, err := s.statementSvc.ListStatements(c.Request.Context(), tenantID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"statements": statements,
+		"count":      len(statements),
+	})
+}
+
+// GET /v1/statements/:id
+// Get a statement with its cost center lines and adjustments
+func (s *Server) getStatement(c *gin.Context) {
+	st, ok := s.loadTenantStatement(c)
+	if !ok {
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"statement": st,
+	})
+}
+
+// GET /v1/statements/:id/export
+// Download a statement's cost center lines
+//
+// Query Parameters:
+//   - format: csv (default), xlsx or parquet
+func (s *Server) exportStatement(c *gin.Context) {
+	st, ok := s.loadTenantStatement(c)
+	if !ok {
+		return
+	}
+
+	format := c.DefaultQuery("format", export.FormatCSV)
+	if !export.ValidFormat(format) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": fmt.Sprintf("invalid format: %s (expected csv, xlsx or parquet)", format)})
+		return
+	}
+
+	err := streamExport(c, fmt.Sprintf("statement-%s-%s", st.Period, st.Status), format, services.StatementExportColumns, func(w export.Writer) error {
+		return services.WriteStatementRows(w, st)
+	})
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+	}
+}
+
+// POST /v1/admin/statements
+// Generate a draft statement for a month, freezing its allocation results per cost center
+func (s *Server) createStatement(c *gin.Context) {
+	tenantID, ok := middleware.GetTenantIDFromContext(c)
+	if !ok {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "no tenant context"})
+		return
+	}
+
+	var req statementRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if _, _, err := services.StatementPeriod(req.Period); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	switch req.ShareIdle {
+	case "", services.ShareIdleWeighted, services.ShareIdleEven, "false":
+	default:
+		c.JSON(http.StatusBadRequest, gin.H{"error": fmt.Sprintf("invalid share_idle: %s (expected weighted, even or false)", req.ShareIdle)})
+		return
+	}
+	currency, err := s.getCurrencyService().ResolveCurrency(c.Request.Context(), tenantID, req.Currency)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	_, err = s.statementSvc.GetStatementForPeriod(c.Request.Context(), tenantID, req.Period)
+	if err == nil {
+		c.JSON(http.StatusConflict, gin.H{"error": fmt.Sprintf("a statement for %s already exists", req.Period)})
+		return
+	}
+	if !errors.Is(err, gorm.ErrRecordNotFound) {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	st := &models.Statement{
+		TenantID:  tenantID,
+		Period:    req.Period,
+		Currency:  currency,
+		ShareIdle: req.ShareIdle,
+		CreatedBy: requestActor(c),
+	}
+	if err := s.statementSvc.Generate(c.Request.Context(), st); err != nil {
+		c.JSON(allocationErrorStatus(err), gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusCreated, gin.H{
+		"statement": st,
+	})
+}
+
+// POST /v1/admin/statements/:id/refresh
+// Recompute a draft statement from the current allocation results, keeping its adjustments
+func (s *Server) refreshStatement(c *gin.Context) {
+	st, ok := s.loadTenantStatement(c)
+	if !ok || !requireStatementStatus(c, st, models.StatementDraft) {
+		return
+	}
+
+	if err := s.statementSvc.Refresh(c.Request.Context(), st); err != nil {
+		c.JSON(allocationErrorStatus(err), gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"statement": st,
+	})
+}
+
+// DELETE /v1/admin/statements/:id
+// Delete a statement that is not finalized
+func (s *Server) deleteStatement(c *gin.Context) {
+	st, ok := s.loadTenantStatement(c)
+	if !ok {
+		return
+	}
+	if st.Status == models.StatementFinalized {
+		c.JSON(http.StatusConflict, gin.H{"error": "finalized statements cannot be deleted"})
+		return
+	}
+
+	if err := s.statementSvc.DeleteStatement(c.Request.Context(), st.ID); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "statement deleted"})
+}
+
+// POST /v1/admin/statements/:id/adjustments
+// Add an adjustment or credit, with a comment, to a cost center on a draft statement
+func (s *Server) createStatementAdjustment(c *gin.Context) {
+	st, ok := s.loadTenantStatement(c)
+	if !ok || !requireStatementStatus(c, st, models.StatementDraft) {
+		return
+	}
+
+	var req adjustmentRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	adj := &models.StatementAdjustment{
+		CostCenter: req.CostCenter,
+		Kind:       req.Kind,
+		Amount:     req.Amount,
+		Comment:    req.Comment,
+		CreatedBy:  requestActor(c),
+	}
+	if adj.Kind == "" {
+		adj.Kind = models.AdjustmentKindAdjustment
+	}
+	if err := services.ValidateAdjustment(st, adj); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	if err := s.statementSvc.AddAdjustment(c.Request.Context(), st, adj); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusCreated, gin.H{
+		"adjustment": adj,
+		"statement":  st,
+	})
+}
+
+// DELETE /v1/admin/statements/:id/adjustments/:adjustment_id
+// Remove an adjustment from a draft statement
+func (s *Server) deleteStatementAdjustment(c *gin.Context) {
+	st, ok := s.loadTenantStatement(c)
+	if !ok || !requireStatementStatus(c, st, models.StatementDraft) {
+		return
+	}
+
+	adjustmentID, err := strconv.ParseUint(c.Param("adjustment_id"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid adjustment ID"})
+		return
+	}
+
+	found, err := s.statementSvc.DeleteAdjustment(c.Request.Context(), st, uint(adjustmentID))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	if !found {
+		c.JSON(http.StatusNotFound, gin.H{"error": "adjustment not found"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"statement": st,
+	})
+}
+
+// POST /v1/admin/statements/:id/review
+// Mark a draft statement as reviewed; it can no longer be refreshed or adjusted
+func (s *Server) reviewStatement(c *gin.Context) {
+	s.transitionStatement(c, models.StatementReviewed)
+}
+
+// POST /v1/admin/statements/:id/reopen
+// Return a reviewed statement to draft
+func (s *Server) reopenStatement(c *gin.Context) {
+	s.transitionStatement(c, models.StatementDraft)
+}
+
+// POST /v1/owner/statements/:id/finalize
+// Finalize a reviewed statement; finalized statements are immutable
+func (s *Server) finalizeStatement(c *gin.Context) {
+	s.transitionStatement(c, models.StatementFinalized)
+}
+
+// transitionStatement moves the statement in the :id parameter to a new status
+func (s *Server) transitionStatement(c *gin.Context, status string) {
+	st, ok := s.loadTenantStatement(c)
+	if !ok {
+		return
+	}
+	if !services.CanTransition(st.Status, status) {
+		c.JSON(http.StatusConflict, gin.H{"error": fmt.Sprintf("cannot move a %s statement to %s", st.Status, status)})
+		return
+	}
+
+	if err := s.statementSvc.SetStatus(c.Request.Context(), st, status, requestActor(c)); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"statement": st,
+	})
+}
+
+// requireStatementStatus writes a 409 response unless the statement has the status
+func requireStatementStatus(c *gin.Context, st *models.Statement, status string) bool {
+	if st.Status != status {
+		c.JSON(http.StatusConflict, gin.H{"error": fmt.Sprintf("statement is %s; only %s statements can be changed", st.Status, status)})
+		return false
+	}
+	return true
+}
+
+// applyCostCenterRequest copies a cost center request onto a cost center and validates it
+// and its place in the hierarchy, writing the error response on failure
+func (s *Server) applyCostCenterRequest(c *gin.Context, cc *models.CostCenter, req *costCenterRequest) bool {
+	cc.Name = req.Name
+	cc.ParentID = req.ParentID
+	cc.Level = req.Level
+	cc.Description = req.Description
+	cc.Clusters = req.Clusters
+	cc.Namespaces = req.Namespaces
+	cc.Labels = req.Labels
+	cc.Priority = req.Priority
+	if err := services.ValidateCostCenter(cc); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return false
+	}
+
+	centers, err := s.statementSvc.ListCostCenters(c.Request.Context(), cc.TenantID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return false
+	}
+	if err := services.CheckCostCenterParent(centers, cc); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return false
+	}
+	return true
+}
+
+// loadTenantCostCenter loads the cost center in the :id parameter and verifies tenant
+// ownership, writing the error response on failure
+func (s *Server) loadTenantCostCenter(c *gin.Context) (*models.CostCenter, bool) {
+	tenantID, ok := middleware.GetTenantIDFromContext(c)
+	if !ok {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "no tenant context"})
+		return nil, false
+	}
+
+	id, err := strconv.ParseUint(c.Param("id"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid cost center ID"})
+		return nil, false
+	}
+
+	cc, err := s.statementSvc.GetCostCenter(c.Request.Context(), uint(id))
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		c.JSON(http.StatusNotFound, gin.H{"error": "cost center not found"})
+		return nil, false
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return nil, false
+	}
+	if cc.TenantID != tenantID {
+		c.JSON(http.StatusForbidden, gin.H{"error": "access denied"})
+		return nil, false
+	}
+	return cc, true
+}
+
+// loadTenantStatement loads the statement in the :id parameter with its lines and
+// adjustments and verifies tenant ownership, writing the error response on failure
+func (s *Server) loadTenantStatement(c *gin.Context) (*models.Statement, bool) {
+	tenantID, ok := middleware.GetTenantIDFromContext(c)
+	if !ok {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "no tenant context"})
+		return nil, false
+	}
+
+	id, err := strconv.ParseUint(c.Param("id"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid statement ID"})
+		return nil, false
+	}
+
+	st, err := s.statementSvc.GetStatement(c.Request.Context(), uint(id))
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		c.JSON(http.StatusNotFound, gin.H{"error": "statement not found"})
+		return nil, false
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return nil, false
+	}
+	if st.TenantID != tenantID {
+		c.JSON(http.StatusForbidden, gin.H{"error": "access denied"})
+		return nil, false
+	}
+	return st, true
+}
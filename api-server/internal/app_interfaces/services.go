@@ -25,7 +25,8 @@ type TimescaleService interface {
 	Health(ctx context.Context) error
 	InsertPodMetric(ctx context.Context, timeStamp time.Time, tenantID int64, cluster, namespace, pod, node string, cpuMilli, memBytes, cpuRequest, memRequest, cpuLimit, memLimit int64) error
 	InsertPodMetricWithExtras(ctx context.Context, timeStamp time.Time, tenantID int64, cluster, namespace, pod, node string, cpuMilli, memBytes, cpuRequest, memRequest, cpuLimit, memLimit int64, labels map[string]string, phase, qosClass string, containers interface{}) error
-	InsertNodeMetric(ctx context.Context, t time.Time, tenantID int64, cluster, node, instanceType string, cpuCap, memCap int64, hourlyCost float64) error
+	InsertNodeMetric(ctx context.Context, t time.Time, tenantID int64, cluster, node, instanceType, providerID string, cpuCap, memCap int64, hourlyCost float64) error
+	InsertNodeMetricWithLabels(ctx context.Context, t time.Time, tenantID int64, cluster, node, instanceType, providerID string, cpuCap, memCap int64, hourlyCost float64, labels map[string]string) error
 	GetTimescalePool() interface{} // Returns *pgxpool.Pool but using interface{} to avoid circular dependency
 }
 
@@ -52,6 +52,21 @@ type GrafanaCfg struct {
 	APIToken string `mapstructure:"api_token" yaml:"api_token"` // API token (alternative to username/password)
 }
 
+type AnomalyCfg struct {
+	Disabled        bool `mapstructure:"disabled" yaml:"disabled"`                 // Disable the background anomaly detector
+	IntervalMinutes int  `mapstructure:"interval_minutes" yaml:"interval_minutes"` // How often the detector runs (default 15)
+}
+
+type BudgetCfg struct {
+	Disabled        bool `mapstructure:"disabled" yaml:"disabled"`                 // Disable the background budget evaluation
+	IntervalMinutes int  `mapstructure:"interval_minutes" yaml:"interval_minutes"` // How often budgets are evaluated (default 60)
+}
+
+type ReportsCfg struct {
+	Disabled        bool `mapstructure:"disabled" yaml:"disabled"`                 // Disable the report scheduler
+	IntervalMinutes int  `mapstructure:"interval_minutes" yaml:"interval_minutes"` // How often due reports are checked (default 1)
+}
+
 type Config struct {
 	Environment string      `mapstructure:"environment"`
 	Server      ServerCfg   `mapstructure:"server"`
@@ -63,6 +78,9 @@ type Config struct {
 	Agent       AgentCfg    `mapstructure:"agent"`
 	Clerk       ClerkCfg    `mapstructure:"clerk" yaml:"clerk"`
 	Grafana     GrafanaCfg  `mapstructure:"grafana" yaml:"grafana"`
+	Anomaly     AnomalyCfg  `mapstructure:"anomaly" yaml:"anomaly"`
+	Budget      BudgetCfg   `mapstructure:"budget" yaml:"budget"`
+	Reports     ReportsCfg  `mapstructure:"reports" yaml:"reports"`
 }
 
 func LoadConfig(path string) (*Config, error) {
@@ -85,8 +85,24 @@ func (db *TimescaleDB) InsertPodMetricWithExtras(ctx context.Context, timeStamp
 	return err
 }
 
-func (db *TimescaleDB) InsertNodeMetric(ctx context.Context, t time.Time, tenantID int64, cluster, node, instanceType string, cpuCap, memCap int64, hourlyCost float64) error {
-	q := `INSERT INTO node_metrics (time, tenant_id, cluster_name, node_name, instance_type, cpu_capacity, memory_capacity, hourly_cost_usd) VALUES ($1,$2,$3,$4,$5,$6,$7,$8)`
-	_, err := db.pool.Exec(ctx, q, t, tenantID, cluster, node, instanceType, cpuCap, memCap, hourlyCost)
+func (db *TimescaleDB) InsertNodeMetric(ctx context.Context, t time.Time, tenantID int64, cluster, node, instanceType, providerID string, cpuCap, memCap int64, hourlyCost float64) error {
+	q := `INSERT INTO node_metrics (time, tenant_id, cluster_name, node_name, instance_type, provider_id, cpu_capacity, memory_capacity, hourly_cost_usd) VALUES ($1,$2,$3,$4,$5,NULLIF($6,''),$7,$8,$9)`
+	_, err := db.pool.Exec(ctx, q, t, tenantID, cluster, node, instanceType, providerID, cpuCap, memCap, hourlyCost)
+	return err
+}
+
+// InsertNodeMetricWithLabels inserts a node metric with the node's labels
+func (db *TimescaleDB) InsertNodeMetricWithLabels(ctx context.Context, t time.Time, tenantID int64, cluster, node, instanceType, providerID string, cpuCap, memCap int64, hourlyCost float64, labels map[string]string) error {
+	var labelsJSON []byte
+	if len(labels) > 0 {
+		var err error
+		labelsJSON, err = json.Marshal(labels)
+		if err != nil {
+			return fmt.Errorf("failed to marshal labels: %w", err)
+		}
+	}
+
+	q := `INSERT INTO node_metrics (time, tenant_id, cluster_name, node_name, instance_type, provider_id, cpu_capacity, memory_capacity, hourly_cost_usd, labels) VALUES ($1,$2,$3,$4,$5,NULLIF($6,''),$7,$8,$9,$10)`
+	_, err := db.pool.Exec(ctx, q, t, tenantID, cluster, node, instanceType, providerID, cpuCap, memCap, hourlyCost, labelsJSON)
 	return err
 }
@@ -67,8 +67,8 @@ func (w *TimescaleServiceWrapper) InsertPodMetric(ctx context.Context, timeStamp
 }
 
 // InsertNodeMetric inserts a node metric.
-func (w *TimescaleServiceWrapper) InsertNodeMetric(ctx context.Context, t time.Time, tenantID int64, cluster, node, instanceType string, cpuCap, memCap int64, hourlyCost float64) error {
-	return w.TimescaleDB.InsertNodeMetric(ctx, t, tenantID, cluster, node, instanceType, cpuCap, memCap, hourlyCost)
+func (w *TimescaleServiceWrapper) InsertNodeMetric(ctx context.Context, t time.Time, tenantID int64, cluster, node, instanceType, providerID string, cpuCap, memCap int64, hourlyCost float64) error {
+	return w.TimescaleDB.InsertNodeMetric(ctx, t, tenantID, cluster, node, instanceType, providerID, cpuCap, memCap, hourlyCost)
 }
 
 // Health checks the health of the TimescaleDB database.
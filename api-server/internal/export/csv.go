@@ -0,0 +1,72 @@
+package export
+
+import (
+	"encoding/csv"
+	"io"
+	"strconv"
+	"time"
+)
+
+// csvFlushRows is how many rows are buffered before they are flushed to the output
+const csvFlushRows = 500
+
+type csvWriter struct {
+	w       *csv.Writer
+	columns []Column
+	record  []string
+	pending int
+}
+
+func newCSVWriter(w io.Writer, columns []Column) (*csvWriter, error) {
+	cw := &csvWriter{w: csv.NewWriter(w), columns: columns, record: make([]string, len(columns))}
+	for i, col := range columns {
+		cw.record[i] = col.Name
+	}
+	if err := cw.w.Write(cw.record); err != nil {
+		return nil, err
+	}
+	return cw, nil
+}
+
+func (cw *csvWriter) WriteRow(values ...interface{}) error {
+	if err := checkRow(cw.columns, values); err != nil {
+		return err
+	}
+	for i, col := range cw.columns {
+		cw.record[i] = formatText(col.Type, values[i])
+	}
+	if err := cw.w.Write(cw.record); err != nil {
+		return err
+	}
+	if cw.pending++; cw.pending >= csvFlushRows {
+		cw.pending = 0
+		cw.w.Flush()
+		return cw.w.Error()
+	}
+	return nil
+}
+
+func (cw *csvWriter) Close() error {
+	cw.w.Flush()
+	return cw.w.Error()
+}
+
+// formatText formats a value as text: RFC3339 times (empty when zero) and shortest
+// round-tripping numbers
+func formatText(typ ColumnType, v interface{}) string {
+	switch typ {
+	case Float:
+		return strconv.FormatFloat(toFloat(v), 'f', -1, 64)
+	case Int:
+		return strconv.FormatInt(toInt(v), 10)
+	case Time:
+		t := toTime(v)
+		if t.IsZero() {
+			return ""
+		}
+		return t.UTC().Format(time.RFC3339)
+	case Bool:
+		return strconv.FormatBool(toBool(v))
+	}
+	return toString(v)
+}
@@ -0,0 +1,138 @@
+// Package export streams tabular rows as CSV, XLSX or Parquet. Writers encode rows as they
+// are written: CSV and XLSX hold no rows in memory, Parquet buffers one row group.
+package export
+
+import (
+	"fmt"
+	"io"
+	"strings"
+	"time"
+)
+
+// Export formats
+const (
+	FormatCSV     = "csv"
+	FormatXLSX    = "xlsx"
+	FormatParquet = "parquet"
+)
+
+// ColumnType is the type of a column's values
+type ColumnType int
+
+const (
+	String ColumnType = iota // string
+	Float                    // float64
+	Int                      // int or int64
+	Time                     // time.Time
+	Bool                     // bool
+)
+
+// Column describes one column of an export
+type Column struct {
+	Name string
+	Type ColumnType
+}
+
+// Writer writes rows of an export; values are given in column order with the Go types of
+// the column types. Close must be called to complete the output.
+type Writer interface {
+	WriteRow(values ...interface{}) error
+	Close() error
+}
+
+// NewWriter creates a writer of the format streaming to w
+func NewWriter(format string, w io.Writer, columns []Column) (Writer, error) {
+	switch strings.ToLower(format) {
+	case FormatCSV:
+		return newCSVWriter(w, columns)
+	case FormatXLSX:
+		return newXLSXWriter(w, columns)
+	case FormatParquet:
+		return newParquetWriter(w, columns), nil
+	}
+	return nil, fmt.Errorf("unsupported export format: %s (expected csv, xlsx or parquet)", format)
+}
+
+// ValidFormat reports whether format is a supported export format
+func ValidFormat(format string) bool {
+	switch strings.ToLower(format) {
+	case FormatCSV, FormatXLSX, FormatParquet:
+		return true
+	}
+	return false
+}
+
+// ContentType returns the MIME type of a format
+func ContentType(format string) string {
+	switch strings.ToLower(format) {
+	case FormatXLSX:
+		return "application/vnd.openxmlformats-officedocument.spreadsheetml.sheet"
+	case FormatParquet:
+		return "application/vnd.apache.parquet"
+	}
+	return "text/csv; charset=utf-8"
+}
+
+// checkRow verifies a row has one value per column
+func checkRow(columns []Column, values []interface{}) error {
+	if len(values) != len(columns) {
+		return fmt.Errorf("row has %d values, expected %d", len(values), len(columns))
+	}
+	return nil
+}
+
+// toFloat, toInt, toTime, toBool and toString convert a value to its column type; a nil or
+// mismatched value yields the zero value
+func toFloat(v interface{}) float64 {
+	switch n := v.(type) {
+	case float64:
+		return n
+	case float32:
+		return float64(n)
+	case int:
+		return float64(n)
+	case int64:
+		return float64(n)
+	}
+	return 0
+}
+
+func toInt(v interface{}) int64 {
+	switch n := v.(type) {
+	case int:
+		return int64(n)
+	case int64:
+		return n
+	case int32:
+		return int64(n)
+	case uint:
+		return int64(n)
+	case float64:
+		return int64(n)
+	}
+	return 0
+}
+
+func toTime(v interface{}) time.Time {
+	if t, ok := v.(time.Time); ok {
+		return t
+	}
+	return time.Time{}
+}
+
+func toBool(v interface{}) bool {
+	b, _ := v.(bool)
+	return b
+}
+
+func toString(v interface{}) string {
+	switch s := v.(type) {
+	case nil:
+		return ""
+	case string:
+		return s
+	case fmt.Stringer:
+		return s.String()
+	}
+	return fmt.Sprint(v)
+}
@@ -0,0 +1,218 @@
+package export
+
+import (
+	"archive/zip"
+	"bytes"
+	"encoding/binary"
+	"encoding/xml"
+	"io"
+	"math"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+var testColumns = []Column{
+	{Name: "window_start", Type: Time},
+	{Name: "name", Type: String},
+	{Name: "total_cost", Type: Float},
+	{Name: "pod_count", Type: Int},
+	{Name: "idle", Type: Bool},
+}
+
+var testStart = time.Date(2024, 5, 1, 0, 0, 0, 0, time.UTC)
+
+func writeTestRows(t *testing.T, format string, rows int) []byte {
+	var buf bytes.Buffer
+	w, err := NewWriter(format, &buf, testColumns)
+	require.NoError(t, err)
+	for i := 0; i < rows; i++ {
+		require.NoError(t, w.WriteRow(testStart, "ns-"+string(rune('a'+i%26)), float64(i)+0.25, i, i%3 == 0))
+	}
+	require.NoError(t, w.Close())
+	return buf.Bytes()
+}
+
+func TestCSVWriter(t *testing.T) {
+	out := writeTestRows(t, FormatCSV, 2)
+	assert.Equal(t, "window_start,name,total_cost,pod_count,idle\n"+
+		"2024-05-01T00:00:00Z,ns-a,0.25,0,true\n"+
+		"2024-05-01T00:00:00Z,ns-b,1.25,1,false\n", string(out))
+
+	var buf bytes.Buffer
+	w, err := NewWriter(FormatCSV, &buf, testColumns)
+	require.NoError(t, err)
+	assert.Error(t, w.WriteRow("too", "few"))
+
+	_, err = NewWriter("json", &buf, testColumns)
+	assert.Error(t, err)
+}
+
+func TestXLSXWriter(t *testing.T) {
+	out := writeTestRows(t, FormatXLSX, 3)
+
+	zr, err := zip.NewReader(bytes.NewReader(out), int64(len(out)))
+	require.NoError(t, err)
+	files := map[string]*zip.File{}
+	for _, f := range zr.File {
+		files[f.Name] = f
+	}
+	for _, name := range []string{"[Content_Types].xml", "_rels/.rels", "xl/workbook.xml", "xl/styles.xml", "xl/worksheets/sheet1.xml"} {
+		require.Contains(t, files, name)
+	}
+
+	rc, err := files["xl/worksheets/sheet1.xml"].Open()
+	require.NoError(t, err)
+	defer rc.Close()
+	var sheet struct {
+		Rows []struct {
+			R     string `xml:"r,attr"`
+			Cells []struct {
+				Ref    string `xml:"r,attr"`
+				Type   string `xml:"t,attr"`
+				Value  string `xml:"v"`
+				Inline string `xml:"is>t"`
+			} `xml:"c"`
+		} `xml:"sheetData>row"`
+	}
+	require.NoError(t, xml.NewDecoder(rc).Decode(&sheet))
+	require.Len(t, sheet.Rows, 4)
+	assert.Equal(t, "window_start", sheet.Rows[0].Cells[0].Inline)
+	row := sheet.Rows[2].Cells
+	assert.Equal(t, "45413", row[0].Value) // 2024-05-01 as an Excel serial date
+	assert.Equal(t, "ns-b", row[1].Inline)
+	assert.Equal(t, "1.25", row[2].Value)
+	assert.Equal(t, "C3", row[2].Ref)
+	assert.Equal(t, "b", row[4].Type)
+
+	assert.Equal(t, "AA", xlsxColumnName(26))
+	assert.Equal(t, "AZ", xlsxColumnName(51))
+}
+
+func TestParquetWriter(t *testing.T) {
+	rows := parquetRowGroupRows + 5
+	out := writeTestRows(t, FormatParquet, rows)
+
+	require.Equal(t, "PAR1", string(out[:4]))
+	require.Equal(t, "PAR1", string(out[len(out)-4:]))
+	footerLen := int(binary.LittleEndian.Uint32(out[len(out)-8:]))
+	meta := decodeThrift(t, bytes.NewReader(out[len(out)-8-footerLen:len(out)-8]))
+
+	assert.Equal(t, int64(rows), meta[3])
+	schema := meta[2].([]interface{})
+	require.Len(t, schema, len(testColumns)+1)
+	assert.Equal(t, int64(len(testColumns)), schema[0].(map[int16]interface{})[5])
+	nameCol := schema[2].(map[int16]interface{})
+	assert.Equal(t, "name", string(nameCol[4].([]byte)))
+	assert.Equal(t, int64(parquetByteArray), nameCol[1])
+	assert.Equal(t, int64(parquetTimestampMillis), schema[1].(map[int16]interface{})[6])
+
+	groups := meta[4].([]interface{})
+	require.Len(t, groups, 2)
+	second := groups[1].(map[int16]interface{})
+	assert.Equal(t, int64(5), second[3])
+
+	// Read back the second row group's name and total_cost pages
+	chunks := second[1].([]interface{})
+	readPage := func(col int) []byte {
+		md := chunks[col].(map[int16]interface{})[3].(map[int16]interface{})
+		r := bytes.NewReader(out[md[9].(int64):])
+		header := decodeThrift(t, r)
+		page := make([]byte, header[3].(int64))
+		_, err := io.ReadFull(r, page)
+		require.NoError(t, err)
+		return page
+	}
+
+	names := readPage(1)
+	var got []string
+	for len(names) > 0 {
+		n := binary.LittleEndian.Uint32(names)
+		got = append(got, string(names[4:4+n]))
+		names = names[4+n:]
+	}
+	require.Len(t, got, 5)
+	assert.Equal(t, "ns-"+string(rune('a'+parquetRowGroupRows%26)), got[0])
+
+	costs := readPage(2)
+	require.Len(t, costs, 5*8)
+	assert.Equal(t, float64(parquetRowGroupRows)+0.25, math.Float64frombits(binary.LittleEndian.Uint64(costs)))
+
+	idle := readPage(4)
+	require.Len(t, idle, 1)
+	// Rows 10000..10004: multiples of 3 are 10002 (bit 2)
+	assert.Equal(t, byte(1<<2), idle[0])
+}
+
+// decodeThrift decodes a compact protocol struct into field id -> value (int64, float64,
+// []byte, []interface{} or nested map)
+func decodeThrift(t *testing.T, r *bytes.Reader) map[int16]interface{} {
+	t.Helper()
+	fields := map[int16]interface{}{}
+	var last int16
+	for {
+		b, err := r.ReadByte()
+		require.NoError(t, err)
+		if b == 0 {
+			return fields
+		}
+		typ := b & 0x0F
+		if delta := int16(b >> 4); delta != 0 {
+			last += delta
+		} else {
+			last = int16(unzigzag(readUvarint(t, r)))
+		}
+		fields[last] = decodeThriftValue(t, r, typ)
+	}
+}
+
+func decodeThriftValue(t *testing.T, r *bytes.Reader, typ byte) interface{} {
+	switch typ {
+	case 1:
+		return true
+	case 2:
+		return false
+	case thriftI32, thriftI64:
+		return unzigzag(readUvarint(t, r))
+	case thriftBinary:
+		b := make([]byte, readUvarint(t, r))
+		_, err := io.ReadFull(r, b)
+		require.NoError(t, err)
+		return b
+	case thriftList:
+		h, err := r.ReadByte()
+		require.NoError(t, err)
+		size := int(h >> 4)
+		if size == 15 {
+			size = int(readUvarint(t, r))
+		}
+		list := make([]interface{}, size)
+		for i := range list {
+			list[i] = decodeThriftValue(t, r, h&0x0F)
+		}
+		return list
+	case thriftStruct:
+		return decodeThrift(t, r)
+	}
+	t.Fatalf("unexpected thrift type %d", typ)
+	return nil
+}
+
+func readUvarint(t *testing.T, r *bytes.Reader) uint64 {
+	v, err := binary.ReadUvarint(r)
+	require.NoError(t, err)
+	return v
+}
+
+func unzigzag(v uint64) int64 {
+	return int64(v>>1) ^ -int64(v&1)
+}
+
+func TestContentType(t *testing.T) {
+	assert.True(t, strings.HasPrefix(ContentType(FormatCSV), "text/csv"))
+	assert.True(t, ValidFormat("XLSX"))
+	assert.False(t, ValidFormat("json"))
+}
@@ -0,0 +1,225 @@
+package export
+
+import (
+	"encoding/binary"
+	"io"
+	"math"
+)
+
+// parquetRowGroupRows is the number of rows buffered per row group
+const parquetRowGroupRows = 10000
+
+// Parquet physical types, converted types and enums
+const (
+	parquetBoolean   = 0
+	parquetInt64     = 2
+	parquetDouble    = 5
+	parquetByteArray = 6
+
+	parquetUTF8            = 0
+	parquetTimestampMillis = 9
+
+	parquetRequired     = 0
+	parquetPlain        = 0
+	parquetRLE          = 3
+	parquetUncompressed = 0
+	parquetDataPage     = 0
+)
+
+var parquetMagic = []byte("PAR1")
+
+// parquetWriter writes a Parquet file of required (non-null) columns. Rows are buffered
+// column by column, PLAIN encoded, and written as one uncompressed data page per column per
+// row group; the footer is written on Close.
+type parquetWriter struct {
+	w         io.Writer
+	offset    int64
+	columns   []Column
+	pages     [][]byte // encoded values of the current row group, per column
+	rows      int      // rows in the current row group
+	bools     []int    // bits used in the last byte of boolean columns
+	rowGroups []parquetRowGroup
+	numRows   int64
+	err       error
+}
+
+type parquetRowGroup struct {
+	numRows int64
+	chunks  []parquetChunk
+}
+
+type parquetChunk struct {
+	offset int64
+	size   int64
+	values int64
+}
+
+func newParquetWriter(w io.Writer, columns []Column) *parquetWriter {
+	pw := &parquetWriter{
+		w:       w,
+		columns: columns,
+		pages:   make([][]byte, len(columns)),
+		bools:   make([]int, len(columns)),
+	}
+	pw.write(parquetMagic)
+	return pw
+}
+
+func (pw *parquetWriter) write(b []byte) {
+	if pw.err != nil {
+		return
+	}
+	n, err := pw.w.Write(b)
+	pw.offset += int64(n)
+	pw.err = err
+}
+
+func (pw *parquetWriter) WriteRow(values ...interface{}) error {
+	if err := checkRow(pw.columns, values); err != nil {
+		return err
+	}
+	for i, col := range pw.columns {
+		page := pw.pages[i]
+		switch col.Type {
+		case Float:
+			page = binary.LittleEndian.AppendUint64(page, math.Float64bits(toFloat(values[i])))
+		case Int:
+			page = binary.LittleEndian.AppendUint64(page, uint64(toInt(values[i])))
+		case Time:
+			var millis int64
+			if t := toTime(values[i]); !t.IsZero() {
+				millis = t.UnixMilli()
+			}
+			page = binary.LittleEndian.AppendUint64(page, uint64(millis))
+		case Bool:
+			// Bit-packed, least significant bit first
+			if pw.bools[i] == 0 {
+				page = append(page, 0)
+			}
+			if toBool(values[i]) {
+				page[len(page)-1] |= 1 << pw.bools[i]
+			}
+			pw.bools[i] = (pw.bools[i] + 1) % 8
+		default:
+			s := toString(values[i])
+			page = binary.LittleEndian.AppendUint32(page, uint32(len(s)))
+			page = append(page, s...)
+		}
+		pw.pages[i] = page
+	}
+
+	pw.rows++
+	if pw.rows >= parquetRowGroupRows {
+		pw.flushRowGroup()
+	}
+	return pw.err
+}
+
+// flushRowGroup writes the buffered rows as a row group
+func (pw *parquetWriter) flushRowGroup() {
+	if pw.rows == 0 {
+		return
+	}
+	group := parquetRowGroup{numRows: int64(pw.rows)}
+	for i, page := range pw.pages {
+		var header thriftWriter
+		header.beginStruct(0, false)
+		header.i32(1, parquetDataPage)
+		header.i32(2, int32(len(page)))
+		header.i32(3, int32(len(page)))
+		header.beginStruct(5, false)
+		header.i32(1, int32(pw.rows))
+		header.i32(2, parquetPlain)
+		header.i32(3, parquetRLE)
+		header.i32(4, parquetRLE)
+		header.endStruct()
+		header.endStruct()
+
+		chunk := parquetChunk{offset: pw.offset, size: int64(len(header.buf) + len(page)), values: int64(pw.rows)}
+		pw.write(header.buf)
+		pw.write(page)
+		group.chunks = append(group.chunks, chunk)
+
+		pw.pages[i] = page[:0]
+		pw.bools[i] = 0
+	}
+	pw.rowGroups = append(pw.rowGroups, group)
+	pw.numRows += int64(pw.rows)
+	pw.rows = 0
+}
+
+func (pw *parquetWriter) Close() error {
+	pw.flushRowGroup()
+
+	var meta thriftWriter
+	meta.beginStruct(0, false)
+	meta.i32(1, 1) // version
+
+	meta.listHeader(2, thriftStruct, len(pw.columns)+1)
+	meta.beginStruct(0, true)
+	meta.string(4, "schema")
+	meta.i32(5, int32(len(pw.columns)))
+	meta.endStruct()
+	for _, col := range pw.columns {
+		physical, converted := parquetType(col.Type)
+		meta.beginStruct(0, true)
+		meta.i32(1, physical)
+		meta.i32(3, parquetRequired)
+		meta.string(4, col.Name)
+		if converted >= 0 {
+			meta.i32(6, converted)
+		}
+		meta.endStruct()
+	}
+
+	meta.i64(3, pw.numRows)
+
+	meta.listHeader(4, thriftStruct, len(pw.rowGroups))
+	for _, group := range pw.rowGroups {
+		meta.beginStruct(0, true)
+		meta.listHeader(1, thriftStruct, len(group.chunks))
+		var groupSize int64
+		for i, chunk := range group.chunks {
+			physical, _ := parquetType(pw.columns[i].Type)
+			meta.beginStruct(0, true)
+			meta.i64(2, chunk.offset)
+			meta.beginStruct(3, false)
+			meta.i32(1, physical)
+			meta.i32List(2, []int32{parquetPlain, parquetRLE})
+			meta.stringList(3, []string{pw.columns[i].Name})
+			meta.i32(4, parquetUncompressed)
+			meta.i64(5, chunk.values)
+			meta.i64(6, chunk.size)
+			meta.i64(7, chunk.size)
+			meta.i64(9, chunk.offset)
+			meta.endStruct()
+			meta.endStruct()
+			groupSize += chunk.size
+		}
+		meta.i64(2, groupSize)
+		meta.i64(3, group.numRows)
+		meta.endStruct()
+	}
+	meta.string(6, "k8s-cost-api-server")
+	meta.endStruct()
+
+	pw.write(meta.buf)
+	pw.write(binary.LittleEndian.AppendUint32(nil, uint32(len(meta.buf))))
+	pw.write(parquetMagic)
+	return pw.err
+}
+
+// parquetType returns the physical and converted type (-1 for none) of a column type
+func parquetType(typ ColumnType) (int32, int32) {
+	switch typ {
+	case Float:
+		return parquetDouble, -1
+	case Int:
+		return parquetInt64, -1
+	case Time:
+		return parquetInt64, parquetTimestampMillis
+	case Bool:
+		return parquetBoolean, -1
+	}
+	return parquetByteArray, parquetUTF8
+}
@@ -0,0 +1,97 @@
+package models
+
+import (
+	"database/sql/driver"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/lib/pq"
+)
+
+// Shared cost split modes
+const (
+	ShareSplitEven     = "even"
+	ShareSplitWeighted = "weighted"
+)
+
+// SharedCostRule marks overhead workloads (kube-system, monitoring, ingress, service mesh)
+// whose cost is spread over all other allocations instead of being reported on its own
+type SharedCostRule struct {
+	ID         uint           `gorm:"primaryKey" json:"id"`
+	TenantID   uint           `gorm:"column:tenant_id;not null" json:"tenant_id"`
+	Name       string         `gorm:"column:name;size:100;not null" json:"name"`
+	Namespaces pq.StringArray `gorm:"column:namespaces;type:text[]" json:"namespaces"`
+	Labels     pq.StringArray `gorm:"column:labels;type:text[]" json:"labels"` // "key=value" or "key"
+	Split      string         `gorm:"column:split;size:20;default:weighted" json:"split"`
+	Enabled    bool           `gorm:"column:enabled;default:true" json:"enabled"`
+	CreatedAt  time.Time      `gorm:"column:created_at;autoCreateTime" json:"created_at"`
+	UpdatedAt  time.Time      `gorm:"column:updated_at;autoUpdateTime" json:"updated_at"`
+}
+
+func (SharedCostRule) TableName() string {
+	return "shared_cost_rules"
+}
+
+// LabelRewrite rewrites the first match of a Postgres regular expression in a label value;
+// Replacement may refer to capture groups as \1 to \9
+type LabelRewrite struct {
+	Pattern     string `json:"pattern"`
+	Replacement string `json:"replacement"`
+}
+
+// LabelRewrites is an ordered list of label value rewrites stored as JSONB
+type LabelRewrites []LabelRewrite
+
+// Value implements driver.Valuer for storing rewrites as JSONB
+func (r LabelRewrites) Value() (driver.Value, error) {
+	if r == nil {
+		return "[]", nil
+	}
+	b, err := json.Marshal(r)
+	if err != nil {
+		return nil, err
+	}
+	return string(b), nil
+}
+
+// Scan implements sql.Scanner for reading rewrites from JSONB
+func (r *LabelRewrites) Scan(value interface{}) error {
+	var data []byte
+	switch v := value.(type) {
+	case nil:
+		*r = LabelRewrites{}
+		return nil
+	case []byte:
+		data = v
+	case string:
+		data = []byte(v)
+	default:
+		return fmt.Errorf("cannot scan %T into LabelRewrites", value)
+	}
+	return json.Unmarshal(data, r)
+}
+
+// VirtualLabelRule derives a virtual pod label at query time, so that allocation by label
+// (aggregate=label:<key>, filter=label:<key>=<value>) is consistent without relabelling
+// workloads. The value is the first source label present, optionally lowercased, passed
+// through the rewrites in order and then the lookup table; pods without a value fall back
+// to their namespace's default and then to Default.
+type VirtualLabelRule struct {
+	ID                uint           `gorm:"primaryKey" json:"id"`
+	TenantID          uint           `gorm:"column:tenant_id;not null" json:"tenant_id"`
+	Key               string         `gorm:"column:key;size:100;not null" json:"key"`         // virtual label key
+	Sources           pq.StringArray `gorm:"column:sources;type:text[]" json:"sources"`       // label keys coalesced in order (default: key)
+	Lowercase         bool           `gorm:"column:lowercase;default:false" json:"lowercase"` // lowercase the source value
+	Rewrites          LabelRewrites  `gorm:"column:rewrites;type:jsonb" json:"rewrites"`
+	Lookup            CostTags       `gorm:"column:lookup;type:jsonb" json:"lookup"`                         // value -> value
+	NamespaceDefaults CostTags       `gorm:"column:namespace_defaults;type:jsonb" json:"namespace_defaults"` // namespace -> value
+	Default           string         `gorm:"column:default_value;size:255" json:"default,omitempty"`
+	Enabled           bool           `gorm:"column:enabled;default:true" json:"enabled"`
+	CreatedAt         time.Time      `gorm:"column:created_at;autoCreateTime" json:"created_at"`
+	UpdatedAt         time.Time      `gorm:"column:updated_at;autoUpdateTime" json:"updated_at"`
+}
+
+func (VirtualLabelRule) TableName() string {
+	return "virtual_label_rules"
+}
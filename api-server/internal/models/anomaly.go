@@ -0,0 +1,156 @@
+package models
+
+import (
+	"database/sql/driver"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/lib/pq"
+)
+
+// Anomaly granularities: the period whose cost is compared with the preceding periods
+const (
+	AnomalyGranularityHour = "hour"
+	AnomalyGranularityDay  = "day"
+)
+
+// Anomaly directions
+const (
+	AnomalySpike = "spike"
+	AnomalyDrop  = "drop"
+)
+
+// Anomaly states: detected anomalies are open until acknowledged or resolved
+const (
+	AnomalyStatusOpen         = "open"
+	AnomalyStatusAcknowledged = "acknowledged"
+	AnomalyStatusResolved     = "resolved"
+)
+
+// Anomaly severities, by how far the cost deviates past the sensitivity threshold
+const (
+	AnomalySeverityLow    = "low"
+	AnomalySeverityMedium = "medium"
+	AnomalySeverityHigh   = "high"
+)
+
+// Anomaly sensitivities and the number of standard deviations from the baseline that
+// flags a period as anomalous
+const (
+	AnomalySensitivityLow    = "low"
+	AnomalySensitivityMedium = "medium"
+	AnomalySensitivityHigh   = "high"
+)
+
+// AnomalyThresholds maps each sensitivity to its z-score threshold
+var AnomalyThresholds = map[string]float64{
+	AnomalySensitivityLow:    4,
+	AnomalySensitivityMedium: 3,
+	AnomalySensitivityHigh:   2,
+}
+
+// AnomalyDriver is a workload (namespace/controller) whose cost change contributed to an
+// anomaly, as a root-cause hint
+type AnomalyDriver struct {
+	Name            string  `json:"name"` // namespace/controller
+	Namespace       string  `json:"namespace"`
+	Controller      string  `json:"controller"`
+	CostUSD         float64 `json:"cost_usd"`
+	BaselineCostUSD float64 `json:"baseline_cost_usd"`
+	ChangeUSD       float64 `json:"change_usd"`
+}
+
+// AnomalyDrivers holds the drivers of an anomaly, stored as JSONB
+type AnomalyDrivers []AnomalyDriver
+
+// Value implements driver.Valuer for storing drivers as JSONB
+func (d AnomalyDrivers) Value() (driver.Value, error) {
+	if d == nil {
+		return "[]", nil
+	}
+	b, err := json.Marshal(d)
+	if err != nil {
+		return nil, err
+	}
+	return string(b), nil
+}
+
+// Scan implements sql.Scanner for reading drivers from JSONB
+func (d *AnomalyDrivers) Scan(value interface{}) error {
+	var data []byte
+	switch v := value.(type) {
+	case nil:
+		*d = AnomalyDrivers{}
+		return nil
+	case []byte:
+		data = v
+	case string:
+		data = []byte(v)
+	default:
+		return fmt.Errorf("cannot scan %T into AnomalyDrivers", value)
+	}
+	return json.Unmarshal(data, d)
+}
+
+// CostAnomaly is a statistically significant spike or drop of the cost of a cluster,
+// namespace or label value in one hour or day, compared with its rolling baseline
+type CostAnomaly struct {
+	ID              uint           `gorm:"primaryKey" json:"id"`
+	TenantID        uint           `gorm:"column:tenant_id;not null" json:"tenant_id"`
+	Granularity     string         `gorm:"column:granularity;size:10;not null" json:"granularity"` // hour, day
+	Dimension       string         `gorm:"column:dimension;size:100;not null" json:"dimension"`    // cluster, namespace, label:<key>
+	Name            string         `gorm:"column:name;size:255;not null" json:"name"`              // cluster name, namespace or label value
+	WindowStart     time.Time      `gorm:"column:window_start;not null" json:"window_start"`
+	WindowEnd       time.Time      `gorm:"column:window_end;not null" json:"window_end"`
+	CostUSD         float64        `gorm:"column:cost_usd;type:decimal(14,4);not null" json:"cost_usd"`
+	BaselineCostUSD float64        `gorm:"column:baseline_cost_usd;type:decimal(14,4);not null" json:"baseline_cost_usd"` // mean of the baseline periods
+	StdDevUSD       float64        `gorm:"column:stddev_usd;type:decimal(14,4);not null" json:"stddev_usd"`
+	ZScore          float64        `gorm:"column:z_score;type:decimal(10,2);not null" json:"z_score"`
+	Direction       string         `gorm:"column:direction;size:10;not null" json:"direction"` // spike, drop
+	Severity        string         `gorm:"column:severity;size:10;not null" json:"severity"`   // low, medium, high
+	Drivers         AnomalyDrivers `gorm:"column:drivers;type:jsonb" json:"drivers"`
+	Status          string         `gorm:"column:status;size:20;not null;default:open" json:"status"`
+	AcknowledgedBy  string         `gorm:"column:acknowledged_by;size:255" json:"acknowledged_by,omitempty"`
+	AcknowledgedAt  *time.Time     `gorm:"column:acknowledged_at" json:"acknowledged_at,omitempty"`
+	ResolvedBy      string         `gorm:"column:resolved_by;size:255" json:"resolved_by,omitempty"`
+	ResolvedAt      *time.Time     `gorm:"column:resolved_at" json:"resolved_at,omitempty"`
+	Note            string         `gorm:"column:note" json:"note,omitempty"`
+	CreatedAt       time.Time      `gorm:"column:created_at;autoCreateTime" json:"created_at"`
+	UpdatedAt       time.Time      `gorm:"column:updated_at;autoUpdateTime" json:"updated_at"`
+}
+
+func (CostAnomaly) TableName() string {
+	return "cost_anomalies"
+}
+
+// AnomalySettings configures anomaly detection for a tenant
+type AnomalySettings struct {
+	TenantID      uint           `gorm:"column:tenant_id;primaryKey" json:"tenant_id"`
+	Enabled       bool           `gorm:"column:enabled;not null" json:"enabled"`
+	Sensitivity   string         `gorm:"column:sensitivity;size:10;not null;default:medium" json:"sensitivity"`   // low, medium, high
+	MinChangeUSD  float64        `gorm:"column:min_change_usd;type:decimal(12,4);not null" json:"min_change_usd"` // ignore smaller changes
+	BaselineDays  int            `gorm:"column:baseline_days;not null;default:14" json:"baseline_days"`           // daily baseline; hourly uses the previous 24 hours
+	Dimensions    pq.StringArray `gorm:"column:dimensions;type:text[]" json:"dimensions"`                         // cluster, namespace, label:<key>
+	Granularities pq.StringArray `gorm:"column:granularities;type:text[]" json:"granularities"`                   // hour, day
+	UpdatedAt     time.Time      `gorm:"column:updated_at;autoUpdateTime" json:"updated_at"`
+}
+
+func (AnomalySettings) TableName() string {
+	return "anomaly_settings"
+}
+
+// AnomalyDetectionRun records that a tenant's period was evaluated, so replicas running the
+// detector evaluate each period once
+type AnomalyDetectionRun struct {
+	ID          uint      `gorm:"primaryKey" json:"id"`
+	TenantID    uint      `gorm:"column:tenant_id;not null" json:"tenant_id"`
+	Granularity string    `gorm:"column:granularity;size:10;not null" json:"granularity"`
+	WindowStart time.Time `gorm:"column:window_start;not null" json:"window_start"`
+	Anomalies   int       `gorm:"column:anomalies;not null;default:0" json:"anomalies"`
+	CreatedAt   time.Time `gorm:"column:created_at;autoCreateTime" json:"created_at"`
+}
+
+func (AnomalyDetectionRun) TableName() string {
+	return "anomaly_detection_runs"
+}
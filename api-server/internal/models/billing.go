@@ -0,0 +1,54 @@
+package models
+
+import "time"
+
+// Billing import statuses
+const (
+	BillingImportCompleted = "completed"
+	BillingImportFailed    = "failed"
+)
+
+// BillingImport records an uploaded cloud billing export (AWS CUR, GCP billing export,
+// Azure cost export) and how much of it could be matched to cluster nodes
+type BillingImport struct {
+	ID            uint          `gorm:"primaryKey" json:"id"`
+	TenantID      uint          `gorm:"column:tenant_id;not null" json:"tenant_id"`
+	Provider      CloudProvider `gorm:"column:provider;size:20;not null" json:"provider"`
+	FileName      string        `gorm:"column:file_name;size:255" json:"file_name"`
+	PeriodStart   *time.Time    `gorm:"column:period_start" json:"period_start,omitempty"`
+	PeriodEnd     *time.Time    `gorm:"column:period_end" json:"period_end,omitempty"`
+	LineItems     int           `gorm:"column:line_items" json:"line_items"`
+	MatchedItems  int           `gorm:"column:matched_items" json:"matched_items"`
+	TotalCost     float64       `gorm:"column:total_cost" json:"total_cost"`         // net cost of all line items
+	MatchedCost   float64       `gorm:"column:matched_cost" json:"matched_cost"`     // net cost attributed to nodes
+	UnmatchedCost float64       `gorm:"column:unmatched_cost" json:"unmatched_cost"` // net cost of non-node resources
+	NodesMatched  int           `gorm:"column:nodes_matched" json:"nodes_matched"`
+	Status        string        `gorm:"column:status;size:20;not null" json:"status"`
+	Error         string        `gorm:"column:error" json:"error,omitempty"`
+	CreatedAt     time.Time     `gorm:"column:created_at;autoCreateTime" json:"created_at"`
+}
+
+func (BillingImport) TableName() string {
+	return "billing_imports"
+}
+
+// ReconciledNodeCost is the actual (invoiced) cost of a node for one day, after
+// reservations, savings plans, discounts and credits
+type ReconciledNodeCost struct {
+	ID          uint      `gorm:"primaryKey" json:"id"`
+	TenantID    uint      `gorm:"column:tenant_id;not null" json:"tenant_id"`
+	ImportID    uint      `gorm:"column:import_id;not null" json:"import_id"`
+	ClusterName string    `gorm:"column:cluster_name;not null" json:"cluster_name"`
+	NodeName    string    `gorm:"column:node_name;not null" json:"node_name"`
+	ResourceID  string    `gorm:"column:resource_id" json:"resource_id"`
+	PeriodStart time.Time `gorm:"column:period_start;not null" json:"period_start"`
+	PeriodEnd   time.Time `gorm:"column:period_end;not null" json:"period_end"`
+	UsageHours  float64   `gorm:"column:usage_hours" json:"usage_hours"`
+	ListCost    float64   `gorm:"column:list_cost" json:"list_cost"` // on-demand equivalent
+	NetCost     float64   `gorm:"column:net_cost" json:"net_cost"`   // after discounts and credits
+	CreatedAt   time.Time `gorm:"column:created_at;autoCreateTime" json:"created_at"`
+}
+
+func (ReconciledNodeCost) TableName() string {
+	return "reconciled_node_costs"
+}
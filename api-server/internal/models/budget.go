@@ -0,0 +1,66 @@
+package models
+
+import (
+	"time"
+
+	"github.com/lib/pq"
+)
+
+// Budget periods
+const (
+	BudgetPeriodMonthly   = "monthly"
+	BudgetPeriodQuarterly = "quarterly"
+)
+
+// Budget alert types: actual spend crossing a threshold, or the projected period-end spend
+// exceeding the budget
+const (
+	BudgetAlertActual   = "actual"
+	BudgetAlertForecast = "forecast"
+)
+
+// DefaultBudgetThresholds are the percentages of the budget that fire alerts by default
+var DefaultBudgetThresholds = []float64{50, 80, 100}
+
+// Budget is a monthly or quarterly spend limit on the allocations matching its filters
+// (cluster, namespace, label or any allocation filter; no filters = the whole tenant)
+type Budget struct {
+	ID              uint            `gorm:"primaryKey" json:"id"`
+	TenantID        uint            `gorm:"column:tenant_id;not null" json:"tenant_id"`
+	Name            string          `gorm:"column:name;size:100;not null" json:"name"`
+	Period          string          `gorm:"column:period;size:20;not null" json:"period"` // monthly, quarterly
+	Amount          float64         `gorm:"column:amount;type:decimal(14,2);not null" json:"amount"`
+	Currency        string          `gorm:"column:currency;size:3;not null" json:"currency"`
+	Filters         pq.StringArray  `gorm:"column:filters;type:text[]" json:"filters"`         // allocation filters, e.g. "namespace:web", "label:team=data"
+	Thresholds      pq.Float64Array `gorm:"column:thresholds;type:float8[]" json:"thresholds"` // percent of the amount
+	AlertOnForecast bool            `gorm:"column:alert_on_forecast;not null" json:"alert_on_forecast"`
+	LastSpent       float64         `gorm:"column:last_spent;type:decimal(14,2)" json:"last_spent"`       // as of the last evaluation
+	LastForecast    float64         `gorm:"column:last_forecast;type:decimal(14,2)" json:"last_forecast"` // projected period-end spend
+	LastEvaluatedAt *time.Time      `gorm:"column:last_evaluated_at" json:"last_evaluated_at,omitempty"`
+	CreatedAt       time.Time       `gorm:"column:created_at;autoCreateTime" json:"created_at"`
+	UpdatedAt       time.Time       `gorm:"column:updated_at;autoUpdateTime" json:"updated_at"`
+}
+
+func (Budget) TableName() string {
+	return "budgets"
+}
+
+// BudgetAlert records that a budget crossed a threshold (or was projected to overrun) in a
+// period. Each threshold fires once per period.
+type BudgetAlert struct {
+	ID          uint      `gorm:"primaryKey" json:"id"`
+	TenantID    uint      `gorm:"column:tenant_id;not null" json:"tenant_id"`
+	BudgetID    uint      `gorm:"column:budget_id;not null" json:"budget_id"`
+	PeriodStart time.Time `gorm:"column:period_start;not null" json:"period_start"`
+	Type        string    `gorm:"column:type;size:20;not null" json:"type"`                     // actual, forecast
+	Threshold   float64   `gorm:"column:threshold;type:decimal(6,2);not null" json:"threshold"` // percent of the amount
+	Amount      float64   `gorm:"column:amount;type:decimal(14,2);not null" json:"amount"`
+	Spent       float64   `gorm:"column:spent;type:decimal(14,2);not null" json:"spent"`
+	Forecast    float64   `gorm:"column:forecast;type:decimal(14,2);not null" json:"forecast"`
+	Currency    string    `gorm:"column:currency;size:3;not null" json:"currency"`
+	CreatedAt   time.Time `gorm:"column:created_at;autoCreateTime" json:"created_at"`
+}
+
+func (BudgetAlert) TableName() string {
+	return "budget_alerts"
+}
@@ -0,0 +1,79 @@
+package models
+
+import (
+	"time"
+
+	"github.com/lib/pq"
+)
+
+// Commitment types
+const (
+	CommitmentReservedInstance = "reserved_instance"
+	CommitmentSavingsPlan      = "savings_plan"
+	CommitmentCommittedUse     = "committed_use" // GCP committed use discount
+)
+
+// Commitment is a reserved instance, savings plan or committed use discount purchased by
+// a tenant. Its upfront and recurring cost is amortized over the term and its discount is
+// applied to the nodes it covers.
+type Commitment struct {
+	ID               uint           `gorm:"primaryKey" json:"id"`
+	TenantID         uint           `gorm:"column:tenant_id;not null" json:"tenant_id"`
+	Name             string         `gorm:"column:name;size:100;not null" json:"name"`
+	Provider         CloudProvider  `gorm:"column:provider;size:20;not null" json:"provider"`
+	Type             string         `gorm:"column:type;size:30;not null" json:"type"`
+	Term             PricingTier    `gorm:"column:term;size:20;not null" json:"term"` // reserved_1yr, reserved_3yr
+	UpfrontCost      float64        `gorm:"column:upfront_cost;type:decimal(14,4);default:0" json:"upfront_cost"`
+	HourlyCommitment float64        `gorm:"column:hourly_commitment;type:decimal(12,6);default:0" json:"hourly_commitment"` // recurring $/hour
+	DiscountPercent  float64        `gorm:"column:discount_percent;type:decimal(5,2);default:0" json:"discount_percent"`    // vs on-demand; 0 = provider default
+	InstanceFamilies pq.StringArray `gorm:"column:instance_families;type:text[]" json:"instance_families"`                  // e.g. "m5", "n2"; empty = any
+	Clusters         pq.StringArray `gorm:"column:clusters;type:text[]" json:"clusters"`                                    // empty = all clusters
+	StartDate        time.Time      `gorm:"column:start_date;not null" json:"start_date"`
+	EndDate          time.Time      `gorm:"column:end_date;not null" json:"end_date"`
+	CreatedAt        time.Time      `gorm:"column:created_at;autoCreateTime" json:"created_at"`
+	UpdatedAt        time.Time      `gorm:"column:updated_at;autoUpdateTime" json:"updated_at"`
+}
+
+func (Commitment) TableName() string {
+	return "commitments"
+}
+
+// TermYears returns the commitment term in years
+func (c *Commitment) TermYears() int {
+	if c.Term == TierReserved3Yr {
+		return 3
+	}
+	return 1
+}
+
+// AmortizedHourlyCost returns the upfront cost spread over the term plus the recurring hourly commitment
+func (c *Commitment) AmortizedHourlyCost() float64 {
+	hours := c.EndDate.Sub(c.StartDate).Hours()
+	if hours <= 0 {
+		return c.HourlyCommitment
+	}
+	return c.UpfrontCost/hours + c.HourlyCommitment
+}
+
+// DefaultCommitmentDiscount returns the typical discount of a commitment term versus
+// on-demand for a provider, derived from the default CPU rates
+func DefaultCommitmentDiscount(provider CloudProvider, term PricingTier) float64 {
+	rates, ok := DefaultPricingRates[provider]
+	if !ok {
+		rates = DefaultPricingRates[ProviderAWS]
+	}
+	onDemand := rates["cpu_on_demand"]
+
+	suffix := "1yr"
+	if term == TierReserved3Yr {
+		suffix = "3yr"
+	}
+	committed, ok := rates["cpu_reserved_"+suffix]
+	if !ok {
+		committed, ok = rates["cpu_committed_"+suffix]
+	}
+	if !ok || onDemand <= 0 {
+		committed, onDemand = DefaultPricingRates[ProviderAWS]["cpu_reserved_"+suffix], DefaultPricingRates[ProviderAWS]["cpu_on_demand"]
+	}
+	return 1 - committed/onDemand
+}
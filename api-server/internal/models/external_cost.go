@@ -0,0 +1,61 @@
+package models
+
+import (
+	"database/sql/driver"
+	"encoding/json"
+	"fmt"
+	"time"
+)
+
+// CostTags holds the tags of an external cost line item (team, namespace, cluster, ...)
+type CostTags map[string]string
+
+// Value implements driver.Valuer for storing tags as JSONB
+func (t CostTags) Value() (driver.Value, error) {
+	if t == nil {
+		return "{}", nil
+	}
+	b, err := json.Marshal(t)
+	if err != nil {
+		return nil, err
+	}
+	return string(b), nil
+}
+
+// Scan implements sql.Scanner for reading tags from JSONB
+func (t *CostTags) Scan(value interface{}) error {
+	var data []byte
+	switch v := value.(type) {
+	case nil:
+		*t = CostTags{}
+		return nil
+	case []byte:
+		data = v
+	case string:
+		data = []byte(v)
+	default:
+		return fmt.Errorf("cannot scan %T into CostTags", value)
+	}
+	return json.Unmarshal(data, t)
+}
+
+// ExternalCost is a cost line item from outside the cluster (managed databases, object
+// storage, load balancers, SaaS licences) that is merged into allocations by its tags
+type ExternalCost struct {
+	ID          uint      `gorm:"primaryKey" json:"id"`
+	TenantID    uint      `gorm:"column:tenant_id;not null" json:"tenant_id"`
+	StartTime   time.Time `gorm:"column:start_time;not null" json:"start_time"`
+	EndTime     time.Time `gorm:"column:end_time;not null" json:"end_time"`
+	Amount      float64   `gorm:"column:amount;not null" json:"amount"`
+	Currency    string    `gorm:"column:currency;size:3;default:USD" json:"currency"` // ISO 4217; converted to USD with the tenant's exchange rates when merged
+	Provider    string    `gorm:"column:provider;size:50" json:"provider,omitempty"`
+	Service     string    `gorm:"column:service;size:255" json:"service,omitempty"`
+	Description string    `gorm:"column:description" json:"description,omitempty"`
+	Tags        CostTags  `gorm:"column:tags;type:jsonb" json:"tags"`
+	Source      string    `gorm:"column:source;size:255" json:"source,omitempty"` // upload batch / file name
+	CreatedAt   time.Time `gorm:"column:created_at;autoCreateTime" json:"created_at"`
+}
+
+func (ExternalCost) TableName() string {
+	return "external_costs"
+}
@@ -21,11 +21,12 @@ type PricingPlan struct {
 }
 
 type Tenant struct {
-	ID           uint   `gorm:"primaryKey"`
-	Name         string
-	PricingPlan  string `gorm:"column:pricing_plan;default:Starter"` // 'Starter', 'Premium', 'Business'
-	GrafanaOrgID int    `gorm:"column:grafana_org_id"`               // Grafana organization ID for OAuth mapping
-	CreatedAt    time.Time
+	ID              uint   `gorm:"primaryKey"`
+	Name            string
+	PricingPlan     string `gorm:"column:pricing_plan;default:Starter"`     // 'Starter', 'Premium', 'Business'
+	GrafanaOrgID    int    `gorm:"column:grafana_org_id"`                   // Grafana organization ID for OAuth mapping
+	DisplayCurrency string `gorm:"column:display_currency;default:USD"`     // Default reporting currency (ISO 4217); costs are stored in USD
+	CreatedAt       time.Time
 }
 
 type User struct {
@@ -0,0 +1,121 @@
+package models
+
+import (
+	"database/sql/driver"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/lib/pq"
+)
+
+// Notification channel types
+const (
+	ChannelSlack   = "slack"
+	ChannelTeams   = "teams"
+	ChannelWebhook = "webhook"
+	ChannelEmail   = "email"
+)
+
+// Notification events a channel can subscribe to
+const (
+	EventAnomaly        = "anomaly"
+	EventBudget         = "budget"
+	EventRecommendation = "recommendation"
+	EventReport         = "report" // sent to the channels a scheduled report selects
+	EventTest           = "test"
+)
+
+// Notification delivery statuses
+const (
+	DeliverySent   = "sent"
+	DeliveryFailed = "failed"
+)
+
+// NotificationChannelConfig holds a channel's destination; which fields apply depends on
+// the channel type. Secret and Password are never returned by the API.
+type NotificationChannelConfig struct {
+	URL         string            `json:"url,omitempty"`     // slack, teams, webhook
+	Secret      string            `json:"secret,omitempty"`  // webhook HMAC signing secret
+	Headers     map[string]string `json:"headers,omitempty"` // webhook
+	SMTPHost    string            `json:"smtp_host,omitempty"`
+	SMTPPort    int               `json:"smtp_port,omitempty"`
+	Username    string            `json:"username,omitempty"`
+	Password    string            `json:"password,omitempty"`
+	From        string            `json:"from,omitempty"`
+	To          []string          `json:"to,omitempty"`
+	ImplicitTLS bool              `json:"implicit_tls,omitempty"` // SMTPS (port 465) instead of STARTTLS
+}
+
+// Value implements driver.Valuer for storing the config as JSONB
+func (c NotificationChannelConfig) Value() (driver.Value, error) {
+	b, err := json.Marshal(c)
+	if err != nil {
+		return nil, err
+	}
+	return string(b), nil
+}
+
+// Scan implements sql.Scanner for reading the config from JSONB
+func (c *NotificationChannelConfig) Scan(value interface{}) error {
+	var data []byte
+	switch v := value.(type) {
+	case nil:
+		*c = NotificationChannelConfig{}
+		return nil
+	case []byte:
+		data = v
+	case string:
+		data = []byte(v)
+	default:
+		return fmt.Errorf("cannot scan %T into NotificationChannelConfig", value)
+	}
+	return json.Unmarshal(data, c)
+}
+
+// NotificationChannel is a tenant's destination for alert notifications
+type NotificationChannel struct {
+	ID        uint                      `gorm:"primaryKey" json:"id"`
+	TenantID  uint                      `gorm:"column:tenant_id;not null" json:"tenant_id"`
+	Name      string                    `gorm:"column:name;size:100;not null" json:"name"`
+	Type      string                    `gorm:"column:type;size:20;not null" json:"type"` // slack, teams, webhook, email
+	Config    NotificationChannelConfig `gorm:"column:config;type:jsonb" json:"config"`
+	Events    pq.StringArray            `gorm:"column:events;type:text[]" json:"events"` // anomaly, budget, recommendation; empty = all
+	Enabled   bool                      `gorm:"column:enabled;not null" json:"enabled"`
+	CreatedAt time.Time                 `gorm:"column:created_at;autoCreateTime" json:"created_at"`
+	UpdatedAt time.Time                 `gorm:"column:updated_at;autoUpdateTime" json:"updated_at"`
+}
+
+func (NotificationChannel) TableName() string {
+	return "notification_channels"
+}
+
+// Subscribed reports whether the channel receives an event
+func (c *NotificationChannel) Subscribed(event string) bool {
+	if event == EventTest || event == EventReport || len(c.Events) == 0 {
+		return true
+	}
+	for _, e := range c.Events {
+		if e == event {
+			return true
+		}
+	}
+	return false
+}
+
+// NotificationDelivery records one notification sent (or not) to a channel
+type NotificationDelivery struct {
+	ID        uint      `gorm:"primaryKey" json:"id"`
+	TenantID  uint      `gorm:"column:tenant_id;not null" json:"tenant_id"`
+	ChannelID uint      `gorm:"column:channel_id;not null" json:"channel_id"`
+	Event     string    `gorm:"column:event;size:50;not null" json:"event"`
+	Title     string    `gorm:"column:title;size:500;not null" json:"title"`
+	Status    string    `gorm:"column:status;size:20;not null" json:"status"` // sent, failed
+	Attempts  int       `gorm:"column:attempts;not null" json:"attempts"`
+	Error     string    `gorm:"column:error" json:"error,omitempty"`
+	CreatedAt time.Time `gorm:"column:created_at;autoCreateTime" json:"created_at"`
+}
+
+func (NotificationDelivery) TableName() string {
+	return "notification_deliveries"
+}
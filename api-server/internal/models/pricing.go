@@ -1,6 +1,7 @@
 package models
 
 import (
+	"strings"
 	"time"
 )
 
@@ -37,6 +38,15 @@ const (
 	ResourceNetwork ResourceType = "network"
 )
 
+// ResourceUnits is the billing unit of each resource type's rates
+var ResourceUnits = map[ResourceType]string{
+	ResourceCPU:     "core-hour",
+	ResourceMemory:  "gb-hour",
+	ResourceGPU:     "gpu-hour",
+	ResourceStorage: "gb-month",
+	ResourceNetwork: "gb",
+}
+
 // PricingConfig represents a pricing configuration for a cloud provider/region
 type PricingConfig struct {
 	ID        uint          `gorm:"primaryKey" json:"id"`
@@ -48,8 +58,13 @@ type PricingConfig struct {
 	CreatedAt time.Time     `gorm:"column:created_at;autoCreateTime" json:"created_at"`
 	UpdatedAt time.Time     `gorm:"column:updated_at;autoUpdateTime" json:"updated_at"`
 
+	// Price of one core relative to one GB of RAM, used to split whole-node hourly costs
+	// into per-core and per-GB rates (nil = ratio of the config's CPU and memory rates)
+	CPURAMCostRatio *float64 `gorm:"column:cpu_ram_cost_ratio;type:decimal(10,4)" json:"cpu_ram_cost_ratio,omitempty"`
+
 	// Relations
-	Rates []PricingRate `gorm:"foreignKey:ConfigID" json:"rates,omitempty"`
+	Rates     []PricingRate     `gorm:"foreignKey:ConfigID" json:"rates,omitempty"`
+	Discounts []PricingDiscount `gorm:"foreignKey:ConfigID" json:"discounts,omitempty"`
 }
 
 func (PricingConfig) TableName() string {
@@ -74,6 +89,35 @@ func (PricingRate) TableName() string {
 	return "pricing_rates"
 }
 
+// DiscountScope determines which rates a pricing discount applies to
+type DiscountScope string
+
+const (
+	DiscountScopeGlobal         DiscountScope = "global"          // whole bill (EDP, enterprise agreement)
+	DiscountScopeResource       DiscountScope = "resource"        // one resource type, e.g. all memory
+	DiscountScopeInstanceFamily DiscountScope = "instance_family" // one instance family, optionally one resource type
+)
+
+// PricingDiscount is a negotiated discount applied on top of a configuration's base rates.
+// Global discounts apply to every rate and stack with each other; of the resource and
+// instance family discounts matching a rate, the most specific one applies.
+type PricingDiscount struct {
+	ID              uint          `gorm:"primaryKey" json:"id"`
+	ConfigID        uint          `gorm:"column:config_id;not null" json:"config_id"`
+	Name            string        `gorm:"column:name;size:100;not null" json:"name"`
+	Scope           DiscountScope `gorm:"column:scope;size:20;not null" json:"scope"`
+	ResourceType    ResourceType  `gorm:"column:resource_type;size:20" json:"resource_type,omitempty"`
+	InstanceFamily  string        `gorm:"column:instance_family;size:50" json:"instance_family,omitempty"`
+	DiscountPercent float64       `gorm:"column:discount_percent;type:decimal(5,2);not null" json:"discount_percent"`
+	EffectiveFrom   time.Time     `gorm:"column:effective_from;type:date" json:"effective_from"`
+	EffectiveTo     *time.Time    `gorm:"column:effective_to;type:date" json:"effective_to,omitempty"`
+	CreatedAt       time.Time     `gorm:"column:created_at;autoCreateTime" json:"created_at"`
+}
+
+func (PricingDiscount) TableName() string {
+	return "pricing_discounts"
+}
+
 // ClusterPricing maps a cluster to a pricing configuration
 type ClusterPricing struct {
 	ClusterName string    `gorm:"column:cluster_name;primaryKey;size:255" json:"cluster_name"`
@@ -117,8 +161,26 @@ type EffectivePricing struct {
 	Provider CloudProvider `json:"provider"`
 	Region   string        `json:"region,omitempty"`
 
-	// Instance-specific pricing overrides
+	// Config the rates were resolved from (ConfigID 0 for system defaults)
+	ConfigID     uint         `json:"config_id,omitempty"`
+	ConfigName   string       `json:"config_name,omitempty"`
+	ConfigSource ConfigSource `json:"config_source"`
+
+	// Instance type and instance family pricing, keyed by type or family ("m5.xlarge", "m5")
 	InstancePricing map[string]*InstancePrice `json:"instance_pricing,omitempty"`
+
+	// Node pricing overrides, keyed by node name
+	NodePricing map[string]*InstancePrice `json:"node_pricing,omitempty"`
+
+	// Price of one core relative to one GB of RAM, used to split whole-node hourly costs
+	CPURAMCostRatio float64 `json:"cpu_ram_cost_ratio"`
+
+	// List (pre-discount) rates; equal to the rates above when no negotiated discounts apply
+	ListCPUPerCoreHour  float64 `json:"list_cpu_per_core_hour"`
+	ListMemoryPerGBHour float64 `json:"list_memory_per_gb_hour"`
+
+	// Discount of committed tiers (reserved_1yr, reserved_3yr) versus on-demand, from the config's CPU rates
+	TierDiscounts map[PricingTier]float64 `json:"tier_discounts,omitempty"`
 }
 
 // InstancePrice holds pricing for a specific instance type
@@ -127,6 +189,172 @@ type InstancePrice struct {
 	CPUPerCoreHour  float64 `json:"cpu_per_core_hour"`
 	MemoryPerGBHour float64 `json:"memory_per_gb_hour"`
 	HourlyCost      float64 `json:"hourly_cost,omitempty"` // Total hourly cost if known
+
+	// List (pre-discount) rates
+	ListCPUPerCoreHour  float64 `json:"list_cpu_per_core_hour,omitempty"`
+	ListMemoryPerGBHour float64 `json:"list_memory_per_gb_hour,omitempty"`
+}
+
+// ConfigSource identifies how a cluster's pricing config was chosen
+type ConfigSource string
+
+const (
+	ConfigSourceCluster       ConfigSource = "cluster_config" // config assigned to the cluster
+	ConfigSourceTenantDefault ConfigSource = "tenant_default" // the tenant's default config
+	ConfigSourceSystemDefault ConfigSource = "system_default" // built-in rates, no config
+	ConfigSourceProposed      ConfigSource = "proposed"       // what-if config replacing the cluster's config
+)
+
+// PriceSource identifies where a node's rates were resolved from
+type PriceSource string
+
+const (
+	PriceSourceNodeOverride   PriceSource = "node_override"   // node_pricing hourly cost override
+	PriceSourceNodeInstance   PriceSource = "node_instance"   // node_pricing instance type mapping
+	PriceSourceInstanceType   PriceSource = "instance_type"   // rates of the node's exact instance type
+	PriceSourceInstanceFamily PriceSource = "instance_family" // rates of the node's instance family
+	PriceSourceDefault        PriceSource = "default"         // the config's default CPU and memory rates
+)
+
+// SplitHourlyCost splits a whole-node hourly cost into per-core and per-GB rates such that
+// cpuCores x cpuRate + memGB x memRate equals the hourly cost, with one core priced at
+// ratio times one GB. It returns false when the node's capacity is unknown.
+func SplitHourlyCost(hourlyCost, cpuCores, memGB, ratio float64) (cpuRate, memRate float64, ok bool) {
+	if hourlyCost <= 0 || ratio <= 0 || cpuCores*ratio+memGB <= 0 {
+		return 0, 0, false
+	}
+	memRate = hourlyCost / (cpuCores*ratio + memGB)
+	return memRate * ratio, memRate, true
+}
+
+// NodeRates resolves the per-core and per-GB rates of a node: its hourly cost override,
+// else its instance type or family pricing, else the default rates. Whole-node hourly
+// costs are split by the CPU:RAM cost ratio using the node's capacity; when the capacity
+// is unknown the default rates apply.
+func (p *EffectivePricing) NodeRates(nodeName, instanceType string, cpuCores, memGB float64) (cpuRate, memRate float64, source PriceSource) {
+	price, source := p.nodeInstancePrice(nodeName, instanceType)
+	if price != nil {
+		if price.HourlyCost > 0 {
+			if cpuRate, memRate, ok := SplitHourlyCost(price.HourlyCost, cpuCores, memGB, p.CPURAMCostRatio); ok {
+				return cpuRate, memRate, source
+			}
+		} else if price.CPUPerCoreHour > 0 || price.MemoryPerGBHour > 0 {
+			cpuRate, memRate = price.CPUPerCoreHour, price.MemoryPerGBHour
+			if cpuRate == 0 {
+				cpuRate = p.CPUPerCoreHour
+			}
+			if memRate == 0 {
+				memRate = p.MemoryPerGBHour
+			}
+			return cpuRate, memRate, source
+		}
+	}
+	return p.CPUPerCoreHour, p.MemoryPerGBHour, PriceSourceDefault
+}
+
+// NodeListRates returns the list (pre-discount) rates of a node, mirroring NodeRates.
+// Hourly cost overrides are actual costs and have no separate list price.
+func (p *EffectivePricing) NodeListRates(nodeName, instanceType string, cpuCores, memGB float64) (cpuRate, memRate float64) {
+	cpuRate, memRate, source := p.NodeRates(nodeName, instanceType, cpuCores, memGB)
+	if source == PriceSourceDefault {
+		return p.ListRates()
+	}
+	price, _ := p.nodeInstancePrice(nodeName, instanceType)
+	if price == nil || price.HourlyCost > 0 {
+		return cpuRate, memRate
+	}
+	listCPU, listMem := p.ListRates()
+	if price.CPUPerCoreHour > 0 {
+		listCPU = price.ListCPUPerCoreHour
+		if listCPU == 0 {
+			listCPU = price.CPUPerCoreHour
+		}
+	}
+	if price.MemoryPerGBHour > 0 {
+		listMem = price.ListMemoryPerGBHour
+		if listMem == 0 {
+			listMem = price.MemoryPerGBHour
+		}
+	}
+	return listCPU, listMem
+}
+
+// nodeInstancePrice returns the pricing that applies to a node and where it came from
+func (p *EffectivePricing) nodeInstancePrice(nodeName, instanceType string) (*InstancePrice, PriceSource) {
+	if price, ok := p.NodePricing[nodeName]; ok {
+		if price.HourlyCost > 0 {
+			return price, PriceSourceNodeOverride
+		}
+		return price, PriceSourceNodeInstance
+	}
+	if instanceType == "" {
+		return nil, PriceSourceDefault
+	}
+	if price, ok := p.InstancePricing[instanceType]; ok {
+		return price, PriceSourceInstanceType
+	}
+	if price, ok := p.InstancePricing[strings.ToLower(instanceType)]; ok {
+		return price, PriceSourceInstanceType
+	}
+	if price := p.FamilyPrice(instanceType); price != nil {
+		return price, PriceSourceInstanceFamily
+	}
+	return nil, PriceSourceDefault
+}
+
+// FamilyPrice returns the pricing of the longest instance family matching an instance
+// type (see InstanceFamilyMatches), or nil. A family without "*" wins over a wildcard
+// family of the same length.
+func (p *EffectivePricing) FamilyPrice(instanceType string) *InstancePrice {
+	var best *InstancePrice
+	bestRank := 0
+	for family, price := range p.InstancePricing {
+		prefix, wildcard := strings.CutSuffix(strings.TrimSpace(family), "*")
+		rank := 2 * len(prefix)
+		if !wildcard {
+			rank++
+		}
+		if rank > bestRank && InstanceFamilyMatches(instanceType, family) {
+			best, bestRank = price, rank
+		}
+	}
+	return best
+}
+
+// InstanceFamilyMatches reports whether an instance type belongs to a family, ignoring
+// case: "m5" matches "m5.xlarge", "n2" matches "n2-standard-4", and a trailing "*"
+// matches any suffix ("standard_d*" matches "Standard_D4s_v3")
+func InstanceFamilyMatches(instanceType, family string) bool {
+	instanceType = strings.ToLower(instanceType)
+	family = strings.ToLower(strings.TrimSpace(family))
+	if instanceType == "" || family == "" {
+		return false
+	}
+	if prefix, ok := strings.CutSuffix(family, "*"); ok {
+		return strings.HasPrefix(instanceType, prefix)
+	}
+	if instanceType == family {
+		return true
+	}
+	for _, sep := range []string{".", "-", "_"} {
+		if strings.HasPrefix(instanceType, family+sep) {
+			return true
+		}
+	}
+	return false
+}
+
+// ListRates returns the list (pre-discount) CPU and memory rates, falling back to the
+// effective rates when no list rates were recorded
+func (p *EffectivePricing) ListRates() (cpuRate, memRate float64) {
+	cpuRate, memRate = p.ListCPUPerCoreHour, p.ListMemoryPerGBHour
+	if cpuRate == 0 {
+		cpuRate = p.CPUPerCoreHour
+	}
+	if memRate == 0 {
+		memRate = p.MemoryPerGBHour
+	}
+	return cpuRate, memRate
 }
 
 // DefaultPricingRates contains default pricing by cloud provider
@@ -0,0 +1,91 @@
+package models
+
+import (
+	"database/sql/driver"
+	"fmt"
+	"time"
+)
+
+// Pricing change actions recorded in pricing versions
+const (
+	PricingActionConfigCreate    = "config.create"
+	PricingActionConfigUpdate    = "config.update"
+	PricingActionConfigDelete    = "config.delete"
+	PricingActionRateCreate      = "rate.create"
+	PricingActionRateUpdate      = "rate.update"
+	PricingActionRateDelete      = "rate.delete"
+	PricingActionRatesImport     = "rates.import"
+	PricingActionDiscountCreate  = "discount.create"
+	PricingActionDiscountUpdate  = "discount.update"
+	PricingActionDiscountDelete  = "discount.delete"
+	PricingActionClusterAssign   = "cluster.assign"
+	PricingActionClusterUnassign = "cluster.unassign"
+	PricingActionNodeSet         = "node.set"
+	PricingActionNodeDelete      = "node.delete"
+)
+
+// PricingJSON is a JSON document stored as JSONB: a pricing config snapshot, or the
+// before/after state of a changed rate, discount, config, cluster assignment or node override
+type PricingJSON []byte
+
+// Value implements driver.Valuer for storing the document as JSONB
+func (j PricingJSON) Value() (driver.Value, error) {
+	if len(j) == 0 {
+		return nil, nil
+	}
+	return string(j), nil
+}
+
+// Scan implements sql.Scanner for reading the document from JSONB
+func (j *PricingJSON) Scan(value interface{}) error {
+	switch v := value.(type) {
+	case nil:
+		*j = nil
+	case []byte:
+		*j = append(PricingJSON(nil), v...)
+	case string:
+		*j = PricingJSON(v)
+	default:
+		return fmt.Errorf("cannot scan %T into PricingJSON", value)
+	}
+	return nil
+}
+
+// MarshalJSON embeds the document as is
+func (j PricingJSON) MarshalJSON() ([]byte, error) {
+	if len(j) == 0 {
+		return []byte("null"), nil
+	}
+	return j, nil
+}
+
+// UnmarshalJSON stores the raw document
+func (j *PricingJSON) UnmarshalJSON(data []byte) error {
+	*j = append(PricingJSON(nil), data...)
+	return nil
+}
+
+// PricingVersion is an immutable record of one change to a pricing configuration: who
+// made it, the before/after state of the changed config, rate or discount, and a snapshot
+// of the whole config (with rates and discounts) after the change. Cluster assignments are
+// recorded under the assigned config and node overrides under their cluster's config (0
+// when none). Versions are the pricing audit trail and let reports be recomputed with
+// pricing as it stood at a point in time.
+type PricingVersion struct {
+	ID         uint        `gorm:"primaryKey" json:"id"`
+	TenantID   uint        `gorm:"column:tenant_id;not null" json:"tenant_id"`
+	ConfigID   uint        `gorm:"column:config_id;not null" json:"config_id"`
+	Version    int         `gorm:"column:version;not null" json:"version"` // per config, starting at 1
+	Action     string      `gorm:"column:action;size:30;not null" json:"action"`
+	EntityType string      `gorm:"column:entity_type;size:20;not null" json:"entity_type"` // config, rate, discount, cluster, node
+	EntityID   uint        `gorm:"column:entity_id" json:"entity_id"`
+	Actor      string      `gorm:"column:actor;size:255" json:"actor,omitempty"` // user email or API key
+	Before     PricingJSON `gorm:"column:before;type:jsonb" json:"before,omitempty"`
+	After      PricingJSON `gorm:"column:after;type:jsonb" json:"after,omitempty"`
+	Snapshot   PricingJSON `gorm:"column:snapshot;type:jsonb" json:"snapshot,omitempty"` // null once the config is deleted
+	CreatedAt  time.Time   `gorm:"column:created_at;autoCreateTime" json:"created_at"`
+}
+
+func (PricingVersion) TableName() string {
+	return "pricing_versions"
+}
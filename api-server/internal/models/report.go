@@ -0,0 +1,106 @@
+package models
+
+import (
+	"database/sql/driver"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/lib/pq"
+)
+
+// Report formats
+const (
+	ReportFormatCSV  = "csv"
+	ReportFormatHTML = "html"
+)
+
+// Report run statuses
+const (
+	ReportRunRunning   = "running"
+	ReportRunSucceeded = "succeeded"
+	ReportRunFailed    = "failed"
+)
+
+// ReportQuery holds the allocation query a report runs
+type ReportQuery struct {
+	Window          string   `json:"window"`         // relative to the run, e.g. "lastweek", "7d", "lastmonth"
+	Aggregate       string   `json:"aggregate"`      // e.g. "namespace", "label:team"
+	Step            string   `json:"step,omitempty"` // "1d", "1w": one row per allocation and step
+	Filters         []string `json:"filters,omitempty"`
+	Idle            bool     `json:"idle,omitempty"`
+	ShareIdle       string   `json:"share_idle,omitempty"`
+	IncludeExternal bool     `json:"include_external,omitempty"`
+	Currency        string   `json:"currency,omitempty"`
+}
+
+// Value implements driver.Valuer for storing the query as JSONB
+func (q ReportQuery) Value() (driver.Value, error) {
+	b, err := json.Marshal(q)
+	if err != nil {
+		return nil, err
+	}
+	return string(b), nil
+}
+
+// Scan implements sql.Scanner for reading the query from JSONB
+func (q *ReportQuery) Scan(value interface{}) error {
+	var data []byte
+	switch v := value.(type) {
+	case nil:
+		*q = ReportQuery{}
+		return nil
+	case []byte:
+		data = v
+	case string:
+		data = []byte(v)
+	default:
+		return fmt.Errorf("cannot scan %T into ReportQuery", value)
+	}
+	return json.Unmarshal(data, q)
+}
+
+// Report is a saved allocation query rendered on a cron schedule and delivered through
+// notification channels
+type Report struct {
+	ID         uint           `gorm:"primaryKey" json:"id"`
+	TenantID   uint           `gorm:"column:tenant_id;not null" json:"tenant_id"`
+	Name       string         `gorm:"column:name;size:100;not null" json:"name"`
+	Query      ReportQuery    `gorm:"column:query;type:jsonb" json:"query"`
+	Format     string         `gorm:"column:format;size:10;not null" json:"format"`      // csv, html
+	Schedule   string         `gorm:"column:schedule;size:100;not null" json:"schedule"` // cron expression, e.g. "0 8 * * mon"
+	Timezone   string         `gorm:"column:timezone;size:64;not null;default:UTC" json:"timezone"`
+	ChannelIDs pq.Int64Array  `gorm:"column:channel_ids;type:bigint[]" json:"channel_ids"` // notification channels to deliver through
+	Recipients pq.StringArray `gorm:"column:recipients;type:text[]" json:"recipients"`     // replace the recipients of email channels
+	Enabled    bool           `gorm:"column:enabled;not null" json:"enabled"`
+	NextRunAt  *time.Time     `gorm:"column:next_run_at" json:"next_run_at,omitempty"`
+	LastRunAt  *time.Time     `gorm:"column:last_run_at" json:"last_run_at,omitempty"`
+	CreatedAt  time.Time      `gorm:"column:created_at;autoCreateTime" json:"created_at"`
+	UpdatedAt  time.Time      `gorm:"column:updated_at;autoUpdateTime" json:"updated_at"`
+}
+
+func (Report) TableName() string {
+	return "reports"
+}
+
+// ReportRun is one execution of a report; its rendered output is kept for download
+type ReportRun struct {
+	ID           uint       `gorm:"primaryKey" json:"id"`
+	TenantID     uint       `gorm:"column:tenant_id;not null" json:"tenant_id"`
+	ReportID     uint       `gorm:"column:report_id;not null" json:"report_id"`
+	ScheduledFor time.Time  `gorm:"column:scheduled_for;not null" json:"scheduled_for"`
+	Manual       bool       `gorm:"column:manual;not null" json:"manual"`
+	Status       string     `gorm:"column:status;size:20;not null" json:"status"` // running, succeeded, failed
+	Format       string     `gorm:"column:format;size:10;not null" json:"format"`
+	Rows         int        `gorm:"column:rows;not null" json:"rows"`
+	Size         int        `gorm:"column:size;not null" json:"size"` // bytes
+	Deliveries   int        `gorm:"column:deliveries;not null" json:"deliveries"`
+	Error        string     `gorm:"column:error" json:"error,omitempty"`
+	Output       []byte     `gorm:"column:output" json:"-"`
+	StartedAt    time.Time  `gorm:"column:started_at;not null" json:"started_at"`
+	FinishedAt   *time.Time `gorm:"column:finished_at" json:"finished_at,omitempty"`
+}
+
+func (ReportRun) TableName() string {
+	return "report_runs"
+}
@@ -0,0 +1,137 @@
+package models
+
+import (
+	"time"
+
+	"github.com/lib/pq"
+)
+
+// Statement statuses: drafts can be regenerated and adjusted, reviewed statements await
+// finalization and finalized statements are immutable
+const (
+	StatementDraft     = "draft"
+	StatementReviewed  = "reviewed"
+	StatementFinalized = "finalized"
+)
+
+// Statement adjustment kinds: an adjustment adds its (signed) amount to a cost center's
+// total, a credit subtracts it
+const (
+	AdjustmentKindAdjustment = "adjustment"
+	AdjustmentKindCredit     = "credit"
+)
+
+// Statement lines that are not cost centers: pods no cost center maps to, and idle cost
+// when it is not shared
+const (
+	StatementLineUnallocated = "__unallocated__"
+	StatementLineIdle        = "__idle__"
+)
+
+// CostCenter is a node of the tenant's cost center hierarchy (e.g. org → department →
+// team → service) that pods are mapped to by cluster, namespace or pod labels. A node
+// matches a pod when the pod runs in one of its clusters (if any are set) and is in one of
+// its namespaces or matches one of its label selectors (if any are set); nodes without
+// rules only roll up their children. A pod belongs to the first matching node ordered by
+// priority (lowest first), then depth (deepest first), then ID.
+type CostCenter struct {
+	ID          uint           `gorm:"primaryKey" json:"id"`
+	TenantID    uint           `gorm:"column:tenant_id;not null" json:"tenant_id"`
+	ParentID    *uint          `gorm:"column:parent_id" json:"parent_id,omitempty"`
+	Name        string         `gorm:"column:name;size:100;not null" json:"name"`
+	Level       string         `gorm:"column:level;size:50" json:"level,omitempty"` // e.g. org, department, team, service
+	Description string         `gorm:"column:description" json:"description,omitempty"`
+	Clusters    pq.StringArray `gorm:"column:clusters;type:text[]" json:"clusters"`     // clusters the node's rules apply to (none = all)
+	Namespaces  pq.StringArray `gorm:"column:namespaces;type:text[]" json:"namespaces"` // namespaces mapped to the cost center
+	Labels      pq.StringArray `gorm:"column:labels;type:text[]" json:"labels"`         // pod label selectors: "key=value" or "key"
+	Priority    int            `gorm:"column:priority;not null;default:0" json:"priority"`
+	CreatedAt   time.Time      `gorm:"column:created_at;autoCreateTime" json:"created_at"`
+	UpdatedAt   time.Time      `gorm:"column:updated_at;autoUpdateTime" json:"updated_at"`
+}
+
+// HasRules reports whether the cost center maps any pods itself
+func (cc *CostCenter) HasRules() bool {
+	return len(cc.Clusters) > 0 || len(cc.Namespaces) > 0 || len(cc.Labels) > 0
+}
+
+func (CostCenter) TableName() string {
+	return "cost_centers"
+}
+
+// Statement is a tenant's chargeback statement for a month: allocation results frozen per
+// cost center when generated, plus manual adjustments and credits
+type Statement struct {
+	ID               uint       `gorm:"primaryKey" json:"id"`
+	TenantID         uint       `gorm:"column:tenant_id;not null" json:"tenant_id"`
+	Period           string     `gorm:"column:period;size:7;not null" json:"period"` // YYYY-MM
+	PeriodStart      time.Time  `gorm:"column:period_start;not null" json:"period_start"`
+	PeriodEnd        time.Time  `gorm:"column:period_end;not null" json:"period_end"`
+	Currency         string     `gorm:"column:currency;size:3;not null" json:"currency"`
+	ShareIdle        string     `gorm:"column:share_idle;size:20;not null" json:"share_idle"` // weighted, even, false
+	Status           string     `gorm:"column:status;size:20;not null" json:"status"`
+	AllocatedCost    float64    `gorm:"column:allocated_cost;type:decimal(14,2);not null" json:"allocated_cost"`
+	AdjustmentsTotal float64    `gorm:"column:adjustments_total;type:decimal(14,2);not null" json:"adjustments_total"`
+	Total            float64    `gorm:"column:total;type:decimal(14,2);not null" json:"total"`
+	GeneratedAt      time.Time  `gorm:"column:generated_at;not null" json:"generated_at"`
+	CreatedBy        string     `gorm:"column:created_by;size:255" json:"created_by,omitempty"`
+	ReviewedBy       string     `gorm:"column:reviewed_by;size:255" json:"reviewed_by,omitempty"`
+	ReviewedAt       *time.Time `gorm:"column:reviewed_at" json:"reviewed_at,omitempty"`
+	FinalizedBy      string     `gorm:"column:finalized_by;size:255" json:"finalized_by,omitempty"`
+	FinalizedAt      *time.Time `gorm:"column:finalized_at" json:"finalized_at,omitempty"`
+	CreatedAt        time.Time  `gorm:"column:created_at;autoCreateTime" json:"created_at"`
+	UpdatedAt        time.Time  `gorm:"column:updated_at;autoUpdateTime" json:"updated_at"`
+
+	Lines       []StatementLine       `gorm:"foreignKey:StatementID" json:"lines,omitempty"`
+	Adjustments []StatementAdjustment `gorm:"foreignKey:StatementID" json:"adjustments,omitempty"`
+}
+
+func (Statement) TableName() string {
+	return "statements"
+}
+
+// StatementLine is one cost center's frozen allocation cost in a statement
+type StatementLine struct {
+	ID             uint           `gorm:"primaryKey" json:"id"`
+	StatementID    uint           `gorm:"column:statement_id;not null" json:"statement_id"`
+	CostCenterID   *uint          `gorm:"column:cost_center_id" json:"cost_center_id,omitempty"` // nil for __unallocated__ and __idle__
+	CostCenter     string         `gorm:"column:cost_center;size:100;not null" json:"cost_center"`
+	Namespaces     pq.StringArray `gorm:"column:namespaces;type:text[]" json:"namespaces"` // namespaces contributing to the line
+	PodCount       int            `gorm:"column:pod_count;not null" json:"pod_count"`
+	CPUCost        float64        `gorm:"column:cpu_cost;type:decimal(14,4);not null" json:"cpu_cost"`
+	RAMCost        float64        `gorm:"column:ram_cost;type:decimal(14,4);not null" json:"ram_cost"`
+	SharedIdleCost float64        `gorm:"column:shared_idle_cost;type:decimal(14,4);not null" json:"shared_idle_cost"`
+	SharedCost     float64        `gorm:"column:shared_cost;type:decimal(14,4);not null" json:"shared_cost"`
+	ExternalCost   float64        `gorm:"column:external_cost;type:decimal(14,4);not null" json:"external_cost"`
+	AllocatedCost  float64        `gorm:"column:allocated_cost;type:decimal(14,4);not null" json:"allocated_cost"`
+	Adjustments    float64        `gorm:"column:adjustments;type:decimal(14,4);not null" json:"adjustments"`
+	Total          float64        `gorm:"column:total;type:decimal(14,4);not null" json:"total"`
+}
+
+func (StatementLine) TableName() string {
+	return "statement_lines"
+}
+
+// StatementAdjustment is a manual adjustment or credit to a cost center's line, with the
+// reason for it
+type StatementAdjustment struct {
+	ID          uint      `gorm:"primaryKey" json:"id"`
+	StatementID uint      `gorm:"column:statement_id;not null" json:"statement_id"`
+	CostCenter  string    `gorm:"column:cost_center;size:100;not null" json:"cost_center"`
+	Kind        string    `gorm:"column:kind;size:20;not null" json:"kind"`                // adjustment, credit
+	Amount      float64   `gorm:"column:amount;type:decimal(14,4);not null" json:"amount"` // credits are positive amounts
+	Comment     string    `gorm:"column:comment;not null" json:"comment"`
+	CreatedBy   string    `gorm:"column:created_by;size:255" json:"created_by,omitempty"`
+	CreatedAt   time.Time `gorm:"column:created_at;autoCreateTime" json:"created_at"`
+}
+
+func (StatementAdjustment) TableName() string {
+	return "statement_adjustments"
+}
+
+// Effect returns the amount the adjustment adds to its cost center's total
+func (a StatementAdjustment) Effect() float64 {
+	if a.Kind == AdjustmentKindCredit {
+		return -a.Amount
+	}
+	return a.Amount
+}
@@ -0,0 +1,184 @@
+package notifications
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"crypto/tls"
+	"encoding/base64"
+	"encoding/hex"
+	"fmt"
+	"mime"
+	"mime/quotedprintable"
+	"net"
+	"net/smtp"
+	"net/textproto"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// EmailChannel sends messages by SMTP as multipart text/HTML email, with the message's
+// attachments. The connection is
+// upgraded with STARTTLS when the server offers it; ImplicitTLS connects over TLS from the
+// start (port 465).
+type EmailChannel struct {
+	Host        string
+	Port        int
+	Username    string
+	Password    string
+	From        string
+	To          []string
+	ImplicitTLS bool
+	Timeout     time.Duration
+}
+
+// Send renders the message and sends it to every recipient
+func (c *EmailChannel) Send(ctx context.Context, msg Message) error {
+	if len(c.To) == 0 {
+		return &PermanentError{Err: fmt.Errorf("no recipients")}
+	}
+	data, err := c.buildMessage(msg)
+	if err != nil {
+		return &PermanentError{Err: err}
+	}
+
+	timeout := c.Timeout
+	if timeout <= 0 {
+		timeout = 30 * time.Second
+	}
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	addr := net.JoinHostPort(c.Host, strconv.Itoa(c.Port))
+	dialer := &net.Dialer{}
+	conn, err := dialer.DialContext(ctx, "tcp", addr)
+	if err != nil {
+		return err
+	}
+	if deadline, ok := ctx.Deadline(); ok {
+		conn.SetDeadline(deadline)
+	}
+	if c.ImplicitTLS {
+		conn = tls.Client(conn, &tls.Config{ServerName: c.Host})
+	}
+
+	client, err := smtp.NewClient(conn, c.Host)
+	if err != nil {
+		conn.Close()
+		return err
+	}
+	defer client.Close()
+
+	if !c.ImplicitTLS {
+		if ok, _ := client.Extension("STARTTLS"); ok {
+			if err := client.StartTLS(&tls.Config{ServerName: c.Host}); err != nil {
+				return err
+			}
+		}
+	}
+	if c.Username != "" {
+		if err := client.Auth(smtp.PlainAuth("", c.Username, c.Password, c.Host)); err != nil {
+			return smtpError(err)
+		}
+	}
+	if err := client.Mail(c.From); err != nil {
+		return smtpError(err)
+	}
+	for _, to := range c.To {
+		if err := client.Rcpt(to); err != nil {
+			return smtpError(err)
+		}
+	}
+	w, err := client.Data()
+	if err != nil {
+		return smtpError(err)
+	}
+	if _, err := w.Write(data); err != nil {
+		return err
+	}
+	if err := w.Close(); err != nil {
+		return smtpError(err)
+	}
+	return client.Quit()
+}
+
+// buildMessage renders the message as a multipart/alternative email, wrapped in a
+// multipart/mixed email when it has attachments
+func (c *EmailChannel) buildMessage(msg Message) ([]byte, error) {
+	text, err := RenderText(msg)
+	if err != nil {
+		return nil, err
+	}
+	html, err := RenderHTML(msg)
+	if err != nil {
+		return nil, err
+	}
+
+	date := msg.Time
+	if date.IsZero() {
+		date = time.Now()
+	}
+	boundary := randomBoundary()
+	mixedBoundary := randomBoundary()
+
+	var buf bytes.Buffer
+	fmt.Fprintf(&buf, "From: %s\r\n", c.From)
+	fmt.Fprintf(&buf, "To: %s\r\n", strings.Join(c.To, ", "))
+	fmt.Fprintf(&buf, "Subject: %s\r\n", mime.QEncoding.Encode("utf-8", msg.Title))
+	fmt.Fprintf(&buf, "Date: %s\r\n", date.Format(time.RFC1123Z))
+	fmt.Fprintf(&buf, "MIME-Version: 1.0\r\n")
+	if len(msg.Attachments) > 0 {
+		fmt.Fprintf(&buf, "Content-Type: multipart/mixed; boundary=%q\r\n\r\n", mixedBoundary)
+		fmt.Fprintf(&buf, "--%s\r\n", mixedBoundary)
+	}
+	fmt.Fprintf(&buf, "Content-Type: multipart/alternative; boundary=%q\r\n\r\n", boundary)
+	for _, part := range []struct{ contentType, body string }{
+		{"text/plain", text},
+		{"text/html", html},
+	} {
+		fmt.Fprintf(&buf, "--%s\r\n", boundary)
+		fmt.Fprintf(&buf, "Content-Type: %s; charset=utf-8\r\n", part.contentType)
+		fmt.Fprintf(&buf, "Content-Transfer-Encoding: quoted-printable\r\n\r\n")
+		qp := quotedprintable.NewWriter(&buf)
+		if _, err := qp.Write([]byte(part.body)); err != nil {
+			return nil, err
+		}
+		if err := qp.Close(); err != nil {
+			return nil, err
+		}
+		buf.WriteString("\r\n")
+	}
+	fmt.Fprintf(&buf, "--%s--\r\n", boundary)
+
+	if len(msg.Attachments) > 0 {
+		for _, a := range msg.Attachments {
+			fmt.Fprintf(&buf, "\r\n--%s\r\n", mixedBoundary)
+			fmt.Fprintf(&buf, "Content-Type: %s\r\n", a.ContentType)
+			fmt.Fprintf(&buf, "Content-Disposition: attachment; filename=%q\r\n", a.Filename)
+			fmt.Fprintf(&buf, "Content-Transfer-Encoding: base64\r\n\r\n")
+			encoded := base64.StdEncoding.EncodeToString(a.Data)
+			for len(encoded) > 76 {
+				buf.WriteString(encoded[:76] + "\r\n")
+				encoded = encoded[76:]
+			}
+			buf.WriteString(encoded + "\r\n")
+		}
+		fmt.Fprintf(&buf, "--%s--\r\n", mixedBoundary)
+	}
+	return buf.Bytes(), nil
+}
+
+// smtpError marks 5xx SMTP replies (rejected sender, recipient or credentials) permanent
+func smtpError(err error) error {
+	if protoErr, ok := err.(*textproto.Error); ok && protoErr.Code >= 500 {
+		return &PermanentError{Err: err}
+	}
+	return err
+}
+
+func randomBoundary() string {
+	b := make([]byte, 12)
+	rand.Read(b)
+	return hex.EncodeToString(b)
+}
@@ -0,0 +1,113 @@
+// Package notifications delivers alert messages to external channels: Slack and Microsoft
+// Teams incoming webhooks, signed JSON webhooks and SMTP email.
+package notifications
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"time"
+)
+
+// Message severities
+const (
+	SeverityInfo     = "info"
+	SeverityWarning  = "warning"
+	SeverityCritical = "critical"
+)
+
+// Field is a labelled value shown with a message
+type Field struct {
+	Name  string `json:"name"`
+	Value string `json:"value"`
+}
+
+// Attachment is a file sent with a message. Email attaches it and the webhook channel
+// embeds it (base64); chat channels cannot carry files and send the message without it.
+type Attachment struct {
+	Filename    string `json:"filename"`
+	ContentType string `json:"content_type"`
+	Data        []byte `json:"data"`
+}
+
+// Message is a notification, rendered by each channel in its own format
+type Message struct {
+	Event       string       `json:"event"`    // anomaly, budget, recommendation, report, test
+	Severity    string       `json:"severity"` // info, warning, critical
+	Title       string       `json:"title"`
+	Text        string       `json:"text"`
+	Fields      []Field      `json:"fields,omitempty"`
+	Link        string       `json:"link,omitempty"`
+	Time        time.Time    `json:"time"`
+	Attachments []Attachment `json:"attachments,omitempty"`
+}
+
+// Channel delivers messages to one destination
+type Channel interface {
+	Send(ctx context.Context, msg Message) error
+}
+
+// PermanentError marks a delivery failure that retrying cannot fix, e.g. a rejected
+// webhook URL or an invalid recipient
+type PermanentError struct {
+	Err error
+}
+
+func (e *PermanentError) Error() string { return e.Err.Error() }
+
+func (e *PermanentError) Unwrap() error { return e.Err }
+
+// IsPermanent reports whether err is a permanent delivery failure
+func IsPermanent(err error) bool {
+	var permanent *PermanentError
+	return errors.As(err, &permanent)
+}
+
+// defaultHTTPClient is used by HTTP channels created without a client
+var defaultHTTPClient = &http.Client{Timeout: 10 * time.Second}
+
+// postJSON posts a JSON payload with extra headers; 4xx responses other than 408 and 429
+// are permanent failures
+func postJSON(ctx context.Context, client *http.Client, url string, body []byte, headers map[string]string) error {
+	if client == nil {
+		client = defaultHTTPClient
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, url, bytes.NewReader(body))
+	if err != nil {
+		return &PermanentError{Err: err}
+	}
+	req.Header.Set("Content-Type", "application/json")
+	for name, value := range headers {
+		req.Header.Set(name, value)
+	}
+
+	resp, err := client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode >= 200 && resp.StatusCode < 300 {
+		return nil
+	}
+
+	detail, _ := io.ReadAll(io.LimitReader(resp.Body, 512))
+	err = fmt.Errorf("webhook returned %d: %s", resp.StatusCode, bytes.TrimSpace(detail))
+	if resp.StatusCode >= 400 && resp.StatusCode < 500 &&
+		resp.StatusCode != http.StatusRequestTimeout && resp.StatusCode != http.StatusTooManyRequests {
+		return &PermanentError{Err: err}
+	}
+	return err
+}
+
+// marshal encodes a channel payload
+func marshal(payload interface{}) ([]byte, error) {
+	body, err := json.Marshal(payload)
+	if err != nil {
+		return nil, &PermanentError{Err: err}
+	}
+	return body, nil
+}
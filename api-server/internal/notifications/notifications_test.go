@@ -0,0 +1,274 @@
+package notifications
+
+import (
+	"bufio"
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+var testMessage = Message{
+	Event:    "budget",
+	Severity: SeverityWarning,
+	Title:    "Budget platform at 80%",
+	Text:     "Spent 800.00 of 1000.00 USD",
+	Fields:   []Field{{Name: "Period", Value: "2024-05"}},
+	Link:     "https://costs.example.com/budgets/1",
+	Time:     time.Date(2024, 5, 20, 10, 0, 0, 0, time.UTC),
+}
+
+// sink records the requests posted to it and answers with the queued status codes
+type sink struct {
+	mu       sync.Mutex
+	statuses []int
+	requests []*http.Request
+	bodies   [][]byte
+}
+
+func (s *sink) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	body, _ := io.ReadAll(r.Body)
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.requests = append(s.requests, r)
+	s.bodies = append(s.bodies, body)
+	status := http.StatusOK
+	if len(s.statuses) > 0 {
+		status, s.statuses = s.statuses[0], s.statuses[1:]
+	}
+	w.WriteHeader(status)
+}
+
+func TestSlackChannel(t *testing.T) {
+	rec := &sink{}
+	srv := httptest.NewServer(rec)
+	defer srv.Close()
+
+	ch := &SlackChannel{WebhookURL: srv.URL}
+	require.NoError(t, ch.Send(context.Background(), testMessage))
+
+	var payload slackPayload
+	require.NoError(t, json.Unmarshal(rec.bodies[0], &payload))
+	assert.Equal(t, testMessage.Title, payload.Text)
+	require.Len(t, payload.Attachments, 1)
+	assert.Equal(t, "#f0b429", payload.Attachments[0].Color)
+	assert.Equal(t, "Period", payload.Attachments[0].Fields[0].Title)
+}
+
+func TestTeamsChannel(t *testing.T) {
+	rec := &sink{}
+	srv := httptest.NewServer(rec)
+	defer srv.Close()
+
+	ch := &TeamsChannel{WebhookURL: srv.URL}
+	require.NoError(t, ch.Send(context.Background(), testMessage))
+
+	var card teamsCard
+	require.NoError(t, json.Unmarshal(rec.bodies[0], &card))
+	assert.Equal(t, "MessageCard", card.Type)
+	assert.Equal(t, "f0b429", card.ThemeColor)
+	assert.Equal(t, "2024-05", card.Sections[0].Facts[0].Value)
+	assert.Equal(t, testMessage.Link, card.PotentialAction[0].Targets[0].URI)
+}
+
+func TestWebhookChannelSignature(t *testing.T) {
+	rec := &sink{}
+	srv := httptest.NewServer(rec)
+	defer srv.Close()
+
+	ch := &WebhookChannel{URL: srv.URL, Secret: "s3cret", Headers: map[string]string{"X-Env": "test"}}
+	require.NoError(t, ch.Send(context.Background(), testMessage))
+
+	req, body := rec.requests[0], rec.bodies[0]
+	assert.Equal(t, "budget", req.Header.Get(EventHeader))
+	assert.Equal(t, "test", req.Header.Get("X-Env"))
+	assert.True(t, VerifySignature("s3cret", req.Header.Get(TimestampHeader), body, req.Header.Get(SignatureHeader)))
+	assert.False(t, VerifySignature("other", req.Header.Get(TimestampHeader), body, req.Header.Get(SignatureHeader)))
+
+	var msg Message
+	require.NoError(t, json.Unmarshal(body, &msg))
+	assert.Equal(t, testMessage.Title, msg.Title)
+}
+
+func TestDeliverRetries(t *testing.T) {
+	policy := RetryPolicy{MaxAttempts: 3, InitialDelay: time.Millisecond}
+
+	rec := &sink{statuses: []int{http.StatusBadGateway, http.StatusTooManyRequests}}
+	srv := httptest.NewServer(rec)
+	defer srv.Close()
+
+	attempts, err := Deliver(context.Background(), &WebhookChannel{URL: srv.URL}, testMessage, policy)
+	require.NoError(t, err)
+	assert.Equal(t, 3, attempts)
+
+	// Exhausted
+	rec.statuses = []int{500, 500, 500}
+	attempts, err = Deliver(context.Background(), &WebhookChannel{URL: srv.URL}, testMessage, policy)
+	assert.Error(t, err)
+	assert.Equal(t, 3, attempts)
+
+	// Client errors are not retried
+	rec.statuses = []int{http.StatusNotFound}
+	attempts, err = Deliver(context.Background(), &WebhookChannel{URL: srv.URL}, testMessage, policy)
+	assert.True(t, IsPermanent(err))
+	assert.Equal(t, 1, attempts)
+}
+
+// fakeSMTP is a minimal SMTP server accepting one message per connection
+type fakeSMTP struct {
+	ln       net.Listener
+	mu       sync.Mutex
+	from     string
+	rcpts    []string
+	data     string
+	rejectTo string
+}
+
+func newFakeSMTP(t *testing.T) *fakeSMTP {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	s := &fakeSMTP{ln: ln}
+	go s.serve()
+	t.Cleanup(func() { ln.Close() })
+	return s
+}
+
+func (s *fakeSMTP) port() int {
+	return s.ln.Addr().(*net.TCPAddr).Port
+}
+
+func (s *fakeSMTP) serve() {
+	for {
+		conn, err := s.ln.Accept()
+		if err != nil {
+			return
+		}
+		go s.handle(conn)
+	}
+}
+
+func (s *fakeSMTP) handle(conn net.Conn) {
+	defer conn.Close()
+	r := bufio.NewReader(conn)
+	reply := func(line string) { io.WriteString(conn, line+"\r\n") }
+
+	reply("220 localhost ESMTP")
+	for {
+		line, err := r.ReadString('\n')
+		if err != nil {
+			return
+		}
+		cmd := strings.TrimSpace(line)
+		upper := strings.ToUpper(cmd)
+		switch {
+		case strings.HasPrefix(upper, "EHLO"), strings.HasPrefix(upper, "HELO"):
+			reply("250 localhost")
+		case strings.HasPrefix(upper, "MAIL FROM:"):
+			s.mu.Lock()
+			s.from = strings.Trim(cmd[len("MAIL FROM:"):], "<>")
+			s.mu.Unlock()
+			reply("250 OK")
+		case strings.HasPrefix(upper, "RCPT TO:"):
+			to := strings.Trim(cmd[len("RCPT TO:"):], "<>")
+			if to == s.rejectTo {
+				reply("550 no such user")
+				continue
+			}
+			s.mu.Lock()
+			s.rcpts = append(s.rcpts, to)
+			s.mu.Unlock()
+			reply("250 OK")
+		case upper == "DATA":
+			reply("354 go ahead")
+			var data strings.Builder
+			for {
+				l, err := r.ReadString('\n')
+				if err != nil {
+					return
+				}
+				if l == ".\r\n" {
+					break
+				}
+				data.WriteString(l)
+			}
+			s.mu.Lock()
+			s.data = data.String()
+			s.mu.Unlock()
+			reply("250 queued")
+		case upper == "QUIT":
+			reply("221 bye")
+			return
+		default:
+			reply("250 OK")
+		}
+	}
+}
+
+func TestEmailChannel(t *testing.T) {
+	server := newFakeSMTP(t)
+	ch := &EmailChannel{
+		Host: "127.0.0.1",
+		Port: server.port(),
+		From: "alerts@example.com",
+		To:   []string{"ops@example.com", "finance@example.com"},
+	}
+	require.NoError(t, ch.Send(context.Background(), testMessage))
+
+	server.mu.Lock()
+	defer server.mu.Unlock()
+	assert.Equal(t, "alerts@example.com", server.from)
+	assert.Equal(t, []string{"ops@example.com", "finance@example.com"}, server.rcpts)
+	assert.Contains(t, server.data, "Subject: Budget platform at 80%")
+	assert.Contains(t, server.data, "multipart/alternative")
+	assert.Contains(t, server.data, "Spent 800.00 of 1000.00 USD")
+	assert.Contains(t, server.data, "text/html")
+	assert.NotContains(t, server.data, "multipart/mixed")
+}
+
+func TestEmailChannelAttachments(t *testing.T) {
+	server := newFakeSMTP(t)
+	ch := &EmailChannel{Host: "127.0.0.1", Port: server.port(), From: "reports@example.com", To: []string{"finance@example.com"}}
+
+	msg := testMessage
+	msg.Attachments = []Attachment{{Filename: "costs.csv", ContentType: "text/csv", Data: []byte("name,total\nweb,12.50\n")}}
+	require.NoError(t, ch.Send(context.Background(), msg))
+
+	server.mu.Lock()
+	defer server.mu.Unlock()
+	assert.Contains(t, server.data, "multipart/mixed")
+	assert.Contains(t, server.data, `filename="costs.csv"`)
+	assert.Contains(t, server.data, base64.StdEncoding.EncodeToString(msg.Attachments[0].Data))
+}
+
+func TestEmailChannelRejectedRecipient(t *testing.T) {
+	server := newFakeSMTP(t)
+	server.rejectTo = "nobody@example.com"
+	ch := &EmailChannel{Host: "127.0.0.1", Port: server.port(), From: "alerts@example.com", To: []string{"nobody@example.com"}}
+
+	err := ch.Send(context.Background(), testMessage)
+	require.Error(t, err)
+	assert.True(t, IsPermanent(err))
+}
+
+func TestRenderText(t *testing.T) {
+	text, err := RenderText(testMessage)
+	require.NoError(t, err)
+	assert.Contains(t, text, "Budget platform at 80%")
+	assert.Contains(t, text, "Period: 2024-05")
+	assert.Contains(t, text, testMessage.Link)
+
+	html, err := RenderHTML(Message{Title: "<script>", Severity: SeverityCritical})
+	require.NoError(t, err)
+	assert.NotContains(t, html, "<script>")
+	assert.Contains(t, html, "#d64545")
+}
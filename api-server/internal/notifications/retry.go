@@ -0,0 +1,44 @@
+package notifications
+
+import (
+	"context"
+	"time"
+)
+
+// RetryPolicy controls how often a failed delivery is retried; the delay doubles after
+// each attempt up to MaxDelay
+type RetryPolicy struct {
+	MaxAttempts  int
+	InitialDelay time.Duration
+	MaxDelay     time.Duration
+}
+
+// DefaultRetryPolicy makes 4 attempts over about 7 seconds
+var DefaultRetryPolicy = RetryPolicy{MaxAttempts: 4, InitialDelay: time.Second, MaxDelay: 30 * time.Second}
+
+// Deliver sends a message, retrying transient failures with exponential backoff. It
+// returns the number of attempts made and the last error.
+func Deliver(ctx context.Context, ch Channel, msg Message, policy RetryPolicy) (int, error) {
+	if policy.MaxAttempts <= 0 {
+		policy.MaxAttempts = 1
+	}
+	delay := policy.InitialDelay
+
+	var err error
+	for attempt := 1; ; attempt++ {
+		err = ch.Send(ctx, msg)
+		if err == nil || IsPermanent(err) || attempt >= policy.MaxAttempts {
+			return attempt, err
+		}
+
+		select {
+		case <-ctx.Done():
+			return attempt, ctx.Err()
+		case <-time.After(delay):
+		}
+		delay *= 2
+		if policy.MaxDelay > 0 && delay > policy.MaxDelay {
+			delay = policy.MaxDelay
+		}
+	}
+}
@@ -0,0 +1,54 @@
+package notifications
+
+import (
+	"context"
+	"net/http"
+)
+
+// SlackChannel posts messages to a Slack incoming webhook
+type SlackChannel struct {
+	WebhookURL string
+	Client     *http.Client
+}
+
+type slackPayload struct {
+	Text        string            `json:"text"` // notification fallback
+	Attachments []slackAttachment `json:"attachments"`
+}
+
+type slackAttachment struct {
+	Color     string       `json:"color"`
+	Title     string       `json:"title"`
+	TitleLink string       `json:"title_link,omitempty"`
+	Text      string       `json:"text"`
+	Fields    []slackField `json:"fields,omitempty"`
+	Timestamp int64        `json:"ts,omitempty"`
+}
+
+type slackField struct {
+	Title string `json:"title"`
+	Value string `json:"value"`
+	Short bool   `json:"short"`
+}
+
+// Send posts the message as a colored attachment
+func (c *SlackChannel) Send(ctx context.Context, msg Message) error {
+	attachment := slackAttachment{
+		Color:     severityColor(msg.Severity),
+		Title:     msg.Title,
+		TitleLink: msg.Link,
+		Text:      msg.Text,
+	}
+	if !msg.Time.IsZero() {
+		attachment.Timestamp = msg.Time.Unix()
+	}
+	for _, f := range msg.Fields {
+		attachment.Fields = append(attachment.Fields, slackField{Title: f.Name, Value: f.Value, Short: len(f.Value) <= 40})
+	}
+
+	body, err := marshal(slackPayload{Text: msg.Title, Attachments: []slackAttachment{attachment}})
+	if err != nil {
+		return err
+	}
+	return postJSON(ctx, c.Client, c.WebhookURL, body, nil)
+}
@@ -0,0 +1,76 @@
+package notifications
+
+import (
+	"context"
+	"net/http"
+	"strings"
+)
+
+// TeamsChannel posts messages to a Microsoft Teams incoming webhook as a MessageCard
+type TeamsChannel struct {
+	WebhookURL string
+	Client     *http.Client
+}
+
+type teamsCard struct {
+	Type            string         `json:"@type"`
+	Context         string         `json:"@context"`
+	ThemeColor      string         `json:"themeColor"`
+	Summary         string         `json:"summary"`
+	Title           string         `json:"title"`
+	Text            string         `json:"text"`
+	Sections        []teamsSection `json:"sections,omitempty"`
+	PotentialAction []teamsAction  `json:"potentialAction,omitempty"`
+}
+
+type teamsSection struct {
+	Facts []teamsFact `json:"facts"`
+}
+
+type teamsFact struct {
+	Name  string `json:"name"`
+	Value string `json:"value"`
+}
+
+type teamsAction struct {
+	Type    string        `json:"@type"`
+	Name    string        `json:"name"`
+	Targets []teamsTarget `json:"targets"`
+}
+
+type teamsTarget struct {
+	OS  string `json:"os"`
+	URI string `json:"uri"`
+}
+
+// Send posts the message as a card with its fields as facts
+func (c *TeamsChannel) Send(ctx context.Context, msg Message) error {
+	card := teamsCard{
+		Type:       "MessageCard",
+		Context:    "https://schema.org/extensions",
+		ThemeColor: strings.TrimPrefix(severityColor(msg.Severity), "#"),
+		Summary:    msg.Title,
+		Title:      msg.Title,
+		Text:       msg.Text,
+	}
+	if len(msg.Fields) > 0 {
+		section := teamsSection{}
+		for _, f := range msg.Fields {
+			section.Facts = append(section.Facts, teamsFact{Name: f.Name, Value: f.Value})
+		}
+		card.Sections = []teamsSection{section}
+	}
+	if msg.Link != "" {
+		card.PotentialAction = []teamsAction{{
+			Type:    "OpenUri",
+			Name:    "View details",
+			Targets: []teamsTarget{{OS: "default", URI: msg.Link}},
+		}}
+	}
+
+	body, err := marshal(card)
+	if err != nil {
+		return err
+	}
+	return postJSON(ctx, c.Client, c.WebhookURL, body, nil)
+}
@@ -0,0 +1,62 @@
+package notifications
+
+import (
+	"bytes"
+	htmltemplate "html/template"
+	"text/template"
+)
+
+// textTemplate renders a message as plain text (email text part, chat fallback text)
+var textTemplate = template.Must(template.New("text").Parse(`{{.Title}}
+
+{{.Text}}
+{{range .Fields}}
+{{.Name}}: {{.Value}}{{end}}
+{{if .Link}}
+{{.Link}}
+{{end}}`))
+
+// htmlTemplate renders a message as the HTML part of an email
+var htmlTemplate = htmltemplate.Must(htmltemplate.New("html").Funcs(htmltemplate.FuncMap{"severityColor": severityColor}).Parse(`<!DOCTYPE html>
+<html>
+<body style="font-family: sans-serif; color: #1f2933;">
+  <h2 style="color: {{severityColor .Severity}};">{{.Title}}</h2>
+  <p>{{.Text}}</p>
+  {{if .Fields}}<table cellpadding="4" style="border-collapse: collapse;">
+    {{range .Fields}}<tr><td style="color: #616e7c;">{{.Name}}</td><td><strong>{{.Value}}</strong></td></tr>
+    {{end}}
+  </table>{{end}}
+  {{if .Link}}<p><a href="{{.Link}}">View details</a></p>{{end}}
+</body>
+</html>
+`))
+
+// RenderText renders a message as plain text
+func RenderText(msg Message) (string, error) {
+	var buf bytes.Buffer
+	if err := textTemplate.Execute(&buf, msg); err != nil {
+		return "", err
+	}
+	return buf.String(), nil
+}
+
+// RenderHTML renders a message as an HTML document
+func RenderHTML(msg Message) (string, error) {
+	var buf bytes.Buffer
+	if err := htmlTemplate.Execute(&buf, msg); err != nil {
+		return "", err
+	}
+	return buf.String(), nil
+}
+
+// severityColor returns the hex color a severity is shown in
+func severityColor(severity string) string {
+	switch severity {
+	case SeverityCritical:
+		return "#d64545"
+	case SeverityWarning:
+		return "#f0b429"
+	default:
+		return "#2680c2"
+	}
+}
@@ -0,0 +1,62 @@
+package notifications
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+// Headers sent with signed webhook deliveries
+const (
+	SignatureHeader = "X-Signature-256"
+	TimestampHeader = "X-Signature-Timestamp"
+	EventHeader     = "X-Notification-Event"
+)
+
+// WebhookChannel posts the message as JSON to any endpoint. When a secret is set the
+// request is signed: SignatureHeader is "sha256=" followed by the hex HMAC-SHA256 of
+// "<timestamp>.<body>" keyed with the secret, the timestamp (Unix seconds) being sent in
+// TimestampHeader so receivers can reject replays.
+type WebhookChannel struct {
+	URL     string
+	Secret  string
+	Headers map[string]string
+	Client  *http.Client
+}
+
+// Send posts the message
+func (c *WebhookChannel) Send(ctx context.Context, msg Message) error {
+	body, err := marshal(msg)
+	if err != nil {
+		return err
+	}
+
+	headers := map[string]string{EventHeader: msg.Event}
+	for name, value := range c.Headers {
+		headers[name] = value
+	}
+	if c.Secret != "" {
+		timestamp := strconv.FormatInt(time.Now().Unix(), 10)
+		headers[TimestampHeader] = timestamp
+		headers[SignatureHeader] = Sign(c.Secret, timestamp, body)
+	}
+	return postJSON(ctx, c.Client, c.URL, body, headers)
+}
+
+// Sign returns the signature of a webhook body sent at timestamp
+func Sign(secret, timestamp string, body []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(timestamp))
+	mac.Write([]byte("."))
+	mac.Write(body)
+	return "sha256=" + hex.EncodeToString(mac.Sum(nil))
+}
+
+// VerifySignature checks a webhook signature in constant time
+func VerifySignature(secret, timestamp string, body []byte, signature string) bool {
+	return hmac.Equal([]byte(Sign(secret, timestamp, body)), []byte(signature))
+}
@@ -0,0 +1,949 @@
+// Package parquet reads Parquet files, such as cloud billing exports, row by row with
+// values formatted as text
+package parquet
+
+import (
+	"bytes"
+	"compress/gzip"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"math"
+	"math/big"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Parquet physical types, converted types and enums
+const (
+	typeBoolean           = 0
+	typeInt32             = 1
+	typeInt64             = 2
+	typeInt96             = 3
+	typeFloat             = 4
+	typeDouble            = 5
+	typeByteArray         = 6
+	typeFixedLenByteArray = 7
+
+	convertedDecimal         = 5
+	convertedDate            = 6
+	convertedTimestampMillis = 9
+	convertedTimestampMicros = 10
+
+	repetitionOptional = 1
+	repetitionRepeated = 2
+
+	encodingPlain                = 0
+	encodingPlainDictionary      = 2
+	encodingRLE                  = 3
+	encodingDeltaBinaryPacked    = 5
+	encodingDeltaLengthByteArray = 6
+	encodingDeltaByteArray       = 7
+	encodingRLEDictionary        = 8
+
+	codecUncompressed = 0
+	codecSnappy       = 1
+	codecGzip         = 2
+
+	pageData       = 0
+	pageDictionary = 2
+	pageDataV2     = 3
+)
+
+var magic = []byte("PAR1")
+
+// Reader reads the rows of a Parquet file with values formatted as text, like the
+// records of a CSV file: numbers in Go's shortest representation, decimals with their
+// scale, dates as 2006-01-02, timestamps as RFC 3339 in UTC and nulls as "". One row group
+// is decoded at a time.
+//
+// Columns nested in (non-repeated) groups are named by their dotted path. Repeated columns
+// (lists and maps) are not read. Pages may be PLAIN, dictionary or DELTA encoded and
+// uncompressed, Snappy or gzip compressed.
+type Reader struct {
+	r       io.ReaderAt
+	columns []column
+	groups  []rowGroupMeta
+	group   int
+	values  [][]string // decoded values of the current row group, per column
+	row     int
+	record  []string
+}
+
+// column is a readable leaf column of the schema
+type column struct {
+	name       string
+	leaf       int // position among all leaf columns, as in row group column chunks
+	physical   int32
+	typeLength int
+	maxDef     int
+	decimal    bool
+	scale      int
+	date       bool
+	timeUnit   time.Duration // timestamps: the unit of the stored integer
+}
+
+type rowGroupMeta struct {
+	rows   int
+	chunks []chunkMeta
+}
+
+type chunkMeta struct {
+	codec      int32
+	values     int64
+	offset     int64
+	size       int64
+	external   bool
+	dictionary int64
+}
+
+// schemaElement is a node of the flattened schema tree
+type schemaElement struct {
+	physical    int32
+	typeLength  int
+	repetition  int32
+	name        string
+	numChildren int
+	converted   int32
+	scale       int
+	decimal     bool
+	date        bool
+	timeUnit    time.Duration
+}
+
+// NewReader reads the footer of the Parquet file of size bytes in r
+func NewReader(r io.ReaderAt, size int64) (*Reader, error) {
+	if size < 12 {
+		return nil, fmt.Errorf("not a parquet file")
+	}
+	tail := make([]byte, 8)
+	if _, err := r.ReadAt(tail, size-8); err != nil {
+		return nil, err
+	}
+	if !bytes.Equal(tail[4:], magic) {
+		return nil, fmt.Errorf("not a parquet file: missing footer magic")
+	}
+	metaSize := int64(binary.LittleEndian.Uint32(tail))
+	if metaSize > size-12 {
+		return nil, fmt.Errorf("invalid parquet file: footer length %d exceeds file size", metaSize)
+	}
+	meta := make([]byte, metaSize)
+	if _, err := r.ReadAt(meta, size-8-metaSize); err != nil {
+		return nil, err
+	}
+
+	pr := &Reader{r: r}
+	schema, groups, err := parseFooter(meta)
+	if err != nil {
+		return nil, fmt.Errorf("invalid parquet footer: %w", err)
+	}
+	if len(schema) == 0 {
+		return nil, fmt.Errorf("invalid parquet footer: empty schema")
+	}
+	leaf := 0
+	if _, err := pr.addColumns(schema, 0, nil, 0, 0, &leaf); err != nil {
+		return nil, err
+	}
+	for _, group := range groups {
+		if len(group.chunks) != leaf {
+			return nil, fmt.Errorf("invalid parquet footer: row group has %d columns, schema has %d", len(group.chunks), leaf)
+		}
+	}
+	pr.groups = groups
+	pr.group = -1
+	pr.record = make([]string, len(pr.columns))
+	return pr, nil
+}
+
+// addColumns walks the schema element at i and its children, adding readable leaf columns.
+// Returns the index of the next sibling.
+func (pr *Reader) addColumns(schema []schemaElement, i int, path []string, maxDef, maxRep int, leaf *int) (int, error) {
+	if i >= len(schema) {
+		return 0, fmt.Errorf("invalid parquet schema: missing children")
+	}
+	el := schema[i]
+	if i > 0 {
+		path = append(path, el.name)
+		switch el.repetition {
+		case repetitionOptional:
+			maxDef++
+		case repetitionRepeated:
+			maxDef++
+			maxRep++
+		}
+	}
+
+	if el.numChildren == 0 && i > 0 {
+		if el.physical < 0 {
+			// An empty group has no column chunk
+			return i + 1, nil
+		}
+		if maxRep == 0 {
+			pr.columns = append(pr.columns, column{
+				name:       strings.Join(path, "."),
+				leaf:       *leaf,
+				physical:   el.physical,
+				typeLength: el.typeLength,
+				maxDef:     maxDef,
+				decimal:    el.decimal,
+				scale:      el.scale,
+				date:       el.date,
+				timeUnit:   el.timeUnit,
+			})
+		}
+		*leaf++
+		return i + 1, nil
+	}
+
+	next := i + 1
+	for c := 0; c < el.numChildren; c++ {
+		var err error
+		if next, err = pr.addColumns(schema, next, path, maxDef, maxRep, leaf); err != nil {
+			return 0, err
+		}
+	}
+	return next, nil
+}
+
+// parseFooter decodes the schema and row groups of the FileMetaData struct
+func parseFooter(meta []byte) ([]schemaElement, []rowGroupMeta, error) {
+	var schema []schemaElement
+	var groups []rowGroupMeta
+	t := &thriftReader{buf: meta}
+	err := t.readStruct(func(id int16, typ byte) error {
+		switch id {
+		case 2:
+			return t.readList(func(byte) error {
+				el, err := parseSchemaElement(t)
+				schema = append(schema, el)
+				return err
+			})
+		case 4:
+			return t.readList(func(byte) error {
+				group, err := parseRowGroup(t)
+				groups = append(groups, group)
+				return err
+			})
+		}
+		return t.skip(typ)
+	})
+	return schema, groups, err
+}
+
+func parseSchemaElement(t *thriftReader) (schemaElement, error) {
+	el := schemaElement{physical: -1, converted: -1}
+	err := t.readStruct(func(id int16, typ byte) error {
+		switch id {
+		case 1, 2, 3, 5, 6, 7:
+			v, err := t.int()
+			switch id {
+			case 1:
+				el.physical = int32(v)
+			case 2:
+				el.typeLength = int(v)
+			case 3:
+				el.repetition = int32(v)
+			case 5:
+				el.numChildren = int(v)
+			case 6:
+				el.converted = int32(v)
+			case 7:
+				el.scale = int(v)
+			}
+			return err
+		case 4:
+			name, err := t.binary()
+			el.name = string(name)
+			return err
+		case 10:
+			return parseLogicalType(t, &el)
+		}
+		return t.skip(typ)
+	})
+
+	switch el.converted {
+	case convertedDecimal:
+		el.decimal = true
+	case convertedDate:
+		el.date = true
+	case convertedTimestampMillis:
+		el.timeUnit = time.Millisecond
+	case convertedTimestampMicros:
+		el.timeUnit = time.Microsecond
+	}
+	return el, err
+}
+
+// parseLogicalType reads the LogicalType union: DECIMAL (5), DATE (6) and
+// TIMESTAMP (8) affect how values are formatted
+func parseLogicalType(t *thriftReader, el *schemaElement) error {
+	return t.readStruct(func(id int16, typ byte) error {
+		switch id {
+		case 5:
+			el.decimal = true
+			return t.readStruct(func(id int16, typ byte) error {
+				if id == 1 {
+					scale, err := t.int()
+					el.scale = int(scale)
+					return err
+				}
+				return t.skip(typ)
+			})
+		case 6:
+			el.date = true
+		case 8:
+			return t.readStruct(func(id int16, typ byte) error {
+				if id != 2 {
+					return t.skip(typ)
+				}
+				// TimeUnit union: MILLIS (1), MICROS (2), NANOS (3)
+				return t.readStruct(func(id int16, typ byte) error {
+					switch id {
+					case 1:
+						el.timeUnit = time.Millisecond
+					case 2:
+						el.timeUnit = time.Microsecond
+					case 3:
+						el.timeUnit = time.Nanosecond
+					}
+					return t.skip(typ)
+				})
+			})
+		}
+		return t.skip(typ)
+	})
+}
+
+func parseRowGroup(t *thriftReader) (rowGroupMeta, error) {
+	var group rowGroupMeta
+	err := t.readStruct(func(id int16, typ byte) error {
+		switch id {
+		case 1:
+			return t.readList(func(byte) error {
+				chunk, err := parseColumnChunk(t)
+				group.chunks = append(group.chunks, chunk)
+				return err
+			})
+		case 3:
+			rows, err := t.int()
+			group.rows = int(rows)
+			return err
+		}
+		return t.skip(typ)
+	})
+	return group, err
+}
+
+func parseColumnChunk(t *thriftReader) (chunkMeta, error) {
+	var chunk chunkMeta
+	err := t.readStruct(func(id int16, typ byte) error {
+		switch id {
+		case 1:
+			chunk.external = true
+		case 3:
+			return t.readStruct(func(id int16, typ byte) error {
+				var err error
+				switch id {
+				case 4:
+					var codec int64
+					codec, err = t.int()
+					chunk.codec = int32(codec)
+				case 5:
+					chunk.values, err = t.int()
+				case 7:
+					chunk.size, err = t.int()
+				case 9:
+					chunk.offset, err = t.int()
+				case 11:
+					chunk.dictionary, err = t.int()
+				default:
+					err = t.skip(typ)
+				}
+				return err
+			})
+		}
+		return t.skip(typ)
+	})
+	return chunk, err
+}
+
+// Columns returns the names of the readable columns, in the order of Read's values
+func (pr *Reader) Columns() []string {
+	names := make([]string, len(pr.columns))
+	for i, col := range pr.columns {
+		names[i] = col.name
+	}
+	return names
+}
+
+// Read returns the next row, or io.EOF after the last row. The returned slice is reused
+// by the next call.
+func (pr *Reader) Read() ([]string, error) {
+	for pr.group < 0 || pr.row >= pr.groups[pr.group].rows {
+		if pr.group+1 >= len(pr.groups) {
+			return nil, io.EOF
+		}
+		pr.group++
+		pr.row = 0
+		if err := pr.readRowGroup(); err != nil {
+			return nil, err
+		}
+	}
+	for i := range pr.columns {
+		pr.record[i] = pr.values[i][pr.row]
+	}
+	pr.row++
+	return pr.record, nil
+}
+
+// readRowGroup decodes every readable column of the current row group
+func (pr *Reader) readRowGroup() error {
+	group := pr.groups[pr.group]
+	pr.values = make([][]string, len(pr.columns))
+	for i := range pr.columns {
+		col := &pr.columns[i]
+		values, err := pr.readChunk(col, group.chunks[col.leaf])
+		if err != nil {
+			return fmt.Errorf("parquet column %s: %w", col.name, err)
+		}
+		if len(values) != group.rows {
+			return fmt.Errorf("parquet column %s: %d values in a row group of %d rows", col.name, len(values), group.rows)
+		}
+		pr.values[i] = values
+	}
+	return nil
+}
+
+// readChunk decodes the pages of a column chunk
+func (pr *Reader) readChunk(col *column, chunk chunkMeta) ([]string, error) {
+	if chunk.external {
+		return nil, fmt.Errorf("column chunks in external files are not supported")
+	}
+	start := chunk.offset
+	if chunk.dictionary > 0 && chunk.dictionary < start {
+		start = chunk.dictionary
+	}
+	if start < 0 || chunk.size < 0 {
+		return nil, fmt.Errorf("invalid column chunk offset")
+	}
+	data := make([]byte, chunk.size)
+	if _, err := pr.r.ReadAt(data, start); err != nil {
+		return nil, fmt.Errorf("failed to read column chunk: %w", err)
+	}
+
+	values := make([]string, 0, chunk.values)
+	var dict []string
+	t := &thriftReader{buf: data}
+	for int64(len(values)) < chunk.values && t.pos < len(data) {
+		header, err := parsePageHeader(t)
+		if err != nil {
+			return nil, fmt.Errorf("invalid page header: %w", err)
+		}
+		if header.compressedSize < 0 || header.compressedSize > len(data)-t.pos {
+			return nil, fmt.Errorf("page exceeds column chunk")
+		}
+		page := data[t.pos : t.pos+header.compressedSize]
+		t.pos += header.compressedSize
+
+		switch header.typ {
+		case pageDictionary:
+			page, err = decompress(chunk.codec, page, header.uncompressedSize)
+			if err != nil {
+				return nil, err
+			}
+			if dict, _, err = col.plainValues(page, header.numValues); err != nil {
+				return nil, fmt.Errorf("invalid dictionary page: %w", err)
+			}
+		case pageData:
+			page, err = decompress(chunk.codec, page, header.uncompressedSize)
+			if err != nil {
+				return nil, err
+			}
+			var defs []uint32
+			if col.maxDef > 0 {
+				if header.defEncoding != encodingRLE {
+					return nil, fmt.Errorf("unsupported definition level encoding %d", header.defEncoding)
+				}
+				if len(page) < 4 {
+					return nil, fmt.Errorf("truncated definition levels")
+				}
+				n := int(binary.LittleEndian.Uint32(page))
+				if n > len(page)-4 {
+					return nil, fmt.Errorf("truncated definition levels")
+				}
+				if defs, err = rleHybrid(page[4:4+n], bitWidth(col.maxDef), header.numValues); err != nil {
+					return nil, err
+				}
+				page = page[4+n:]
+			}
+			if values, err = col.appendPage(values, page, header.encoding, header.numValues, defs, dict); err != nil {
+				return nil, err
+			}
+		case pageDataV2:
+			levels := header.defLength + header.repLength
+			if levels > len(page) {
+				return nil, fmt.Errorf("truncated levels")
+			}
+			var defs []uint32
+			if col.maxDef > 0 {
+				if defs, err = rleHybrid(page[header.repLength:levels], bitWidth(col.maxDef), header.numValues); err != nil {
+					return nil, err
+				}
+			}
+			body := page[levels:]
+			if header.compressed {
+				if body, err = decompress(chunk.codec, body, header.uncompressedSize-levels); err != nil {
+					return nil, err
+				}
+			}
+			if values, err = col.appendPage(values, body, header.encoding, header.numValues, defs, dict); err != nil {
+				return nil, err
+			}
+		}
+	}
+	return values, nil
+}
+
+// pageHeader holds the PageHeader fields of data pages (v1 and v2) and dictionary pages
+type pageHeader struct {
+	typ              int32
+	uncompressedSize int
+	compressedSize   int
+	numValues        int
+	encoding         int32
+	defEncoding      int32
+	defLength        int
+	repLength        int
+	compressed       bool
+}
+
+func parsePageHeader(t *thriftReader) (pageHeader, error) {
+	header := pageHeader{compressed: true}
+	err := t.readStruct(func(id int16, typ byte) error {
+		switch id {
+		case 1, 2, 3:
+			v, err := t.int()
+			switch id {
+			case 1:
+				header.typ = int32(v)
+			case 2:
+				header.uncompressedSize = int(v)
+			case 3:
+				header.compressedSize = int(v)
+			}
+			return err
+		case 5, 7, 8:
+			// DataPageHeader, DictionaryPageHeader and DataPageHeaderV2
+			return t.readStruct(func(field int16, typ byte) error {
+				if id == 8 && field == 7 {
+					header.compressed = typ == thriftTrue
+					return nil
+				}
+				if typ != thriftI32 {
+					return t.skip(typ)
+				}
+				v, err := t.int()
+				switch {
+				case field == 1:
+					header.numValues = int(v)
+				case field == 2 && id != 8, field == 4 && id == 8:
+					header.encoding = int32(v)
+				case field == 3 && id == 5:
+					header.defEncoding = int32(v)
+				case field == 5 && id == 8:
+					header.defLength = int(v)
+				case field == 6 && id == 8:
+					header.repLength = int(v)
+				}
+				return err
+			})
+		}
+		return t.skip(typ)
+	})
+	if header.defLength < 0 || header.repLength < 0 || header.numValues < 0 {
+		return header, fmt.Errorf("negative page size")
+	}
+	return header, err
+}
+
+// appendPage decodes the values of a data page and appends them, with "" for nulls
+func (col *column) appendPage(values []string, data []byte, encoding int32, numValues int, defs []uint32, dict []string) ([]string, error) {
+	present := numValues
+	if defs != nil {
+		present = 0
+		for _, d := range defs {
+			if int(d) == col.maxDef {
+				present++
+			}
+		}
+	}
+
+	var decoded []string
+	var err error
+	switch encoding {
+	case encodingPlain:
+		decoded, _, err = col.plainValues(data, present)
+	case encodingPlainDictionary, encodingRLEDictionary:
+		if dict == nil {
+			return nil, fmt.Errorf("dictionary encoded page without a dictionary")
+		}
+		if len(data) == 0 {
+			if present > 0 {
+				return nil, fmt.Errorf("truncated dictionary indices")
+			}
+			break
+		}
+		var indices []uint32
+		if indices, err = rleHybrid(data[1:], int(data[0]), present); err != nil {
+			return nil, err
+		}
+		decoded = make([]string, present)
+		for i, idx := range indices {
+			if int(idx) >= len(dict) {
+				return nil, fmt.Errorf("dictionary index %d out of range", idx)
+			}
+			decoded[i] = dict[idx]
+		}
+	case encodingRLE:
+		if col.physical != typeBoolean || len(data) < 4 {
+			return nil, fmt.Errorf("unsupported RLE encoded values")
+		}
+		var bits []uint32
+		if bits, err = rleHybrid(data[4:], 1, present); err != nil {
+			return nil, err
+		}
+		decoded = make([]string, present)
+		for i, b := range bits {
+			decoded[i] = strconv.FormatBool(b == 1)
+		}
+	case encodingDeltaBinaryPacked:
+		if col.physical != typeInt32 && col.physical != typeInt64 {
+			return nil, fmt.Errorf("DELTA_BINARY_PACKED encoding on a non-integer column")
+		}
+		var ints []int64
+		if ints, _, err = deltaBinaryPacked(data); err != nil {
+			return nil, err
+		}
+		decoded = make([]string, len(ints))
+		for i, v := range ints {
+			decoded[i] = col.formatInt(v)
+		}
+	case encodingDeltaLengthByteArray, encodingDeltaByteArray:
+		var raw [][]byte
+		if raw, err = deltaByteArrays(data, encoding == encodingDeltaByteArray); err != nil {
+			return nil, err
+		}
+		decoded = make([]string, len(raw))
+		for i, b := range raw {
+			decoded[i] = col.formatBytes(b)
+		}
+	default:
+		return nil, fmt.Errorf("unsupported encoding %d", encoding)
+	}
+	if err != nil {
+		return nil, err
+	}
+	if len(decoded) < present {
+		return nil, fmt.Errorf("page has %d values, expected %d", len(decoded), present)
+	}
+
+	if defs == nil {
+		return append(values, decoded[:present]...), nil
+	}
+	j := 0
+	for _, d := range defs {
+		if int(d) == col.maxDef {
+			values = append(values, decoded[j])
+			j++
+		} else {
+			values = append(values, "")
+		}
+	}
+	return values, nil
+}
+
+// plainValues decodes n PLAIN encoded values, returning the remaining data
+func (col *column) plainValues(data []byte, n int) ([]string, []byte, error) {
+	values := make([]string, n)
+	if col.physical == typeBoolean {
+		if len(data) < (n+7)/8 {
+			return nil, nil, fmt.Errorf("truncated values")
+		}
+		for i := range values {
+			values[i] = strconv.FormatBool(data[i/8]>>(i%8)&1 == 1)
+		}
+		return values, data[(n+7)/8:], nil
+	}
+
+	for i := range values {
+		var size int
+		switch col.physical {
+		case typeInt32, typeFloat:
+			size = 4
+		case typeInt64, typeDouble:
+			size = 8
+		case typeInt96:
+			size = 12
+		case typeFixedLenByteArray:
+			size = col.typeLength
+		case typeByteArray:
+			if len(data) < 4 {
+				return nil, nil, fmt.Errorf("truncated values")
+			}
+			size = int(binary.LittleEndian.Uint32(data))
+			data = data[4:]
+		default:
+			return nil, nil, fmt.Errorf("unsupported physical type %d", col.physical)
+		}
+		if size < 0 || len(data) < size {
+			return nil, nil, fmt.Errorf("truncated values")
+		}
+		v := data[:size]
+		data = data[size:]
+
+		switch col.physical {
+		case typeInt32:
+			values[i] = col.formatInt(int64(int32(binary.LittleEndian.Uint32(v))))
+		case typeInt64:
+			values[i] = col.formatInt(int64(binary.LittleEndian.Uint64(v)))
+		case typeInt96:
+			// Nanoseconds within the day, then the Julian day
+			nanos := int64(binary.LittleEndian.Uint64(v))
+			day := int64(binary.LittleEndian.Uint32(v[8:]))
+			values[i] = time.Unix((day-2440588)*86400, nanos).UTC().Format(time.RFC3339Nano)
+		case typeFloat:
+			values[i] = strconv.FormatFloat(float64(math.Float32frombits(binary.LittleEndian.Uint32(v))), 'g', -1, 32)
+		case typeDouble:
+			values[i] = strconv.FormatFloat(math.Float64frombits(binary.LittleEndian.Uint64(v)), 'g', -1, 64)
+		default:
+			values[i] = col.formatBytes(v)
+		}
+	}
+	return values, data, nil
+}
+
+// formatInt formats an INT32 or INT64 value by the column's logical type
+func (col *column) formatInt(v int64) string {
+	switch {
+	case col.decimal:
+		return formatDecimal(big.NewInt(v), col.scale)
+	case col.date:
+		return time.Unix(v*86400, 0).UTC().Format("2006-01-02")
+	case col.timeUnit > 0:
+		return time.Unix(0, v*int64(col.timeUnit)).UTC().Format(time.RFC3339Nano)
+	}
+	return strconv.FormatInt(v, 10)
+}
+
+// formatBytes formats a BYTE_ARRAY or FIXED_LEN_BYTE_ARRAY value: decimals are big-endian
+// two's complement, anything else is text
+func (col *column) formatBytes(v []byte) string {
+	if !col.decimal {
+		return string(v)
+	}
+	unscaled := new(big.Int).SetBytes(v)
+	if len(v) > 0 && v[0]&0x80 != 0 {
+		unscaled.Sub(unscaled, new(big.Int).Lsh(big.NewInt(1), uint(len(v)*8)))
+	}
+	return formatDecimal(unscaled, col.scale)
+}
+
+// formatDecimal formats unscaled * 10^-scale
+func formatDecimal(unscaled *big.Int, scale int) string {
+	s := unscaled.String()
+	if scale <= 0 {
+		return s
+	}
+	sign := ""
+	if strings.HasPrefix(s, "-") {
+		sign, s = "-", s[1:]
+	}
+	if len(s) <= scale {
+		s = strings.Repeat("0", scale-len(s)+1) + s
+	}
+	return sign + s[:len(s)-scale] + "." + s[len(s)-scale:]
+}
+
+// bitWidth returns the number of bits needed to store values up to max
+func bitWidth(max int) int {
+	width := 0
+	for ; max > 0; max >>= 1 {
+		width++
+	}
+	return width
+}
+
+// unpackBits returns the i-th value of width bits from little-endian bit-packed data
+func unpackBits(data []byte, i, width int) uint64 {
+	var v uint64
+	bit := i * width
+	for b := 0; b < width; b++ {
+		pos := bit + b
+		if pos/8 < len(data) && data[pos/8]>>(pos%8)&1 == 1 {
+			v |= 1 << b
+		}
+	}
+	return v
+}
+
+// rleHybrid decodes n values of the RLE / bit-packing hybrid encoding used for levels,
+// dictionary indices and booleans
+func rleHybrid(data []byte, width, n int) ([]uint32, error) {
+	if width > 32 {
+		return nil, fmt.Errorf("invalid bit width %d", width)
+	}
+	values := make([]uint32, 0, n)
+	byteWidth := (width + 7) / 8
+	for len(values) < n {
+		header, k := binary.Uvarint(data)
+		if k <= 0 {
+			return nil, fmt.Errorf("truncated RLE data")
+		}
+		data = data[k:]
+		count := int(header >> 1)
+		if count == 0 {
+			return nil, fmt.Errorf("empty RLE run")
+		}
+
+		if header&1 == 0 {
+			// Run of one repeated value
+			if len(data) < byteWidth {
+				return nil, fmt.Errorf("truncated RLE data")
+			}
+			var v uint32
+			for i := 0; i < byteWidth; i++ {
+				v |= uint32(data[i]) << (8 * i)
+			}
+			data = data[byteWidth:]
+			for i := 0; i < count && len(values) < n; i++ {
+				values = append(values, v)
+			}
+			continue
+		}
+
+		// Bit-packed groups of 8 values
+		count *= 8
+		size := count * width / 8
+		if size > len(data) {
+			size = len(data)
+		}
+		for i := 0; i < count && len(values) < n; i++ {
+			values = append(values, uint32(unpackBits(data[:size], i, width)))
+		}
+		data = data[size:]
+	}
+	return values, nil
+}
+
+// deltaBinaryPacked decodes DELTA_BINARY_PACKED integers, returning the remaining data
+func deltaBinaryPacked(data []byte) ([]int64, []byte, error) {
+	var header [3]uint64
+	for i := range header {
+		v, k := binary.Uvarint(data)
+		if k <= 0 {
+			return nil, nil, fmt.Errorf("truncated delta header")
+		}
+		header[i], data = v, data[k:]
+	}
+	blockSize, miniblocks, total := header[0], header[1], header[2]
+	first, k := binary.Uvarint(data)
+	if k <= 0 || miniblocks == 0 || blockSize%miniblocks != 0 || blockSize/miniblocks%8 != 0 {
+		return nil, nil, fmt.Errorf("invalid delta header")
+	}
+	data = data[k:]
+	perMiniblock := int(blockSize / miniblocks)
+
+	values := make([]int64, 0, min(total, 1<<16))
+	last := unzigzag(first)
+	if total > 0 {
+		values = append(values, last)
+	}
+	for uint64(len(values)) < total {
+		minDelta, k := binary.Uvarint(data)
+		if k <= 0 || len(data)-k < int(miniblocks) {
+			return nil, nil, fmt.Errorf("truncated delta block")
+		}
+		widths := data[k : k+int(miniblocks)]
+		data = data[k+int(miniblocks):]
+		for _, width := range widths {
+			if uint64(len(values)) >= total {
+				break
+			}
+			if width > 64 {
+				return nil, nil, fmt.Errorf("invalid delta bit width %d", width)
+			}
+			size := perMiniblock * int(width) / 8
+			if len(data) < size {
+				return nil, nil, fmt.Errorf("truncated delta miniblock")
+			}
+			for i := 0; i < perMiniblock && uint64(len(values)) < total; i++ {
+				last += unzigzag(minDelta) + int64(unpackBits(data[:size], i, int(width)))
+				values = append(values, last)
+			}
+			data = data[size:]
+		}
+	}
+	return values, data, nil
+}
+
+// deltaByteArrays decodes DELTA_LENGTH_BYTE_ARRAY values or, with prefixed, DELTA_BYTE_ARRAY
+// values (each a prefix of the previous value followed by a suffix)
+func deltaByteArrays(data []byte, prefixed bool) ([][]byte, error) {
+	var prefixes []int64
+	var err error
+	if prefixed {
+		if prefixes, data, err = deltaBinaryPacked(data); err != nil {
+			return nil, err
+		}
+	}
+	lengths, data, err := deltaBinaryPacked(data)
+	if err != nil {
+		return nil, err
+	}
+	if prefixed && len(prefixes) != len(lengths) {
+		return nil, fmt.Errorf("delta prefix and suffix counts differ")
+	}
+
+	values := make([][]byte, len(lengths))
+	var prev []byte
+	for i, n := range lengths {
+		if n < 0 || n > int64(len(data)) {
+			return nil, fmt.Errorf("truncated delta byte array")
+		}
+		v := data[:n]
+		data = data[n:]
+		if prefixed {
+			p := prefixes[i]
+			if p < 0 || p > int64(len(prev)) {
+				return nil, fmt.Errorf("invalid delta prefix length")
+			}
+			v = append(append([]byte{}, prev[:p]...), v...)
+		}
+		values[i] = v
+		prev = v
+	}
+	return values, nil
+}
+
+// decompress decompresses a page with the column chunk's codec
+func decompress(codec int32, data []byte, size int) ([]byte, error) {
+	switch codec {
+	case codecUncompressed:
+		return data, nil
+	case codecSnappy:
+		return snappyDecode(data)
+	case codecGzip:
+		gz, err := gzip.NewReader(bytes.NewReader(data))
+		if err != nil {
+			return nil, err
+		}
+		defer gz.Close()
+		out := bytes.NewBuffer(make([]byte, 0, max(size, 0)))
+		if _, err := io.Copy(out, gz); err != nil {
+			return nil, err
+		}
+		return out.Bytes(), nil
+	}
+	return nil, fmt.Errorf("unsupported compression codec %d (expected uncompressed, snappy or gzip)", codec)
+}
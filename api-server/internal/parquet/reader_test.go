@@ -0,0 +1,307 @@
+package parquet
+
+import (
+	"bytes"
+	"encoding/binary"
+	"io"
+	"math"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// thriftWriter encodes the compact protocol structs of test files
+type thriftWriter struct {
+	buf    []byte
+	fields []int16 // last field id of each open struct
+}
+
+func zigzag(v int64) uint64 {
+	return uint64((v << 1) ^ (v >> 63))
+}
+
+func (t *thriftWriter) varint(v uint64) {
+	t.buf = binary.AppendUvarint(t.buf, v)
+}
+
+func (t *thriftWriter) fieldHeader(id int16, typ byte) {
+	last := &t.fields[len(t.fields)-1]
+	if delta := id - *last; delta > 0 && delta <= 15 {
+		t.buf = append(t.buf, byte(delta)<<4|typ)
+	} else {
+		t.buf = append(t.buf, typ)
+		t.varint(zigzag(int64(id)))
+	}
+	*last = id
+}
+
+func (t *thriftWriter) i32(id int16, v int32) {
+	t.fieldHeader(id, thriftI32)
+	t.varint(zigzag(int64(v)))
+}
+
+func (t *thriftWriter) i64(id int16, v int64) {
+	t.fieldHeader(id, thriftI64)
+	t.varint(zigzag(v))
+}
+
+func (t *thriftWriter) string(id int16, v string) {
+	t.fieldHeader(id, thriftBinary)
+	t.varint(uint64(len(v)))
+	t.buf = append(t.buf, v...)
+}
+
+func (t *thriftWriter) listHeader(id int16, elemType byte, size int) {
+	t.fieldHeader(id, thriftList)
+	t.buf = append(t.buf, byte(size)<<4|elemType)
+}
+
+func (t *thriftWriter) beginStruct(id int16, inList bool) {
+	if len(t.fields) > 0 && !inList {
+		t.fieldHeader(id, thriftStruct)
+	}
+	t.fields = append(t.fields, 0)
+}
+
+func (t *thriftWriter) endStruct() {
+	t.buf = append(t.buf, 0)
+	t.fields = t.fields[:len(t.fields)-1]
+}
+
+// snappyLiteral encodes data as a Snappy block of one literal
+func snappyLiteral(data []byte) []byte {
+	out := binary.AppendUvarint(nil, uint64(len(data)))
+	out = append(out, byte(len(data)-1)<<2)
+	return append(out, data...)
+}
+
+// testPage encodes a page header of type typ, with its type specific header written by
+// header, followed by the page data
+func testPage(typ int32, uncompressed int, data []byte, header func(*thriftWriter)) []byte {
+	var h thriftWriter
+	h.beginStruct(0, false)
+	h.i32(1, typ)
+	h.i32(2, int32(uncompressed))
+	h.i32(3, int32(len(data)))
+	header(&h)
+	h.endStruct()
+	return append(h.buf, data...)
+}
+
+// testDataPage is an uncompressed v1 data page of n values
+func testDataPage(n int, encoding int32, data []byte) []byte {
+	return testPage(pageData, len(data), data, func(h *thriftWriter) {
+		h.beginStruct(5, false)
+		h.i32(1, int32(n))
+		h.i32(2, encoding)
+		h.i32(3, encodingRLE)
+		h.i32(4, encodingRLE)
+		h.endStruct()
+	})
+}
+
+// testColumn is a schema element of a test file: a group with children, or a leaf column
+// with its column chunk
+type testColumn struct {
+	name       string
+	children   int32
+	physical   int32
+	repetition int32
+	converted  int32 // 0: none
+	scale      int32
+	precision  int32
+	codec      int32
+	chunk      []byte
+	dictionary int // length of the dictionary page at the start of chunk
+}
+
+// buildTestFile assembles a Parquet file of one row group from a flattened schema
+func buildTestFile(rows int64, columns []testColumn) []byte {
+	file := append([]byte(nil), magic...)
+	offsets := make([]int64, len(columns))
+	for i, col := range columns {
+		offsets[i] = int64(len(file))
+		file = append(file, col.chunk...)
+	}
+
+	var meta thriftWriter
+	meta.beginStruct(0, false)
+	meta.i32(1, 1)
+	meta.listHeader(2, thriftStruct, len(columns)+1)
+	meta.beginStruct(0, true)
+	meta.string(4, "schema")
+	var top int32
+	for i := 0; i < len(columns); i += int(columns[i].children) + 1 {
+		top++
+	}
+	meta.i32(5, top)
+	meta.endStruct()
+	leaves := 0
+	for _, col := range columns {
+		meta.beginStruct(0, true)
+		if col.children == 0 {
+			meta.i32(1, col.physical)
+			leaves++
+		}
+		meta.i32(3, col.repetition)
+		meta.string(4, col.name)
+		if col.children > 0 {
+			meta.i32(5, col.children)
+		}
+		if col.converted != 0 {
+			meta.i32(6, col.converted)
+		}
+		if col.precision > 0 {
+			meta.i32(7, col.scale)
+			meta.i32(8, col.precision)
+		}
+		meta.endStruct()
+	}
+	meta.i64(3, rows)
+	meta.listHeader(4, thriftStruct, 1)
+	meta.beginStruct(0, true)
+	meta.listHeader(1, thriftStruct, leaves)
+	for i, col := range columns {
+		if col.children > 0 {
+			continue
+		}
+		var offset int64
+		if len(col.chunk) > 0 {
+			offset = offsets[i]
+		}
+		meta.beginStruct(0, true)
+		meta.i64(2, offset)
+		meta.beginStruct(3, false)
+		meta.i32(1, col.physical)
+		meta.i32(4, col.codec)
+		meta.i64(5, rows)
+		meta.i64(6, int64(len(col.chunk)))
+		meta.i64(7, int64(len(col.chunk)))
+		meta.i64(9, offset+int64(col.dictionary))
+		if col.dictionary > 0 {
+			meta.i64(11, offset)
+		}
+		meta.endStruct()
+		meta.endStruct()
+	}
+	meta.i64(2, int64(len(file)))
+	meta.i64(3, rows)
+	meta.endStruct()
+	meta.endStruct()
+
+	file = append(file, meta.buf...)
+	file = binary.LittleEndian.AppendUint32(file, uint32(len(meta.buf)))
+	return append(file, magic...)
+}
+
+func readAll(t *testing.T, file []byte) ([]string, [][]string) {
+	r, err := NewReader(bytes.NewReader(file), int64(len(file)))
+	require.NoError(t, err)
+	var rows [][]string
+	for {
+		record, err := r.Read()
+		if err == io.EOF {
+			break
+		}
+		require.NoError(t, err)
+		rows = append(rows, append([]string(nil), record...))
+	}
+	return r.Columns(), rows
+}
+
+func TestReader_Plain(t *testing.T) {
+	var times, costs []byte
+	for _, ms := range []int64{1709251200000, 1709254800000} {
+		times = binary.LittleEndian.AppendUint64(times, uint64(ms))
+	}
+	for _, cost := range []float64{0.192, 1.5} {
+		costs = binary.LittleEndian.AppendUint64(costs, math.Float64bits(cost))
+	}
+	// Definition levels 1, 0 as one bit-packed group, then the single present value
+	day := []byte{2, 0, 0, 0, 3, 0b01}
+	day = binary.LittleEndian.AppendUint32(day, 19783)
+	ids := []byte{3, 0, 0, 0, 'i', '-', '1', 3, 0, 0, 0, 'i', '-', '2'}
+
+	columns, rows := readAll(t, buildTestFile(2, []testColumn{
+		{name: "time", physical: typeInt64, converted: convertedTimestampMillis, chunk: testDataPage(2, encodingPlain, times)},
+		{name: "cost", physical: typeDouble, chunk: testDataPage(2, encodingPlain, costs)},
+		{name: "spot", physical: typeBoolean, chunk: testDataPage(2, encodingPlain, []byte{0b01})},
+		{name: "day", physical: typeInt32, repetition: repetitionOptional, converted: convertedDate, chunk: testDataPage(2, encodingPlain, day)},
+		{name: "resource", children: 1},
+		{name: "id", physical: typeByteArray, chunk: testDataPage(2, encodingPlain, ids)},
+	}))
+	assert.Equal(t, []string{"time", "cost", "spot", "day", "resource.id"}, columns)
+	assert.Equal(t, [][]string{
+		{"2024-03-01T00:00:00Z", "0.192", "true", "2024-03-01", "i-1"},
+		{"2024-03-01T01:00:00Z", "1.5", "false", "", "i-2"},
+	}, rows)
+}
+
+func TestReader_Encodings(t *testing.T) {
+	// "name": optional, dictionary encoded, Snappy compressed; values b, null, a
+	dict := []byte{1, 0, 0, 0, 'a', 1, 0, 0, 0, 'b'}
+	dictPage := testPage(pageDictionary, len(dict), snappyLiteral(dict), func(h *thriftWriter) {
+		h.beginStruct(7, false)
+		h.i32(1, 2)
+		h.i32(2, encodingPlain)
+		h.endStruct()
+	})
+	// Definition levels 1,0,1 and dictionary indices 1,0, each as one bit-packed group
+	data := []byte{2, 0, 0, 0, 3, 0b101, 1, 3, 0b01}
+	dataPage := testPage(pageData, len(data), snappyLiteral(data), func(h *thriftWriter) {
+		h.beginStruct(5, false)
+		h.i32(1, 3)
+		h.i32(2, encodingRLEDictionary)
+		h.i32(3, encodingRLE)
+		h.i32(4, encodingRLE)
+		h.endStruct()
+	})
+
+	// "amount": required DECIMAL(10,2) INT64, DELTA_BINARY_PACKED in an uncompressed v2 page;
+	// values 1234, -5, 100 are deltas -1239, +105: min delta -1239, packed 0 and 1344 in 11 bits
+	delta := binary.AppendUvarint(nil, 128)
+	delta = binary.AppendUvarint(delta, 4)
+	delta = binary.AppendUvarint(delta, 3)
+	delta = binary.AppendUvarint(delta, zigzag(1234))
+	delta = binary.AppendUvarint(delta, zigzag(-1239))
+	delta = append(delta, 11, 0, 0, 0)
+	miniblock := make([]byte, 32*11/8)
+	binary.LittleEndian.PutUint32(miniblock, 1344<<11)
+	delta = append(delta, miniblock...)
+	amountPage := testPage(pageDataV2, len(delta), delta, func(h *thriftWriter) {
+		h.beginStruct(8, false)
+		h.i32(1, 3)
+		h.i32(2, 0)
+		h.i32(3, 3)
+		h.i32(4, encodingDeltaBinaryPacked)
+		h.i32(5, 0)
+		h.i32(6, 0)
+		h.fieldHeader(7, thriftFalse)
+		h.endStruct()
+	})
+
+	columns, rows := readAll(t, buildTestFile(3, []testColumn{
+		{name: "name", physical: typeByteArray, repetition: repetitionOptional, codec: codecSnappy,
+			chunk: append(dictPage, dataPage...), dictionary: len(dictPage)},
+		{name: "tags", physical: typeByteArray, repetition: repetitionRepeated}, // never read
+		{name: "amount", physical: typeInt64, converted: convertedDecimal, scale: 2, precision: 10, codec: codecSnappy, chunk: amountPage},
+	}))
+	assert.Equal(t, []string{"name", "amount"}, columns)
+	assert.Equal(t, [][]string{{"b", "12.34"}, {"", "-0.05"}, {"a", "1.00"}}, rows)
+
+	_, err := NewReader(bytes.NewReader([]byte("PAR1 not parquet")), 16)
+	assert.Error(t, err)
+}
+
+func TestSnappyDecode(t *testing.T) {
+	// Literal "abc" then an overlapping copy of 6 bytes at offset 3
+	out, err := snappyDecode([]byte{9, 2 << 2, 'a', 'b', 'c', (6-4)<<2 | 1, 3})
+	require.NoError(t, err)
+	assert.Equal(t, "abcabcabc", string(out))
+
+	_, err = snappyDecode([]byte{9, 2 << 2, 'a', 'b', 'c'})
+	assert.Error(t, err)
+	_, err = snappyDecode([]byte{4, 1, 9})
+	assert.Error(t, err)
+}
@@ -0,0 +1,76 @@
+package parquet
+
+import (
+	"encoding/binary"
+	"fmt"
+)
+
+// snappyDecode decodes a Snappy block (the raw format used by Parquet, without framing)
+func snappyDecode(src []byte) ([]byte, error) {
+	size, k := binary.Uvarint(src)
+	if k <= 0 || size > uint64(len(src))*255 {
+		return nil, fmt.Errorf("invalid snappy data")
+	}
+	dst := make([]byte, 0, size)
+	src = src[k:]
+	for len(src) > 0 {
+		tag := src[0]
+		var length, offset int
+		switch tag & 3 {
+		case 0:
+			// Literal, with its length in the tag or the following 1-4 bytes
+			length = int(tag >> 2)
+			src = src[1:]
+			if length >= 60 {
+				n := length - 59
+				if len(src) < n {
+					return nil, fmt.Errorf("invalid snappy data")
+				}
+				length = 0
+				for i := 0; i < n; i++ {
+					length |= int(src[i]) << (8 * i)
+				}
+				src = src[n:]
+			}
+			length++
+			if length <= 0 || length > len(src) {
+				return nil, fmt.Errorf("invalid snappy data")
+			}
+			dst = append(dst, src[:length]...)
+			src = src[length:]
+			continue
+		case 1:
+			if len(src) < 2 {
+				return nil, fmt.Errorf("invalid snappy data")
+			}
+			length = 4 + int(tag>>2&7)
+			offset = int(tag&0xE0)<<3 | int(src[1])
+			src = src[2:]
+		case 2:
+			if len(src) < 3 {
+				return nil, fmt.Errorf("invalid snappy data")
+			}
+			length = 1 + int(tag>>2)
+			offset = int(binary.LittleEndian.Uint16(src[1:]))
+			src = src[3:]
+		case 3:
+			if len(src) < 5 {
+				return nil, fmt.Errorf("invalid snappy data")
+			}
+			length = 1 + int(tag>>2)
+			offset = int(binary.LittleEndian.Uint32(src[1:]))
+			src = src[5:]
+		}
+		if offset <= 0 || offset > len(dst) {
+			return nil, fmt.Errorf("invalid snappy data")
+		}
+		// Copies may overlap their own output
+		for i := 0; i < length; i++ {
+			dst = append(dst, dst[len(dst)-offset])
+		}
+	}
+	if uint64(len(dst)) != size {
+		return nil, fmt.Errorf("invalid snappy data: length mismatch")
+	}
+	return dst, nil
+}
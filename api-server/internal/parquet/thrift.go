@@ -0,0 +1,169 @@
+package parquet
+
+import (
+	"encoding/binary"
+	"errors"
+)
+
+// Thrift compact protocol type codes, as used by Parquet's page headers and file footer
+const (
+	thriftTrue   = 1
+	thriftFalse  = 2
+	thriftByte   = 3
+	thriftI16    = 4
+	thriftI32    = 5
+	thriftI64    = 6
+	thriftDouble = 7
+	thriftBinary = 8
+	thriftList   = 9
+	thriftSet    = 10
+	thriftMap    = 11
+	thriftStruct = 12
+)
+
+var errThriftTruncated = errors.New("truncated thrift data")
+
+func unzigzag(v uint64) int64 {
+	return int64(v>>1) ^ -int64(v&1)
+}
+
+// thriftReader decodes Thrift structs encoded with the compact protocol. Callers visit the
+// fields of a struct with readStruct and either read or skip each value.
+type thriftReader struct {
+	buf []byte
+	pos int
+}
+
+func (t *thriftReader) byte() (byte, error) {
+	if t.pos >= len(t.buf) {
+		return 0, errThriftTruncated
+	}
+	t.pos++
+	return t.buf[t.pos-1], nil
+}
+
+func (t *thriftReader) uvarint() (uint64, error) {
+	v, n := binary.Uvarint(t.buf[t.pos:])
+	if n <= 0 {
+		return 0, errThriftTruncated
+	}
+	t.pos += n
+	return v, nil
+}
+
+// int reads an i16, i32 or i64 value
+func (t *thriftReader) int() (int64, error) {
+	v, err := t.uvarint()
+	return unzigzag(v), err
+}
+
+func (t *thriftReader) binary() ([]byte, error) {
+	n, err := t.uvarint()
+	if err != nil {
+		return nil, err
+	}
+	if n > uint64(len(t.buf)-t.pos) {
+		return nil, errThriftTruncated
+	}
+	t.pos += int(n)
+	return t.buf[t.pos-int(n) : t.pos], nil
+}
+
+// readStruct calls field for each field of a struct until its stop field. field must read
+// or skip the value; boolean values are carried in typ (thriftTrue or thriftFalse).
+func (t *thriftReader) readStruct(field func(id int16, typ byte) error) error {
+	var last int16
+	for {
+		b, err := t.byte()
+		if err != nil {
+			return err
+		}
+		if b == 0 {
+			return nil
+		}
+		typ := b & 0x0F
+		if delta := int16(b >> 4); delta != 0 {
+			last += delta
+		} else {
+			id, err := t.int()
+			if err != nil {
+				return err
+			}
+			last = int16(id)
+		}
+		if err := field(last, typ); err != nil {
+			return err
+		}
+	}
+}
+
+// readList calls elem for each element of a list or set
+func (t *thriftReader) readList(elem func(typ byte) error) error {
+	h, err := t.byte()
+	if err != nil {
+		return err
+	}
+	size := uint64(h >> 4)
+	if size == 15 {
+		if size, err = t.uvarint(); err != nil {
+			return err
+		}
+	}
+	if size > uint64(len(t.buf)-t.pos) {
+		return errThriftTruncated
+	}
+	for i := uint64(0); i < size; i++ {
+		if err := elem(h & 0x0F); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// skip skips a struct field value of type typ
+func (t *thriftReader) skip(typ byte) error {
+	switch typ {
+	case thriftTrue, thriftFalse:
+		return nil
+	}
+	return t.skipValue(typ)
+}
+
+// skipValue skips a value of type typ inside a container, where booleans take a byte
+func (t *thriftReader) skipValue(typ byte) error {
+	var err error
+	switch typ {
+	case thriftTrue, thriftFalse, thriftByte:
+		_, err = t.byte()
+	case thriftI16, thriftI32, thriftI64:
+		_, err = t.uvarint()
+	case thriftDouble:
+		if len(t.buf)-t.pos < 8 {
+			return errThriftTruncated
+		}
+		t.pos += 8
+	case thriftBinary:
+		_, err = t.binary()
+	case thriftList, thriftSet:
+		err = t.readList(t.skipValue)
+	case thriftMap:
+		var size uint64
+		if size, err = t.uvarint(); err != nil || size == 0 {
+			return err
+		}
+		var kv byte
+		if kv, err = t.byte(); err != nil {
+			return err
+		}
+		for i := uint64(0); i < size && err == nil; i++ {
+			if err = t.skipValue(kv >> 4); err == nil {
+				err = t.skipValue(kv & 0x0F)
+			}
+		}
+	case thriftStruct:
+		err = t.readStruct(func(_ int16, typ byte) error { return t.skip(typ) })
+	default:
+		err = errors.New("invalid thrift type")
+	}
+	return err
+}
@@ -0,0 +1,33 @@
+package services
+
+// Container-level allocation reads the per-container metrics the agent reports in
+// pod_metrics.containers. Each pod row is expanded into one row per container; pods without
+// container metrics (older agents) keep a single row costed at the pod's own values and
+// are reported under __unallocated__.
+
+// podMetricsByContainer expands pod_metrics rows into their containers. containers may be
+// NULL or a JSON null when the agent sent none.
+const podMetricsByContainer = `pod_metrics
+		LEFT JOIN LATERAL jsonb_array_elements(
+			CASE WHEN jsonb_typeof(containers) = 'array' THEN containers ELSE '[]'::jsonb END
+		) AS c(container) ON true`
+
+// containerNameExpr is the name of the container a row was expanded into, empty for pods
+// without container metrics
+const containerNameExpr = "COALESCE(container->>'container_name', '')"
+
+// containerAggregateExpr names a row's container allocation
+const containerAggregateExpr = "COALESCE(NULLIF(container->>'container_name', ''), '__unallocated__')"
+
+// podMetricColumns are the CPU usage, CPU request, memory usage and memory request columns
+// of a pod row
+var podMetricColumns = [4]string{"cpu_millicores", "cpu_request_millicores", "memory_bytes", "memory_request_bytes"}
+
+// containerMetricColumns are the same values for a container row, falling back to the pod's
+// for pods without container metrics
+var containerMetricColumns = [4]string{
+	"COALESCE((container->>'cpu_usage_millicores')::bigint, cpu_millicores)",
+	"COALESCE((container->>'cpu_request_millicores')::bigint, cpu_request_millicores)",
+	"COALESCE((container->>'memory_usage_bytes')::bigint, memory_bytes)",
+	"COALESCE((container->>'memory_request_bytes')::bigint, memory_request_bytes)",
+}
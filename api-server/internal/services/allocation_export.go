@@ -0,0 +1,85 @@
+package services
+
+import (
+	"encoding/json"
+	"sort"
+
+	"github.com/bugfreev587/k8s-cost-api-server/internal/export"
+)
+
+// AllocationExportColumns are the columns of a flattened allocation export: one row per
+// allocation per step, with its properties, usage and cost fields
+var AllocationExportColumns = []export.Column{
+	{Name: "window_start", Type: export.Time},
+	{Name: "window_end", Type: export.Time},
+	{Name: "name", Type: export.String},
+	{Name: "cluster", Type: export.String},
+	{Name: "node", Type: export.String},
+	{Name: "namespace", Type: export.String},
+	{Name: "controller_kind", Type: export.String},
+	{Name: "controller", Type: export.String},
+	{Name: "pod", Type: export.String},
+	{Name: "container", Type: export.String},
+	{Name: "labels", Type: export.String}, // JSON object
+	{Name: "currency", Type: export.String},
+	{Name: "minutes", Type: export.Float},
+	{Name: "pod_count", Type: export.Int},
+	{Name: "cpu_cores", Type: export.Float},
+	{Name: "cpu_core_request_average", Type: export.Float},
+	{Name: "cpu_core_usage_average", Type: export.Float},
+	{Name: "cpu_core_hours", Type: export.Float},
+	{Name: "cpu_cost", Type: export.Float},
+	{Name: "cpu_efficiency", Type: export.Float},
+	{Name: "ram_bytes", Type: export.Float},
+	{Name: "ram_byte_request_average", Type: export.Float},
+	{Name: "ram_byte_usage_average", Type: export.Float},
+	{Name: "ram_byte_hours", Type: export.Float},
+	{Name: "ram_cost", Type: export.Float},
+	{Name: "ram_efficiency", Type: export.Float},
+	{Name: "shared_idle_cost", Type: export.Float},
+	{Name: "shared_cost", Type: export.Float},
+	{Name: "external_cost", Type: export.Float},
+	{Name: "list_cost", Type: export.Float},
+	{Name: "total_cost", Type: export.Float},
+	{Name: "total_efficiency", Type: export.Float},
+}
+
+// WriteAllocationSetRows writes one step's allocation set as AllocationExportColumns rows, by
+// allocation name. Exports call it for each set from EachAllocationSet, so a file covering
+// many steps never holds more than one step in memory
+func WriteAllocationSetRows(w export.Writer, set AllocationSet, currency string) error {
+	if currency == "" {
+		currency = "USD"
+	}
+	names := make([]string, 0, len(set.Allocations))
+	for name := range set.Allocations {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	for _, name := range names {
+		a := set.Allocations[name]
+		labels := ""
+		if len(a.Properties.Labels) > 0 {
+			b, _ := json.Marshal(a.Properties.Labels)
+			labels = string(b)
+		}
+		start, end := a.Start, a.End
+		if start.IsZero() {
+			start, end = set.Window.Start, set.Window.End
+		}
+		err := w.WriteRow(
+			start, end, name,
+			a.Properties.Cluster, a.Properties.Node, a.Properties.Namespace,
+			a.Properties.ControllerKind, a.Properties.Controller, a.Properties.Pod, a.Properties.Container,
+			labels, currency, a.Minutes, a.PodCount,
+			a.CPUCores, a.CPUCoreRequestAvg, a.CPUCoreUsageAvg, a.CPUCoreHours, a.CPUCost, a.CPUEfficiency,
+			a.RAMBytes, a.RAMByteRequestAvg, a.RAMByteUsageAvg, a.RAMByteHours, a.RAMCost, a.RAMEfficiency,
+			a.SharedIdleCost, a.SharedCost, a.ExternalCost, a.ListCost, a.TotalCost, a.TotalEfficiency,
+		)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,56 @@
+package services
+
+import (
+	"bytes"
+	"encoding/csv"
+	"testing"
+	"time"
+
+	"github.com/bugfreev587/k8s-cost-api-server/internal/export"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestWriteAllocationSetRows(t *testing.T) {
+	day := time.Date(2026, 3, 31, 0, 0, 0, 0, time.UTC)
+	window := TimeWindow{Start: day, End: day.Add(24 * time.Hour)}
+	set := AllocationSet{
+		Window: window,
+		Allocations: map[string]*Allocation{
+			"shop": {
+				Name:       "shop",
+				Start:      day.Add(time.Hour),
+				End:        day.Add(2 * time.Hour),
+				TotalCost:  3.5,
+				Properties: AllocationProps{Cluster: "prod", Namespace: "shop", Labels: map[string]string{"team": "checkout"}},
+			},
+			"__idle__": {Name: "__idle__", TotalCost: 6},
+		},
+	}
+
+	var buf bytes.Buffer
+	w, err := export.NewWriter(export.FormatCSV, &buf, AllocationExportColumns)
+	require.NoError(t, err)
+	require.NoError(t, WriteAllocationSetRows(w, set, ""))
+	require.NoError(t, w.Close())
+
+	records, err := csv.NewReader(&buf).ReadAll()
+	require.NoError(t, err)
+	require.Len(t, records, 3)
+	col := make(map[string]int)
+	for i, name := range records[0] {
+		col[name] = i
+	}
+
+	// Rows are ordered by name; an allocation without its own window gets the set's
+	idle, shop := records[1], records[2]
+	assert.Equal(t, "__idle__", idle[col["name"]])
+	assert.Equal(t, window.Start.Format(time.RFC3339), idle[col["window_start"]])
+	assert.Equal(t, "USD", idle[col["currency"]])
+	assert.Equal(t, "", idle[col["labels"]])
+
+	assert.Equal(t, "shop", shop[col["name"]])
+	assert.Equal(t, day.Add(time.Hour).Format(time.RFC3339), shop[col["window_start"]])
+	assert.Equal(t, `{"team":"checkout"}`, shop[col["labels"]])
+	assert.Equal(t, "3.5", shop[col["total_cost"]])
+}
@@ -0,0 +1,151 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"strings"
+	"time"
+
+	"github.com/bugfreev587/k8s-cost-api-server/internal/models"
+)
+
+// mergeExternalCosts adds the tenant's external line items overlapping the window to the
+// allocations whose name matches the item's tags for the requested aggregation. Items are
+// converted to USD at the exchange rate effective at their start and prorated to the window;
+// items with no matching allocation get an allocation of their own. Uploads are checked
+// for exchange rates, so items left without one (their rate was deleted since) are skipped
+// and logged rather than failing the query. Returns the total external cost merged.
+func (s *AllocationService) mergeExternalCosts(ctx context.Context, tenantID int64, allocations map[string]*Allocation, start, end time.Time, params AllocationParams) (float64, error) {
+	if s.postgresDB == nil {
+		return 0, nil
+	}
+
+	costs, err := NewExternalCostService(s.postgresDB).ListExternalCosts(ctx, uint(tenantID), start, end)
+	if err != nil {
+		return 0, fmt.Errorf("failed to load external costs: %w", err)
+	}
+
+	currencySvc := NewCurrencyService(s.postgresDB)
+	converters := make(map[string]*CurrencyConverter)
+	for _, cost := range costs {
+		if _, ok := converters[cost.Currency]; ok {
+			continue
+		}
+		converter, err := currencySvc.PartialConverter(ctx, uint(tenantID), cost.Currency, end)
+		if err != nil {
+			return 0, fmt.Errorf("failed to convert external costs: %w", err)
+		}
+		converters[cost.Currency] = converter
+	}
+
+	aggregates := strings.Split(params.Aggregate, ",")
+	var total float64
+	for _, cost := range costs {
+		if !externalCostMatchesFilter(cost.Tags, params.filter) {
+			continue
+		}
+		converter := converters[cost.Currency]
+		if !converter.Covers(cost.StartTime) {
+			log.Printf("external cost %d (tenant %d) skipped: %v", cost.ID, tenantID,
+				&MissingRateError{Currency: cost.Currency, At: cost.StartTime})
+			continue
+		}
+		amount := converter.ToBase(cost.Amount, cost.StartTime) * windowOverlap(cost.StartTime, cost.EndTime, start, end)
+		if amount == 0 {
+			continue
+		}
+
+		name := externalAllocationName(cost.Tags, aggregates)
+		alloc, ok := allocations[name]
+		if !ok {
+			alloc = &Allocation{
+				Name:    name,
+				Window:  TimeWindow{Start: start, End: end},
+				Start:   start,
+				End:     end,
+				Minutes: end.Sub(start).Minutes(),
+				Properties: AllocationProps{
+					Cluster:   cost.Tags["cluster"],
+					Namespace: cost.Tags["namespace"],
+				},
+			}
+			allocations[name] = alloc
+		}
+		alloc.ExternalCost += amount
+		alloc.TotalCost += amount
+		alloc.ListCost += amount
+		total += amount
+	}
+	return total, nil
+}
+
+// externalAllocationName builds the allocation name an external line item belongs to.
+// Namespace, cluster and label aggregations read the tag of the same name; other
+// aggregations (node, pod, controller, container) and missing tags map to "__unallocated__".
+func externalAllocationName(tags models.CostTags, aggregates []string) string {
+	parts := make([]string, 0, len(aggregates))
+	for _, agg := range aggregates {
+		agg = strings.TrimSpace(strings.ToLower(agg))
+		if agg == "" {
+			agg = "namespace"
+		}
+
+		var key string
+		switch {
+		case strings.HasPrefix(agg, "label:"):
+			key = strings.TrimPrefix(agg, "label:")
+		case agg == "namespace", agg == "cluster":
+			key = agg
+		}
+
+		value := tags[key]
+		if key == "" || value == "" {
+			value = "__unallocated__"
+		}
+		parts = append(parts, value)
+	}
+	return strings.Join(parts, "/")
+}
+
+// externalCostMatchesFilter applies an allocation filter to an external line item's tags.
+// Node, pod and controller conditions never match since external costs are not tied to
+// cluster workloads.
+func externalCostMatchesFilter(tags models.CostTags, filter Filter) bool {
+	if filter == nil {
+		return true
+	}
+	return filter.matches(func(field, key string) (string, bool) {
+		switch field {
+		case "namespace", "cluster":
+			return tags[field], true
+		case "label":
+			return tags[key], true
+		}
+		return "", false
+	})
+}
+
+// windowOverlap returns the fraction of [itemStart, itemEnd] falling within [start, end).
+// Point-in-time items count fully when they fall inside the window.
+func windowOverlap(itemStart, itemEnd, start, end time.Time) float64 {
+	if !itemEnd.After(itemStart) {
+		if !itemStart.Before(start) && itemStart.Before(end) {
+			return 1
+		}
+		return 0
+	}
+
+	overlapStart := itemStart
+	if start.After(overlapStart) {
+		overlapStart = start
+	}
+	overlapEnd := itemEnd
+	if end.Before(overlapEnd) {
+		overlapEnd = end
+	}
+	if !overlapEnd.After(overlapStart) {
+		return 0
+	}
+	return overlapEnd.Sub(overlapStart).Seconds() / itemEnd.Sub(itemStart).Seconds()
+}
@@ -0,0 +1,53 @@
+package services
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/bugfreev587/k8s-cost-api-server/internal/models"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestWindowOverlap(t *testing.T) {
+	day := func(d int) time.Time { return time.Date(2024, 3, d, 0, 0, 0, 0, time.UTC) }
+	tests := []struct {
+		name                           string
+		itemStart, itemEnd, start, end time.Time
+		want                           float64
+	}{
+		{name: "inside", itemStart: day(2), itemEnd: day(3), start: day(1), end: day(5), want: 1},
+		{name: "covers window", itemStart: day(1), itemEnd: day(11), start: day(2), end: day(4), want: 0.2},
+		{name: "starts before", itemStart: day(1), itemEnd: day(3), start: day(2), end: day(5), want: 0.5},
+		{name: "ends after", itemStart: day(4), itemEnd: day(8), start: day(1), end: day(5), want: 0.25},
+		{name: "disjoint", itemStart: day(6), itemEnd: day(7), start: day(1), end: day(5), want: 0},
+		{name: "point inside", itemStart: day(2), itemEnd: day(2), start: day(1), end: day(5), want: 1},
+		{name: "point at end", itemStart: day(5), itemEnd: day(5), start: day(1), end: day(5), want: 0},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.InDelta(t, tt.want, windowOverlap(tt.itemStart, tt.itemEnd, tt.start, tt.end), 1e-9)
+		})
+	}
+}
+
+func TestExternalAllocationName(t *testing.T) {
+	tags := models.CostTags{"namespace": "payments", "cluster": "prod", "team": "data"}
+	tests := []struct {
+		aggregate string
+		want      string
+	}{
+		{aggregate: "namespace", want: "payments"},
+		{aggregate: "cluster,namespace", want: "prod/payments"},
+		{aggregate: "label:team", want: "data"},
+		{aggregate: "label:owner", want: "__unallocated__"},
+		{aggregate: "pod", want: "__unallocated__"},
+		{aggregate: "", want: "payments"},
+	}
+	for _, tt := range tests {
+		assert.Equal(t, tt.want, externalAllocationName(tags, strings.Split(tt.aggregate, ",")), tt.aggregate)
+	}
+
+	// Items without the tag are unallocated
+	assert.Equal(t, "__unallocated__", externalAllocationName(models.CostTags{}, []string{"namespace"}))
+}
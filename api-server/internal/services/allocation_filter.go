@@ -0,0 +1,460 @@
+package services
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Allocation filters follow the OpenCost v2 filter language:
+//
+//	namespace:"a","b"+label[app]!:"x"
+//	(cluster:"prod"|cluster:"staging")+pod<~:"web-"
+//
+// A condition is a field, an operator and one or more quoted values. ":" matches any of the
+// values exactly, "~:" contains, "<~:" starts with and ">~:" ends with any of them; a "!"
+// before the operator negates it. Conditions combine with "+" (and) and "|" (or), "+"
+// binding tighter, and group with parentheses. An empty value ("") matches a missing label.
+//
+// Filters without quoted values are read in the legacy form: "namespace:a,b",
+// "cluster:prod", "container:a,b", "node:n1", "pod:web" (contains) and "label:key=value".
+
+// FilterOp is a filter condition operator
+type FilterOp string
+
+const (
+	FilterEquals     FilterOp = ":"
+	FilterContains   FilterOp = "~:"
+	FilterStartsWith FilterOp = "<~:"
+	FilterEndsWith   FilterOp = ">~:"
+)
+
+// controllerNameExpr derives a pod's controller name by removing the pod name's hash suffix
+const controllerNameExpr = "REGEXP_REPLACE(pod_name, '-[a-z0-9]{5,10}(-[a-z0-9]{5})?$', '')"
+
+// filterColumns maps filter fields other than label to pod_metrics columns
+var filterColumns = map[string]string{
+	"cluster":    "cluster_name",
+	"node":       "node_name",
+	"namespace":  "namespace",
+	"pod":        "pod_name",
+	"controller": controllerNameExpr,
+	"container":  containerNameExpr,
+}
+
+// Filter is a parsed allocation filter expression
+type Filter interface {
+	// sql compiles the filter to a SQL condition; see compileFilter
+	sql(c *filterCompiler) string
+	// matches evaluates the filter against an item whose field values lookup returns;
+	// conditions on fields the item does not have (known false) never match
+	matches(lookup func(field, key string) (value string, known bool)) bool
+	// walk calls fn for each of the filter's conditions
+	walk(fn func(*FilterCondition))
+}
+
+// FilterCondition matches a field (and label key) against a list of values
+type FilterCondition struct {
+	Field  string
+	Key    string // label key, for the label field
+	Op     FilterOp
+	Negate bool
+	Values []string
+}
+
+// FilterAnd matches when all of its filters match
+type FilterAnd []Filter
+
+// FilterOr matches when any of its filters matches
+type FilterOr []Filter
+
+// FilterError describes an invalid filter expression
+type FilterError struct {
+	Filter string
+	Pos    int
+	Msg    string
+}
+
+func (e *FilterError) Error() string {
+	return fmt.Sprintf("invalid filter %q at position %d: %s", e.Filter, e.Pos, e.Msg)
+}
+
+// ParseFilters parses a list of filter expressions, which all have to match. It returns nil
+// when there are none.
+func ParseFilters(filters []string) (Filter, error) {
+	var all FilterAnd
+	for _, filter := range filters {
+		f, err := ParseFilter(filter)
+		if err != nil {
+			return nil, err
+		}
+		all = append(all, f)
+	}
+	switch len(all) {
+	case 0:
+		return nil, nil
+	case 1:
+		return all[0], nil
+	}
+	return all, nil
+}
+
+// ParseFilter parses a filter expression in the v2 or legacy form
+func ParseFilter(filter string) (Filter, error) {
+	if strings.TrimSpace(filter) == "" {
+		return nil, &FilterError{Filter: filter, Msg: "empty filter"}
+	}
+	if !strings.Contains(filter, `"`) {
+		return parseLegacyFilter(filter)
+	}
+
+	p := &filterParser{input: filter}
+	f, err := p.parseOr()
+	if err != nil {
+		return nil, err
+	}
+	if p.skipSpace(); p.pos < len(p.input) {
+		return nil, p.errorf("unexpected %q", p.input[p.pos])
+	}
+	return f, nil
+}
+
+// parseLegacyFilter reads a "type:value" filter
+func parseLegacyFilter(filter string) (Filter, error) {
+	field, value, ok := strings.Cut(filter, ":")
+	field = strings.ToLower(strings.TrimSpace(field))
+	if !ok || value == "" {
+		return nil, &FilterError{Filter: filter, Msg: `expected field:"value"`}
+	}
+
+	switch field {
+	case "namespace", "cluster", "container":
+		var values []string
+		for _, v := range strings.Split(value, ",") {
+			values = append(values, strings.TrimSpace(v))
+		}
+		return &FilterCondition{Field: field, Op: FilterEquals, Values: values}, nil
+	case "node":
+		return &FilterCondition{Field: field, Op: FilterEquals, Values: []string{value}}, nil
+	case "pod":
+		return &FilterCondition{Field: field, Op: FilterContains, Values: []string{value}}, nil
+	case "label":
+		key, v, ok := strings.Cut(value, "=")
+		if !ok || strings.TrimSpace(key) == "" {
+			return nil, &FilterError{Filter: filter, Pos: len(field) + 1, Msg: "expected label:key=value"}
+		}
+		return &FilterCondition{Field: field, Key: strings.TrimSpace(key), Op: FilterEquals, Values: []string{v}}, nil
+	}
+	return nil, &FilterError{Filter: filter, Msg: fmt.Sprintf("unknown field %q", field)}
+}
+
+type filterParser struct {
+	input string
+	pos   int
+}
+
+func (p *filterParser) errorf(format string, args ...interface{}) error {
+	return &FilterError{Filter: p.input, Pos: p.pos, Msg: fmt.Sprintf(format, args...)}
+}
+
+func (p *filterParser) skipSpace() {
+	for p.pos < len(p.input) && (p.input[p.pos] == ' ' || p.input[p.pos] == '\t') {
+		p.pos++
+	}
+}
+
+// accept consumes s if the input continues with it
+func (p *filterParser) accept(s string) bool {
+	p.skipSpace()
+	if strings.HasPrefix(p.input[p.pos:], s) {
+		p.pos += len(s)
+		return true
+	}
+	return false
+}
+
+// parseOr parses and-expressions separated by "|"
+func (p *filterParser) parseOr() (Filter, error) {
+	var or FilterOr
+	for {
+		f, err := p.parseAnd()
+		if err != nil {
+			return nil, err
+		}
+		or = append(or, f)
+		if !p.accept("|") {
+			break
+		}
+	}
+	if len(or) == 1 {
+		return or[0], nil
+	}
+	return or, nil
+}
+
+// parseAnd parses terms separated by "+"
+func (p *filterParser) parseAnd() (Filter, error) {
+	var and FilterAnd
+	for {
+		f, err := p.parseTerm()
+		if err != nil {
+			return nil, err
+		}
+		and = append(and, f)
+		if !p.accept("+") {
+			break
+		}
+	}
+	if len(and) == 1 {
+		return and[0], nil
+	}
+	return and, nil
+}
+
+// parseTerm parses a parenthesized expression or a condition
+func (p *filterParser) parseTerm() (Filter, error) {
+	if p.accept("(") {
+		f, err := p.parseOr()
+		if err != nil {
+			return nil, err
+		}
+		if !p.accept(")") {
+			return nil, p.errorf(`expected ")"`)
+		}
+		return f, nil
+	}
+	return p.parseCondition()
+}
+
+// parseCondition parses field[key]op"value","value"...
+func (p *filterParser) parseCondition() (Filter, error) {
+	p.skipSpace()
+	start := p.pos
+	for p.pos < len(p.input) && isFieldChar(p.input[p.pos]) {
+		p.pos++
+	}
+	if p.pos == start {
+		return nil, p.errorf("expected a field")
+	}
+	cond := &FilterCondition{Field: strings.ToLower(p.input[start:p.pos])}
+	if _, ok := filterColumns[cond.Field]; !ok && cond.Field != "label" {
+		p.pos = start
+		return nil, p.errorf("unknown field %q", cond.Field)
+	}
+
+	if p.pos < len(p.input) && p.input[p.pos] == '[' {
+		end := strings.IndexByte(p.input[p.pos:], ']')
+		if end < 0 {
+			return nil, p.errorf(`expected "]"`)
+		}
+		cond.Key = strings.TrimSpace(p.input[p.pos+1 : p.pos+end])
+		if cond.Key == "" {
+			return nil, p.errorf("empty key")
+		}
+		p.pos += end + 1
+	}
+	if (cond.Field == "label") != (cond.Key != "") {
+		p.pos = start
+		if cond.Field == "label" {
+			return nil, p.errorf("label needs a key: label[key]")
+		}
+		return nil, p.errorf("field %q does not take a key", cond.Field)
+	}
+
+	p.skipSpace()
+	cond.Negate = p.accept("!")
+	switch {
+	case p.accept(string(FilterEquals)):
+		cond.Op = FilterEquals
+	case p.accept(string(FilterContains)):
+		cond.Op = FilterContains
+	case p.accept(string(FilterStartsWith)):
+		cond.Op = FilterStartsWith
+	case p.accept(string(FilterEndsWith)):
+		cond.Op = FilterEndsWith
+	default:
+		return nil, p.errorf(`expected an operator (":", "~:", "<~:" or ">~:")`)
+	}
+
+	for {
+		value, err := p.parseString()
+		if err != nil {
+			return nil, err
+		}
+		cond.Values = append(cond.Values, value)
+		if !p.accept(",") {
+			break
+		}
+	}
+	return cond, nil
+}
+
+// parseString parses a double-quoted string in which \" and \\ are escapes
+func (p *filterParser) parseString() (string, error) {
+	if !p.accept(`"`) {
+		return "", p.errorf("expected a quoted value")
+	}
+	var b strings.Builder
+	for p.pos < len(p.input) {
+		ch := p.input[p.pos]
+		p.pos++
+		switch ch {
+		case '"':
+			return b.String(), nil
+		case '\\':
+			if p.pos == len(p.input) {
+				return "", p.errorf("unterminated value")
+			}
+			b.WriteByte(p.input[p.pos])
+			p.pos++
+		default:
+			b.WriteByte(ch)
+		}
+	}
+	return "", p.errorf("unterminated value")
+}
+
+func isFieldChar(ch byte) bool {
+	return ch >= 'a' && ch <= 'z' || ch >= 'A' && ch <= 'Z' || ch == '_'
+}
+
+// filterCompiler numbers placeholders and collects parameters while compiling a filter.
+// column resolves a field (and label key) to a SQL expression and may add parameters itself.
+type filterCompiler struct {
+	args   *[]interface{}
+	argIdx *int
+	column func(field, key string) string
+}
+
+// param adds a parameter and returns its placeholder
+func (c *filterCompiler) param(value interface{}) string {
+	*c.args = append(*c.args, value)
+	*c.argIdx++
+	return fmt.Sprintf("$%d", *c.argIdx-1)
+}
+
+// compileFilter compiles a filter to a SQL condition, appending its parameters to args and
+// numbering placeholders from *argIdx
+func compileFilter(f Filter, column func(field, key string) string, args *[]interface{}, argIdx *int) string {
+	return f.sql(&filterCompiler{args: args, argIdx: argIdx, column: column})
+}
+
+func (f *FilterCondition) sql(c *filterCompiler) string {
+	col := c.column(f.Field, f.Key)
+	if f.Field == "label" {
+		// Missing labels compare as the empty string
+		col = fmt.Sprintf("COALESCE(%s, '')", col)
+	}
+
+	if f.Op == FilterEquals {
+		values := c.param(append([]string(nil), f.Values...))
+		if f.Negate {
+			return fmt.Sprintf("%s <> ALL(%s::text[])", col, values)
+		}
+		return fmt.Sprintf("%s = ANY(%s::text[])", col, values)
+	}
+
+	patterns := make([]string, len(f.Values))
+	for i, v := range f.Values {
+		v = likeEscaper.Replace(v)
+		switch f.Op {
+		case FilterContains:
+			patterns[i] = "%" + v + "%"
+		case FilterStartsWith:
+			patterns[i] = v + "%"
+		case FilterEndsWith:
+			patterns[i] = "%" + v
+		}
+	}
+	if f.Negate {
+		return fmt.Sprintf("%s NOT LIKE ALL(%s::text[])", col, c.param(patterns))
+	}
+	return fmt.Sprintf("%s LIKE ANY(%s::text[])", col, c.param(patterns))
+}
+
+// likeEscaper escapes LIKE wildcards with the default escape character
+var likeEscaper = strings.NewReplacer(`\`, `\\`, `%`, `\%`, `_`, `\_`)
+
+func (f FilterAnd) sql(c *filterCompiler) string {
+	conds := make([]string, len(f))
+	for i, sub := range f {
+		conds[i] = sub.sql(c)
+	}
+	return "(" + strings.Join(conds, " AND ") + ")"
+}
+
+func (f FilterOr) sql(c *filterCompiler) string {
+	conds := make([]string, len(f))
+	for i, sub := range f {
+		conds[i] = sub.sql(c)
+	}
+	return "(" + strings.Join(conds, " OR ") + ")"
+}
+
+func (f *FilterCondition) matches(lookup func(field, key string) (string, bool)) bool {
+	value, known := lookup(f.Field, f.Key)
+	if !known {
+		return false
+	}
+	matched := false
+	for _, v := range f.Values {
+		switch f.Op {
+		case FilterEquals:
+			matched = value == v
+		case FilterContains:
+			matched = strings.Contains(value, v)
+		case FilterStartsWith:
+			matched = strings.HasPrefix(value, v)
+		case FilterEndsWith:
+			matched = strings.HasSuffix(value, v)
+		}
+		if matched {
+			break
+		}
+	}
+	return matched != f.Negate
+}
+
+func (f FilterAnd) matches(lookup func(field, key string) (string, bool)) bool {
+	for _, sub := range f {
+		if !sub.matches(lookup) {
+			return false
+		}
+	}
+	return true
+}
+
+func (f FilterOr) matches(lookup func(field, key string) (string, bool)) bool {
+	for _, sub := range f {
+		if sub.matches(lookup) {
+			return true
+		}
+	}
+	return false
+}
+
+func (f *FilterCondition) walk(fn func(*FilterCondition)) {
+	fn(f)
+}
+
+func (f FilterAnd) walk(fn func(*FilterCondition)) {
+	for _, sub := range f {
+		sub.walk(fn)
+	}
+}
+
+func (f FilterOr) walk(fn func(*FilterCondition)) {
+	for _, sub := range f {
+		sub.walk(fn)
+	}
+}
+
+// filterUsesField reports whether any of a filter's conditions is on field
+func filterUsesField(f Filter, field string) bool {
+	uses := false
+	if f != nil {
+		f.walk(func(c *FilterCondition) {
+			uses = uses || c.Field == field
+		})
+	}
+	return uses
+}
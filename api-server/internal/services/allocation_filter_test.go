@@ -0,0 +1,132 @@
+package services
+
+import (
+	"errors"
+	"fmt"
+	"testing"
+
+	"github.com/bugfreev587/k8s-cost-api-server/internal/models"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseFilter(t *testing.T) {
+	f, err := ParseFilter(`namespace:"a","b"+label[app]!:"x"`)
+	require.NoError(t, err)
+	assert.Equal(t, FilterAnd{
+		&FilterCondition{Field: "namespace", Op: FilterEquals, Values: []string{"a", "b"}},
+		&FilterCondition{Field: "label", Key: "app", Op: FilterEquals, Negate: true, Values: []string{"x"}},
+	}, f)
+
+	// "+" binds tighter than "|"; parentheses group
+	f, err = ParseFilter(`cluster:"prod" | cluster:"eu" + (pod<~:"web-" | pod>~:"-api") + namespace!~:"test"`)
+	require.NoError(t, err)
+	assert.Equal(t, FilterOr{
+		&FilterCondition{Field: "cluster", Op: FilterEquals, Values: []string{"prod"}},
+		FilterAnd{
+			&FilterCondition{Field: "cluster", Op: FilterEquals, Values: []string{"eu"}},
+			FilterOr{
+				&FilterCondition{Field: "pod", Op: FilterStartsWith, Values: []string{"web-"}},
+				&FilterCondition{Field: "pod", Op: FilterEndsWith, Values: []string{"-api"}},
+			},
+			&FilterCondition{Field: "namespace", Op: FilterContains, Negate: true, Values: []string{"test"}},
+		},
+	}, f)
+
+	f, err = ParseFilter(`label[app.kubernetes.io/name]:"say \"hi\""`)
+	require.NoError(t, err)
+	assert.Equal(t, &FilterCondition{Field: "label", Key: "app.kubernetes.io/name", Op: FilterEquals, Values: []string{`say "hi"`}}, f)
+}
+
+func TestParseFilter_Legacy(t *testing.T) {
+	cases := map[string]Filter{
+		"namespace:a, b":   &FilterCondition{Field: "namespace", Op: FilterEquals, Values: []string{"a", "b"}},
+		"cluster:prod":     &FilterCondition{Field: "cluster", Op: FilterEquals, Values: []string{"prod"}},
+		"pod:web":          &FilterCondition{Field: "pod", Op: FilterContains, Values: []string{"web"}},
+		"label:team=data":  &FilterCondition{Field: "label", Key: "team", Op: FilterEquals, Values: []string{"data"}},
+		"Node:ip-10-0-0-1": &FilterCondition{Field: "node", Op: FilterEquals, Values: []string{"ip-10-0-0-1"}},
+	}
+	for input, want := range cases {
+		f, err := ParseFilter(input)
+		require.NoError(t, err, input)
+		assert.Equal(t, want, f, input)
+	}
+}
+
+func TestParseFilter_Errors(t *testing.T) {
+	for _, input := range []string{
+		"",
+		"team:data",
+		"label:team",
+		`namespace:a"`,
+		`namespace="a"`,
+		`namespace:"a`,
+		`namespace:"a"+`,
+		`(namespace:"a"`,
+		`namespace:"a")`,
+		`label:"a"`,
+		`namespace[x]:"a"`,
+		`owner:"a"`,
+		`namespace:"a" cluster:"b"`,
+	} {
+		_, err := ParseFilter(input)
+		var filterErr *FilterError
+		assert.True(t, errors.As(err, &filterErr), "%q: %v", input, err)
+	}
+}
+
+func TestCompileFilter(t *testing.T) {
+	f, err := ParseFilters([]string{`namespace:"a","b"+label[app]!:"x"|pod~:"50%_off"`, "cluster:prod"})
+	require.NoError(t, err)
+
+	args := []interface{}{"tenant"}
+	argIdx := 2
+	column := func(field, key string) string {
+		if field == "label" {
+			args = append(args, key)
+			argIdx++
+			return fmt.Sprintf("labels->>$%d", argIdx-1)
+		}
+		return filterColumns[field]
+	}
+	sql := compileFilter(f, column, &args, &argIdx)
+
+	assert.Equal(t, "(((namespace = ANY($2::text[]) AND COALESCE(labels->>$3, '') <> ALL($4::text[])) OR pod_name LIKE ANY($5::text[])) AND cluster_name = ANY($6::text[]))", sql)
+	assert.Equal(t, []interface{}{"tenant", []string{"a", "b"}, "app", []string{"x"}, []string{`%50\%\_off%`}, []string{"prod"}}, args)
+	assert.Equal(t, 7, argIdx)
+}
+
+func TestExternalCostMatchesFilter(t *testing.T) {
+	tags := models.CostTags{"namespace": "web", "cluster": "prod", "team": "data"}
+	match := func(filters ...string) bool {
+		f, err := ParseFilters(filters)
+		require.NoError(t, err)
+		return externalCostMatchesFilter(tags, f)
+	}
+
+	assert.True(t, match())
+	assert.True(t, match("namespace:web,api", "label:team=data"))
+	assert.True(t, match(`label[owner]:""`))
+	assert.True(t, match(`namespace!:"api"+(cluster:"dev"|label[team]<~:"da")`))
+	assert.False(t, match(`namespace!:"web"`))
+	assert.False(t, match(`namespace:"web"+label[team]:"ml"`))
+	// External costs are not tied to workloads
+	assert.False(t, match("pod:web"))
+	assert.False(t, match(`node!:"n1"`))
+}
+
+func TestParseFilter_Container(t *testing.T) {
+	f, err := ParseFilter(`container!:"istio-proxy","fluent-bit"`)
+	require.NoError(t, err)
+	assert.True(t, filterUsesField(f, "container"))
+	assert.False(t, filterUsesField(f, "label"))
+
+	f, err = ParseFilter("container:istio-proxy,linkerd-proxy")
+	require.NoError(t, err)
+	assert.Equal(t, &FilterCondition{Field: "container", Op: FilterEquals, Values: []string{"istio-proxy", "linkerd-proxy"}}, f)
+
+	var args []interface{}
+	argIdx := 4
+	sql := compileFilter(f, func(field, key string) string { return filterColumns[field] }, &args, &argIdx)
+	assert.Equal(t, "COALESCE(container->>'container_name', '') = ANY($4::text[])", sql)
+}
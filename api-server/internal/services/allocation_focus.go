@@ -0,0 +1,310 @@
+package services
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"math"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/bugfreev587/k8s-cost-api-server/internal/export"
+	"github.com/bugfreev587/k8s-cost-api-server/internal/models"
+)
+
+// FocusVersion is the FinOps Open Cost and Usage Specification version of FOCUS exports
+const FocusVersion = "1.0"
+
+// FocusColumns are the columns of a FOCUS export. Kubernetes dimensions without a FOCUS
+// column are carried in x_ prefixed custom columns and in Tags.
+var FocusColumns = []export.Column{
+	{Name: "BilledCost", Type: export.Float},
+	{Name: "BillingAccountId", Type: export.String},
+	{Name: "BillingCurrency", Type: export.String},
+	{Name: "BillingPeriodStart", Type: export.Time},
+	{Name: "BillingPeriodEnd", Type: export.Time},
+	{Name: "ChargeCategory", Type: export.String},
+	{Name: "ChargeClass", Type: export.String},
+	{Name: "ChargeDescription", Type: export.String},
+	{Name: "ChargeFrequency", Type: export.String},
+	{Name: "ChargePeriodStart", Type: export.Time},
+	{Name: "ChargePeriodEnd", Type: export.Time},
+	{Name: "ConsumedQuantity", Type: export.Float},
+	{Name: "ConsumedUnit", Type: export.String},
+	{Name: "ContractedCost", Type: export.Float},
+	{Name: "EffectiveCost", Type: export.Float},
+	{Name: "InvoiceIssuerName", Type: export.String},
+	{Name: "ListCost", Type: export.Float},
+	{Name: "PricingQuantity", Type: export.Float},
+	{Name: "PricingUnit", Type: export.String},
+	{Name: "ProviderName", Type: export.String},
+	{Name: "PublisherName", Type: export.String},
+	{Name: "RegionId", Type: export.String},
+	{Name: "ResourceId", Type: export.String},
+	{Name: "ResourceName", Type: export.String},
+	{Name: "ResourceType", Type: export.String},
+	{Name: "ServiceCategory", Type: export.String},
+	{Name: "ServiceName", Type: export.String},
+	{Name: "SubAccountId", Type: export.String},
+	{Name: "SubAccountName", Type: export.String},
+	{Name: "Tags", Type: export.String}, // JSON object
+	{Name: "x_ClusterName", Type: export.String},
+	{Name: "x_Namespace", Type: export.String},
+	{Name: "x_Node", Type: export.String},
+	{Name: "x_Pod", Type: export.String},
+	{Name: "x_CostComponent", Type: export.String},
+}
+
+// Tag keys added to a pod's labels in the FOCUS Tags column
+const (
+	focusTagCluster   = "kubernetes.io/cluster"
+	focusTagNamespace = "kubernetes.io/namespace"
+)
+
+// focusRow is one FOCUS charge: one cost component (cpu, memory, shared or idle) of one pod
+// or idle scope for one day
+type focusRow struct {
+	BilledCost         float64
+	BillingAccountID   string
+	BillingCurrency    string
+	BillingPeriodStart time.Time
+	BillingPeriodEnd   time.Time
+	ChargeDescription  string
+	ChargePeriodStart  time.Time
+	ChargePeriodEnd    time.Time
+	ConsumedQuantity   float64
+	ConsumedUnit       string
+	EffectiveCost      float64
+	ListCost           float64
+	ProviderName       string
+	RegionID           string
+	ResourceID         string
+	ResourceName       string
+	ResourceType       string
+	Cluster            string
+	Namespace          string
+	Node               string
+	Pod                string
+	Component          string
+	Tags               map[string]string
+}
+
+func (r focusRow) write(w export.Writer) error {
+	tags := "{}"
+	if len(r.Tags) > 0 {
+		b, _ := json.Marshal(r.Tags)
+		tags = string(b)
+	}
+	return w.WriteRow(
+		r.BilledCost, r.BillingAccountID, r.BillingCurrency, r.BillingPeriodStart, r.BillingPeriodEnd,
+		"Usage", "", r.ChargeDescription, "Usage-Based", r.ChargePeriodStart, r.ChargePeriodEnd,
+		r.ConsumedQuantity, r.ConsumedUnit, r.EffectiveCost, r.EffectiveCost, r.ProviderName,
+		r.ListCost, r.ConsumedQuantity, r.ConsumedUnit, r.ProviderName, "Kubernetes", r.RegionID,
+		r.ResourceID, r.ResourceName, r.ResourceType, "Compute", "Kubernetes",
+		r.Cluster, r.Cluster, tags,
+		r.Cluster, r.Namespace, r.Node, r.Pod, r.Component,
+	)
+}
+
+// focusCluster is the provider and region of a cluster, from its pricing config
+type focusCluster struct {
+	Provider string
+	Region   string
+}
+
+// FocusExport is the tenant's allocations for a window, ready to be written as FOCUS rows
+type FocusExport struct {
+	tenantID  int64
+	resp      *AllocationResponse
+	podLabels map[string]map[string]string
+	clusters  map[string]focusCluster
+}
+
+// Focus computes the tenant's daily allocations per pod for a FOCUS export. Idle cost is
+// reported per cluster unless it is shared or another idle scope is requested.
+func (s *AllocationService) Focus(ctx context.Context, tenantID int64, params AllocationParams) (*FocusExport, error) {
+	params.Aggregate = "cluster,pod"
+	params.Step = "1d"
+	params.Accumulate = "false"
+	params.Offset = 0
+	params.Limit = math.MaxInt32
+	if params.IdleBy == "" || params.IdleBy == IdleByTenant {
+		params.IdleBy = IdleByCluster
+	}
+
+	resp, err := s.GetAllocations(ctx, tenantID, params)
+	if err != nil {
+		return nil, err
+	}
+	f := &FocusExport{tenantID: tenantID, resp: resp, clusters: make(map[string]focusCluster)}
+	if len(resp.Data) == 0 {
+		return f, nil
+	}
+
+	start, end := resp.Data[0].Window.Start, resp.Data[len(resp.Data)-1].Window.End
+	if f.podLabels, err = s.loadPodLabels(ctx, tenantID, start, end); err != nil {
+		return nil, err
+	}
+	for _, set := range resp.Data {
+		for _, a := range set.Allocations {
+			if _, ok := f.clusters[a.Properties.Cluster]; !ok {
+				f.clusters[a.Properties.Cluster] = s.focusCluster(ctx, tenantID, a.Properties.Cluster, start)
+			}
+		}
+	}
+	return f, nil
+}
+
+// Write writes the export as FocusColumns rows: per pod and day, one charge each for CPU,
+// memory and (when non-zero) shared and external costs, plus the idle CPU and memory cost
+// of each idle scope. Costs are the allocations' effective costs, so BilledCost,
+// EffectiveCost and ContractedCost are equal; ListCost is the allocation's list cost split
+// across its components in proportion to their cost.
+func (f *FocusExport) Write(w export.Writer) error {
+	currency := f.resp.Currency
+	if currency == "" {
+		currency = "USD"
+	}
+	account := strconv.FormatInt(f.tenantID, 10)
+
+	for _, set := range f.resp.Data {
+		names := make([]string, 0, len(set.Allocations))
+		for name := range set.Allocations {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+
+		for _, name := range names {
+			a := set.Allocations[name]
+			cluster := f.clusters[a.Properties.Cluster]
+			chargeStart, chargeEnd := a.Start, a.End
+			if chargeStart.IsZero() {
+				chargeStart, chargeEnd = set.Window.Start, set.Window.End
+			}
+			periodStart := time.Date(chargeStart.Year(), chargeStart.Month(), 1, 0, 0, 0, 0, chargeStart.Location())
+
+			base := focusRow{
+				BillingAccountID:   account,
+				BillingCurrency:    currency,
+				BillingPeriodStart: periodStart,
+				BillingPeriodEnd:   periodStart.AddDate(0, 1, 0),
+				ChargePeriodStart:  chargeStart,
+				ChargePeriodEnd:    chargeEnd,
+				ProviderName:       cluster.Provider,
+				RegionID:           cluster.Region,
+				Cluster:            a.Properties.Cluster,
+				Namespace:          a.Properties.Namespace,
+			}
+			for _, row := range focusRows(base, name, a, f.podLabels) {
+				if err := row.write(w); err != nil {
+					return err
+				}
+			}
+		}
+	}
+	return nil
+}
+
+// focusRows splits an allocation into its FOCUS charges
+func focusRows(base focusRow, name string, a *Allocation, podLabels map[string]map[string]string) []focusRow {
+	listShare := func(cost float64) float64 {
+		if a.TotalCost == 0 {
+			return 0
+		}
+		return a.ListCost * cost / a.TotalCost
+	}
+	row := func(component, description string, cost, quantity float64, unit string) focusRow {
+		r := base
+		r.Component = component
+		r.ChargeDescription = description
+		r.BilledCost = cost
+		r.EffectiveCost = cost
+		r.ListCost = listShare(cost)
+		r.ConsumedQuantity = quantity
+		r.ConsumedUnit = unit
+		return r
+	}
+
+	if strings.HasSuffix(name, "__idle__") {
+		base.ResourceID = name
+		base.ResourceName = name
+		base.ResourceType = "Idle Capacity"
+		base.Namespace = ""
+		base.Node = a.Properties.Node
+		return []focusRow{
+			row("cpu_idle", "Unallocated cluster CPU capacity", a.CPUCost, 0, ""),
+			row("memory_idle", "Unallocated cluster memory capacity", a.RAMCost, 0, ""),
+		}
+	}
+
+	pod := strings.TrimPrefix(name, a.Properties.Cluster+"/"+a.Properties.Namespace+"/")
+	base.ResourceID = name
+	base.ResourceName = pod
+	base.ResourceType = "Pod"
+	base.Pod = pod
+	base.Node = a.Properties.Node
+	base.Tags = map[string]string{}
+	for k, v := range podLabels[podKey(a.Properties.Cluster, a.Properties.Namespace, pod)] {
+		base.Tags[k] = v
+	}
+	base.Tags[focusTagCluster] = a.Properties.Cluster
+	base.Tags[focusTagNamespace] = a.Properties.Namespace
+
+	rows := []focusRow{
+		row("cpu", "Kubernetes pod CPU", a.CPUCost, a.CPUCoreHours, "Core-Hours"),
+		row("memory", "Kubernetes pod memory", a.RAMCost, a.RAMByteHours/1024/1024/1024, "GiB-Hours"),
+	}
+	if other := a.TotalCost - a.CPUCost - a.RAMCost; math.Abs(other) > 1e-9 {
+		rows = append(rows, row("shared", "Shared and external costs allocated to the pod", other, 0, ""))
+	}
+	return rows
+}
+
+// focusCluster returns a cluster's provider and region from its effective pricing config
+func (s *AllocationService) focusCluster(ctx context.Context, tenantID int64, cluster string, asOf time.Time) focusCluster {
+	if s.pricingSvc == nil || cluster == "" {
+		return focusCluster{Provider: string(models.ProviderCustom)}
+	}
+	pricing, err := s.pricingSvc.GetEffectiveRates(ctx, uint(tenantID), cluster, asOf)
+	if err != nil || pricing.Provider == "" {
+		return focusCluster{Provider: string(models.ProviderCustom)}
+	}
+	return focusCluster{Provider: string(pricing.Provider), Region: pricing.Region}
+}
+
+// podKey identifies a pod within a tenant
+func podKey(cluster, namespace, pod string) string {
+	return cluster + "/" + namespace + "/" + pod
+}
+
+// loadPodLabels returns the latest labels reported for each pod in the window, keyed by podKey
+func (s *AllocationService) loadPodLabels(ctx context.Context, tenantID int64, start, end time.Time) (map[string]map[string]string, error) {
+	rows, err := s.pool.Query(ctx, `
+		SELECT DISTINCT ON (cluster_name, namespace, pod_name) cluster_name, namespace, pod_name, labels
+		FROM pod_metrics
+		WHERE tenant_id = $1 AND time >= $2 AND time <= $3 AND labels IS NOT NULL
+			AND pod_name != '__aggregate__'
+		ORDER BY cluster_name, namespace, pod_name, time DESC
+	`, tenantID, start, end)
+	if err != nil {
+		return nil, fmt.Errorf("pod labels query failed: %w", err)
+	}
+	defer rows.Close()
+
+	labels := make(map[string]map[string]string)
+	for rows.Next() {
+		var cluster, namespace, pod string
+		var raw []byte
+		if err := rows.Scan(&cluster, &namespace, &pod, &raw); err != nil {
+			return nil, fmt.Errorf("pod labels scan failed: %w", err)
+		}
+		var podLabels map[string]string
+		if err := json.Unmarshal(raw, &podLabels); err != nil {
+			continue
+		}
+		labels[podKey(cluster, namespace, pod)] = podLabels
+	}
+	return labels, rows.Err()
+}
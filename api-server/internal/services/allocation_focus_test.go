@@ -0,0 +1,98 @@
+package services
+
+import (
+	"bytes"
+	"encoding/csv"
+	"testing"
+	"time"
+
+	"github.com/bugfreev587/k8s-cost-api-server/internal/export"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestFocusExport_Write(t *testing.T) {
+	day := time.Date(2026, 3, 31, 0, 0, 0, 0, time.UTC)
+	window := TimeWindow{Start: day, End: day.Add(24 * time.Hour)}
+	pod := &Allocation{
+		Name:         "prod/shop/web-7d9f-x2k4p",
+		Start:        window.Start,
+		End:          window.End,
+		CPUCoreHours: 48,
+		CPUCost:      2,
+		RAMByteHours: 96 * 1024 * 1024 * 1024,
+		RAMCost:      1,
+		SharedCost:   0.5,
+		TotalCost:    3.5,
+		ListCost:     7,
+		Properties:   AllocationProps{Cluster: "prod", Namespace: "shop", Node: "n1"},
+	}
+	idle := &Allocation{
+		Name:       "prod/__idle__",
+		Start:      window.Start,
+		End:        window.End,
+		CPUCost:    4,
+		RAMCost:    2,
+		TotalCost:  6,
+		ListCost:   6,
+		Properties: AllocationProps{Cluster: "prod"},
+	}
+	f := &FocusExport{
+		tenantID: 7,
+		resp: &AllocationResponse{Currency: "EUR", Data: []AllocationSet{{
+			Window:      window,
+			Allocations: map[string]*Allocation{pod.Name: pod, idle.Name: idle},
+		}}},
+		podLabels: map[string]map[string]string{podKey("prod", "shop", "web-7d9f-x2k4p"): {"team": "checkout"}},
+		clusters:  map[string]focusCluster{"prod": {Provider: "aws", Region: "us-east-1"}},
+	}
+
+	var buf bytes.Buffer
+	w, err := export.NewWriter(export.FormatCSV, &buf, FocusColumns)
+	require.NoError(t, err)
+	require.NoError(t, f.Write(w))
+	require.NoError(t, w.Close())
+
+	records, err := csv.NewReader(&buf).ReadAll()
+	require.NoError(t, err)
+	require.Len(t, records, 6) // header, 2 idle charges, 3 pod charges
+
+	col := make(map[string]int)
+	for i, name := range records[0] {
+		col[name] = i
+	}
+	byComponent := make(map[string][]string)
+	for _, r := range records[1:] {
+		byComponent[r[col["x_CostComponent"]]] = r
+	}
+
+	cpu := byComponent["cpu"]
+	require.NotNil(t, cpu)
+	assert.Equal(t, "2", cpu[col["BilledCost"]])
+	assert.Equal(t, "2", cpu[col["EffectiveCost"]])
+	assert.Equal(t, "4", cpu[col["ListCost"]])
+	assert.Equal(t, "48", cpu[col["ConsumedQuantity"]])
+	assert.Equal(t, "Core-Hours", cpu[col["ConsumedUnit"]])
+	assert.Equal(t, "EUR", cpu[col["BillingCurrency"]])
+	assert.Equal(t, "7", cpu[col["BillingAccountId"]])
+	assert.Equal(t, "aws", cpu[col["ProviderName"]])
+	assert.Equal(t, "us-east-1", cpu[col["RegionId"]])
+	assert.Equal(t, "prod", cpu[col["SubAccountId"]])
+	assert.Equal(t, "prod/shop/web-7d9f-x2k4p", cpu[col["ResourceId"]])
+	assert.Equal(t, "web-7d9f-x2k4p", cpu[col["ResourceName"]])
+	assert.Equal(t, "Pod", cpu[col["ResourceType"]])
+	assert.Equal(t, "shop", cpu[col["x_Namespace"]])
+	assert.Equal(t, "2026-03-01T00:00:00Z", cpu[col["BillingPeriodStart"]])
+	assert.Equal(t, "2026-04-01T00:00:00Z", cpu[col["BillingPeriodEnd"]])
+	assert.Equal(t, "2026-03-31T00:00:00Z", cpu[col["ChargePeriodStart"]])
+	assert.JSONEq(t, `{"team":"checkout","kubernetes.io/cluster":"prod","kubernetes.io/namespace":"shop"}`, cpu[col["Tags"]])
+
+	assert.Equal(t, "96", byComponent["memory"][col["ConsumedQuantity"]])
+	assert.Equal(t, "GiB-Hours", byComponent["memory"][col["ConsumedUnit"]])
+	assert.Equal(t, "0.5", byComponent["shared"][col["BilledCost"]])
+	assert.Equal(t, "1", byComponent["shared"][col["ListCost"]])
+
+	assert.Equal(t, "4", byComponent["cpu_idle"][col["BilledCost"]])
+	assert.Equal(t, "Idle Capacity", byComponent["cpu_idle"][col["ResourceType"]])
+	assert.Equal(t, "2", byComponent["memory_idle"][col["BilledCost"]])
+}
@@ -0,0 +1,348 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"time"
+
+	"github.com/bugfreev587/k8s-cost-api-server/internal/models"
+)
+
+// Idle scopes (AllocationParams.IdleBy)
+const (
+	IdleByTenant  = "tenant"
+	IdleByCluster = "cluster"
+	IdleByNode    = "node"
+)
+
+// Idle sharing methods (AllocationParams.ShareIdle)
+const (
+	ShareIdleEven     = "even"
+	ShareIdleWeighted = "weighted"
+)
+
+// IdleCost holds the cost of unallocated capacity within one idle scope.
+// Cluster and Node are empty when the scope is tenant-wide (Node is empty for cluster scope).
+type IdleCost struct {
+	Cluster string
+	Node    string
+	CPUCost float64
+	RAMCost float64
+
+	// CPU and RAM idle cost at list prices
+	ListCPUCost float64
+	ListRAMCost float64
+}
+
+// TotalCost returns the combined CPU and RAM idle cost
+func (i *IdleCost) TotalCost() float64 {
+	return i.CPUCost + i.RAMCost
+}
+
+// Name returns the allocation name used to report this idle cost
+func (i *IdleCost) Name() string {
+	switch {
+	case i.Node != "":
+		return i.Cluster + "/" + i.Node + "/__idle__"
+	case i.Cluster != "":
+		return i.Cluster + "/__idle__"
+	default:
+		return "__idle__"
+	}
+}
+
+// contains reports whether an allocation fragment belongs to this idle scope
+func (i *IdleCost) contains(alloc *Allocation) bool {
+	if i.Cluster != "" && alloc.Properties.Cluster != i.Cluster {
+		return false
+	}
+	if i.Node != "" && alloc.Properties.Node != i.Node {
+		return false
+	}
+	return true
+}
+
+func (i *IdleCost) toAllocation(start, end time.Time) *Allocation {
+	return &Allocation{
+		Name:      i.Name(),
+		Window:    TimeWindow{Start: start, End: end},
+		Start:     start,
+		End:       end,
+		Minutes:   end.Sub(start).Minutes(),
+		CPUCost:   i.CPUCost,
+		RAMCost:   i.RAMCost,
+		TotalCost: i.TotalCost(),
+		ListCost:  i.ListCPUCost + i.ListRAMCost,
+		Properties: AllocationProps{
+			Cluster: i.Cluster,
+			Node:    i.Node,
+		},
+	}
+}
+
+// normalizeShareIdle maps the shareIdle parameter to a sharing method ("" = don't share)
+func normalizeShareIdle(shareIdle string) string {
+	switch shareIdle {
+	case "true", ShareIdleEven:
+		return ShareIdleEven
+	case ShareIdleWeighted:
+		return ShareIdleWeighted
+	default:
+		return ""
+	}
+}
+
+// calculateIdleCosts calculates the cost of unused node capacity, split into CPU and RAM,
+// and groups it by the requested idle scope.
+//
+// Each node's idle cost is what remains of its cost once its pods are charged at the
+// node's rates, where a pod's allocation is max(request, usage); see nodeIdleCost.
+func (s *AllocationService) calculateIdleCosts(ctx context.Context, tenantID int64, startTime, endTime time.Time, params AllocationParams) ([]*IdleCost, error) {
+	query := `
+		WITH node_capacity AS (
+			SELECT
+				cluster_name,
+				node_name,
+				AVG(cpu_capacity) / 1000.0 as cpu_cores,
+				AVG(memory_capacity) as memory_bytes,
+				AVG(hourly_cost_usd) as hourly_cost
+			FROM node_metrics
+			WHERE tenant_id = $1 AND time >= $2 AND time <= $3
+			GROUP BY cluster_name, node_name
+		),
+		pod_samples AS (
+			SELECT
+				cluster_name,
+				node_name,
+				time,
+				SUM(GREATEST(cpu_millicores, cpu_request_millicores)) / 1000.0 as cpu_cores,
+				SUM(GREATEST(memory_bytes, memory_request_bytes)) as memory_bytes
+			FROM pod_metrics
+			WHERE tenant_id = $1 AND time >= $2 AND time <= $3
+				AND pod_name != '__aggregate__'
+			GROUP BY cluster_name, node_name, time
+		),
+		pod_allocation AS (
+			SELECT
+				cluster_name,
+				node_name,
+				AVG(cpu_cores) as cpu_cores,
+				AVG(memory_bytes) as memory_bytes
+			FROM pod_samples
+			GROUP BY cluster_name, node_name
+		)
+		SELECT
+			nc.cluster_name,
+			nc.node_name,
+			COALESCE(nc.cpu_cores, 0)::float8,
+			COALESCE(nc.memory_bytes, 0)::float8,
+			COALESCE(nc.hourly_cost, 0)::float8,
+			COALESCE(pa.cpu_cores, 0)::float8,
+			COALESCE(pa.memory_bytes, 0)::float8
+		FROM node_capacity nc
+		LEFT JOIN pod_allocation pa ON nc.cluster_name = pa.cluster_name AND nc.node_name = pa.node_name
+	`
+
+	rows, err := s.pool.Query(ctx, query, tenantID, startTime, endTime)
+	if err != nil {
+		return nil, fmt.Errorf("idle query failed: %w", err)
+	}
+	defer rows.Close()
+
+	durationHours := endTime.Sub(startTime).Hours()
+	if durationHours <= 0 {
+		durationHours = 1
+	}
+
+	pricing := s.newClusterPricing(ctx, tenantID, startTime)
+	scopes := make(map[string]*IdleCost)
+	var ordered []*IdleCost
+	for rows.Next() {
+		var clusterName, nodeName string
+		var cpuCores, memBytes, hourlyCost, allocCPU, allocMem float64
+		if err := rows.Scan(&clusterName, &nodeName, &cpuCores, &memBytes, &hourlyCost, &allocCPU, &allocMem); err != nil {
+			return nil, fmt.Errorf("idle scan failed: %w", err)
+		}
+
+		node := params.node(clusterName, nodeName)
+		node.CPUCores, node.MemoryBytes = cpuCores, memBytes
+		adj, adjusted := params.nodeCosts[nodeKey(clusterName, nodeName)]
+		idle := pricing.nodeIdleCost(node, hourlyCost, allocCPU, allocMem, durationHours, adj, adjusted)
+		switch params.IdleBy {
+		case IdleByNode:
+			idle.Cluster, idle.Node = clusterName, nodeName
+		case IdleByCluster:
+			idle.Cluster = clusterName
+		}
+
+		if existing, ok := scopes[idle.Name()]; ok {
+			existing.CPUCost += idle.CPUCost
+			existing.RAMCost += idle.RAMCost
+			existing.ListCPUCost += idle.ListCPUCost
+			existing.ListRAMCost += idle.ListRAMCost
+		} else {
+			scopes[idle.Name()] = idle
+			ordered = append(ordered, idle)
+		}
+	}
+
+	return ordered, rows.Err()
+}
+
+// nodeIdleCost returns the idle cost of a node over the given hours: the remainder of the
+// node's cost once its allocated CPU and RAM (capped at capacity) are charged at the rates
+// its pods are charged, so pod and idle costs add up to the node's cost.
+//
+// The node's cost is split into a CPU and a RAM portion in proportion to the node's rates
+// (see getNodePricing). A configured node price (hourly cost override, instance type or
+// family pricing) takes precedence over the hourly cost reported by the agent. The split is
+// made at list rates and scaled by the cluster's negotiated discounts; nodes with
+// reconciled billing data use their actual hourly cost; commitment discounts scale the rest.
+func (p *clusterPricing) nodeIdleCost(node nodeInventory, hourlyCost, allocCPU, allocMem, hours float64, adj nodeCostAdjustment, adjusted bool) *IdleCost {
+	memGB := node.MemoryBytes / 1024 / 1024 / 1024
+	cpuRate, memRate, source := p.nodeRates(node)
+	listCPURate, listMemRate := p.nodeListRates(node)
+	if source != models.PriceSourceDefault {
+		hourlyCost = 0
+	}
+	cpuListCost, ramListCost := splitNodeCost(hourlyCost, node.CPUCores, memGB, listCPURate, listMemRate)
+
+	var cpuNodeCost, ramNodeCost float64
+	if adj.ReconciledHourlyCost > 0 {
+		cpuNodeCost, ramNodeCost = splitNodeCost(adj.ReconciledHourlyCost, node.CPUCores, memGB, cpuRate, memRate)
+	} else {
+		cpuNodeCost = cpuListCost * rateRatio(cpuRate, listCPURate)
+		ramNodeCost = ramListCost * rateRatio(memRate, listMemRate)
+		if adjusted && adj.RateFactor > 0 {
+			cpuNodeCost *= adj.RateFactor
+			ramNodeCost *= adj.RateFactor
+		}
+	}
+	if adjusted && adj.RateFactor > 0 {
+		// Pods on the node are charged at its reconciled or commitment-discounted rates
+		cpuRate *= adj.RateFactor
+		memRate *= adj.RateFactor
+	}
+
+	allocCPU = math.Min(allocCPU, node.CPUCores)
+	allocGB := math.Min(allocMem, node.MemoryBytes) / 1024 / 1024 / 1024
+	return &IdleCost{
+		CPUCost:     remainder(cpuNodeCost, allocCPU*cpuRate) * hours,
+		RAMCost:     remainder(ramNodeCost, allocGB*memRate) * hours,
+		ListCPUCost: remainder(cpuListCost, allocCPU*listCPURate) * hours,
+		ListRAMCost: remainder(ramListCost, allocGB*listMemRate) * hours,
+	}
+}
+
+// remainder returns what is left of a cost after a charged amount, never negative
+func remainder(cost, charged float64) float64 {
+	return math.Max(cost-charged, 0)
+}
+
+// splitNodeCost splits a node's hourly cost into CPU and RAM portions using the
+// relative CPU and RAM rates. When the node cost is unknown the modeled cost
+// (capacity x rate) is used for each portion.
+func splitNodeCost(hourlyCost, cpuCores, memGB, cpuRate, memRate float64) (cpuCost, ramCost float64) {
+	cpuCost = cpuCores * cpuRate
+	ramCost = memGB * memRate
+	modeled := cpuCost + ramCost
+	if hourlyCost <= 0 || modeled <= 0 {
+		return cpuCost, ramCost
+	}
+	return hourlyCost * cpuCost / modeled, hourlyCost * ramCost / modeled
+}
+
+// rateRatio returns the ratio of a discounted rate to its list rate (1 when unknown)
+func rateRatio(rate, listRate float64) float64 {
+	if listRate <= 0 {
+		return 1
+	}
+	return rate / listRate
+}
+
+// distributeIdleCost shares each idle scope's cost across the allocation fragments in
+// that scope and returns the idle costs that could not be shared (scopes without
+// allocations).
+//
+// "even" splits each scope's idle cost equally across the distinct allocations in it;
+// "weighted" shares CPU idle in proportion to CPU cost and RAM idle in proportion to
+// RAM cost. Shared amounts are added to CPUCost, RAMCost, TotalCost and SharedIdleCost.
+func (s *AllocationService) distributeIdleCost(fragments []*Allocation, idleCosts []*IdleCost, method string) []*IdleCost {
+	var unshared []*IdleCost
+
+	for _, idle := range idleCosts {
+		if idle.TotalCost() <= 0 {
+			continue
+		}
+
+		var inScope []*Allocation
+		for _, alloc := range fragments {
+			if idle.contains(alloc) {
+				inScope = append(inScope, alloc)
+			}
+		}
+		if len(inScope) == 0 {
+			unshared = append(unshared, idle)
+			continue
+		}
+
+		var cpuWeights, ramWeights []float64
+		if method == ShareIdleWeighted {
+			cpuWeights = fragmentWeights(inScope, func(a *Allocation) float64 { return a.CPUCost })
+			ramWeights = fragmentWeights(inScope, func(a *Allocation) float64 { return a.RAMCost })
+		} else {
+			cpuWeights = evenWeights(inScope)
+			ramWeights = cpuWeights
+		}
+
+		for i, alloc := range inScope {
+			cpuShare := idle.CPUCost * cpuWeights[i]
+			ramShare := idle.RAMCost * ramWeights[i]
+			alloc.CPUCost += cpuShare
+			alloc.RAMCost += ramShare
+			alloc.TotalCost += cpuShare + ramShare
+			alloc.SharedIdleCost += cpuShare + ramShare
+			alloc.ListCost += idle.ListCPUCost*cpuWeights[i] + idle.ListRAMCost*ramWeights[i]
+		}
+	}
+
+	return unshared
+}
+
+// fragmentWeights returns each fragment's proportion of the weighted value,
+// falling back to an even split when the values sum to zero
+func fragmentWeights(fragments []*Allocation, value func(*Allocation) float64) []float64 {
+	var total float64
+	for _, alloc := range fragments {
+		total += value(alloc)
+	}
+	if total <= 0 {
+		return evenWeights(fragments)
+	}
+
+	weights := make([]float64, len(fragments))
+	for i, alloc := range fragments {
+		weights[i] = value(alloc) / total
+	}
+	return weights
+}
+
+// evenWeights splits equally across distinct allocation names. A name with several
+// fragments in the scope receives its whole share on its first fragment.
+func evenWeights(fragments []*Allocation) []float64 {
+	seen := make(map[string]bool)
+	for _, alloc := range fragments {
+		seen[alloc.Name] = true
+	}
+
+	weights := make([]float64, len(fragments))
+	assigned := make(map[string]bool)
+	for i, alloc := range fragments {
+		if !assigned[alloc.Name] {
+			weights[i] = 1 / float64(len(seen))
+			assigned[alloc.Name] = true
+		}
+	}
+	return weights
+}
@@ -0,0 +1,125 @@
+package services
+
+import (
+	"testing"
+
+	"github.com/bugfreev587/k8s-cost-api-server/internal/models"
+	"github.com/stretchr/testify/assert"
+)
+
+func testFragment(name, cluster, node string, cpuCost, ramCost float64) *Allocation {
+	return &Allocation{
+		Name:       name,
+		CPUCost:    cpuCost,
+		RAMCost:    ramCost,
+		TotalCost:  cpuCost + ramCost,
+		Properties: AllocationProps{Cluster: cluster, Node: node},
+	}
+}
+
+func TestDistributeIdleCost_WeightedByResourceWithinCluster(t *testing.T) {
+	s := &AllocationService{}
+	fragments := []*Allocation{
+		testFragment("web", "prod", "n1", 3, 1),
+		testFragment("batch", "prod", "n2", 1, 3),
+		testFragment("web", "dev", "n3", 1, 1),
+	}
+	idle := []*IdleCost{
+		{Cluster: "prod", CPUCost: 8, RAMCost: 4},
+		{Cluster: "staging", CPUCost: 2, RAMCost: 2},
+	}
+
+	unshared := s.distributeIdleCost(fragments, idle, ShareIdleWeighted)
+
+	// prod CPU idle split 3:1, RAM idle split 1:3; dev is untouched
+	assert.InDelta(t, 9, fragments[0].CPUCost, 1e-9)
+	assert.InDelta(t, 2, fragments[0].RAMCost, 1e-9)
+	assert.InDelta(t, 7, fragments[0].SharedIdleCost, 1e-9)
+	assert.InDelta(t, 3, fragments[1].CPUCost, 1e-9)
+	assert.InDelta(t, 6, fragments[1].RAMCost, 1e-9)
+	assert.InDelta(t, 5, fragments[1].SharedIdleCost, 1e-9)
+	assert.Zero(t, fragments[2].SharedIdleCost)
+
+	// staging has no allocations so its idle stays unshared
+	if assert.Len(t, unshared, 1) {
+		assert.Equal(t, "staging/__idle__", unshared[0].Name())
+	}
+
+	var shared float64
+	for _, f := range fragments {
+		shared += f.SharedIdleCost
+	}
+	assert.InDelta(t, 12, shared, 1e-9)
+}
+
+func TestDistributeIdleCost_EvenSplitsPerAllocationName(t *testing.T) {
+	s := &AllocationService{}
+	fragments := []*Allocation{
+		testFragment("web", "prod", "n1", 1, 1),
+		testFragment("web", "prod", "n2", 1, 1),
+		testFragment("batch", "prod", "n2", 5, 5),
+	}
+	idle := []*IdleCost{{CPUCost: 4, RAMCost: 2}}
+
+	unshared := s.distributeIdleCost(fragments, idle, ShareIdleEven)
+
+	assert.Empty(t, unshared)
+	merged := s.mergeFragments(fragments)
+	assert.InDelta(t, 3, merged["web"].SharedIdleCost, 1e-9)
+	assert.InDelta(t, 3, merged["batch"].SharedIdleCost, 1e-9)
+	assert.InDelta(t, 7, merged["web"].TotalCost, 1e-9)
+}
+
+func TestSplitNodeCost(t *testing.T) {
+	cpu, ram := splitNodeCost(1.0, 4, 16, 0.03, 0.004)
+	assert.InDelta(t, 1.0, cpu+ram, 1e-9)
+	assert.InDelta(t, 0.12/0.184, cpu, 1e-9)
+
+	// Unknown node cost falls back to the modeled cost
+	cpu, ram = splitNodeCost(0, 2, 8, 0.03, 0.004)
+	assert.InDelta(t, 0.06, cpu, 1e-9)
+	assert.InDelta(t, 0.032, ram, 1e-9)
+}
+
+func TestNodeIdleCost_PodsAndIdleAddUpToNodePrice(t *testing.T) {
+	pricing := &models.EffectivePricing{
+		CPUPerCoreHour:  0.04,
+		MemoryPerGBHour: 0.005,
+		CPURAMCostRatio: 8,
+		InstancePricing: map[string]*models.InstancePrice{"m5.xlarge": {InstanceType: "m5.xlarge", HourlyCost: 0.192}},
+		NodePricing:     map[string]*models.InstancePrice{},
+	}
+	p := &clusterPricing{rates: map[string]*models.EffectivePricing{"prod": pricing}}
+	gib := float64(1 << 30)
+	node := nodeInventory{Cluster: "prod", Node: "n1", InstanceType: "m5.xlarge", CPUCores: 4, MemoryBytes: 16 * gib}
+	cpuRate, memRate, source := p.nodeRates(node)
+	assert.Equal(t, models.PriceSourceInstanceType, source)
+
+	const hours = 24.0
+	for _, tc := range []struct {
+		name              string
+		allocCPU, allocGB float64
+	}{
+		{"fully packed", 4, 16},
+		{"partly packed", 3, 4},
+		{"empty", 0, 0},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			// The agent's own estimate of the node cost is ignored for a priced instance type
+			idle := p.nodeIdleCost(node, 0.5, tc.allocCPU, tc.allocGB*gib, hours, nodeCostAdjustment{}, false)
+			podCost := (tc.allocCPU*cpuRate + tc.allocGB*memRate) * hours
+			assert.InDelta(t, 0.192*hours, podCost+idle.TotalCost(), 1e-9)
+			assert.InDelta(t, 0.192*hours, podCost+idle.ListCPUCost+idle.ListRAMCost, 1e-9)
+		})
+	}
+
+	// Pods using more than the node's capacity leave no idle cost
+	idle := p.nodeIdleCost(node, 0, 5, 20*gib, hours, nodeCostAdjustment{}, false)
+	assert.Zero(t, idle.TotalCost())
+
+	// Reconciled billing cost: pods are charged at the node's scaled rates
+	adj := nodeCostAdjustment{ReconciledHourlyCost: 0.15, RateFactor: 0.15 / 0.192}
+	idle = p.nodeIdleCost(node, 0, 2, 8*gib, hours, adj, true)
+	podCost := (2*cpuRate + 8*memRate) * adj.RateFactor * hours
+	assert.InDelta(t, 0.15*hours, podCost+idle.TotalCost(), 1e-9)
+}
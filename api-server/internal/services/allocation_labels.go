@@ -0,0 +1,204 @@
+package services
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"regexp"
+	"strings"
+
+	"github.com/bugfreev587/k8s-cost-api-server/internal/models"
+	"github.com/jackc/pgx/v5/pgconn"
+)
+
+// MaxLabelRewrites caps the number of regex rewrites in a virtual label rule
+const MaxLabelRewrites = 20
+
+var labelKeyPattern = regexp.MustCompile(`^[A-Za-z0-9]([A-Za-z0-9._/-]*[A-Za-z0-9])?$`)
+
+// pgInvalidRegularExpression is the SQLSTATE Postgres raises for a malformed pattern
+const pgInvalidRegularExpression = "2201B"
+
+// RewritePatternError describes a rewrite pattern Postgres cannot compile
+type RewritePatternError struct {
+	Pattern string
+	Msg     string
+}
+
+func (e *RewritePatternError) Error() string {
+	return fmt.Sprintf("invalid rewrite pattern %q: %s", e.Pattern, e.Msg)
+}
+
+// resolveVirtualLabels loads the tenant's enabled virtual label rules, keyed by lowercased
+// key, when the query aggregates or filters by label and raw labels were not requested
+func (s *AllocationService) resolveVirtualLabels(ctx context.Context, tenantID int64, params AllocationParams) (map[string]*models.VirtualLabelRule, error) {
+	if params.RawLabels || s.postgresDB == nil {
+		return nil, nil
+	}
+	needed := false
+	for _, agg := range strings.Split(params.Aggregate, ",") {
+		if strings.HasPrefix(strings.ToLower(strings.TrimSpace(agg)), "label:") {
+			needed = true
+		}
+	}
+	if filterUsesField(params.filter, "label") {
+		needed = true
+	}
+	if !needed {
+		return nil, nil
+	}
+
+	var stored []models.VirtualLabelRule
+	if err := s.postgresDB.WithContext(ctx).
+		Where("tenant_id = ? AND enabled = true", tenantID).
+		Find(&stored).Error; err != nil {
+		return nil, fmt.Errorf("failed to load virtual label rules: %w", err)
+	}
+
+	rules := make(map[string]*models.VirtualLabelRule, len(stored))
+	for i := range stored {
+		rule := &stored[i]
+		if err := ValidateVirtualLabelRule(rule); err != nil {
+			return nil, fmt.Errorf("virtual label rule %q: %w", rule.Key, err)
+		}
+		rules[rule.Key] = rule
+	}
+	return rules, nil
+}
+
+// virtualLabelExpr builds a SQL expression that evaluates to a virtual label's value for a
+// pod_metrics row, NULL when the rule derives none. Placeholders start at argIdx.
+func virtualLabelExpr(rule *models.VirtualLabelRule, argIdx int) (string, []interface{}) {
+	var args []interface{}
+	param := func(value interface{}, cast string) string {
+		args = append(args, value)
+		argIdx++
+		return fmt.Sprintf("$%d%s", argIdx-1, cast)
+	}
+
+	sources := make([]string, len(rule.Sources))
+	for i, key := range rule.Sources {
+		sources[i] = fmt.Sprintf("labels->>%s", param(key, "::text"))
+	}
+	expr := sources[0]
+	if len(sources) > 1 {
+		expr = fmt.Sprintf("COALESCE(%s)", strings.Join(sources, ", "))
+	}
+
+	if rule.Lowercase {
+		expr = fmt.Sprintf("LOWER(%s)", expr)
+	}
+	for _, rw := range rule.Rewrites {
+		// A value rewritten to the empty string has no value
+		expr = fmt.Sprintf("NULLIF(REGEXP_REPLACE(%s, %s, %s), '')", expr, param(rw.Pattern, "::text"), param(rw.Replacement, "::text"))
+	}
+	if len(rule.Lookup) > 0 {
+		lookup, _ := json.Marshal(rule.Lookup)
+		p := param(string(lookup), "::jsonb")
+		expr = fmt.Sprintf("COALESCE(%s->>%s, %s)", p, expr, expr)
+	}
+	if len(rule.NamespaceDefaults) > 0 {
+		defaults, _ := json.Marshal(rule.NamespaceDefaults)
+		expr = fmt.Sprintf("COALESCE(%s, %s->>namespace)", expr, param(string(defaults), "::jsonb"))
+	}
+	if rule.Default != "" {
+		expr = fmt.Sprintf("COALESCE(%s, %s)", expr, param(rule.Default, "::text"))
+	}
+	return "(" + expr + ")", args
+}
+
+// ValidateVirtualLabelRule checks a virtual label rule, lowercasing its key and defaulting
+// its sources to the key itself. Rewrite patterns are Postgres regular expressions, which
+// CheckRewritePatterns compiles in the database.
+func ValidateVirtualLabelRule(rule *models.VirtualLabelRule) error {
+	rule.Key = strings.ToLower(strings.TrimSpace(rule.Key))
+	if len(rule.Key) > 100 || !labelKeyPattern.MatchString(rule.Key) {
+		return fmt.Errorf("invalid label key: %q", rule.Key)
+	}
+
+	if len(rule.Sources) == 0 {
+		rule.Sources = []string{rule.Key}
+	}
+	for i, source := range rule.Sources {
+		source = strings.TrimSpace(source)
+		if !labelKeyPattern.MatchString(source) {
+			return fmt.Errorf("invalid source label key: %q", source)
+		}
+		rule.Sources[i] = source
+	}
+
+	if len(rule.Rewrites) > MaxLabelRewrites {
+		return fmt.Errorf("a rule can have at most %d rewrites", MaxLabelRewrites)
+	}
+	for _, rw := range rule.Rewrites {
+		if rw.Pattern == "" {
+			return fmt.Errorf("rewrite pattern is required")
+		}
+	}
+
+	for from := range rule.Lookup {
+		if from == "" {
+			return fmt.Errorf("lookup values must not be empty")
+		}
+	}
+	for namespace, value := range rule.NamespaceDefaults {
+		if namespace == "" || value == "" {
+			return fmt.Errorf("namespace defaults need a namespace and a value")
+		}
+	}
+	return nil
+}
+
+// CheckRewritePatterns compiles a rule's rewrite patterns with Postgres REGEXP_REPLACE, which
+// evaluates them at query time; its regular expressions differ from Go's, so a pattern is
+// only valid if the database accepts it. A rejected pattern is a *RewritePatternError.
+func (s *AllocationService) CheckRewritePatterns(ctx context.Context, rule *models.VirtualLabelRule) error {
+	if len(rule.Rewrites) == 0 {
+		return nil
+	}
+	if s.pool == nil {
+		return fmt.Errorf("rewrite patterns cannot be checked without a database")
+	}
+	for _, rw := range rule.Rewrites {
+		var out string
+		err := s.pool.QueryRow(ctx, `SELECT REGEXP_REPLACE('', $1::text, '')`, rw.Pattern).Scan(&out)
+		var pgErr *pgconn.PgError
+		if errors.As(err, &pgErr) && pgErr.Code == pgInvalidRegularExpression {
+			return &RewritePatternError{Pattern: rw.Pattern, Msg: pgErr.Message}
+		}
+		if err != nil {
+			return fmt.Errorf("failed to check rewrite pattern %q: %w", rw.Pattern, err)
+		}
+	}
+	return nil
+}
+
+// ListVirtualLabelRules lists a tenant's virtual label rules
+func (s *AllocationService) ListVirtualLabelRules(ctx context.Context, tenantID uint) ([]models.VirtualLabelRule, error) {
+	var rules []models.VirtualLabelRule
+	err := s.postgresDB.WithContext(ctx).
+		Where("tenant_id = ?", tenantID).
+		Order("key ASC").
+		Find(&rules).Error
+	return rules, err
+}
+
+// GetVirtualLabelRule retrieves a virtual label rule
+func (s *AllocationService) GetVirtualLabelRule(ctx context.Context, ruleID uint) (*models.VirtualLabelRule, error) {
+	var rule models.VirtualLabelRule
+	if err := s.postgresDB.WithContext(ctx).First(&rule, ruleID).Error; err != nil {
+		return nil, err
+	}
+	return &rule, nil
+}
+
+// SaveVirtualLabelRule creates or updates a virtual label rule
+func (s *AllocationService) SaveVirtualLabelRule(ctx context.Context, rule *models.VirtualLabelRule) error {
+	return s.postgresDB.WithContext(ctx).Save(rule).Error
+}
+
+// DeleteVirtualLabelRule deletes a virtual label rule
+func (s *AllocationService) DeleteVirtualLabelRule(ctx context.Context, ruleID uint) error {
+	return s.postgresDB.WithContext(ctx).Delete(&models.VirtualLabelRule{}, ruleID).Error
+}
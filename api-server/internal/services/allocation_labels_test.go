@@ -0,0 +1,112 @@
+package services
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"testing"
+
+	"github.com/bugfreev587/k8s-cost-api-server/internal/models"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// bindLabelExpr substitutes a virtualLabelExpr's placeholders, from argIdx on, with their
+// arguments as quoted literals, so tests can check which value each step of the expression
+// uses. It fails the test if a placeholder has no argument or an argument is unused.
+func bindLabelExpr(t *testing.T, expr string, args []interface{}, argIdx int) string {
+	t.Helper()
+	used := make([]bool, len(args))
+	bound := regexp.MustCompile(`\$\d+`).ReplaceAllStringFunc(expr, func(placeholder string) string {
+		n, _ := strconv.Atoi(placeholder[1:])
+		i := n - argIdx
+		if i < 0 || i >= len(args) {
+			t.Errorf("placeholder %s has no argument", placeholder)
+			return placeholder
+		}
+		used[i] = true
+		return fmt.Sprintf("'%v'", args[i])
+	})
+	for i, ok := range used {
+		assert.True(t, ok, "argument %d is not used", i)
+	}
+	return bound
+}
+
+func TestVirtualLabelExpr(t *testing.T) {
+	sources := "COALESCE(labels->>'team'::text, labels->>'squad'::text)"
+	tests := []struct {
+		name     string
+		rule     models.VirtualLabelRule
+		contains []string
+	}{
+		{
+			name:     "single source",
+			rule:     models.VirtualLabelRule{Sources: []string{"team"}},
+			contains: []string{"(labels->>'team'::text)"},
+		},
+		{
+			name:     "first source with a value",
+			rule:     models.VirtualLabelRule{Sources: []string{"team", "squad"}},
+			contains: []string{"(" + sources + ")"},
+		},
+		{
+			name: "rewrites apply in order to the lowercased value",
+			rule: models.VirtualLabelRule{
+				Sources:   []string{"team", "squad"},
+				Lowercase: true,
+				Rewrites:  models.LabelRewrites{{Pattern: `^team-`, Replacement: ""}, {Pattern: `-v\d+$`, Replacement: ""}},
+			},
+			contains: []string{
+				"NULLIF(REGEXP_REPLACE(NULLIF(REGEXP_REPLACE(LOWER(" + sources + "), '^team-'::text, ''::text), ''), '-v\\d+$'::text, ''::text), '')",
+			},
+		},
+		{
+			name: "lookup maps the rewritten value and keeps unmapped values",
+			rule: models.VirtualLabelRule{
+				Sources:  []string{"team"},
+				Rewrites: models.LabelRewrites{{Pattern: `^team-`, Replacement: ""}},
+				Lookup:   models.CostTags{"pay": "payments"},
+			},
+			contains: []string{
+				`COALESCE('{"pay":"payments"}'::jsonb->>NULLIF(REGEXP_REPLACE(labels->>'team'::text, '^team-'::text, ''::text), ''), ` +
+					"NULLIF(REGEXP_REPLACE(labels->>'team'::text, '^team-'::text, ''::text), ''))",
+			},
+		},
+		{
+			name: "namespace defaults and then the default fill missing values",
+			rule: models.VirtualLabelRule{
+				Sources:           []string{"team"},
+				NamespaceDefaults: models.CostTags{"checkout": "payments"},
+				Default:           "platform",
+			},
+			contains: []string{
+				`(COALESCE(COALESCE(labels->>'team'::text, '{"checkout":"payments"}'::jsonb->>namespace), 'platform'::text))`,
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			expr, args := virtualLabelExpr(&tt.rule, 4)
+			bound := bindLabelExpr(t, expr, args, 4)
+			for _, want := range tt.contains {
+				assert.Contains(t, bound, want)
+			}
+		})
+	}
+}
+
+func TestValidateVirtualLabelRule(t *testing.T) {
+	rule := &models.VirtualLabelRule{Key: " Team "}
+	require.NoError(t, ValidateVirtualLabelRule(rule))
+	assert.Equal(t, "team", rule.Key)
+	assert.Equal(t, []string{"team"}, []string(rule.Sources))
+
+	assert.Error(t, ValidateVirtualLabelRule(&models.VirtualLabelRule{Key: "team name"}))
+	assert.Error(t, ValidateVirtualLabelRule(&models.VirtualLabelRule{Key: "team", Sources: []string{""}}))
+	assert.Error(t, ValidateVirtualLabelRule(&models.VirtualLabelRule{Key: "team", Rewrites: models.LabelRewrites{{Pattern: ""}}}))
+	// Patterns are compiled by Postgres, whose syntax Go's regexp does not accept in full
+	assert.NoError(t, ValidateVirtualLabelRule(&models.VirtualLabelRule{Key: "team", Rewrites: models.LabelRewrites{{Pattern: `^team-(?=\w)`}}}))
+	assert.Error(t, ValidateVirtualLabelRule(&models.VirtualLabelRule{Key: "team", NamespaceDefaults: models.CostTags{"checkout": ""}}))
+	assert.NoError(t, ValidateVirtualLabelRule(&models.VirtualLabelRule{Key: "app.kubernetes.io/part-of"}))
+}
@@ -0,0 +1,183 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/bugfreev587/k8s-cost-api-server/internal/models"
+)
+
+// nodeCostAdjustment adjusts a node's modeled cost: ReconciledHourlyCost is its actual
+// cost from imported billing data (0 if none) and RateFactor scales its modeled CPU and
+// RAM rates to match the reconciled cost or apply commitment discounts (0 = no change)
+type nodeCostAdjustment struct {
+	ReconciledHourlyCost float64
+	RateFactor           float64
+}
+
+// nodeKey identifies a node within a tenant
+func nodeKey(cluster, node string) string {
+	return cluster + "/" + node
+}
+
+// nodeInventory is a node's average capacity and modeled on-demand cost over a window
+type nodeInventory struct {
+	Cluster       string
+	Node          string
+	InstanceType  string
+	CPUCores      float64
+	MemoryBytes   float64
+	ModeledHourly float64
+}
+
+// loadNodeInventory returns the tenant's nodes reporting metrics in the window with their
+// modeled hourly cost (capacity x rates)
+func (s *AllocationService) loadNodeInventory(ctx context.Context, tenantID int64, start, end time.Time) ([]nodeInventory, error) {
+	rows, err := s.pool.Query(ctx, `
+		SELECT cluster_name, node_name, COALESCE(MAX(instance_type), ''),
+			COALESCE(AVG(cpu_capacity), 0)::float8 / 1000.0,
+			COALESCE(AVG(memory_capacity), 0)::float8
+		FROM node_metrics
+		WHERE tenant_id = $1 AND time >= $2 AND time <= $3
+		GROUP BY cluster_name, node_name
+	`, tenantID, start, end)
+	if err != nil {
+		return nil, fmt.Errorf("node inventory query failed: %w", err)
+	}
+	defer rows.Close()
+
+	var nodes []nodeInventory
+	for rows.Next() {
+		var n nodeInventory
+		if err := rows.Scan(&n.Cluster, &n.Node, &n.InstanceType, &n.CPUCores, &n.MemoryBytes); err != nil {
+			return nil, fmt.Errorf("node inventory scan failed: %w", err)
+		}
+		nodes = append(nodes, n)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	pricing := s.newClusterPricing(ctx, tenantID, start)
+	for i := range nodes {
+		cpuRate, memRate, _ := pricing.nodeRates(nodes[i])
+		nodes[i].ModeledHourly = nodes[i].CPUCores*cpuRate + nodes[i].MemoryBytes/1024/1024/1024*memRate
+	}
+	return nodes, nil
+}
+
+// node returns the step's inventory of a node, or the node with unknown capacity
+func (p AllocationParams) node(cluster, name string) nodeInventory {
+	if n, ok := p.nodes[nodeKey(cluster, name)]; ok {
+		return n
+	}
+	return nodeInventory{Cluster: cluster, Node: name}
+}
+
+// loadNodeCostAdjustments returns the cost adjustments of the window's nodes, keyed by
+// nodeKey. Nodes with reconciled billing data use their actual cost (which already
+// reflects any discounts); commitments are applied to the remaining nodes.
+func (s *AllocationService) loadNodeCostAdjustments(ctx context.Context, tenantID int64, start, end time.Time, nodes []nodeInventory, params AllocationParams) (map[string]nodeCostAdjustment, error) {
+	if s.postgresDB == nil {
+		return nil, nil
+	}
+
+	adjustments := make(map[string]nodeCostAdjustment)
+
+	if params.Reconcile {
+		var billed []struct {
+			ClusterName string
+			NodeName    string
+			NetCost     float64
+			UsageHours  float64
+		}
+		err := s.postgresDB.WithContext(ctx).Raw(`
+			SELECT cluster_name, node_name, SUM(net_cost) as net_cost, SUM(usage_hours) as usage_hours
+			FROM reconciled_node_costs
+			WHERE tenant_id = ? AND period_start < ? AND period_end > ?
+			GROUP BY cluster_name, node_name
+		`, tenantID, end, start).Scan(&billed).Error
+		if err != nil {
+			return nil, fmt.Errorf("failed to load reconciled node costs: %w", err)
+		}
+
+		modeled := make(map[string]float64, len(nodes))
+		for _, n := range nodes {
+			modeled[nodeKey(n.Cluster, n.Node)] = n.ModeledHourly
+		}
+		for _, b := range billed {
+			if b.UsageHours <= 0 {
+				continue
+			}
+			key := nodeKey(b.ClusterName, b.NodeName)
+			adj := nodeCostAdjustment{ReconciledHourlyCost: b.NetCost / b.UsageHours}
+			if m := modeled[key]; m > 0 {
+				adj.RateFactor = adj.ReconciledHourlyCost / m
+			}
+			adjustments[key] = adj
+		}
+	}
+
+	if params.ApplyCommitments {
+		commitments, err := s.loadActiveCommitments(ctx, tenantID, start, end)
+		if err != nil {
+			return nil, err
+		}
+		if len(commitments) > 0 {
+			var eligible []commitmentNode
+			for _, n := range nodes {
+				key := nodeKey(n.Cluster, n.Node)
+				if _, reconciled := adjustments[key]; reconciled || n.ModeledHourly <= 0 {
+					continue
+				}
+				eligible = append(eligible, commitmentNode{Key: key, Cluster: n.Cluster, InstanceType: n.InstanceType, OnDemandHourly: n.ModeledHourly})
+			}
+
+			reductions, _ := applyCommitments(eligible, commitments, start, end, s.commitmentDiscountFunc(ctx, tenantID, start))
+			for _, n := range eligible {
+				if reduction := reductions[n.Key]; reduction > 0 {
+					adjustments[n.Key] = nodeCostAdjustment{RateFactor: 1 - reduction/n.OnDemandHourly}
+				}
+			}
+		}
+	}
+
+	return adjustments, nil
+}
+
+// loadActiveCommitments returns the tenant's commitments active during the window
+func (s *AllocationService) loadActiveCommitments(ctx context.Context, tenantID int64, start, end time.Time) ([]models.Commitment, error) {
+	var commitments []models.Commitment
+	err := s.postgresDB.WithContext(ctx).
+		Where("tenant_id = ? AND start_date < ? AND end_date > ?", tenantID, end, start).
+		Order("id ASC").
+		Find(&commitments).Error
+	if err != nil {
+		return nil, fmt.Errorf("failed to load commitments: %w", err)
+	}
+	return commitments, nil
+}
+
+// commitmentDiscountFunc resolves a commitment's discount versus on-demand: its declared
+// discount, else the committed tier discount of its cluster's pricing config, else the
+// provider default for its term
+func (s *AllocationService) commitmentDiscountFunc(ctx context.Context, tenantID int64, asOf time.Time) func(*models.Commitment) float64 {
+	return func(c *models.Commitment) float64 {
+		if c.DiscountPercent > 0 {
+			return c.DiscountPercent / 100
+		}
+		if s.pricingSvc != nil {
+			cluster := ""
+			if len(c.Clusters) > 0 {
+				cluster = c.Clusters[0]
+			}
+			if pricing, err := s.pricingSvc.GetEffectiveRates(ctx, uint(tenantID), cluster, asOf); err == nil && pricing != nil {
+				if discount, ok := pricing.TierDiscounts[c.Term]; ok {
+					return discount
+				}
+			}
+		}
+		return models.DefaultCommitmentDiscount(c.Provider, c.Term)
+	}
+}
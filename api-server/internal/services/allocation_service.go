@@ -9,6 +9,7 @@ import (
 	"strings"
 	"time"
 
+	"github.com/bugfreev587/k8s-cost-api-server/internal/models"
 	"github.com/jackc/pgx/v5/pgxpool"
 	"gorm.io/gorm"
 )
@@ -27,69 +28,110 @@ func NewAllocationService(pool *pgxpool.Pool) *AllocationService {
 
 // NewAllocationServiceWithPricing creates an allocation service with dynamic pricing support
 func NewAllocationServiceWithPricing(pool *pgxpool.Pool, postgresDB *gorm.DB) *AllocationService {
+	return NewAllocationServiceWithPricingService(pool, postgresDB, NewPricingService(postgresDB))
+}
+
+// NewAllocationServiceWithPricingService creates an allocation service priced by an existing
+// pricing service, sharing its cache
+func NewAllocationServiceWithPricingService(pool *pgxpool.Pool, postgresDB *gorm.DB, pricingSvc *PricingService) *AllocationService {
 	return &AllocationService{
 		pool:       pool,
 		postgresDB: postgresDB,
-		pricingSvc: NewPricingService(postgresDB),
+		pricingSvc: pricingSvc,
 	}
 }
 
-// getClusterPricing returns effective pricing for a cluster
-func (s *AllocationService) getClusterPricing(ctx context.Context, tenantID int64, clusterName string, asOf time.Time) (cpuRate, memRate float64) {
-	// Default rates
-	cpuRate = DefaultCPUCostPerCoreHour
-	memRate = DefaultRAMCostPerGBHour
+// getNodePricing returns the rates of a node: its hourly cost override or instance type
+// pricing, with whole-node prices split by the node's capacity, else the cluster defaults
+func (s *AllocationService) getNodePricing(ctx context.Context, tenantID int64, node nodeInventory, asOf time.Time) (cpuRate, memRate float64, source models.PriceSource) {
+	return s.newClusterPricing(ctx, tenantID, asOf).nodeRates(node)
+}
 
-	// If pricing service is available, get cluster-specific pricing
-	if s.pricingSvc != nil {
-		pricing, err := s.pricingSvc.GetEffectiveRates(ctx, uint(tenantID), clusterName, asOf)
-		if err == nil && pricing != nil {
-			cpuRate = pricing.CPUPerCoreHour
-			memRate = pricing.MemoryPerGBHour
-		}
-	}
+// getNodeListPricing returns the list (pre-discount) rates for a node, mirroring getNodePricing
+func (s *AllocationService) getNodeListPricing(ctx context.Context, tenantID int64, node nodeInventory, asOf time.Time) (cpuRate, memRate float64) {
+	return s.newClusterPricing(ctx, tenantID, asOf).nodeListRates(node)
+}
 
-	return cpuRate, memRate
+// clusterPricing resolves each cluster's effective rates once, for pricing many nodes
+// at the same point in time
+type clusterPricing struct {
+	s        *AllocationService
+	ctx      context.Context
+	tenantID int64
+	asOf     time.Time
+	rates    map[string]*models.EffectivePricing // nil when the defaults apply
 }
 
-// getNodePricing returns pricing for a specific node (with instance-type overrides)
-func (s *AllocationService) getNodePricing(ctx context.Context, tenantID int64, clusterName, nodeName string, asOf time.Time) (cpuRate, memRate float64, hasOverride bool) {
-	// Start with cluster defaults
-	cpuRate, memRate = s.getClusterPricing(ctx, tenantID, clusterName, asOf)
-	hasOverride = false
-
-	// If pricing service is available, check for node-specific overrides
-	if s.pricingSvc != nil {
-		pricing, err := s.pricingSvc.GetEffectiveRates(ctx, uint(tenantID), clusterName, asOf)
-		if err == nil && pricing != nil && pricing.InstancePricing != nil {
-			// Check for node-specific pricing
-			if nodePricing, ok := pricing.InstancePricing[nodeName]; ok {
-				if nodePricing.CPUPerCoreHour > 0 {
-					cpuRate = nodePricing.CPUPerCoreHour
-					hasOverride = true
-				}
-				if nodePricing.MemoryPerGBHour > 0 {
-					memRate = nodePricing.MemoryPerGBHour
-					hasOverride = true
-				}
-			}
+func (s *AllocationService) newClusterPricing(ctx context.Context, tenantID int64, asOf time.Time) *clusterPricing {
+	return &clusterPricing{s: s, ctx: ctx, tenantID: tenantID, asOf: asOf, rates: make(map[string]*models.EffectivePricing)}
+}
+
+// get returns a cluster's effective pricing, nil when no pricing is available
+func (p *clusterPricing) get(cluster string) *models.EffectivePricing {
+	if pricing, ok := p.rates[cluster]; ok {
+		return pricing
+	}
+	var pricing *models.EffectivePricing
+	if p.s.pricingSvc != nil {
+		if rates, err := p.s.pricingSvc.GetEffectiveRates(p.ctx, uint(p.tenantID), cluster, p.asOf); err == nil {
+			pricing = rates
 		}
 	}
+	p.rates[cluster] = pricing
+	return pricing
+}
 
-	return cpuRate, memRate, hasOverride
+// nodeRates returns a node's rates; see getNodePricing
+func (p *clusterPricing) nodeRates(node nodeInventory) (cpuRate, memRate float64, source models.PriceSource) {
+	pricing := p.get(node.Cluster)
+	if pricing == nil {
+		return DefaultCPUCostPerCoreHour, DefaultRAMCostPerGBHour, models.PriceSourceDefault
+	}
+	return pricing.NodeRates(node.Node, node.InstanceType, node.CPUCores, node.MemoryBytes/1024/1024/1024)
+}
+
+// nodeListRates returns a node's list rates; see getNodeListPricing
+func (p *clusterPricing) nodeListRates(node nodeInventory) (cpuRate, memRate float64) {
+	pricing := p.get(node.Cluster)
+	if pricing == nil {
+		return DefaultCPUCostPerCoreHour, DefaultRAMCostPerGBHour
+	}
+	return pricing.NodeListRates(node.Node, node.InstanceType, node.CPUCores, node.MemoryBytes/1024/1024/1024)
 }
 
 // AllocationParams represents query parameters for the allocation API
 type AllocationParams struct {
 	Window     string   // "24h", "7d", "lastweek", "2024-01-01,2024-01-07"
-	Aggregate  string   // "namespace", "cluster", "label:team", "node", "pod", or comma-separated
+	Aggregate  string   // "namespace", "cluster", "label:team", "node", "pod", "container", "costCenter[:level]", or comma-separated
 	Step       string   // "1h", "1d", "1w" - time bucket size for time-series results
 	Accumulate string   // "true", "false", "hour", "day", "week" - how to accumulate results
 	Idle       bool     // Include idle cost allocation
-	ShareIdle  string   // "true", "false", "weighted" - how to distribute idle costs
-	Filters    []string // Filter expressions: "namespace:kube-system", "cluster:prod", "label:app=nginx"
+	ShareIdle  string   // "true"/"even", "false", "weighted" - how to distribute idle costs
+	IdleBy     string   // "tenant" (default), "cluster", "node" - scope within which idle costs are computed and shared
+	Filters    []string // Filter expressions, all of which must match: `namespace:"a","b"+label[app]!:"x"`, or legacy "namespace:kube-system", "label:app=nginx"
 	Offset     int      // Pagination offset
 	Limit      int      // Pagination limit (default 1000)
+
+	// Shared costs: workloads matching these namespaces/labels are spread over all other allocations
+	ShareNamespaces   []string // Namespaces whose cost is shared, e.g. "kube-system", "monitoring"
+	ShareLabels       []string // Pod label selectors whose cost is shared: "key=value" or "key"
+	ShareSplit        string   // "even" or "weighted" (default)
+	ApplySharingRules bool     // Apply the tenant's stored shared cost rules when no share parameters are given
+
+	RawLabels bool // Aggregate and filter on raw pod labels, ignoring the tenant's virtual label rules
+
+	IncludeExternal bool // Merge external (out-of-cluster) cost line items into allocations by their tags
+	Reconcile        bool // Use actual node costs from imported billing exports where available
+	ApplyCommitments bool // Apply amortized reserved instance / savings plan / committed use discounts
+	Currency         string // ISO 4217 currency to report costs in ("" = USD), converted at each step's rate
+	PricingAsOf      *time.Time // Price with the pricing configs as they stood at this time (nil = current)
+
+	filter        Filter // parsed Filters
+	sharingRules  []SharingRule
+	costCenters   *CostCenterTree                     // loaded for costCenter aggregations
+	virtualLabels map[string]*models.VirtualLabelRule // loaded for label aggregations and filters, keyed by label key
+	nodeCosts     map[string]nodeCostAdjustment       // per step, keyed by nodeKey
+	nodes         map[string]nodeInventory            // per step, keyed by nodeKey
 }
 
 // Allocation represents a single allocation entry (OpenCost-compatible structure)
@@ -121,8 +163,23 @@ type Allocation struct {
 	TotalCost       float64 `json:"totalCost"`
 	TotalEfficiency float64 `json:"totalEfficiency"`
 
+	// Idle cost shared into this allocation (already included in CPUCost, RAMCost and TotalCost)
+	SharedIdleCost float64 `json:"sharedIdleCost"`
+
+	// Cost of shared overhead workloads spread onto this allocation (already included in TotalCost)
+	SharedCost float64 `json:"sharedCost"`
+
+	// Cost of external line items (managed services, licences) matched to this allocation (already included in TotalCost)
+	ExternalCost float64 `json:"externalCost"`
+
+	// TotalCost at list prices, before negotiated discounts, commitments and billing reconciliation
+	ListCost float64 `json:"listCost"`
+
 	// Counts
 	PodCount int `json:"podCount,omitempty"`
+
+	// Name of the sharing rule matching this fragment ("" = not shared)
+	sharingRule string
 }
 
 // AllocationProps contains properties for an allocation
@@ -145,17 +202,38 @@ type TimeWindow struct {
 
 // AllocationResponse is the full response for the allocation API (OpenCost-compatible)
 type AllocationResponse struct {
-	Code   int              `json:"code"`
-	Status string           `json:"status"`
-	Data   []AllocationSet  `json:"data"`
+	Code        int             `json:"code"`
+	Status      string          `json:"status"`
+	Currency    string          `json:"currency,omitempty"`
+	PricingAsOf *time.Time      `json:"pricingAsOf,omitempty"`
+	Data        []AllocationSet `json:"data"`
 }
 
 // AllocationSet represents a set of allocations for a time period
 type AllocationSet struct {
-	Allocations map[string]*Allocation `json:"allocations"`
-	Window      TimeWindow             `json:"window"`
-	TotalCost   float64                `json:"totalCost"`
-	IdleCost    float64                `json:"idleCost,omitempty"`
+	Allocations  map[string]*Allocation `json:"allocations"`
+	Window       TimeWindow             `json:"window"`
+	TotalCost    float64                `json:"totalCost"`
+	ListCost     float64                `json:"listCost"`
+	IdleCost     float64                `json:"idleCost,omitempty"`
+	ExternalCost float64                `json:"externalCost,omitempty"`
+}
+
+// convert multiplies every cost in the set by an exchange rate
+func (set *AllocationSet) convert(rate float64) {
+	set.TotalCost *= rate
+	set.ListCost *= rate
+	set.IdleCost *= rate
+	set.ExternalCost *= rate
+	for _, alloc := range set.Allocations {
+		alloc.CPUCost *= rate
+		alloc.RAMCost *= rate
+		alloc.TotalCost *= rate
+		alloc.SharedIdleCost *= rate
+		alloc.SharedCost *= rate
+		alloc.ExternalCost *= rate
+		alloc.ListCost *= rate
+	}
 }
 
 // Cost constants (configurable in production)
@@ -174,74 +252,192 @@ func (s *AllocationService) GetAllocations(ctx context.Context, tenantID int64,
 		params.Accumulate = "true"
 	}
 
+	var allocationSets []AllocationSet
+	currency, err := s.eachAllocationSet(ctx, tenantID, params, func(set AllocationSet, _ string) error {
+		allocationSets = append(allocationSets, set)
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	// If accumulate=true, merge all sets into one
+	if params.Accumulate == "true" && len(allocationSets) > 1 {
+		start, end := allocationSets[0].Window.Start, allocationSets[len(allocationSets)-1].Window.End
+		allocationSets = []AllocationSet{s.mergeAllocationSets(allocationSets, start, end)}
+	}
+
+	// Apply pagination
+	allocationSets = s.paginateResults(allocationSets, params.Offset, params.Limit)
+
+	return &AllocationResponse{
+		Code:        200,
+		Status:      "success",
+		Currency:    currency,
+		PricingAsOf: params.PricingAsOf,
+		Data:        allocationSets,
+	}, nil
+}
+
+// EachAllocationSet computes allocations like GetAllocations but hands each step's set, with
+// the currency of its costs, to fn as soon as it is computed instead of collecting them, so
+// only one step is held in memory. Offset and limit apply to each set and only when given.
+// An error from a step or from fn stops the iteration and is returned.
+func (s *AllocationService) EachAllocationSet(ctx context.Context, tenantID int64, params AllocationParams, fn func(set AllocationSet, currency string) error) error {
+	_, err := s.eachAllocationSet(ctx, tenantID, params, func(set AllocationSet, currency string) error {
+		if params.Offset > 0 || params.Limit > 0 {
+			set = s.paginateResults([]AllocationSet{set}, params.Offset, params.Limit)[0]
+		}
+		return fn(set, currency)
+	})
+	return err
+}
+
+// eachAllocationSet computes the allocation set of each step of params.Window and passes it
+// to fn, unpaginated. It returns the currency of the costs.
+func (s *AllocationService) eachAllocationSet(ctx context.Context, tenantID int64, params AllocationParams, fn func(set AllocationSet, currency string) error) (string, error) {
+	if params.Accumulate == "" {
+		params.Accumulate = "true"
+	}
+	switch params.IdleBy {
+	case "", IdleByTenant, IdleByCluster, IdleByNode:
+	default:
+		return "", fmt.Errorf("invalid idleBy: %s", params.IdleBy)
+	}
+	filter, err := ParseFilters(params.Filters)
+	if err != nil {
+		return "", err
+	}
+	params.filter = filter
+
+	// Price with the pricing configs as they stood at PricingAsOf
+	if params.PricingAsOf != nil && s.pricingSvc != nil {
+		pinned := *s
+		pinned.pricingSvc = s.pricingSvc.PinnedAt(*params.PricingAsOf)
+		s = &pinned
+	}
+
+	sharingRules, err := s.resolveSharingRules(ctx, tenantID, params)
+	if err != nil {
+		return "", err
+	}
+	params.sharingRules = sharingRules
+
+	if params.costCenters, err = s.resolveCostCenters(ctx, tenantID, params); err != nil {
+		return "", err
+	}
+	if params.virtualLabels, err = s.resolveVirtualLabels(ctx, tenantID, params); err != nil {
+		return "", err
+	}
+
 	// Parse window into start/end times
 	startTime, endTime, err := s.parseWindow(params.Window)
 	if err != nil {
-		return nil, fmt.Errorf("invalid window: %w", err)
+		return "", fmt.Errorf("invalid window: %w", err)
 	}
 
 	// Determine time steps based on accumulate parameter
 	steps := s.calculateSteps(startTime, endTime, params.Step, params.Accumulate)
 
-	// Build response with allocation sets for each step
-	var allocationSets []AllocationSet
+	// Costs are computed in USD and converted at the rate effective at each step's start
+	currency := models.BaseCurrency
+	var converter *CurrencyConverter
+	if params.Currency != "" && params.Currency != models.BaseCurrency {
+		if s.postgresDB == nil {
+			return "", fmt.Errorf("currency conversion is not available")
+		}
+		converter, err = NewCurrencyService(s.postgresDB).Converter(ctx, uint(tenantID), params.Currency, startTime, endTime)
+		if err != nil {
+			return "", err
+		}
+		currency = converter.Currency
+	}
 
+	// Build the allocation set for each step
 	for _, step := range steps {
-		// Query allocations for this time step
-		allocations, err := s.queryAllocations(ctx, tenantID, step.Start, step.End, params)
+		// Load node capacity and instance types (for pricing whole-node costs) and node cost
+		// adjustments (reconciled billing data, commitments) for this time step
+		nodes, err := s.loadNodeInventory(ctx, tenantID, step.Start, step.End)
 		if err != nil {
-			return nil, err
+			return "", err
+		}
+		params.nodes = make(map[string]nodeInventory, len(nodes))
+		for _, n := range nodes {
+			params.nodes[nodeKey(n.Cluster, n.Node)] = n
+		}
+		params.nodeCosts = nil
+		if params.Reconcile || params.ApplyCommitments {
+			params.nodeCosts, err = s.loadNodeCostAdjustments(ctx, tenantID, step.Start, step.End, nodes, params)
+			if err != nil {
+				return "", err
+			}
+		}
+
+		// Query per-cluster/node allocation fragments for this time step
+		fragments, err := s.queryAllocationFragments(ctx, tenantID, step.Start, step.End, params)
+		if err != nil {
+			return "", err
 		}
 
 		// Calculate idle costs if requested
 		var idleCost float64
+		var idleAllocations []*Allocation
 		if params.Idle {
-			idleCost, _ = s.calculateIdleCost(ctx, tenantID, step.Start, step.End)
-
-			// Distribute idle costs if shareIdle is set
-			if params.ShareIdle == "true" || params.ShareIdle == "weighted" {
-				s.distributeIdleCost(allocations, idleCost, params.ShareIdle)
-				idleCost = 0 // Idle cost is now distributed
-			} else if params.Idle {
-				// Add __idle__ allocation
-				allocations["__idle__"] = &Allocation{
-					Name:      "__idle__",
-					Window:    TimeWindow{Start: step.Start, End: step.End},
-					Start:     step.Start,
-					End:       step.End,
-					Minutes:   step.End.Sub(step.Start).Minutes(),
-					TotalCost: idleCost,
-				}
+			idleCosts, err := s.calculateIdleCosts(ctx, tenantID, step.Start, step.End, params)
+			if err != nil {
+				return "", err
+			}
+			// Distribute idle costs if shareIdle is set; idle in scopes without
+			// any allocations cannot be shared and is still reported as idle
+			if method := normalizeShareIdle(params.ShareIdle); method != "" {
+				idleCosts = s.distributeIdleCost(fragments, idleCosts, method)
+			}
+			for _, idle := range idleCosts {
+				idleCost += idle.TotalCost()
+				idleAllocations = append(idleAllocations, idle.toAllocation(step.Start, step.End))
+			}
+		}
+
+		// Spread shared overhead costs (including their idle share) over the other allocations
+		fragments = s.distributeSharedCost(fragments, params.sharingRules)
+
+		allocations := s.mergeFragments(fragments)
+		for _, idleAlloc := range idleAllocations {
+			allocations[idleAlloc.Name] = idleAlloc
+		}
+
+		// Merge external costs into the allocations matching their tags
+		var externalCost float64
+		if params.IncludeExternal {
+			externalCost, err = s.mergeExternalCosts(ctx, tenantID, allocations, step.Start, step.End, params)
+			if err != nil {
+				return "", err
 			}
 		}
 
 		// Calculate total cost
-		var totalCost float64
+		var totalCost, listCost float64
 		for _, alloc := range allocations {
 			totalCost += alloc.TotalCost
+			listCost += alloc.ListCost
 		}
 
-		allocationSets = append(allocationSets, AllocationSet{
-			Allocations: allocations,
-			Window:      TimeWindow{Start: step.Start, End: step.End},
-			TotalCost:   totalCost,
-			IdleCost:    idleCost,
-		})
-	}
-
-	// If accumulate=true, merge all sets into one
-	if params.Accumulate == "true" && len(allocationSets) > 1 {
-		allocationSets = []AllocationSet{s.mergeAllocationSets(allocationSets, startTime, endTime)}
+		set := AllocationSet{
+			Allocations:  allocations,
+			Window:       TimeWindow{Start: step.Start, End: step.End},
+			TotalCost:    totalCost,
+			ListCost:     listCost,
+			IdleCost:     idleCost,
+			ExternalCost: externalCost,
+		}
+		if converter != nil {
+			set.convert(converter.RateAt(step.Start))
+		}
+		if err := fn(set, currency); err != nil {
+			return "", err
+		}
 	}
-
-	// Apply pagination
-	allocationSets = s.paginateResults(allocationSets, params.Offset, params.Limit)
-
-	return &AllocationResponse{
-		Code:   200,
-		Status: "success",
-		Data:   allocationSets,
-	}, nil
+	return currency, nil
 }
 
 // parseWindow parses various window formats into start/end times
@@ -401,26 +597,68 @@ func (s *AllocationService) parseStepDuration(step string) time.Duration {
 	return 0
 }
 
-// queryAllocations executes the allocation query based on aggregation type
-func (s *AllocationService) queryAllocations(ctx context.Context, tenantID int64, startTime, endTime time.Time, params AllocationParams) (map[string]*Allocation, error) {
+// queryAllocationFragments executes the allocation query based on aggregation type.
+// It returns one fragment per allocation name, cluster, namespace and node so that
+// idle and shared costs can be attributed within a scope before fragments are merged.
+func (s *AllocationService) queryAllocationFragments(ctx context.Context, tenantID int64, startTime, endTime time.Time, params AllocationParams) ([]*Allocation, error) {
 	// Parse aggregate parameter (supports comma-separated multi-aggregation)
 	aggregates := strings.Split(params.Aggregate, ",")
 	if len(aggregates) == 0 || params.Aggregate == "" {
 		aggregates = []string{"namespace"}
 	}
 
-	// Build grouping columns
+	// Build grouping columns; placeholders after tenant, start and end are numbered in the
+	// order their expressions are built
 	var groupByCols, selectCols []string
-	var labelKeys []string
+	var labelConds []string // pods without a value for an aggregated label are left out
+	containerCol := "''" // reported as the allocation's container when aggregating by container
+	var aggArgs []interface{}
+	argIdx := 4
+
+	// Virtual label expressions are built once per key and reused by the existence and
+	// value filters
+	virtualLabels := make(map[string]string)
+	virtualLabel := func(key string, args *[]interface{}) (string, bool) {
+		key = strings.ToLower(key)
+		if expr, ok := virtualLabels[key]; ok {
+			return expr, true
+		}
+		rule, ok := params.virtualLabels[key]
+		if !ok {
+			return "", false
+		}
+		expr, exprArgs := virtualLabelExpr(rule, argIdx)
+		*args = append(*args, exprArgs...)
+		argIdx += len(exprArgs)
+		virtualLabels[key] = expr
+		return expr, true
+	}
 
 	for _, agg := range aggregates {
 		agg = strings.TrimSpace(strings.ToLower(agg))
 
 		if strings.HasPrefix(agg, "label:") {
 			labelKey := strings.TrimPrefix(agg, "label:")
-			labelKeys = append(labelKeys, labelKey)
-			selectCols = append(selectCols, fmt.Sprintf("COALESCE(labels->>'%s', '__unallocated__')", labelKey))
-			groupByCols = append(groupByCols, fmt.Sprintf("labels->>'%s'", labelKey))
+			expr, ok := virtualLabel(labelKey, &aggArgs)
+			if ok {
+				labelConds = append(labelConds, expr+" IS NOT NULL")
+			} else {
+				expr = fmt.Sprintf("labels->>$%d::text", argIdx)
+				labelConds = append(labelConds, fmt.Sprintf("labels ? $%d::text", argIdx))
+				aggArgs = append(aggArgs, labelKey)
+				argIdx++
+			}
+			selectCols = append(selectCols, fmt.Sprintf("COALESCE(%s, '__unallocated__')", expr))
+			groupByCols = append(groupByCols, expr)
+		} else if isCostCenterAggregate(agg) && params.costCenters != nil {
+			// costCenter or costCenter:<level>: the cost center the first matching mapping rule
+			// reports under at the level, __unallocated__ when no rule matches
+			_, level, _ := strings.Cut(agg, ":")
+			expr, exprArgs := costCenterExpr(params.costCenters, level, argIdx)
+			aggArgs = append(aggArgs, exprArgs...)
+			argIdx += len(exprArgs)
+			selectCols = append(selectCols, expr)
+			groupByCols = append(groupByCols, expr)
 		} else {
 			switch agg {
 			case "cluster":
@@ -435,10 +673,14 @@ func (s *AllocationService) queryAllocations(ctx context.Context, tenantID int64
 			case "pod":
 				selectCols = append(selectCols, "CONCAT(namespace, '/', pod_name)")
 				groupByCols = append(groupByCols, "namespace", "pod_name")
+			case "container":
+				containerCol = containerAggregateExpr
+				selectCols = append(selectCols, containerAggregateExpr)
+				groupByCols = append(groupByCols, containerAggregateExpr)
 			case "controller":
 				// Extract controller from pod name (remove hash suffix)
-				selectCols = append(selectCols, "REGEXP_REPLACE(pod_name, '-[a-z0-9]{5,10}(-[a-z0-9]{5})?$', '')")
-				groupByCols = append(groupByCols, "REGEXP_REPLACE(pod_name, '-[a-z0-9]{5,10}(-[a-z0-9]{5})?$', '')")
+				selectCols = append(selectCols, controllerNameExpr)
+				groupByCols = append(groupByCols, controllerNameExpr)
 			default:
 				selectCols = append(selectCols, "namespace")
 				groupByCols = append(groupByCols, "namespace")
@@ -454,95 +696,63 @@ func (s *AllocationService) queryAllocations(ctx context.Context, tenantID int64
 		nameExpr = fmt.Sprintf("CONCAT(%s)", strings.Join(selectCols, ", '/', "))
 	}
 
+	// Tag rows matching a sharing rule so their cost can be spread afterwards
+	sharedExpr, sharedArgs := sharingRuleExpr(params.sharingRules, argIdx)
+
+	// Container aggregations and filters expand each pod row into its containers and cost
+	// the containers' own requests and usage
+	from, metrics := "pod_metrics", podMetricColumns
+	if containerCol == containerAggregateExpr || filterUsesField(params.filter, "container") {
+		from, metrics = podMetricsByContainer, containerMetricColumns
+	}
+
 	// Build query
 	query := fmt.Sprintf(`
 		SELECT
 			%s as name,
+			%s as shared_rule,
+			%s as container_name,
 			cluster_name,
 			namespace,
 			node_name,
-			AVG(cpu_millicores) / 1000.0 as cpu_cores_usage,
-			AVG(cpu_request_millicores) / 1000.0 as cpu_cores_request,
-			AVG(memory_bytes) as memory_bytes_usage,
-			AVG(memory_request_bytes) as memory_bytes_request,
+			AVG(%s) / 1000.0 as cpu_cores_usage,
+			AVG(%s) / 1000.0 as cpu_cores_request,
+			AVG(%s) as memory_bytes_usage,
+			AVG(%s) as memory_bytes_request,
 			COUNT(DISTINCT pod_name) as pod_count
-		FROM pod_metrics
+		FROM %s
 		WHERE tenant_id = $1
 			AND time >= $2
 			AND time <= $3
 			AND pod_name != '__aggregate__'
-	`, nameExpr)
+	`, nameExpr, sharedExpr, containerCol, metrics[0], metrics[1], metrics[2], metrics[3], from)
 
-	args := []interface{}{tenantID, startTime, endTime}
-	argIdx := 4
+	args := append([]interface{}{tenantID, startTime, endTime}, aggArgs...)
+	args = append(args, sharedArgs...)
+	argIdx += len(sharedArgs)
 
-	// Add label existence filters for label aggregations
-	for _, labelKey := range labelKeys {
-		query += fmt.Sprintf(" AND labels ? '%s'", labelKey)
+	for _, cond := range labelConds {
+		query += " AND " + cond
 	}
 
 	// Add filters
-	for _, filter := range params.Filters {
-		// Parse filter: "namespace:value", "cluster:value", "label:key=value"
-		parts := strings.SplitN(filter, ":", 2)
-		if len(parts) != 2 {
-			continue
-		}
-		filterType := strings.ToLower(parts[0])
-		filterValue := parts[1]
-
-		switch filterType {
-		case "namespace":
-			values := strings.Split(filterValue, ",")
-			if len(values) == 1 {
-				query += fmt.Sprintf(" AND namespace = $%d", argIdx)
-				args = append(args, filterValue)
-				argIdx++
-			} else {
-				placeholders := make([]string, len(values))
-				for i, v := range values {
-					placeholders[i] = fmt.Sprintf("$%d", argIdx)
-					args = append(args, strings.TrimSpace(v))
-					argIdx++
-				}
-				query += fmt.Sprintf(" AND namespace IN (%s)", strings.Join(placeholders, ","))
+	if params.filter != nil {
+		column := func(field, key string) string {
+			if field != "label" {
+				return filterColumns[field]
 			}
-		case "cluster":
-			values := strings.Split(filterValue, ",")
-			if len(values) == 1 {
-				query += fmt.Sprintf(" AND cluster_name = $%d", argIdx)
-				args = append(args, filterValue)
-				argIdx++
-			} else {
-				placeholders := make([]string, len(values))
-				for i, v := range values {
-					placeholders[i] = fmt.Sprintf("$%d", argIdx)
-					args = append(args, strings.TrimSpace(v))
-					argIdx++
-				}
-				query += fmt.Sprintf(" AND cluster_name IN (%s)", strings.Join(placeholders, ","))
+			if expr, ok := virtualLabel(key, &args); ok {
+				return expr
 			}
-		case "node":
-			query += fmt.Sprintf(" AND node_name = $%d", argIdx)
-			args = append(args, filterValue)
-			argIdx++
-		case "label":
-			// label filter format: "label:key=value"
-			labelParts := strings.SplitN(filterValue, "=", 2)
-			if len(labelParts) == 2 {
-				query += fmt.Sprintf(" AND labels->>'%s' = $%d", labelParts[0], argIdx)
-				args = append(args, labelParts[1])
-				argIdx++
-			}
-		case "pod":
-			query += fmt.Sprintf(" AND pod_name LIKE $%d", argIdx)
-			args = append(args, "%"+filterValue+"%")
+			args = append(args, key)
 			argIdx++
+			return fmt.Sprintf("labels->>$%d::text", argIdx-1)
 		}
+		query += " AND " + compileFilter(params.filter, column, &args, &argIdx)
 	}
 
 	query += fmt.Sprintf(`
-		GROUP BY %s, cluster_name, namespace, node_name
+		GROUP BY %s, shared_rule, container_name, cluster_name, namespace, node_name
 		ORDER BY cpu_cores_usage DESC
 	`, strings.Join(groupByCols, ", "))
 
@@ -552,19 +762,20 @@ func (s *AllocationService) queryAllocations(ctx context.Context, tenantID int64
 	}
 	defer rows.Close()
 
-	results := make(map[string]*Allocation)
+	var fragments []*Allocation
 	durationHours := endTime.Sub(startTime).Hours()
 	if durationHours <= 0 {
 		durationHours = 1
 	}
 	minutes := endTime.Sub(startTime).Minutes()
+	pricing := s.newClusterPricing(ctx, tenantID, startTime)
 
 	for rows.Next() {
-		var name, clusterName, namespace, nodeName string
+		var name, sharedRule, containerName, clusterName, namespace, nodeName string
 		var cpuCoresUsage, cpuCoresRequest, memBytesUsage, memBytesRequest float64
 		var podCount int
 
-		if err := rows.Scan(&name, &clusterName, &namespace, &nodeName,
+		if err := rows.Scan(&name, &sharedRule, &containerName, &clusterName, &namespace, &nodeName,
 			&cpuCoresUsage, &cpuCoresRequest, &memBytesUsage, &memBytesRequest, &podCount); err != nil {
 			return nil, fmt.Errorf("scan failed: %w", err)
 		}
@@ -587,11 +798,20 @@ func (s *AllocationService) queryAllocations(ctx context.Context, tenantID int64
 		ramByteHours := effectiveRAM * durationHours
 
 		// Get pricing rates (dynamic or default)
-		cpuRate, memRate, _ := s.getNodePricing(ctx, tenantID, clusterName, nodeName, startTime)
+		node := params.node(clusterName, nodeName)
+		cpuRate, memRate, _ := pricing.nodeRates(node)
+		if adj, ok := params.nodeCosts[nodeKey(clusterName, nodeName)]; ok && adj.RateFactor > 0 {
+			// Scale modeled rates to the node's reconciled (billed) or commitment-discounted cost
+			cpuRate *= adj.RateFactor
+			memRate *= adj.RateFactor
+		}
 		cpuCost := cpuCoreHours * cpuRate
 		ramCost := (ramByteHours / 1024 / 1024 / 1024) * memRate
 		totalCost := cpuCost + ramCost
 
+		listCPURate, listMemRate := pricing.nodeListRates(node)
+		listCost := cpuCoreHours*listCPURate + (ramByteHours/1024/1024/1024)*listMemRate
+
 		// Calculate efficiencies
 		var cpuEfficiency, ramEfficiency float64
 		if cpuCoresRequest > 0 {
@@ -625,17 +845,29 @@ func (s *AllocationService) queryAllocations(ctx context.Context, tenantID int64
 
 			TotalCost:       totalCost,
 			TotalEfficiency: totalEfficiency,
+			ListCost:        listCost,
 			PodCount:        podCount,
 
 			Properties: AllocationProps{
 				Cluster:   clusterName,
 				Namespace: namespace,
 				Node:      nodeName,
+				Container: containerName,
 			},
+			sharingRule: sharedRule,
 		}
 
-		// Merge if same name exists (aggregate across clusters/nodes)
-		if existing, ok := results[name]; ok {
+		fragments = append(fragments, alloc)
+	}
+
+	return fragments, rows.Err()
+}
+
+// mergeFragments merges allocation fragments with the same name (aggregate across clusters/nodes)
+func (s *AllocationService) mergeFragments(fragments []*Allocation) map[string]*Allocation {
+	results := make(map[string]*Allocation)
+	for _, alloc := range fragments {
+		if existing, ok := results[alloc.Name]; ok {
 			existing.CPUCores += alloc.CPUCores
 			existing.CPUCoreHours += alloc.CPUCoreHours
 			existing.CPUCost += alloc.CPUCost
@@ -643,90 +875,17 @@ func (s *AllocationService) queryAllocations(ctx context.Context, tenantID int64
 			existing.RAMByteHours += alloc.RAMByteHours
 			existing.RAMCost += alloc.RAMCost
 			existing.TotalCost += alloc.TotalCost
+			existing.SharedIdleCost += alloc.SharedIdleCost
+			existing.SharedCost += alloc.SharedCost
+			existing.ExternalCost += alloc.ExternalCost
+			existing.ListCost += alloc.ListCost
 			existing.PodCount += alloc.PodCount
 		} else {
-			results[name] = alloc
-		}
-	}
-
-	return results, rows.Err()
-}
-
-// calculateIdleCost calculates the cost of unused cluster capacity
-func (s *AllocationService) calculateIdleCost(ctx context.Context, tenantID int64, startTime, endTime time.Time) (float64, error) {
-	query := `
-		WITH node_capacity AS (
-			SELECT
-				cluster_name,
-				AVG(cpu_capacity) as avg_cpu_capacity,
-				AVG(memory_capacity) as avg_memory_capacity,
-				AVG(hourly_cost_usd) as avg_hourly_cost
-			FROM node_metrics
-			WHERE tenant_id = $1 AND time >= $2 AND time <= $3
-			GROUP BY cluster_name
-		),
-		pod_usage AS (
-			SELECT
-				cluster_name,
-				SUM(cpu_millicores) / 1000.0 as total_cpu_used,
-				SUM(memory_bytes) as total_memory_used
-			FROM pod_metrics
-			WHERE tenant_id = $1 AND time >= $2 AND time <= $3
-				AND pod_name != '__aggregate__'
-			GROUP BY cluster_name
-		)
-		SELECT
-			COALESCE(SUM(
-				CASE
-					WHEN nc.avg_cpu_capacity > 0
-					THEN (1 - LEAST(COALESCE(pu.total_cpu_used, 0) / nc.avg_cpu_capacity, 1)) * nc.avg_hourly_cost
-					ELSE 0
-				END
-			), 0) as idle_cost_per_hour
-		FROM node_capacity nc
-		LEFT JOIN pod_usage pu ON nc.cluster_name = pu.cluster_name
-	`
-
-	var idleCostPerHour float64
-	err := s.pool.QueryRow(ctx, query, tenantID, startTime, endTime).Scan(&idleCostPerHour)
-	if err != nil {
-		return 0, err
-	}
-
-	duration := endTime.Sub(startTime).Hours()
-	if duration <= 0 {
-		duration = 1
-	}
-
-	return idleCostPerHour * duration, nil
-}
-
-// distributeIdleCost distributes idle costs across allocations
-func (s *AllocationService) distributeIdleCost(allocations map[string]*Allocation, idleCost float64, method string) {
-	if idleCost <= 0 || len(allocations) == 0 {
-		return
-	}
-
-	if method == "weighted" {
-		// Distribute proportionally based on total cost
-		var totalCost float64
-		for _, alloc := range allocations {
-			totalCost += alloc.TotalCost
-		}
-		if totalCost <= 0 {
-			return
-		}
-		for _, alloc := range allocations {
-			share := (alloc.TotalCost / totalCost) * idleCost
-			alloc.TotalCost += share
-		}
-	} else {
-		// Distribute evenly
-		share := idleCost / float64(len(allocations))
-		for _, alloc := range allocations {
-			alloc.TotalCost += share
+			allocCopy := *alloc
+			results[alloc.Name] = &allocCopy
 		}
 	}
+	return results
 }
 
 // mergeAllocationSets merges multiple allocation sets into one
@@ -738,6 +897,7 @@ func (s *AllocationService) mergeAllocationSets(sets []AllocationSet, start, end
 
 	for _, set := range sets {
 		merged.IdleCost += set.IdleCost
+		merged.ExternalCost += set.ExternalCost
 		for name, alloc := range set.Allocations {
 			if existing, ok := merged.Allocations[name]; ok {
 				existing.CPUCores += alloc.CPUCores
@@ -747,6 +907,10 @@ func (s *AllocationService) mergeAllocationSets(sets []AllocationSet, start, end
 				existing.RAMByteHours += alloc.RAMByteHours
 				existing.RAMCost += alloc.RAMCost
 				existing.TotalCost += alloc.TotalCost
+				existing.SharedIdleCost += alloc.SharedIdleCost
+				existing.SharedCost += alloc.SharedCost
+				existing.ExternalCost += alloc.ExternalCost
+				existing.ListCost += alloc.ListCost
 				existing.PodCount += alloc.PodCount
 				existing.Minutes += alloc.Minutes
 			} else {
@@ -762,6 +926,7 @@ func (s *AllocationService) mergeAllocationSets(sets []AllocationSet, start, end
 	// Recalculate total cost
 	for _, alloc := range merged.Allocations {
 		merged.TotalCost += alloc.TotalCost
+		merged.ListCost += alloc.ListCost
 	}
 
 	return merged
@@ -0,0 +1,215 @@
+package services
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"github.com/bugfreev587/k8s-cost-api-server/internal/models"
+)
+
+// SharingRule identifies overhead workloads whose cost is spread over all other allocations
+type SharingRule struct {
+	Name       string
+	Namespaces []string
+	Labels     []string // "key=value" or "key"
+	Split      string   // "even" or "weighted"
+}
+
+// resolveSharingRules returns the sharing rules for a query: rules given explicitly in the
+// parameters take precedence, otherwise the tenant's stored rules apply when requested
+func (s *AllocationService) resolveSharingRules(ctx context.Context, tenantID int64, params AllocationParams) ([]SharingRule, error) {
+	if len(params.ShareNamespaces) > 0 || len(params.ShareLabels) > 0 {
+		rule := SharingRule{
+			Name:       "request",
+			Namespaces: params.ShareNamespaces,
+			Labels:     params.ShareLabels,
+			Split:      params.ShareSplit,
+		}
+		if err := validateSharingRule(&rule); err != nil {
+			return nil, err
+		}
+		return []SharingRule{rule}, nil
+	}
+
+	if !params.ApplySharingRules || s.postgresDB == nil {
+		return nil, nil
+	}
+
+	var stored []models.SharedCostRule
+	if err := s.postgresDB.WithContext(ctx).
+		Where("tenant_id = ? AND enabled = true", tenantID).
+		Order("id ASC").
+		Find(&stored).Error; err != nil {
+		return nil, fmt.Errorf("failed to load sharing rules: %w", err)
+	}
+
+	rules := make([]SharingRule, 0, len(stored))
+	for _, r := range stored {
+		rule := SharingRule{
+			Name:       r.Name,
+			Namespaces: r.Namespaces,
+			Labels:     r.Labels,
+			Split:      r.Split,
+		}
+		if err := validateSharingRule(&rule); err != nil {
+			return nil, fmt.Errorf("sharing rule %q: %w", r.Name, err)
+		}
+		rules = append(rules, rule)
+	}
+	return rules, nil
+}
+
+// validateSharingRule checks a rule's split mode and label selectors, defaulting the split to weighted
+func validateSharingRule(rule *SharingRule) error {
+	switch rule.Split {
+	case "":
+		rule.Split = models.ShareSplitWeighted
+	case models.ShareSplitEven, models.ShareSplitWeighted:
+	default:
+		return fmt.Errorf("invalid shareSplit: %s", rule.Split)
+	}
+	for _, selector := range rule.Labels {
+		if key, _, _ := strings.Cut(selector, "="); strings.TrimSpace(key) == "" {
+			return fmt.Errorf("invalid share label selector: %q", selector)
+		}
+	}
+	return nil
+}
+
+// sharingRuleExpr builds a SQL expression that evaluates to the name of the first sharing
+// rule matching a pod_metrics row (” when none match). Placeholders start at argIdx.
+func sharingRuleExpr(rules []SharingRule, argIdx int) (string, []interface{}) {
+	if len(rules) == 0 {
+		return "''", nil
+	}
+
+	var args []interface{}
+	var whens []string
+	for _, rule := range rules {
+		var conds []string
+		if len(rule.Namespaces) > 0 {
+			conds = append(conds, fmt.Sprintf("namespace = ANY($%d)", argIdx))
+			args = append(args, rule.Namespaces)
+			argIdx++
+		}
+		for _, selector := range rule.Labels {
+			key, value, hasValue := strings.Cut(selector, "=")
+			key = strings.TrimSpace(key)
+			if hasValue {
+				match, _ := json.Marshal(map[string]string{key: strings.TrimSpace(value)})
+				conds = append(conds, fmt.Sprintf("labels @> $%d::jsonb", argIdx))
+				args = append(args, string(match))
+			} else {
+				conds = append(conds, fmt.Sprintf("labels ? $%d", argIdx))
+				args = append(args, key)
+			}
+			argIdx++
+		}
+		if len(conds) == 0 {
+			continue
+		}
+		whens = append(whens, fmt.Sprintf("WHEN %s THEN $%d::text", strings.Join(conds, " OR "), argIdx))
+		args = append(args, rule.Name)
+		argIdx++
+	}
+
+	if len(whens) == 0 {
+		return "''", nil
+	}
+	return fmt.Sprintf("CASE %s ELSE '' END", strings.Join(whens, " ")), args
+}
+
+// distributeSharedCost removes fragments matched by a sharing rule and spreads their total
+// cost over the remaining fragments, either evenly per allocation or weighted by each
+// allocation's own cost. Idle already shared onto the removed fragments moves on as
+// SharedIdleCost, the rest is added to SharedCost; both are added to TotalCost. If nothing
+// remains to share onto, the shared fragments are kept as they are.
+func (s *AllocationService) distributeSharedCost(fragments []*Allocation, rules []SharingRule) []*Allocation {
+	if len(rules) == 0 {
+		return fragments
+	}
+
+	var recipients []*Allocation
+	sharedByRule := make(map[string][]*Allocation)
+	for _, alloc := range fragments {
+		if alloc.sharingRule == "" {
+			recipients = append(recipients, alloc)
+		} else {
+			sharedByRule[alloc.sharingRule] = append(sharedByRule[alloc.sharingRule], alloc)
+		}
+	}
+	if len(recipients) == 0 || len(sharedByRule) == 0 {
+		return fragments
+	}
+
+	for _, rule := range rules {
+		var sharedCost, sharedIdleCost, sharedListCost float64
+		for _, alloc := range sharedByRule[rule.Name] {
+			sharedCost += alloc.TotalCost
+			sharedIdleCost += alloc.SharedIdleCost
+			sharedListCost += alloc.ListCost
+		}
+		if sharedCost == 0 {
+			continue
+		}
+
+		var weights []float64
+		if rule.Split == models.ShareSplitEven {
+			weights = evenWeights(recipients)
+		} else {
+			weights = fragmentWeights(recipients, func(a *Allocation) float64 { return a.TotalCost - a.SharedCost })
+		}
+		for i, alloc := range recipients {
+			alloc.SharedIdleCost += sharedIdleCost * weights[i]
+			alloc.SharedCost += (sharedCost - sharedIdleCost) * weights[i]
+			alloc.TotalCost += sharedCost * weights[i]
+			alloc.ListCost += sharedListCost * weights[i]
+		}
+	}
+
+	return recipients
+}
+
+// ListSharingRules lists a tenant's shared cost rules
+func (s *AllocationService) ListSharingRules(ctx context.Context, tenantID uint) ([]models.SharedCostRule, error) {
+	var rules []models.SharedCostRule
+	err := s.postgresDB.WithContext(ctx).
+		Where("tenant_id = ?", tenantID).
+		Order("id ASC").
+		Find(&rules).Error
+	return rules, err
+}
+
+// GetSharingRule retrieves a shared cost rule
+func (s *AllocationService) GetSharingRule(ctx context.Context, ruleID uint) (*models.SharedCostRule, error) {
+	var rule models.SharedCostRule
+	if err := s.postgresDB.WithContext(ctx).First(&rule, ruleID).Error; err != nil {
+		return nil, err
+	}
+	return &rule, nil
+}
+
+// ValidateSharedCostRule checks a stored rule before it is saved, defaulting its split to weighted
+func ValidateSharedCostRule(rule *models.SharedCostRule) error {
+	if len(rule.Namespaces) == 0 && len(rule.Labels) == 0 {
+		return fmt.Errorf("sharing rule needs at least one namespace or label selector")
+	}
+	check := SharingRule{Name: rule.Name, Namespaces: rule.Namespaces, Labels: rule.Labels, Split: rule.Split}
+	if err := validateSharingRule(&check); err != nil {
+		return err
+	}
+	rule.Split = check.Split
+	return nil
+}
+
+// SaveSharingRule creates or updates a shared cost rule
+func (s *AllocationService) SaveSharingRule(ctx context.Context, rule *models.SharedCostRule) error {
+	return s.postgresDB.WithContext(ctx).Save(rule).Error
+}
+
+// DeleteSharingRule deletes a shared cost rule
+func (s *AllocationService) DeleteSharingRule(ctx context.Context, ruleID uint) error {
+	return s.postgresDB.WithContext(ctx).Delete(&models.SharedCostRule{}, ruleID).Error
+}
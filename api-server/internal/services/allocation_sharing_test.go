@@ -0,0 +1,117 @@
+package services
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func sharedFragment(name, rule string, cpuCost, ramCost float64) *Allocation {
+	alloc := testFragment(name, "prod", "n1", cpuCost, ramCost)
+	alloc.sharingRule = rule
+	return alloc
+}
+
+func totals(fragments []*Allocation) (total, sharedIdle float64) {
+	for _, f := range fragments {
+		total += f.TotalCost
+		sharedIdle += f.SharedIdleCost
+	}
+	return total, sharedIdle
+}
+
+func TestDistributeSharedCost(t *testing.T) {
+	s := &AllocationService{}
+	rule := SharingRule{Name: "overhead", Namespaces: []string{"kube-system"}}
+
+	tests := []struct {
+		name  string
+		split string
+		want  map[string]float64 // shared cost per recipient
+	}{
+		// web costs 3x batch, so it takes 3/4 of the overhead
+		{name: "weighted", split: "weighted", want: map[string]float64{"web": 4.5, "batch": 1.5}},
+		{name: "even", split: "even", want: map[string]float64{"web": 3, "batch": 3}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			fragments := []*Allocation{
+				testFragment("web", "prod", "n1", 4, 2),
+				testFragment("batch", "prod", "n1", 1, 1),
+				sharedFragment("kube-system", rule.Name, 3, 3),
+			}
+			rule := rule
+			rule.Split = tt.split
+
+			result := s.distributeSharedCost(fragments, []SharingRule{rule})
+
+			if assert.Len(t, result, 2) {
+				for _, alloc := range result {
+					assert.InDelta(t, tt.want[alloc.Name], alloc.SharedCost, 1e-9, alloc.Name)
+				}
+			}
+			total, _ := totals(result)
+			assert.InDelta(t, 14, total, 1e-9)
+		})
+	}
+}
+
+func TestDistributeSharedCost_CarriesSharedIdle(t *testing.T) {
+	s := &AllocationService{}
+	fragments := []*Allocation{
+		testFragment("web", "prod", "n1", 1, 1),
+		testFragment("batch", "prod", "n1", 1, 1),
+		sharedFragment("kube-system", "overhead", 1, 1),
+	}
+	s.distributeIdleCost(fragments, []*IdleCost{{CPUCost: 3, RAMCost: 3}}, ShareIdleEven)
+	beforeTotal, beforeIdle := totals(fragments)
+
+	result := s.distributeSharedCost(fragments, []SharingRule{{Name: "overhead", Split: "even"}})
+
+	afterTotal, afterIdle := totals(result)
+	assert.InDelta(t, beforeTotal, afterTotal, 1e-9)
+	assert.InDelta(t, beforeIdle, afterIdle, 1e-9)
+	for _, alloc := range result {
+		// each recipient: 2 idle of its own, half of kube-system's 2 idle and 2 own cost
+		assert.InDelta(t, 3, alloc.SharedIdleCost, 1e-9)
+		assert.InDelta(t, 1, alloc.SharedCost, 1e-9)
+		assert.InDelta(t, 6, alloc.TotalCost, 1e-9)
+	}
+}
+
+func TestDistributeSharedCost_NoTargets(t *testing.T) {
+	s := &AllocationService{}
+	fragments := []*Allocation{
+		sharedFragment("kube-system", "overhead", 1, 1),
+		sharedFragment("monitoring", "overhead", 2, 2),
+	}
+
+	result := s.distributeSharedCost(fragments, []SharingRule{{Name: "overhead", Split: "weighted"}})
+
+	assert.Equal(t, fragments, result)
+	total, _ := totals(result)
+	assert.InDelta(t, 6, total, 1e-9)
+	assert.Zero(t, result[0].SharedCost)
+}
+
+func TestSharingRuleExpr(t *testing.T) {
+	expr, args := sharingRuleExpr([]SharingRule{
+		{Name: "system", Namespaces: []string{"kube-system", "monitoring"}, Labels: []string{"tier=infra", "overhead"}},
+		{Name: "empty"},
+		{Name: "mesh", Labels: []string{"app = istio"}},
+	}, 4)
+
+	// Rules without selectors are skipped; each rule's name is the last parameter it binds
+	assert.Equal(t, 2, strings.Count(expr, "WHEN "))
+	assert.Contains(t, expr, "THEN $7::text")
+	assert.Contains(t, expr, "THEN $9::text")
+	assert.Equal(t, []interface{}{
+		[]string{"kube-system", "monitoring"}, `{"tier":"infra"}`, "overhead", "system",
+		`{"app":"istio"}`, "mesh",
+	}, args)
+
+	expr, args = sharingRuleExpr(nil, 4)
+	assert.Equal(t, "''", expr)
+	assert.Empty(t, args)
+}
@@ -0,0 +1,116 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"sort"
+
+	"github.com/bugfreev587/k8s-cost-api-server/internal/models"
+)
+
+// WhatIfItem is the cost of one allocation under the current and the proposed pricing
+type WhatIfItem struct {
+	Name         string          `json:"name"`
+	Properties   AllocationProps `json:"properties"`
+	BaselineCost float64         `json:"baselineCost"`
+	ProposedCost float64         `json:"proposedCost"`
+	Delta        float64         `json:"delta"`
+	DeltaPercent float64         `json:"deltaPercent"` // 0 when the baseline cost is 0
+}
+
+// WhatIfResult compares a window's allocations under the current and a proposed pricing config
+type WhatIfResult struct {
+	Window        TimeWindow   `json:"window"`
+	Aggregate     string       `json:"aggregate"`
+	Currency      string       `json:"currency,omitempty"`
+	Clusters      []string     `json:"clusters,omitempty"`
+	BaselineTotal float64      `json:"baselineTotal"`
+	ProposedTotal float64      `json:"proposedTotal"`
+	Delta         float64      `json:"delta"`
+	DeltaPercent  float64      `json:"deltaPercent"`
+	Items         []WhatIfItem `json:"items"`
+}
+
+// WhatIf recomputes a window's allocations with a proposed pricing config in place of the
+// assigned config of the given clusters (all clusters when empty) and returns the cost
+// difference per allocation, largest changes first. The baseline uses the current
+// pricing, or the pricing as of params.PricingAsOf.
+func (s *AllocationService) WhatIf(ctx context.Context, tenantID int64, params AllocationParams, proposed *models.PricingConfig, clusters []string) (*WhatIfResult, error) {
+	if s.pricingSvc == nil {
+		return nil, fmt.Errorf("what-if repricing requires pricing configuration")
+	}
+	params.Accumulate = "true"
+	params.Step = ""
+	params.Offset = 0
+	params.Limit = math.MaxInt32 // compare every allocation
+
+	baseline, err := s.GetAllocations(ctx, tenantID, params)
+	if err != nil {
+		return nil, err
+	}
+
+	repriced := *s
+	repriced.pricingSvc = s.pricingSvc.WithProposedConfig(proposed, clusters)
+	proposedResp, err := repriced.GetAllocations(ctx, tenantID, params)
+	if err != nil {
+		return nil, err
+	}
+
+	result := &WhatIfResult{
+		Aggregate: params.Aggregate,
+		Currency:  baseline.Currency,
+		Clusters:  clusters,
+	}
+	items := make(map[string]*WhatIfItem)
+	var order []string
+	add := func(set []AllocationSet, proposed bool) {
+		if len(set) == 0 {
+			return
+		}
+		result.Window = set[0].Window
+		for name, alloc := range set[0].Allocations {
+			item, ok := items[name]
+			if !ok {
+				item = &WhatIfItem{Name: name, Properties: alloc.Properties}
+				items[name] = item
+				order = append(order, name)
+			}
+			if proposed {
+				item.ProposedCost += alloc.TotalCost
+			} else {
+				item.BaselineCost += alloc.TotalCost
+			}
+		}
+	}
+	add(baseline.Data, false)
+	add(proposedResp.Data, true)
+
+	for _, name := range order {
+		item := items[name]
+		item.Delta = item.ProposedCost - item.BaselineCost
+		item.DeltaPercent = percentChange(item.BaselineCost, item.ProposedCost)
+		result.BaselineTotal += item.BaselineCost
+		result.ProposedTotal += item.ProposedCost
+		result.Items = append(result.Items, *item)
+	}
+	result.Delta = result.ProposedTotal - result.BaselineTotal
+	result.DeltaPercent = percentChange(result.BaselineTotal, result.ProposedTotal)
+
+	sort.SliceStable(result.Items, func(i, j int) bool {
+		di, dj := math.Abs(result.Items[i].Delta), math.Abs(result.Items[j].Delta)
+		if di != dj {
+			return di > dj
+		}
+		return result.Items[i].Name < result.Items[j].Name
+	})
+	return result, nil
+}
+
+// percentChange returns the change from baseline to proposed in percent (0 when the baseline is 0)
+func percentChange(baseline, proposed float64) float64 {
+	if baseline == 0 {
+		return 0
+	}
+	return (proposed - baseline) / baseline * 100
+}
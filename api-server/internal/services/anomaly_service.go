@@ -0,0 +1,497 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"math"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/bugfreev587/k8s-cost-api-server/internal/models"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// hourlyBaselinePeriods is the number of preceding hours an hour's cost is compared with
+const hourlyBaselinePeriods = 24
+
+// maxAnomalyDrivers is the number of root-cause hints stored per anomaly
+const maxAnomalyDrivers = 5
+
+// maxAnomalyZScore caps stored z-scores, e.g. of allocations without baseline cost
+const maxAnomalyZScore = 999
+
+// AnomalyService detects cost anomalies and manages their acknowledge/resolve workflow
+type AnomalyService struct {
+	db     *gorm.DB
+	alloc  *AllocationService
+	notify *NotificationService
+}
+
+// NewAnomalyService creates an anomaly service computing costs with the allocation service
+// and notifying new anomalies (notify may be nil)
+func NewAnomalyService(db *gorm.DB, alloc *AllocationService, notify *NotificationService) *AnomalyService {
+	return &AnomalyService{db: db, alloc: alloc, notify: notify}
+}
+
+// DefaultAnomalySettings returns the settings of tenants that have not configured detection
+func DefaultAnomalySettings(tenantID uint) models.AnomalySettings {
+	return models.AnomalySettings{
+		TenantID:      tenantID,
+		Enabled:       true,
+		Sensitivity:   models.AnomalySensitivityMedium,
+		MinChangeUSD:  1,
+		BaselineDays:  14,
+		Dimensions:    []string{"cluster", "namespace"},
+		Granularities: []string{models.AnomalyGranularityHour, models.AnomalyGranularityDay},
+	}
+}
+
+// ValidateAnomalySettings checks anomaly settings before they are saved
+func ValidateAnomalySettings(settings *models.AnomalySettings) error {
+	if _, ok := models.AnomalyThresholds[settings.Sensitivity]; !ok {
+		return fmt.Errorf("invalid sensitivity: %s (expected low, medium or high)", settings.Sensitivity)
+	}
+	if settings.MinChangeUSD < 0 {
+		return fmt.Errorf("min_change_usd must not be negative")
+	}
+	if settings.BaselineDays < 3 || settings.BaselineDays > 90 {
+		return fmt.Errorf("baseline_days must be between 3 and 90")
+	}
+	if len(settings.Dimensions) == 0 {
+		return fmt.Errorf("at least one dimension required")
+	}
+	for _, dim := range settings.Dimensions {
+		if dim != "cluster" && dim != "namespace" && !(strings.HasPrefix(dim, "label:") && len(dim) > len("label:")) {
+			return fmt.Errorf("invalid dimension: %s (expected cluster, namespace or label:<key>)", dim)
+		}
+	}
+	if len(settings.Granularities) == 0 {
+		return fmt.Errorf("at least one granularity required")
+	}
+	for _, g := range settings.Granularities {
+		if g != models.AnomalyGranularityHour && g != models.AnomalyGranularityDay {
+			return fmt.Errorf("invalid granularity: %s (expected hour or day)", g)
+		}
+	}
+	return nil
+}
+
+// GetSettings returns a tenant's anomaly settings, or the defaults when not configured
+func (s *AnomalyService) GetSettings(ctx context.Context, tenantID uint) (*models.AnomalySettings, error) {
+	var settings models.AnomalySettings
+	err := s.db.WithContext(ctx).Where("tenant_id = ?", tenantID).First(&settings).Error
+	if err == gorm.ErrRecordNotFound {
+		defaults := DefaultAnomalySettings(tenantID)
+		return &defaults, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &settings, nil
+}
+
+// SaveSettings creates or replaces a tenant's anomaly settings
+func (s *AnomalyService) SaveSettings(ctx context.Context, settings *models.AnomalySettings) error {
+	return s.db.WithContext(ctx).Save(settings).Error
+}
+
+// AnomalyFilter selects anomalies to list
+type AnomalyFilter struct {
+	Status      string
+	Granularity string
+	Dimension   string
+	Since       time.Time
+	Limit       int
+}
+
+// ListAnomalies lists a tenant's anomalies, most recent first
+func (s *AnomalyService) ListAnomalies(ctx context.Context, tenantID uint, filter AnomalyFilter) ([]models.CostAnomaly, error) {
+	query := s.db.WithContext(ctx).Where("tenant_id = ?", tenantID)
+	if filter.Status != "" {
+		query = query.Where("status = ?", filter.Status)
+	}
+	if filter.Granularity != "" {
+		query = query.Where("granularity = ?", filter.Granularity)
+	}
+	if filter.Dimension != "" {
+		query = query.Where("dimension = ?", filter.Dimension)
+	}
+	if !filter.Since.IsZero() {
+		query = query.Where("window_start >= ?", filter.Since)
+	}
+	if filter.Limit <= 0 {
+		filter.Limit = 100
+	}
+
+	var anomalies []models.CostAnomaly
+	err := query.Order("window_start DESC, id DESC").Limit(filter.Limit).Find(&anomalies).Error
+	return anomalies, err
+}
+
+// GetAnomaly retrieves an anomaly by ID
+func (s *AnomalyService) GetAnomaly(ctx context.Context, id uint) (*models.CostAnomaly, error) {
+	var anomaly models.CostAnomaly
+	if err := s.db.WithContext(ctx).First(&anomaly, id).Error; err != nil {
+		return nil, err
+	}
+	return &anomaly, nil
+}
+
+// Acknowledge marks an open anomaly as acknowledged
+func (s *AnomalyService) Acknowledge(ctx context.Context, anomaly *models.CostAnomaly, actor, note string) error {
+	if anomaly.Status != models.AnomalyStatusOpen {
+		return fmt.Errorf("anomaly is %s", anomaly.Status)
+	}
+	now := time.Now()
+	anomaly.Status = models.AnomalyStatusAcknowledged
+	anomaly.AcknowledgedBy = actor
+	anomaly.AcknowledgedAt = &now
+	if note != "" {
+		anomaly.Note = note
+	}
+	return s.db.WithContext(ctx).Save(anomaly).Error
+}
+
+// Resolve marks an open or acknowledged anomaly as resolved
+func (s *AnomalyService) Resolve(ctx context.Context, anomaly *models.CostAnomaly, actor, note string) error {
+	if anomaly.Status == models.AnomalyStatusResolved {
+		return fmt.Errorf("anomaly is already resolved")
+	}
+	now := time.Now()
+	anomaly.Status = models.AnomalyStatusResolved
+	anomaly.ResolvedBy = actor
+	anomaly.ResolvedAt = &now
+	if note != "" {
+		anomaly.Note = note
+	}
+	return s.db.WithContext(ctx).Save(anomaly).Error
+}
+
+// Run evaluates every tenant at each interval until ctx is cancelled
+func (s *AnomalyService) Run(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		if err := s.DetectAll(ctx, time.Now()); err != nil {
+			log.Printf("anomaly detection failed: %v", err)
+		}
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// DetectAll evaluates the last complete hour and day of every tenant. Each period is
+// claimed before it is evaluated, so it is evaluated once even with several replicas.
+func (s *AnomalyService) DetectAll(ctx context.Context, now time.Time) error {
+	var tenantIDs []uint
+	if err := s.db.WithContext(ctx).Model(&models.Tenant{}).Pluck("id", &tenantIDs).Error; err != nil {
+		return err
+	}
+
+	for _, tenantID := range tenantIDs {
+		settings, err := s.GetSettings(ctx, tenantID)
+		if err != nil {
+			return err
+		}
+		if !settings.Enabled {
+			continue
+		}
+		for _, granularity := range settings.Granularities {
+			windowStart, _ := lastCompletePeriod(granularity, now)
+			claimed, err := s.claimRun(ctx, tenantID, granularity, windowStart)
+			if err != nil {
+				return err
+			}
+			if !claimed {
+				continue
+			}
+			found, err := s.detectPeriod(ctx, settings, granularity, windowStart)
+			if err != nil {
+				log.Printf("anomaly detection failed for tenant %d (%s %s): %v", tenantID, granularity, windowStart.Format(time.RFC3339), err)
+				continue
+			}
+			s.db.WithContext(ctx).Model(&models.AnomalyDetectionRun{}).
+				Where("tenant_id = ? AND granularity = ? AND window_start = ?", tenantID, granularity, windowStart).
+				Update("anomalies", len(found))
+		}
+	}
+	return nil
+}
+
+// DetectTenant evaluates the last complete hour and day of a tenant now, regardless of
+// earlier runs, and returns the new anomalies
+func (s *AnomalyService) DetectTenant(ctx context.Context, tenantID uint, now time.Time) ([]models.CostAnomaly, error) {
+	settings, err := s.GetSettings(ctx, tenantID)
+	if err != nil {
+		return nil, err
+	}
+
+	var found []models.CostAnomaly
+	for _, granularity := range settings.Granularities {
+		windowStart, _ := lastCompletePeriod(granularity, now)
+		anomalies, err := s.detectPeriod(ctx, settings, granularity, windowStart)
+		if err != nil {
+			return nil, err
+		}
+		found = append(found, anomalies...)
+	}
+	return found, nil
+}
+
+// claimRun records that a tenant's period is being evaluated; it returns false when the
+// period was already claimed
+func (s *AnomalyService) claimRun(ctx context.Context, tenantID uint, granularity string, windowStart time.Time) (bool, error) {
+	run := models.AnomalyDetectionRun{TenantID: tenantID, Granularity: granularity, WindowStart: windowStart}
+	result := s.db.WithContext(ctx).Clauses(clause.OnConflict{DoNothing: true}).Create(&run)
+	if result.Error != nil {
+		return false, result.Error
+	}
+	return result.RowsAffected > 0, nil
+}
+
+// detectPeriod compares the cost of each configured dimension's allocations in the period
+// starting at windowStart with the preceding periods and stores the anomalies found
+func (s *AnomalyService) detectPeriod(ctx context.Context, settings *models.AnomalySettings, granularity string, windowStart time.Time) ([]models.CostAnomaly, error) {
+	period, baselinePeriods, step := anomalyPeriod(granularity, settings.BaselineDays)
+	windowEnd := windowStart.Add(period)
+	baselineStart := windowStart.Add(-time.Duration(baselinePeriods) * period)
+	threshold := models.AnomalyThresholds[settings.Sensitivity]
+
+	var found []models.CostAnomaly
+	for _, dimension := range settings.Dimensions {
+		response, err := s.alloc.GetAllocations(ctx, int64(settings.TenantID), AllocationParams{
+			Window:     baselineStart.Format(time.RFC3339) + "," + windowEnd.Format(time.RFC3339),
+			Aggregate:  dimension,
+			Step:       step,
+			Accumulate: "false",
+			Limit:      baselinePeriods + 2,
+		})
+		if err != nil {
+			return nil, err
+		}
+
+		// Cost of each allocation per period; the last period is the one evaluated
+		series := make(map[string][]float64)
+		for _, set := range response.Data {
+			i := int(set.Window.Start.Sub(baselineStart) / period)
+			if i < 0 || i > baselinePeriods {
+				continue
+			}
+			for name, alloc := range set.Allocations {
+				if series[name] == nil {
+					series[name] = make([]float64, baselinePeriods+1)
+				}
+				series[name][i] += alloc.TotalCost
+			}
+		}
+
+		for name, costs := range series {
+			result, ok := detectAnomaly(costs[:baselinePeriods], costs[baselinePeriods], threshold, settings.MinChangeUSD)
+			if !ok {
+				continue
+			}
+			anomaly := models.CostAnomaly{
+				TenantID:        settings.TenantID,
+				Granularity:     granularity,
+				Dimension:       dimension,
+				Name:            name,
+				WindowStart:     windowStart,
+				WindowEnd:       windowEnd,
+				CostUSD:         costs[baselinePeriods],
+				BaselineCostUSD: result.Mean,
+				StdDevUSD:       result.StdDev,
+				ZScore:          math.Round(result.ZScore*100) / 100,
+				Direction:       result.Direction,
+				Severity:        anomalySeverity(result.ZScore, threshold),
+				Status:          models.AnomalyStatusOpen,
+			}
+			anomaly.Drivers, err = s.anomalyDrivers(ctx, &anomaly, baselineStart, baselinePeriods)
+			if err != nil {
+				log.Printf("anomaly drivers failed for tenant %d %s=%s: %v", settings.TenantID, dimension, name, err)
+			}
+
+			created := s.db.WithContext(ctx).Clauses(clause.OnConflict{DoNothing: true}).Create(&anomaly)
+			if created.Error != nil {
+				return nil, created.Error
+			}
+			if created.RowsAffected > 0 {
+				found = append(found, anomaly)
+				s.notify.Notify(anomaly.TenantID, s.notify.AnomalyMessage(&anomaly))
+			}
+		}
+	}
+	return found, nil
+}
+
+// anomalyDrivers compares the cost of each namespace/controller of the anomalous allocation
+// in the period with its average over the baseline periods and returns the largest changes
+// in the anomaly's direction
+func (s *AnomalyService) anomalyDrivers(ctx context.Context, anomaly *models.CostAnomaly, baselineStart time.Time, baselinePeriods int) (models.AnomalyDrivers, error) {
+	filter, ok := anomalyFilter(anomaly.Dimension, anomaly.Name)
+	if !ok {
+		return nil, nil
+	}
+	costs := func(start, end time.Time) (map[string]float64, error) {
+		response, err := s.alloc.GetAllocations(ctx, int64(anomaly.TenantID), AllocationParams{
+			Window:    start.Format(time.RFC3339) + "," + end.Format(time.RFC3339),
+			Aggregate: "namespace,controller",
+			Filters:   []string{filter},
+		})
+		if err != nil {
+			return nil, err
+		}
+		byName := make(map[string]float64)
+		for _, set := range response.Data {
+			for name, alloc := range set.Allocations {
+				byName[name] += alloc.TotalCost
+			}
+		}
+		return byName, nil
+	}
+
+	current, err := costs(anomaly.WindowStart, anomaly.WindowEnd)
+	if err != nil {
+		return nil, err
+	}
+	baseline, err := costs(baselineStart, anomaly.WindowStart)
+	if err != nil {
+		return nil, err
+	}
+	return rankAnomalyDrivers(current, baseline, baselinePeriods, anomaly.Direction), nil
+}
+
+// rankAnomalyDrivers returns the workloads whose cost changed most in the anomaly's
+// direction; baseline costs are totals over baselinePeriods periods
+func rankAnomalyDrivers(current, baseline map[string]float64, baselinePeriods int, direction string) models.AnomalyDrivers {
+	names := make(map[string]bool, len(current)+len(baseline))
+	for name := range current {
+		names[name] = true
+	}
+	for name := range baseline {
+		names[name] = true
+	}
+
+	var drivers models.AnomalyDrivers
+	for name := range names {
+		avg := baseline[name] / float64(baselinePeriods)
+		change := current[name] - avg
+		if (direction == models.AnomalySpike && change <= 0) || (direction == models.AnomalyDrop && change >= 0) {
+			continue
+		}
+		namespace, controller, _ := strings.Cut(name, "/")
+		drivers = append(drivers, models.AnomalyDriver{
+			Name:            name,
+			Namespace:       namespace,
+			Controller:      controller,
+			CostUSD:         current[name],
+			BaselineCostUSD: avg,
+			ChangeUSD:       change,
+		})
+	}
+	sort.Slice(drivers, func(i, j int) bool {
+		if a, b := math.Abs(drivers[i].ChangeUSD), math.Abs(drivers[j].ChangeUSD); a != b {
+			return a > b
+		}
+		return drivers[i].Name < drivers[j].Name
+	})
+	if len(drivers) > maxAnomalyDrivers {
+		drivers = drivers[:maxAnomalyDrivers]
+	}
+	return drivers
+}
+
+// anomalyResult is the deviation of a period's cost from its baseline
+type anomalyResult struct {
+	Mean      float64
+	StdDev    float64
+	ZScore    float64
+	Direction string
+}
+
+// detectAnomaly compares a cost with the mean and standard deviation of its baseline and
+// reports whether it deviates by at least threshold standard deviations and at least
+// minChange. The standard deviation is floored at 5% of the mean so a perfectly flat
+// baseline does not turn every small change into an anomaly.
+func detectAnomaly(baseline []float64, cost, threshold, minChange float64) (anomalyResult, bool) {
+	if len(baseline) == 0 {
+		return anomalyResult{}, false
+	}
+	m := mean(baseline)
+	var variance float64
+	for _, v := range baseline {
+		variance += (v - m) * (v - m)
+	}
+	stddev := math.Sqrt(variance / float64(len(baseline)))
+
+	result := anomalyResult{Mean: m, StdDev: stddev, Direction: models.AnomalySpike}
+	change := cost - m
+	if change < 0 {
+		result.Direction = models.AnomalyDrop
+	}
+	if math.Abs(change) < minChange || change == 0 {
+		return result, false
+	}
+
+	sigma := math.Max(stddev, 0.05*math.Abs(m))
+	if sigma == 0 {
+		// A new allocation (no baseline cost) changing by at least minChange; the z-score
+		// is capped so it can be stored
+		result.ZScore = math.Copysign(maxAnomalyZScore, change)
+	} else {
+		result.ZScore = math.Max(-maxAnomalyZScore, math.Min(maxAnomalyZScore, change/sigma))
+	}
+	return result, math.Abs(result.ZScore) >= threshold
+}
+
+// anomalySeverity grades an anomaly by its z-score relative to the sensitivity threshold
+func anomalySeverity(zScore, threshold float64) string {
+	switch z := math.Abs(zScore); {
+	case z >= 2*threshold:
+		return models.AnomalySeverityHigh
+	case z >= 1.5*threshold:
+		return models.AnomalySeverityMedium
+	default:
+		return models.AnomalySeverityLow
+	}
+}
+
+// anomalyFilter returns the allocation filter selecting an anomaly's allocation, or false
+// for allocations without a filterable value (e.g. workloads missing the label)
+func anomalyFilter(dimension, name string) (string, bool) {
+	if name == "" || strings.HasPrefix(name, "__") {
+		return "", false
+	}
+	switch {
+	case dimension == "cluster" || dimension == "namespace":
+		return dimension + ":" + name, true
+	case strings.HasPrefix(dimension, "label:"):
+		return "label:" + strings.TrimPrefix(dimension, "label:") + "=" + name, true
+	}
+	return "", false
+}
+
+// anomalyPeriod returns the length of a granularity's period, the number of baseline
+// periods and the allocation step
+func anomalyPeriod(granularity string, baselineDays int) (time.Duration, int, string) {
+	if granularity == models.AnomalyGranularityHour {
+		return time.Hour, hourlyBaselinePeriods, "1h"
+	}
+	return 24 * time.Hour, baselineDays, "1d"
+}
+
+// lastCompletePeriod returns the bounds of the last complete hour or UTC day before now
+func lastCompletePeriod(granularity string, now time.Time) (time.Time, time.Time) {
+	if granularity == models.AnomalyGranularityHour {
+		end := now.UTC().Truncate(time.Hour)
+		return end.Add(-time.Hour), end
+	}
+	end := truncateDay(now)
+	return end.AddDate(0, 0, -1), end
+}
@@ -0,0 +1,105 @@
+package services
+
+import (
+	"testing"
+	"time"
+
+	"github.com/bugfreev587/k8s-cost-api-server/internal/models"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestDetectAnomaly(t *testing.T) {
+	baseline := []float64{100, 102, 98, 101, 99, 100, 100}
+
+	result, ok := detectAnomaly(baseline, 180, 3, 1)
+	require.True(t, ok)
+	assert.Equal(t, models.AnomalySpike, result.Direction)
+	assert.InDelta(t, 100, result.Mean, 1e-9)
+	assert.Greater(t, result.ZScore, 3.0)
+
+	result, ok = detectAnomaly(baseline, 20, 3, 1)
+	require.True(t, ok)
+	assert.Equal(t, models.AnomalyDrop, result.Direction)
+	assert.Less(t, result.ZScore, -3.0)
+
+	// Within the normal variation
+	_, ok = detectAnomaly(baseline, 103, 3, 1)
+	assert.False(t, ok)
+
+	// Significant but smaller than the minimum change
+	_, ok = detectAnomaly([]float64{1, 1, 1, 1}, 1.5, 3, 1)
+	assert.False(t, ok)
+}
+
+func TestDetectAnomalyNewAllocation(t *testing.T) {
+	result, ok := detectAnomaly([]float64{0, 0, 0}, 25, 3, 1)
+	require.True(t, ok)
+	assert.Equal(t, float64(maxAnomalyZScore), result.ZScore)
+	assert.Equal(t, models.AnomalySeverityHigh, anomalySeverity(result.ZScore, 3))
+}
+
+func TestAnomalySeverity(t *testing.T) {
+	assert.Equal(t, models.AnomalySeverityLow, anomalySeverity(3.5, 3))
+	assert.Equal(t, models.AnomalySeverityMedium, anomalySeverity(-4.5, 3))
+	assert.Equal(t, models.AnomalySeverityHigh, anomalySeverity(6, 3))
+}
+
+func TestRankAnomalyDrivers(t *testing.T) {
+	current := map[string]float64{"batch/etl": 90, "web/api": 12, "web/frontend": 4}
+	baseline := map[string]float64{"batch/etl": 70, "web/api": 70, "web/frontend": 35, "web/old": 14} // over 7 periods
+
+	drivers := rankAnomalyDrivers(current, baseline, 7, models.AnomalySpike)
+	require.Len(t, drivers, 2)
+	assert.Equal(t, "batch/etl", drivers[0].Name)
+	assert.Equal(t, "batch", drivers[0].Namespace)
+	assert.Equal(t, "etl", drivers[0].Controller)
+	assert.InDelta(t, 80, drivers[0].ChangeUSD, 1e-9)
+	assert.Equal(t, "web/api", drivers[1].Name)
+
+	drivers = rankAnomalyDrivers(current, baseline, 7, models.AnomalyDrop)
+	require.Len(t, drivers, 2)
+	assert.Equal(t, "web/old", drivers[0].Name)
+	assert.Equal(t, "web/frontend", drivers[1].Name)
+}
+
+func TestAnomalyFilter(t *testing.T) {
+	filter, ok := anomalyFilter("namespace", "batch")
+	assert.True(t, ok)
+	assert.Equal(t, "namespace:batch", filter)
+
+	filter, ok = anomalyFilter("label:team", "data")
+	assert.True(t, ok)
+	assert.Equal(t, "label:team=data", filter)
+
+	_, ok = anomalyFilter("label:team", "__unallocated__")
+	assert.False(t, ok)
+}
+
+func TestValidateAnomalySettings(t *testing.T) {
+	settings := DefaultAnomalySettings(1)
+	require.NoError(t, ValidateAnomalySettings(&settings))
+
+	settings.Dimensions = []string{"label:"}
+	assert.Error(t, ValidateAnomalySettings(&settings))
+
+	settings = DefaultAnomalySettings(1)
+	settings.Sensitivity = "extreme"
+	assert.Error(t, ValidateAnomalySettings(&settings))
+
+	settings = DefaultAnomalySettings(1)
+	settings.Granularities = []string{"week"}
+	assert.Error(t, ValidateAnomalySettings(&settings))
+}
+
+func TestLastCompletePeriod(t *testing.T) {
+	now := time.Date(2024, 3, 5, 14, 20, 0, 0, time.UTC)
+
+	start, end := lastCompletePeriod(models.AnomalyGranularityHour, now)
+	assert.Equal(t, time.Date(2024, 3, 5, 13, 0, 0, 0, time.UTC), start)
+	assert.Equal(t, time.Date(2024, 3, 5, 14, 0, 0, 0, time.UTC), end)
+
+	start, end = lastCompletePeriod(models.AnomalyGranularityDay, now)
+	assert.Equal(t, time.Date(2024, 3, 4, 0, 0, 0, 0, time.UTC), start)
+	assert.Equal(t, time.Date(2024, 3, 5, 0, 0, 0, 0, time.UTC), end)
+}
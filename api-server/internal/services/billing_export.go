@@ -0,0 +1,408 @@
+package services
+
+import (
+	"bufio"
+	"bytes"
+	"compress/gzip"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/bugfreev587/k8s-cost-api-server/internal/models"
+	"github.com/bugfreev587/k8s-cost-api-server/internal/parquet"
+)
+
+// BillingLineItem is a cost line from a cloud billing export, normalized across providers
+type BillingLineItem struct {
+	ResourceID string
+	Start      time.Time
+	End        time.Time
+	ListCost   float64 // on-demand equivalent cost
+	NetCost    float64 // cost after reservations, savings plans, discounts and credits
+	Adjustment bool    // credit, refund or discount not tied to usage of a resource
+	Currency   string  // ISO 4217 billing currency of the costs
+}
+
+// ParseBillingExport parses an uploaded billing export file (optionally gzip-compressed):
+// AWS Cost and Usage Report CSV or Parquet (legacy and CUR 2.0 column names), GCP billing
+// export CSV (BigQuery export saved as CSV), or Azure cost export CSV or Parquet (actual or
+// amortized). Costs are in each line's billing currency.
+func ParseBillingExport(provider models.CloudProvider, r io.Reader) ([]BillingLineItem, error) {
+	br := bufio.NewReader(r)
+	if magic, _ := br.Peek(2); bytes.Equal(magic, []byte{0x1f, 0x8b}) {
+		gz, err := gzip.NewReader(br)
+		if err != nil {
+			return nil, fmt.Errorf("invalid gzip file: %w", err)
+		}
+		defer gz.Close()
+		br = bufio.NewReader(gz)
+	}
+	if magic, _ := br.Peek(4); string(magic) == "PAR1" {
+		return parseParquetBillingExport(provider, br)
+	}
+
+	reader := csv.NewReader(br)
+	reader.FieldsPerRecord = -1
+	reader.LazyQuotes = true
+
+	header, err := reader.Read()
+	if err != nil {
+		return nil, fmt.Errorf("failed to read CSV header: %w", err)
+	}
+	return parseBillingRecords(provider, header, reader.Read, "line")
+}
+
+// parseParquetBillingExport parses a Parquet billing export. Parquet is read from its
+// footer, so the upload is spooled to a temporary file first.
+func parseParquetBillingExport(provider models.CloudProvider, r io.Reader) ([]BillingLineItem, error) {
+	f, err := os.CreateTemp("", "billing-export-*.parquet")
+	if err != nil {
+		return nil, fmt.Errorf("failed to buffer parquet file: %w", err)
+	}
+	defer os.Remove(f.Name())
+	defer f.Close()
+
+	size, err := io.Copy(f, r)
+	if err != nil {
+		return nil, fmt.Errorf("failed to buffer parquet file: %w", err)
+	}
+	reader, err := parquet.NewReader(f, size)
+	if err != nil {
+		return nil, err
+	}
+	return parseBillingRecords(provider, reader.Columns(), reader.Read, "row")
+}
+
+// parseBillingRecords parses the records returned by read (io.EOF at the end) with the
+// provider's parser for the header's columns. Errors are reported by unit ("line" or "row").
+func parseBillingRecords(provider models.CloudProvider, header []string, read func() ([]string, error), unit string) ([]BillingLineItem, error) {
+	cols := newBillingColumns(header)
+
+	var parse func(record []string) (BillingLineItem, bool, error)
+	var err error
+	switch provider {
+	case models.ProviderAWS:
+		parse, err = awsCURParser(cols)
+	case models.ProviderGCP:
+		parse, err = gcpExportParser(cols)
+	case models.ProviderAzure:
+		parse, err = azureExportParser(cols)
+	default:
+		return nil, fmt.Errorf("unsupported billing provider: %s", provider)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	// CSV lines count the header; Parquet rows start at 1
+	first := 1
+	if unit == "line" {
+		first = 2
+	}
+	var items []BillingLineItem
+	for n := first; ; n++ {
+		record, err := read()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, fmt.Errorf("%s %d: %w", unit, n, err)
+		}
+		item, ok, err := parse(record)
+		if err != nil {
+			return nil, fmt.Errorf("%s %d: %w", unit, n, err)
+		}
+		if ok {
+			items = append(items, item)
+		}
+	}
+	return items, nil
+}
+
+// billingColumns looks up CSV columns by case-insensitive name
+type billingColumns map[string]int
+
+func newBillingColumns(header []string) billingColumns {
+	cols := make(billingColumns, len(header))
+	for i, name := range header {
+		name = strings.ToLower(strings.TrimSpace(strings.TrimPrefix(name, "\ufeff")))
+		if _, exists := cols[name]; !exists {
+			cols[name] = i
+		}
+	}
+	return cols
+}
+
+// index returns the position of the first existing column among names, or -1
+func (c billingColumns) index(names ...string) int {
+	for _, name := range names {
+		if i, ok := c[name]; ok {
+			return i
+		}
+	}
+	return -1
+}
+
+// billingField returns the trimmed value at index i, or "" when the column is missing
+func billingField(record []string, i int) string {
+	if i < 0 || i >= len(record) {
+		return ""
+	}
+	return strings.TrimSpace(record[i])
+}
+
+// billingFloat parses a numeric field, treating empty values as zero
+func billingFloat(record []string, i int) (float64, error) {
+	value := billingField(record, i)
+	if value == "" {
+		return 0, nil
+	}
+	f, err := strconv.ParseFloat(value, 64)
+	if err != nil {
+		return 0, fmt.Errorf("invalid number %q", value)
+	}
+	return f, nil
+}
+
+// billingTimeLayouts are the timestamp formats used by the supported billing exports
+var billingTimeLayouts = []string{
+	time.RFC3339,
+	"2006-01-02T15:04:05Z0700",
+	"2006-01-02 15:04:05 MST",
+	"2006-01-02 15:04:05",
+	"2006-01-02",
+	"01/02/2006",
+}
+
+// billingCurrency returns the normalized currency of a line; exports without a currency
+// column are in USD
+func billingCurrency(record []string, i int) (string, error) {
+	value := billingField(record, i)
+	if value == "" {
+		return models.BaseCurrency, nil
+	}
+	return NormalizeCurrency(value)
+}
+
+// parseBillingTime parses a billing export timestamp (UTC unless a zone is given)
+func parseBillingTime(value string) (time.Time, error) {
+	for _, layout := range billingTimeLayouts {
+		if t, err := time.Parse(layout, value); err == nil {
+			return t.UTC(), nil
+		}
+	}
+	return time.Time{}, fmt.Errorf("invalid time %q", value)
+}
+
+// parseBillingWindow parses the usage start and end of a line item. Lines without an
+// end cover one day from their start.
+func parseBillingWindow(startValue, endValue string) (time.Time, time.Time, error) {
+	start, err := parseBillingTime(startValue)
+	if err != nil {
+		return time.Time{}, time.Time{}, err
+	}
+	end := start.AddDate(0, 0, 1)
+	if endValue != "" {
+		if end, err = parseBillingTime(endValue); err != nil {
+			return time.Time{}, time.Time{}, err
+		}
+	}
+	return start, end, nil
+}
+
+// awsCURParser parses AWS Cost and Usage Report lines. Reserved instance and savings plan
+// usage is costed at its effective (amortized) cost; negation and fee lines are skipped
+// since they are already reflected in the effective cost.
+func awsCURParser(cols billingColumns) (func([]string) (BillingLineItem, bool, error), error) {
+	resourceCol := cols.index("lineitem/resourceid", "line_item_resource_id")
+	startCol := cols.index("lineitem/usagestartdate", "line_item_usage_start_date")
+	endCol := cols.index("lineitem/usageenddate", "line_item_usage_end_date")
+	typeCol := cols.index("lineitem/lineitemtype", "line_item_line_item_type")
+	costCol := cols.index("lineitem/unblendedcost", "line_item_unblended_cost")
+	spCol := cols.index("savingsplan/savingsplaneffectivecost", "savings_plan_savings_plan_effective_cost")
+	riCol := cols.index("reservation/effectivecost", "reservation_effective_cost")
+	publicCol := cols.index("pricing/publicondemandcost", "pricing_public_on_demand_cost")
+	currencyCol := cols.index("lineitem/currencycode", "line_item_currency_code")
+	if startCol < 0 || costCol < 0 {
+		return nil, fmt.Errorf("not an AWS CUR file: missing lineItem/UsageStartDate or lineItem/UnblendedCost column")
+	}
+
+	return func(record []string) (BillingLineItem, bool, error) {
+		lineType := billingField(record, typeCol)
+		switch lineType {
+		case "SavingsPlanNegation", "SavingsPlanUpfrontFee", "SavingsPlanRecurringFee", "RIFee", "Fee", "Tax":
+			return BillingLineItem{}, false, nil
+		}
+
+		start, end, err := parseBillingWindow(billingField(record, startCol), billingField(record, endCol))
+		if err != nil {
+			return BillingLineItem{}, false, err
+		}
+		unblended, err := billingFloat(record, costCol)
+		if err != nil {
+			return BillingLineItem{}, false, err
+		}
+		currency, err := billingCurrency(record, currencyCol)
+		if err != nil {
+			return BillingLineItem{}, false, err
+		}
+
+		item := BillingLineItem{
+			ResourceID: billingField(record, resourceCol),
+			Start:      start,
+			End:        end,
+			ListCost:   unblended,
+			NetCost:    unblended,
+			Currency:   currency,
+		}
+		if public, err := billingFloat(record, publicCol); err == nil && public > 0 {
+			item.ListCost = public
+		}
+
+		switch lineType {
+		case "SavingsPlanCoveredUsage":
+			if item.NetCost, err = billingFloat(record, spCol); err != nil {
+				return BillingLineItem{}, false, err
+			}
+		case "DiscountedUsage":
+			if item.NetCost, err = billingFloat(record, riCol); err != nil {
+				return BillingLineItem{}, false, err
+			}
+		case "Credit", "Refund", "EdpDiscount", "PrivateRateDiscount", "BundledDiscount", "SppDiscount", "Discount":
+			item.ListCost = 0
+			item.Adjustment = item.ResourceID == ""
+		}
+		return item, true, nil
+	}, nil
+}
+
+// gcpExportParser parses GCP billing export lines. Credits (sustained and committed use
+// discounts, promotions) are given per line either as a JSON array or a plain amount.
+func gcpExportParser(cols billingColumns) (func([]string) (BillingLineItem, bool, error), error) {
+	resourceCol := cols.index("resource.global_name", "resource.name", "resource_global_name", "resource_name")
+	startCol := cols.index("usage_start_time")
+	endCol := cols.index("usage_end_time")
+	costCol := cols.index("cost")
+	creditsCol := cols.index("credits", "credits.amount", "credits_amount")
+	currencyCol := cols.index("currency")
+	if startCol < 0 || costCol < 0 {
+		return nil, fmt.Errorf("not a GCP billing export: missing usage_start_time or cost column")
+	}
+
+	return func(record []string) (BillingLineItem, bool, error) {
+		start, end, err := parseBillingWindow(billingField(record, startCol), billingField(record, endCol))
+		if err != nil {
+			return BillingLineItem{}, false, err
+		}
+		cost, err := billingFloat(record, costCol)
+		if err != nil {
+			return BillingLineItem{}, false, err
+		}
+		credits, err := parseGCPCredits(billingField(record, creditsCol))
+		if err != nil {
+			return BillingLineItem{}, false, err
+		}
+		currency, err := billingCurrency(record, currencyCol)
+		if err != nil {
+			return BillingLineItem{}, false, err
+		}
+
+		resource := billingField(record, resourceCol)
+		return BillingLineItem{
+			ResourceID: resource,
+			Start:      start,
+			End:        end,
+			ListCost:   cost,
+			NetCost:    cost + credits,
+			Adjustment: resource == "" && cost == 0 && credits != 0,
+			Currency:   currency,
+		}, true, nil
+	}, nil
+}
+
+// parseGCPCredits sums a credits value: a JSON array of {"amount": ...} or a number
+func parseGCPCredits(value string) (float64, error) {
+	if value == "" || value == "[]" {
+		return 0, nil
+	}
+	if strings.HasPrefix(value, "[") {
+		var credits []struct {
+			Amount float64 `json:"amount"`
+		}
+		if err := json.Unmarshal([]byte(value), &credits); err != nil {
+			return 0, fmt.Errorf("invalid credits %q", value)
+		}
+		var total float64
+		for _, credit := range credits {
+			total += credit.Amount
+		}
+		return total, nil
+	}
+	f, err := strconv.ParseFloat(value, 64)
+	if err != nil {
+		return 0, fmt.Errorf("invalid credits %q", value)
+	}
+	return f, nil
+}
+
+// azureExportParser parses Azure cost export lines. Amortized exports already spread
+// reservation and savings plan purchases over usage, so purchase lines are skipped.
+func azureExportParser(cols billingColumns) (func([]string) (BillingLineItem, bool, error), error) {
+	resourceCol := cols.index("resourceid", "instanceid", "resource id")
+	dateCol := cols.index("date", "usagedatetime", "usagedate")
+	costCol := cols.index("costinbillingcurrency", "cost", "pretaxcost", "costinusd")
+	chargeCol := cols.index("chargetype")
+	paygCol := cols.index("paygprice")
+	quantityCol := cols.index("quantity", "usagequantity")
+	currencyCol := cols.index("billingcurrency", "billingcurrencycode", "currency")
+	if dateCol < 0 || costCol < 0 {
+		return nil, fmt.Errorf("not an Azure cost export: missing Date or CostInBillingCurrency column")
+	}
+	if costCol == cols.index("costinusd") {
+		currencyCol = -1
+	}
+
+	return func(record []string) (BillingLineItem, bool, error) {
+		chargeType := strings.ToLower(billingField(record, chargeCol))
+		if chargeType == "purchase" {
+			return BillingLineItem{}, false, nil
+		}
+
+		start, end, err := parseBillingWindow(billingField(record, dateCol), "")
+		if err != nil {
+			return BillingLineItem{}, false, err
+		}
+		cost, err := billingFloat(record, costCol)
+		if err != nil {
+			return BillingLineItem{}, false, err
+		}
+		currency, err := billingCurrency(record, currencyCol)
+		if err != nil {
+			return BillingLineItem{}, false, err
+		}
+
+		item := BillingLineItem{
+			ResourceID: billingField(record, resourceCol),
+			Start:      start,
+			End:        end,
+			ListCost:   cost,
+			NetCost:    cost,
+			Currency:   currency,
+		}
+		payg, _ := billingFloat(record, paygCol)
+		quantity, _ := billingFloat(record, quantityCol)
+		if payg > 0 && quantity > 0 {
+			item.ListCost = payg * quantity
+		}
+		if chargeType == "refund" {
+			item.ListCost = 0
+			item.Adjustment = item.ResourceID == ""
+		}
+		return item, true, nil
+	}, nil
+}
@@ -0,0 +1,150 @@
+package services
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/bugfreev587/k8s-cost-api-server/internal/export"
+	"github.com/bugfreev587/k8s-cost-api-server/internal/models"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseBillingExport_AWSCUR(t *testing.T) {
+	csv := `lineItem/LineItemType,lineItem/ResourceId,lineItem/UsageStartDate,lineItem/UsageEndDate,lineItem/UnblendedCost,savingsPlan/SavingsPlanEffectiveCost,reservation/EffectiveCost
+Usage,i-0aaa,2024-03-01T00:00:00Z,2024-03-01T01:00:00Z,0.192,,
+SavingsPlanCoveredUsage,i-0bbb,2024-03-01T00:00:00Z,2024-03-01T01:00:00Z,0.192,0.120,
+SavingsPlanNegation,i-0bbb,2024-03-01T00:00:00Z,2024-03-01T01:00:00Z,-0.192,,
+DiscountedUsage,i-0ccc,2024-03-01T00:00:00Z,2024-03-01T01:00:00Z,0,,0.100
+Credit,,2024-03-01T00:00:00Z,2024-04-01T00:00:00Z,-5.00,,
+`
+	items, err := ParseBillingExport(models.ProviderAWS, strings.NewReader(csv))
+	require.NoError(t, err)
+	require.Len(t, items, 4)
+
+	assert.InDelta(t, 0.192, items[0].NetCost, 1e-9)
+	assert.InDelta(t, 0.120, items[1].NetCost, 1e-9)
+	assert.InDelta(t, 0.192, items[1].ListCost, 1e-9)
+	assert.InDelta(t, 0.100, items[2].NetCost, 1e-9)
+	assert.True(t, items[3].Adjustment)
+}
+
+func TestParseBillingExport_GCPCredits(t *testing.T) {
+	csv := `resource.name,usage_start_time,usage_end_time,cost,credits
+gke-prod-pool-1,2024-03-01 00:00:00 UTC,2024-03-01 01:00:00 UTC,0.50,"[{""name"":""Sustained use"",""amount"":-0.10}]"
+`
+	items, err := ParseBillingExport(models.ProviderGCP, strings.NewReader(csv))
+	require.NoError(t, err)
+	require.Len(t, items, 1)
+	assert.InDelta(t, 0.40, items[0].NetCost, 1e-9)
+	assert.InDelta(t, 0.50, items[0].ListCost, 1e-9)
+}
+
+func TestParseBillingExport_AWSParquet(t *testing.T) {
+	var buf bytes.Buffer
+	w, err := export.NewWriter(export.FormatParquet, &buf, []export.Column{
+		{Name: "line_item_line_item_type", Type: export.String},
+		{Name: "line_item_resource_id", Type: export.String},
+		{Name: "line_item_usage_start_date", Type: export.Time},
+		{Name: "line_item_usage_end_date", Type: export.Time},
+		{Name: "line_item_unblended_cost", Type: export.Float},
+		{Name: "line_item_currency_code", Type: export.String},
+	})
+	require.NoError(t, err)
+	start := time.Date(2024, 3, 1, 0, 0, 0, 0, time.UTC)
+	require.NoError(t, w.WriteRow("Usage", "i-0aaa", start, start.Add(time.Hour), 0.192, "EUR"))
+	require.NoError(t, w.WriteRow("Tax", "", start, start.Add(time.Hour), 0.02, "EUR"))
+	require.NoError(t, w.Close())
+
+	items, err := ParseBillingExport(models.ProviderAWS, &buf)
+	require.NoError(t, err)
+	require.Len(t, items, 1)
+	assert.Equal(t, BillingLineItem{ResourceID: "i-0aaa", Start: start, End: start.Add(time.Hour), ListCost: 0.192, NetCost: 0.192, Currency: "EUR"}, items[0])
+
+	_, err = ParseBillingExport(models.ProviderAWS, strings.NewReader("PAR1\x00\x00"))
+	assert.ErrorContains(t, err, "parquet")
+}
+
+func TestParseBillingExport_Currency(t *testing.T) {
+	tests := []struct {
+		name     string
+		provider models.CloudProvider
+		csv      string
+		want     string
+		err      string
+	}{
+		{
+			name:     "aws currency code",
+			provider: models.ProviderAWS,
+			csv:      "lineItem/UsageStartDate,lineItem/UnblendedCost,lineItem/CurrencyCode\n2024-03-01T00:00:00Z,1,jpy\n",
+			want:     "JPY",
+		},
+		{
+			name:     "aws without currency",
+			provider: models.ProviderAWS,
+			csv:      "lineItem/UsageStartDate,lineItem/UnblendedCost\n2024-03-01T00:00:00Z,1\n",
+			want:     "USD",
+		},
+		{
+			name:     "gcp currency",
+			provider: models.ProviderGCP,
+			csv:      "usage_start_time,cost,currency\n2024-03-01 00:00:00 UTC,1,EUR\n",
+			want:     "EUR",
+		},
+		{
+			name:     "azure billing currency",
+			provider: models.ProviderAzure,
+			csv:      "Date,CostInBillingCurrency,BillingCurrency\n2024-03-01,1,GBP\n",
+			want:     "GBP",
+		},
+		{
+			name:     "azure cost in usd",
+			provider: models.ProviderAzure,
+			csv:      "Date,CostInUSD,BillingCurrency\n2024-03-01,1,GBP\n",
+			want:     "USD",
+		},
+		{
+			name:     "unknown currency",
+			provider: models.ProviderGCP,
+			csv:      "usage_start_time,cost,currency\n2024-03-01 00:00:00 UTC,1,XXY\n",
+			err:      "line 2: unknown currency",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			items, err := ParseBillingExport(tt.provider, strings.NewReader(tt.csv))
+			if tt.err != "" {
+				assert.ErrorContains(t, err, tt.err)
+				return
+			}
+			require.NoError(t, err)
+			require.Len(t, items, 1)
+			assert.Equal(t, tt.want, items[0].Currency)
+		})
+	}
+}
+
+func TestBillingNodeIndex_Match(t *testing.T) {
+	index := newBillingNodeIndex([]billingNode{
+		{Cluster: "eks", Node: "ip-10-0-1-2.ec2.internal", ProviderID: "aws:///us-east-1a/i-0abc"},
+		{Cluster: "gke", Node: "gke-prod-pool-1", ProviderID: "gce://proj/us-central1-a/gke-prod-pool-1"},
+		{Cluster: "aks", Node: "aks-pool-vmss000000", ProviderID: "azure:///subscriptions/s/resourceGroups/RG/providers/Microsoft.Compute/virtualMachineScaleSets/aks-pool-vmss/virtualMachines/0"},
+	})
+
+	n, ok := index.match("i-0abc")
+	assert.True(t, ok)
+	assert.Equal(t, "eks", n.Cluster)
+
+	n, ok = index.match("projects/proj/zones/us-central1-a/instances/gke-prod-pool-1")
+	assert.True(t, ok)
+	assert.Equal(t, "gke", n.Cluster)
+
+	n, ok = index.match("/subscriptions/s/resourcegroups/rg/providers/microsoft.compute/virtualmachinescalesets/aks-pool-vmss/virtualmachines/0")
+	assert.True(t, ok)
+	assert.Equal(t, "aks", n.Cluster)
+
+	_, ok = index.match("vol-0123")
+	assert.False(t, ok)
+}
@@ -0,0 +1,348 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/bugfreev587/k8s-cost-api-server/internal/models"
+	"github.com/jackc/pgx/v5/pgxpool"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// BillingReconciliationService matches cloud billing exports to cluster nodes and stores
+// each node's actual daily cost, which allocations use in place of modeled pricing
+type BillingReconciliationService struct {
+	pool *pgxpool.Pool
+	db   *gorm.DB
+}
+
+// NewBillingReconciliationService creates a new billing reconciliation service
+func NewBillingReconciliationService(pool *pgxpool.Pool, db *gorm.DB) *BillingReconciliationService {
+	return &BillingReconciliationService{pool: pool, db: db}
+}
+
+// billingNode is a cluster node as reported by the agent
+type billingNode struct {
+	Cluster    string
+	Node       string
+	ProviderID string
+}
+
+// nodeDay accumulates a node's billed cost for one UTC day
+type nodeDay struct {
+	node      billingNode
+	day       time.Time
+	resource  string
+	listCost  float64
+	netCost   float64
+	intervals map[[2]int64]time.Duration
+}
+
+// ImportBillingExport parses a billing export, matches its line items to the tenant's
+// nodes by instance ID or name, and stores the reconciled daily cost of each matched node.
+// Costs billed in another currency are converted to USD at the tenant's exchange rate
+// effective at the start of each line. Credits and discounts not tied to a resource are
+// spread over all usage in proportion to cost. A failed import is recorded with its error.
+func (s *BillingReconciliationService) ImportBillingExport(ctx context.Context, tenantID uint, provider models.CloudProvider, fileName string, r io.Reader) (*models.BillingImport, error) {
+	record := &models.BillingImport{
+		TenantID: tenantID,
+		Provider: provider,
+		FileName: fileName,
+		Status:   models.BillingImportCompleted,
+	}
+
+	items, err := ParseBillingExport(provider, r)
+	if err == nil && len(items) == 0 {
+		err = fmt.Errorf("billing export contains no line items")
+	}
+	if err == nil {
+		err = s.convertToBase(ctx, tenantID, items)
+	}
+	if err != nil {
+		record.Status = models.BillingImportFailed
+		record.Error = err.Error()
+		if saveErr := s.db.WithContext(ctx).Create(record).Error; saveErr != nil {
+			return nil, saveErr
+		}
+		return record, err
+	}
+
+	periodStart, periodEnd := items[0].Start, items[0].End
+	for _, item := range items {
+		if item.Start.Before(periodStart) {
+			periodStart = item.Start
+		}
+		if item.End.After(periodEnd) {
+			periodEnd = item.End
+		}
+	}
+	record.PeriodStart, record.PeriodEnd = &periodStart, &periodEnd
+	record.LineItems = len(items)
+
+	nodes, err := s.loadNodes(ctx, tenantID, periodStart, periodEnd)
+	if err != nil {
+		return nil, err
+	}
+	index := newBillingNodeIndex(nodes)
+
+	// Attribute usage to node-days
+	days := make(map[string]*nodeDay)
+	var usageCost, adjustments float64
+	for _, item := range items {
+		record.TotalCost += item.NetCost
+		if item.Adjustment {
+			adjustments += item.NetCost
+			continue
+		}
+		usageCost += item.NetCost
+
+		node, ok := index.match(item.ResourceID)
+		if !ok {
+			record.UnmatchedCost += item.NetCost
+			continue
+		}
+		record.MatchedItems++
+
+		day := item.Start.Truncate(24 * time.Hour)
+		key := node.Cluster + "/" + node.Node + "/" + day.Format("2006-01-02")
+		nd, ok := days[key]
+		if !ok {
+			nd = &nodeDay{node: node, day: day, resource: item.ResourceID, intervals: make(map[[2]int64]time.Duration)}
+			days[key] = nd
+		}
+		nd.listCost += item.ListCost
+		nd.netCost += item.NetCost
+		nd.intervals[[2]int64{item.Start.Unix(), item.End.Unix()}] = clipDuration(item.Start, item.End, day, day.Add(24*time.Hour))
+	}
+
+	// Spread unattributed credits and discounts in proportion to usage cost
+	adjustmentFactor := 1.0
+	if usageCost > 0 {
+		adjustmentFactor += adjustments / usageCost
+		record.UnmatchedCost += record.UnmatchedCost * (adjustmentFactor - 1)
+	} else {
+		record.UnmatchedCost += adjustments
+	}
+
+	costs := make([]models.ReconciledNodeCost, 0, len(days))
+	matchedNodes := make(map[string]bool)
+	for _, nd := range days {
+		var usage time.Duration
+		for _, d := range nd.intervals {
+			usage += d
+		}
+		if usage > 24*time.Hour {
+			usage = 24 * time.Hour
+		}
+
+		net := nd.netCost * adjustmentFactor
+		record.MatchedCost += net
+		matchedNodes[nd.node.Cluster+"/"+nd.node.Node] = true
+		costs = append(costs, models.ReconciledNodeCost{
+			TenantID:    tenantID,
+			ClusterName: nd.node.Cluster,
+			NodeName:    nd.node.Node,
+			ResourceID:  nd.resource,
+			PeriodStart: nd.day,
+			PeriodEnd:   nd.day.Add(24 * time.Hour),
+			UsageHours:  usage.Hours(),
+			ListCost:    nd.listCost,
+			NetCost:     net,
+		})
+	}
+	record.NodesMatched = len(matchedNodes)
+	sort.Slice(costs, func(i, j int) bool {
+		if !costs[i].PeriodStart.Equal(costs[j].PeriodStart) {
+			return costs[i].PeriodStart.Before(costs[j].PeriodStart)
+		}
+		return costs[i].ClusterName+"/"+costs[i].NodeName < costs[j].ClusterName+"/"+costs[j].NodeName
+	})
+
+	err = s.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := tx.Create(record).Error; err != nil {
+			return err
+		}
+		if len(costs) == 0 {
+			return nil
+		}
+		for i := range costs {
+			costs[i].ImportID = record.ID
+		}
+		// A later import for the same node and day replaces the earlier figure
+		return tx.Clauses(clause.OnConflict{
+			Columns:   []clause.Column{{Name: "tenant_id"}, {Name: "cluster_name"}, {Name: "node_name"}, {Name: "period_start"}},
+			DoUpdates: clause.AssignmentColumns([]string{"import_id", "resource_id", "period_end", "usage_hours", "list_cost", "net_cost"}),
+		}).CreateInBatches(costs, 500).Error
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to save billing import: %w", err)
+	}
+	return record, nil
+}
+
+// convertToBase converts the costs of line items billed in another currency to USD
+func (s *BillingReconciliationService) convertToBase(ctx context.Context, tenantID uint, items []BillingLineItem) error {
+	earliest := make(map[string]time.Time)
+	var end time.Time
+	for _, item := range items {
+		if item.Currency == "" || item.Currency == models.BaseCurrency {
+			continue
+		}
+		if t, ok := earliest[item.Currency]; !ok || item.Start.Before(t) {
+			earliest[item.Currency] = item.Start
+		}
+		if item.End.After(end) {
+			end = item.End
+		}
+	}
+	if len(earliest) == 0 {
+		return nil
+	}
+
+	converters, err := NewCurrencyService(s.db).BaseConverters(ctx, tenantID, earliest, end)
+	if err != nil {
+		return fmt.Errorf("failed to convert billing currency: %w", err)
+	}
+	for i := range items {
+		if converter, ok := converters[items[i].Currency]; ok {
+			items[i].ListCost = converter.ToBase(items[i].ListCost, items[i].Start)
+			items[i].NetCost = converter.ToBase(items[i].NetCost, items[i].Start)
+		}
+	}
+	return nil
+}
+
+// loadNodes returns the tenant's nodes reporting metrics during the billing period
+func (s *BillingReconciliationService) loadNodes(ctx context.Context, tenantID uint, start, end time.Time) ([]billingNode, error) {
+	if s.pool == nil {
+		return nil, fmt.Errorf("metrics database not available")
+	}
+	rows, err := s.pool.Query(ctx, `
+		SELECT DISTINCT cluster_name, node_name, COALESCE(provider_id, '')
+		FROM node_metrics
+		WHERE tenant_id = $1 AND time >= $2 AND time <= $3
+	`, int64(tenantID), start.Add(-24*time.Hour), end.Add(24*time.Hour))
+	if err != nil {
+		return nil, fmt.Errorf("node query failed: %w", err)
+	}
+	defer rows.Close()
+
+	var nodes []billingNode
+	for rows.Next() {
+		var n billingNode
+		if err := rows.Scan(&n.Cluster, &n.Node, &n.ProviderID); err != nil {
+			return nil, fmt.Errorf("node scan failed: %w", err)
+		}
+		nodes = append(nodes, n)
+	}
+	return nodes, rows.Err()
+}
+
+// billingNodeIndex maps billing resource identifiers to nodes
+type billingNodeIndex map[string]billingNode
+
+// newBillingNodeIndex indexes nodes by provider ID (with and without its scheme), by
+// instance ID or name (the last provider ID segment, except for Azure where that is a
+// VM scale set index) and by node name
+func newBillingNodeIndex(nodes []billingNode) billingNodeIndex {
+	index := make(billingNodeIndex)
+	add := func(key string, n billingNode) {
+		if key = strings.ToLower(strings.Trim(key, "/")); key != "" {
+			if _, exists := index[key]; !exists {
+				index[key] = n
+			}
+		}
+	}
+
+	for _, n := range nodes {
+		add(n.Node, n)
+		if n.ProviderID == "" {
+			continue
+		}
+		add(n.ProviderID, n)
+		scheme, path, hasScheme := strings.Cut(n.ProviderID, "://")
+		if !hasScheme {
+			continue
+		}
+		add(path, n)
+		if scheme != "azure" {
+			add(path[strings.LastIndex(path, "/")+1:], n)
+		}
+	}
+	return index
+}
+
+// match finds the node for a billing resource ID, trying the full ID then its last segment
+func (idx billingNodeIndex) match(resourceID string) (billingNode, bool) {
+	key := strings.ToLower(strings.Trim(resourceID, "/"))
+	if key == "" {
+		return billingNode{}, false
+	}
+	if n, ok := idx[key]; ok {
+		return n, true
+	}
+	if !strings.HasPrefix(key, "subscriptions/") {
+		if n, ok := idx[key[strings.LastIndex(key, "/")+1:]]; ok {
+			return n, true
+		}
+	}
+	return billingNode{}, false
+}
+
+// clipDuration returns the part of [start, end) inside [from, to)
+func clipDuration(start, end, from, to time.Time) time.Duration {
+	if start.Before(from) {
+		start = from
+	}
+	if end.After(to) {
+		end = to
+	}
+	if !end.After(start) {
+		return 0
+	}
+	return end.Sub(start)
+}
+
+// ListImports lists a tenant's billing imports, newest first
+func (s *BillingReconciliationService) ListImports(ctx context.Context, tenantID uint) ([]models.BillingImport, error) {
+	var imports []models.BillingImport
+	err := s.db.WithContext(ctx).
+		Where("tenant_id = ?", tenantID).
+		Order("created_at DESC").
+		Find(&imports).Error
+	return imports, err
+}
+
+// GetImport retrieves a billing import
+func (s *BillingReconciliationService) GetImport(ctx context.Context, importID uint) (*models.BillingImport, error) {
+	var record models.BillingImport
+	if err := s.db.WithContext(ctx).First(&record, importID).Error; err != nil {
+		return nil, err
+	}
+	return &record, nil
+}
+
+// DeleteImport deletes a billing import and the node costs it reconciled
+func (s *BillingReconciliationService) DeleteImport(ctx context.Context, importID uint) error {
+	return s.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := tx.Where("import_id = ?", importID).Delete(&models.ReconciledNodeCost{}).Error; err != nil {
+			return err
+		}
+		return tx.Delete(&models.BillingImport{}, importID).Error
+	})
+}
+
+// ListReconciledNodeCosts returns the reconciled daily node costs overlapping a time range
+func (s *BillingReconciliationService) ListReconciledNodeCosts(ctx context.Context, tenantID uint, start, end time.Time) ([]models.ReconciledNodeCost, error) {
+	var costs []models.ReconciledNodeCost
+	err := s.db.WithContext(ctx).
+		Where("tenant_id = ? AND period_start < ? AND period_end > ?", tenantID, end, start).
+		Order("period_start ASC, cluster_name ASC, node_name ASC").
+		Find(&costs).Error
+	return costs, err
+}
@@ -0,0 +1,317 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"math"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/bugfreev587/k8s-cost-api-server/internal/models"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// BudgetService manages budgets, evaluates their spend and forecast and fires threshold alerts
+type BudgetService struct {
+	db     *gorm.DB
+	alloc  *AllocationService
+	notify *NotificationService
+}
+
+// NewBudgetService creates a budget service computing spend with the allocation service
+// and notifying fired alerts (notify may be nil)
+func NewBudgetService(db *gorm.DB, alloc *AllocationService, notify *NotificationService) *BudgetService {
+	return &BudgetService{db: db, alloc: alloc, notify: notify}
+}
+
+// ValidateBudget checks a budget before it is saved and applies the default thresholds
+func ValidateBudget(b *models.Budget) error {
+	if strings.TrimSpace(b.Name) == "" {
+		return fmt.Errorf("name required")
+	}
+	if b.Period != models.BudgetPeriodMonthly && b.Period != models.BudgetPeriodQuarterly {
+		return fmt.Errorf("invalid period: %s (expected monthly or quarterly)", b.Period)
+	}
+	if b.Amount <= 0 {
+		return fmt.Errorf("amount must be positive")
+	}
+	if _, err := ParseFilters(b.Filters); err != nil {
+		return err
+	}
+	if len(b.Thresholds) == 0 {
+		b.Thresholds = append(b.Thresholds, models.DefaultBudgetThresholds...)
+	}
+	for _, t := range b.Thresholds {
+		if t <= 0 || t > 1000 {
+			return fmt.Errorf("thresholds must be percentages between 0 and 1000")
+		}
+	}
+	sort.Float64s(b.Thresholds)
+	return nil
+}
+
+// ListBudgets lists a tenant's budgets with their last evaluated spend
+func (s *BudgetService) ListBudgets(ctx context.Context, tenantID uint) ([]models.Budget, error) {
+	var budgets []models.Budget
+	err := s.db.WithContext(ctx).Where("tenant_id = ?", tenantID).Order("name").Find(&budgets).Error
+	return budgets, err
+}
+
+// GetBudget retrieves a budget by ID
+func (s *BudgetService) GetBudget(ctx context.Context, id uint) (*models.Budget, error) {
+	var budget models.Budget
+	if err := s.db.WithContext(ctx).First(&budget, id).Error; err != nil {
+		return nil, err
+	}
+	return &budget, nil
+}
+
+// SaveBudget creates or updates a budget
+func (s *BudgetService) SaveBudget(ctx context.Context, budget *models.Budget) error {
+	return s.db.WithContext(ctx).Save(budget).Error
+}
+
+// DeleteBudget deletes a budget and its alerts
+func (s *BudgetService) DeleteBudget(ctx context.Context, id uint) error {
+	return s.db.WithContext(ctx).Delete(&models.Budget{}, id).Error
+}
+
+// ListAlerts lists a budget's alerts (all of the tenant's budgets when budgetID is 0), newest first
+func (s *BudgetService) ListAlerts(ctx context.Context, tenantID, budgetID uint, limit int) ([]models.BudgetAlert, error) {
+	query := s.db.WithContext(ctx).Where("tenant_id = ?", tenantID)
+	if budgetID != 0 {
+		query = query.Where("budget_id = ?", budgetID)
+	}
+	if limit <= 0 {
+		limit = 100
+	}
+	var alerts []models.BudgetAlert
+	err := query.Order("created_at DESC, id DESC").Limit(limit).Find(&alerts).Error
+	return alerts, err
+}
+
+// BudgetBurnPoint is one day of a budget's burn-down: the cumulative spend (past days) or
+// projected spend (today onwards) against an even burn of the amount over the period
+type BudgetBurnPoint struct {
+	Date     time.Time `json:"date"`
+	Budget   float64   `json:"budget"`
+	Actual   *float64  `json:"actual,omitempty"`
+	Forecast *float64  `json:"forecast,omitempty"`
+}
+
+// BudgetStatus is a budget's spend in its current period
+type BudgetStatus struct {
+	PeriodStart     time.Time         `json:"period_start"`
+	PeriodEnd       time.Time         `json:"period_end"`
+	Amount          float64           `json:"amount"`
+	Currency        string            `json:"currency"`
+	Spent           float64           `json:"spent"`
+	PercentSpent    float64           `json:"percent_spent"`
+	Remaining       float64           `json:"remaining"`
+	Forecast        float64           `json:"forecast"` // projected period-end spend
+	ForecastLower   float64           `json:"forecast_lower"`
+	ForecastUpper   float64           `json:"forecast_upper"`
+	PercentForecast float64           `json:"percent_forecast"`
+	ForecastMethod  string            `json:"forecast_method"`
+	BurnDown        []BudgetBurnPoint `json:"burn_down"`
+}
+
+// budgetPeriod returns the bounds of the budget period containing now
+func budgetPeriod(period string, now time.Time) (time.Time, time.Time) {
+	if period == models.BudgetPeriodQuarterly {
+		return quarterBounds(truncateDay(now))
+	}
+	return monthBounds(truncateDay(now))
+}
+
+// Status computes a budget's spend so far in the current period, its projected period-end
+// spend and its daily burn-down
+func (s *BudgetService) Status(ctx context.Context, budget *models.Budget, now time.Time) (*BudgetStatus, error) {
+	periodStart, periodEnd := budgetPeriod(budget.Period, now)
+	today := truncateDay(now)
+
+	spentResp, err := s.alloc.GetAllocations(ctx, int64(budget.TenantID), AllocationParams{
+		Window:     periodStart.Format(time.RFC3339) + "," + now.UTC().Format(time.RFC3339),
+		Aggregate:  "cluster",
+		Accumulate: "true",
+		Filters:    budget.Filters,
+		Currency:   budget.Currency,
+	})
+	if err != nil {
+		return nil, err
+	}
+	var spent float64
+	for _, set := range spentResp.Data {
+		spent += set.TotalCost
+	}
+
+	horizon := int(periodEnd.Sub(today).Hours() / 24)
+	if horizon < 1 {
+		horizon = 1
+	}
+	report, err := s.alloc.Forecast(ctx, int64(budget.TenantID), ForecastParams{
+		Aggregate: "cluster",
+		Filters:   budget.Filters,
+		Currency:  budget.Currency,
+		Horizon:   horizon,
+		Now:       now,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	status := &BudgetStatus{
+		PeriodStart:    periodStart,
+		PeriodEnd:      periodEnd,
+		Amount:         budget.Amount,
+		Currency:       budget.Currency,
+		Spent:          spent,
+		Remaining:      budget.Amount - spent,
+		Forecast:       spent,
+		ForecastLower:  spent,
+		ForecastUpper:  spent,
+		ForecastMethod: report.Total.Method,
+	}
+	projectionPeriod := "month"
+	if budget.Period == models.BudgetPeriodQuarterly {
+		projectionPeriod = "quarter"
+	}
+	for _, p := range report.Total.Projections {
+		if p.Period == projectionPeriod {
+			// The projection covers complete days; today's partial spend is already in spent
+			status.Forecast = math.Max(p.Expected, spent)
+			status.ForecastLower = math.Max(p.Lower, spent)
+			status.ForecastUpper = math.Max(p.Upper, spent)
+		}
+	}
+	status.PercentSpent = spent / budget.Amount * 100
+	status.PercentForecast = status.Forecast / budget.Amount * 100
+	status.BurnDown = budgetBurnDown(budget.Amount, periodStart, periodEnd, report.Total.History, report.Total.Forecast)
+	return status, nil
+}
+
+// budgetBurnDown builds the daily cumulative spend of a period from the daily history and
+// forecast, next to an even burn of the amount
+func budgetBurnDown(amount float64, periodStart, periodEnd time.Time, history []CostPoint, forecast []ForecastPoint) []BudgetBurnPoint {
+	days := int(periodEnd.Sub(periodStart).Hours() / 24)
+	points := make([]BudgetBurnPoint, 0, days)
+	var cumulative float64
+	for i := 0; i < days; i++ {
+		date := periodStart.AddDate(0, 0, i)
+		point := BudgetBurnPoint{Date: date, Budget: amount * float64(i+1) / float64(days)}
+		for _, h := range history {
+			if h.Date.Equal(date) {
+				cumulative += h.Cost
+				actual := cumulative
+				point.Actual = &actual
+			}
+		}
+		for _, f := range forecast {
+			if f.Date.Equal(date) {
+				cumulative += f.Expected
+				projected := cumulative
+				point.Forecast = &projected
+			}
+		}
+		points = append(points, point)
+	}
+	return points
+}
+
+// budgetAlertsDue returns the alerts a budget's spend and forecast call for in a period:
+// one per threshold reached by the actual spend, and a forecast alert when the projected
+// period-end spend exceeds the budget before the spend does
+func budgetAlertsDue(budget *models.Budget, periodStart time.Time, spent, forecast float64) []models.BudgetAlert {
+	alert := func(alertType string, threshold float64) models.BudgetAlert {
+		return models.BudgetAlert{
+			TenantID:    budget.TenantID,
+			BudgetID:    budget.ID,
+			PeriodStart: periodStart,
+			Type:        alertType,
+			Threshold:   threshold,
+			Amount:      budget.Amount,
+			Spent:       spent,
+			Forecast:    forecast,
+			Currency:    budget.Currency,
+		}
+	}
+
+	var due []models.BudgetAlert
+	for _, threshold := range budget.Thresholds {
+		if spent >= budget.Amount*threshold/100 {
+			due = append(due, alert(models.BudgetAlertActual, threshold))
+		}
+	}
+	if budget.AlertOnForecast && forecast > budget.Amount && spent < budget.Amount {
+		due = append(due, alert(models.BudgetAlertForecast, 100))
+	}
+	return due
+}
+
+// Evaluate computes a budget's current status, records it on the budget and fires the
+// alerts due that have not fired yet in the period; it returns the alerts fired
+func (s *BudgetService) Evaluate(ctx context.Context, budget *models.Budget, now time.Time) ([]models.BudgetAlert, error) {
+	status, err := s.Status(ctx, budget, now)
+	if err != nil {
+		return nil, err
+	}
+
+	evaluatedAt := now
+	err = s.db.WithContext(ctx).Model(budget).Updates(map[string]interface{}{
+		"last_spent":        status.Spent,
+		"last_forecast":     status.Forecast,
+		"last_evaluated_at": evaluatedAt,
+	}).Error
+	if err != nil {
+		return nil, err
+	}
+
+	var fired []models.BudgetAlert
+	for _, alert := range budgetAlertsDue(budget, status.PeriodStart, status.Spent, status.Forecast) {
+		result := s.db.WithContext(ctx).Clauses(clause.OnConflict{DoNothing: true}).Create(&alert)
+		if result.Error != nil {
+			return fired, result.Error
+		}
+		if result.RowsAffected > 0 {
+			log.Printf("budget %q (tenant %d): %s alert at %.0f%% - spent %.2f, forecast %.2f of %.2f %s",
+				budget.Name, budget.TenantID, alert.Type, alert.Threshold, alert.Spent, alert.Forecast, alert.Amount, alert.Currency)
+			fired = append(fired, alert)
+			s.notify.Notify(budget.TenantID, s.notify.BudgetAlertMessage(budget, &alert))
+		}
+	}
+	return fired, nil
+}
+
+// EvaluateAll evaluates every budget
+func (s *BudgetService) EvaluateAll(ctx context.Context, now time.Time) error {
+	var budgets []models.Budget
+	if err := s.db.WithContext(ctx).Order("id").Find(&budgets).Error; err != nil {
+		return err
+	}
+	for i := range budgets {
+		if _, err := s.Evaluate(ctx, &budgets[i], now); err != nil {
+			log.Printf("budget evaluation failed for budget %d (tenant %d): %v", budgets[i].ID, budgets[i].TenantID, err)
+		}
+	}
+	return nil
+}
+
+// Run evaluates every budget at each interval until ctx is cancelled
+func (s *BudgetService) Run(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		if err := s.EvaluateAll(ctx, time.Now()); err != nil {
+			log.Printf("budget evaluation failed: %v", err)
+		}
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
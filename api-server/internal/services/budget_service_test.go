@@ -0,0 +1,96 @@
+package services
+
+import (
+	"testing"
+	"time"
+
+	"github.com/bugfreev587/k8s-cost-api-server/internal/models"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestValidateBudget(t *testing.T) {
+	budget := &models.Budget{
+		Name:    "platform",
+		Period:  models.BudgetPeriodMonthly,
+		Amount:  1000,
+		Filters: []string{"cluster:prod", "label:team=platform"},
+	}
+	require.NoError(t, ValidateBudget(budget))
+	assert.Equal(t, []float64{50, 80, 100}, []float64(budget.Thresholds))
+
+	budget.Thresholds = []float64{120, 90}
+	require.NoError(t, ValidateBudget(budget))
+	assert.Equal(t, []float64{90, 120}, []float64(budget.Thresholds))
+
+	invalid := []models.Budget{
+		{Name: "", Period: models.BudgetPeriodMonthly, Amount: 1},
+		{Name: "x", Period: "yearly", Amount: 1},
+		{Name: "x", Period: models.BudgetPeriodQuarterly, Amount: 0},
+		{Name: "x", Period: models.BudgetPeriodMonthly, Amount: 1, Filters: []string{"team:data"}},
+		{Name: "x", Period: models.BudgetPeriodMonthly, Amount: 1, Filters: []string{"label:team"}},
+		{Name: "x", Period: models.BudgetPeriodMonthly, Amount: 1, Thresholds: []float64{-5}},
+	}
+	for _, b := range invalid {
+		b := b
+		assert.Error(t, ValidateBudget(&b), "%+v", b)
+	}
+}
+
+func TestBudgetPeriod(t *testing.T) {
+	now := time.Date(2024, 5, 17, 13, 0, 0, 0, time.UTC)
+
+	start, end := budgetPeriod(models.BudgetPeriodMonthly, now)
+	assert.Equal(t, time.Date(2024, 5, 1, 0, 0, 0, 0, time.UTC), start)
+	assert.Equal(t, time.Date(2024, 6, 1, 0, 0, 0, 0, time.UTC), end)
+
+	start, end = budgetPeriod(models.BudgetPeriodQuarterly, now)
+	assert.Equal(t, time.Date(2024, 4, 1, 0, 0, 0, 0, time.UTC), start)
+	assert.Equal(t, time.Date(2024, 7, 1, 0, 0, 0, 0, time.UTC), end)
+}
+
+func TestBudgetAlertsDue(t *testing.T) {
+	budget := &models.Budget{ID: 7, TenantID: 3, Amount: 1000, Thresholds: []float64{50, 80, 100}, AlertOnForecast: true}
+	periodStart := time.Date(2024, 5, 1, 0, 0, 0, 0, time.UTC)
+
+	due := budgetAlertsDue(budget, periodStart, 850, 1200)
+	require.Len(t, due, 3)
+	assert.Equal(t, models.BudgetAlertActual, due[0].Type)
+	assert.Equal(t, 50.0, due[0].Threshold)
+	assert.Equal(t, 80.0, due[1].Threshold)
+	assert.Equal(t, models.BudgetAlertForecast, due[2].Type)
+	assert.Equal(t, uint(7), due[2].BudgetID)
+
+	// Overrun already reached: no forecast alert
+	due = budgetAlertsDue(budget, periodStart, 1100, 1500)
+	require.Len(t, due, 3)
+	for _, alert := range due {
+		assert.Equal(t, models.BudgetAlertActual, alert.Type)
+	}
+
+	budget.AlertOnForecast = false
+	assert.Empty(t, budgetAlertsDue(budget, periodStart, 100, 2000))
+}
+
+func TestBudgetBurnDown(t *testing.T) {
+	start := time.Date(2024, 2, 1, 0, 0, 0, 0, time.UTC)
+	end := time.Date(2024, 3, 1, 0, 0, 0, 0, time.UTC)
+	history := []CostPoint{
+		{Date: start, Cost: 10},
+		{Date: start.AddDate(0, 0, 1), Cost: 20},
+	}
+	forecast := []ForecastPoint{
+		{Date: start.AddDate(0, 0, 2), Expected: 15},
+	}
+
+	points := budgetBurnDown(290, start, end, history, forecast)
+	require.Len(t, points, 29)
+	assert.InDelta(t, 10, points[0].Budget, 1e-9)
+	assert.InDelta(t, 290, points[28].Budget, 1e-9)
+	require.NotNil(t, points[1].Actual)
+	assert.Equal(t, 30.0, *points[1].Actual)
+	require.NotNil(t, points[2].Forecast)
+	assert.Equal(t, 45.0, *points[2].Forecast)
+	assert.Nil(t, points[2].Actual)
+	assert.Nil(t, points[3].Forecast)
+}
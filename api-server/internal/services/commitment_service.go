@@ -0,0 +1,170 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/bugfreev587/k8s-cost-api-server/internal/models"
+	"github.com/jackc/pgx/v5/pgxpool"
+	"gorm.io/gorm"
+)
+
+// CommitmentService manages reserved instances, savings plans and committed use discounts
+// and reports their utilization
+type CommitmentService struct {
+	db    *gorm.DB
+	alloc *AllocationService
+}
+
+// NewCommitmentService creates a new commitment service
+func NewCommitmentService(pool *pgxpool.Pool, db *gorm.DB) *CommitmentService {
+	return NewCommitmentServiceWithAllocation(db, NewAllocationServiceWithPricing(pool, db))
+}
+
+// NewCommitmentServiceWithAllocation creates a commitment service that measures utilization
+// with an existing allocation service
+func NewCommitmentServiceWithAllocation(db *gorm.DB, alloc *AllocationService) *CommitmentService {
+	return &CommitmentService{
+		db:    db,
+		alloc: alloc,
+	}
+}
+
+// ValidateCommitment checks a commitment and derives its end date from the term
+func ValidateCommitment(c *models.Commitment) error {
+	switch c.Type {
+	case models.CommitmentReservedInstance, models.CommitmentSavingsPlan, models.CommitmentCommittedUse:
+	default:
+		return fmt.Errorf("invalid commitment type: %s", c.Type)
+	}
+	switch c.Term {
+	case models.TierReserved1Yr, models.TierReserved3Yr:
+	default:
+		return fmt.Errorf("invalid term: %s (expected %s or %s)", c.Term, models.TierReserved1Yr, models.TierReserved3Yr)
+	}
+	if c.StartDate.IsZero() {
+		return fmt.Errorf("start_date is required")
+	}
+	if c.UpfrontCost < 0 || c.HourlyCommitment < 0 {
+		return fmt.Errorf("upfront_cost and hourly_commitment must not be negative")
+	}
+	if c.UpfrontCost == 0 && c.HourlyCommitment == 0 {
+		return fmt.Errorf("either upfront_cost or hourly_commitment is required")
+	}
+	if c.DiscountPercent < 0 || c.DiscountPercent >= 100 {
+		return fmt.Errorf("discount_percent must be between 0 and 100")
+	}
+	c.EndDate = c.StartDate.AddDate(c.TermYears(), 0, 0)
+	return nil
+}
+
+// ListCommitments lists a tenant's commitments
+func (s *CommitmentService) ListCommitments(ctx context.Context, tenantID uint) ([]models.Commitment, error) {
+	var commitments []models.Commitment
+	err := s.db.WithContext(ctx).
+		Where("tenant_id = ?", tenantID).
+		Order("start_date ASC, id ASC").
+		Find(&commitments).Error
+	return commitments, err
+}
+
+// GetCommitment retrieves a commitment
+func (s *CommitmentService) GetCommitment(ctx context.Context, commitmentID uint) (*models.Commitment, error) {
+	var commitment models.Commitment
+	if err := s.db.WithContext(ctx).First(&commitment, commitmentID).Error; err != nil {
+		return nil, err
+	}
+	return &commitment, nil
+}
+
+// SaveCommitment creates or updates a commitment
+func (s *CommitmentService) SaveCommitment(ctx context.Context, commitment *models.Commitment) error {
+	return s.db.WithContext(ctx).Save(commitment).Error
+}
+
+// DeleteCommitment deletes a commitment
+func (s *CommitmentService) DeleteCommitment(ctx context.Context, commitmentID uint) error {
+	return s.db.WithContext(ctx).Delete(&models.Commitment{}, commitmentID).Error
+}
+
+// CommitmentUtilizationReport summarizes commitment usage over a window
+type CommitmentUtilizationReport struct {
+	Window              TimeWindow        `json:"window"`
+	Commitments         []CommitmentUsage `json:"commitments"`
+	AmortizedCost       float64           `json:"amortizedCost"`
+	CoveredOnDemandCost float64           `json:"coveredOnDemandCost"`
+	WastedCost          float64           `json:"wastedCost"`
+	Savings             float64           `json:"savings"`
+	Utilization         float64           `json:"utilization"` // amortized-cost weighted, 0-1
+}
+
+// GetUtilization reports each commitment's utilization, savings and wasted (unused)
+// commitment over the window, evaluated day by day against the tenant's nodes
+func (s *CommitmentService) GetUtilization(ctx context.Context, tenantID uint, window string) (*CommitmentUtilizationReport, error) {
+	start, end, err := s.alloc.parseWindow(window)
+	if err != nil {
+		return nil, fmt.Errorf("invalid window: %w", err)
+	}
+
+	report := &CommitmentUtilizationReport{Window: TimeWindow{Start: start, End: end}}
+	commitments, err := s.alloc.loadActiveCommitments(ctx, int64(tenantID), start, end)
+	if err != nil || len(commitments) == 0 {
+		return report, err
+	}
+
+	totals := make(map[uint]*CommitmentUsage)
+	var order []uint
+	for _, step := range s.alloc.calculateSteps(start, end, "1d", "false") {
+		nodes, err := s.alloc.loadNodeInventory(ctx, int64(tenantID), step.Start, step.End)
+		if err != nil {
+			return nil, err
+		}
+		eligible := make([]commitmentNode, 0, len(nodes))
+		for _, n := range nodes {
+			eligible = append(eligible, commitmentNode{Key: nodeKey(n.Cluster, n.Node), Cluster: n.Cluster, InstanceType: n.InstanceType, OnDemandHourly: n.ModeledHourly})
+		}
+
+		_, usage := applyCommitments(eligible, commitments, step.Start, step.End, s.alloc.commitmentDiscountFunc(ctx, int64(tenantID), step.Start))
+		for _, u := range usage {
+			total, ok := totals[u.CommitmentID]
+			if !ok {
+				total = &CommitmentUsage{CommitmentID: u.CommitmentID, Name: u.Name, Type: u.Type, Discount: u.Discount}
+				totals[u.CommitmentID] = total
+				order = append(order, u.CommitmentID)
+			}
+			total.Hours += u.Hours
+			total.AmortizedCost += u.AmortizedCost
+			total.CoveredOnDemandCost += u.CoveredOnDemandCost
+			total.WastedCost += u.WastedCost
+			total.Savings += u.Savings
+		}
+	}
+
+	for _, id := range order {
+		u := totals[id]
+		if u.AmortizedCost > 0 {
+			u.Utilization = 1 - u.WastedCost/u.AmortizedCost
+		}
+		report.Commitments = append(report.Commitments, *u)
+		report.AmortizedCost += u.AmortizedCost
+		report.CoveredOnDemandCost += u.CoveredOnDemandCost
+		report.WastedCost += u.WastedCost
+		report.Savings += u.Savings
+	}
+	if report.AmortizedCost > 0 {
+		report.Utilization = 1 - report.WastedCost/report.AmortizedCost
+	}
+	return report, nil
+}
+
+// commitmentDateLayout is the date format accepted for commitment start dates
+const commitmentDateLayout = "2006-01-02"
+
+// ParseCommitmentDate parses a commitment start date (YYYY-MM-DD or RFC3339)
+func ParseCommitmentDate(value string) (time.Time, error) {
+	if t, err := time.Parse(commitmentDateLayout, value); err == nil {
+		return t, nil
+	}
+	return time.Parse(time.RFC3339, value)
+}
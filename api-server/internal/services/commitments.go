@@ -0,0 +1,172 @@
+package services
+
+import (
+	"sort"
+	"time"
+
+	"github.com/bugfreev587/k8s-cost-api-server/internal/models"
+)
+
+// commitmentNode is a node eligible for commitment coverage, with its on-demand hourly cost
+type commitmentNode struct {
+	Key            string
+	Cluster        string
+	InstanceType   string
+	OnDemandHourly float64
+}
+
+// CommitmentUsage reports how much of a commitment was used over a time range
+type CommitmentUsage struct {
+	CommitmentID        uint    `json:"commitmentId"`
+	Name                string  `json:"name"`
+	Type                string  `json:"type"`
+	Hours               float64 `json:"hours"`
+	AmortizedCost       float64 `json:"amortizedCost"`       // upfront and recurring cost for the range
+	CoveredOnDemandCost float64 `json:"coveredOnDemandCost"` // on-demand value of the usage covered
+	WastedCost          float64 `json:"wastedCost"`          // amortized cost of unused commitment
+	Savings             float64 `json:"savings"`             // covered on-demand value minus amortized cost
+	Utilization         float64 `json:"utilization"`         // 0-1
+	Discount            float64 `json:"discount"`            // 0-1 versus on-demand
+}
+
+// commitmentPriority orders commitment types the way providers apply them: reserved
+// instances and committed use discounts first, then savings plans
+func commitmentPriority(commitmentType string) int {
+	switch commitmentType {
+	case models.CommitmentReservedInstance, models.CommitmentCommittedUse:
+		return 0
+	default:
+		return 1
+	}
+}
+
+// applyCommitments spreads each commitment's coverage over the matching nodes for the
+// window [start, end) and returns the hourly cost reduction per node key along with each
+// commitment's usage.
+//
+// A commitment with amortized hourly cost A and discount d covers up to A/(1-d) of
+// on-demand spend per hour. Coverage is shared across matching nodes in proportion to
+// their uncovered on-demand cost, and each covered dollar is reduced by d. The window is
+// split where commitments start or end, so a commitment only covers usage, and only
+// reduces costs, for the part of the window it is active.
+func applyCommitments(nodes []commitmentNode, commitments []models.Commitment, start, end time.Time, discountFor func(*models.Commitment) float64) (map[string]float64, []CommitmentUsage) {
+	ordered := make([]models.Commitment, 0, len(commitments))
+	for _, c := range commitments {
+		if clipDuration(start, end, c.StartDate, c.EndDate) > 0 {
+			ordered = append(ordered, c)
+		}
+	}
+	sort.SliceStable(ordered, func(i, j int) bool {
+		return commitmentPriority(ordered[i].Type) < commitmentPriority(ordered[j].Type)
+	})
+
+	usage := make([]CommitmentUsage, len(ordered))
+	capacities := make([]float64, len(ordered))
+	bounds := []time.Time{start, end}
+	for i := range ordered {
+		c := &ordered[i]
+		hours := clipDuration(start, end, c.StartDate, c.EndDate).Hours()
+		discount := discountFor(c)
+		amortized := c.AmortizedHourlyCost()
+		usage[i] = CommitmentUsage{
+			CommitmentID:  c.ID,
+			Name:          c.Name,
+			Type:          c.Type,
+			Hours:         hours,
+			AmortizedCost: amortized * hours,
+			Discount:      discount,
+		}
+		if amortized > 0 && discount > 0 && discount < 1 {
+			capacities[i] = amortized / (1 - discount)
+		}
+		for _, t := range []time.Time{c.StartDate, c.EndDate} {
+			if t.After(start) && t.Before(end) {
+				bounds = append(bounds, t)
+			}
+		}
+	}
+	sort.Slice(bounds, func(i, j int) bool { return bounds[i].Before(bounds[j]) })
+
+	windowHours := end.Sub(start).Hours()
+	reductions := make(map[string]float64)
+	remaining := make(map[string]float64, len(nodes))
+	for b := 0; b+1 < len(bounds); b++ {
+		segStart, segEnd := bounds[b], bounds[b+1]
+		segHours := segEnd.Sub(segStart).Hours()
+		if segHours <= 0 {
+			continue
+		}
+		fraction := segHours / windowHours
+
+		for _, n := range nodes {
+			remaining[n.Key] = n.OnDemandHourly
+		}
+		for i := range ordered {
+			c := &ordered[i]
+			if capacities[i] <= 0 || c.StartDate.After(segStart) || !c.EndDate.After(segStart) {
+				continue
+			}
+
+			var eligible []commitmentNode
+			var eligibleCost float64
+			for _, n := range nodes {
+				if remaining[n.Key] > 0 && commitmentCovers(c, n) {
+					eligible = append(eligible, n)
+					eligibleCost += remaining[n.Key]
+				}
+			}
+			if eligibleCost <= 0 {
+				continue
+			}
+
+			covered := eligibleCost
+			if covered > capacities[i] {
+				covered = capacities[i]
+			}
+			share := covered / eligibleCost
+			for _, n := range eligible {
+				nodeCovered := remaining[n.Key] * share
+				remaining[n.Key] -= nodeCovered
+				reductions[n.Key] += nodeCovered * usage[i].Discount * fraction
+			}
+			usage[i].CoveredOnDemandCost += covered * segHours
+		}
+	}
+
+	for i := range usage {
+		u := &usage[i]
+		if capacities[i] <= 0 {
+			continue
+		}
+		u.Utilization = u.CoveredOnDemandCost / (capacities[i] * u.Hours)
+		u.WastedCost = u.AmortizedCost * (1 - u.Utilization)
+		u.Savings = u.CoveredOnDemandCost - u.AmortizedCost
+	}
+	return reductions, usage
+}
+
+// commitmentCovers reports whether a commitment applies to a node
+func commitmentCovers(c *models.Commitment, n commitmentNode) bool {
+	if len(c.Clusters) > 0 && !containsString(c.Clusters, n.Cluster) {
+		return false
+	}
+	if len(c.InstanceFamilies) == 0 {
+		return true
+	}
+	for _, family := range c.InstanceFamilies {
+		if models.InstanceFamilyMatches(n.InstanceType, family) {
+			return true
+		}
+	}
+	return false
+}
+
+// containsString reports whether values contains s
+func containsString(values []string, s string) bool {
+	for _, v := range values {
+		if v == s {
+			return true
+		}
+	}
+	return false
+}
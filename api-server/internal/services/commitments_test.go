@@ -0,0 +1,130 @@
+package services
+
+import (
+	"testing"
+	"time"
+
+	"github.com/bugfreev587/k8s-cost-api-server/internal/models"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestApplyCommitments_PartialCoverageAndWaste(t *testing.T) {
+	start := time.Date(2024, 3, 1, 0, 0, 0, 0, time.UTC)
+	end := start.Add(24 * time.Hour)
+	nodes := []commitmentNode{
+		{Key: "prod/a", Cluster: "prod", InstanceType: "m5.xlarge", OnDemandHourly: 1.0},
+		{Key: "prod/b", Cluster: "prod", InstanceType: "m5.large", OnDemandHourly: 0.5},
+		{Key: "prod/c", Cluster: "prod", InstanceType: "c5.large", OnDemandHourly: 0.5},
+	}
+	commitments := []models.Commitment{
+		// Covers $1/h of m5 on-demand spend at 40% off: $0.60/h amortized
+		{ID: 1, Name: "m5-ri", Type: models.CommitmentReservedInstance, HourlyCommitment: 0.6, InstanceFamilies: []string{"m5"},
+			StartDate: start.AddDate(0, -1, 0), EndDate: start.AddDate(1, -1, 0)},
+		// $0.60/h savings plan at 40% off covers $1/h, but only $0.5/h (c5) plus the uncovered m5 remain
+		{ID: 2, Name: "sp", Type: models.CommitmentSavingsPlan, HourlyCommitment: 0.6,
+			StartDate: start.AddDate(0, -1, 0), EndDate: start.AddDate(1, -1, 0)},
+	}
+	discount := func(*models.Commitment) float64 { return 0.4 }
+
+	reductions, usage := applyCommitments(nodes, commitments, start, end, discount)
+
+	// The RI covers m5 nodes in proportion to their cost (2/3 and 1/3 of $1/h)
+	// The savings plan then covers the remaining $0.5/h m5 and $0.5/h c5 fully
+	assert.InDelta(t, 0.4, reductions["prod/a"], 1e-9)
+	assert.InDelta(t, 0.2, reductions["prod/b"], 1e-9)
+	assert.InDelta(t, 0.2, reductions["prod/c"], 1e-9)
+
+	if assert.Len(t, usage, 2) {
+		assert.InDelta(t, 1.0, usage[0].Utilization, 1e-9)
+		assert.Zero(t, usage[0].WastedCost)
+		assert.InDelta(t, 1.0, usage[1].Utilization, 1e-9)
+		assert.InDelta(t, 24*(1.0-0.6), usage[1].Savings, 1e-9)
+	}
+}
+
+func TestApplyCommitments_UnusedCommitmentIsWasted(t *testing.T) {
+	start := time.Date(2024, 3, 1, 0, 0, 0, 0, time.UTC)
+	end := start.Add(10 * time.Hour)
+	nodes := []commitmentNode{{Key: "prod/a", Cluster: "prod", InstanceType: "n2-standard-4", OnDemandHourly: 0.5}}
+	commitments := []models.Commitment{
+		{ID: 1, Name: "cud", Type: models.CommitmentCommittedUse, HourlyCommitment: 0.6, InstanceFamilies: []string{"n2"},
+			StartDate: start, EndDate: start.AddDate(1, 0, 0)},
+	}
+
+	reductions, usage := applyCommitments(nodes, commitments, start, end, func(*models.Commitment) float64 { return 0.4 })
+
+	assert.InDelta(t, 0.2, reductions["prod/a"], 1e-9)
+	if assert.Len(t, usage, 1) {
+		assert.InDelta(t, 0.5, usage[0].Utilization, 1e-9)
+		assert.InDelta(t, 3.0, usage[0].WastedCost, 1e-9)
+	}
+}
+
+func TestApplyCommitments_StartsMidWindow(t *testing.T) {
+	start := time.Date(2024, 3, 1, 0, 0, 0, 0, time.UTC)
+	end := start.Add(24 * time.Hour)
+	mid := start.Add(12 * time.Hour)
+	nodes := []commitmentNode{{Key: "prod/a", Cluster: "prod", InstanceType: "m5.xlarge", OnDemandHourly: 1.0}}
+	discount := func(*models.Commitment) float64 { return 0.4 }
+
+	// A $0.60/h RI at 40% off covers the $1/h node, but only from the middle of the window
+	commitments := []models.Commitment{
+		{ID: 1, Name: "new-ri", Type: models.CommitmentReservedInstance, HourlyCommitment: 0.6, InstanceFamilies: []string{"m5"},
+			StartDate: mid, EndDate: mid.AddDate(1, 0, 0)},
+	}
+	reductions, usage := applyCommitments(nodes, commitments, start, end, discount)
+
+	assert.InDelta(t, 0.2, reductions["prod/a"], 1e-9)
+	if assert.Len(t, usage, 1) {
+		assert.InDelta(t, 12.0, usage[0].Hours, 1e-9)
+		assert.InDelta(t, 12.0, usage[0].CoveredOnDemandCost, 1e-9)
+		assert.InDelta(t, 1.0, usage[0].Utilization, 1e-9)
+		assert.Zero(t, usage[0].WastedCost)
+		assert.InDelta(t, 12-12*0.6, usage[0].Savings, 1e-9)
+	}
+
+	// An RI expiring mid-window and its renewal each cover their own half; a savings plan
+	// for the whole window finds nothing left to cover
+	commitments = []models.Commitment{
+		{ID: 1, Name: "old-ri", Type: models.CommitmentReservedInstance, HourlyCommitment: 0.6, InstanceFamilies: []string{"m5"},
+			StartDate: mid.AddDate(-1, 0, 0), EndDate: mid},
+		{ID: 2, Name: "new-ri", Type: models.CommitmentReservedInstance, HourlyCommitment: 0.6, InstanceFamilies: []string{"m5"},
+			StartDate: mid, EndDate: mid.AddDate(1, 0, 0)},
+		{ID: 3, Name: "sp", Type: models.CommitmentSavingsPlan, HourlyCommitment: 0.3,
+			StartDate: start.AddDate(0, -1, 0), EndDate: start.AddDate(1, -1, 0)},
+	}
+	reductions, usage = applyCommitments(nodes, commitments, start, end, discount)
+
+	assert.InDelta(t, 0.4, reductions["prod/a"], 1e-9)
+	if assert.Len(t, usage, 3) {
+		assert.InDelta(t, 1.0, usage[0].Utilization, 1e-9)
+		assert.InDelta(t, 1.0, usage[1].Utilization, 1e-9)
+		assert.Zero(t, usage[2].Utilization)
+		assert.InDelta(t, 24*0.3, usage[2].WastedCost, 1e-9)
+	}
+}
+
+func TestInstanceFamilyMatches(t *testing.T) {
+	assert.True(t, models.InstanceFamilyMatches("m5.xlarge", "m5"))
+	assert.False(t, models.InstanceFamilyMatches("m5a.xlarge", "m5"))
+	assert.True(t, models.InstanceFamilyMatches("n2-standard-4", "n2"))
+	assert.True(t, models.InstanceFamilyMatches("Standard_D4s_v3", "standard_d*"))
+}
+
+func TestFamilyPrice(t *testing.T) {
+	m5 := &models.InstancePrice{HourlyCost: 0.1}
+	m5Large := &models.InstancePrice{HourlyCost: 0.2}
+	azureD := &models.InstancePrice{HourlyCost: 0.3}
+	pricing := &models.EffectivePricing{InstancePricing: map[string]*models.InstancePrice{
+		"m5": m5, "m5.large": m5Large, "Standard_D*": azureD,
+	}}
+
+	assert.Same(t, m5, pricing.FamilyPrice("m5.xlarge"))
+	assert.Same(t, m5Large, pricing.FamilyPrice("M5.LARGE"))
+	assert.Same(t, azureD, pricing.FamilyPrice("Standard_D4s_v3"))
+	assert.Nil(t, pricing.FamilyPrice("m5a.xlarge"))
+
+	pricing.InstancePricing["m5*"] = azureD
+	assert.Same(t, m5, pricing.FamilyPrice("m5.xlarge"))
+	assert.Same(t, azureD, pricing.FamilyPrice("m5a.xlarge"))
+}
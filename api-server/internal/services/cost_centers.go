@@ -0,0 +1,284 @@
+package services
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/bugfreev587/k8s-cost-api-server/internal/models"
+)
+
+// MaxCostCenterDepth is the maximum depth of the cost center hierarchy
+const MaxCostCenterDepth = 8
+
+// CostCenterTree is a tenant's cost center hierarchy
+type CostCenterTree struct {
+	byID  map[uint]*models.CostCenter
+	depth map[uint]int // 1 for roots
+
+	// matchOrder lists the nodes with mapping rules in the order pods are matched against them
+	matchOrder []*models.CostCenter
+}
+
+// NewCostCenterTree builds the hierarchy of a tenant's cost centers, rejecting missing
+// parents, cycles and hierarchies deeper than MaxCostCenterDepth
+func NewCostCenterTree(centers []models.CostCenter) (*CostCenterTree, error) {
+	t := &CostCenterTree{
+		byID:  make(map[uint]*models.CostCenter, len(centers)),
+		depth: make(map[uint]int, len(centers)),
+	}
+	for i := range centers {
+		t.byID[centers[i].ID] = &centers[i]
+	}
+	for _, cc := range t.byID {
+		depth := 1
+		for node := cc; node.ParentID != nil; depth++ {
+			parent, ok := t.byID[*node.ParentID]
+			if !ok {
+				return nil, fmt.Errorf("cost center %q: parent %d not found", cc.Name, *node.ParentID)
+			}
+			if parent.ID == cc.ID || depth >= MaxCostCenterDepth {
+				return nil, fmt.Errorf("cost center %q: hierarchy has a cycle or is deeper than %d levels", cc.Name, MaxCostCenterDepth)
+			}
+			node = parent
+		}
+		t.depth[cc.ID] = depth
+		if cc.HasRules() {
+			t.matchOrder = append(t.matchOrder, cc)
+		}
+	}
+	sort.Slice(t.matchOrder, func(i, j int) bool {
+		a, b := t.matchOrder[i], t.matchOrder[j]
+		if a.Priority != b.Priority {
+			return a.Priority < b.Priority
+		}
+		if t.depth[a.ID] != t.depth[b.ID] {
+			return t.depth[a.ID] > t.depth[b.ID]
+		}
+		return a.ID < b.ID
+	})
+	return t, nil
+}
+
+// Get returns a cost center of the tree by ID
+func (t *CostCenterTree) Get(id uint) (*models.CostCenter, bool) {
+	cc, ok := t.byID[id]
+	return cc, ok
+}
+
+// Depth returns a cost center's depth in the hierarchy (1 for roots)
+func (t *CostCenterTree) Depth(cc *models.CostCenter) int {
+	return t.depth[cc.ID]
+}
+
+// Path returns the names of a cost center and its ancestors, root first
+func (t *CostCenterTree) Path(cc *models.CostCenter) []string {
+	path := make([]string, t.depth[cc.ID])
+	for i, node := len(path)-1, cc; i >= 0 && node != nil; i-- {
+		path[i] = node.Name
+		if node.ParentID == nil {
+			break
+		}
+		node = t.byID[*node.ParentID]
+	}
+	return path
+}
+
+// Match returns the cost center a pod maps to, or nil if no mapping rule matches it
+func (t *CostCenterTree) Match(cluster, namespace string, labels map[string]string) *models.CostCenter {
+	for _, cc := range t.matchOrder {
+		if costCenterMatches(cc, cluster, namespace, labels) {
+			return cc
+		}
+	}
+	return nil
+}
+
+// costCenterMatches reports whether a pod matches a cost center's own mapping rules
+func costCenterMatches(cc *models.CostCenter, cluster, namespace string, labels map[string]string) bool {
+	if !cc.HasRules() {
+		return false
+	}
+	if len(cc.Clusters) > 0 && !containsString(cc.Clusters, cluster) {
+		return false
+	}
+	if len(cc.Namespaces) == 0 && len(cc.Labels) == 0 {
+		return true
+	}
+	if containsString(cc.Namespaces, namespace) {
+		return true
+	}
+	for _, selector := range cc.Labels {
+		if labelSelectorMatches(selector, labels) {
+			return true
+		}
+	}
+	return false
+}
+
+// AtLevel returns the ancestor (or the node itself) a cost center is reported under for a
+// level: a level name ("department") selects the nearest node with that level, a number
+// the ancestor at that depth (1 = root). Nodes above the level, or without an ancestor of
+// that level, are reported as themselves; an empty level reports the node itself.
+func (t *CostCenterTree) AtLevel(cc *models.CostCenter, level string) *models.CostCenter {
+	if level == "" {
+		return cc
+	}
+	if depth, err := strconv.Atoi(level); err == nil {
+		node := cc
+		for t.depth[node.ID] > depth && node.ParentID != nil {
+			node = t.byID[*node.ParentID]
+		}
+		return node
+	}
+	for node := cc; node != nil; {
+		if strings.EqualFold(node.Level, level) {
+			return node
+		}
+		if node.ParentID == nil {
+			break
+		}
+		node = t.byID[*node.ParentID]
+	}
+	return cc
+}
+
+// costCenterExpr builds a SQL expression that evaluates to the name of the cost center a
+// pod_metrics row is reported under at a level ("__unallocated__" when no mapping rule
+// matches). Placeholders start at argIdx.
+func costCenterExpr(tree *CostCenterTree, level string, argIdx int) (string, []interface{}) {
+	var args []interface{}
+	var whens []string
+	for _, cc := range tree.matchOrder {
+		var conds []string
+		if len(cc.Clusters) > 0 {
+			conds = append(conds, fmt.Sprintf("cluster_name = ANY($%d)", argIdx))
+			args = append(args, []string(cc.Clusters))
+			argIdx++
+		}
+		var anyOf []string
+		if len(cc.Namespaces) > 0 {
+			anyOf = append(anyOf, fmt.Sprintf("namespace = ANY($%d)", argIdx))
+			args = append(args, []string(cc.Namespaces))
+			argIdx++
+		}
+		for _, selector := range cc.Labels {
+			key, value, hasValue := strings.Cut(selector, "=")
+			key = strings.TrimSpace(key)
+			if hasValue {
+				match, _ := json.Marshal(map[string]string{key: strings.TrimSpace(value)})
+				anyOf = append(anyOf, fmt.Sprintf("labels @> $%d::jsonb", argIdx))
+				args = append(args, string(match))
+			} else {
+				anyOf = append(anyOf, fmt.Sprintf("labels ? $%d", argIdx))
+				args = append(args, key)
+			}
+			argIdx++
+		}
+		if len(anyOf) > 0 {
+			conds = append(conds, "("+strings.Join(anyOf, " OR ")+")")
+		}
+		whens = append(whens, fmt.Sprintf("WHEN %s THEN $%d::text", strings.Join(conds, " AND "), argIdx))
+		args = append(args, tree.AtLevel(cc, level).Name)
+		argIdx++
+	}
+
+	if len(whens) == 0 {
+		return "'__unallocated__'", nil
+	}
+	return fmt.Sprintf("CASE %s ELSE '__unallocated__' END", strings.Join(whens, " ")), args
+}
+
+// resolveCostCenters loads the tenant's cost center hierarchy when the query aggregates
+// by cost center
+func (s *AllocationService) resolveCostCenters(ctx context.Context, tenantID int64, params AllocationParams) (*CostCenterTree, error) {
+	needed := false
+	for _, agg := range strings.Split(params.Aggregate, ",") {
+		if isCostCenterAggregate(strings.ToLower(strings.TrimSpace(agg))) {
+			needed = true
+		}
+	}
+	if !needed {
+		return nil, nil
+	}
+	if s.postgresDB == nil {
+		return nil, fmt.Errorf("cost center aggregation is not available")
+	}
+
+	var centers []models.CostCenter
+	if err := s.postgresDB.WithContext(ctx).Where("tenant_id = ?", tenantID).Find(&centers).Error; err != nil {
+		return nil, fmt.Errorf("failed to load cost centers: %w", err)
+	}
+	return NewCostCenterTree(centers)
+}
+
+// isCostCenterAggregate reports whether a (lowercased) aggregate is "costcenter" or
+// "costcenter:<level>"
+func isCostCenterAggregate(agg string) bool {
+	return agg == "costcenter" || strings.HasPrefix(agg, "costcenter:")
+}
+
+// CheckCostCenterParent verifies that a cost center's parent is one of the tenant's cost
+// centers and that saving it keeps the hierarchy a tree no deeper than MaxCostCenterDepth
+func CheckCostCenterParent(centers []models.CostCenter, cc *models.CostCenter) error {
+	if cc.ParentID == nil {
+		return nil
+	}
+	updated := make([]models.CostCenter, 0, len(centers)+1)
+	found := false
+	for _, c := range centers {
+		if c.ID == cc.ID {
+			continue
+		}
+		if c.ID == *cc.ParentID {
+			found = true
+		}
+		updated = append(updated, c)
+	}
+	if !found {
+		return fmt.Errorf("parent cost center %d not found", *cc.ParentID)
+	}
+	node := *cc
+	if node.ID == 0 {
+		node.ID = ^uint(0) // not saved yet
+	}
+	_, err := NewCostCenterTree(append(updated, node))
+	return err
+}
+
+// CostCenterNode is a cost center with its children, for displaying the hierarchy
+type CostCenterNode struct {
+	models.CostCenter
+	Path     string            `json:"path"`
+	Depth    int               `json:"depth"`
+	Children []*CostCenterNode `json:"children,omitempty"`
+}
+
+// Roots returns the hierarchy as nested nodes, siblings ordered by name
+func (t *CostCenterTree) Roots() []*CostCenterNode {
+	nodes := make(map[uint]*CostCenterNode, len(t.byID))
+	for id, cc := range t.byID {
+		nodes[id] = &CostCenterNode{CostCenter: *cc, Path: strings.Join(t.Path(cc), "/"), Depth: t.depth[id]}
+	}
+	var roots []*CostCenterNode
+	for _, node := range nodes {
+		if node.ParentID == nil {
+			roots = append(roots, node)
+		} else {
+			parent := nodes[*node.ParentID]
+			parent.Children = append(parent.Children, node)
+		}
+	}
+	var sortNodes func([]*CostCenterNode)
+	sortNodes = func(list []*CostCenterNode) {
+		sort.Slice(list, func(i, j int) bool { return list[i].Name < list[j].Name })
+		for _, n := range list {
+			sortNodes(n.Children)
+		}
+	}
+	sortNodes(roots)
+	return roots
+}
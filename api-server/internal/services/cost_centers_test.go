@@ -0,0 +1,140 @@
+package services
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/bugfreev587/k8s-cost-api-server/internal/models"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func uintPtr(v uint) *uint { return &v }
+
+// testCostCenters is acme (org) → payments (department) → checkout (team) → checkout-api
+// (service), plus a data department mapped by label and a catch-all for the prod cluster
+func testCostCenters() []models.CostCenter {
+	return []models.CostCenter{
+		{ID: 1, Name: "acme", Level: "org"},
+		{ID: 2, Name: "payments", Level: "department", ParentID: uintPtr(1), Namespaces: []string{"payments"}},
+		{ID: 3, Name: "checkout", Level: "team", ParentID: uintPtr(2), Namespaces: []string{"checkout"}},
+		{ID: 4, Name: "checkout-api", Level: "service", ParentID: uintPtr(3), Labels: []string{"app=checkout-api"}},
+		{ID: 5, Name: "data", Level: "department", ParentID: uintPtr(1), Labels: []string{"team=data", "squad=data"}},
+		{ID: 6, Name: "prod-shared", Level: "department", ParentID: uintPtr(1), Clusters: []string{"prod"}, Priority: 10},
+		{ID: 7, Name: "eu-checkout", Level: "team", ParentID: uintPtr(2), Clusters: []string{"eu"}, Namespaces: []string{"checkout"}, Priority: -1},
+	}
+}
+
+func TestCostCenterTree_Match(t *testing.T) {
+	tree, err := NewCostCenterTree(testCostCenters())
+	require.NoError(t, err)
+
+	name := func(cluster, namespace string, labels map[string]string) string {
+		if cc := tree.Match(cluster, namespace, labels); cc != nil {
+			return cc.Name
+		}
+		return "__unallocated__"
+	}
+	// Deeper nodes win at equal priority
+	assert.Equal(t, "checkout-api", name("us", "checkout", map[string]string{"app": "checkout-api"}))
+	assert.Equal(t, "checkout", name("us", "checkout", map[string]string{"app": "web"}))
+	assert.Equal(t, "payments", name("us", "payments", nil))
+	// Cluster-restricted rules only match in their clusters; eu-checkout goes first by priority
+	assert.Equal(t, "eu-checkout", name("eu", "checkout", nil))
+	assert.Equal(t, "checkout", name("us", "checkout", nil))
+	// Any label selector matches
+	assert.Equal(t, "data", name("us", "etl", map[string]string{"squad": "data"}))
+	// Lower priority numbers win: prod-shared (priority 10) only catches what nothing else maps
+	assert.Equal(t, "checkout", name("prod", "checkout", nil))
+	assert.Equal(t, "prod-shared", name("prod", "sandbox", nil))
+	assert.Equal(t, "__unallocated__", name("dev", "sandbox", nil))
+}
+
+func TestCostCenterTree_AtLevel(t *testing.T) {
+	tree, err := NewCostCenterTree(testCostCenters())
+	require.NoError(t, err)
+	api, _ := tree.Get(4)
+	payments, _ := tree.Get(2)
+
+	assert.Equal(t, []string{"acme", "payments", "checkout", "checkout-api"}, tree.Path(api))
+	assert.Equal(t, 4, tree.Depth(api))
+	assert.Equal(t, "checkout-api", tree.AtLevel(api, "").Name)
+	assert.Equal(t, "checkout", tree.AtLevel(api, "team").Name)
+	assert.Equal(t, "payments", tree.AtLevel(api, "Department").Name)
+	assert.Equal(t, "acme", tree.AtLevel(api, "1").Name)
+	assert.Equal(t, "payments", tree.AtLevel(api, "2").Name)
+	// Nodes above the level are reported as themselves
+	assert.Equal(t, "payments", tree.AtLevel(payments, "service").Name)
+	assert.Equal(t, "payments", tree.AtLevel(payments, "3").Name)
+}
+
+func TestCostCenterTree_Roots(t *testing.T) {
+	tree, err := NewCostCenterTree(testCostCenters())
+	require.NoError(t, err)
+
+	roots := tree.Roots()
+	require.Len(t, roots, 1)
+	assert.Equal(t, "acme", roots[0].Name)
+	require.Len(t, roots[0].Children, 3)
+	assert.Equal(t, "data", roots[0].Children[0].Name)
+	payments := roots[0].Children[1]
+	assert.Equal(t, "acme/payments", payments.Path)
+	assert.Equal(t, "acme/payments/checkout/checkout-api", payments.Children[0].Children[0].Path)
+}
+
+func TestNewCostCenterTree_RejectsInvalidHierarchies(t *testing.T) {
+	_, err := NewCostCenterTree([]models.CostCenter{{ID: 1, Name: "a", ParentID: uintPtr(9)}})
+	assert.Error(t, err)
+
+	_, err = NewCostCenterTree([]models.CostCenter{
+		{ID: 1, Name: "a", ParentID: uintPtr(2)},
+		{ID: 2, Name: "b", ParentID: uintPtr(1)},
+	})
+	assert.Error(t, err)
+
+	var deep []models.CostCenter
+	for i := uint(1); i <= MaxCostCenterDepth+1; i++ {
+		cc := models.CostCenter{ID: i, Name: "n"}
+		if i > 1 {
+			cc.ParentID = uintPtr(i - 1)
+		}
+		deep = append(deep, cc)
+	}
+	_, err = NewCostCenterTree(deep)
+	assert.Error(t, err)
+	_, err = NewCostCenterTree(deep[:MaxCostCenterDepth])
+	assert.NoError(t, err)
+}
+
+func TestCheckCostCenterParent(t *testing.T) {
+	centers := testCostCenters()
+
+	moved := centers[1] // payments under its own descendant
+	moved.ParentID = uintPtr(4)
+	assert.Error(t, CheckCostCenterParent(centers, &moved))
+
+	assert.Error(t, CheckCostCenterParent(centers, &models.CostCenter{Name: "new", ParentID: uintPtr(99)}))
+	assert.NoError(t, CheckCostCenterParent(centers, &models.CostCenter{Name: "new", ParentID: uintPtr(3)}))
+	assert.NoError(t, CheckCostCenterParent(centers, &models.CostCenter{Name: "root"}))
+}
+
+func TestCostCenterExpr(t *testing.T) {
+	tree, err := NewCostCenterTree(testCostCenters())
+	require.NoError(t, err)
+
+	expr, args := costCenterExpr(tree, "department", 4)
+	assert.True(t, strings.HasPrefix(expr, "CASE WHEN cluster_name = ANY($4) AND (namespace = ANY($5)) THEN $6::text"), expr)
+	assert.True(t, strings.HasSuffix(expr, "ELSE '__unallocated__' END"), expr)
+	assert.Contains(t, expr, "WHEN (labels @> $7::jsonb) THEN $8::text")
+	assert.Equal(t, []string{"eu"}, args[0])
+	assert.Equal(t, "payments", args[2]) // eu-checkout rolled up to its department
+	assert.Equal(t, `{"app":"checkout-api"}`, args[3])
+	assert.Equal(t, "payments", args[4])
+	assert.Equal(t, "prod-shared", args[len(args)-1])
+	// Values are passed as parameters, never interpolated
+	assert.NotContains(t, expr, "checkout")
+
+	expr, args = costCenterExpr(&CostCenterTree{}, "", 4)
+	assert.Equal(t, "'__unallocated__'", expr)
+	assert.Empty(t, args)
+}
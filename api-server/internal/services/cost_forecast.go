@@ -0,0 +1,405 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"sort"
+	"time"
+)
+
+// Forecast methods, chosen by the length of the history
+const (
+	ForecastHoltWinters = "holt_winters" // level, trend and weekly seasonality (two weeks of history or more)
+	ForecastLinear      = "linear"       // least-squares trend
+	ForecastFlat        = "flat"         // a single day of history
+)
+
+// forecastSeason is the seasonal period of daily costs: the weekly pattern of workloads
+const forecastSeason = 7
+
+// Forecast defaults and limits
+const (
+	DefaultForecastHistoryDays = 56
+	DefaultForecastHorizon     = 30
+	DefaultForecastConfidence  = 0.95
+	MaxForecastHistoryDays     = 365
+	MaxForecastHorizon         = 366
+)
+
+// ForecastParams selects the allocations to forecast and the forecast horizon
+type ForecastParams struct {
+	Aggregate   string   // allocation aggregation, e.g. "namespace", "cluster", "label:team"
+	Filters     []string // allocation filters
+	Idle        bool     // forecast idle cost as its own allocation
+	ShareIdle   string   // distribute idle cost over the allocations
+	Currency    string   // ISO 4217 currency ("" = USD)
+	HistoryDays int      // complete days of history the model is fitted on (default 56)
+	Horizon     int      // days to forecast from today (default 30)
+	Confidence  float64  // confidence level of the bands, between 0 and 1 (default 0.95)
+	Now         time.Time
+}
+
+// CostPoint is the cost of one past day
+type CostPoint struct {
+	Date time.Time `json:"date"`
+	Cost float64   `json:"cost"`
+}
+
+// ForecastPoint is the projected cost of one day with its confidence band
+type ForecastPoint struct {
+	Date     time.Time `json:"date"`
+	Expected float64   `json:"expected"`
+	Lower    float64   `json:"lower"`
+	Upper    float64   `json:"upper"`
+}
+
+// PeriodProjection is the cost of a calendar period (month or quarter) so far plus the
+// forecast for its remaining days. Its bands add up the daily bands, so they are wide
+// rather than optimistic.
+type PeriodProjection struct {
+	Period   string    `json:"period"` // "month" or "quarter"
+	Start    time.Time `json:"start"`
+	End      time.Time `json:"end"`
+	ToDate   float64   `json:"to_date"`
+	Expected float64   `json:"expected"`
+	Lower    float64   `json:"lower"`
+	Upper    float64   `json:"upper"`
+}
+
+// CostForecast is the history, daily forecast and period projections of one allocation
+type CostForecast struct {
+	Name        string             `json:"name"`
+	Method      string             `json:"method"`
+	History     []CostPoint        `json:"history"`
+	Forecast    []ForecastPoint    `json:"forecast"`
+	Projections []PeriodProjection `json:"projections"`
+}
+
+// ForecastReport holds the forecast of every allocation and of their total
+type ForecastReport struct {
+	Aggregate    string         `json:"aggregate"`
+	Currency     string         `json:"currency"`
+	Confidence   float64        `json:"confidence"`
+	HistoryStart time.Time      `json:"history_start"`
+	HistoryEnd   time.Time      `json:"history_end"`
+	Horizon      int            `json:"horizon"`
+	Total        CostForecast   `json:"total"`
+	Items        []CostForecast `json:"items"`
+}
+
+// Forecast projects the daily cost of each allocation from its daily allocation history.
+// The history covers complete days up to the start of today (and at least the current
+// quarter), the forecast starts today; month and quarter projections add the cost so far to
+// the forecast of their remaining days. The total is forecast from its own series.
+func (s *AllocationService) Forecast(ctx context.Context, tenantID int64, params ForecastParams) (*ForecastReport, error) {
+	if params.Aggregate == "" {
+		params.Aggregate = "namespace"
+	}
+	if params.HistoryDays <= 0 {
+		params.HistoryDays = DefaultForecastHistoryDays
+	}
+	if params.Horizon <= 0 {
+		params.Horizon = DefaultForecastHorizon
+	}
+	if params.Confidence == 0 {
+		params.Confidence = DefaultForecastConfidence
+	}
+	if params.HistoryDays > MaxForecastHistoryDays {
+		return nil, fmt.Errorf("history must be at most %d days", MaxForecastHistoryDays)
+	}
+	if params.Horizon > MaxForecastHorizon {
+		return nil, fmt.Errorf("horizon must be at most %d days", MaxForecastHorizon)
+	}
+	if params.Confidence <= 0 || params.Confidence >= 1 {
+		return nil, fmt.Errorf("confidence must be between 0 and 1")
+	}
+	if params.Now.IsZero() {
+		params.Now = time.Now()
+	}
+
+	today := truncateDay(params.Now)
+	monthStart, monthEnd := monthBounds(today)
+	quarterStart, quarterEnd := quarterBounds(today)
+	historyStart := today.AddDate(0, 0, -params.HistoryDays)
+	if quarterStart.Before(historyStart) {
+		historyStart = quarterStart
+	}
+	days := int(today.Sub(historyStart).Hours() / 24)
+
+	// Forecast at least to the end of the quarter so both projections are complete
+	steps := params.Horizon
+	if toQuarterEnd := int(quarterEnd.Sub(today).Hours() / 24); toQuarterEnd > steps {
+		steps = toQuarterEnd
+	}
+
+	report := &ForecastReport{
+		Aggregate:    params.Aggregate,
+		Currency:     params.Currency,
+		Confidence:   params.Confidence,
+		HistoryStart: historyStart,
+		HistoryEnd:   today,
+		Horizon:      params.Horizon,
+		Items:        []CostForecast{},
+	}
+	if days == 0 {
+		return report, nil
+	}
+
+	response, err := s.GetAllocations(ctx, tenantID, AllocationParams{
+		Window:     historyStart.Format(time.RFC3339) + "," + today.Format(time.RFC3339),
+		Aggregate:  params.Aggregate,
+		Step:       "1d",
+		Accumulate: "false",
+		Filters:    params.Filters,
+		Idle:       params.Idle,
+		ShareIdle:  params.ShareIdle,
+		Currency:   params.Currency,
+		Limit:      days + 1,
+	})
+	if err != nil {
+		return nil, err
+	}
+	report.Currency = response.Currency
+
+	total := make([]float64, days)
+	series := make(map[string][]float64)
+	for _, set := range response.Data {
+		day := int(set.Window.Start.Sub(historyStart).Hours() / 24)
+		if day < 0 || day >= days {
+			continue
+		}
+		total[day] += set.TotalCost
+		for name, alloc := range set.Allocations {
+			if series[name] == nil {
+				series[name] = make([]float64, days)
+			}
+			series[name][day] += alloc.TotalCost
+		}
+	}
+
+	z := math.Sqrt2 * math.Erfinv(params.Confidence)
+	build := func(name string, costs []float64) CostForecast {
+		// The model is fitted on the requested history only; older days of the quarter
+		// are kept for its cost to date
+		fitFrom := days - params.HistoryDays
+		if fitFrom < 0 {
+			fitFrom = 0
+		}
+		method, points := forecastSeries(costs[fitFrom:], steps, z)
+
+		fc := CostForecast{Name: name, Method: method}
+		for i, cost := range costs {
+			fc.History = append(fc.History, CostPoint{Date: historyStart.AddDate(0, 0, i), Cost: cost})
+		}
+		for i := range points {
+			points[i].Date = today.AddDate(0, 0, i)
+		}
+		fc.Projections = []PeriodProjection{
+			projectPeriod("month", monthStart, monthEnd, fc.History, points),
+			projectPeriod("quarter", quarterStart, quarterEnd, fc.History, points),
+		}
+		fc.Forecast = points[:params.Horizon]
+		return fc
+	}
+
+	report.Total = build("__total__", total)
+	for name, costs := range series {
+		report.Items = append(report.Items, build(name, costs))
+	}
+	// Largest projected month first
+	sort.Slice(report.Items, func(i, j int) bool {
+		a, b := report.Items[i].Projections[0].Expected, report.Items[j].Projections[0].Expected
+		if a != b {
+			return a > b
+		}
+		return report.Items[i].Name < report.Items[j].Name
+	})
+	return report, nil
+}
+
+// projectPeriod adds the cost of a period so far to the forecast of its remaining days
+func projectPeriod(period string, start, end time.Time, history []CostPoint, forecast []ForecastPoint) PeriodProjection {
+	p := PeriodProjection{Period: period, Start: start, End: end}
+	for _, point := range history {
+		if !point.Date.Before(start) && point.Date.Before(end) {
+			p.ToDate += point.Cost
+		}
+	}
+	p.Expected, p.Lower, p.Upper = p.ToDate, p.ToDate, p.ToDate
+	for _, point := range forecast {
+		if !point.Date.Before(start) && point.Date.Before(end) {
+			p.Expected += point.Expected
+			p.Lower += point.Lower
+			p.Upper += point.Upper
+		}
+	}
+	return p
+}
+
+// forecastSeries forecasts the next steps values of a daily series with bands of z standard
+// errors. It uses Holt-Winters with weekly seasonality when there are at least two weeks of
+// history, else a linear trend. Costs are never projected below zero.
+func forecastSeries(y []float64, steps int, z float64) (string, []ForecastPoint) {
+	points := make([]ForecastPoint, steps)
+	switch {
+	case len(y) >= 2*forecastSeason:
+		expected, width := holtWintersForecast(y, steps)
+		for h := range points {
+			points[h] = bandedPoint(expected[h], z*width[h])
+		}
+		return ForecastHoltWinters, points
+	case len(y) >= 2:
+		expected, width := linearForecast(y, steps)
+		for h := range points {
+			points[h] = bandedPoint(expected[h], z*width[h])
+		}
+		return ForecastLinear, points
+	default:
+		var last float64
+		if len(y) == 1 {
+			last = y[0]
+		}
+		for h := range points {
+			points[h] = bandedPoint(last, 0)
+		}
+		return ForecastFlat, points
+	}
+}
+
+func bandedPoint(expected, margin float64) ForecastPoint {
+	return ForecastPoint{
+		Expected: math.Max(expected, 0),
+		Lower:    math.Max(expected-margin, 0),
+		Upper:    math.Max(expected+margin, 0),
+	}
+}
+
+// holtWintersGrid are the smoothing parameters tried when fitting Holt-Winters; the
+// combination with the smallest one-step-ahead squared error is used
+var holtWintersGrid = struct{ alpha, beta, gamma []float64 }{
+	alpha: []float64{0.1, 0.2, 0.3, 0.4, 0.5, 0.6, 0.7, 0.8, 0.9},
+	beta:  []float64{0, 0.01, 0.05, 0.1, 0.2},
+	gamma: []float64{0.05, 0.1, 0.2, 0.3, 0.5},
+}
+
+// holtWintersForecast fits additive Holt-Winters with weekly seasonality and returns the
+// forecast and the standard error of each step
+func holtWintersForecast(y []float64, steps int) (expected, stderr []float64) {
+	bestSSE := math.Inf(1)
+	var bestAlpha float64
+	for _, alpha := range holtWintersGrid.alpha {
+		for _, beta := range holtWintersGrid.beta {
+			for _, gamma := range holtWintersGrid.gamma {
+				forecast, sse := holtWinters(y, alpha, beta, gamma, steps)
+				if sse < bestSSE {
+					bestSSE, bestAlpha, expected = sse, alpha, forecast
+				}
+			}
+		}
+	}
+
+	// Errors accumulate with the horizon as the level absorbs each step's shock
+	sigma := math.Sqrt(bestSSE / float64(len(y)-forecastSeason))
+	stderr = make([]float64, steps)
+	for h := range stderr {
+		stderr[h] = sigma * math.Sqrt(1+float64(h)*bestAlpha*bestAlpha)
+	}
+	return expected, stderr
+}
+
+// holtWinters runs additive Holt-Winters over y, initialized from its first two seasons,
+// and returns the forecast of the next steps values and the one-step-ahead squared error
+func holtWinters(y []float64, alpha, beta, gamma float64, steps int) ([]float64, float64) {
+	m := forecastSeason
+	first, second := mean(y[:m]), mean(y[m:2*m])
+	level := first
+	trend := (second - first) / float64(m)
+	seasonal := make([]float64, m)
+	for i := 0; i < m; i++ {
+		seasonal[i] = y[i] - first
+	}
+
+	var sse float64
+	for t := m; t < len(y); t++ {
+		s := seasonal[t%m]
+		e := y[t] - (level + trend + s)
+		sse += e * e
+
+		newLevel := alpha*(y[t]-s) + (1-alpha)*(level+trend)
+		trend = beta*(newLevel-level) + (1-beta)*trend
+		seasonal[t%m] = gamma*(y[t]-newLevel) + (1-gamma)*s
+		level = newLevel
+	}
+
+	forecast := make([]float64, steps)
+	for h := range forecast {
+		forecast[h] = level + float64(h+1)*trend + seasonal[(len(y)+h)%m]
+	}
+	return forecast, sse
+}
+
+// linearForecast fits a least-squares line and returns the forecast and the standard error
+// of each step's prediction
+func linearForecast(y []float64, steps int) (expected, stderr []float64) {
+	n := float64(len(y))
+	xMean := (n - 1) / 2
+	yMean := mean(y)
+	var sxx, sxy float64
+	for i, v := range y {
+		dx := float64(i) - xMean
+		sxx += dx * dx
+		sxy += dx * (v - yMean)
+	}
+	slope := sxy / sxx
+	intercept := yMean - slope*xMean
+
+	var sse float64
+	for i, v := range y {
+		e := v - (intercept + slope*float64(i))
+		sse += e * e
+	}
+	var sigma float64
+	if len(y) > 2 {
+		sigma = math.Sqrt(sse / (n - 2))
+	}
+
+	expected = make([]float64, steps)
+	stderr = make([]float64, steps)
+	for h := range expected {
+		x := n + float64(h)
+		expected[h] = intercept + slope*x
+		stderr[h] = sigma * math.Sqrt(1+1/n+(x-xMean)*(x-xMean)/sxx)
+	}
+	return expected, stderr
+}
+
+func mean(values []float64) float64 {
+	if len(values) == 0 {
+		return 0
+	}
+	var sum float64
+	for _, v := range values {
+		sum += v
+	}
+	return sum / float64(len(values))
+}
+
+// truncateDay returns the start of t's day in UTC
+func truncateDay(t time.Time) time.Time {
+	t = t.UTC()
+	return time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, time.UTC)
+}
+
+// monthBounds returns the first day of day's month and of the next month
+func monthBounds(day time.Time) (time.Time, time.Time) {
+	start := time.Date(day.Year(), day.Month(), 1, 0, 0, 0, 0, time.UTC)
+	return start, start.AddDate(0, 1, 0)
+}
+
+// quarterBounds returns the first day of day's calendar quarter and of the next quarter
+func quarterBounds(day time.Time) (time.Time, time.Time) {
+	month := time.Month((int(day.Month())-1)/3*3 + 1)
+	start := time.Date(day.Year(), month, 1, 0, 0, 0, 0, time.UTC)
+	return start, start.AddDate(0, 3, 0)
+}
@@ -0,0 +1,84 @@
+package services
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestForecastSeriesWeeklyPattern(t *testing.T) {
+	// Four weeks of weekday cost 100 and weekend cost 40
+	var y []float64
+	for day := 0; day < 28; day++ {
+		if day%7 >= 5 {
+			y = append(y, 40)
+		} else {
+			y = append(y, 100)
+		}
+	}
+
+	method, points := forecastSeries(y, 7, 1.96)
+	assert.Equal(t, ForecastHoltWinters, method)
+	require.Len(t, points, 7)
+	for h, point := range points {
+		want := 100.0
+		if (len(y)+h)%7 >= 5 {
+			want = 40
+		}
+		assert.InDelta(t, want, point.Expected, 1, "day %d", h)
+		assert.LessOrEqual(t, point.Lower, point.Expected)
+		assert.GreaterOrEqual(t, point.Upper, point.Expected)
+	}
+}
+
+func TestForecastSeriesLinear(t *testing.T) {
+	method, points := forecastSeries([]float64{10, 12, 14, 16, 18}, 3, 1.96)
+	assert.Equal(t, ForecastLinear, method)
+	assert.InDelta(t, 20, points[0].Expected, 1e-9)
+	assert.InDelta(t, 24, points[2].Expected, 1e-9)
+	assert.InDelta(t, 20, points[0].Upper, 1e-9, "a perfect fit has no band")
+
+	// A falling trend is never projected below zero
+	_, points = forecastSeries([]float64{30, 20, 10}, 3, 1.96)
+	assert.Equal(t, 0.0, points[2].Expected)
+	assert.Equal(t, 0.0, points[2].Lower)
+}
+
+func TestForecastSeriesShortHistory(t *testing.T) {
+	method, points := forecastSeries([]float64{5}, 2, 1.96)
+	assert.Equal(t, ForecastFlat, method)
+	assert.Equal(t, 5.0, points[1].Expected)
+
+	method, points = forecastSeries(nil, 2, 1.96)
+	assert.Equal(t, ForecastFlat, method)
+	assert.Equal(t, 0.0, points[0].Expected)
+}
+
+func TestProjectPeriod(t *testing.T) {
+	start, end := monthBounds(time.Date(2024, 2, 27, 0, 0, 0, 0, time.UTC))
+	history := []CostPoint{
+		{Date: time.Date(2024, 1, 31, 0, 0, 0, 0, time.UTC), Cost: 99}, // previous month
+		{Date: time.Date(2024, 2, 25, 0, 0, 0, 0, time.UTC), Cost: 10},
+		{Date: time.Date(2024, 2, 26, 0, 0, 0, 0, time.UTC), Cost: 10},
+	}
+	forecast := []ForecastPoint{
+		{Date: time.Date(2024, 2, 27, 0, 0, 0, 0, time.UTC), Expected: 12, Lower: 10, Upper: 14},
+		{Date: time.Date(2024, 2, 28, 0, 0, 0, 0, time.UTC), Expected: 12, Lower: 10, Upper: 14},
+		{Date: time.Date(2024, 2, 29, 0, 0, 0, 0, time.UTC), Expected: 12, Lower: 10, Upper: 14},
+		{Date: time.Date(2024, 3, 1, 0, 0, 0, 0, time.UTC), Expected: 50, Lower: 50, Upper: 50}, // next month
+	}
+
+	p := projectPeriod("month", start, end, history, forecast)
+	assert.Equal(t, 20.0, p.ToDate)
+	assert.Equal(t, 56.0, p.Expected)
+	assert.Equal(t, 50.0, p.Lower)
+	assert.Equal(t, 62.0, p.Upper)
+}
+
+func TestQuarterBounds(t *testing.T) {
+	start, end := quarterBounds(time.Date(2024, 11, 15, 0, 0, 0, 0, time.UTC))
+	assert.Equal(t, time.Date(2024, 10, 1, 0, 0, 0, 0, time.UTC), start)
+	assert.Equal(t, time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC), end)
+}
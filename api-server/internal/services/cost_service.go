@@ -18,6 +18,20 @@ func NewCostService(pool *pgxpool.Pool) *CostService {
 
 // CostByNamespace returns cost breakdown by namespace for a tenant
 func (s *CostService) CostByNamespace(ctx context.Context, tenantID int64, startTime, endTime time.Time) ([]NamespaceCost, error) {
+	var results []NamespaceCost
+	err := s.EachNamespaceCost(ctx, tenantID, startTime, endTime, func(nc NamespaceCost) error {
+		results = append(results, nc)
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return results, nil
+}
+
+// EachNamespaceCost passes the cost of each namespace to fn as its row is read, so exports
+// can stream them without collecting every row; an error from fn stops the iteration
+func (s *CostService) EachNamespaceCost(ctx context.Context, tenantID int64, startTime, endTime time.Time, fn func(NamespaceCost) error) error {
 	query := `
 		SELECT 
 			namespace,
@@ -37,11 +51,10 @@ func (s *CostService) CostByNamespace(ctx context.Context, tenantID int64, start
 
 	rows, err := s.pool.Query(ctx, query, tenantID, startTime, endTime)
 	if err != nil {
-		return nil, fmt.Errorf("query failed: %w", err)
+		return fmt.Errorf("query failed: %w", err)
 	}
 	defer rows.Close()
 
-	var results []NamespaceCost
 	for rows.Next() {
 		var nc NamespaceCost
 		if err := rows.Scan(
@@ -52,18 +65,34 @@ func (s *CostService) CostByNamespace(ctx context.Context, tenantID int64, start
 			&nc.AvgMemoryUsage,
 			&nc.PodCount,
 		); err != nil {
-			return nil, fmt.Errorf("scan failed: %w", err)
+			return fmt.Errorf("scan failed: %w", err)
 		}
 		// Calculate cost based on node hourly costs
 		nc.EstimatedCostUSD = s.calculateNamespaceCost(ctx, tenantID, nc.Namespace, startTime, endTime)
-		results = append(results, nc)
+		if err := fn(nc); err != nil {
+			return err
+		}
 	}
 
-	return results, rows.Err()
+	return rows.Err()
 }
 
 // CostByCluster returns cost breakdown by cluster for a tenant
 func (s *CostService) CostByCluster(ctx context.Context, tenantID int64, startTime, endTime time.Time) ([]ClusterCost, error) {
+	var results []ClusterCost
+	err := s.EachClusterCost(ctx, tenantID, startTime, endTime, func(cc ClusterCost) error {
+		results = append(results, cc)
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return results, nil
+}
+
+// EachClusterCost passes the cost of each cluster to fn as its row is read, as
+// EachNamespaceCost does for namespaces
+func (s *CostService) EachClusterCost(ctx context.Context, tenantID int64, startTime, endTime time.Time, fn func(ClusterCost) error) error {
 	query := `
 		SELECT 
 			cluster_name,
@@ -84,11 +113,10 @@ func (s *CostService) CostByCluster(ctx context.Context, tenantID int64, startTi
 
 	rows, err := s.pool.Query(ctx, query, tenantID, startTime, endTime)
 	if err != nil {
-		return nil, fmt.Errorf("query failed: %w", err)
+		return fmt.Errorf("query failed: %w", err)
 	}
 	defer rows.Close()
 
-	var results []ClusterCost
 	for rows.Next() {
 		var cc ClusterCost
 		if err := rows.Scan(
@@ -100,18 +128,34 @@ func (s *CostService) CostByCluster(ctx context.Context, tenantID int64, startTi
 			&cc.PodCount,
 			&cc.NamespaceCount,
 		); err != nil {
-			return nil, fmt.Errorf("scan failed: %w", err)
+			return fmt.Errorf("scan failed: %w", err)
 		}
 		// Calculate cost based on node hourly costs
 		cc.EstimatedCostUSD = s.calculateClusterCost(ctx, tenantID, cc.ClusterName, startTime, endTime)
-		results = append(results, cc)
+		if err := fn(cc); err != nil {
+			return err
+		}
 	}
 
-	return results, rows.Err()
+	return rows.Err()
 }
 
 // UtilizationVsRequests returns resource utilization vs requests for pods
 func (s *CostService) UtilizationVsRequests(ctx context.Context, tenantID int64, startTime, endTime time.Time, namespace, cluster string) ([]UtilizationMetric, error) {
+	var results []UtilizationMetric
+	err := s.EachUtilizationMetric(ctx, tenantID, startTime, endTime, namespace, cluster, func(um UtilizationMetric) error {
+		results = append(results, um)
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return results, nil
+}
+
+// EachUtilizationMetric passes the utilization of each pod to fn as its row is read, as
+// EachNamespaceCost does for namespaces
+func (s *CostService) EachUtilizationMetric(ctx context.Context, tenantID int64, startTime, endTime time.Time, namespace, cluster string, fn func(UtilizationMetric) error) error {
 	query := `
 		SELECT 
 			cluster_name,
@@ -161,11 +205,10 @@ func (s *CostService) UtilizationVsRequests(ctx context.Context, tenantID int64,
 
 	rows, err := s.pool.Query(ctx, query, args...)
 	if err != nil {
-		return nil, fmt.Errorf("query failed: %w", err)
+		return fmt.Errorf("query failed: %w", err)
 	}
 	defer rows.Close()
 
-	var results []UtilizationMetric
 	for rows.Next() {
 		var um UtilizationMetric
 		if err := rows.Scan(
@@ -179,16 +222,32 @@ func (s *CostService) UtilizationVsRequests(ctx context.Context, tenantID int64,
 			&um.CPUUtilizationPercent,
 			&um.MemoryUtilizationPercent,
 		); err != nil {
-			return nil, fmt.Errorf("scan failed: %w", err)
+			return fmt.Errorf("scan failed: %w", err)
+		}
+		if err := fn(um); err != nil {
+			return err
 		}
-		results = append(results, um)
 	}
 
-	return results, rows.Err()
+	return rows.Err()
 }
 
 // CostTrends returns daily or weekly cost trends
 func (s *CostService) CostTrends(ctx context.Context, tenantID int64, startTime, endTime time.Time, interval string) ([]CostTrend, error) {
+	var results []CostTrend
+	err := s.EachCostTrend(ctx, tenantID, startTime, endTime, interval, func(ct CostTrend) error {
+		results = append(results, ct)
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return results, nil
+}
+
+// EachCostTrend passes each time bucket to fn as its row is read, as EachNamespaceCost
+// does for namespaces
+func (s *CostService) EachCostTrend(ctx context.Context, tenantID int64, startTime, endTime time.Time, interval string, fn func(CostTrend) error) error {
 	var timeBucket string
 	switch interval {
 	case "daily", "day":
@@ -220,11 +279,10 @@ func (s *CostService) CostTrends(ctx context.Context, tenantID int64, startTime,
 
 	rows, err := s.pool.Query(ctx, query, tenantID, startTime, endTime)
 	if err != nil {
-		return nil, fmt.Errorf("query failed: %w", err)
+		return fmt.Errorf("query failed: %w", err)
 	}
 	defer rows.Close()
 
-	var results []CostTrend
 	for rows.Next() {
 		var ct CostTrend
 		if err := rows.Scan(
@@ -235,14 +293,16 @@ func (s *CostService) CostTrends(ctx context.Context, tenantID int64, startTime,
 			&ct.AvgMemoryUsage,
 			&ct.PodCount,
 		); err != nil {
-			return nil, fmt.Errorf("scan failed: %w", err)
+			return fmt.Errorf("scan failed: %w", err)
 		}
 		// Calculate estimated cost for this time bucket
 		ct.EstimatedCostUSD = s.calculateTimeBucketCost(ctx, tenantID, ct.Time, timeBucket)
-		results = append(results, ct)
+		if err := fn(ct); err != nil {
+			return err
+		}
 	}
 
-	return results, rows.Err()
+	return rows.Err()
 }
 
 // Helper function to calculate namespace cost based on node costs
@@ -329,6 +389,7 @@ type NamespaceCost struct {
 	AvgMemoryUsage     float64 `json:"avg_memory_usage_bytes"`
 	PodCount           int     `json:"pod_count"`
 	EstimatedCostUSD   float64 `json:"estimated_cost_usd"`
+	EstimatedCost      float64 `json:"estimated_cost"` // EstimatedCostUSD in the requested currency
 }
 
 type ClusterCost struct {
@@ -340,6 +401,7 @@ type ClusterCost struct {
 	PodCount           int     `json:"pod_count"`
 	NamespaceCount     int     `json:"namespace_count"`
 	EstimatedCostUSD   float64 `json:"estimated_cost_usd"`
+	EstimatedCost      float64 `json:"estimated_cost"` // EstimatedCostUSD in the requested currency
 }
 
 type UtilizationMetric struct {
@@ -362,4 +424,5 @@ type CostTrend struct {
 	AvgMemoryUsage     float64   `json:"avg_memory_usage_bytes"`
 	PodCount           int       `json:"pod_count"`
 	EstimatedCostUSD   float64   `json:"estimated_cost_usd"`
+	EstimatedCost      float64   `json:"estimated_cost"` // EstimatedCostUSD in the requested currency
 }
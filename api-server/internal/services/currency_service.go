@@ -0,0 +1,211 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/bugfreev587/k8s-cost-api-server/internal/models"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// CurrencyService manages tenant display currencies and exchange rates
+type CurrencyService struct {
+	db *gorm.DB
+}
+
+// NewCurrencyService creates a new currency service
+func NewCurrencyService(db *gorm.DB) *CurrencyService {
+	return &CurrencyService{db: db}
+}
+
+// NormalizeCurrency validates an ISO 4217 currency code and returns it upper-cased
+func NormalizeCurrency(code string) (string, error) {
+	code = strings.ToUpper(strings.TrimSpace(code))
+	if len(code) != 3 {
+		return "", fmt.Errorf("invalid currency: %q (expected an ISO 4217 code such as EUR)", code)
+	}
+	if !models.Currencies[code] {
+		return "", fmt.Errorf("unknown currency: %q (expected an ISO 4217 code such as EUR)", code)
+	}
+	return code, nil
+}
+
+// ResolveCurrency returns the requested currency, or the tenant's display currency when
+// none is requested
+func (s *CurrencyService) ResolveCurrency(ctx context.Context, tenantID uint, requested string) (string, error) {
+	if requested != "" {
+		return NormalizeCurrency(requested)
+	}
+
+	var tenant models.Tenant
+	if err := s.db.WithContext(ctx).Select("display_currency").First(&tenant, tenantID).Error; err != nil {
+		return "", fmt.Errorf("failed to load tenant: %w", err)
+	}
+	if tenant.DisplayCurrency == "" {
+		return models.BaseCurrency, nil
+	}
+	return NormalizeCurrency(tenant.DisplayCurrency)
+}
+
+// SetDisplayCurrency sets the tenant's default reporting currency
+func (s *CurrencyService) SetDisplayCurrency(ctx context.Context, tenantID uint, currency string) error {
+	result := s.db.WithContext(ctx).Model(&models.Tenant{}).
+		Where("id = ?", tenantID).
+		Update("display_currency", currency)
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return gorm.ErrRecordNotFound
+	}
+	return nil
+}
+
+// ListExchangeRates lists a tenant's exchange rates, optionally for one currency
+func (s *CurrencyService) ListExchangeRates(ctx context.Context, tenantID uint, currency string) ([]models.ExchangeRate, error) {
+	query := s.db.WithContext(ctx).Where("tenant_id = ?", tenantID)
+	if currency != "" {
+		query = query.Where("currency = ?", currency)
+	}
+	var rates []models.ExchangeRate
+	err := query.Order("currency ASC, effective_from DESC").Find(&rates).Error
+	return rates, err
+}
+
+// SetExchangeRate creates the rate, replacing any rate for the same currency and effective date
+func (s *CurrencyService) SetExchangeRate(ctx context.Context, rate *models.ExchangeRate) error {
+	return s.db.WithContext(ctx).Clauses(clause.OnConflict{
+		Columns:   []clause.Column{{Name: "tenant_id"}, {Name: "currency"}, {Name: "effective_from"}},
+		DoUpdates: clause.AssignmentColumns([]string{"rate"}),
+	}).Create(rate).Error
+}
+
+// DeleteExchangeRate deletes one of a tenant's exchange rates
+func (s *CurrencyService) DeleteExchangeRate(ctx context.Context, tenantID, rateID uint) (int64, error) {
+	result := s.db.WithContext(ctx).
+		Where("tenant_id = ? AND id = ?", tenantID, rateID).
+		Delete(&models.ExchangeRate{})
+	return result.RowsAffected, result.Error
+}
+
+// Converter returns a converter from USD to the currency for costs between start and end.
+// A rate must be effective at start; converting to USD needs no rates.
+func (s *CurrencyService) Converter(ctx context.Context, tenantID uint, currency string, start, end time.Time) (*CurrencyConverter, error) {
+	if currency == "" || currency == models.BaseCurrency {
+		return &CurrencyConverter{Currency: models.BaseCurrency}, nil
+	}
+
+	rates, err := s.loadRates(ctx, tenantID, currency, end)
+	if err != nil {
+		return nil, err
+	}
+	return newCurrencyConverter(currency, rates, start)
+}
+
+// PartialConverter returns a converter for the currency with whatever rates take effect by
+// end, for callers that check Covers and skip amounts without a rate
+func (s *CurrencyService) PartialConverter(ctx context.Context, tenantID uint, currency string, end time.Time) (*CurrencyConverter, error) {
+	if currency == "" || currency == models.BaseCurrency {
+		return &CurrencyConverter{Currency: models.BaseCurrency}, nil
+	}
+
+	rates, err := s.loadRates(ctx, tenantID, currency, end)
+	if err != nil {
+		return nil, err
+	}
+	return &CurrencyConverter{Currency: currency, rates: rates}, nil
+}
+
+// loadRates loads a currency's exchange rates taking effect by end, oldest first
+func (s *CurrencyService) loadRates(ctx context.Context, tenantID uint, currency string, end time.Time) ([]models.ExchangeRate, error) {
+	var rates []models.ExchangeRate
+	err := s.db.WithContext(ctx).
+		Where("tenant_id = ? AND currency = ? AND effective_from <= ?", tenantID, currency, end).
+		Order("effective_from ASC").
+		Find(&rates).Error
+	if err != nil {
+		return nil, fmt.Errorf("failed to load exchange rates: %w", err)
+	}
+	return rates, nil
+}
+
+// CurrencyConverter converts USD amounts using the exchange rate effective at a given time
+type CurrencyConverter struct {
+	Currency string
+	rates    []models.ExchangeRate // ascending by EffectiveFrom
+}
+
+// newCurrencyConverter builds a converter, checking that a rate is effective at start
+func newCurrencyConverter(currency string, rates []models.ExchangeRate, start time.Time) (*CurrencyConverter, error) {
+	sorted := make([]models.ExchangeRate, len(rates))
+	copy(sorted, rates)
+	sort.SliceStable(sorted, func(i, j int) bool { return sorted[i].EffectiveFrom.Before(sorted[j].EffectiveFrom) })
+
+	if len(sorted) == 0 || sorted[0].EffectiveFrom.After(start) {
+		return nil, &MissingRateError{Currency: currency, At: start}
+	}
+	return &CurrencyConverter{Currency: currency, rates: sorted}, nil
+}
+
+// MissingRateError reports that no exchange rate of a currency is effective at a time
+type MissingRateError struct {
+	Currency string
+	At       time.Time
+}
+
+func (e *MissingRateError) Error() string {
+	return fmt.Sprintf("no %s exchange rate effective at %s", e.Currency, e.At.Format("2006-01-02"))
+}
+
+// Covers reports whether a rate is effective at t (always for USD)
+func (c *CurrencyConverter) Covers(t time.Time) bool {
+	if c.Currency == models.BaseCurrency {
+		return true
+	}
+	return len(c.rates) > 0 && !c.rates[0].EffectiveFrom.After(t)
+}
+
+// RateAt returns the exchange rate effective at t (1 for USD)
+func (c *CurrencyConverter) RateAt(t time.Time) float64 {
+	if c == nil || len(c.rates) == 0 {
+		return 1
+	}
+	i := sort.Search(len(c.rates), func(i int) bool { return c.rates[i].EffectiveFrom.After(t) })
+	if i == 0 {
+		return c.rates[0].Rate
+	}
+	return c.rates[i-1].Rate
+}
+
+// Convert converts a USD amount using the rate effective at t
+func (c *CurrencyConverter) Convert(amount float64, t time.Time) float64 {
+	return amount * c.RateAt(t)
+}
+
+// ToBase converts an amount in the converter's currency to USD using the rate effective at t
+func (c *CurrencyConverter) ToBase(amount float64, t time.Time) float64 {
+	rate := c.RateAt(t)
+	if rate <= 0 {
+		return amount
+	}
+	return amount / rate
+}
+
+// BaseConverters returns converters to USD for amounts in several currencies, keyed by
+// currency. Each currency needs a rate effective at the earliest time given for it; USD
+// needs none.
+func (s *CurrencyService) BaseConverters(ctx context.Context, tenantID uint, earliest map[string]time.Time, end time.Time) (map[string]*CurrencyConverter, error) {
+	converters := make(map[string]*CurrencyConverter, len(earliest))
+	for currency, start := range earliest {
+		converter, err := s.Converter(ctx, tenantID, currency, start, end)
+		if err != nil {
+			return nil, err
+		}
+		converters[currency] = converter
+	}
+	return converters, nil
+}
@@ -0,0 +1,52 @@
+package services
+
+import (
+	"testing"
+	"time"
+
+	"github.com/bugfreev587/k8s-cost-api-server/internal/models"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCurrencyConverter_RateAt(t *testing.T) {
+	jan := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	feb := time.Date(2024, 2, 1, 0, 0, 0, 0, time.UTC)
+	rates := []models.ExchangeRate{
+		{Currency: "EUR", Rate: 0.90, EffectiveFrom: feb},
+		{Currency: "EUR", Rate: 0.92, EffectiveFrom: jan},
+	}
+
+	converter, err := newCurrencyConverter("EUR", rates, jan.Add(12*time.Hour))
+	require.NoError(t, err)
+	assert.InDelta(t, 0.92, converter.RateAt(jan.AddDate(0, 0, 15)), 1e-12)
+	assert.InDelta(t, 0.90, converter.RateAt(feb), 1e-12)
+	assert.InDelta(t, 90.0, converter.Convert(100, feb.AddDate(0, 0, 3)), 1e-9)
+	assert.InDelta(t, 100.0, converter.ToBase(92, jan.AddDate(0, 0, 3)), 1e-9)
+	assert.InDelta(t, 100.0, converter.ToBase(90, feb), 1e-9)
+
+	_, err = newCurrencyConverter("EUR", rates, jan.AddDate(0, 0, -1))
+	assert.ErrorContains(t, err, "no EUR exchange rate")
+	var rateErr *MissingRateError
+	assert.ErrorAs(t, err, &rateErr)
+}
+
+func TestCurrencyConverter_Covers(t *testing.T) {
+	jan := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	converter := &CurrencyConverter{Currency: "EUR", rates: []models.ExchangeRate{{Currency: "EUR", Rate: 0.92, EffectiveFrom: jan}}}
+	assert.True(t, converter.Covers(jan))
+	assert.False(t, converter.Covers(jan.Add(-time.Second)))
+	assert.False(t, (&CurrencyConverter{Currency: "EUR"}).Covers(jan))
+	assert.True(t, (&CurrencyConverter{Currency: models.BaseCurrency}).Covers(time.Time{}))
+}
+
+func TestNormalizeCurrency(t *testing.T) {
+	code, err := NormalizeCurrency(" gbp ")
+	require.NoError(t, err)
+	assert.Equal(t, "GBP", code)
+
+	_, err = NormalizeCurrency("EURO")
+	assert.Error(t, err)
+	_, err = NormalizeCurrency("XYZ")
+	assert.Error(t, err)
+}
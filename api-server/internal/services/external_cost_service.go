@@ -0,0 +1,262 @@
+package services
+
+import (
+	"context"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/bugfreev587/k8s-cost-api-server/internal/models"
+	"gorm.io/gorm"
+)
+
+// ExternalCostService manages cost line items from outside the cluster
+type ExternalCostService struct {
+	db *gorm.DB
+}
+
+// NewExternalCostService creates a new external cost service
+func NewExternalCostService(db *gorm.DB) *ExternalCostService {
+	return &ExternalCostService{db: db}
+}
+
+// externalCostInput is one uploaded line item before validation
+type externalCostInput struct {
+	StartTime   string            `json:"start_time"`
+	EndTime     string            `json:"end_time"`
+	Time        string            `json:"time"`
+	Amount      float64           `json:"amount"`
+	Currency    string            `json:"currency"`
+	Provider    string            `json:"provider"`
+	Service     string            `json:"service"`
+	Description string            `json:"description"`
+	Tags        map[string]string `json:"tags"`
+}
+
+// ParseExternalCostsJSON parses line items from a JSON array or an object with an "items" array
+func ParseExternalCostsJSON(r io.Reader) ([]models.ExternalCost, error) {
+	data, err := io.ReadAll(r)
+	if err != nil {
+		return nil, err
+	}
+
+	var inputs []externalCostInput
+	if err := json.Unmarshal(data, &inputs); err != nil {
+		var wrapped struct {
+			Items []externalCostInput `json:"items"`
+		}
+		if err := json.Unmarshal(data, &wrapped); err != nil {
+			return nil, fmt.Errorf("invalid JSON: expected an array of line items or {\"items\": [...]}")
+		}
+		inputs = wrapped.Items
+	}
+
+	costs := make([]models.ExternalCost, 0, len(inputs))
+	for i, in := range inputs {
+		cost, err := in.toExternalCost()
+		if err != nil {
+			return nil, fmt.Errorf("item %d: %w", i+1, err)
+		}
+		costs = append(costs, cost)
+	}
+	return costs, nil
+}
+
+// ParseExternalCostsCSV parses line items from CSV with a header row.
+//
+// Recognized columns: start_time (or start, time, date), end_time (or end), amount (or cost),
+// currency, provider, service, description, tags ("key=value;key=value"), and one column per
+// tag named "tag:<key>".
+func ParseExternalCostsCSV(r io.Reader) ([]models.ExternalCost, error) {
+	reader := csv.NewReader(r)
+	reader.TrimLeadingSpace = true
+
+	header, err := reader.Read()
+	if err != nil {
+		return nil, fmt.Errorf("failed to read CSV header: %w", err)
+	}
+	for i := range header {
+		header[i] = strings.ToLower(strings.TrimSpace(header[i]))
+	}
+
+	var costs []models.ExternalCost
+	for line := 2; ; line++ {
+		record, err := reader.Read()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, fmt.Errorf("line %d: %w", line, err)
+		}
+
+		in := externalCostInput{Tags: make(map[string]string)}
+		for i, col := range header {
+			if i >= len(record) {
+				break
+			}
+			value := strings.TrimSpace(record[i])
+			switch col {
+			case "start_time", "start", "time", "date":
+				in.StartTime = value
+			case "end_time", "end":
+				in.EndTime = value
+			case "amount", "cost":
+				if value == "" {
+					continue
+				}
+				amount, err := strconv.ParseFloat(value, 64)
+				if err != nil {
+					return nil, fmt.Errorf("line %d: invalid amount %q", line, value)
+				}
+				in.Amount = amount
+			case "currency":
+				in.Currency = value
+			case "provider":
+				in.Provider = value
+			case "service":
+				in.Service = value
+			case "description":
+				in.Description = value
+			case "tags":
+				for _, pair := range strings.Split(value, ";") {
+					if key, val, ok := strings.Cut(pair, "="); ok && strings.TrimSpace(key) != "" {
+						in.Tags[strings.TrimSpace(key)] = strings.TrimSpace(val)
+					}
+				}
+			default:
+				if key, ok := strings.CutPrefix(col, "tag:"); ok && key != "" && value != "" {
+					in.Tags[key] = value
+				}
+			}
+		}
+
+		cost, err := in.toExternalCost()
+		if err != nil {
+			return nil, fmt.Errorf("line %d: %w", line, err)
+		}
+		costs = append(costs, cost)
+	}
+	return costs, nil
+}
+
+// toExternalCost validates an input line item. A date-only start without an end covers that day.
+func (in externalCostInput) toExternalCost() (models.ExternalCost, error) {
+	startStr := in.StartTime
+	if startStr == "" {
+		startStr = in.Time
+	}
+	if startStr == "" {
+		return models.ExternalCost{}, fmt.Errorf("start_time is required")
+	}
+	start, dateOnly, err := parseCostTime(startStr)
+	if err != nil {
+		return models.ExternalCost{}, fmt.Errorf("invalid start_time: %w", err)
+	}
+
+	end := start
+	if dateOnly {
+		end = start.AddDate(0, 0, 1)
+	}
+	if in.EndTime != "" {
+		if end, _, err = parseCostTime(in.EndTime); err != nil {
+			return models.ExternalCost{}, fmt.Errorf("invalid end_time: %w", err)
+		}
+	}
+	if end.Before(start) {
+		return models.ExternalCost{}, fmt.Errorf("end_time is before start_time")
+	}
+
+	currency := models.BaseCurrency
+	if strings.TrimSpace(in.Currency) != "" {
+		if currency, err = NormalizeCurrency(in.Currency); err != nil {
+			return models.ExternalCost{}, err
+		}
+	}
+	tags := models.CostTags(in.Tags)
+	if tags == nil {
+		tags = models.CostTags{}
+	}
+
+	return models.ExternalCost{
+		StartTime:   start,
+		EndTime:     end,
+		Amount:      in.Amount,
+		Currency:    currency,
+		Provider:    in.Provider,
+		Service:     in.Service,
+		Description: in.Description,
+		Tags:        tags,
+	}, nil
+}
+
+// parseCostTime parses an RFC3339 timestamp or a YYYY-MM-DD date (UTC)
+func parseCostTime(value string) (t time.Time, dateOnly bool, err error) {
+	if t, err = time.Parse(time.RFC3339, value); err == nil {
+		return t.UTC(), false, nil
+	}
+	if t, err = time.Parse("2006-01-02", value); err == nil {
+		return t, true, nil
+	}
+	return time.Time{}, false, fmt.Errorf("expected RFC3339 or YYYY-MM-DD, got %q", value)
+}
+
+// CreateExternalCosts stores uploaded line items for a tenant. Items in another currency
+// than USD need an exchange rate effective at their start; otherwise nothing is stored and
+// a *MissingRateError is returned.
+func (s *ExternalCostService) CreateExternalCosts(ctx context.Context, tenantID uint, source string, costs []models.ExternalCost) error {
+	if len(costs) == 0 {
+		return nil
+	}
+
+	earliest := make(map[string]time.Time)
+	var end time.Time
+	for _, cost := range costs {
+		if cost.Currency == models.BaseCurrency {
+			continue
+		}
+		if t, ok := earliest[cost.Currency]; !ok || cost.StartTime.Before(t) {
+			earliest[cost.Currency] = cost.StartTime
+		}
+		if cost.StartTime.After(end) {
+			end = cost.StartTime
+		}
+	}
+	if _, err := NewCurrencyService(s.db).BaseConverters(ctx, tenantID, earliest, end); err != nil {
+		return err
+	}
+	for i := range costs {
+		costs[i].TenantID = tenantID
+		costs[i].Source = source
+	}
+	return s.db.WithContext(ctx).CreateInBatches(costs, 500).Error
+}
+
+// ListExternalCosts returns a tenant's line items overlapping the given time range
+func (s *ExternalCostService) ListExternalCosts(ctx context.Context, tenantID uint, start, end time.Time) ([]models.ExternalCost, error) {
+	var costs []models.ExternalCost
+	err := s.db.WithContext(ctx).
+		Where("tenant_id = ? AND start_time <= ? AND end_time >= ?", tenantID, end, start).
+		Order("start_time ASC, id ASC").
+		Find(&costs).Error
+	return costs, err
+}
+
+// DeleteExternalCost deletes a single line item belonging to the tenant
+func (s *ExternalCostService) DeleteExternalCost(ctx context.Context, tenantID, costID uint) (int64, error) {
+	result := s.db.WithContext(ctx).
+		Where("tenant_id = ?", tenantID).
+		Delete(&models.ExternalCost{}, costID)
+	return result.RowsAffected, result.Error
+}
+
+// DeleteExternalCostsBySource deletes all line items of an upload
+func (s *ExternalCostService) DeleteExternalCostsBySource(ctx context.Context, tenantID uint, source string) (int64, error) {
+	result := s.db.WithContext(ctx).
+		Where("tenant_id = ? AND source = ?", tenantID, source).
+		Delete(&models.ExternalCost{})
+	return result.RowsAffected, result.Error
+}
@@ -0,0 +1,73 @@
+package services
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/bugfreev587/k8s-cost-api-server/internal/models"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseExternalCostsCSV(t *testing.T) {
+	costs, err := ParseExternalCostsCSV(strings.NewReader(
+		"Date,Amount,Currency,Service,Tags,tag:team\n" +
+			"2024-03-01,12.5,eur,rds,namespace=payments;cluster=prod,data\n" +
+			"2024-03-02T06:00:00Z,3,,s3,,\n"))
+	require.NoError(t, err)
+	require.Len(t, costs, 2)
+
+	// A date-only start covers the day
+	assert.Equal(t, time.Date(2024, 3, 1, 0, 0, 0, 0, time.UTC), costs[0].StartTime)
+	assert.Equal(t, time.Date(2024, 3, 2, 0, 0, 0, 0, time.UTC), costs[0].EndTime)
+	assert.Equal(t, 12.5, costs[0].Amount)
+	assert.Equal(t, "EUR", costs[0].Currency)
+	assert.Equal(t, models.CostTags{"namespace": "payments", "cluster": "prod", "team": "data"}, costs[0].Tags)
+
+	// A timestamp start without an end is a point in time; currency defaults to USD
+	assert.Equal(t, costs[1].StartTime, costs[1].EndTime)
+	assert.Equal(t, "USD", costs[1].Currency)
+	assert.Equal(t, models.CostTags{}, costs[1].Tags)
+}
+
+func TestParseExternalCostsCSV_Malformed(t *testing.T) {
+	tests := []struct {
+		name string
+		csv  string
+		err  string
+	}{
+		{name: "empty", csv: "", err: "failed to read CSV header"},
+		{name: "bad amount", csv: "date,amount\n2024-03-01,1\n2024-03-02,ten\n", err: `line 3: invalid amount "ten"`},
+		{name: "missing start", csv: "amount\n1\n", err: "line 2: start_time is required"},
+		{name: "bad start", csv: "start,amount\n03/01/2024,1\n", err: "line 2: invalid start_time"},
+		{name: "end before start", csv: "start,end,amount\n2024-03-02,2024-03-01,1\n", err: "line 2: end_time is before start_time"},
+		{name: "unknown currency", csv: "date,amount,currency\n2024-03-01,1,ABC\n", err: "line 2: unknown currency"},
+		{name: "ragged row", csv: "date,amount\n2024-03-01,1,extra\n", err: "line 2:"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := ParseExternalCostsCSV(strings.NewReader(tt.csv))
+			assert.ErrorContains(t, err, tt.err)
+		})
+	}
+}
+
+func TestParseExternalCostsJSON(t *testing.T) {
+	for _, body := range []string{
+		`[{"start_time": "2024-03-01", "amount": 5, "currency": "jpy", "tags": {"team": "data"}}]`,
+		`{"items": [{"time": "2024-03-01", "amount": 5, "currency": "JPY", "tags": {"team": "data"}}]}`,
+	} {
+		costs, err := ParseExternalCostsJSON(strings.NewReader(body))
+		require.NoError(t, err, body)
+		require.Len(t, costs, 1)
+		assert.Equal(t, "JPY", costs[0].Currency)
+		assert.Equal(t, 24*time.Hour, costs[0].EndTime.Sub(costs[0].StartTime))
+		assert.Equal(t, models.CostTags{"team": "data"}, costs[0].Tags)
+	}
+
+	_, err := ParseExternalCostsJSON(strings.NewReader(`[{"amount": 5}`))
+	assert.ErrorContains(t, err, "invalid JSON")
+	_, err = ParseExternalCostsJSON(strings.NewReader(`[{"amount": 5}]`))
+	assert.ErrorContains(t, err, "item 1: start_time is required")
+}
@@ -0,0 +1,344 @@
+package services
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/bugfreev587/k8s-cost-api-server/internal/models"
+	"gorm.io/gorm"
+)
+
+// ValidateNodePricing checks a node pricing override before it is saved: it must declare an
+// instance type or a whole-node hourly cost, and the tier defaults to on-demand
+func ValidateNodePricing(np *models.NodePricing) error {
+	if np.PricingTier == "" {
+		np.PricingTier = models.TierOnDemand
+	}
+	if tierRank(np.PricingTier) == len(pricingTiers) {
+		return fmt.Errorf("invalid pricing_tier: %s", np.PricingTier)
+	}
+	if np.HourlyCostOverride != nil && *np.HourlyCostOverride <= 0 {
+		return fmt.Errorf("hourly_cost_override must be positive")
+	}
+	if np.InstanceType == "" && np.HourlyCostOverride == nil {
+		return fmt.Errorf("instance_type or hourly_cost_override required")
+	}
+	return nil
+}
+
+// GetNodePricing retrieves a node's pricing override
+func (s *PricingService) GetNodePricing(ctx context.Context, tenantID uint, clusterName, nodeName string) (*models.NodePricing, error) {
+	var nodePricing models.NodePricing
+	err := s.db.WithContext(ctx).
+		Where("tenant_id = ? AND cluster_name = ? AND node_name = ?", tenantID, clusterName, nodeName).
+		First(&nodePricing).Error
+	if err != nil {
+		return nil, err
+	}
+	return &nodePricing, nil
+}
+
+// ListNodePricing lists the node pricing overrides of a cluster (all clusters when empty)
+func (s *PricingService) ListNodePricing(ctx context.Context, tenantID uint, clusterName string) ([]models.NodePricing, error) {
+	query := s.db.WithContext(ctx).Where("tenant_id = ?", tenantID)
+	if clusterName != "" {
+		query = query.Where("cluster_name = ?", clusterName)
+	}
+	var nodes []models.NodePricing
+	err := query.Order("cluster_name, node_name").Find(&nodes).Error
+	return nodes, err
+}
+
+// DeleteNodePricing removes a node's pricing override
+func (s *PricingService) DeleteNodePricing(ctx context.Context, tenantID uint, clusterName, nodeName string) error {
+	err := s.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		var existing models.NodePricing
+		err := tx.Where("tenant_id = ? AND cluster_name = ? AND node_name = ?", tenantID, clusterName, nodeName).
+			First(&existing).Error
+		if err != nil {
+			return err
+		}
+		if err := tx.Delete(&existing).Error; err != nil {
+			return err
+		}
+		configID, err := clusterConfigID(tx, tenantID, clusterName)
+		if err != nil {
+			return err
+		}
+		return s.recordVersion(tx, tenantID, configID, models.PricingActionNodeDelete, "node", existing.ID, existing, nil)
+	})
+	if err != nil {
+		return err
+	}
+
+	s.cache.InvalidateTenant(tenantID)
+	return nil
+}
+
+// SetNodePricingBulk applies the same override to several nodes of a cluster in one
+// transaction, creating or updating each node's override
+func (s *PricingService) SetNodePricingBulk(ctx context.Context, tenantID uint, clusterName string, nodeNames []string, override models.NodePricing) ([]models.NodePricing, error) {
+	saved := make([]models.NodePricing, 0, len(nodeNames))
+	err := s.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		for _, nodeName := range nodeNames {
+			nodePricing := models.NodePricing{
+				TenantID:           tenantID,
+				ClusterName:        clusterName,
+				NodeName:           nodeName,
+				InstanceType:       override.InstanceType,
+				PricingTier:        override.PricingTier,
+				HourlyCostOverride: override.HourlyCostOverride,
+			}
+			if err := s.setNodePricing(tx, &nodePricing); err != nil {
+				return err
+			}
+			saved = append(saved, nodePricing)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	s.cache.InvalidateTenant(tenantID)
+	return saved, nil
+}
+
+// setNodePricing creates or replaces a node's override in tx and records the change
+func (s *PricingService) setNodePricing(tx *gorm.DB, nodePricing *models.NodePricing) error {
+	before, err := upsertNodePricing(tx, nodePricing)
+	if err != nil {
+		return err
+	}
+	configID, err := clusterConfigID(tx, nodePricing.TenantID, nodePricing.ClusterName)
+	if err != nil {
+		return err
+	}
+	return s.recordVersion(tx, nodePricing.TenantID, configID, models.PricingActionNodeSet, "node", nodePricing.ID, before, nodePricing)
+}
+
+// upsertNodePricing creates a node's override or replaces the existing one, which it
+// returns (nil when created)
+func upsertNodePricing(tx *gorm.DB, nodePricing *models.NodePricing) (*models.NodePricing, error) {
+	nodePricing.UpdatedAt = time.Now()
+
+	var existing models.NodePricing
+	err := tx.Where("tenant_id = ? AND cluster_name = ? AND node_name = ?",
+		nodePricing.TenantID, nodePricing.ClusterName, nodePricing.NodeName).
+		First(&existing).Error
+	if err == gorm.ErrRecordNotFound {
+		return nil, tx.Create(nodePricing).Error
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	nodePricing.ID = existing.ID
+	nodePricing.CreatedAt = existing.CreatedAt
+	return &existing, tx.Save(nodePricing).Error
+}
+
+// clusterConfigID returns the config pricing a cluster, its assigned config or the tenant's
+// default (0 when neither), which node override changes are recorded under
+func clusterConfigID(tx *gorm.DB, tenantID uint, clusterName string) (uint, error) {
+	var clusterPricing models.ClusterPricing
+	err := tx.Where("tenant_id = ? AND cluster_name = ?", tenantID, clusterName).First(&clusterPricing).Error
+	if err == nil {
+		return clusterPricing.ConfigID, nil
+	}
+	if err != gorm.ErrRecordNotFound {
+		return 0, err
+	}
+
+	var config models.PricingConfig
+	err = tx.Where("tenant_id = ? AND is_default = true", tenantID).First(&config).Error
+	if err == gorm.ErrRecordNotFound {
+		return 0, nil
+	}
+	return config.ID, err
+}
+
+// NodePrice is a node's resolved hourly price and where it came from
+type NodePrice struct {
+	Cluster         string              `json:"cluster"`
+	Node            string              `json:"node"`
+	InstanceType    string              `json:"instance_type,omitempty"`
+	Labels          map[string]string   `json:"labels,omitempty"`
+	CPUCores        float64             `json:"cpu_cores"`
+	MemoryGB        float64             `json:"memory_gb"`
+	HourlyCost      float64             `json:"hourly_cost"`
+	CPUPerCoreHour  float64             `json:"cpu_per_core_hour"`
+	MemoryPerGBHour float64             `json:"memory_per_gb_hour"`
+	Source          string              `json:"source"` // node_override, node_instance, instance_type, instance_family, cluster_config, tenant_default, system_default
+	ConfigID        uint                `json:"config_id,omitempty"`
+	ConfigName      string              `json:"config_name,omitempty"`
+	Override        *models.NodePricing `json:"override,omitempty"`
+}
+
+// nodePriceSource combines a node's price source with its cluster's config source: rates
+// from a node override or an instance type/family keep the node source, the config's
+// default rates report how the config was chosen
+func nodePriceSource(source models.PriceSource, configSource models.ConfigSource) string {
+	if source != models.PriceSourceDefault {
+		return string(source)
+	}
+	if configSource == "" {
+		return string(models.ConfigSourceSystemDefault)
+	}
+	return string(configSource)
+}
+
+// ListNodePrices lists the nodes reporting metrics in the window (optionally one cluster's)
+// with their resolved hourly cost, rates and price source, as allocation prices them
+func (s *AllocationService) ListNodePrices(ctx context.Context, tenantID int64, clusterName, window string) ([]NodePrice, error) {
+	start, end, err := s.parseWindow(window)
+	if err != nil {
+		return nil, err
+	}
+
+	nodes, err := s.loadNodeInventory(ctx, tenantID, start, end)
+	if err != nil {
+		return nil, err
+	}
+	labels, err := s.loadNodeLabels(ctx, tenantID, start, end)
+	if err != nil {
+		return nil, err
+	}
+
+	overrides := make(map[string]models.NodePricing)
+	if s.pricingSvc != nil {
+		list, err := s.pricingSvc.ListNodePricing(ctx, uint(tenantID), clusterName)
+		if err != nil {
+			return nil, err
+		}
+		for _, o := range list {
+			overrides[nodeKey(o.ClusterName, o.NodeName)] = o
+		}
+	}
+
+	prices := make([]NodePrice, 0, len(nodes))
+	for _, node := range nodes {
+		if clusterName != "" && node.Cluster != clusterName {
+			continue
+		}
+		memGB := node.MemoryBytes / 1024 / 1024 / 1024
+		price := NodePrice{
+			Cluster:      node.Cluster,
+			Node:         node.Node,
+			InstanceType: node.InstanceType,
+			Labels:       labels[nodeKey(node.Cluster, node.Node)],
+			CPUCores:     node.CPUCores,
+			MemoryGB:     memGB,
+		}
+
+		cpuRate, memRate, source := s.getNodePricing(ctx, tenantID, node, end)
+		var configSource models.ConfigSource
+		if s.pricingSvc != nil {
+			if pricing, err := s.pricingSvc.GetEffectiveRates(ctx, uint(tenantID), node.Cluster, end); err == nil && pricing != nil {
+				configSource = pricing.ConfigSource
+				price.ConfigID = pricing.ConfigID
+				price.ConfigName = pricing.ConfigName
+			}
+		}
+		price.CPUPerCoreHour = cpuRate
+		price.MemoryPerGBHour = memRate
+		price.HourlyCost = node.CPUCores*cpuRate + memGB*memRate
+		price.Source = nodePriceSource(source, configSource)
+		if o, ok := overrides[nodeKey(node.Cluster, node.Node)]; ok {
+			price.Override = &o
+		}
+		prices = append(prices, price)
+	}
+
+	sort.Slice(prices, func(i, j int) bool {
+		if prices[i].Cluster != prices[j].Cluster {
+			return prices[i].Cluster < prices[j].Cluster
+		}
+		return prices[i].Node < prices[j].Node
+	})
+	return prices, nil
+}
+
+// loadNodeLabels returns the latest labels reported for each node in the window, keyed by nodeKey
+func (s *AllocationService) loadNodeLabels(ctx context.Context, tenantID int64, start, end time.Time) (map[string]map[string]string, error) {
+	rows, err := s.pool.Query(ctx, `
+		SELECT DISTINCT ON (cluster_name, node_name) cluster_name, node_name, labels
+		FROM node_metrics
+		WHERE tenant_id = $1 AND time >= $2 AND time <= $3 AND labels IS NOT NULL
+		ORDER BY cluster_name, node_name, time DESC
+	`, tenantID, start, end)
+	if err != nil {
+		return nil, fmt.Errorf("node labels query failed: %w", err)
+	}
+	defer rows.Close()
+
+	labels := make(map[string]map[string]string)
+	for rows.Next() {
+		var cluster, node string
+		var raw []byte
+		if err := rows.Scan(&cluster, &node, &raw); err != nil {
+			return nil, fmt.Errorf("node labels scan failed: %w", err)
+		}
+		var nodeLabels map[string]string
+		if err := json.Unmarshal(raw, &nodeLabels); err != nil {
+			continue
+		}
+		labels[nodeKey(cluster, node)] = nodeLabels
+	}
+	return labels, rows.Err()
+}
+
+// NodeSelector selects the nodes of a cluster for a bulk pricing override: by label
+// selectors ("key=value" or "key", all must match) and/or by reported instance type
+type NodeSelector struct {
+	Labels       []string `json:"labels"`
+	InstanceType string   `json:"instance_type"`
+}
+
+// Validate checks that the selector selects something and its label selectors are well formed
+func (sel NodeSelector) Validate() error {
+	if len(sel.Labels) == 0 && sel.InstanceType == "" {
+		return fmt.Errorf("selector requires labels or instance_type")
+	}
+	for _, selector := range sel.Labels {
+		if key, _, _ := strings.Cut(selector, "="); strings.TrimSpace(key) == "" {
+			return fmt.Errorf("invalid label selector: %q", selector)
+		}
+	}
+	return nil
+}
+
+// Matches reports whether a node matches the selector
+func (sel NodeSelector) Matches(instanceType string, labels map[string]string) bool {
+	if sel.InstanceType != "" && !strings.EqualFold(sel.InstanceType, instanceType) {
+		return false
+	}
+	for _, selector := range sel.Labels {
+		key, value, hasValue := strings.Cut(selector, "=")
+		actual, ok := labels[strings.TrimSpace(key)]
+		if !ok || (hasValue && actual != strings.TrimSpace(value)) {
+			return false
+		}
+	}
+	return true
+}
+
+// SelectNodes returns the names of a cluster's nodes reporting metrics in the window that
+// match the selector
+func (s *AllocationService) SelectNodes(ctx context.Context, tenantID int64, clusterName, window string, sel NodeSelector) ([]string, error) {
+	prices, err := s.ListNodePrices(ctx, tenantID, clusterName, window)
+	if err != nil {
+		return nil, err
+	}
+	var names []string
+	for _, node := range prices {
+		if sel.Matches(node.InstanceType, node.Labels) {
+			names = append(names, node.Node)
+		}
+	}
+	return names, nil
+}
@@ -0,0 +1,45 @@
+package services
+
+import (
+	"testing"
+
+	"github.com/bugfreev587/k8s-cost-api-server/internal/models"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestNodeSelectorMatches(t *testing.T) {
+	labels := map[string]string{
+		"karpenter.sh/capacity-type":       "spot",
+		"node.kubernetes.io/instance-type": "m5.xlarge",
+		"gpu":                              "",
+	}
+
+	sel := NodeSelector{Labels: []string{"karpenter.sh/capacity-type=spot", "gpu"}}
+	assert.NoError(t, sel.Validate())
+	assert.True(t, sel.Matches("m5.xlarge", labels))
+	assert.False(t, sel.Matches("m5.xlarge", map[string]string{"karpenter.sh/capacity-type": "spot"}))
+
+	sel = NodeSelector{Labels: []string{"karpenter.sh/capacity-type=on-demand"}}
+	assert.False(t, sel.Matches("m5.xlarge", labels))
+
+	sel = NodeSelector{InstanceType: "M5.XLarge"}
+	assert.True(t, sel.Matches("m5.xlarge", nil))
+	assert.False(t, sel.Matches("m5.large", nil))
+
+	assert.Error(t, NodeSelector{}.Validate())
+	assert.Error(t, NodeSelector{Labels: []string{"=spot"}}.Validate())
+}
+
+func TestValidateNodePricingAndSource(t *testing.T) {
+	np := &models.NodePricing{InstanceType: "m5.xlarge"}
+	assert.NoError(t, ValidateNodePricing(np))
+	assert.Equal(t, models.TierOnDemand, np.PricingTier)
+
+	zero := 0.0
+	assert.Error(t, ValidateNodePricing(&models.NodePricing{HourlyCostOverride: &zero}))
+	assert.Error(t, ValidateNodePricing(&models.NodePricing{}))
+
+	assert.Equal(t, "node_override", nodePriceSource(models.PriceSourceNodeOverride, models.ConfigSourceCluster))
+	assert.Equal(t, "tenant_default", nodePriceSource(models.PriceSourceDefault, models.ConfigSourceTenantDefault))
+	assert.Equal(t, "system_default", nodePriceSource(models.PriceSourceDefault, ""))
+}
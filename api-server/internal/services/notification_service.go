@@ -0,0 +1,306 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"net/mail"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/bugfreev587/k8s-cost-api-server/internal/models"
+	"github.com/bugfreev587/k8s-cost-api-server/internal/notifications"
+	"gorm.io/gorm"
+)
+
+// notificationTimeout bounds an asynchronous delivery, retries included
+const notificationTimeout = 2 * time.Minute
+
+// NotificationService manages tenants' notification channels and delivers alert
+// notifications to them, recording every delivery
+type NotificationService struct {
+	db          *gorm.DB
+	frontendURL string
+	policy      notifications.RetryPolicy
+}
+
+// NewNotificationService creates a notification service; messages link to the frontend URL
+// when set
+func NewNotificationService(db *gorm.DB, frontendURL string) *NotificationService {
+	return &NotificationService{
+		db:          db,
+		frontendURL: strings.TrimRight(frontendURL, "/"),
+		policy:      notifications.DefaultRetryPolicy,
+	}
+}
+
+var notificationEvents = map[string]bool{
+	models.EventAnomaly:        true,
+	models.EventBudget:         true,
+	models.EventRecommendation: true,
+}
+
+// ValidateChannel checks a notification channel before it is saved and applies defaults
+func ValidateChannel(ch *models.NotificationChannel) error {
+	if strings.TrimSpace(ch.Name) == "" {
+		return fmt.Errorf("name required")
+	}
+	for _, event := range ch.Events {
+		if !notificationEvents[event] {
+			return fmt.Errorf("invalid event: %s (expected anomaly, budget or recommendation)", event)
+		}
+	}
+
+	cfg := &ch.Config
+	switch ch.Type {
+	case models.ChannelSlack, models.ChannelTeams, models.ChannelWebhook:
+		u, err := url.Parse(cfg.URL)
+		if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+			return fmt.Errorf("config.url must be an http(s) URL")
+		}
+	case models.ChannelEmail:
+		if cfg.SMTPHost == "" {
+			return fmt.Errorf("config.smtp_host required")
+		}
+		if cfg.SMTPPort == 0 {
+			cfg.SMTPPort = 587
+		}
+		if cfg.SMTPPort < 0 || cfg.SMTPPort > 65535 {
+			return fmt.Errorf("invalid config.smtp_port: %d", cfg.SMTPPort)
+		}
+		if _, err := mail.ParseAddress(cfg.From); err != nil {
+			return fmt.Errorf("invalid config.from: %q", cfg.From)
+		}
+		if len(cfg.To) == 0 {
+			return fmt.Errorf("config.to requires at least one recipient")
+		}
+		for _, to := range cfg.To {
+			if _, err := mail.ParseAddress(to); err != nil {
+				return fmt.Errorf("invalid recipient: %q", to)
+			}
+		}
+	default:
+		return fmt.Errorf("invalid type: %s (expected slack, teams, webhook or email)", ch.Type)
+	}
+	return nil
+}
+
+// ListChannels lists a tenant's notification channels
+func (s *NotificationService) ListChannels(ctx context.Context, tenantID uint) ([]models.NotificationChannel, error) {
+	var channels []models.NotificationChannel
+	err := s.db.WithContext(ctx).Where("tenant_id = ?", tenantID).Order("name").Find(&channels).Error
+	return channels, err
+}
+
+// GetChannel retrieves a notification channel by ID
+func (s *NotificationService) GetChannel(ctx context.Context, id uint) (*models.NotificationChannel, error) {
+	var channel models.NotificationChannel
+	if err := s.db.WithContext(ctx).First(&channel, id).Error; err != nil {
+		return nil, err
+	}
+	return &channel, nil
+}
+
+// SaveChannel creates or updates a notification channel
+func (s *NotificationService) SaveChannel(ctx context.Context, channel *models.NotificationChannel) error {
+	return s.db.WithContext(ctx).Save(channel).Error
+}
+
+// DeleteChannel deletes a notification channel and its delivery log
+func (s *NotificationService) DeleteChannel(ctx context.Context, id uint) error {
+	return s.db.WithContext(ctx).Delete(&models.NotificationChannel{}, id).Error
+}
+
+// ListDeliveries lists a tenant's deliveries (one channel's when channelID is set), newest first
+func (s *NotificationService) ListDeliveries(ctx context.Context, tenantID, channelID uint, limit int) ([]models.NotificationDelivery, error) {
+	query := s.db.WithContext(ctx).Where("tenant_id = ?", tenantID)
+	if channelID != 0 {
+		query = query.Where("channel_id = ?", channelID)
+	}
+	if limit <= 0 {
+		limit = 100
+	}
+	var deliveries []models.NotificationDelivery
+	err := query.Order("created_at DESC, id DESC").Limit(limit).Find(&deliveries).Error
+	return deliveries, err
+}
+
+// Notify sends a message to the tenant's enabled channels subscribed to its event. Delivery
+// (with retries) runs in the background; a nil service drops the message.
+func (s *NotificationService) Notify(tenantID uint, msg notifications.Message) {
+	if s == nil {
+		return
+	}
+	if msg.Time.IsZero() {
+		msg.Time = time.Now()
+	}
+
+	go func() {
+		ctx, cancel := context.WithTimeout(context.Background(), notificationTimeout)
+		defer cancel()
+
+		var channels []models.NotificationChannel
+		err := s.db.WithContext(ctx).Where("tenant_id = ? AND enabled", tenantID).Find(&channels).Error
+		if err != nil {
+			log.Printf("notifications: failed to load channels for tenant %d: %v", tenantID, err)
+			return
+		}
+		for i := range channels {
+			if channels[i].Subscribed(msg.Event) {
+				s.deliver(ctx, &channels[i], msg, s.policy)
+			}
+		}
+	}()
+}
+
+// SendTest sends a test message to a channel, without retries, and returns its delivery
+func (s *NotificationService) SendTest(ctx context.Context, channel *models.NotificationChannel) *models.NotificationDelivery {
+	msg := notifications.Message{
+		Event:    models.EventTest,
+		Severity: notifications.SeverityInfo,
+		Title:    "Test notification",
+		Text:     fmt.Sprintf("Notifications are set up for channel %q.", channel.Name),
+		Link:     s.link("/dashboard"),
+		Time:     time.Now(),
+	}
+	return s.deliver(ctx, channel, msg, notifications.RetryPolicy{MaxAttempts: 1})
+}
+
+// Send sends a message to a channel, retrying transient failures, and returns its delivery
+func (s *NotificationService) Send(ctx context.Context, channel *models.NotificationChannel, msg notifications.Message) *models.NotificationDelivery {
+	if msg.Time.IsZero() {
+		msg.Time = time.Now()
+	}
+	return s.deliver(ctx, channel, msg, s.policy)
+}
+
+// deliver sends a message to a channel and records the delivery
+func (s *NotificationService) deliver(ctx context.Context, channel *models.NotificationChannel, msg notifications.Message, policy notifications.RetryPolicy) *models.NotificationDelivery {
+	delivery := &models.NotificationDelivery{
+		TenantID:  channel.TenantID,
+		ChannelID: channel.ID,
+		Event:     msg.Event,
+		Title:     msg.Title,
+		Status:    models.DeliverySent,
+	}
+
+	ch, err := notificationChannel(channel)
+	if err == nil {
+		delivery.Attempts, err = notifications.Deliver(ctx, ch, msg, policy)
+	}
+	if err != nil {
+		delivery.Status = models.DeliveryFailed
+		delivery.Error = err.Error()
+		log.Printf("notifications: delivery to channel %d (tenant %d) failed after %d attempt(s): %v",
+			channel.ID, channel.TenantID, delivery.Attempts, err)
+	}
+
+	if err := s.db.WithContext(ctx).Create(delivery).Error; err != nil {
+		log.Printf("notifications: failed to record delivery to channel %d: %v", channel.ID, err)
+	}
+	return delivery
+}
+
+// notificationChannel builds the channel delivering to a configured destination
+func notificationChannel(channel *models.NotificationChannel) (notifications.Channel, error) {
+	cfg := channel.Config
+	switch channel.Type {
+	case models.ChannelSlack:
+		return &notifications.SlackChannel{WebhookURL: cfg.URL}, nil
+	case models.ChannelTeams:
+		return &notifications.TeamsChannel{WebhookURL: cfg.URL}, nil
+	case models.ChannelWebhook:
+		return &notifications.WebhookChannel{URL: cfg.URL, Secret: cfg.Secret, Headers: cfg.Headers}, nil
+	case models.ChannelEmail:
+		return &notifications.EmailChannel{
+			Host:        cfg.SMTPHost,
+			Port:        cfg.SMTPPort,
+			Username:    cfg.Username,
+			Password:    cfg.Password,
+			From:        cfg.From,
+			To:          cfg.To,
+			ImplicitTLS: cfg.ImplicitTLS,
+		}, nil
+	}
+	return nil, fmt.Errorf("unsupported channel type: %s", channel.Type)
+}
+
+// link returns a frontend URL, or "" when the frontend URL is not configured
+func (s *NotificationService) link(path string) string {
+	if s == nil || s.frontendURL == "" {
+		return ""
+	}
+	return s.frontendURL + path
+}
+
+// AnomalyMessage describes a detected cost anomaly
+func (s *NotificationService) AnomalyMessage(anomaly *models.CostAnomaly) notifications.Message {
+	severity := notifications.SeverityWarning
+	if anomaly.Severity == models.AnomalySeverityHigh {
+		severity = notifications.SeverityCritical
+	}
+	change := "spike"
+	if anomaly.Direction == models.AnomalyDrop {
+		change = "drop"
+	}
+
+	msg := notifications.Message{
+		Event:    models.EventAnomaly,
+		Severity: severity,
+		Title:    fmt.Sprintf("Cost %s: %s %s", change, anomaly.Dimension, anomaly.Name),
+		Text: fmt.Sprintf("Cost was $%.2f for the %s starting %s, against a baseline of $%.2f (z-score %.2f).",
+			anomaly.CostUSD, anomaly.Granularity, anomaly.WindowStart.UTC().Format("2006-01-02 15:04 UTC"),
+			anomaly.BaselineCostUSD, anomaly.ZScore),
+		Fields: []notifications.Field{
+			{Name: "Severity", Value: anomaly.Severity},
+			{Name: "Change", Value: fmt.Sprintf("$%+.2f", anomaly.CostUSD-anomaly.BaselineCostUSD)},
+		},
+		Link: s.link("/dashboard"),
+	}
+	for _, d := range anomaly.Drivers {
+		msg.Fields = append(msg.Fields, notifications.Field{Name: "Driver " + d.Name, Value: fmt.Sprintf("$%+.2f", d.ChangeUSD)})
+	}
+	return msg
+}
+
+// BudgetAlertMessage describes a budget threshold or forecast overrun alert
+func (s *NotificationService) BudgetAlertMessage(budget *models.Budget, alert *models.BudgetAlert) notifications.Message {
+	msg := notifications.Message{
+		Event:    models.EventBudget,
+		Severity: notifications.SeverityWarning,
+		Fields: []notifications.Field{
+			{Name: "Period", Value: fmt.Sprintf("%s from %s", budget.Period, alert.PeriodStart.Format("2006-01-02"))},
+			{Name: "Spent", Value: fmt.Sprintf("%.2f %s", alert.Spent, alert.Currency)},
+			{Name: "Forecast", Value: fmt.Sprintf("%.2f %s", alert.Forecast, alert.Currency)},
+			{Name: "Budget", Value: fmt.Sprintf("%.2f %s", alert.Amount, alert.Currency)},
+		},
+		Link: s.link("/dashboard"),
+	}
+	if alert.Type == models.BudgetAlertForecast {
+		msg.Title = fmt.Sprintf("Budget %s is forecast to overrun", budget.Name)
+		msg.Text = fmt.Sprintf("Spend is projected to reach %.2f %s by the end of the period, over the budget of %.2f %s.",
+			alert.Forecast, alert.Currency, alert.Amount, alert.Currency)
+	} else {
+		msg.Title = fmt.Sprintf("Budget %s reached %.0f%%", budget.Name, alert.Threshold)
+		msg.Text = fmt.Sprintf("Spent %.2f of %.2f %s so far this period.", alert.Spent, alert.Amount, alert.Currency)
+		if alert.Threshold >= 100 {
+			msg.Severity = notifications.SeverityCritical
+		}
+	}
+	if len(budget.Filters) > 0 {
+		msg.Fields = append(msg.Fields, notifications.Field{Name: "Scope", Value: strings.Join(budget.Filters, ", ")})
+	}
+	return msg
+}
+
+// RecommendationMessage summarizes the right-sizing recommendations created by a run
+func (s *NotificationService) RecommendationMessage(count int, savings float64, currency string) notifications.Message {
+	return notifications.Message{
+		Event:    models.EventRecommendation,
+		Severity: notifications.SeverityInfo,
+		Title:    fmt.Sprintf("%d new right-sizing recommendations", count),
+		Text:     fmt.Sprintf("Applying the new recommendations could save %.2f %s.", savings, currency),
+		Link:     s.link("/dashboard"),
+	}
+}
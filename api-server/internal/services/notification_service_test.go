@@ -0,0 +1,80 @@
+package services
+
+import (
+	"testing"
+	"time"
+
+	"github.com/bugfreev587/k8s-cost-api-server/internal/models"
+	"github.com/bugfreev587/k8s-cost-api-server/internal/notifications"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestValidateChannel(t *testing.T) {
+	email := &models.NotificationChannel{
+		Name: "finance",
+		Type: models.ChannelEmail,
+		Config: models.NotificationChannelConfig{
+			SMTPHost: "smtp.example.com",
+			From:     "Cost alerts <alerts@example.com>",
+			To:       []string{"finance@example.com"},
+		},
+		Events: []string{models.EventBudget},
+	}
+	require.NoError(t, ValidateChannel(email))
+	assert.Equal(t, 587, email.Config.SMTPPort)
+
+	invalid := []models.NotificationChannel{
+		{Name: "x", Type: "pager", Config: models.NotificationChannelConfig{URL: "https://example.com"}},
+		{Name: "x", Type: models.ChannelSlack, Config: models.NotificationChannelConfig{URL: "hooks.slack.com/x"}},
+		{Name: "x", Type: models.ChannelWebhook, Config: models.NotificationChannelConfig{URL: "ftp://example.com"}},
+		{Name: "x", Type: models.ChannelTeams, Config: models.NotificationChannelConfig{URL: "https://example.com"}, Events: []string{"deploy"}},
+		{Name: "x", Type: models.ChannelEmail, Config: models.NotificationChannelConfig{SMTPHost: "smtp", From: "alerts@example.com"}},
+		{Name: "x", Type: models.ChannelEmail, Config: models.NotificationChannelConfig{SMTPHost: "smtp", From: "alerts", To: []string{"a@example.com"}}},
+		{Name: "", Type: models.ChannelSlack, Config: models.NotificationChannelConfig{URL: "https://example.com"}},
+	}
+	for _, ch := range invalid {
+		ch := ch
+		assert.Error(t, ValidateChannel(&ch), "%+v", ch)
+	}
+}
+
+func TestChannelSubscribed(t *testing.T) {
+	all := &models.NotificationChannel{}
+	assert.True(t, all.Subscribed(models.EventAnomaly))
+
+	budgets := &models.NotificationChannel{Events: []string{models.EventBudget}}
+	assert.True(t, budgets.Subscribed(models.EventBudget))
+	assert.False(t, budgets.Subscribed(models.EventAnomaly))
+	assert.True(t, budgets.Subscribed(models.EventTest))
+}
+
+func TestBudgetAlertMessage(t *testing.T) {
+	svc := NewNotificationService(nil, "https://costs.example.com/")
+	budget := &models.Budget{Name: "platform", Period: models.BudgetPeriodMonthly, Filters: []string{"namespace:web"}}
+	alert := &models.BudgetAlert{
+		PeriodStart: time.Date(2024, 5, 1, 0, 0, 0, 0, time.UTC),
+		Type:        models.BudgetAlertActual,
+		Threshold:   100,
+		Amount:      1000,
+		Spent:       1020,
+		Forecast:    1400,
+		Currency:    "EUR",
+	}
+
+	msg := svc.BudgetAlertMessage(budget, alert)
+	assert.Equal(t, models.EventBudget, msg.Event)
+	assert.Equal(t, notifications.SeverityCritical, msg.Severity)
+	assert.Equal(t, "Budget platform reached 100%", msg.Title)
+	assert.Equal(t, "https://costs.example.com/dashboard", msg.Link)
+	assert.Contains(t, msg.Fields, notifications.Field{Name: "Scope", Value: "namespace:web"})
+
+	alert.Type = models.BudgetAlertForecast
+	msg = svc.BudgetAlertMessage(budget, alert)
+	assert.Equal(t, notifications.SeverityWarning, msg.Severity)
+	assert.Contains(t, msg.Title, "forecast to overrun")
+
+	// Without a service (notifications disabled) messages have no link
+	var disabled *NotificationService
+	assert.Empty(t, disabled.BudgetAlertMessage(budget, alert).Link)
+}
@@ -0,0 +1,242 @@
+package services
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/go-redis/redis/v8"
+
+	"github.com/bugfreev587/k8s-cost-api-server/internal/models"
+)
+
+const (
+	// pricingInvalidateChannel carries the IDs of tenants whose pricing changed
+	pricingInvalidateChannel = "pricing:invalidate"
+
+	// pricingLocalTTL bounds how long an instance serves an entry from memory when Redis is
+	// shared, in case an invalidation message was missed (e.g. during a reconnect)
+	pricingLocalTTL = 1 * time.Minute
+
+	pricingRedisTimeout = 500 * time.Millisecond
+)
+
+// PricingCache caches effective pricing lookups, keyed "<tenantID>:<cluster>:<date>". Entries
+// are kept in memory and, when a Redis client is configured, shared through Redis so every
+// replica sees the same pricing. Invalidating a tenant bumps its generation in Redis, which
+// orphans its shared entries, and is published so other replicas drop their local entries.
+type PricingCache struct {
+	mu        sync.RWMutex
+	entries   map[string]*pricingCacheEntry
+	nextSweep time.Time // when setLocal next removes expired entries
+	ttl       time.Duration
+	rdb       *redis.Client
+}
+
+type pricingCacheEntry struct {
+	pricing   *models.EffectivePricing
+	expiresAt time.Time
+}
+
+// NewPricingCache creates a pricing cache; with a nil Redis client the cache is local to
+// the process
+func NewPricingCache(rdb *redis.Client, ttl time.Duration) *PricingCache {
+	return &PricingCache{
+		entries: make(map[string]*pricingCacheEntry),
+		ttl:     ttl,
+		rdb:     rdb,
+	}
+}
+
+// Get retrieves a cached pricing entry, from memory or else from Redis
+func (c *PricingCache) Get(key string) *models.EffectivePricing {
+	c.mu.RLock()
+	entry, ok := c.entries[key]
+	c.mu.RUnlock()
+	if ok {
+		if time.Now().Before(entry.expiresAt) {
+			return entry.pricing
+		}
+		c.mu.Lock()
+		if c.entries[key] == entry {
+			delete(c.entries, key)
+		}
+		c.mu.Unlock()
+	}
+	if c.rdb == nil {
+		return nil
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), pricingRedisTimeout)
+	defer cancel()
+
+	redisKey, err := c.redisKey(ctx, key)
+	if err != nil {
+		return nil
+	}
+	data, err := c.rdb.Get(ctx, redisKey).Bytes()
+	if err != nil {
+		if err != redis.Nil {
+			log.Printf("pricing cache: redis get failed: %v", err)
+		}
+		return nil
+	}
+	var pricing models.EffectivePricing
+	if err := json.Unmarshal(data, &pricing); err != nil {
+		return nil
+	}
+	c.setLocal(key, &pricing)
+	return &pricing
+}
+
+// Set stores a pricing entry in cache
+func (c *PricingCache) Set(key string, pricing *models.EffectivePricing) {
+	c.setLocal(key, pricing)
+	if c.rdb == nil {
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), pricingRedisTimeout)
+	defer cancel()
+
+	data, err := json.Marshal(pricing)
+	if err != nil {
+		return
+	}
+	redisKey, err := c.redisKey(ctx, key)
+	if err != nil {
+		return
+	}
+	if err := c.rdb.Set(ctx, redisKey, data, c.ttl).Err(); err != nil {
+		log.Printf("pricing cache: redis set failed: %v", err)
+	}
+}
+
+// InvalidateTenant removes all cached entries for a tenant, on every replica sharing the cache
+func (c *PricingCache) InvalidateTenant(tenantID uint) {
+	c.dropTenant(tenantID)
+	if c.rdb == nil {
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), pricingRedisTimeout)
+	defer cancel()
+
+	if err := c.rdb.Incr(ctx, pricingGenerationKey(tenantID)).Err(); err != nil {
+		log.Printf("pricing cache: failed to invalidate tenant %d: %v", tenantID, err)
+	}
+	if err := c.rdb.Publish(ctx, pricingInvalidateChannel, strconv.FormatUint(uint64(tenantID), 10)).Err(); err != nil {
+		log.Printf("pricing cache: failed to publish invalidation for tenant %d: %v", tenantID, err)
+	}
+}
+
+// Clear removes all cached entries held in memory
+func (c *PricingCache) Clear() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.entries = make(map[string]*pricingCacheEntry)
+}
+
+// Subscribe listens for invalidations published by other replicas and drops the affected
+// tenants' entries from memory until ctx is cancelled. It returns immediately when the
+// cache has no Redis client.
+func (c *PricingCache) Subscribe(ctx context.Context) {
+	if c.rdb == nil {
+		return
+	}
+
+	sub := c.rdb.Subscribe(ctx, pricingInvalidateChannel)
+	defer sub.Close()
+
+	ch := sub.Channel()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case msg, ok := <-ch:
+			if !ok {
+				return
+			}
+			tenantID, err := strconv.ParseUint(msg.Payload, 10, 64)
+			if err != nil {
+				continue
+			}
+			c.dropTenant(uint(tenantID))
+		}
+	}
+}
+
+// setLocal stores an entry in memory; with Redis shared its lifetime is capped so a missed
+// invalidation cannot serve stale pricing for long. Expired entries are swept at most once
+// per lifetime, so entries that are never read again do not accumulate.
+func (c *PricingCache) setLocal(key string, pricing *models.EffectivePricing) {
+	ttl := c.ttl
+	if c.rdb != nil && ttl > pricingLocalTTL {
+		ttl = pricingLocalTTL
+	}
+
+	now := time.Now()
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if !now.Before(c.nextSweep) {
+		for k, entry := range c.entries {
+			if !now.Before(entry.expiresAt) {
+				delete(c.entries, k)
+			}
+		}
+		c.nextSweep = now.Add(ttl)
+	}
+	c.entries[key] = &pricingCacheEntry{
+		pricing:   pricing,
+		expiresAt: now.Add(ttl),
+	}
+}
+
+// dropTenant removes a tenant's entries from memory
+func (c *PricingCache) dropTenant(tenantID uint) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	prefix := fmt.Sprintf("%d:", tenantID)
+	for key := range c.entries {
+		if strings.HasPrefix(key, prefix) {
+			delete(c.entries, key)
+		}
+	}
+}
+
+// redisKey returns the Redis key of a cache key under its tenant's current generation
+func (c *PricingCache) redisKey(ctx context.Context, key string) (string, error) {
+	tenantID, ok := pricingCacheTenant(key)
+	if !ok {
+		return "", fmt.Errorf("invalid pricing cache key: %s", key)
+	}
+	gen, err := c.rdb.Get(ctx, pricingGenerationKey(tenantID)).Int64()
+	if err != nil && err != redis.Nil {
+		return "", err
+	}
+	return fmt.Sprintf("pricing:%d:%s", gen, key), nil
+}
+
+// pricingGenerationKey is the Redis key of a tenant's cache generation
+func pricingGenerationKey(tenantID uint) string {
+	return fmt.Sprintf("pricing:gen:%d", tenantID)
+}
+
+// pricingCacheTenant returns the tenant ID a cache key belongs to
+func pricingCacheTenant(key string) (uint, bool) {
+	prefix, _, found := strings.Cut(key, ":")
+	if !found {
+		return 0, false
+	}
+	tenantID, err := strconv.ParseUint(prefix, 10, 64)
+	if err != nil {
+		return 0, false
+	}
+	return uint(tenantID), true
+}
@@ -0,0 +1,266 @@
+package services
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"io"
+	"net"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/bugfreev587/k8s-cost-api-server/internal/models"
+	"github.com/go-redis/redis/v8"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestPricingCacheInvalidateTenant(t *testing.T) {
+	cache := NewPricingCache(nil, time.Hour)
+	cache.Set("1:prod:2024-01-01", &models.EffectivePricing{CPUPerCoreHour: 0.03})
+	cache.Set("12:prod:2024-01-01", &models.EffectivePricing{CPUPerCoreHour: 0.04})
+
+	assert.Equal(t, 0.03, cache.Get("1:prod:2024-01-01").CPUPerCoreHour)
+
+	cache.InvalidateTenant(1)
+	assert.Nil(t, cache.Get("1:prod:2024-01-01"))
+	assert.NotNil(t, cache.Get("12:prod:2024-01-01"), "tenant 12 shares the prefix digit but not the tenant")
+}
+
+func TestPricingCacheTenant(t *testing.T) {
+	tenantID, ok := pricingCacheTenant("42:prod:2024-01-01@2024-01-02T00:00:00Z")
+	assert.True(t, ok)
+	assert.Equal(t, uint(42), tenantID)
+
+	_, ok = pricingCacheTenant("prod")
+	assert.False(t, ok)
+	_, ok = pricingCacheTenant("x:prod")
+	assert.False(t, ok)
+}
+
+func TestPricingCacheExpiry(t *testing.T) {
+	cache := NewPricingCache(nil, 20*time.Millisecond)
+	cache.Set("1:prod:2024-01-01", &models.EffectivePricing{CPUPerCoreHour: 0.03})
+	cache.Set("1:prod:2024-01-02", &models.EffectivePricing{CPUPerCoreHour: 0.03})
+	time.Sleep(30 * time.Millisecond)
+
+	assert.Nil(t, cache.Get("1:prod:2024-01-01"))
+	assert.False(t, cachedLocally(cache, "1:prod:2024-01-01"), "expired entries are removed when read")
+
+	cache.Set("1:prod:2024-01-03", &models.EffectivePricing{CPUPerCoreHour: 0.03})
+	assert.False(t, cachedLocally(cache, "1:prod:2024-01-02"), "expired entries are swept when others are stored")
+	assert.True(t, cachedLocally(cache, "1:prod:2024-01-03"))
+}
+
+func TestPricingCacheSharedThroughRedis(t *testing.T) {
+	srv := newFakeRedis(t)
+	replicaA := NewPricingCache(srv.client(t), time.Hour)
+	replicaB := NewPricingCache(srv.client(t), time.Hour)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go replicaB.Subscribe(ctx)
+	require.Eventually(t, func() bool { return srv.subscribers(pricingInvalidateChannel) == 1 }, time.Second, 5*time.Millisecond)
+
+	replicaA.Set("1:prod:2024-01-01", &models.EffectivePricing{CPUPerCoreHour: 0.03})
+	replicaA.Set("12:prod:2024-01-01", &models.EffectivePricing{CPUPerCoreHour: 0.04})
+	assert.True(t, srv.has("pricing:0:1:prod:2024-01-01"), "entries are stored under the tenant's generation")
+
+	// Replica B reads A's entries from Redis and keeps them in memory
+	pricing := replicaB.Get("1:prod:2024-01-01")
+	require.NotNil(t, pricing)
+	assert.Equal(t, 0.03, pricing.CPUPerCoreHour)
+	assert.True(t, cachedLocally(replicaB, "1:prod:2024-01-01"))
+	require.NotNil(t, replicaB.Get("12:prod:2024-01-01"))
+
+	// Invalidating on A bumps the tenant's generation and B drops its local entries
+	replicaA.InvalidateTenant(1)
+	assert.Equal(t, "1", srv.value(pricingGenerationKey(1)))
+	require.Eventually(t, func() bool { return !cachedLocally(replicaB, "1:prod:2024-01-01") }, time.Second, 5*time.Millisecond)
+	assert.True(t, cachedLocally(replicaB, "12:prod:2024-01-01"))
+	assert.Nil(t, replicaB.Get("1:prod:2024-01-01"), "the previous generation's entry is orphaned")
+
+	// Entries stored after the invalidation are shared under the new generation
+	replicaB.Set("1:prod:2024-01-01", &models.EffectivePricing{CPUPerCoreHour: 0.05})
+	assert.True(t, srv.has("pricing:1:1:prod:2024-01-01"))
+	replicaA.Clear()
+	pricing = replicaA.Get("1:prod:2024-01-01")
+	require.NotNil(t, pricing)
+	assert.Equal(t, 0.05, pricing.CPUPerCoreHour)
+}
+
+// cachedLocally reports whether a cache holds an entry in memory, expired or not
+func cachedLocally(c *PricingCache, key string) bool {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	_, ok := c.entries[key]
+	return ok
+}
+
+// fakeRedis is an in-process server speaking enough of the Redis protocol for the pricing
+// cache: GET, SET, INCR, PUBLISH, SUBSCRIBE and PING
+type fakeRedis struct {
+	ln   net.Listener
+	mu   sync.Mutex
+	data map[string]string
+	subs map[string][]*fakeRedisConn
+}
+
+type fakeRedisConn struct {
+	mu sync.Mutex
+	w  *bufio.Writer
+}
+
+func newFakeRedis(t *testing.T) *fakeRedis {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	srv := &fakeRedis{ln: ln, data: make(map[string]string), subs: make(map[string][]*fakeRedisConn)}
+	t.Cleanup(func() { ln.Close() })
+	go func() {
+		for {
+			conn, err := ln.Accept()
+			if err != nil {
+				return
+			}
+			go srv.serve(conn)
+		}
+	}()
+	return srv
+}
+
+// client returns a Redis client of the server, closed when the test ends
+func (f *fakeRedis) client(t *testing.T) *redis.Client {
+	rdb := redis.NewClient(&redis.Options{Addr: f.ln.Addr().String()})
+	t.Cleanup(func() { rdb.Close() })
+	return rdb
+}
+
+func (f *fakeRedis) has(key string) bool {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	_, ok := f.data[key]
+	return ok
+}
+
+func (f *fakeRedis) value(key string) string {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return f.data[key]
+}
+
+func (f *fakeRedis) subscribers(channel string) int {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return len(f.subs[channel])
+}
+
+func (f *fakeRedis) serve(conn net.Conn) {
+	defer conn.Close()
+	c := &fakeRedisConn{w: bufio.NewWriter(conn)}
+	defer f.unsubscribe(c)
+
+	r := bufio.NewReader(conn)
+	for {
+		args, err := readRedisCommand(r)
+		if err != nil {
+			return
+		}
+		c.send(f.exec(c, args))
+	}
+}
+
+// exec runs a command and returns its encoded reply
+func (f *fakeRedis) exec(c *fakeRedisConn, args []string) string {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	switch strings.ToUpper(args[0]) {
+	case "PING":
+		return "+PONG\r\n"
+	case "GET":
+		value, ok := f.data[args[1]]
+		if !ok {
+			return "$-1\r\n"
+		}
+		return redisBulk(value)
+	case "SET":
+		f.data[args[1]] = args[2]
+		return "+OK\r\n"
+	case "INCR":
+		n, _ := strconv.ParseInt(f.data[args[1]], 10, 64)
+		n++
+		f.data[args[1]] = strconv.FormatInt(n, 10)
+		return fmt.Sprintf(":%d\r\n", n)
+	case "PUBLISH":
+		for _, sub := range f.subs[args[1]] {
+			go sub.send("*3\r\n" + redisBulk("message") + redisBulk(args[1]) + redisBulk(args[2]))
+		}
+		return fmt.Sprintf(":%d\r\n", len(f.subs[args[1]]))
+	case "SUBSCRIBE":
+		var reply string
+		for i, channel := range args[1:] {
+			f.subs[channel] = append(f.subs[channel], c)
+			reply += "*3\r\n" + redisBulk("subscribe") + redisBulk(channel) + fmt.Sprintf(":%d\r\n", i+1)
+		}
+		return reply
+	default:
+		return "-ERR unknown command '" + args[0] + "'\r\n"
+	}
+}
+
+// unsubscribe removes a closed connection from every channel
+func (f *fakeRedis) unsubscribe(c *fakeRedisConn) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	for channel, subs := range f.subs {
+		for i, sub := range subs {
+			if sub == c {
+				f.subs[channel] = append(subs[:i], subs[i+1:]...)
+				break
+			}
+		}
+	}
+}
+
+func (c *fakeRedisConn) send(reply string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.w.WriteString(reply)
+	c.w.Flush()
+}
+
+// readRedisCommand reads a command sent as an array of bulk strings
+func readRedisCommand(r *bufio.Reader) ([]string, error) {
+	line, err := r.ReadString('\n')
+	if err != nil {
+		return nil, err
+	}
+	n, err := strconv.Atoi(strings.TrimSpace(strings.TrimPrefix(line, "*")))
+	if err != nil || n <= 0 {
+		return nil, fmt.Errorf("invalid command header: %q", line)
+	}
+
+	args := make([]string, n)
+	for i := range args {
+		if line, err = r.ReadString('\n'); err != nil {
+			return nil, err
+		}
+		size, err := strconv.Atoi(strings.TrimSpace(strings.TrimPrefix(line, "$")))
+		if err != nil {
+			return nil, fmt.Errorf("invalid bulk string header: %q", line)
+		}
+		buf := make([]byte, size+2)
+		if _, err := io.ReadFull(r, buf); err != nil {
+			return nil, err
+		}
+		args[i] = string(buf[:size])
+	}
+	return args, nil
+}
+
+func redisBulk(s string) string {
+	return fmt.Sprintf("$%d\r\n%s\r\n", len(s), s)
+}
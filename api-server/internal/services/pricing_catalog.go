@@ -0,0 +1,287 @@
+package services
+
+import (
+	"bufio"
+	"compress/gzip"
+	"embed"
+	"encoding/json"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/bugfreev587/k8s-cost-api-server/internal/models"
+)
+
+// CatalogEntry is one price from a provider price list: either a whole instance price
+// with its shape (AWS, Azure), or per-unit CPU/RAM/GPU rates of a family (GCP)
+type CatalogEntry struct {
+	InstanceFamily  string             `json:"instance_family"`
+	InstanceType    string             `json:"instance_type,omitempty"`
+	Tier            models.PricingTier `json:"tier"`
+	VCPUs           float64            `json:"vcpus,omitempty"`
+	MemoryGB        float64            `json:"memory_gb,omitempty"`
+	GPUs            float64            `json:"gpus,omitempty"`
+	HourlyCost      float64            `json:"hourly_cost,omitempty"`
+	CPUPerCoreHour  float64            `json:"cpu_per_core_hour,omitempty"`
+	MemoryPerGBHour float64            `json:"memory_per_gb_hour,omitempty"`
+	GPUPerHour      float64            `json:"gpu_per_hour,omitempty"`
+}
+
+// snapshotCatalog is the format of the bundled price list snapshots
+type snapshotCatalog struct {
+	Provider   models.CloudProvider      `json:"provider"`
+	CapturedAt string                    `json:"captured_at"`
+	Regions    map[string][]CatalogEntry `json:"regions"`
+}
+
+//go:embed catalog/*.json
+var snapshotFiles embed.FS
+
+// SnapshotCatalogInfo describes the bundled price list snapshot of a provider
+type SnapshotCatalogInfo struct {
+	Provider   models.CloudProvider `json:"provider"`
+	CapturedAt string               `json:"captured_at"`
+	Regions    []string             `json:"regions"`
+}
+
+// loadSnapshotCatalog reads the bundled snapshot of a provider
+func loadSnapshotCatalog(provider models.CloudProvider) (*snapshotCatalog, error) {
+	data, err := snapshotFiles.ReadFile("catalog/" + string(provider) + ".json")
+	if err != nil {
+		return nil, fmt.Errorf("no bundled price catalog for provider %s", provider)
+	}
+	var catalog snapshotCatalog
+	if err := json.Unmarshal(data, &catalog); err != nil {
+		return nil, fmt.Errorf("invalid bundled price catalog for %s: %w", provider, err)
+	}
+	return &catalog, nil
+}
+
+// GetSnapshotCatalogInfo returns the capture date and regions of a provider's bundled snapshot
+func GetSnapshotCatalogInfo(provider models.CloudProvider) (*SnapshotCatalogInfo, error) {
+	catalog, err := loadSnapshotCatalog(provider)
+	if err != nil {
+		return nil, err
+	}
+	info := &SnapshotCatalogInfo{Provider: provider, CapturedAt: catalog.CapturedAt}
+	for region := range catalog.Regions {
+		info.Regions = append(info.Regions, region)
+	}
+	sort.Strings(info.Regions)
+	return info, nil
+}
+
+// SnapshotCatalogEntries returns the bundled snapshot prices for a region and tier
+func SnapshotCatalogEntries(provider models.CloudProvider, region string, tier models.PricingTier) ([]CatalogEntry, error) {
+	catalog, err := loadSnapshotCatalog(provider)
+	if err != nil {
+		return nil, err
+	}
+	entries, ok := catalog.Regions[region]
+	if !ok {
+		regions := make([]string, 0, len(catalog.Regions))
+		for r := range catalog.Regions {
+			regions = append(regions, r)
+		}
+		sort.Strings(regions)
+		return nil, fmt.Errorf("bundled %s catalog has no region %q (available: %s)", provider, region, strings.Join(regions, ", "))
+	}
+
+	var matched []CatalogEntry
+	for _, e := range entries {
+		if e.Tier == tier || (tier == models.TierPreemptible && e.Tier == models.TierSpot) {
+			matched = append(matched, e)
+		}
+	}
+	if len(matched) == 0 {
+		return nil, fmt.Errorf("bundled %s catalog has no %s prices for %s", provider, tier, region)
+	}
+	return matched, nil
+}
+
+// ParsePriceList parses an uploaded provider price list (optionally gzipped) into catalog
+// entries for a region and tier: an AWS Price List offer file, a GCP Cloud Billing Catalog
+// SKU export, or an Azure Retail Prices API response
+func ParsePriceList(provider models.CloudProvider, r io.Reader, region string, tier models.PricingTier) ([]CatalogEntry, error) {
+	br := bufio.NewReader(r)
+	if magic, err := br.Peek(2); err == nil && magic[0] == 0x1f && magic[1] == 0x8b {
+		gz, err := gzip.NewReader(br)
+		if err != nil {
+			return nil, fmt.Errorf("invalid gzip data: %w", err)
+		}
+		defer gz.Close()
+		br = bufio.NewReader(gz)
+	}
+
+	var entries []CatalogEntry
+	var err error
+	switch provider {
+	case models.ProviderAWS:
+		entries, err = parseAWSPriceList(br, region, tier)
+	case models.ProviderGCP:
+		entries, err = parseGCPSkus(br, region, tier)
+	case models.ProviderAzure:
+		entries, err = parseAzureRetailPrices(br, region, tier)
+	default:
+		return nil, fmt.Errorf("price list import is not supported for provider %s", provider)
+	}
+	if err != nil {
+		return nil, err
+	}
+	if len(entries) == 0 {
+		return nil, fmt.Errorf("no %s compute prices found for region %q", tier, region)
+	}
+	return entries, nil
+}
+
+// familyRates accumulates per-unit rates of the instance types in a family
+type familyRates struct {
+	cpu, mem, gpu          float64
+	cpuWeight, memWeight   float64
+	gpuWeight              float64
+	hasCPU, hasMem, hasGPU bool
+}
+
+func (f *familyRates) add(cpuRate, cpuWeight, memRate, memWeight, gpuRate, gpuWeight float64) {
+	if cpuWeight > 0 {
+		f.cpu += cpuRate * cpuWeight
+		f.cpuWeight += cpuWeight
+		f.hasCPU = true
+	}
+	if memWeight > 0 {
+		f.mem += memRate * memWeight
+		f.memWeight += memWeight
+		f.hasMem = true
+	}
+	if gpuWeight > 0 {
+		f.gpu += gpuRate * gpuWeight
+		f.gpuWeight += gpuWeight
+		f.hasGPU = true
+	}
+}
+
+// CatalogRates converts catalog entries into per-instance-family pricing rates.
+//
+// Whole instance prices are split into CPU and RAM using the provider's default on-demand
+// CPU:RAM rate ratio. For GPU instances, CPU and RAM are first priced at the default
+// rates (at most half the instance price) and the remainder is the GPU price. Rates of
+// the instance types in a family are averaged, weighted by their vCPUs, memory and GPUs.
+func CatalogRates(provider models.CloudProvider, entries []CatalogEntry, tier models.PricingTier, effectiveFrom time.Time) []models.PricingRate {
+	baseCPU := models.GetDefaultCPURate(provider, models.TierOnDemand)
+	baseMem := models.GetDefaultMemoryRate(provider, models.TierOnDemand)
+
+	families := make(map[string]*familyRates)
+	var order []string
+	for _, e := range entries {
+		family := strings.ToLower(e.InstanceFamily)
+		if family == "" {
+			continue
+		}
+		f, ok := families[family]
+		if !ok {
+			f = &familyRates{}
+			families[family] = f
+			order = append(order, family)
+		}
+
+		if e.HourlyCost <= 0 {
+			// Per-unit rates (GCP); weight equally
+			var cpuW, memW, gpuW float64
+			if e.CPUPerCoreHour > 0 {
+				cpuW = 1
+			}
+			if e.MemoryPerGBHour > 0 {
+				memW = 1
+			}
+			if e.GPUPerHour > 0 {
+				gpuW = 1
+			}
+			f.add(e.CPUPerCoreHour, cpuW, e.MemoryPerGBHour, memW, e.GPUPerHour, gpuW)
+			continue
+		}
+		if e.VCPUs <= 0 || e.MemoryGB <= 0 {
+			continue
+		}
+
+		base := e.VCPUs*baseCPU + e.MemoryGB*baseMem
+		var gpuRate float64
+		if e.GPUs > 0 {
+			if base > e.HourlyCost/2 {
+				base = e.HourlyCost / 2
+			}
+			gpuRate = (e.HourlyCost - base) / e.GPUs
+		} else {
+			base = e.HourlyCost
+		}
+		scale := base / (e.VCPUs*baseCPU + e.MemoryGB*baseMem)
+		f.add(baseCPU*scale, e.VCPUs, baseMem*scale, e.MemoryGB, gpuRate, e.GPUs)
+	}
+
+	sort.Strings(order)
+	var rates []models.PricingRate
+	for _, family := range order {
+		f := families[family]
+		add := func(resource models.ResourceType, unit string, cost float64) {
+			rates = append(rates, models.PricingRate{
+				ResourceType:   resource,
+				PricingTier:    tier,
+				InstanceFamily: family,
+				Unit:           unit,
+				CostPerUnit:    cost,
+				EffectiveFrom:  effectiveFrom,
+			})
+		}
+		if f.hasCPU {
+			add(models.ResourceCPU, "core-hour", f.cpu/f.cpuWeight)
+		}
+		if f.hasMem {
+			add(models.ResourceMemory, "gb-hour", f.mem/f.memWeight)
+		}
+		if f.hasGPU {
+			add(models.ResourceGPU, "gpu-hour", f.gpu/f.gpuWeight)
+		}
+	}
+	return rates
+}
+
+// skipJSONValue consumes the next JSON value from the decoder
+func skipJSONValue(dec *json.Decoder) error {
+	var skip json.RawMessage
+	return dec.Decode(&skip)
+}
+
+// expectDelim reads the next token and checks that it is the given delimiter
+func expectDelim(dec *json.Decoder, delim json.Delim) error {
+	tok, err := dec.Token()
+	if err != nil {
+		return err
+	}
+	if d, ok := tok.(json.Delim); !ok || d != delim {
+		return fmt.Errorf("unexpected JSON token %v (expected %v)", tok, delim)
+	}
+	return nil
+}
+
+// decodeObject streams a JSON object, calling fn for each key with the decoder positioned
+// at the key's value; fn must consume the value
+func decodeObject(dec *json.Decoder, fn func(key string) error) error {
+	if err := expectDelim(dec, '{'); err != nil {
+		return err
+	}
+	for dec.More() {
+		tok, err := dec.Token()
+		if err != nil {
+			return err
+		}
+		key, ok := tok.(string)
+		if !ok {
+			return fmt.Errorf("unexpected JSON object key %v", tok)
+		}
+		if err := fn(key); err != nil {
+			return err
+		}
+	}
+	return expectDelim(dec, '}')
+}
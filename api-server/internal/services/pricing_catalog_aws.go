@@ -0,0 +1,170 @@
+package services
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+
+	"github.com/bugfreev587/k8s-cost-api-server/internal/models"
+)
+
+// awsProduct is a product of the AWS Price List offer file (AmazonEC2 index.json)
+type awsProduct struct {
+	SKU           string            `json:"sku"`
+	ProductFamily string            `json:"productFamily"`
+	Attributes    map[string]string `json:"attributes"`
+}
+
+// awsTerm is an offer term of a product, with its price dimensions
+type awsTerm struct {
+	TermAttributes  map[string]string `json:"termAttributes"`
+	PriceDimensions map[string]struct {
+		Unit         string            `json:"unit"`
+		PricePerUnit map[string]string `json:"pricePerUnit"`
+	} `json:"priceDimensions"`
+}
+
+// awsTermType maps a pricing tier to the offer file term type and lease length
+func awsTermType(tier models.PricingTier) (termType, lease string, err error) {
+	switch tier {
+	case models.TierOnDemand:
+		return "OnDemand", "", nil
+	case models.TierReserved1Yr:
+		return "Reserved", "1yr", nil
+	case models.TierReserved3Yr:
+		return "Reserved", "3yr", nil
+	default:
+		return "", "", fmt.Errorf("the AWS price list has no %s prices (supported: %s, %s, %s)",
+			tier, models.TierOnDemand, models.TierReserved1Yr, models.TierReserved3Yr)
+	}
+}
+
+// awsInstanceProduct reports whether a product is a shared-tenancy Linux instance in the region
+func awsInstanceProduct(p *awsProduct, region string) bool {
+	a := p.Attributes
+	if p.ProductFamily != "Compute Instance" || a["instanceType"] == "" {
+		return false
+	}
+	if a["operatingSystem"] != "Linux" || a["tenancy"] != "Shared" {
+		return false
+	}
+	if sw := a["preInstalledSw"]; sw != "" && sw != "NA" {
+		return false
+	}
+	if cs := a["capacitystatus"]; cs != "" && cs != "Used" {
+		return false
+	}
+	if rc := a["regionCode"]; region != "" && rc != "" && rc != region {
+		return false
+	}
+	return true
+}
+
+// awsHourlyPrice returns the USD hourly price of a term ("Hrs" dimension)
+func awsHourlyPrice(term *awsTerm) (float64, bool) {
+	for _, dim := range term.PriceDimensions {
+		if !strings.EqualFold(dim.Unit, "Hrs") {
+			continue
+		}
+		price, err := strconv.ParseFloat(dim.PricePerUnit["USD"], 64)
+		if err == nil && price > 0 {
+			return price, true
+		}
+	}
+	return 0, false
+}
+
+// awsQuantity parses attribute values such as "4", "16 GiB" or "1,952 GiB"
+func awsQuantity(value string) float64 {
+	value = strings.ReplaceAll(value, ",", "")
+	if fields := strings.Fields(value); len(fields) > 0 {
+		v, _ := strconv.ParseFloat(fields[0], 64)
+		return v
+	}
+	return 0
+}
+
+// awsInstanceFamily returns the family of an EC2 instance type ("m5.xlarge" -> "m5")
+func awsInstanceFamily(instanceType string) string {
+	family, _, _ := strings.Cut(instanceType, ".")
+	return strings.ToLower(family)
+}
+
+// parseAWSPriceList streams an AWS Price List offer file. Products and terms are joined by
+// SKU; reserved prices use the standard, no upfront offering.
+func parseAWSPriceList(r io.Reader, region string, tier models.PricingTier) ([]CatalogEntry, error) {
+	termType, lease, err := awsTermType(tier)
+	if err != nil {
+		return nil, err
+	}
+
+	products := make(map[string]*awsProduct)
+	prices := make(map[string]float64)
+	dec := json.NewDecoder(r)
+	err = decodeObject(dec, func(key string) error {
+		switch key {
+		case "products":
+			return decodeObject(dec, func(string) error {
+				var p awsProduct
+				if err := dec.Decode(&p); err != nil {
+					return err
+				}
+				if awsInstanceProduct(&p, region) {
+					products[p.SKU] = &p
+				}
+				return nil
+			})
+		case "terms":
+			return decodeObject(dec, func(kind string) error {
+				if kind != termType {
+					return skipJSONValue(dec)
+				}
+				return decodeObject(dec, func(sku string) error {
+					var terms map[string]awsTerm
+					if err := dec.Decode(&terms); err != nil {
+						return err
+					}
+					for _, term := range terms {
+						if lease != "" {
+							a := term.TermAttributes
+							if a["LeaseContractLength"] != lease || a["PurchaseOption"] != "No Upfront" ||
+								(a["OfferingClass"] != "" && a["OfferingClass"] != "standard") {
+								continue
+							}
+						}
+						if price, ok := awsHourlyPrice(&term); ok {
+							prices[sku] = price
+						}
+					}
+					return nil
+				})
+			})
+		default:
+			return skipJSONValue(dec)
+		}
+	})
+	if err != nil {
+		return nil, fmt.Errorf("invalid AWS price list: %w", err)
+	}
+
+	var entries []CatalogEntry
+	for sku, p := range products {
+		price, ok := prices[sku]
+		if !ok {
+			continue
+		}
+		a := p.Attributes
+		entries = append(entries, CatalogEntry{
+			InstanceFamily: awsInstanceFamily(a["instanceType"]),
+			InstanceType:   a["instanceType"],
+			Tier:           tier,
+			VCPUs:          awsQuantity(a["vcpu"]),
+			MemoryGB:       awsQuantity(a["memory"]),
+			GPUs:           awsQuantity(a["gpu"]),
+			HourlyCost:     price,
+		})
+	}
+	return entries, nil
+}
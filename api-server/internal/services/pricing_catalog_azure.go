@@ -0,0 +1,179 @@
+package services
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/bugfreev587/k8s-cost-api-server/internal/models"
+)
+
+// azureRetailPrice is an item of the Azure Retail Prices API (prices.azure.com/api/retail/prices)
+type azureRetailPrice struct {
+	CurrencyCode    string  `json:"currencyCode"`
+	RetailPrice     float64 `json:"retailPrice"`
+	UnitPrice       float64 `json:"unitPrice"`
+	ArmRegionName   string  `json:"armRegionName"`
+	ArmSkuName      string  `json:"armSkuName"`
+	ProductName     string  `json:"productName"`
+	SkuName         string  `json:"skuName"`
+	ServiceName     string  `json:"serviceName"`
+	Type            string  `json:"type"`
+	ReservationTerm string  `json:"reservationTerm"`
+	UnitOfMeasure   string  `json:"unitOfMeasure"`
+}
+
+// azureVMShape is the vCPU, memory and GPU count of a VM size
+type azureVMShape struct {
+	VCPUs    float64
+	MemoryGB float64
+	GPUs     float64
+}
+
+// azureVMShapes lists common VM sizes; the retail prices API does not include VM shapes,
+// so sizes not listed here are skipped
+var azureVMShapes = map[string]azureVMShape{
+	"standard_b2s":          {2, 4, 0},
+	"standard_b2ms":         {2, 8, 0},
+	"standard_b4ms":         {4, 16, 0},
+	"standard_b8ms":         {8, 32, 0},
+	"standard_d2s_v3":       {2, 8, 0},
+	"standard_d4s_v3":       {4, 16, 0},
+	"standard_d8s_v3":       {8, 32, 0},
+	"standard_d16s_v3":      {16, 64, 0},
+	"standard_d32s_v3":      {32, 128, 0},
+	"standard_d2s_v4":       {2, 8, 0},
+	"standard_d4s_v4":       {4, 16, 0},
+	"standard_d8s_v4":       {8, 32, 0},
+	"standard_d16s_v4":      {16, 64, 0},
+	"standard_d2s_v5":       {2, 8, 0},
+	"standard_d4s_v5":       {4, 16, 0},
+	"standard_d8s_v5":       {8, 32, 0},
+	"standard_d16s_v5":      {16, 64, 0},
+	"standard_d32s_v5":      {32, 128, 0},
+	"standard_d2as_v5":      {2, 8, 0},
+	"standard_d4as_v5":      {4, 16, 0},
+	"standard_d8as_v5":      {8, 32, 0},
+	"standard_d16as_v5":     {16, 64, 0},
+	"standard_e2s_v3":       {2, 16, 0},
+	"standard_e4s_v3":       {4, 32, 0},
+	"standard_e8s_v3":       {8, 64, 0},
+	"standard_e16s_v3":      {16, 128, 0},
+	"standard_e2s_v5":       {2, 16, 0},
+	"standard_e4s_v5":       {4, 32, 0},
+	"standard_e8s_v5":       {8, 64, 0},
+	"standard_e16s_v5":      {16, 128, 0},
+	"standard_f2s_v2":       {2, 4, 0},
+	"standard_f4s_v2":       {4, 8, 0},
+	"standard_f8s_v2":       {8, 16, 0},
+	"standard_f16s_v2":      {16, 32, 0},
+	"standard_nc6s_v3":      {6, 112, 1},
+	"standard_nc12s_v3":     {12, 224, 2},
+	"standard_nc24s_v3":     {24, 448, 4},
+	"standard_nc4as_t4_v3":  {4, 28, 1},
+	"standard_nc8as_t4_v3":  {8, 56, 1},
+	"standard_nc16as_t4_v3": {16, 110, 1},
+	"standard_nc64as_t4_v3": {64, 440, 4},
+}
+
+// azureTierMatches reports whether a retail price item belongs to the pricing tier
+func azureTierMatches(item *azureRetailPrice, tier models.PricingTier) (bool, error) {
+	spot := strings.Contains(item.SkuName, "Spot")
+	lowPriority := strings.Contains(item.SkuName, "Low Priority")
+	switch tier {
+	case models.TierOnDemand:
+		return item.Type == "Consumption" && !spot && !lowPriority, nil
+	case models.TierSpot:
+		return item.Type == "Consumption" && spot, nil
+	case models.TierReserved1Yr:
+		return item.Type == "Reservation" && item.ReservationTerm == "1 Year", nil
+	case models.TierReserved3Yr:
+		return item.Type == "Reservation" && item.ReservationTerm == "3 Years", nil
+	default:
+		return false, fmt.Errorf("unsupported Azure pricing tier: %s", tier)
+	}
+}
+
+// azureHourlyPrice returns an item's hourly price; reservation prices cover the whole term
+func azureHourlyPrice(item *azureRetailPrice) float64 {
+	price := item.RetailPrice
+	if price == 0 {
+		price = item.UnitPrice
+	}
+	if item.Type == "Reservation" {
+		switch item.ReservationTerm {
+		case "1 Year":
+			return price / hoursPerYear
+		case "3 Years":
+			return price / (3 * hoursPerYear)
+		}
+	}
+	return price
+}
+
+// hoursPerYear is used to spread reservation term prices per hour
+const hoursPerYear = 8760
+
+// parseAzureRetailPrices parses an Azure Retail Prices API response ({"Items": [...]} or a
+// bare array) into Linux VM prices for a region. Azure sizes are not prefix families, so
+// each VM size is its own instance family.
+func parseAzureRetailPrices(r io.Reader, region string, tier models.PricingTier) ([]CatalogEntry, error) {
+	if _, err := azureTierMatches(&azureRetailPrice{}, tier); err != nil {
+		return nil, err
+	}
+
+	var raw json.RawMessage
+	if err := json.NewDecoder(r).Decode(&raw); err != nil {
+		return nil, fmt.Errorf("invalid Azure retail prices: %w", err)
+	}
+	var items []azureRetailPrice
+	var err error
+	if trimmed := strings.TrimSpace(string(raw)); strings.HasPrefix(trimmed, "[") {
+		err = json.Unmarshal(raw, &items)
+	} else {
+		var page struct {
+			Items []azureRetailPrice `json:"Items"`
+		}
+		err = json.Unmarshal(raw, &page)
+		items = page.Items
+	}
+	if err != nil {
+		return nil, fmt.Errorf("invalid Azure retail prices: %w", err)
+	}
+
+	seen := make(map[string]bool)
+	var entries []CatalogEntry
+	for i := range items {
+		item := &items[i]
+		if item.ServiceName != "Virtual Machines" || strings.Contains(item.ProductName, "Windows") {
+			continue
+		}
+		if item.CurrencyCode != "" && item.CurrencyCode != "USD" {
+			continue
+		}
+		if region != "" && !strings.EqualFold(item.ArmRegionName, region) {
+			continue
+		}
+		if ok, _ := azureTierMatches(item, tier); !ok {
+			continue
+		}
+
+		size := strings.ToLower(item.ArmSkuName)
+		shape, ok := azureVMShapes[size]
+		if !ok || seen[size] {
+			continue
+		}
+		seen[size] = true
+		entries = append(entries, CatalogEntry{
+			InstanceFamily: size,
+			InstanceType:   item.ArmSkuName,
+			Tier:           tier,
+			VCPUs:          shape.VCPUs,
+			MemoryGB:       shape.MemoryGB,
+			GPUs:           shape.GPUs,
+			HourlyCost:     azureHourlyPrice(item),
+		})
+	}
+	return entries, nil
+}
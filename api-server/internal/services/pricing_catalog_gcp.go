@@ -0,0 +1,197 @@
+package services
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/bugfreev587/k8s-cost-api-server/internal/models"
+)
+
+// gcpSku is a SKU of the Cloud Billing Catalog API (services/6F81-5844-456A/skus)
+type gcpSku struct {
+	Description string `json:"description"`
+	Category    struct {
+		ResourceFamily string `json:"resourceFamily"`
+		ResourceGroup  string `json:"resourceGroup"`
+		UsageType      string `json:"usageType"`
+	} `json:"category"`
+	ServiceRegions []string `json:"serviceRegions"`
+	PricingInfo    []struct {
+		PricingExpression struct {
+			UsageUnit   string `json:"usageUnit"`
+			TieredRates []struct {
+				StartUsageAmount float64 `json:"startUsageAmount"`
+				UnitPrice        struct {
+					CurrencyCode string      `json:"currencyCode"`
+					Units        json.Number `json:"units"`
+					Nanos        int64       `json:"nanos"`
+				} `json:"unitPrice"`
+			} `json:"tieredRates"`
+		} `json:"pricingExpression"`
+	} `json:"pricingInfo"`
+}
+
+// gcpUsageType maps a pricing tier to the SKU usage type
+func gcpUsageType(tier models.PricingTier) (string, error) {
+	switch tier {
+	case models.TierOnDemand:
+		return "OnDemand", nil
+	case models.TierSpot, models.TierPreemptible:
+		return "Preemptible", nil
+	case models.TierReserved1Yr:
+		return "Commit1Yr", nil
+	case models.TierReserved3Yr:
+		return "Commit3Yr", nil
+	default:
+		return "", fmt.Errorf("unsupported GCP pricing tier: %s", tier)
+	}
+}
+
+var (
+	// "N2 Instance Core running in Americas", "Compute optimized Core running in Americas", "Spot Preemptible N2D AMD Instance Ram running in Iowa"
+	gcpInstanceSkuRe = regexp.MustCompile(`^(?:Spot Preemptible |Preemptible )?(.+?) (?:Instance )?(Core|Ram) running in `)
+	// "Commitment v1: N2 Cpu in Americas for 1 Year"
+	gcpCommitSkuRe = regexp.MustCompile(`^Commitment v1: (.+?) (Cpu|Ram) in .* for \d+ Years?$`)
+	// "Nvidia Tesla T4 GPU running in Americas"
+	gcpGPUSkuRe = regexp.MustCompile(`^(?:Spot Preemptible |Preemptible )?(?:Commitment v1: )?(Nvidia .+?) GPU (?:running )?in `)
+)
+
+// gcpFamilyAliases maps descriptive series names to machine families
+var gcpFamilyAliases = map[string]string{
+	"compute optimized": "c2",
+	"memory-optimized":  "m1",
+	"memory optimized":  "m1",
+}
+
+// gcpMachineFamily returns the machine family of a SKU series name ("N2", "N1 Predefined",
+// "N2D AMD", "Compute optimized"), or "" for custom and sole-tenant SKUs
+func gcpMachineFamily(series string) string {
+	s := strings.ToLower(strings.TrimSpace(series))
+	if strings.Contains(s, "custom") || strings.Contains(s, "sole tenancy") || strings.Contains(s, "extended") {
+		return ""
+	}
+	if family, ok := gcpFamilyAliases[s]; ok {
+		return family
+	}
+	fields := strings.Fields(s)
+	if len(fields) == 0 {
+		return ""
+	}
+	return fields[0]
+}
+
+// gcpUnitPrice returns the SKU's USD price of the first tier
+func gcpUnitPrice(sku *gcpSku) (float64, string, bool) {
+	for _, info := range sku.PricingInfo {
+		for _, rate := range info.PricingExpression.TieredRates {
+			if rate.UnitPrice.CurrencyCode != "" && rate.UnitPrice.CurrencyCode != "USD" {
+				continue
+			}
+			units, _ := strconv.ParseFloat(rate.UnitPrice.Units.String(), 64)
+			price := units + float64(rate.UnitPrice.Nanos)/1e9
+			if price > 0 {
+				return price, info.PricingExpression.UsageUnit, true
+			}
+		}
+	}
+	return 0, "", false
+}
+
+// gcpGiBFactor returns the number of hours in a GCP memory usage unit (GiB-hours or GiB-months)
+func gcpGiBFactor(usageUnit string) float64 {
+	switch usageUnit {
+	case "GiBy.mo":
+		return 730
+	default: // GiBy.h
+		return 1
+	}
+}
+
+// parseGCPSkus parses a Cloud Billing Catalog SKU export ({"skus": [...]} or a bare array)
+// into per-family core, RAM and GPU rates for a region
+func parseGCPSkus(r io.Reader, region string, tier models.PricingTier) ([]CatalogEntry, error) {
+	usageType, err := gcpUsageType(tier)
+	if err != nil {
+		return nil, err
+	}
+
+	var skus []gcpSku
+	dec := json.NewDecoder(r)
+	dec.UseNumber()
+	var raw json.RawMessage
+	if err := dec.Decode(&raw); err != nil {
+		return nil, fmt.Errorf("invalid GCP SKU export: %w", err)
+	}
+	if trimmed := strings.TrimSpace(string(raw)); strings.HasPrefix(trimmed, "[") {
+		err = json.Unmarshal(raw, &skus)
+	} else {
+		var page struct {
+			Skus []gcpSku `json:"skus"`
+		}
+		err = json.Unmarshal(raw, &page)
+		skus = page.Skus
+	}
+	if err != nil {
+		return nil, fmt.Errorf("invalid GCP SKU export: %w", err)
+	}
+
+	rates := make(map[string]*CatalogEntry)
+	var order []string
+	entry := func(family string) *CatalogEntry {
+		if e, ok := rates[family]; ok {
+			return e
+		}
+		e := &CatalogEntry{InstanceFamily: family, Tier: tier}
+		rates[family] = e
+		order = append(order, family)
+		return e
+	}
+
+	for i := range skus {
+		sku := &skus[i]
+		if sku.Category.ResourceFamily != "Compute" || sku.Category.UsageType != usageType {
+			continue
+		}
+		if region != "" && !containsString(sku.ServiceRegions, region) {
+			continue
+		}
+		price, unit, ok := gcpUnitPrice(sku)
+		if !ok {
+			continue
+		}
+
+		if m := gcpGPUSkuRe.FindStringSubmatch(sku.Description); m != nil {
+			family := strings.ReplaceAll(strings.ToLower(m[1]), " ", "-")
+			entry(family).GPUPerHour = price
+			continue
+		}
+
+		var series, resource string
+		if m := gcpInstanceSkuRe.FindStringSubmatch(sku.Description); m != nil {
+			series, resource = m[1], m[2]
+		} else if m := gcpCommitSkuRe.FindStringSubmatch(sku.Description); m != nil {
+			series, resource = m[1], m[2]
+		} else {
+			continue
+		}
+		family := gcpMachineFamily(series)
+		if family == "" {
+			continue
+		}
+		if resource == "Core" || resource == "Cpu" {
+			entry(family).CPUPerCoreHour = price
+		} else {
+			entry(family).MemoryPerGBHour = price / gcpGiBFactor(unit)
+		}
+	}
+
+	entries := make([]CatalogEntry, 0, len(order))
+	for _, family := range order {
+		entries = append(entries, *rates[family])
+	}
+	return entries, nil
+}
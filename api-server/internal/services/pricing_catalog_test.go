@@ -0,0 +1,181 @@
+package services
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/bugfreev587/k8s-cost-api-server/internal/models"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const awsPriceListSample = `{
+  "formatVersion": "v1.0",
+  "offerCode": "AmazonEC2",
+  "products": {
+    "SKU1": {"sku": "SKU1", "productFamily": "Compute Instance", "attributes": {
+      "instanceType": "m5.large", "vcpu": "2", "memory": "8 GiB", "operatingSystem": "Linux",
+      "tenancy": "Shared", "preInstalledSw": "NA", "capacitystatus": "Used", "regionCode": "us-east-1"}},
+    "SKU2": {"sku": "SKU2", "productFamily": "Compute Instance", "attributes": {
+      "instanceType": "m5.large", "vcpu": "2", "memory": "8 GiB", "operatingSystem": "Windows",
+      "tenancy": "Shared", "preInstalledSw": "NA", "capacitystatus": "Used", "regionCode": "us-east-1"}},
+    "SKU3": {"sku": "SKU3", "productFamily": "Compute Instance", "attributes": {
+      "instanceType": "g4dn.xlarge", "vcpu": "4", "memory": "16 GiB", "gpu": "1", "operatingSystem": "Linux",
+      "tenancy": "Shared", "preInstalledSw": "NA", "capacitystatus": "Used", "regionCode": "us-east-1"}},
+    "SKU4": {"sku": "SKU4", "productFamily": "Storage", "attributes": {"volumeType": "gp3"}}
+  },
+  "terms": {
+    "OnDemand": {
+      "SKU1": {"SKU1.T1": {"priceDimensions": {"SKU1.T1.D1": {"unit": "Hrs", "pricePerUnit": {"USD": "0.0960000000"}}}, "termAttributes": {}}},
+      "SKU2": {"SKU2.T1": {"priceDimensions": {"SKU2.T1.D1": {"unit": "Hrs", "pricePerUnit": {"USD": "0.1880000000"}}}, "termAttributes": {}}},
+      "SKU3": {"SKU3.T1": {"priceDimensions": {"SKU3.T1.D1": {"unit": "Hrs", "pricePerUnit": {"USD": "0.5260000000"}}}, "termAttributes": {}}}
+    },
+    "Reserved": {
+      "SKU1": {"SKU1.T2": {"priceDimensions": {"SKU1.T2.D1": {"unit": "Hrs", "pricePerUnit": {"USD": "0.0600000000"}}},
+        "termAttributes": {"LeaseContractLength": "1yr", "OfferingClass": "standard", "PurchaseOption": "No Upfront"}}}
+    }
+  }
+}`
+
+func TestParseAWSPriceList(t *testing.T) {
+	entries, err := ParsePriceList(models.ProviderAWS, strings.NewReader(awsPriceListSample), "us-east-1", models.TierOnDemand)
+	require.NoError(t, err)
+	require.Len(t, entries, 2)
+
+	byType := make(map[string]CatalogEntry)
+	for _, e := range entries {
+		byType[e.InstanceType] = e
+	}
+	assert.Equal(t, "m5", byType["m5.large"].InstanceFamily)
+	assert.InDelta(t, 0.096, byType["m5.large"].HourlyCost, 1e-9)
+	assert.Equal(t, 8.0, byType["m5.large"].MemoryGB)
+	assert.Equal(t, 1.0, byType["g4dn.xlarge"].GPUs)
+
+	reserved, err := ParsePriceList(models.ProviderAWS, strings.NewReader(awsPriceListSample), "us-east-1", models.TierReserved1Yr)
+	require.NoError(t, err)
+	require.Len(t, reserved, 1)
+	assert.InDelta(t, 0.06, reserved[0].HourlyCost, 1e-9)
+
+	_, err = ParsePriceList(models.ProviderAWS, strings.NewReader(awsPriceListSample), "eu-west-1", models.TierOnDemand)
+	assert.Error(t, err)
+}
+
+const gcpSkusSample = `{"skus": [
+  {"description": "N2 Instance Core running in Americas",
+   "category": {"resourceFamily": "Compute", "resourceGroup": "N2Standard", "usageType": "OnDemand"},
+   "serviceRegions": ["us-central1", "us-east1"],
+   "pricingInfo": [{"pricingExpression": {"usageUnit": "h", "tieredRates": [{"unitPrice": {"currencyCode": "USD", "units": "0", "nanos": 31611000}}]}}]},
+  {"description": "N2 Instance Ram running in Americas",
+   "category": {"resourceFamily": "Compute", "resourceGroup": "N2Standard", "usageType": "OnDemand"},
+   "serviceRegions": ["us-central1"],
+   "pricingInfo": [{"pricingExpression": {"usageUnit": "GiBy.h", "tieredRates": [{"unitPrice": {"currencyCode": "USD", "units": "0", "nanos": 4237000}}]}}]},
+  {"description": "N2 Custom Instance Core running in Americas",
+   "category": {"resourceFamily": "Compute", "resourceGroup": "N2Custom", "usageType": "OnDemand"},
+   "serviceRegions": ["us-central1"],
+   "pricingInfo": [{"pricingExpression": {"usageUnit": "h", "tieredRates": [{"unitPrice": {"currencyCode": "USD", "units": "0", "nanos": 33191550}}]}}]},
+  {"description": "Compute optimized Core running in Americas",
+   "category": {"resourceFamily": "Compute", "resourceGroup": "CPU", "usageType": "OnDemand"},
+   "serviceRegions": ["us-central1"],
+   "pricingInfo": [{"pricingExpression": {"usageUnit": "h", "tieredRates": [{"unitPrice": {"currencyCode": "USD", "units": "0", "nanos": 33982000}}]}}]},
+  {"description": "Nvidia Tesla T4 GPU running in Americas",
+   "category": {"resourceFamily": "Compute", "resourceGroup": "GPU", "usageType": "OnDemand"},
+   "serviceRegions": ["us-central1"],
+   "pricingInfo": [{"pricingExpression": {"usageUnit": "h", "tieredRates": [{"unitPrice": {"currencyCode": "USD", "units": "0", "nanos": 350000000}}]}}]},
+  {"description": "Spot Preemptible N2 Instance Core running in Americas",
+   "category": {"resourceFamily": "Compute", "resourceGroup": "N2Standard", "usageType": "Preemptible"},
+   "serviceRegions": ["us-central1"],
+   "pricingInfo": [{"pricingExpression": {"usageUnit": "h", "tieredRates": [{"unitPrice": {"currencyCode": "USD", "units": "0", "nanos": 7650000}}]}}]}
+]}`
+
+func TestParseGCPSkus(t *testing.T) {
+	entries, err := ParsePriceList(models.ProviderGCP, strings.NewReader(gcpSkusSample), "us-central1", models.TierOnDemand)
+	require.NoError(t, err)
+
+	byFamily := make(map[string]CatalogEntry)
+	for _, e := range entries {
+		byFamily[e.InstanceFamily] = e
+	}
+	require.Len(t, byFamily, 3)
+	assert.InDelta(t, 0.031611, byFamily["n2"].CPUPerCoreHour, 1e-9)
+	assert.InDelta(t, 0.004237, byFamily["n2"].MemoryPerGBHour, 1e-9)
+	assert.InDelta(t, 0.033982, byFamily["c2"].CPUPerCoreHour, 1e-9)
+	assert.InDelta(t, 0.35, byFamily["nvidia-tesla-t4"].GPUPerHour, 1e-9)
+
+	spot, err := ParsePriceList(models.ProviderGCP, strings.NewReader(gcpSkusSample), "us-central1", models.TierSpot)
+	require.NoError(t, err)
+	require.Len(t, spot, 1)
+	assert.InDelta(t, 0.00765, spot[0].CPUPerCoreHour, 1e-9)
+}
+
+const azurePricesSample = `{"Items": [
+  {"currencyCode": "USD", "retailPrice": 0.096, "armRegionName": "eastus", "armSkuName": "Standard_D2s_v3",
+   "productName": "Virtual Machines DSv3 Series", "skuName": "D2s v3", "serviceName": "Virtual Machines", "type": "Consumption", "unitOfMeasure": "1 Hour"},
+  {"currencyCode": "USD", "retailPrice": 0.0192, "armRegionName": "eastus", "armSkuName": "Standard_D2s_v3",
+   "productName": "Virtual Machines DSv3 Series", "skuName": "D2s v3 Spot", "serviceName": "Virtual Machines", "type": "Consumption", "unitOfMeasure": "1 Hour"},
+  {"currencyCode": "USD", "retailPrice": 0.188, "armRegionName": "eastus", "armSkuName": "Standard_D2s_v3",
+   "productName": "Virtual Machines DSv3 Series Windows", "skuName": "D2s v3", "serviceName": "Virtual Machines", "type": "Consumption", "unitOfMeasure": "1 Hour"},
+  {"currencyCode": "USD", "retailPrice": 525.6, "armRegionName": "eastus", "armSkuName": "Standard_D2s_v3",
+   "productName": "Virtual Machines DSv3 Series", "skuName": "D2s v3", "serviceName": "Virtual Machines", "type": "Reservation", "reservationTerm": "1 Year", "unitOfMeasure": "1 Hour"},
+  {"currencyCode": "USD", "retailPrice": 0.096, "armRegionName": "westus2", "armSkuName": "Standard_D2s_v3",
+   "productName": "Virtual Machines DSv3 Series", "skuName": "D2s v3", "serviceName": "Virtual Machines", "type": "Consumption", "unitOfMeasure": "1 Hour"}
+]}`
+
+func TestParseAzureRetailPrices(t *testing.T) {
+	entries, err := ParsePriceList(models.ProviderAzure, strings.NewReader(azurePricesSample), "eastus", models.TierOnDemand)
+	require.NoError(t, err)
+	require.Len(t, entries, 1)
+	assert.Equal(t, "standard_d2s_v3", entries[0].InstanceFamily)
+	assert.InDelta(t, 0.096, entries[0].HourlyCost, 1e-9)
+
+	reserved, err := ParsePriceList(models.ProviderAzure, strings.NewReader(azurePricesSample), "eastus", models.TierReserved1Yr)
+	require.NoError(t, err)
+	require.Len(t, reserved, 1)
+	assert.InDelta(t, 0.06, reserved[0].HourlyCost, 1e-9)
+}
+
+func TestCatalogRates(t *testing.T) {
+	day := time.Date(2024, 6, 1, 0, 0, 0, 0, time.UTC)
+	entries := []CatalogEntry{
+		{InstanceFamily: "m5", InstanceType: "m5.large", VCPUs: 2, MemoryGB: 8, HourlyCost: 0.096},
+		{InstanceFamily: "m5", InstanceType: "m5.xlarge", VCPUs: 4, MemoryGB: 16, HourlyCost: 0.192},
+		{InstanceFamily: "g4dn", InstanceType: "g4dn.xlarge", VCPUs: 4, MemoryGB: 16, GPUs: 1, HourlyCost: 0.526},
+	}
+	rates := CatalogRates(models.ProviderAWS, entries, models.TierOnDemand, day)
+
+	byKey := make(map[string]models.PricingRate)
+	for _, r := range rates {
+		byKey[r.InstanceFamily+"/"+string(r.ResourceType)] = r
+	}
+	require.Len(t, byKey, 5)
+
+	// Splitting an instance price reproduces it exactly
+	m5CPU, m5Mem := byKey["m5/cpu"].CostPerUnit, byKey["m5/memory"].CostPerUnit
+	assert.InDelta(t, 0.096, 2*m5CPU+8*m5Mem, 1e-9)
+	assert.InDelta(t, 0.0425/0.0053, m5CPU/m5Mem, 1e-6)
+	assert.Equal(t, "core-hour", byKey["m5/cpu"].Unit)
+	assert.Equal(t, day, byKey["m5/cpu"].EffectiveFrom)
+
+	// GPU instances price CPU and RAM at the default rates; the rest is the GPU
+	g4CPU, g4Mem, g4GPU := byKey["g4dn/cpu"].CostPerUnit, byKey["g4dn/memory"].CostPerUnit, byKey["g4dn/gpu"].CostPerUnit
+	assert.InDelta(t, 0.0425, g4CPU, 1e-9)
+	assert.InDelta(t, 0.526, 4*g4CPU+16*g4Mem+g4GPU, 1e-9)
+}
+
+func TestSnapshotCatalogs(t *testing.T) {
+	for _, provider := range []models.CloudProvider{models.ProviderAWS, models.ProviderGCP, models.ProviderAzure} {
+		info, err := GetSnapshotCatalogInfo(provider)
+		require.NoError(t, err, provider)
+		require.NotEmpty(t, info.Regions, provider)
+		assert.NotEmpty(t, info.CapturedAt, provider)
+
+		entries, err := SnapshotCatalogEntries(provider, info.Regions[0], models.TierOnDemand)
+		require.NoError(t, err, provider)
+		assert.NotEmpty(t, CatalogRates(provider, entries, models.TierOnDemand, time.Now()), provider)
+	}
+
+	_, err := SnapshotCatalogEntries(models.ProviderAWS, "mars-north-1", models.TierOnDemand)
+	assert.Error(t, err)
+	_, err = GetSnapshotCatalogInfo(models.ProviderOCI)
+	assert.Error(t, err)
+}
@@ -0,0 +1,112 @@
+package services
+
+import (
+	"fmt"
+	"sort"
+
+	"github.com/bugfreev587/k8s-cost-api-server/internal/models"
+)
+
+// ValidatePricingDiscount checks a negotiated discount before it is saved
+func ValidatePricingDiscount(d *models.PricingDiscount) error {
+	if d.DiscountPercent < 0 || d.DiscountPercent >= 100 {
+		return fmt.Errorf("discount_percent must be between 0 and 100")
+	}
+	switch d.Scope {
+	case models.DiscountScopeGlobal:
+		if d.ResourceType != "" || d.InstanceFamily != "" {
+			return fmt.Errorf("global discounts apply to all rates and cannot set resource_type or instance_family")
+		}
+	case models.DiscountScopeResource:
+		if d.ResourceType == "" {
+			return fmt.Errorf("resource discounts require resource_type")
+		}
+		if d.InstanceFamily != "" {
+			return fmt.Errorf("resource discounts cannot set instance_family (use scope %s)", models.DiscountScopeInstanceFamily)
+		}
+	case models.DiscountScopeInstanceFamily:
+		if d.InstanceFamily == "" {
+			return fmt.Errorf("instance family discounts require instance_family")
+		}
+	default:
+		return fmt.Errorf("invalid scope: %s", d.Scope)
+	}
+	if d.EffectiveTo != nil && d.EffectiveTo.Before(d.EffectiveFrom) {
+		return fmt.Errorf("effective_to must not be before effective_from")
+	}
+	return nil
+}
+
+// applyPricingDiscounts records the base CPU and memory rates as list rates and applies
+// the negotiated discounts to every rate of the effective pricing
+func applyPricingDiscounts(pricing *models.EffectivePricing, discounts []models.PricingDiscount) {
+	pricing.ListCPUPerCoreHour = pricing.CPUPerCoreHour
+	pricing.ListMemoryPerGBHour = pricing.MemoryPerGBHour
+	for _, price := range pricing.InstancePricing {
+		price.ListCPUPerCoreHour = price.CPUPerCoreHour
+		price.ListMemoryPerGBHour = price.MemoryPerGBHour
+	}
+	if len(discounts) == 0 {
+		return
+	}
+
+	// Later discounts win over earlier ones of the same specificity
+	ordered := make([]models.PricingDiscount, len(discounts))
+	copy(ordered, discounts)
+	sort.SliceStable(ordered, func(i, j int) bool {
+		if !ordered[i].EffectiveFrom.Equal(ordered[j].EffectiveFrom) {
+			return ordered[i].EffectiveFrom.Before(ordered[j].EffectiveFrom)
+		}
+		return ordered[i].ID < ordered[j].ID
+	})
+
+	pricing.CPUPerCoreHour *= discountFactor(ordered, models.ResourceCPU, "")
+	pricing.MemoryPerGBHour *= discountFactor(ordered, models.ResourceMemory, "")
+	pricing.StoragePerGBMonth *= discountFactor(ordered, models.ResourceStorage, "")
+	for key := range pricing.GPUPerHour {
+		family := key
+		if family == "default" {
+			family = ""
+		}
+		pricing.GPUPerHour[key] *= discountFactor(ordered, models.ResourceGPU, family)
+	}
+	for family, price := range pricing.InstancePricing {
+		price.CPUPerCoreHour *= discountFactor(ordered, models.ResourceCPU, family)
+		price.MemoryPerGBHour *= discountFactor(ordered, models.ResourceMemory, family)
+	}
+}
+
+// discountFactor returns the multiplier (0-1] applied to a rate of the given resource and
+// instance family: every global discount, combined with the most specific matching
+// discount of instance family + resource, instance family, or resource
+func discountFactor(discounts []models.PricingDiscount, resource models.ResourceType, family string) float64 {
+	factor := 1.0
+	var specific float64
+	specificity := 0
+	for _, d := range discounts {
+		level := 0
+		switch d.Scope {
+		case models.DiscountScopeGlobal:
+			factor *= 1 - d.DiscountPercent/100
+			continue
+		case models.DiscountScopeResource:
+			if d.ResourceType == resource {
+				level = 1
+			}
+		case models.DiscountScopeInstanceFamily:
+			if family != "" && models.InstanceFamilyMatches(family, d.InstanceFamily) {
+				switch d.ResourceType {
+				case "":
+					level = 2
+				case resource:
+					level = 3
+				}
+			}
+		}
+		if level > 0 && level >= specificity {
+			specific = d.DiscountPercent
+			specificity = level
+		}
+	}
+	return factor * (1 - specific/100)
+}
@@ -0,0 +1,46 @@
+package services
+
+import (
+	"testing"
+	"time"
+
+	"github.com/bugfreev587/k8s-cost-api-server/internal/models"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestApplyPricingDiscounts(t *testing.T) {
+	day := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	pricing := &models.EffectivePricing{
+		CPUPerCoreHour:  0.04,
+		MemoryPerGBHour: 0.005,
+		GPUPerHour:      map[string]float64{"default": 1.0},
+		InstancePricing: map[string]*models.InstancePrice{
+			"m5": {InstanceType: "m5", CPUPerCoreHour: 0.05, MemoryPerGBHour: 0.006},
+		},
+	}
+	discounts := []models.PricingDiscount{
+		{ID: 1, Scope: models.DiscountScopeGlobal, DiscountPercent: 10, EffectiveFrom: day},
+		{ID: 2, Scope: models.DiscountScopeResource, ResourceType: models.ResourceMemory, DiscountPercent: 20, EffectiveFrom: day},
+		{ID: 3, Scope: models.DiscountScopeInstanceFamily, InstanceFamily: "m5", DiscountPercent: 30, EffectiveFrom: day},
+	}
+
+	applyPricingDiscounts(pricing, discounts)
+
+	// List rates keep the base prices
+	assert.InDelta(t, 0.04, pricing.ListCPUPerCoreHour, 1e-12)
+	assert.InDelta(t, 0.05, pricing.InstancePricing["m5"].ListCPUPerCoreHour, 1e-12)
+
+	// Global only, global + resource, global + instance family (most specific wins over resource)
+	assert.InDelta(t, 0.04*0.9, pricing.CPUPerCoreHour, 1e-12)
+	assert.InDelta(t, 0.005*0.9*0.8, pricing.MemoryPerGBHour, 1e-12)
+	assert.InDelta(t, 0.9, pricing.GPUPerHour["default"], 1e-12)
+	assert.InDelta(t, 0.05*0.9*0.7, pricing.InstancePricing["m5"].CPUPerCoreHour, 1e-12)
+	assert.InDelta(t, 0.006*0.9*0.7, pricing.InstancePricing["m5"].MemoryPerGBHour, 1e-12)
+}
+
+func TestValidatePricingDiscount(t *testing.T) {
+	assert.NoError(t, ValidatePricingDiscount(&models.PricingDiscount{Scope: models.DiscountScopeGlobal, DiscountPercent: 5}))
+	assert.Error(t, ValidatePricingDiscount(&models.PricingDiscount{Scope: models.DiscountScopeResource, DiscountPercent: 5}))
+	assert.Error(t, ValidatePricingDiscount(&models.PricingDiscount{Scope: models.DiscountScopeGlobal, DiscountPercent: 100}))
+	assert.Error(t, ValidatePricingDiscount(&models.PricingDiscount{Scope: "bogus", DiscountPercent: 5}))
+}
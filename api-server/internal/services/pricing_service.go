@@ -3,7 +3,7 @@ package services
 import (
 	"context"
 	"fmt"
-	"sync"
+	"strings"
 	"time"
 
 	"github.com/bugfreev587/k8s-cost-api-server/internal/models"
@@ -14,28 +14,23 @@ import (
 type PricingService struct {
 	db    *gorm.DB
 	cache *PricingCache
-}
 
-// PricingCache provides in-memory caching for pricing lookups
-type PricingCache struct {
-	mu      sync.RWMutex
-	entries map[string]*pricingCacheEntry
-	ttl     time.Duration
+	actor    string     // recorded as the author of pricing changes
+	pinnedAt *time.Time // resolve configs as they stood at this time (see PinnedAt)
+	proposed *proposedPricing
 }
 
-type pricingCacheEntry struct {
-	pricing   *models.EffectivePricing
-	expiresAt time.Time
+// NewPricingService creates a new pricing service with a process-local cache
+func NewPricingService(db *gorm.DB) *PricingService {
+	return NewPricingServiceWithCache(db, NewPricingCache(nil, 1*time.Hour))
 }
 
-// NewPricingService creates a new pricing service
-func NewPricingService(db *gorm.DB) *PricingService {
+// NewPricingServiceWithCache creates a pricing service using a shared cache, so lookups
+// and invalidations are shared with every service (and replica) using the same cache
+func NewPricingServiceWithCache(db *gorm.DB, cache *PricingCache) *PricingService {
 	return &PricingService{
-		db: db,
-		cache: &PricingCache{
-			entries: make(map[string]*pricingCacheEntry),
-			ttl:     1 * time.Hour,
-		},
+		db:    db,
+		cache: cache,
 	}
 }
 
@@ -43,15 +38,52 @@ func NewPricingService(db *gorm.DB) *PricingService {
 func (s *PricingService) GetEffectiveRates(ctx context.Context, tenantID uint, clusterName string, asOf time.Time) (*models.EffectivePricing, error) {
 	// 1. Check cache first
 	cacheKey := fmt.Sprintf("%d:%s:%s", tenantID, clusterName, asOf.Format("2006-01-02"))
+	if s.pinnedAt != nil {
+		cacheKey += "@" + s.pinnedAt.Format(time.RFC3339Nano)
+	}
 	if cached := s.cache.Get(cacheKey); cached != nil {
 		return cached, nil
 	}
 
-	// 2. Find pricing config for cluster
+	// 2. Find pricing config for cluster; a proposed (what-if) config replaces it
+	config, configSource, err := s.resolveClusterConfig(ctx, tenantID, clusterName, asOf)
+	if err != nil {
+		return nil, err
+	}
+
+	// 3. Build effective pricing from rates, then apply negotiated discounts
+	var pricing *models.EffectivePricing
+	if config == nil {
+		pricing = s.getSystemDefaults(models.ProviderCustom)
+	} else {
+		pricing = s.buildEffectivePricing(config)
+		pricing.ConfigSource = configSource
+		applyPricingDiscounts(pricing, config.Discounts)
+	}
+
+	// 4. Load node-level overrides
+	nodeOverrides, err := s.nodeOverrides(ctx, tenantID, clusterName)
+	if err != nil {
+		return nil, err
+	}
+	s.applyNodeOverrides(pricing, nodeOverrides)
+
+	// 5. Cache and return
+	s.cache.Set(cacheKey, pricing)
+	return pricing, nil
+}
+
+// resolveClusterConfig returns the pricing config of a cluster with the rates effective at
+// asOf: the proposed config, the cluster's assigned config or the tenant's default config.
+// It returns a nil config when the system defaults apply.
+func (s *PricingService) resolveClusterConfig(ctx context.Context, tenantID uint, clusterName string, asOf time.Time) (*models.PricingConfig, models.ConfigSource, error) {
+	if config := s.proposed.configFor(clusterName, asOf); config != nil {
+		return config, models.ConfigSourceProposed, nil
+	}
+
 	var configID uint
-	var clusterPricing models.ClusterPricing
-	err := s.db.Where("tenant_id = ? AND cluster_name = ?", tenantID, clusterName).
-		First(&clusterPricing).Error
+	configSource := models.ConfigSourceCluster
+	clusterPricing, err := s.clusterAssignment(ctx, tenantID, clusterName)
 
 	if err == gorm.ErrRecordNotFound {
 		// Use tenant's default config
@@ -60,48 +92,37 @@ func (s *PricingService) GetEffectiveRates(ctx context.Context, tenantID uint, c
 			First(&defaultConfig).Error
 		if err != nil {
 			// Fall back to system defaults
-			pricing := s.getSystemDefaults(models.ProviderCustom)
-			s.cache.Set(cacheKey, pricing)
-			return pricing, nil
+			return nil, models.ConfigSourceSystemDefault, nil
 		}
 		configID = defaultConfig.ID
+		configSource = models.ConfigSourceTenantDefault
 	} else if err != nil {
-		return nil, fmt.Errorf("failed to lookup cluster pricing: %w", err)
+		return nil, "", fmt.Errorf("failed to lookup cluster pricing: %w", err)
 	} else {
 		configID = clusterPricing.ConfigID
 	}
 
-	// 3. Load pricing config with rates
-	var config models.PricingConfig
-	err = s.db.Preload("Rates", "effective_from <= ? AND (effective_to IS NULL OR effective_to >= ?)", asOf, asOf).
-		First(&config, configID).Error
-	if err != nil {
-		pricing := s.getSystemDefaults(models.ProviderCustom)
-		s.cache.Set(cacheKey, pricing)
-		return pricing, nil
+	// Load pricing config with rates (as it stood at the pinned time, if any)
+	config, err := s.loadEffectiveConfig(ctx, configID, asOf)
+	if err != nil || config == nil {
+		return nil, models.ConfigSourceSystemDefault, nil
 	}
-
-	// 4. Build effective pricing from rates
-	pricing := s.buildEffectivePricing(&config)
-
-	// 5. Load node-level overrides
-	var nodeOverrides []models.NodePricing
-	s.db.Where("tenant_id = ? AND cluster_name = ?", tenantID, clusterName).Find(&nodeOverrides)
-	s.applyNodeOverrides(pricing, nodeOverrides)
-
-	// 6. Cache and return
-	s.cache.Set(cacheKey, pricing)
-	return pricing, nil
+	return config, configSource, nil
 }
 
 // getSystemDefaults returns default pricing for a provider
 func (s *PricingService) getSystemDefaults(provider models.CloudProvider) *models.EffectivePricing {
+	cpuRate := models.GetDefaultCPURate(provider, models.TierOnDemand)
+	memRate := models.GetDefaultMemoryRate(provider, models.TierOnDemand)
 	return &models.EffectivePricing{
-		CPUPerCoreHour:  models.GetDefaultCPURate(provider, models.TierOnDemand),
-		MemoryPerGBHour: models.GetDefaultMemoryRate(provider, models.TierOnDemand),
+		CPUPerCoreHour:  cpuRate,
+		MemoryPerGBHour: memRate,
 		Provider:        provider,
+		ConfigSource:    models.ConfigSourceSystemDefault,
 		GPUPerHour:      make(map[string]float64),
 		InstancePricing: make(map[string]*models.InstancePrice),
+		NodePricing:     make(map[string]*models.InstancePrice),
+		CPURAMCostRatio: cpuRate / memRate,
 	}
 }
 
@@ -110,29 +131,48 @@ func (s *PricingService) buildEffectivePricing(config *models.PricingConfig) *mo
 	pricing := &models.EffectivePricing{
 		Provider:        config.Provider,
 		Region:          config.Region,
+		ConfigID:        config.ID,
+		ConfigName:      config.Name,
 		GPUPerHour:      make(map[string]float64),
 		InstancePricing: make(map[string]*models.InstancePrice),
+		NodePricing:     make(map[string]*models.InstancePrice),
 	}
 
-	// Process rates - prioritize instance-specific rates over generic
-	for _, rate := range config.Rates {
+	// Generic CPU rates by tier, used to derive committed tier discounts
+	cpuTierRates := make(map[models.PricingTier]float64)
+
+	// Process rates - prioritize instance-specific rates over generic. Overlapping rates
+	// of the same resource/tier/family resolve to the latest one.
+	for _, rate := range winningRates(config.Rates) {
 		if rate.InstanceFamily != "" {
-			// Instance-specific rate
+			// Instance-specific rate; on-demand wins over other tiers of the same family
+			if rate.ResourceType == models.ResourceGPU {
+				if _, ok := pricing.GPUPerHour[rate.InstanceFamily]; !ok || rate.PricingTier == models.TierOnDemand {
+					pricing.GPUPerHour[rate.InstanceFamily] = rate.CostPerUnit
+				}
+				continue
+			}
 			if _, ok := pricing.InstancePricing[rate.InstanceFamily]; !ok {
 				pricing.InstancePricing[rate.InstanceFamily] = &models.InstancePrice{
 					InstanceType: rate.InstanceFamily,
 				}
 			}
+			price := pricing.InstancePricing[rate.InstanceFamily]
 			switch rate.ResourceType {
 			case models.ResourceCPU:
-				pricing.InstancePricing[rate.InstanceFamily].CPUPerCoreHour = rate.CostPerUnit
+				if price.CPUPerCoreHour == 0 || rate.PricingTier == models.TierOnDemand {
+					price.CPUPerCoreHour = rate.CostPerUnit
+				}
 			case models.ResourceMemory:
-				pricing.InstancePricing[rate.InstanceFamily].MemoryPerGBHour = rate.CostPerUnit
+				if price.MemoryPerGBHour == 0 || rate.PricingTier == models.TierOnDemand {
+					price.MemoryPerGBHour = rate.CostPerUnit
+				}
 			}
 		} else {
 			// Generic rate (default for this config)
 			switch rate.ResourceType {
 			case models.ResourceCPU:
+				cpuTierRates[rate.PricingTier] = rate.CostPerUnit
 				if pricing.CPUPerCoreHour == 0 || rate.PricingTier == models.TierOnDemand {
 					pricing.CPUPerCoreHour = rate.CostPerUnit
 				}
@@ -141,14 +181,14 @@ func (s *PricingService) buildEffectivePricing(config *models.PricingConfig) *mo
 					pricing.MemoryPerGBHour = rate.CostPerUnit
 				}
 			case models.ResourceGPU:
-				// GPU rates keyed by instance family or generic
-				key := "default"
-				if rate.InstanceFamily != "" {
-					key = rate.InstanceFamily
+				// Generic GPU rate; family GPU rates are handled above
+				if _, ok := pricing.GPUPerHour["default"]; !ok || rate.PricingTier == models.TierOnDemand {
+					pricing.GPUPerHour["default"] = rate.CostPerUnit
 				}
-				pricing.GPUPerHour[key] = rate.CostPerUnit
 			case models.ResourceStorage:
-				pricing.StoragePerGBMonth = rate.CostPerUnit
+				if pricing.StoragePerGBMonth == 0 || rate.PricingTier == models.TierOnDemand {
+					pricing.StoragePerGBMonth = rate.CostPerUnit
+				}
 			}
 		}
 	}
@@ -161,21 +201,42 @@ func (s *PricingService) buildEffectivePricing(config *models.PricingConfig) *mo
 		pricing.MemoryPerGBHour = models.GetDefaultMemoryRate(config.Provider, models.TierOnDemand)
 	}
 
+	// CPU:RAM cost ratio for splitting whole-node prices, from the (pre-discount) default rates
+	pricing.CPURAMCostRatio = pricing.CPUPerCoreHour / pricing.MemoryPerGBHour
+	if config.CPURAMCostRatio != nil && *config.CPURAMCostRatio > 0 {
+		pricing.CPURAMCostRatio = *config.CPURAMCostRatio
+	}
+
+	// Committed tier discounts relative to the on-demand CPU rate
+	if onDemand := cpuTierRates[models.TierOnDemand]; onDemand > 0 {
+		for _, tier := range []models.PricingTier{models.TierReserved1Yr, models.TierReserved3Yr} {
+			if rate, ok := cpuTierRates[tier]; ok && rate < onDemand {
+				if pricing.TierDiscounts == nil {
+					pricing.TierDiscounts = make(map[models.PricingTier]float64)
+				}
+				pricing.TierDiscounts[tier] = 1 - rate/onDemand
+			}
+		}
+	}
+
 	return pricing
 }
 
-// applyNodeOverrides applies node-specific pricing
+// applyNodeOverrides applies node-specific pricing: a whole-node hourly cost, or the
+// pricing of the node's declared instance type or family
 func (s *PricingService) applyNodeOverrides(pricing *models.EffectivePricing, nodes []models.NodePricing) {
 	for _, node := range nodes {
 		if node.HourlyCostOverride != nil && *node.HourlyCostOverride > 0 {
-			pricing.InstancePricing[node.NodeName] = &models.InstancePrice{
+			pricing.NodePricing[node.NodeName] = &models.InstancePrice{
 				InstanceType: node.InstanceType,
 				HourlyCost:   *node.HourlyCostOverride,
 			}
 		} else if node.InstanceType != "" {
-			// Look up instance type pricing
+			// Look up instance type pricing, then the instance family ("m5" for "m5.xlarge")
 			if instancePrice, ok := pricing.InstancePricing[node.InstanceType]; ok {
-				pricing.InstancePricing[node.NodeName] = instancePrice
+				pricing.NodePricing[node.NodeName] = instancePrice
+			} else if instancePrice := pricing.FamilyPrice(node.InstanceType); instancePrice != nil {
+				pricing.NodePricing[node.NodeName] = instancePrice
 			}
 		}
 	}
@@ -183,14 +244,22 @@ func (s *PricingService) applyNodeOverrides(pricing *models.EffectivePricing, no
 
 // CreateConfig creates a new pricing configuration
 func (s *PricingService) CreateConfig(ctx context.Context, config *models.PricingConfig) error {
-	// If setting as default, unset other defaults first
-	if config.IsDefault {
-		s.db.Model(&models.PricingConfig{}).
-			Where("tenant_id = ? AND is_default = true", config.TenantID).
-			Update("is_default", false)
-	}
-
-	if err := s.db.Create(config).Error; err != nil {
+	err := s.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		// If setting as default, unset other defaults first
+		if config.IsDefault {
+			err := tx.Model(&models.PricingConfig{}).
+				Where("tenant_id = ? AND is_default = true", config.TenantID).
+				Update("is_default", false).Error
+			if err != nil {
+				return err
+			}
+		}
+		if err := tx.Create(config).Error; err != nil {
+			return err
+		}
+		return s.recordVersion(tx, config.TenantID, config.ID, models.PricingActionConfigCreate, "config", config.ID, nil, config)
+	})
+	if err != nil {
 		return err
 	}
 
@@ -201,15 +270,27 @@ func (s *PricingService) CreateConfig(ctx context.Context, config *models.Pricin
 
 // UpdateConfig updates an existing pricing configuration
 func (s *PricingService) UpdateConfig(ctx context.Context, config *models.PricingConfig) error {
-	// If setting as default, unset other defaults first
-	if config.IsDefault {
-		s.db.Model(&models.PricingConfig{}).
-			Where("tenant_id = ? AND is_default = true AND id != ?", config.TenantID, config.ID).
-			Update("is_default", false)
-	}
-
 	config.UpdatedAt = time.Now()
-	if err := s.db.Save(config).Error; err != nil {
+	err := s.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		var before models.PricingConfig
+		if err := tx.First(&before, config.ID).Error; err != nil {
+			return err
+		}
+		// If setting as default, unset other defaults first
+		if config.IsDefault {
+			err := tx.Model(&models.PricingConfig{}).
+				Where("tenant_id = ? AND is_default = true AND id != ?", config.TenantID, config.ID).
+				Update("is_default", false).Error
+			if err != nil {
+				return err
+			}
+		}
+		if err := tx.Save(config).Error; err != nil {
+			return err
+		}
+		return s.recordVersion(tx, config.TenantID, config.ID, models.PricingActionConfigUpdate, "config", config.ID, before, config)
+	})
+	if err != nil {
 		return err
 	}
 
@@ -224,7 +305,13 @@ func (s *PricingService) DeleteConfig(ctx context.Context, configID uint) error
 		return err
 	}
 
-	if err := s.db.Delete(&config).Error; err != nil {
+	err := s.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := tx.Delete(&config).Error; err != nil {
+			return err
+		}
+		return s.recordVersion(tx, config.TenantID, config.ID, models.PricingActionConfigDelete, "config", config.ID, config, nil)
+	})
+	if err != nil {
 		return err
 	}
 
@@ -235,7 +322,7 @@ func (s *PricingService) DeleteConfig(ctx context.Context, configID uint) error
 // GetConfig retrieves a pricing configuration with its rates
 func (s *PricingService) GetConfig(ctx context.Context, configID uint) (*models.PricingConfig, error) {
 	var config models.PricingConfig
-	err := s.db.Preload("Rates").First(&config, configID).Error
+	err := s.db.Preload("Rates").Preload("Discounts").First(&config, configID).Error
 	if err != nil {
 		return nil, err
 	}
@@ -247,39 +334,167 @@ func (s *PricingService) ListConfigs(ctx context.Context, tenantID uint) ([]mode
 	var configs []models.PricingConfig
 	err := s.db.Where("tenant_id = ?", tenantID).
 		Preload("Rates").
+		Preload("Discounts").
 		Order("is_default DESC, name ASC").
 		Find(&configs).Error
 	return configs, err
 }
 
-// AddRate adds a pricing rate to a configuration
-func (s *PricingService) AddRate(ctx context.Context, rate *models.PricingRate) error {
+// AddRate adds a pricing rate to a configuration. A rate overlapping the effective range
+// of an existing rate with the same resource, tier and family is rejected with a
+// *RateOverlapError, unless supersede is set: the existing rates are then trimmed (or
+// removed) so the new rate alone covers its range.
+func (s *PricingService) AddRate(ctx context.Context, rate *models.PricingRate, supersede bool) error {
 	if rate.EffectiveFrom.IsZero() {
 		rate.EffectiveFrom = time.Now()
 	}
-
-	if err := s.db.Create(rate).Error; err != nil {
+	if err := ValidatePricingRate(rate); err != nil {
 		return err
 	}
 
-	// Get tenant ID for cache invalidation
 	var config models.PricingConfig
-	if err := s.db.First(&config, rate.ConfigID).Error; err == nil {
-		s.cache.InvalidateTenant(config.TenantID)
+	if err := s.db.First(&config, rate.ConfigID).Error; err != nil {
+		return err
+	}
+
+	err := s.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		superseded, err := s.resolveRateOverlaps(tx, rate, supersede)
+		if err != nil {
+			return err
+		}
+		if err := tx.Create(rate).Error; err != nil {
+			return err
+		}
+		return s.recordVersion(tx, config.TenantID, config.ID, models.PricingActionRateCreate, "rate", rate.ID, superseded, rate)
+	})
+	if err != nil {
+		return err
 	}
+
+	s.cache.InvalidateTenant(config.TenantID)
 	return nil
 }
 
-// UpdateRate updates an existing pricing rate
-func (s *PricingService) UpdateRate(ctx context.Context, rate *models.PricingRate) error {
-	if err := s.db.Save(rate).Error; err != nil {
+// UpdateRate updates an existing pricing rate; overlaps with other rates are handled as in AddRate
+func (s *PricingService) UpdateRate(ctx context.Context, rate *models.PricingRate, supersede bool) error {
+	if err := ValidatePricingRate(rate); err != nil {
 		return err
 	}
 
 	var config models.PricingConfig
-	if err := s.db.First(&config, rate.ConfigID).Error; err == nil {
-		s.cache.InvalidateTenant(config.TenantID)
+	if err := s.db.First(&config, rate.ConfigID).Error; err != nil {
+		return err
+	}
+
+	err := s.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		var before models.PricingRate
+		if err := tx.First(&before, rate.ID).Error; err != nil {
+			return err
+		}
+		if _, err := s.resolveRateOverlaps(tx, rate, supersede); err != nil {
+			return err
+		}
+		if err := tx.Save(rate).Error; err != nil {
+			return err
+		}
+		return s.recordVersion(tx, config.TenantID, config.ID, models.PricingActionRateUpdate, "rate", rate.ID, before, rate)
+	})
+	if err != nil {
+		return err
 	}
+
+	s.cache.InvalidateTenant(config.TenantID)
+	return nil
+}
+
+// resolveRateOverlaps finds the rates overlapping rate's effective range and, with
+// supersede, trims them around it; otherwise an overlap is a *RateOverlapError. It
+// returns the overlapping rates as they were before being trimmed.
+func (s *PricingService) resolveRateOverlaps(tx *gorm.DB, rate *models.PricingRate, supersede bool) ([]models.PricingRate, error) {
+	var candidates []models.PricingRate
+	if err := tx.Where("config_id = ? AND resource_type = ? AND pricing_tier = ? AND LOWER(COALESCE(instance_family, '')) = ?",
+		rate.ConfigID, rate.ResourceType, rate.PricingTier, strings.ToLower(rate.InstanceFamily)).
+		Find(&candidates).Error; err != nil {
+		return nil, err
+	}
+
+	overlaps := overlappingRates(candidates, *rate)
+	if len(overlaps) == 0 {
+		return nil, nil
+	}
+	if !supersede {
+		return nil, &RateOverlapError{Conflicts: overlaps}
+	}
+
+	plan := planSupersede(overlaps, *rate)
+	for i := range plan.updated {
+		if err := tx.Save(&plan.updated[i]).Error; err != nil {
+			return nil, err
+		}
+	}
+	for i := range plan.deleted {
+		if err := tx.Delete(&plan.deleted[i]).Error; err != nil {
+			return nil, err
+		}
+	}
+	if len(plan.created) > 0 {
+		if err := tx.Create(&plan.created).Error; err != nil {
+			return nil, err
+		}
+	}
+	return overlaps, nil
+}
+
+// ImportCatalogRates replaces the configuration's rates of the given tier for the imported
+// instance families: existing rates are end-dated the day before the new rates take effect
+func (s *PricingService) ImportCatalogRates(ctx context.Context, configID uint, tier models.PricingTier, rates []models.PricingRate) error {
+	var config models.PricingConfig
+	if err := s.db.First(&config, configID).Error; err != nil {
+		return err
+	}
+
+	families := make([]string, 0, len(rates))
+	seen := make(map[string]bool)
+	for _, rate := range rates {
+		if !seen[rate.InstanceFamily] {
+			seen[rate.InstanceFamily] = true
+			families = append(families, rate.InstanceFamily)
+		}
+	}
+
+	err := s.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		for i := range rates {
+			rates[i].ConfigID = configID
+			rates[i].PricingTier = tier
+		}
+		if len(rates) == 0 {
+			return nil
+		}
+
+		// Rates starting on or after the import date are replaced outright
+		effectiveFrom := rates[0].EffectiveFrom
+		scope := tx.Model(&models.PricingRate{}).
+			Where("config_id = ? AND pricing_tier = ? AND instance_family IN ?", configID, tier, families)
+		if err := scope.Session(&gorm.Session{}).
+			Where("effective_from >= ?", effectiveFrom).
+			Delete(&models.PricingRate{}).Error; err != nil {
+			return err
+		}
+		if err := scope.Session(&gorm.Session{}).
+			Where("effective_to IS NULL OR effective_to >= ?", effectiveFrom).
+			Update("effective_to", effectiveFrom.AddDate(0, 0, -1)).Error; err != nil {
+			return err
+		}
+		if err := tx.Create(&rates).Error; err != nil {
+			return err
+		}
+		return s.recordVersion(tx, config.TenantID, config.ID, models.PricingActionRatesImport, "rate", 0, nil, rates)
+	})
+	if err != nil {
+		return err
+	}
+
+	s.cache.InvalidateTenant(config.TenantID)
 	return nil
 }
 
@@ -290,21 +505,110 @@ func (s *PricingService) DeleteRate(ctx context.Context, rateID uint) error {
 		return err
 	}
 
-	if err := s.db.Delete(&rate).Error; err != nil {
+	var config models.PricingConfig
+	if err := s.db.First(&config, rate.ConfigID).Error; err != nil {
+		return err
+	}
+
+	err := s.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := tx.Delete(&rate).Error; err != nil {
+			return err
+		}
+		return s.recordVersion(tx, config.TenantID, config.ID, models.PricingActionRateDelete, "rate", rate.ID, rate, nil)
+	})
+	if err != nil {
+		return err
+	}
+
+	s.cache.InvalidateTenant(config.TenantID)
+	return nil
+}
+
+// GetDiscount retrieves a negotiated discount
+func (s *PricingService) GetDiscount(ctx context.Context, discountID uint) (*models.PricingDiscount, error) {
+	var discount models.PricingDiscount
+	if err := s.db.First(&discount, discountID).Error; err != nil {
+		return nil, err
+	}
+	return &discount, nil
+}
+
+// SaveDiscount creates or updates a negotiated discount
+func (s *PricingService) SaveDiscount(ctx context.Context, discount *models.PricingDiscount) error {
+	var config models.PricingConfig
+	if err := s.db.First(&config, discount.ConfigID).Error; err != nil {
+		return err
+	}
+
+	err := s.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		action := models.PricingActionDiscountCreate
+		var before *models.PricingDiscount
+		if discount.ID != 0 {
+			action = models.PricingActionDiscountUpdate
+			before = &models.PricingDiscount{}
+			if err := tx.First(before, discount.ID).Error; err != nil {
+				return err
+			}
+		}
+		if err := tx.Save(discount).Error; err != nil {
+			return err
+		}
+		return s.recordVersion(tx, config.TenantID, config.ID, action, "discount", discount.ID, before, discount)
+	})
+	if err != nil {
+		return err
+	}
+
+	s.cache.InvalidateTenant(config.TenantID)
+	return nil
+}
+
+// DeleteDiscount deletes a negotiated discount
+func (s *PricingService) DeleteDiscount(ctx context.Context, discountID uint) error {
+	var discount models.PricingDiscount
+	if err := s.db.First(&discount, discountID).Error; err != nil {
 		return err
 	}
 
 	var config models.PricingConfig
-	if err := s.db.First(&config, rate.ConfigID).Error; err == nil {
-		s.cache.InvalidateTenant(config.TenantID)
+	if err := s.db.First(&config, discount.ConfigID).Error; err != nil {
+		return err
 	}
+
+	err := s.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := tx.Delete(&discount).Error; err != nil {
+			return err
+		}
+		return s.recordVersion(tx, config.TenantID, config.ID, models.PricingActionDiscountDelete, "discount", discount.ID, discount, nil)
+	})
+	if err != nil {
+		return err
+	}
+
+	s.cache.InvalidateTenant(config.TenantID)
 	return nil
 }
 
 // SetClusterPricing assigns a pricing config to a cluster
 func (s *PricingService) SetClusterPricing(ctx context.Context, clusterPricing *models.ClusterPricing) error {
-	// Upsert
-	err := s.db.Save(clusterPricing).Error
+	err := s.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		var before *models.ClusterPricing
+		var existing models.ClusterPricing
+		err := tx.Where("tenant_id = ? AND cluster_name = ?", clusterPricing.TenantID, clusterPricing.ClusterName).
+			First(&existing).Error
+		if err == nil {
+			before = &existing
+			clusterPricing.CreatedAt = existing.CreatedAt
+		} else if err != gorm.ErrRecordNotFound {
+			return err
+		}
+
+		// Upsert
+		if err := tx.Save(clusterPricing).Error; err != nil {
+			return err
+		}
+		return s.recordVersion(tx, clusterPricing.TenantID, clusterPricing.ConfigID, models.PricingActionClusterAssign, "cluster", 0, before, clusterPricing)
+	})
 	if err != nil {
 		return err
 	}
@@ -336,13 +640,18 @@ func (s *PricingService) ListClusterPricing(ctx context.Context, tenantID uint)
 
 // DeleteClusterPricing removes pricing config assignment for a cluster
 func (s *PricingService) DeleteClusterPricing(ctx context.Context, tenantID uint, clusterName string) error {
-	result := s.db.Where("tenant_id = ? AND cluster_name = ?", tenantID, clusterName).
-		Delete(&models.ClusterPricing{})
-	if result.Error != nil {
-		return result.Error
-	}
-	if result.RowsAffected == 0 {
-		return gorm.ErrRecordNotFound
+	err := s.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		var existing models.ClusterPricing
+		if err := tx.Where("tenant_id = ? AND cluster_name = ?", tenantID, clusterName).First(&existing).Error; err != nil {
+			return err
+		}
+		if err := tx.Where("tenant_id = ? AND cluster_name = ?", tenantID, clusterName).Delete(&models.ClusterPricing{}).Error; err != nil {
+			return err
+		}
+		return s.recordVersion(tx, tenantID, existing.ConfigID, models.PricingActionClusterUnassign, "cluster", 0, existing, nil)
+	})
+	if err != nil {
+		return err
 	}
 	s.cache.InvalidateTenant(tenantID)
 	return nil
@@ -350,28 +659,11 @@ func (s *PricingService) DeleteClusterPricing(ctx context.Context, tenantID uint
 
 // SetNodePricing sets pricing override for a node
 func (s *PricingService) SetNodePricing(ctx context.Context, nodePricing *models.NodePricing) error {
-	nodePricing.UpdatedAt = time.Now()
-
-	// Check if exists
-	var existing models.NodePricing
-	err := s.db.Where("tenant_id = ? AND cluster_name = ? AND node_name = ?",
-		nodePricing.TenantID, nodePricing.ClusterName, nodePricing.NodeName).
-		First(&existing).Error
-
-	if err == gorm.ErrRecordNotFound {
-		// Create new
-		if err := s.db.Create(nodePricing).Error; err != nil {
-			return err
-		}
-	} else if err != nil {
+	err := s.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		return s.setNodePricing(tx, nodePricing)
+	})
+	if err != nil {
 		return err
-	} else {
-		// Update existing
-		nodePricing.ID = existing.ID
-		nodePricing.CreatedAt = existing.CreatedAt
-		if err := s.db.Save(nodePricing).Error; err != nil {
-			return err
-		}
 	}
 
 	s.cache.InvalidateTenant(nodePricing.TenantID)
@@ -396,51 +688,3 @@ func (s *PricingService) ListProviders() []models.CloudProvider {
 		models.ProviderCustom,
 	}
 }
-
-// PricingCache methods
-
-// Get retrieves a cached pricing entry
-func (c *PricingCache) Get(key string) *models.EffectivePricing {
-	c.mu.RLock()
-	defer c.mu.RUnlock()
-
-	entry, ok := c.entries[key]
-	if !ok {
-		return nil
-	}
-	if time.Now().After(entry.expiresAt) {
-		return nil
-	}
-	return entry.pricing
-}
-
-// Set stores a pricing entry in cache
-func (c *PricingCache) Set(key string, pricing *models.EffectivePricing) {
-	c.mu.Lock()
-	defer c.mu.Unlock()
-
-	c.entries[key] = &pricingCacheEntry{
-		pricing:   pricing,
-		expiresAt: time.Now().Add(c.ttl),
-	}
-}
-
-// InvalidateTenant removes all cached entries for a tenant
-func (c *PricingCache) InvalidateTenant(tenantID uint) {
-	c.mu.Lock()
-	defer c.mu.Unlock()
-
-	prefix := fmt.Sprintf("%d:", tenantID)
-	for key := range c.entries {
-		if len(key) >= len(prefix) && key[:len(prefix)] == prefix {
-			delete(c.entries, key)
-		}
-	}
-}
-
-// Clear removes all cached entries
-func (c *PricingCache) Clear() {
-	c.mu.Lock()
-	defer c.mu.Unlock()
-	c.entries = make(map[string]*pricingCacheEntry)
-}
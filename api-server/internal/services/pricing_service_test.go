@@ -0,0 +1,61 @@
+package services
+
+import (
+	"testing"
+
+	"github.com/bugfreev587/k8s-cost-api-server/internal/models"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestNodeRatesSplitHourlyCost(t *testing.T) {
+	svc := &PricingService{}
+	config := &models.PricingConfig{
+		Provider: models.ProviderAWS,
+		Rates: []models.PricingRate{
+			{ResourceType: models.ResourceCPU, PricingTier: models.TierOnDemand, CostPerUnit: 0.04},
+			{ResourceType: models.ResourceMemory, PricingTier: models.TierOnDemand, CostPerUnit: 0.005},
+			{ResourceType: models.ResourceCPU, PricingTier: models.TierOnDemand, InstanceFamily: "m5", CostPerUnit: 0.05},
+			{ResourceType: models.ResourceCPU, PricingTier: models.TierSpot, InstanceFamily: "m5", CostPerUnit: 0.015},
+			{ResourceType: models.ResourceMemory, PricingTier: models.TierOnDemand, InstanceFamily: "m5", CostPerUnit: 0.006},
+		},
+	}
+	pricing := svc.buildEffectivePricing(config)
+	assert.InDelta(t, 8.0, pricing.CPURAMCostRatio, 1e-9)
+
+	override := 0.2
+	svc.applyNodeOverrides(pricing, []models.NodePricing{
+		{NodeName: "node-a", HourlyCostOverride: &override},
+		{NodeName: "node-b", InstanceType: "m5.xlarge"},
+	})
+
+	// Whole-node price split 8:1 sums back to the node price over its capacity
+	cpuRate, memRate, source := pricing.NodeRates("node-a", "", 4, 16)
+	assert.Equal(t, models.PriceSourceNodeOverride, source)
+	assert.InDelta(t, 8.0, cpuRate/memRate, 1e-9)
+	assert.InDelta(t, 0.2, 4*cpuRate+16*memRate, 1e-12)
+
+	// Unknown capacity falls back to the default rates
+	cpuRate, _, source = pricing.NodeRates("node-a", "", 0, 0)
+	assert.Equal(t, models.PriceSourceDefault, source)
+	assert.InDelta(t, 0.04, cpuRate, 1e-12)
+
+	// Declared instance type, metrics-reported instance family (on-demand wins over spot)
+	cpuRate, _, source = pricing.NodeRates("node-b", "", 4, 16)
+	assert.Equal(t, models.PriceSourceNodeInstance, source)
+	assert.InDelta(t, 0.05, cpuRate, 1e-12)
+	cpuRate, memRate, source = pricing.NodeRates("node-c", "m5.2xlarge", 8, 32)
+	assert.Equal(t, models.PriceSourceInstanceFamily, source)
+	assert.InDelta(t, 0.05, cpuRate, 1e-12)
+	assert.InDelta(t, 0.006, memRate, 1e-12)
+	_, _, source = pricing.NodeRates("node-d", "m5a.large", 2, 8)
+	assert.Equal(t, models.PriceSourceDefault, source)
+
+	// A configured ratio overrides the one derived from the rates
+	ratio := 4.0
+	config.CPURAMCostRatio = &ratio
+	pricing = svc.buildEffectivePricing(config)
+	pricing.NodePricing["node-a"] = &models.InstancePrice{HourlyCost: 0.2}
+	cpuRate, memRate, _ = pricing.NodeRates("node-a", "", 4, 16)
+	assert.InDelta(t, 4.0, cpuRate/memRate, 1e-9)
+	assert.InDelta(t, 0.2, 4*cpuRate+16*memRate, 1e-12)
+}
@@ -0,0 +1,418 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/bugfreev587/k8s-cost-api-server/internal/models"
+)
+
+// pricingTiers lists the known pricing tiers in the order they are preferred when a
+// config has rates of several tiers for the same resource and family
+var pricingTiers = []models.PricingTier{
+	models.TierOnDemand,
+	models.TierSpot,
+	models.TierPreemptible,
+	models.TierReserved1Yr,
+	models.TierReserved3Yr,
+}
+
+// tierRank returns a tier's position in pricingTiers (unknown tiers last)
+func tierRank(tier models.PricingTier) int {
+	for i, t := range pricingTiers {
+		if t == tier {
+			return i
+		}
+	}
+	return len(pricingTiers)
+}
+
+// ValidatePricingRate checks a pricing rate before it is saved: known resource type and
+// tier, the resource's unit (an empty unit is set to it), a non-negative cost and an
+// effective range that does not end before it starts
+func ValidatePricingRate(rate *models.PricingRate) error {
+	unit, ok := models.ResourceUnits[rate.ResourceType]
+	if !ok {
+		return fmt.Errorf("invalid resource_type: %s", rate.ResourceType)
+	}
+	if tierRank(rate.PricingTier) == len(pricingTiers) {
+		return fmt.Errorf("invalid pricing_tier: %s", rate.PricingTier)
+	}
+	rate.Unit = strings.ToLower(strings.TrimSpace(rate.Unit))
+	if rate.Unit == "" {
+		rate.Unit = unit
+	}
+	if rate.Unit != unit {
+		return fmt.Errorf("invalid unit %q for %s rates (expected %q)", rate.Unit, rate.ResourceType, unit)
+	}
+	if rate.CostPerUnit < 0 {
+		return fmt.Errorf("cost_per_unit must not be negative")
+	}
+	if rate.EffectiveTo != nil && rateDay(*rate.EffectiveTo).Before(rateDay(rate.EffectiveFrom)) {
+		return fmt.Errorf("effective_to must not be before effective_from")
+	}
+	return nil
+}
+
+// RateOverlapError is returned when a rate's effective range overlaps existing rates of
+// the same resource, tier and instance family
+type RateOverlapError struct {
+	Conflicts []models.PricingRate
+}
+
+func (e *RateOverlapError) Error() string {
+	ranges := make([]string, len(e.Conflicts))
+	for i, rate := range e.Conflicts {
+		ranges[i] = fmt.Sprintf("rate %d (%s)", rate.ID, formatRateRange(rate.EffectiveFrom, rate.EffectiveTo))
+	}
+	return fmt.Sprintf("effective range overlaps %s; end-date the existing rates or set supersede", strings.Join(ranges, ", "))
+}
+
+// rateKey identifies the rates that compete with each other: same resource, tier and
+// instance family (case-insensitive, as family lookups are)
+type rateKey struct {
+	resource models.ResourceType
+	tier     models.PricingTier
+	family   string
+}
+
+func keyOfRate(rate models.PricingRate) rateKey {
+	return rateKey{rate.ResourceType, rate.PricingTier, strings.ToLower(rate.InstanceFamily)}
+}
+
+// rateDay truncates a rate date to its day; effective dates are stored as dates
+func rateDay(t time.Time) time.Time {
+	y, m, d := t.Date()
+	return time.Date(y, m, d, 0, 0, 0, 0, time.UTC)
+}
+
+// rangesOverlap reports whether two inclusive effective date ranges share a day
+func rangesOverlap(aFrom time.Time, aTo *time.Time, bFrom time.Time, bTo *time.Time) bool {
+	if aTo != nil && rateDay(*aTo).Before(rateDay(bFrom)) {
+		return false
+	}
+	if bTo != nil && rateDay(*bTo).Before(rateDay(aFrom)) {
+		return false
+	}
+	return true
+}
+
+// rateWins reports whether rate a takes precedence over rate b where both are effective:
+// the later effective_from wins, then the more recently created (higher ID)
+func rateWins(a, b models.PricingRate) bool {
+	aFrom, bFrom := rateDay(a.EffectiveFrom), rateDay(b.EffectiveFrom)
+	if !aFrom.Equal(bFrom) {
+		return aFrom.After(bFrom)
+	}
+	return a.ID > b.ID
+}
+
+// winningRates keeps the winning rate of each resource/tier/family among rates effective
+// at the same time, ordered by resource type, tier preference and family so the effective
+// pricing built from them does not depend on the order rates were loaded in
+func winningRates(rates []models.PricingRate) []models.PricingRate {
+	winners := make(map[rateKey]models.PricingRate)
+	for _, rate := range rates {
+		key := keyOfRate(rate)
+		if current, ok := winners[key]; !ok || rateWins(rate, current) {
+			winners[key] = rate
+		}
+	}
+
+	result := make([]models.PricingRate, 0, len(winners))
+	for _, rate := range winners {
+		result = append(result, rate)
+	}
+	sort.Slice(result, func(i, j int) bool {
+		a, b := result[i], result[j]
+		if a.ResourceType != b.ResourceType {
+			return a.ResourceType < b.ResourceType
+		}
+		if ra, rb := tierRank(a.PricingTier), tierRank(b.PricingTier); ra != rb {
+			return ra < rb
+		}
+		return strings.ToLower(a.InstanceFamily) < strings.ToLower(b.InstanceFamily)
+	})
+	return result
+}
+
+// overlappingRates returns the rates competing with rate (same resource, tier and
+// family, other ID) whose effective range overlaps its range
+func overlappingRates(existing []models.PricingRate, rate models.PricingRate) []models.PricingRate {
+	key := keyOfRate(rate)
+	var overlaps []models.PricingRate
+	for _, other := range existing {
+		if other.ID == rate.ID || keyOfRate(other) != key {
+			continue
+		}
+		if rangesOverlap(other.EffectiveFrom, other.EffectiveTo, rate.EffectiveFrom, rate.EffectiveTo) {
+			overlaps = append(overlaps, other)
+		}
+	}
+	return overlaps
+}
+
+// rateSupersedePlan is the set of changes that gives a new rate sole ownership of its
+// effective range
+type rateSupersedePlan struct {
+	updated []models.PricingRate // trimmed to end before or start after the new range
+	deleted []models.PricingRate // entirely within the new range
+	created []models.PricingRate // the remainder of rates that spanned the whole new range
+}
+
+// planSupersede trims the overlapping rates around a new rate's effective range: rates
+// inside the range are deleted, rates straddling either end are shortened, and a rate
+// spanning the whole range is split around it
+func planSupersede(overlaps []models.PricingRate, rate models.PricingRate) rateSupersedePlan {
+	var plan rateSupersedePlan
+	from := rateDay(rate.EffectiveFrom)
+	for _, other := range overlaps {
+		startsBefore := rateDay(other.EffectiveFrom).Before(from)
+		endsAfter := rate.EffectiveTo != nil &&
+			(other.EffectiveTo == nil || rateDay(*other.EffectiveTo).After(rateDay(*rate.EffectiveTo)))
+		after := func() time.Time { return rateDay(*rate.EffectiveTo).AddDate(0, 0, 1) }
+
+		switch {
+		case startsBefore && endsAfter:
+			tail := other
+			tail.ID = 0
+			tail.CreatedAt = time.Time{}
+			tail.EffectiveFrom = after()
+			plan.created = append(plan.created, tail)
+			fallthrough
+		case startsBefore:
+			dayBefore := from.AddDate(0, 0, -1)
+			other.EffectiveTo = &dayBefore
+			plan.updated = append(plan.updated, other)
+		case endsAfter:
+			other.EffectiveFrom = after()
+			plan.updated = append(plan.updated, other)
+		default:
+			plan.deleted = append(plan.deleted, other)
+		}
+	}
+	return plan
+}
+
+// Pricing validation issue severities
+const (
+	PricingIssueError   = "error"
+	PricingIssueWarning = "warning"
+)
+
+// PricingRuleIssue is a problem found in a configuration's rates
+type PricingRuleIssue struct {
+	Severity string `json:"severity"`
+	Code     string `json:"code"` // invalid_rate, overlap, zero_cost
+	RateIDs  []uint `json:"rate_ids"`
+	Message  string `json:"message"`
+}
+
+// RateSegment is a date range during which one rate wins for a resource/tier/family
+type RateSegment struct {
+	From        time.Time  `json:"from"`
+	To          *time.Time `json:"to,omitempty"` // nil = open-ended
+	RateID      uint       `json:"rate_id"`
+	CostPerUnit float64    `json:"cost_per_unit"`
+	Overrides   []uint     `json:"overrides,omitempty"` // overlapping rates the winner takes precedence over
+}
+
+// PricingRuleTimeline shows which rate wins over time for one resource/tier/family
+type PricingRuleTimeline struct {
+	ResourceType   models.ResourceType `json:"resource_type"`
+	PricingTier    models.PricingTier  `json:"pricing_tier"`
+	InstanceFamily string              `json:"instance_family,omitempty"`
+	Segments       []RateSegment       `json:"segments"`
+}
+
+// PricingValidationReport lists a configuration's rate problems and, for each
+// resource/tier/family, the winning rate over time (or at one date)
+type PricingValidationReport struct {
+	ConfigID uint                  `json:"config_id"`
+	Valid    bool                  `json:"valid"` // no errors
+	At       *time.Time            `json:"at,omitempty"`
+	Issues   []PricingRuleIssue    `json:"issues"`
+	Rules    []PricingRuleTimeline `json:"rules"`
+}
+
+// ValidateConfig checks all rates of a configuration, including past and future ones.
+// When at is set, each rule only shows the segment containing that date.
+func (s *PricingService) ValidateConfig(ctx context.Context, configID uint, at *time.Time) (*PricingValidationReport, error) {
+	var config models.PricingConfig
+	if err := s.db.WithContext(ctx).Preload("Rates").First(&config, configID).Error; err != nil {
+		return nil, err
+	}
+	report := validatePricingRates(config.Rates, at)
+	report.ConfigID = config.ID
+	return report, nil
+}
+
+// validatePricingRates builds the validation report of a set of rates
+func validatePricingRates(rates []models.PricingRate, at *time.Time) *PricingValidationReport {
+	report := &PricingValidationReport{
+		Valid:  true,
+		Issues: []PricingRuleIssue{},
+		Rules:  []PricingRuleTimeline{},
+	}
+	addIssue := func(severity, code, message string, ids ...uint) {
+		report.Issues = append(report.Issues, PricingRuleIssue{Severity: severity, Code: code, RateIDs: ids, Message: message})
+		if severity == PricingIssueError {
+			report.Valid = false
+		}
+	}
+	if at != nil {
+		day := rateDay(*at)
+		report.At = &day
+	}
+
+	groups := make(map[rateKey][]models.PricingRate)
+	var keys []rateKey
+	for _, rate := range rates {
+		check := rate
+		if err := ValidatePricingRate(&check); err != nil {
+			addIssue(PricingIssueError, "invalid_rate", fmt.Sprintf("rate %d: %v", rate.ID, err), rate.ID)
+			if rate.EffectiveTo != nil && rateDay(*rate.EffectiveTo).Before(rateDay(rate.EffectiveFrom)) {
+				continue // never effective
+			}
+		} else if rate.CostPerUnit == 0 {
+			addIssue(PricingIssueWarning, "zero_cost", fmt.Sprintf("rate %d prices %s at 0", rate.ID, rate.ResourceType), rate.ID)
+		}
+		key := keyOfRate(rate)
+		if _, ok := groups[key]; !ok {
+			keys = append(keys, key)
+		}
+		groups[key] = append(groups[key], rate)
+	}
+	sort.Slice(keys, func(i, j int) bool {
+		if keys[i].resource != keys[j].resource {
+			return keys[i].resource < keys[j].resource
+		}
+		if ri, rj := tierRank(keys[i].tier), tierRank(keys[j].tier); ri != rj {
+			return ri < rj
+		}
+		return keys[i].family < keys[j].family
+	})
+
+	for _, key := range keys {
+		group := groups[key]
+		sort.Slice(group, func(i, j int) bool { return rateWins(group[j], group[i]) })
+		for i, rate := range group {
+			for _, other := range group[i+1:] {
+				if rangesOverlap(rate.EffectiveFrom, rate.EffectiveTo, other.EffectiveFrom, other.EffectiveTo) {
+					addIssue(PricingIssueError, "overlap", fmt.Sprintf("rates %d (%s) and %d (%s) overlap; rate %d wins",
+						rate.ID, formatRateRange(rate.EffectiveFrom, rate.EffectiveTo),
+						other.ID, formatRateRange(other.EffectiveFrom, other.EffectiveTo), other.ID), rate.ID, other.ID)
+				}
+			}
+		}
+
+		timeline := PricingRuleTimeline{
+			ResourceType:   group[0].ResourceType,
+			PricingTier:    group[0].PricingTier,
+			InstanceFamily: group[0].InstanceFamily,
+		}
+		for _, segment := range rateTimeline(group) {
+			if report.At != nil && !effectiveAt(segment.From, segment.To, *report.At) {
+				continue
+			}
+			timeline.Segments = append(timeline.Segments, segment)
+		}
+		if len(timeline.Segments) > 0 {
+			report.Rules = append(report.Rules, timeline)
+		}
+	}
+	return report
+}
+
+// rateTimeline splits the effective ranges of competing rates into segments with a single
+// winning rate, merging adjacent segments with the same winner and overridden rates
+func rateTimeline(rates []models.PricingRate) []RateSegment {
+	var bounds []time.Time
+	seen := make(map[time.Time]bool)
+	addBound := func(t time.Time) {
+		if !seen[t] {
+			seen[t] = true
+			bounds = append(bounds, t)
+		}
+	}
+	for _, rate := range rates {
+		addBound(rateDay(rate.EffectiveFrom))
+		if rate.EffectiveTo != nil {
+			addBound(rateDay(*rate.EffectiveTo).AddDate(0, 0, 1))
+		}
+	}
+	sort.Slice(bounds, func(i, j int) bool { return bounds[i].Before(bounds[j]) })
+
+	var segments []RateSegment
+	for i, start := range bounds {
+		var winner *models.PricingRate
+		var competing []uint
+		for j := range rates {
+			rate := rates[j]
+			var to *time.Time
+			if rate.EffectiveTo != nil {
+				day := rateDay(*rate.EffectiveTo)
+				to = &day
+			}
+			if !effectiveAt(rateDay(rate.EffectiveFrom), to, start) {
+				continue
+			}
+			if winner == nil || rateWins(rate, *winner) {
+				if winner != nil {
+					competing = append(competing, winner.ID)
+				}
+				winner = &rates[j]
+			} else {
+				competing = append(competing, rate.ID)
+			}
+		}
+		if winner == nil {
+			continue
+		}
+		sort.Slice(competing, func(a, b int) bool { return competing[a] < competing[b] })
+
+		var end *time.Time
+		if i+1 < len(bounds) {
+			last := bounds[i+1].AddDate(0, 0, -1)
+			end = &last
+		}
+		if n := len(segments); n > 0 {
+			prev := &segments[n-1]
+			if prev.RateID == winner.ID && prev.To != nil && prev.To.AddDate(0, 0, 1).Equal(start) && equalIDs(prev.Overrides, competing) {
+				prev.To = end
+				continue
+			}
+		}
+		segments = append(segments, RateSegment{
+			From:        start,
+			To:          end,
+			RateID:      winner.ID,
+			CostPerUnit: winner.CostPerUnit,
+			Overrides:   competing,
+		})
+	}
+	return segments
+}
+
+func equalIDs(a, b []uint) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+	return true
+}
+
+// formatRateRange formats an effective date range for messages
+func formatRateRange(from time.Time, to *time.Time) string {
+	if to == nil {
+		return from.Format("2006-01-02") + " onwards"
+	}
+	return from.Format("2006-01-02") + " to " + to.Format("2006-01-02")
+}
@@ -0,0 +1,98 @@
+package services
+
+import (
+	"testing"
+	"time"
+
+	"github.com/bugfreev587/k8s-cost-api-server/internal/models"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func day(s string) time.Time {
+	t, _ := time.Parse("2006-01-02", s)
+	return t
+}
+
+func dayPtr(s string) *time.Time {
+	t := day(s)
+	return &t
+}
+
+func TestValidatePricingRate(t *testing.T) {
+	rate := &models.PricingRate{ResourceType: models.ResourceMemory, PricingTier: models.TierOnDemand, CostPerUnit: 0.004, EffectiveFrom: day("2024-01-01")}
+	require.NoError(t, ValidatePricingRate(rate))
+	assert.Equal(t, "gb-hour", rate.Unit)
+
+	rate.Unit = "Core-Hour"
+	assert.ErrorContains(t, ValidatePricingRate(rate), `invalid unit "core-hour" for memory`)
+
+	rate.Unit = "gb-hour"
+	rate.CostPerUnit = -1
+	assert.Error(t, ValidatePricingRate(rate))
+
+	rate.CostPerUnit = 0.004
+	rate.EffectiveTo = dayPtr("2023-12-31")
+	assert.Error(t, ValidatePricingRate(rate))
+
+	assert.Error(t, ValidatePricingRate(&models.PricingRate{ResourceType: models.ResourceCPU, PricingTier: "committed"}))
+}
+
+func TestPlanSupersede(t *testing.T) {
+	existing := []models.PricingRate{
+		{ID: 1, ResourceType: models.ResourceCPU, PricingTier: models.TierOnDemand, EffectiveFrom: day("2024-01-01")},
+		{ID: 2, ResourceType: models.ResourceCPU, PricingTier: models.TierOnDemand, EffectiveFrom: day("2024-03-05"), EffectiveTo: dayPtr("2024-03-10")},
+		{ID: 3, ResourceType: models.ResourceCPU, PricingTier: models.TierSpot, EffectiveFrom: day("2024-01-01")},
+		{ID: 4, ResourceType: models.ResourceCPU, PricingTier: models.TierOnDemand, EffectiveFrom: day("2023-01-01"), EffectiveTo: dayPtr("2023-12-31")},
+	}
+	rate := models.PricingRate{ResourceType: models.ResourceCPU, PricingTier: models.TierOnDemand, EffectiveFrom: day("2024-03-01"), EffectiveTo: dayPtr("2024-03-31")}
+
+	overlaps := overlappingRates(existing, rate)
+	require.Len(t, overlaps, 2)
+
+	plan := planSupersede(overlaps, rate)
+	require.Len(t, plan.updated, 1)
+	assert.Equal(t, uint(1), plan.updated[0].ID)
+	assert.Equal(t, day("2024-02-29"), *plan.updated[0].EffectiveTo)
+	require.Len(t, plan.created, 1, "the open-ended rate continues after the new range")
+	assert.Equal(t, day("2024-04-01"), plan.created[0].EffectiveFrom)
+	assert.Nil(t, plan.created[0].EffectiveTo)
+	require.Len(t, plan.deleted, 1)
+	assert.Equal(t, uint(2), plan.deleted[0].ID)
+	assert.Nil(t, overlaps[0].EffectiveTo, "the overlaps keep their original state")
+}
+
+func TestValidatePricingRatesTimeline(t *testing.T) {
+	rates := []models.PricingRate{
+		{ID: 1, ResourceType: models.ResourceCPU, PricingTier: models.TierOnDemand, Unit: "core-hour", CostPerUnit: 0.04, EffectiveFrom: day("2024-01-01")},
+		{ID: 2, ResourceType: models.ResourceCPU, PricingTier: models.TierOnDemand, Unit: "core-hour", CostPerUnit: 0.03, EffectiveFrom: day("2024-03-01"), EffectiveTo: dayPtr("2024-03-31")},
+		{ID: 3, ResourceType: models.ResourceMemory, PricingTier: models.TierOnDemand, Unit: "core-hour", CostPerUnit: 0.005, EffectiveFrom: day("2024-01-01")},
+	}
+
+	report := validatePricingRates(rates, nil)
+	assert.False(t, report.Valid)
+	require.Len(t, report.Issues, 2)
+	assert.Equal(t, "invalid_rate", report.Issues[0].Code)
+	assert.Equal(t, "overlap", report.Issues[1].Code)
+	assert.Equal(t, []uint{1, 2}, report.Issues[1].RateIDs)
+
+	require.Len(t, report.Rules, 2)
+	cpu := report.Rules[0]
+	require.Len(t, cpu.Segments, 3)
+	assert.Equal(t, uint(1), cpu.Segments[0].RateID)
+	assert.Equal(t, day("2024-02-29"), *cpu.Segments[0].To)
+	assert.Equal(t, uint(2), cpu.Segments[1].RateID)
+	assert.Equal(t, []uint{1}, cpu.Segments[1].Overrides)
+	assert.Equal(t, day("2024-04-01"), cpu.Segments[2].From)
+	assert.Nil(t, cpu.Segments[2].To)
+
+	report = validatePricingRates(rates, dayPtr("2024-03-15"))
+	require.Len(t, report.Rules[0].Segments, 1)
+	assert.Equal(t, uint(2), report.Rules[0].Segments[0].RateID)
+
+	// Effective pricing resolves the overlap to the same rate regardless of load order
+	config := &models.PricingConfig{Provider: models.ProviderAWS, Rates: []models.PricingRate{rates[1], rates[0]}}
+	assert.InDelta(t, 0.03, (&PricingService{}).buildEffectivePricing(config).CPUPerCoreHour, 1e-12)
+	config.Rates = []models.PricingRate{rates[0], rates[1]}
+	assert.InDelta(t, 0.03, (&PricingService{}).buildEffectivePricing(config).CPUPerCoreHour, 1e-12)
+}
@@ -0,0 +1,340 @@
+package services
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/bugfreev587/k8s-cost-api-server/internal/models"
+	"gorm.io/gorm"
+)
+
+// WithActor returns a copy of the service that records actor as the author of the
+// pricing changes it makes
+func (s *PricingService) WithActor(actor string) *PricingService {
+	clone := *s
+	clone.actor = actor
+	return &clone
+}
+
+// PinnedAt returns a copy of the service that resolves pricing configs, cluster assignments
+// and node overrides as they stood at the given time, from their recorded versions, so
+// that past reports can be reproduced after pricing was edited. Assignments and overrides
+// not changed since versioning began use their current state.
+func (s *PricingService) PinnedAt(t time.Time) *PricingService {
+	clone := *s
+	clone.pinnedAt = &t
+	return &clone
+}
+
+// proposedPricing is a what-if pricing config that replaces the assigned config of the
+// listed clusters (all clusters when none are listed)
+type proposedPricing struct {
+	config   *models.PricingConfig
+	clusters []string
+}
+
+// configFor returns the proposed config for a cluster with the rates and discounts
+// effective at asOf, or nil when no proposal applies to the cluster
+func (p *proposedPricing) configFor(clusterName string, asOf time.Time) *models.PricingConfig {
+	if p == nil || (len(p.clusters) > 0 && !containsString(p.clusters, clusterName)) {
+		return nil
+	}
+	return effectiveConfigAt(p.config, asOf)
+}
+
+// WithProposedConfig returns a copy of the service that prices the given clusters (all
+// clusters when empty) with a proposed config instead of their assigned one. Rates and
+// discounts without an effective_from date apply to the whole window. The copy has its
+// own cache so proposed rates never leak into regular lookups.
+func (s *PricingService) WithProposedConfig(config *models.PricingConfig, clusters []string) *PricingService {
+	clone := *s
+	clone.proposed = &proposedPricing{config: config, clusters: clusters}
+	clone.cache = NewPricingCache(nil, s.cache.ttl)
+	return &clone
+}
+
+// loadEffectiveConfig loads a config with the rates and discounts effective at asOf: its
+// current state, or the state recorded in its latest version at or before the pinned time.
+// It returns nil when the config did not exist at the pinned time.
+func (s *PricingService) loadEffectiveConfig(ctx context.Context, configID uint, asOf time.Time) (*models.PricingConfig, error) {
+	if s.pinnedAt != nil {
+		config, err := s.configAsOf(ctx, configID, *s.pinnedAt)
+		if err != nil || config == nil {
+			return nil, err
+		}
+		return effectiveConfigAt(config, asOf), nil
+	}
+
+	var config models.PricingConfig
+	err := s.db.Preload("Rates", "effective_from <= ? AND (effective_to IS NULL OR effective_to >= ?)", asOf, asOf).
+		Preload("Discounts", "effective_from <= ? AND (effective_to IS NULL OR effective_to >= ?)", asOf, asOf).
+		First(&config, configID).Error
+	if err != nil {
+		return nil, err
+	}
+	return &config, nil
+}
+
+// configAsOf returns the snapshot of a config from its latest version recorded at or
+// before t, or nil when the config did not exist (or was deleted) at that time
+func (s *PricingService) configAsOf(ctx context.Context, configID uint, t time.Time) (*models.PricingConfig, error) {
+	var version models.PricingVersion
+	err := s.db.WithContext(ctx).
+		Where("config_id = ? AND created_at <= ?", configID, t).
+		Order("version DESC").
+		First(&version).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to load pricing version: %w", err)
+	}
+	if len(version.Snapshot) == 0 {
+		return nil, nil
+	}
+
+	var config models.PricingConfig
+	if err := json.Unmarshal(version.Snapshot, &config); err != nil {
+		return nil, fmt.Errorf("invalid snapshot in pricing version %d: %w", version.ID, err)
+	}
+	return &config, nil
+}
+
+// clusterAssignment returns a cluster's config assignment, as it stood at the pinned time
+// if any, or gorm.ErrRecordNotFound when the cluster has none
+func (s *PricingService) clusterAssignment(ctx context.Context, tenantID uint, clusterName string) (*models.ClusterPricing, error) {
+	if s.pinnedAt != nil {
+		versions, err := s.clusterVersions(ctx, tenantID, "cluster", clusterName)
+		if err != nil {
+			return nil, err
+		}
+		if state, ok := stateAsOf(versions, *s.pinnedAt); ok {
+			if state == nil {
+				return nil, gorm.ErrRecordNotFound
+			}
+			var clusterPricing models.ClusterPricing
+			if err := json.Unmarshal(state, &clusterPricing); err != nil {
+				return nil, fmt.Errorf("invalid cluster assignment in pricing version: %w", err)
+			}
+			return &clusterPricing, nil
+		}
+	}
+
+	var clusterPricing models.ClusterPricing
+	err := s.db.WithContext(ctx).
+		Where("tenant_id = ? AND cluster_name = ?", tenantID, clusterName).
+		First(&clusterPricing).Error
+	if err != nil {
+		return nil, err
+	}
+	return &clusterPricing, nil
+}
+
+// nodeOverrides returns the node pricing overrides of a cluster, as they stood at the
+// pinned time if any
+func (s *PricingService) nodeOverrides(ctx context.Context, tenantID uint, clusterName string) ([]models.NodePricing, error) {
+	var current []models.NodePricing
+	err := s.db.WithContext(ctx).
+		Where("tenant_id = ? AND cluster_name = ?", tenantID, clusterName).
+		Find(&current).Error
+	if err != nil {
+		return nil, fmt.Errorf("failed to load node pricing: %w", err)
+	}
+	if s.pinnedAt == nil {
+		return current, nil
+	}
+
+	versions, err := s.clusterVersions(ctx, tenantID, "node", clusterName)
+	if err != nil {
+		return nil, err
+	}
+	return nodeOverridesAsOf(current, versions, *s.pinnedAt)
+}
+
+// nodeOverridesAsOf rebuilds the node overrides of a cluster at t from the current
+// overrides and the versions of the cluster's overrides (oldest first); overrides without
+// versions keep their current state
+func nodeOverridesAsOf(current []models.NodePricing, versions []models.PricingVersion, t time.Time) ([]models.NodePricing, error) {
+	history := make(map[uint][]models.PricingVersion)
+	var ids []uint
+	for _, version := range versions {
+		if _, ok := history[version.EntityID]; !ok {
+			ids = append(ids, version.EntityID)
+		}
+		history[version.EntityID] = append(history[version.EntityID], version)
+	}
+
+	var overrides []models.NodePricing
+	for _, override := range current {
+		if _, ok := history[override.ID]; !ok {
+			overrides = append(overrides, override)
+		}
+	}
+	for _, id := range ids {
+		state, _ := stateAsOf(history[id], t)
+		if state == nil {
+			continue
+		}
+		var override models.NodePricing
+		if err := json.Unmarshal(state, &override); err != nil {
+			return nil, fmt.Errorf("invalid node override in pricing version: %w", err)
+		}
+		overrides = append(overrides, override)
+	}
+	return overrides, nil
+}
+
+// clusterVersions loads the versions of one cluster's assignment (entityType "cluster") or
+// node overrides ("node"), oldest first, without config snapshots
+func (s *PricingService) clusterVersions(ctx context.Context, tenantID uint, entityType, clusterName string) ([]models.PricingVersion, error) {
+	var versions []models.PricingVersion
+	err := s.db.WithContext(ctx).
+		Omit("snapshot").
+		Where("tenant_id = ? AND entity_type = ? AND COALESCE(after, before)->>'cluster_name' = ?", tenantID, entityType, clusterName).
+		Order("created_at, id").
+		Find(&versions).Error
+	if err != nil {
+		return nil, fmt.Errorf("failed to load pricing versions: %w", err)
+	}
+	return versions, nil
+}
+
+// stateAsOf returns the state of a cluster assignment or node override at t from its
+// versions (oldest first): the after state of the last change at or before t, or the before
+// state of the first later change. ok is false when there are no versions.
+func stateAsOf(versions []models.PricingVersion, t time.Time) (state models.PricingJSON, ok bool) {
+	if len(versions) == 0 {
+		return nil, false
+	}
+	for i := len(versions) - 1; i >= 0; i-- {
+		if !versions[i].CreatedAt.After(t) {
+			return versions[i].After, true
+		}
+	}
+	return versions[0].Before, true
+}
+
+// effectiveConfigAt returns a copy of a config keeping the rates and discounts effective
+// at asOf; a zero effective_from means always effective
+func effectiveConfigAt(config *models.PricingConfig, asOf time.Time) *models.PricingConfig {
+	effective := *config
+	effective.Rates = nil
+	for _, rate := range config.Rates {
+		if effectiveAt(rate.EffectiveFrom, rate.EffectiveTo, asOf) {
+			effective.Rates = append(effective.Rates, rate)
+		}
+	}
+	effective.Discounts = nil
+	for _, discount := range config.Discounts {
+		if effectiveAt(discount.EffectiveFrom, discount.EffectiveTo, asOf) {
+			effective.Discounts = append(effective.Discounts, discount)
+		}
+	}
+	return &effective
+}
+
+// effectiveAt mirrors the effective date filter of pricing rate queries
+func effectiveAt(from time.Time, to *time.Time, asOf time.Time) bool {
+	if !from.IsZero() && from.After(asOf) {
+		return false
+	}
+	return to == nil || !to.Before(asOf)
+}
+
+// recordVersion appends a version of a config after a change made in tx, with the
+// before/after state of the changed entity and a snapshot of the whole config
+func (s *PricingService) recordVersion(tx *gorm.DB, tenantID, configID uint, action, entityType string, entityID uint, before, after interface{}) error {
+	version := &models.PricingVersion{
+		TenantID:   tenantID,
+		ConfigID:   configID,
+		Action:     action,
+		EntityType: entityType,
+		EntityID:   entityID,
+		Actor:      s.actor,
+	}
+
+	var err error
+	if version.Before, err = pricingJSON(before); err != nil {
+		return err
+	}
+	if version.After, err = pricingJSON(after); err != nil {
+		return err
+	}
+
+	var config models.PricingConfig
+	err = tx.Preload("Rates").Preload("Discounts").First(&config, configID).Error
+	switch {
+	case err == nil:
+		if version.Snapshot, err = pricingJSON(config); err != nil {
+			return err
+		}
+	case !errors.Is(err, gorm.ErrRecordNotFound):
+		return err
+	}
+
+	if err := tx.Model(&models.PricingVersion{}).
+		Where("config_id = ?", configID).
+		Select("COALESCE(MAX(version), 0)").
+		Scan(&version.Version).Error; err != nil {
+		return err
+	}
+	version.Version++
+
+	return tx.Create(version).Error
+}
+
+// pricingJSON marshals a changed entity or snapshot; nil values are stored as NULL
+func pricingJSON(v interface{}) (models.PricingJSON, error) {
+	data, err := json.Marshal(v)
+	if err != nil {
+		return nil, err
+	}
+	if string(data) == "null" {
+		return nil, nil
+	}
+	return data, nil
+}
+
+// ListVersions lists the versions of a config, newest first, without their snapshots
+func (s *PricingService) ListVersions(ctx context.Context, configID uint) ([]models.PricingVersion, error) {
+	var versions []models.PricingVersion
+	err := s.db.WithContext(ctx).
+		Omit("snapshot").
+		Where("config_id = ?", configID).
+		Order("version DESC").
+		Find(&versions).Error
+	return versions, err
+}
+
+// GetVersion retrieves a pricing version with its snapshot
+func (s *PricingService) GetVersion(ctx context.Context, versionID uint) (*models.PricingVersion, error) {
+	var version models.PricingVersion
+	if err := s.db.WithContext(ctx).First(&version, versionID).Error; err != nil {
+		return nil, err
+	}
+	return &version, nil
+}
+
+// ListAuditLog lists a tenant's pricing changes, newest first, without config snapshots.
+// configID 0 lists changes to all configs.
+func (s *PricingService) ListAuditLog(ctx context.Context, tenantID, configID uint, since time.Time, limit int) ([]models.PricingVersion, error) {
+	query := s.db.WithContext(ctx).
+		Omit("snapshot").
+		Where("tenant_id = ?", tenantID)
+	if configID != 0 {
+		query = query.Where("config_id = ?", configID)
+	}
+	if !since.IsZero() {
+		query = query.Where("created_at >= ?", since)
+	}
+	if limit <= 0 {
+		limit = 100
+	}
+
+	var versions []models.PricingVersion
+	err := query.Order("created_at DESC, id DESC").Limit(limit).Find(&versions).Error
+	return versions, err
+}
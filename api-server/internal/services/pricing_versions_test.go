@@ -0,0 +1,112 @@
+package services
+
+import (
+	"testing"
+	"time"
+
+	"github.com/bugfreev587/k8s-cost-api-server/internal/models"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestProposedPricingEffectiveConfig(t *testing.T) {
+	jan := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	feb := time.Date(2024, 2, 1, 0, 0, 0, 0, time.UTC)
+	endJan := feb.AddDate(0, 0, -1)
+	config := &models.PricingConfig{
+		Provider: models.ProviderAWS,
+		Rates: []models.PricingRate{
+			{ResourceType: models.ResourceCPU, CostPerUnit: 0.04, EffectiveFrom: jan, EffectiveTo: &endJan},
+			{ResourceType: models.ResourceCPU, CostPerUnit: 0.03, EffectiveFrom: feb},
+			{ResourceType: models.ResourceMemory, CostPerUnit: 0.005}, // no effective_from: always
+		},
+		Discounts: []models.PricingDiscount{
+			{Name: "edp", Scope: models.DiscountScopeGlobal, DiscountPercent: 10, EffectiveFrom: feb},
+		},
+	}
+
+	proposed := &proposedPricing{config: config, clusters: []string{"prod"}}
+	assert.Nil(t, proposed.configFor("staging", jan))
+
+	effective := proposed.configFor("prod", jan.AddDate(0, 0, 14))
+	require.NotNil(t, effective)
+	require.Len(t, effective.Rates, 2)
+	assert.InDelta(t, 0.04, effective.Rates[0].CostPerUnit, 1e-12)
+	assert.Empty(t, effective.Discounts)
+	assert.Len(t, config.Rates, 3, "the proposal itself is not modified")
+
+	effective = proposed.configFor("prod", feb.AddDate(0, 0, 3))
+	require.Len(t, effective.Rates, 2)
+	assert.InDelta(t, 0.03, effective.Rates[0].CostPerUnit, 1e-12)
+	assert.Len(t, effective.Discounts, 1)
+
+	// No clusters listed: the proposal applies everywhere
+	all := &proposedPricing{config: config}
+	assert.NotNil(t, all.configFor("staging", feb))
+	var none *proposedPricing
+	assert.Nil(t, none.configFor("prod", feb))
+}
+
+func TestPercentChange(t *testing.T) {
+	assert.InDelta(t, -25.0, percentChange(100, 75), 1e-9)
+	assert.InDelta(t, 50.0, percentChange(10, 15), 1e-9)
+	assert.Equal(t, 0.0, percentChange(0, 5))
+}
+
+func TestNodeOverridesAsOf(t *testing.T) {
+	jan := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	feb := time.Date(2024, 2, 1, 0, 0, 0, 0, time.UTC)
+	mar := time.Date(2024, 3, 1, 0, 0, 0, 0, time.UTC)
+	override := func(id uint, node, instanceType string) models.NodePricing {
+		return models.NodePricing{ID: id, ClusterName: "prod", NodeName: node, InstanceType: instanceType, PricingTier: models.TierOnDemand}
+	}
+	state := func(np models.NodePricing) models.PricingJSON {
+		data, err := pricingJSON(np)
+		require.NoError(t, err)
+		return data
+	}
+
+	// node-1 predates versioning; node-2 was changed from m5.large to m5.xlarge in
+	// February; node-3 was created in January and deleted in March
+	current := []models.NodePricing{override(1, "node-1", "c5.large"), override(2, "node-2", "m5.xlarge")}
+	versions := []models.PricingVersion{
+		{EntityID: 3, CreatedAt: jan, After: state(override(3, "node-3", "r5.large"))},
+		{EntityID: 2, CreatedAt: feb, Before: state(override(2, "node-2", "m5.large")), After: state(override(2, "node-2", "m5.xlarge"))},
+		{EntityID: 3, CreatedAt: mar, Before: state(override(3, "node-3", "r5.large"))},
+	}
+	instanceTypes := func(at time.Time) map[string]string {
+		overrides, err := nodeOverridesAsOf(current, versions, at)
+		require.NoError(t, err)
+		types := make(map[string]string)
+		for _, o := range overrides {
+			types[o.NodeName] = o.InstanceType
+		}
+		return types
+	}
+
+	assert.Equal(t, map[string]string{"node-1": "c5.large", "node-2": "m5.large"}, instanceTypes(jan.AddDate(0, 0, -1)))
+	assert.Equal(t, map[string]string{"node-1": "c5.large", "node-2": "m5.large", "node-3": "r5.large"}, instanceTypes(jan.AddDate(0, 0, 10)))
+	assert.Equal(t, map[string]string{"node-1": "c5.large", "node-2": "m5.xlarge", "node-3": "r5.large"}, instanceTypes(feb))
+	assert.Equal(t, map[string]string{"node-1": "c5.large", "node-2": "m5.xlarge"}, instanceTypes(mar.AddDate(0, 0, 1)))
+}
+
+func TestStateAsOf(t *testing.T) {
+	jan := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	feb := time.Date(2024, 2, 1, 0, 0, 0, 0, time.UTC)
+	versions := []models.PricingVersion{
+		{CreatedAt: jan, Before: models.PricingJSON(`{"config_id":1}`), After: models.PricingJSON(`{"config_id":2}`)},
+		{CreatedAt: feb, Before: models.PricingJSON(`{"config_id":2}`)}, // unassigned
+	}
+
+	_, ok := stateAsOf(nil, jan)
+	assert.False(t, ok, "entities without versions keep their current state")
+
+	state, ok := stateAsOf(versions, jan.AddDate(0, 0, -1))
+	assert.True(t, ok)
+	assert.JSONEq(t, `{"config_id":1}`, string(state))
+	state, _ = stateAsOf(versions, jan)
+	assert.JSONEq(t, `{"config_id":2}`, string(state))
+	state, ok = stateAsOf(versions, feb.AddDate(0, 0, 1))
+	assert.True(t, ok)
+	assert.Nil(t, state)
+}
@@ -0,0 +1,511 @@
+package services
+
+import (
+	"bytes"
+	"context"
+	"encoding/csv"
+	"fmt"
+	htmltemplate "html/template"
+	"log"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/bugfreev587/k8s-cost-api-server/internal/models"
+	"github.com/bugfreev587/k8s-cost-api-server/internal/notifications"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// reportSummaryRows is the number of top allocations listed in a report's message
+const reportSummaryRows = 5
+
+// ReportService manages scheduled cost reports: it runs their allocation queries when due,
+// renders them and delivers them through notification channels
+type ReportService struct {
+	db     *gorm.DB
+	alloc  *AllocationService
+	notify *NotificationService
+}
+
+// NewReportService creates a report service querying the allocation service and delivering
+// through the notification service
+func NewReportService(db *gorm.DB, alloc *AllocationService, notify *NotificationService) *ReportService {
+	return &ReportService{db: db, alloc: alloc, notify: notify}
+}
+
+// ValidateReport checks a report before it is saved, applies defaults and computes its
+// next run from now
+func ValidateReport(r *models.Report, now time.Time) error {
+	if strings.TrimSpace(r.Name) == "" {
+		return fmt.Errorf("name required")
+	}
+	if r.Format == "" {
+		r.Format = models.ReportFormatCSV
+	}
+	if r.Format != models.ReportFormatCSV && r.Format != models.ReportFormatHTML {
+		return fmt.Errorf("invalid format: %s (expected csv or html)", r.Format)
+	}
+	if r.Query.Window == "" {
+		r.Query.Window = "lastweek"
+	}
+	if r.Query.Aggregate == "" {
+		r.Query.Aggregate = "namespace"
+	}
+	if _, err := ParseFilters(r.Query.Filters); err != nil {
+		return err
+	}
+	if r.Timezone == "" {
+		r.Timezone = "UTC"
+	}
+	loc, err := time.LoadLocation(r.Timezone)
+	if err != nil {
+		return fmt.Errorf("invalid timezone: %s", r.Timezone)
+	}
+	schedule, err := ParseCron(r.Schedule)
+	if err != nil {
+		return err
+	}
+	if len(r.ChannelIDs) == 0 {
+		return fmt.Errorf("channel_ids requires at least one notification channel")
+	}
+
+	r.NextRunAt = nil
+	if next := schedule.Next(now.In(loc)); !next.IsZero() {
+		next = next.UTC()
+		r.NextRunAt = &next
+	}
+	return nil
+}
+
+// ListReports lists a tenant's reports
+func (s *ReportService) ListReports(ctx context.Context, tenantID uint) ([]models.Report, error) {
+	var reports []models.Report
+	err := s.db.WithContext(ctx).Where("tenant_id = ?", tenantID).Order("name").Find(&reports).Error
+	return reports, err
+}
+
+// GetReport retrieves a report by ID
+func (s *ReportService) GetReport(ctx context.Context, id uint) (*models.Report, error) {
+	var report models.Report
+	if err := s.db.WithContext(ctx).First(&report, id).Error; err != nil {
+		return nil, err
+	}
+	return &report, nil
+}
+
+// UnknownChannels returns the channel IDs that are not notification channels of the tenant
+func (s *ReportService) UnknownChannels(ctx context.Context, tenantID uint, channelIDs []int64) ([]int64, error) {
+	var found []int64
+	err := s.db.WithContext(ctx).Model(&models.NotificationChannel{}).
+		Where("tenant_id = ? AND id IN ?", tenantID, channelIDs).
+		Pluck("id", &found).Error
+	if err != nil {
+		return nil, err
+	}
+	known := make(map[int64]bool, len(found))
+	for _, id := range found {
+		known[id] = true
+	}
+	var unknown []int64
+	for _, id := range channelIDs {
+		if !known[id] {
+			unknown = append(unknown, id)
+		}
+	}
+	return unknown, nil
+}
+
+// SaveReport creates or updates a report
+func (s *ReportService) SaveReport(ctx context.Context, report *models.Report) error {
+	return s.db.WithContext(ctx).Save(report).Error
+}
+
+// DeleteReport deletes a report and its run history
+func (s *ReportService) DeleteReport(ctx context.Context, id uint) error {
+	return s.db.WithContext(ctx).Delete(&models.Report{}, id).Error
+}
+
+// ListRuns lists a report's runs, newest first
+func (s *ReportService) ListRuns(ctx context.Context, reportID uint, limit int) ([]models.ReportRun, error) {
+	if limit <= 0 {
+		limit = 50
+	}
+	var runs []models.ReportRun
+	err := s.db.WithContext(ctx).
+		Omit("output").
+		Where("report_id = ?", reportID).
+		Order("started_at DESC, id DESC").
+		Limit(limit).
+		Find(&runs).Error
+	return runs, err
+}
+
+// GetRun retrieves a report run with its output
+func (s *ReportService) GetRun(ctx context.Context, reportID, runID uint) (*models.ReportRun, error) {
+	var run models.ReportRun
+	err := s.db.WithContext(ctx).Where("report_id = ?", reportID).First(&run, runID).Error
+	if err != nil {
+		return nil, err
+	}
+	return &run, nil
+}
+
+// RunNow runs a report immediately, outside its schedule
+func (s *ReportService) RunNow(ctx context.Context, report *models.Report) (*models.ReportRun, error) {
+	now := time.Now().UTC()
+	run := &models.ReportRun{
+		TenantID:     report.TenantID,
+		ReportID:     report.ID,
+		ScheduledFor: now,
+		Manual:       true,
+		Status:       models.ReportRunRunning,
+		Format:       report.Format,
+		StartedAt:    now,
+	}
+	if err := s.db.WithContext(ctx).Create(run).Error; err != nil {
+		return nil, err
+	}
+	s.execute(ctx, report, run)
+	return run, nil
+}
+
+// RunDue runs every enabled report whose next run is due. Each scheduled run is claimed by
+// inserting its run row, so with several replicas only one runs it.
+func (s *ReportService) RunDue(ctx context.Context, now time.Time) error {
+	var reports []models.Report
+	err := s.db.WithContext(ctx).
+		Where("enabled AND next_run_at IS NOT NULL AND next_run_at <= ?", now).
+		Order("next_run_at").
+		Find(&reports).Error
+	if err != nil {
+		return err
+	}
+
+	for i := range reports {
+		report := &reports[i]
+		run := &models.ReportRun{
+			TenantID:     report.TenantID,
+			ReportID:     report.ID,
+			ScheduledFor: *report.NextRunAt,
+			Status:       models.ReportRunRunning,
+			Format:       report.Format,
+			StartedAt:    now,
+		}
+		claimed := s.db.WithContext(ctx).Clauses(clause.OnConflict{DoNothing: true}).Create(run)
+		if claimed.Error != nil {
+			log.Printf("report %d: failed to claim run: %v", report.ID, claimed.Error)
+			continue
+		}
+		if claimed.RowsAffected == 0 {
+			continue
+		}
+
+		if err := s.advance(ctx, report, now); err != nil {
+			log.Printf("report %d: failed to schedule next run: %v", report.ID, err)
+		}
+		s.execute(ctx, report, run)
+	}
+	return nil
+}
+
+// advance moves a report's next run past now
+func (s *ReportService) advance(ctx context.Context, report *models.Report, now time.Time) error {
+	loc, err := time.LoadLocation(report.Timezone)
+	if err != nil {
+		loc = time.UTC
+	}
+	var next *time.Time
+	if schedule, err := ParseCron(report.Schedule); err == nil {
+		if t := schedule.Next(now.In(loc)); !t.IsZero() {
+			t = t.UTC()
+			next = &t
+		}
+	}
+	return s.db.WithContext(ctx).Model(report).Updates(map[string]interface{}{
+		"next_run_at": next,
+		"last_run_at": now,
+	}).Error
+}
+
+// Run runs due reports at each interval until ctx is cancelled
+func (s *ReportService) Run(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		if err := s.RunDue(ctx, time.Now().UTC()); err != nil {
+			log.Printf("scheduled reports failed: %v", err)
+		}
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// execute queries, renders and delivers a report, recording the outcome on its run
+func (s *ReportService) execute(ctx context.Context, report *models.Report, run *models.ReportRun) {
+	err := s.produce(ctx, report, run)
+	finished := time.Now().UTC()
+	run.FinishedAt = &finished
+	run.Status = models.ReportRunSucceeded
+	if err != nil {
+		run.Status = models.ReportRunFailed
+		run.Error = err.Error()
+		log.Printf("report %d (tenant %d) failed: %v", report.ID, report.TenantID, err)
+	}
+	if err := s.db.WithContext(ctx).Save(run).Error; err != nil {
+		log.Printf("report %d: failed to record run: %v", report.ID, err)
+	}
+}
+
+// produce runs the report's query, renders it onto the run and delivers it to the
+// report's channels
+func (s *ReportService) produce(ctx context.Context, report *models.Report, run *models.ReportRun) error {
+	q := report.Query
+	params := AllocationParams{
+		Window:          q.Window,
+		Aggregate:       q.Aggregate,
+		Step:            q.Step,
+		Accumulate:      "true",
+		Filters:         q.Filters,
+		Idle:            q.Idle,
+		ShareIdle:       q.ShareIdle,
+		IncludeExternal: q.IncludeExternal,
+		Reconcile:       true,
+		Currency:        q.Currency,
+	}
+	if q.Step != "" {
+		params.Accumulate = "false"
+	}
+	resp, err := s.alloc.GetAllocations(ctx, int64(report.TenantID), params)
+	if err != nil {
+		return err
+	}
+
+	rows := reportRows(resp)
+	currency := resp.Currency
+	if currency == "" {
+		currency = "USD"
+	}
+	var output []byte
+	contentType := "text/csv"
+	if report.Format == models.ReportFormatHTML {
+		contentType = "text/html"
+		output, err = RenderReportHTML(report, rows, currency)
+	} else {
+		output, err = RenderReportCSV(rows, currency)
+	}
+	if err != nil {
+		return err
+	}
+	run.Output = output
+	run.Rows = len(rows)
+	run.Size = len(output)
+
+	msg := reportMessage(report, rows, currency, run.ScheduledFor)
+	msg.Link = s.notify.link("/dashboard")
+	msg.Attachments = []notifications.Attachment{{
+		Filename:    ReportFilename(report, run),
+		ContentType: contentType,
+		Data:        output,
+	}}
+	return s.deliver(ctx, report, msg, run)
+}
+
+// deliver sends a report message to each of the report's channels
+func (s *ReportService) deliver(ctx context.Context, report *models.Report, msg notifications.Message, run *models.ReportRun) error {
+	var channels []models.NotificationChannel
+	err := s.db.WithContext(ctx).
+		Where("tenant_id = ? AND id IN ?", report.TenantID, []int64(report.ChannelIDs)).
+		Find(&channels).Error
+	if err != nil {
+		return err
+	}
+
+	var failed []string
+	for i := range channels {
+		channel := &channels[i]
+		if channel.Type == models.ChannelEmail && len(report.Recipients) > 0 {
+			channel.Config.To = report.Recipients
+		}
+		delivery := s.notify.Send(ctx, channel, msg)
+		if delivery.Status == models.DeliverySent {
+			run.Deliveries++
+		} else {
+			failed = append(failed, fmt.Sprintf("%s: %s", channel.Name, delivery.Error))
+		}
+	}
+	if len(channels) == 0 {
+		return fmt.Errorf("no notification channels to deliver to")
+	}
+	if len(failed) > 0 {
+		return fmt.Errorf("delivery failed: %s", strings.Join(failed, "; "))
+	}
+	return nil
+}
+
+// ReportFilename is the file name a report run's output is attached and downloaded as
+func ReportFilename(report *models.Report, run *models.ReportRun) string {
+	name := strings.Map(func(r rune) rune {
+		if r >= 'a' && r <= 'z' || r >= 'A' && r <= 'Z' || r >= '0' && r <= '9' || r == '-' || r == '_' {
+			return r
+		}
+		return '-'
+	}, report.Name)
+	return fmt.Sprintf("%s-%s.%s", name, run.ScheduledFor.UTC().Format("2006-01-02"), run.Format)
+}
+
+// ReportRow is one allocation (per step) of a report
+type ReportRow struct {
+	WindowStart    time.Time
+	WindowEnd      time.Time
+	Name           string
+	CPUCost        float64
+	RAMCost        float64
+	SharedIdleCost float64
+	SharedCost     float64
+	ExternalCost   float64
+	TotalCost      float64
+	Efficiency     float64
+}
+
+// reportRows flattens an allocation response into rows, by window then by cost
+func reportRows(resp *AllocationResponse) []ReportRow {
+	var rows []ReportRow
+	for _, set := range resp.Data {
+		start := len(rows)
+		for name, a := range set.Allocations {
+			rows = append(rows, ReportRow{
+				WindowStart:    set.Window.Start,
+				WindowEnd:      set.Window.End,
+				Name:           name,
+				CPUCost:        a.CPUCost,
+				RAMCost:        a.RAMCost,
+				SharedIdleCost: a.SharedIdleCost,
+				SharedCost:     a.SharedCost,
+				ExternalCost:   a.ExternalCost,
+				TotalCost:      a.TotalCost,
+				Efficiency:     a.TotalEfficiency,
+			})
+		}
+		window := rows[start:]
+		sort.Slice(window, func(i, j int) bool {
+			if window[i].TotalCost != window[j].TotalCost {
+				return window[i].TotalCost > window[j].TotalCost
+			}
+			return window[i].Name < window[j].Name
+		})
+	}
+	return rows
+}
+
+// RenderReportCSV renders report rows as CSV
+func RenderReportCSV(rows []ReportRow, currency string) ([]byte, error) {
+	var buf bytes.Buffer
+	w := csv.NewWriter(&buf)
+	w.Write([]string{"window_start", "window_end", "name", "currency", "cpu_cost", "ram_cost",
+		"shared_idle_cost", "shared_cost", "external_cost", "total_cost", "efficiency"})
+	money := func(v float64) string { return strconv.FormatFloat(v, 'f', 2, 64) }
+	for _, r := range rows {
+		w.Write([]string{
+			r.WindowStart.UTC().Format(time.RFC3339),
+			r.WindowEnd.UTC().Format(time.RFC3339),
+			r.Name,
+			currency,
+			money(r.CPUCost),
+			money(r.RAMCost),
+			money(r.SharedIdleCost),
+			money(r.SharedCost),
+			money(r.ExternalCost),
+			money(r.TotalCost),
+			strconv.FormatFloat(r.Efficiency, 'f', 4, 64),
+		})
+	}
+	w.Flush()
+	return buf.Bytes(), w.Error()
+}
+
+var reportHTMLTemplate = htmltemplate.Must(htmltemplate.New("report").Funcs(htmltemplate.FuncMap{
+	"money":   func(v float64) string { return strconv.FormatFloat(v, 'f', 2, 64) },
+	"percent": func(v float64) string { return strconv.FormatFloat(v*100, 'f', 1, 64) + "%" },
+	"date":    func(t time.Time) string { return t.UTC().Format("2006-01-02 15:04") },
+}).Parse(`<!DOCTYPE html>
+<html>
+<head><meta charset="utf-8"><title>{{.Report.Name}}</title></head>
+<body style="font-family: sans-serif; color: #1f2933;">
+  <h2>{{.Report.Name}}</h2>
+  <p>Costs by {{.Report.Query.Aggregate}} in {{.Currency}}, window {{.Report.Query.Window}}.
+  Total: <strong>{{money .Total}} {{.Currency}}</strong></p>
+  <table cellpadding="6" style="border-collapse: collapse; text-align: right;">
+    <tr style="background: #f5f7fa;">
+      <th style="text-align: left;">Window</th><th style="text-align: left;">Name</th>
+      <th>CPU</th><th>RAM</th><th>Shared idle</th><th>Shared</th><th>External</th><th>Total</th><th>Efficiency</th>
+    </tr>
+    {{range .Rows}}<tr style="border-top: 1px solid #e4e7eb;">
+      <td style="text-align: left;">{{date .WindowStart}} - {{date .WindowEnd}}</td><td style="text-align: left;">{{.Name}}</td>
+      <td>{{money .CPUCost}}</td><td>{{money .RAMCost}}</td><td>{{money .SharedIdleCost}}</td><td>{{money .SharedCost}}</td>
+      <td>{{money .ExternalCost}}</td><td><strong>{{money .TotalCost}}</strong></td><td>{{percent .Efficiency}}</td>
+    </tr>
+    {{end}}
+  </table>
+</body>
+</html>
+`))
+
+// RenderReportHTML renders report rows as an HTML table
+func RenderReportHTML(report *models.Report, rows []ReportRow, currency string) ([]byte, error) {
+	var buf bytes.Buffer
+	err := reportHTMLTemplate.Execute(&buf, map[string]interface{}{
+		"Report":   report,
+		"Rows":     rows,
+		"Currency": currency,
+		"Total":    reportTotal(rows),
+	})
+	return buf.Bytes(), err
+}
+
+func reportTotal(rows []ReportRow) float64 {
+	var total float64
+	for _, r := range rows {
+		total += r.TotalCost
+	}
+	return total
+}
+
+// reportMessage summarizes a report run: its total and largest allocations
+func reportMessage(report *models.Report, rows []ReportRow, currency string, scheduledFor time.Time) notifications.Message {
+	msg := notifications.Message{
+		Event:    models.EventReport,
+		Severity: notifications.SeverityInfo,
+		Title:    "Cost report: " + report.Name,
+		Text: fmt.Sprintf("Total cost by %s for window %s: %.2f %s (%d rows).",
+			report.Query.Aggregate, report.Query.Window, reportTotal(rows), currency, len(rows)),
+		Time: scheduledFor,
+	}
+
+	totals := make(map[string]float64)
+	for _, r := range rows {
+		totals[r.Name] += r.TotalCost
+	}
+	names := make([]string, 0, len(totals))
+	for name := range totals {
+		names = append(names, name)
+	}
+	sort.Slice(names, func(i, j int) bool {
+		if totals[names[i]] != totals[names[j]] {
+			return totals[names[i]] > totals[names[j]]
+		}
+		return names[i] < names[j]
+	})
+	if len(names) > reportSummaryRows {
+		names = names[:reportSummaryRows]
+	}
+	for _, name := range names {
+		msg.Fields = append(msg.Fields, notifications.Field{Name: name, Value: fmt.Sprintf("%.2f %s", totals[name], currency)})
+	}
+	return msg
+}
@@ -0,0 +1,78 @@
+package services
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/bugfreev587/k8s-cost-api-server/internal/models"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestValidateReport(t *testing.T) {
+	now := time.Date(2024, 5, 15, 10, 0, 0, 0, time.UTC) // Wednesday
+	report := &models.Report{
+		Name:       "weekly by team",
+		Query:      models.ReportQuery{Aggregate: "label:team"},
+		Schedule:   "0 8 * * mon",
+		Timezone:   "Europe/Berlin",
+		ChannelIDs: []int64{1},
+	}
+	require.NoError(t, ValidateReport(report, now))
+	assert.Equal(t, models.ReportFormatCSV, report.Format)
+	assert.Equal(t, "lastweek", report.Query.Window)
+	require.NotNil(t, report.NextRunAt)
+	assert.Equal(t, time.Date(2024, 5, 20, 6, 0, 0, 0, time.UTC), *report.NextRunAt) // 08:00 CEST
+
+	invalid := []models.Report{
+		{Name: "x", Schedule: "bad", ChannelIDs: []int64{1}},
+		{Name: "x", Schedule: "@daily", Format: "pdf", ChannelIDs: []int64{1}},
+		{Name: "x", Schedule: "@daily", Timezone: "Mars/Olympus", ChannelIDs: []int64{1}},
+		{Name: "x", Schedule: "@daily"},
+		{Name: "", Schedule: "@daily", ChannelIDs: []int64{1}},
+	}
+	for _, r := range invalid {
+		r := r
+		assert.Error(t, ValidateReport(&r, now), "%+v", r)
+	}
+}
+
+func TestReportRendering(t *testing.T) {
+	window := TimeWindow{
+		Start: time.Date(2024, 5, 6, 0, 0, 0, 0, time.UTC),
+		End:   time.Date(2024, 5, 13, 0, 0, 0, 0, time.UTC),
+	}
+	resp := &AllocationResponse{Data: []AllocationSet{{
+		Window: window,
+		Allocations: map[string]*Allocation{
+			"data":     {CPUCost: 30, RAMCost: 10, TotalCost: 40, TotalEfficiency: 0.5},
+			"platform": {CPUCost: 70, RAMCost: 50, TotalCost: 120, TotalEfficiency: 0.25},
+		},
+	}}}
+	rows := reportRows(resp)
+	require.Len(t, rows, 2)
+	assert.Equal(t, "platform", rows[0].Name)
+
+	out, err := RenderReportCSV(rows, "EUR")
+	require.NoError(t, err)
+	lines := strings.Split(strings.TrimSpace(string(out)), "\n")
+	require.Len(t, lines, 3)
+	assert.True(t, strings.HasPrefix(lines[0], "window_start,window_end,name,currency"))
+	assert.Equal(t, "2024-05-06T00:00:00Z,2024-05-13T00:00:00Z,platform,EUR,70.00,50.00,0.00,0.00,0.00,120.00,0.2500", lines[1])
+
+	report := &models.Report{Name: "<weekly>", Query: models.ReportQuery{Window: "lastweek", Aggregate: "label:team"}}
+	html, err := RenderReportHTML(report, rows, "EUR")
+	require.NoError(t, err)
+	assert.Contains(t, string(html), "&lt;weekly&gt;")
+	assert.Contains(t, string(html), "160.00 EUR")
+
+	msg := reportMessage(report, rows, "EUR", window.End)
+	assert.Equal(t, models.EventReport, msg.Event)
+	assert.Contains(t, msg.Text, "160.00 EUR")
+	require.Len(t, msg.Fields, 2)
+	assert.Equal(t, "platform", msg.Fields[0].Name)
+
+	run := &models.ReportRun{ScheduledFor: window.End, Format: models.ReportFormatCSV}
+	assert.Equal(t, "-weekly--2024-05-13.csv", ReportFilename(report, run))
+}
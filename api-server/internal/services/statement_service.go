@@ -0,0 +1,472 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/bugfreev587/k8s-cost-api-server/internal/export"
+	"github.com/bugfreev587/k8s-cost-api-server/internal/models"
+	"gorm.io/gorm"
+)
+
+// StatementService manages cost centers and monthly chargeback statements
+type StatementService struct {
+	db    *gorm.DB
+	alloc *AllocationService
+}
+
+// NewStatementService creates a statement service computing statements with the allocation service
+func NewStatementService(db *gorm.DB, alloc *AllocationService) *StatementService {
+	return &StatementService{db: db, alloc: alloc}
+}
+
+// ValidateCostCenter checks a cost center before it is saved
+func ValidateCostCenter(cc *models.CostCenter) error {
+	cc.Name = strings.TrimSpace(cc.Name)
+	if cc.Name == "" {
+		return fmt.Errorf("name required")
+	}
+	if strings.HasPrefix(cc.Name, "__") {
+		return fmt.Errorf("invalid name: %q (names starting with __ are reserved)", cc.Name)
+	}
+	cc.Level = strings.TrimSpace(cc.Level)
+	if len(cc.Level) > 50 {
+		return fmt.Errorf("level must be at most 50 characters")
+	}
+	if cc.ParentID != nil && *cc.ParentID == cc.ID && cc.ID != 0 {
+		return fmt.Errorf("a cost center cannot be its own parent")
+	}
+	for _, ns := range cc.Namespaces {
+		if strings.TrimSpace(ns) == "" {
+			return fmt.Errorf("namespaces must not be empty")
+		}
+	}
+	for _, cluster := range cc.Clusters {
+		if strings.TrimSpace(cluster) == "" {
+			return fmt.Errorf("clusters must not be empty")
+		}
+	}
+	for _, selector := range cc.Labels {
+		if key, _, _ := strings.Cut(selector, "="); strings.TrimSpace(key) == "" {
+			return fmt.Errorf("invalid label selector: %q", selector)
+		}
+	}
+	return nil
+}
+
+// ListCostCenters lists a tenant's cost centers by name
+func (s *StatementService) ListCostCenters(ctx context.Context, tenantID uint) ([]models.CostCenter, error) {
+	var centers []models.CostCenter
+	err := s.db.WithContext(ctx).Where("tenant_id = ?", tenantID).Order("name").Find(&centers).Error
+	return centers, err
+}
+
+// CostCenterTree loads a tenant's cost center hierarchy
+func (s *StatementService) CostCenterTree(ctx context.Context, tenantID uint) (*CostCenterTree, error) {
+	centers, err := s.ListCostCenters(ctx, tenantID)
+	if err != nil {
+		return nil, err
+	}
+	return NewCostCenterTree(centers)
+}
+
+// HasChildCostCenters reports whether a cost center has children
+func (s *StatementService) HasChildCostCenters(ctx context.Context, id uint) (bool, error) {
+	var count int64
+	err := s.db.WithContext(ctx).Model(&models.CostCenter{}).Where("parent_id = ?", id).Count(&count).Error
+	return count > 0, err
+}
+
+// GetCostCenter retrieves a cost center by ID
+func (s *StatementService) GetCostCenter(ctx context.Context, id uint) (*models.CostCenter, error) {
+	var cc models.CostCenter
+	if err := s.db.WithContext(ctx).First(&cc, id).Error; err != nil {
+		return nil, err
+	}
+	return &cc, nil
+}
+
+// SaveCostCenter creates or updates a cost center
+func (s *StatementService) SaveCostCenter(ctx context.Context, cc *models.CostCenter) error {
+	return s.db.WithContext(ctx).Save(cc).Error
+}
+
+// DeleteCostCenter deletes a cost center; statement lines keep its name
+func (s *StatementService) DeleteCostCenter(ctx context.Context, id uint) error {
+	return s.db.WithContext(ctx).Delete(&models.CostCenter{}, id).Error
+}
+
+// labelSelectorMatches reports whether labels match a "key=value" or "key" selector
+func labelSelectorMatches(selector string, labels map[string]string) bool {
+	key, value, hasValue := strings.Cut(selector, "=")
+	actual, ok := labels[strings.TrimSpace(key)]
+	return ok && (!hasValue || actual == strings.TrimSpace(value))
+}
+
+// StatementPeriod returns the bounds of a statement period ("2006-01", UTC)
+func StatementPeriod(period string) (time.Time, time.Time, error) {
+	start, err := time.Parse("2006-01", period)
+	if err != nil {
+		return time.Time{}, time.Time{}, fmt.Errorf("invalid period: %q (expected YYYY-MM)", period)
+	}
+	return start, start.AddDate(0, 1, 0), nil
+}
+
+// ListStatements lists a tenant's statements, newest period first, without lines
+func (s *StatementService) ListStatements(ctx context.Context, tenantID uint) ([]models.Statement, error) {
+	var statements []models.Statement
+	err := s.db.WithContext(ctx).Where("tenant_id = ?", tenantID).Order("period DESC").Find(&statements).Error
+	return statements, err
+}
+
+// GetStatement retrieves a statement by ID with its lines and adjustments
+func (s *StatementService) GetStatement(ctx context.Context, id uint) (*models.Statement, error) {
+	var st models.Statement
+	err := s.db.WithContext(ctx).
+		Preload("Lines", func(db *gorm.DB) *gorm.DB { return db.Order("total DESC, cost_center") }).
+		Preload("Adjustments", func(db *gorm.DB) *gorm.DB { return db.Order("created_at, id") }).
+		First(&st, id).Error
+	if err != nil {
+		return nil, err
+	}
+	return &st, nil
+}
+
+// GetStatementForPeriod retrieves a tenant's statement for a period, if any
+func (s *StatementService) GetStatementForPeriod(ctx context.Context, tenantID uint, period string) (*models.Statement, error) {
+	var st models.Statement
+	if err := s.db.WithContext(ctx).Where("tenant_id = ? AND period = ?", tenantID, period).First(&st).Error; err != nil {
+		return nil, err
+	}
+	return &st, nil
+}
+
+// Generate creates a draft statement for a period, freezing the period's allocation
+// results per cost center
+func (s *StatementService) Generate(ctx context.Context, st *models.Statement) error {
+	start, end, err := StatementPeriod(st.Period)
+	if err != nil {
+		return err
+	}
+	st.PeriodStart, st.PeriodEnd = start, end
+	st.Status = models.StatementDraft
+	if st.ShareIdle == "" {
+		st.ShareIdle = ShareIdleWeighted
+	}
+
+	lines, err := s.computeLines(ctx, st)
+	if err != nil {
+		return err
+	}
+	st.Lines = lines
+	st.GeneratedAt = time.Now()
+	applyAdjustments(st)
+	return s.db.WithContext(ctx).Create(st).Error
+}
+
+// Refresh recomputes a draft statement's lines from the current allocation results,
+// keeping its adjustments
+func (s *StatementService) Refresh(ctx context.Context, st *models.Statement) error {
+	lines, err := s.computeLines(ctx, st)
+	if err != nil {
+		return err
+	}
+	st.Lines = lines
+	st.GeneratedAt = time.Now()
+	applyAdjustments(st)
+
+	return s.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := tx.Where("statement_id = ?", st.ID).Delete(&models.StatementLine{}).Error; err != nil {
+			return err
+		}
+		for i := range st.Lines {
+			st.Lines[i].ID = 0
+			st.Lines[i].StatementID = st.ID
+		}
+		if len(st.Lines) > 0 {
+			if err := tx.Create(&st.Lines).Error; err != nil {
+				return err
+			}
+		}
+		return tx.Omit("Lines", "Adjustments").Save(st).Error
+	})
+}
+
+// computeLines computes a statement's lines: the period's pod allocations summed per cost
+// center, with pods no cost center maps to under __unallocated__ and unshared idle cost
+// under __idle__
+func (s *StatementService) computeLines(ctx context.Context, st *models.Statement) ([]models.StatementLine, error) {
+	tree, err := s.CostCenterTree(ctx, st.TenantID)
+	if err != nil {
+		return nil, err
+	}
+
+	params := AllocationParams{
+		Window:            st.PeriodStart.Format(time.RFC3339) + "," + st.PeriodEnd.Format(time.RFC3339),
+		Aggregate:         "cluster,pod",
+		Accumulate:        "true",
+		Idle:              true,
+		ShareIdle:         st.ShareIdle,
+		IdleBy:            IdleByCluster,
+		ApplySharingRules: true,
+		Reconcile:         true,
+		ApplyCommitments:  true,
+		Currency:          st.Currency,
+		Limit:             math.MaxInt32,
+	}
+	resp, err := s.alloc.GetAllocations(ctx, int64(st.TenantID), params)
+	if err != nil {
+		return nil, err
+	}
+	labels, err := s.alloc.loadPodLabels(ctx, int64(st.TenantID), st.PeriodStart, st.PeriodEnd)
+	if err != nil {
+		return nil, err
+	}
+
+	var allocations []*Allocation
+	for _, set := range resp.Data {
+		for _, a := range set.Allocations {
+			allocations = append(allocations, a)
+		}
+	}
+	return statementLines(tree, allocations, labels), nil
+}
+
+// statementLines sums pod allocations (aggregated by cluster and pod) per cost center
+// they map to; every cost center with mapping rules gets a line, even without cost, so it
+// can be adjusted
+func statementLines(tree *CostCenterTree, allocations []*Allocation, podLabels map[string]map[string]string) []models.StatementLine {
+	lines := make(map[string]*models.StatementLine)
+	namespaces := make(map[string]map[string]bool)
+	for _, cc := range tree.matchOrder {
+		id := cc.ID
+		lines[cc.Name] = &models.StatementLine{CostCenter: cc.Name, CostCenterID: &id}
+		namespaces[cc.Name] = make(map[string]bool)
+	}
+	for _, a := range allocations {
+		name := models.StatementLineUnallocated
+		var centerID *uint
+		if strings.HasSuffix(a.Name, "__idle__") {
+			name = models.StatementLineIdle
+		} else {
+			pod := strings.TrimPrefix(a.Name, a.Properties.Cluster+"/"+a.Properties.Namespace+"/")
+			labels := podLabels[podKey(a.Properties.Cluster, a.Properties.Namespace, pod)]
+			if cc := tree.Match(a.Properties.Cluster, a.Properties.Namespace, labels); cc != nil {
+				name = cc.Name
+				id := cc.ID
+				centerID = &id
+			}
+		}
+
+		line, ok := lines[name]
+		if !ok {
+			line = &models.StatementLine{CostCenter: name, CostCenterID: centerID}
+			lines[name] = line
+			namespaces[name] = make(map[string]bool)
+		}
+		if a.Properties.Namespace != "" {
+			namespaces[name][a.Properties.Namespace] = true
+		}
+		line.PodCount += a.PodCount
+		line.CPUCost += a.CPUCost
+		line.RAMCost += a.RAMCost
+		line.SharedIdleCost += a.SharedIdleCost
+		line.SharedCost += a.SharedCost
+		line.ExternalCost += a.ExternalCost
+		line.AllocatedCost += a.TotalCost
+	}
+
+	result := make([]models.StatementLine, 0, len(lines))
+	for name, line := range lines {
+		for ns := range namespaces[name] {
+			line.Namespaces = append(line.Namespaces, ns)
+		}
+		sort.Strings(line.Namespaces)
+		result = append(result, *line)
+	}
+	sort.Slice(result, func(i, j int) bool { return result[i].CostCenter < result[j].CostCenter })
+	return result
+}
+
+// applyAdjustments recomputes the statement's line and statement totals from its
+// allocated costs and adjustments
+func applyAdjustments(st *models.Statement) {
+	byCenter := make(map[string]float64)
+	st.AdjustmentsTotal = 0
+	for _, adj := range st.Adjustments {
+		byCenter[adj.CostCenter] += adj.Effect()
+		st.AdjustmentsTotal += adj.Effect()
+	}
+	st.AllocatedCost = 0
+	st.Total = 0
+	for i := range st.Lines {
+		line := &st.Lines[i]
+		line.Adjustments = byCenter[line.CostCenter]
+		line.Total = line.AllocatedCost + line.Adjustments
+		st.AllocatedCost += line.AllocatedCost
+		st.Total += line.Total
+	}
+}
+
+// ValidateAdjustment checks an adjustment against its draft statement: the cost center
+// must be one of the statement's lines, the comment is required, credits are positive and
+// adjustments non-zero
+func ValidateAdjustment(st *models.Statement, adj *models.StatementAdjustment) error {
+	found := false
+	for _, line := range st.Lines {
+		if line.CostCenter == adj.CostCenter {
+			found = true
+			break
+		}
+	}
+	if !found {
+		return fmt.Errorf("cost center %q is not on the statement", adj.CostCenter)
+	}
+	switch adj.Kind {
+	case models.AdjustmentKindAdjustment:
+		if adj.Amount == 0 {
+			return fmt.Errorf("amount must not be zero")
+		}
+	case models.AdjustmentKindCredit:
+		if adj.Amount <= 0 {
+			return fmt.Errorf("credit amount must be positive")
+		}
+	default:
+		return fmt.Errorf("invalid kind: %s (expected adjustment or credit)", adj.Kind)
+	}
+	if strings.TrimSpace(adj.Comment) == "" {
+		return fmt.Errorf("comment required")
+	}
+	return nil
+}
+
+// AddAdjustment adds an adjustment to a draft statement and updates its totals
+func (s *StatementService) AddAdjustment(ctx context.Context, st *models.Statement, adj *models.StatementAdjustment) error {
+	adj.StatementID = st.ID
+	st.Adjustments = append(st.Adjustments, *adj)
+	applyAdjustments(st)
+	return s.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := tx.Create(adj).Error; err != nil {
+			return err
+		}
+		st.Adjustments[len(st.Adjustments)-1] = *adj
+		return s.saveTotals(tx, st)
+	})
+}
+
+// DeleteAdjustment removes an adjustment from a draft statement and updates its totals;
+// it reports whether the adjustment was on the statement
+func (s *StatementService) DeleteAdjustment(ctx context.Context, st *models.Statement, adjustmentID uint) (bool, error) {
+	kept := st.Adjustments[:0]
+	found := false
+	for _, adj := range st.Adjustments {
+		if adj.ID == adjustmentID {
+			found = true
+			continue
+		}
+		kept = append(kept, adj)
+	}
+	if !found {
+		return false, nil
+	}
+	st.Adjustments = kept
+	applyAdjustments(st)
+	return true, s.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := tx.Delete(&models.StatementAdjustment{}, adjustmentID).Error; err != nil {
+			return err
+		}
+		return s.saveTotals(tx, st)
+	})
+}
+
+// saveTotals saves the totals of a statement and its lines
+func (s *StatementService) saveTotals(tx *gorm.DB, st *models.Statement) error {
+	for _, line := range st.Lines {
+		err := tx.Model(&models.StatementLine{}).Where("id = ?", line.ID).
+			Updates(map[string]interface{}{"adjustments": line.Adjustments, "total": line.Total}).Error
+		if err != nil {
+			return err
+		}
+	}
+	return tx.Model(st).Updates(map[string]interface{}{
+		"adjustments_total": st.AdjustmentsTotal,
+		"total":             st.Total,
+	}).Error
+}
+
+// statementTransitions are the allowed status changes: review a draft, reopen or finalize
+// a reviewed statement
+var statementTransitions = map[string][]string{
+	models.StatementDraft:    {models.StatementReviewed},
+	models.StatementReviewed: {models.StatementDraft, models.StatementFinalized},
+}
+
+// CanTransition reports whether a statement can move from one status to another
+func CanTransition(from, to string) bool {
+	for _, allowed := range statementTransitions[from] {
+		if allowed == to {
+			return true
+		}
+	}
+	return false
+}
+
+// SetStatus moves a statement to a new status, recording who reviewed or finalized it.
+// The caller checks CanTransition first.
+func (s *StatementService) SetStatus(ctx context.Context, st *models.Statement, status, actor string) error {
+	now := time.Now()
+	updates := map[string]interface{}{"status": status}
+	switch status {
+	case models.StatementReviewed:
+		st.ReviewedBy, st.ReviewedAt = actor, &now
+		updates["reviewed_by"], updates["reviewed_at"] = actor, now
+	case models.StatementFinalized:
+		st.FinalizedBy, st.FinalizedAt = actor, &now
+		updates["finalized_by"], updates["finalized_at"] = actor, now
+	case models.StatementDraft:
+		st.ReviewedBy, st.ReviewedAt = "", nil
+		updates["reviewed_by"], updates["reviewed_at"] = "", nil
+	}
+	st.Status = status
+	return s.db.WithContext(ctx).Model(st).Updates(updates).Error
+}
+
+// DeleteStatement deletes a statement that is not finalized
+func (s *StatementService) DeleteStatement(ctx context.Context, id uint) error {
+	return s.db.WithContext(ctx).Where("status <> ?", models.StatementFinalized).Delete(&models.Statement{}, id).Error
+}
+
+// StatementExportColumns are the columns of a statement export: one row per cost center
+var StatementExportColumns = []export.Column{
+	{Name: "period", Type: export.String},
+	{Name: "status", Type: export.String},
+	{Name: "cost_center", Type: export.String},
+	{Name: "namespaces", Type: export.String},
+	{Name: "pod_count", Type: export.Int},
+	{Name: "cpu_cost", Type: export.Float},
+	{Name: "ram_cost", Type: export.Float},
+	{Name: "shared_idle_cost", Type: export.Float},
+	{Name: "shared_cost", Type: export.Float},
+	{Name: "external_cost", Type: export.Float},
+	{Name: "allocated_cost", Type: export.Float},
+	{Name: "adjustments", Type: export.Float},
+	{Name: "total", Type: export.Float},
+	{Name: "currency", Type: export.String},
+}
+
+// WriteStatementRows writes a statement's lines as StatementExportColumns rows
+func WriteStatementRows(w export.Writer, st *models.Statement) error {
+	for _, line := range st.Lines {
+		err := w.WriteRow(st.Period, st.Status, line.CostCenter, strings.Join(line.Namespaces, ","), line.PodCount,
+			line.CPUCost, line.RAMCost, line.SharedIdleCost, line.SharedCost, line.ExternalCost,
+			line.AllocatedCost, line.Adjustments, line.Total, st.Currency)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
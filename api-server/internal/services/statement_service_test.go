@@ -0,0 +1,119 @@
+package services
+
+import (
+	"testing"
+
+	"github.com/bugfreev587/k8s-cost-api-server/internal/models"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestStatementLines_MapsPodsToCostCenters(t *testing.T) {
+	centers := []models.CostCenter{
+		{ID: 1, Name: "payments", Namespaces: []string{"checkout"}, Priority: 0},
+		{ID: 2, Name: "data", Labels: []string{"team=data"}, Priority: 1},
+		{ID: 3, Name: "platform", Labels: []string{"platform"}, Priority: 2},
+	}
+	pod := func(cluster, ns, name string, cost float64) *Allocation {
+		return &Allocation{
+			Name:       cluster + "/" + ns + "/" + name,
+			CPUCost:    cost / 2,
+			RAMCost:    cost / 2,
+			TotalCost:  cost,
+			PodCount:   1,
+			Properties: AllocationProps{Cluster: cluster, Namespace: ns},
+		}
+	}
+	allocations := []*Allocation{
+		pod("prod", "checkout", "api-1", 10),
+		pod("dev", "checkout", "api-1", 2),
+		pod("prod", "etl", "spark-1", 5),
+		pod("prod", "monitoring", "prom-0", 3),
+		pod("prod", "sandbox", "tmp-1", 1),
+		{Name: "prod/__idle__", CPUCost: 4, TotalCost: 4, Properties: AllocationProps{Cluster: "prod"}},
+	}
+	labels := map[string]map[string]string{
+		podKey("prod", "etl", "spark-1"):       {"team": "data"},
+		podKey("prod", "monitoring", "prom-0"): {"platform": "true", "team": "data"},
+	}
+
+	tree, err := NewCostCenterTree(centers)
+	require.NoError(t, err)
+	lines := statementLines(tree, allocations, labels)
+	byName := make(map[string]models.StatementLine)
+	for _, line := range lines {
+		byName[line.CostCenter] = line
+	}
+	require.Len(t, byName, 5)
+
+	assert.InDelta(t, 12, byName["payments"].AllocatedCost, 1e-9)
+	assert.Equal(t, 2, byName["payments"].PodCount)
+	assert.Equal(t, uint(1), *byName["payments"].CostCenterID)
+	assert.Equal(t, []string{"checkout"}, []string(byName["payments"].Namespaces))
+	// prom-0 matches both data and platform; data has the lower priority
+	assert.InDelta(t, 8, byName["data"].AllocatedCost, 1e-9)
+	assert.Equal(t, []string{"etl", "monitoring"}, []string(byName["data"].Namespaces))
+	assert.InDelta(t, 1, byName[models.StatementLineUnallocated].AllocatedCost, 1e-9)
+	assert.Nil(t, byName[models.StatementLineUnallocated].CostCenterID)
+	assert.InDelta(t, 4, byName[models.StatementLineIdle].AllocatedCost, 1e-9)
+	assert.Zero(t, byName["platform"].AllocatedCost)
+}
+
+func TestApplyAdjustments(t *testing.T) {
+	st := &models.Statement{
+		Lines: []models.StatementLine{
+			{CostCenter: "payments", AllocatedCost: 100},
+			{CostCenter: "data", AllocatedCost: 50},
+		},
+		Adjustments: []models.StatementAdjustment{
+			{CostCenter: "payments", Kind: models.AdjustmentKindAdjustment, Amount: 20, Comment: "license"},
+			{CostCenter: "payments", Kind: models.AdjustmentKindCredit, Amount: 5, Comment: "outage"},
+			{CostCenter: "data", Kind: models.AdjustmentKindAdjustment, Amount: -10, Comment: "double counted"},
+		},
+	}
+	applyAdjustments(st)
+
+	assert.InDelta(t, 15, st.Lines[0].Adjustments, 1e-9)
+	assert.InDelta(t, 115, st.Lines[0].Total, 1e-9)
+	assert.InDelta(t, 40, st.Lines[1].Total, 1e-9)
+	assert.InDelta(t, 150, st.AllocatedCost, 1e-9)
+	assert.InDelta(t, 5, st.AdjustmentsTotal, 1e-9)
+	assert.InDelta(t, 155, st.Total, 1e-9)
+}
+
+func TestValidateAdjustment(t *testing.T) {
+	st := &models.Statement{Lines: []models.StatementLine{{CostCenter: "payments"}}}
+	valid := models.StatementAdjustment{CostCenter: "payments", Kind: models.AdjustmentKindCredit, Amount: 5, Comment: "SLA credit"}
+	assert.NoError(t, ValidateAdjustment(st, &valid))
+
+	for name, mutate := range map[string]func(*models.StatementAdjustment){
+		"unknown cost center": func(a *models.StatementAdjustment) { a.CostCenter = "nope" },
+		"negative credit":     func(a *models.StatementAdjustment) { a.Amount = -5 },
+		"zero adjustment":     func(a *models.StatementAdjustment) { a.Kind, a.Amount = models.AdjustmentKindAdjustment, 0 },
+		"missing comment":     func(a *models.StatementAdjustment) { a.Comment = " " },
+		"invalid kind":        func(a *models.StatementAdjustment) { a.Kind = "refund" },
+	} {
+		adj := valid
+		mutate(&adj)
+		assert.Error(t, ValidateAdjustment(st, &adj), name)
+	}
+}
+
+func TestStatementWorkflow(t *testing.T) {
+	assert.True(t, CanTransition(models.StatementDraft, models.StatementReviewed))
+	assert.True(t, CanTransition(models.StatementReviewed, models.StatementDraft))
+	assert.True(t, CanTransition(models.StatementReviewed, models.StatementFinalized))
+	assert.False(t, CanTransition(models.StatementDraft, models.StatementFinalized))
+	assert.False(t, CanTransition(models.StatementFinalized, models.StatementDraft))
+	assert.False(t, CanTransition(models.StatementFinalized, models.StatementReviewed))
+}
+
+func TestStatementPeriod(t *testing.T) {
+	start, end, err := StatementPeriod("2026-12")
+	require.NoError(t, err)
+	assert.Equal(t, "2026-12-01T00:00:00Z", start.Format("2006-01-02T15:04:05Z07:00"))
+	assert.Equal(t, "2027-01-01T00:00:00Z", end.Format("2006-01-02T15:04:05Z07:00"))
+
+	_, _, err = StatementPeriod("2026-13")
+	assert.Error(t, err)
+}
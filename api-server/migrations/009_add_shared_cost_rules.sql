@@ -0,0 +1,19 @@
+-- Migration: Add shared cost rules for allocation
+-- Costs of overhead workloads (kube-system, monitoring, ingress, service mesh) matched by
+-- these rules are spread over the remaining allocations by default
+
+CREATE TABLE IF NOT EXISTS shared_cost_rules (
+  id BIGSERIAL PRIMARY KEY,
+  tenant_id BIGINT NOT NULL REFERENCES tenants(id) ON DELETE CASCADE,
+  name VARCHAR(100) NOT NULL,
+  namespaces TEXT[] DEFAULT ARRAY[]::TEXT[],  -- namespaces whose cost is shared
+  labels TEXT[] DEFAULT ARRAY[]::TEXT[],      -- 'key=value' or 'key' pod label selectors
+  split VARCHAR(20) NOT NULL DEFAULT 'weighted',  -- even, weighted
+  enabled BOOLEAN NOT NULL DEFAULT true,
+  created_at timestamptz DEFAULT now(),
+  updated_at timestamptz DEFAULT now(),
+  UNIQUE(tenant_id, name),
+  CONSTRAINT shared_cost_rules_split_check CHECK (split IN ('even', 'weighted'))
+);
+
+CREATE INDEX IF NOT EXISTS idx_shared_cost_rules_tenant ON shared_cost_rules(tenant_id) WHERE enabled = true;
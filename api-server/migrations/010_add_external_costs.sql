@@ -0,0 +1,22 @@
+-- Migration: Add external cost line items
+-- Costs from outside the cluster (managed services, licences) uploaded per tenant and
+-- merged into allocation results by matching their tags to namespaces, clusters and labels
+
+CREATE TABLE IF NOT EXISTS external_costs (
+  id BIGSERIAL PRIMARY KEY,
+  tenant_id BIGINT NOT NULL REFERENCES tenants(id) ON DELETE CASCADE,
+  start_time timestamptz NOT NULL,
+  end_time timestamptz NOT NULL,
+  amount DOUBLE PRECISION NOT NULL,
+  currency VARCHAR(3) NOT NULL DEFAULT 'USD',
+  provider VARCHAR(50),           -- aws, gcp, azure, datadog, ...
+  service VARCHAR(255),           -- e.g. 'Amazon RDS', 'S3'
+  description TEXT,
+  tags JSONB NOT NULL DEFAULT '{}'::jsonb,
+  source VARCHAR(255),            -- upload batch / file name
+  created_at timestamptz DEFAULT now(),
+  CONSTRAINT external_costs_window_check CHECK (end_time >= start_time)
+);
+
+CREATE INDEX IF NOT EXISTS idx_external_costs_tenant_time ON external_costs(tenant_id, start_time, end_time);
+CREATE INDEX IF NOT EXISTS idx_external_costs_tags ON external_costs USING GIN (tags);
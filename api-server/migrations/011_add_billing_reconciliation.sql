@@ -0,0 +1,48 @@
+-- Migration: Add cloud billing reconciliation
+-- Uploaded billing exports are matched to nodes by instance ID / name and the actual
+-- node cost replaces modeled pricing in allocations
+
+-- TimescaleDB: cloud provider instance ID reported by the agent (node.spec.providerID)
+ALTER TABLE node_metrics ADD COLUMN IF NOT EXISTS provider_id TEXT;
+
+-- PostgreSQL:
+-- Uploaded cloud billing exports (AWS CUR, GCP billing export, Azure cost export)
+CREATE TABLE IF NOT EXISTS billing_imports (
+  id BIGSERIAL PRIMARY KEY,
+  tenant_id BIGINT NOT NULL REFERENCES tenants(id) ON DELETE CASCADE,
+  provider VARCHAR(20) NOT NULL,  -- aws, gcp, azure
+  file_name VARCHAR(255),
+  period_start timestamptz,
+  period_end timestamptz,
+  line_items INTEGER NOT NULL DEFAULT 0,
+  matched_items INTEGER NOT NULL DEFAULT 0,
+  total_cost DOUBLE PRECISION NOT NULL DEFAULT 0,      -- net cost of all line items
+  matched_cost DOUBLE PRECISION NOT NULL DEFAULT 0,    -- net cost attributed to nodes
+  unmatched_cost DOUBLE PRECISION NOT NULL DEFAULT 0,  -- net cost of non-node resources
+  nodes_matched INTEGER NOT NULL DEFAULT 0,
+  status VARCHAR(20) NOT NULL,    -- completed, failed
+  error TEXT,
+  created_at timestamptz DEFAULT now()
+);
+
+CREATE INDEX IF NOT EXISTS idx_billing_imports_tenant ON billing_imports(tenant_id, created_at DESC);
+
+-- Actual daily node cost after reservations, savings plans, discounts and credits.
+-- A later import for the same node and day replaces the earlier figure.
+CREATE TABLE IF NOT EXISTS reconciled_node_costs (
+  id BIGSERIAL PRIMARY KEY,
+  tenant_id BIGINT NOT NULL REFERENCES tenants(id) ON DELETE CASCADE,
+  import_id BIGINT NOT NULL REFERENCES billing_imports(id) ON DELETE CASCADE,
+  cluster_name TEXT NOT NULL,
+  node_name TEXT NOT NULL,
+  resource_id TEXT,               -- instance ID / resource name from the bill
+  period_start timestamptz NOT NULL,
+  period_end timestamptz NOT NULL,
+  usage_hours DOUBLE PRECISION NOT NULL DEFAULT 0,
+  list_cost DOUBLE PRECISION NOT NULL DEFAULT 0,  -- on-demand equivalent
+  net_cost DOUBLE PRECISION NOT NULL DEFAULT 0,   -- after discounts and credits
+  created_at timestamptz DEFAULT now(),
+  UNIQUE(tenant_id, cluster_name, node_name, period_start)
+);
+
+CREATE INDEX IF NOT EXISTS idx_reconciled_node_costs_period ON reconciled_node_costs(tenant_id, period_start, period_end);
@@ -0,0 +1,25 @@
+-- Migration: Add commitments (reserved instances, savings plans, committed use discounts)
+
+-- Reserved instances, savings plans and committed use discounts. The upfront cost is
+-- amortized over the term and the discount applied to the covered nodes in allocation.
+CREATE TABLE IF NOT EXISTS commitments (
+  id BIGSERIAL PRIMARY KEY,
+  tenant_id BIGINT NOT NULL REFERENCES tenants(id) ON DELETE CASCADE,
+  name VARCHAR(100) NOT NULL,
+  provider VARCHAR(20) NOT NULL,
+  type VARCHAR(30) NOT NULL,             -- reserved_instance, savings_plan, committed_use
+  term VARCHAR(20) NOT NULL,             -- reserved_1yr, reserved_3yr
+  upfront_cost DECIMAL(14,4) NOT NULL DEFAULT 0,
+  hourly_commitment DECIMAL(12,6) NOT NULL DEFAULT 0,  -- recurring $/hour
+  discount_percent DECIMAL(5,2) NOT NULL DEFAULT 0,    -- vs on-demand; 0 = provider default
+  instance_families TEXT[] DEFAULT ARRAY[]::TEXT[],    -- e.g. 'm5', 'n2'; empty = any
+  clusters TEXT[] DEFAULT ARRAY[]::TEXT[],             -- empty = all clusters
+  start_date timestamptz NOT NULL,
+  end_date timestamptz NOT NULL,
+  created_at timestamptz DEFAULT now(),
+  updated_at timestamptz DEFAULT now(),
+  CONSTRAINT commitments_type_check CHECK (type IN ('reserved_instance', 'savings_plan', 'committed_use')),
+  CONSTRAINT commitments_term_check CHECK (term IN ('reserved_1yr', 'reserved_3yr'))
+);
+
+CREATE INDEX IF NOT EXISTS idx_commitments_tenant_period ON commitments(tenant_id, start_date, end_date);
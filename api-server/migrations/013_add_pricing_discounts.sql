@@ -0,0 +1,19 @@
+-- Migration: Add negotiated pricing discounts
+
+-- Negotiated discounts (EDP, private pricing) applied on top of a configuration's rates
+CREATE TABLE IF NOT EXISTS pricing_discounts (
+  id BIGSERIAL PRIMARY KEY,
+  config_id BIGINT NOT NULL REFERENCES pricing_configs(id) ON DELETE CASCADE,
+  name VARCHAR(100) NOT NULL,
+  scope VARCHAR(20) NOT NULL,           -- global, resource, instance_family
+  resource_type VARCHAR(20),            -- cpu, memory, gpu, storage (NULL = all resources)
+  instance_family VARCHAR(50),          -- for instance_family scope: m5, n2, Standard_D*
+  discount_percent DECIMAL(5,2) NOT NULL,
+  effective_from DATE DEFAULT CURRENT_DATE,
+  effective_to DATE,                    -- NULL = currently active
+  created_at timestamptz DEFAULT now(),
+  CONSTRAINT pricing_discounts_scope_check CHECK (scope IN ('global', 'resource', 'instance_family')),
+  CONSTRAINT pricing_discounts_percent_check CHECK (discount_percent >= 0 AND discount_percent < 100)
+);
+
+CREATE INDEX IF NOT EXISTS idx_pricing_discounts_effective ON pricing_discounts(config_id, effective_from, effective_to);
@@ -0,0 +1,20 @@
+-- Migration: Add tenant display currency and exchange rates
+
+ALTER TABLE tenants ADD COLUMN IF NOT EXISTS display_currency CHAR(3) DEFAULT 'USD';
+
+COMMENT ON COLUMN tenants.display_currency IS 'Default currency for cost reporting (ISO 4217); costs are stored in USD';
+
+-- Exchange rates from USD (all stored costs) to a display currency, effective from a date
+-- until superseded by a later rate for the same currency
+CREATE TABLE IF NOT EXISTS exchange_rates (
+  id BIGSERIAL PRIMARY KEY,
+  tenant_id BIGINT NOT NULL REFERENCES tenants(id) ON DELETE CASCADE,
+  currency CHAR(3) NOT NULL,                 -- ISO 4217 code, e.g. EUR, GBP
+  rate DECIMAL(18,8) NOT NULL,               -- units of currency per 1 USD
+  effective_from DATE NOT NULL,
+  created_at timestamptz DEFAULT now(),
+  UNIQUE(tenant_id, currency, effective_from),
+  CONSTRAINT exchange_rates_rate_check CHECK (rate > 0)
+);
+
+CREATE INDEX IF NOT EXISTS idx_exchange_rates_lookup ON exchange_rates(tenant_id, currency, effective_from DESC);
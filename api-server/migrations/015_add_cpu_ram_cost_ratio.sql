@@ -0,0 +1,10 @@
+-- Migration: Add a configurable CPU:RAM cost ratio to pricing configs
+
+-- Price of one core relative to one GB of RAM, used to split whole-node hourly costs
+-- (node hourly cost overrides) into per-core and per-GB rates.
+-- NULL = the ratio of the config's on-demand CPU and memory rates.
+ALTER TABLE pricing_configs ADD COLUMN IF NOT EXISTS cpu_ram_cost_ratio DECIMAL(10,4);
+
+ALTER TABLE pricing_configs DROP CONSTRAINT IF EXISTS pricing_configs_cpu_ram_cost_ratio_check;
+ALTER TABLE pricing_configs ADD CONSTRAINT pricing_configs_cpu_ram_cost_ratio_check
+  CHECK (cpu_ram_cost_ratio IS NULL OR cpu_ram_cost_ratio > 0);
@@ -0,0 +1,72 @@
+-- Migration: Add pricing versions (audit history and point-in-time pricing)
+
+-- One immutable row per change to a pricing config, its rates or its discounts.
+-- config_id has no foreign key so the history of deleted configs is kept.
+CREATE TABLE IF NOT EXISTS pricing_versions (
+  id BIGSERIAL PRIMARY KEY,
+  tenant_id BIGINT NOT NULL REFERENCES tenants(id) ON DELETE CASCADE,
+  config_id BIGINT NOT NULL,
+  version INT NOT NULL,                 -- per config, starting at 1
+  action VARCHAR(30) NOT NULL,          -- config.create, rate.update, rates.import, discount.delete, ...
+  entity_type VARCHAR(20) NOT NULL,     -- config, rate, discount
+  entity_id BIGINT,
+  actor VARCHAR(255),                   -- user email or API key
+  before JSONB,
+  after JSONB,
+  snapshot JSONB,                       -- config with rates and discounts after the change (NULL = deleted)
+  created_at timestamptz DEFAULT now(),
+  UNIQUE(config_id, version)
+);
+
+CREATE INDEX IF NOT EXISTS idx_pricing_versions_tenant ON pricing_versions(tenant_id, created_at DESC);
+CREATE INDEX IF NOT EXISTS idx_pricing_versions_config ON pricing_versions(config_id, created_at);
+
+-- Baseline version for existing configs so pinned pricing can resolve them.
+-- Dates are written as RFC3339 timestamps to match the application's snapshots.
+INSERT INTO pricing_versions (tenant_id, config_id, version, action, entity_type, entity_id, actor, after, snapshot, created_at)
+SELECT c.tenant_id, c.id, 1, 'config.create', 'config', c.id, 'migration', s.snapshot, s.snapshot, c.created_at
+FROM pricing_configs c
+CROSS JOIN LATERAL (
+  SELECT jsonb_build_object(
+    'id', c.id,
+    'tenant_id', c.tenant_id,
+    'name', c.name,
+    'provider', c.provider,
+    'region', c.region,
+    'is_default', c.is_default,
+    'cpu_ram_cost_ratio', c.cpu_ram_cost_ratio,
+    'created_at', c.created_at,
+    'updated_at', c.updated_at,
+    'rates', COALESCE((
+      SELECT jsonb_agg(jsonb_build_object(
+        'id', r.id,
+        'config_id', r.config_id,
+        'resource_type', r.resource_type,
+        'pricing_tier', r.pricing_tier,
+        'instance_family', r.instance_family,
+        'unit', r.unit,
+        'cost_per_unit', r.cost_per_unit,
+        'effective_from', to_char(r.effective_from, 'YYYY-MM-DD"T"00:00:00"Z"'),
+        'effective_to', to_char(r.effective_to, 'YYYY-MM-DD"T"00:00:00"Z"'),
+        'created_at', r.created_at
+      ) ORDER BY r.id)
+      FROM pricing_rates r WHERE r.config_id = c.id
+    ), '[]'::jsonb),
+    'discounts', COALESCE((
+      SELECT jsonb_agg(jsonb_build_object(
+        'id', d.id,
+        'config_id', d.config_id,
+        'name', d.name,
+        'scope', d.scope,
+        'resource_type', d.resource_type,
+        'instance_family', d.instance_family,
+        'discount_percent', d.discount_percent,
+        'effective_from', to_char(d.effective_from, 'YYYY-MM-DD"T"00:00:00"Z"'),
+        'effective_to', to_char(d.effective_to, 'YYYY-MM-DD"T"00:00:00"Z"'),
+        'created_at', d.created_at
+      ) ORDER BY d.id)
+      FROM pricing_discounts d WHERE d.config_id = c.id
+    ), '[]'::jsonb)
+  ) AS snapshot
+) s
+WHERE NOT EXISTS (SELECT 1 FROM pricing_versions v WHERE v.config_id = c.id);
@@ -0,0 +1,4 @@
+-- Migration: Add node labels to node_metrics (TimescaleDB)
+-- Node labels let pricing overrides be applied to nodes by label selector
+
+ALTER TABLE node_metrics ADD COLUMN IF NOT EXISTS labels JSONB;
@@ -0,0 +1,55 @@
+-- Migration: Add cost anomaly detection
+
+-- Per-tenant detection settings; tenants without a row use the defaults
+CREATE TABLE IF NOT EXISTS anomaly_settings (
+  tenant_id BIGINT PRIMARY KEY REFERENCES tenants(id) ON DELETE CASCADE,
+  enabled BOOLEAN NOT NULL DEFAULT TRUE,
+  sensitivity VARCHAR(10) NOT NULL DEFAULT 'medium',  -- low, medium, high
+  min_change_usd DECIMAL(12,4) NOT NULL DEFAULT 1,    -- ignore smaller changes
+  baseline_days INT NOT NULL DEFAULT 14,              -- daily baseline; hourly uses the previous 24 hours
+  dimensions TEXT[] DEFAULT ARRAY['cluster', 'namespace']::TEXT[],
+  granularities TEXT[] DEFAULT ARRAY['hour', 'day']::TEXT[],
+  updated_at timestamptz DEFAULT now(),
+  CONSTRAINT anomaly_settings_sensitivity_check CHECK (sensitivity IN ('low', 'medium', 'high'))
+);
+
+-- Detected spikes and drops of the cost of a cluster, namespace or label value
+CREATE TABLE IF NOT EXISTS cost_anomalies (
+  id BIGSERIAL PRIMARY KEY,
+  tenant_id BIGINT NOT NULL REFERENCES tenants(id) ON DELETE CASCADE,
+  granularity VARCHAR(10) NOT NULL,      -- hour, day
+  dimension VARCHAR(100) NOT NULL,       -- cluster, namespace, label:<key>
+  name VARCHAR(255) NOT NULL,
+  window_start timestamptz NOT NULL,
+  window_end timestamptz NOT NULL,
+  cost_usd DECIMAL(14,4) NOT NULL,
+  baseline_cost_usd DECIMAL(14,4) NOT NULL,
+  stddev_usd DECIMAL(14,4) NOT NULL,
+  z_score DECIMAL(10,2) NOT NULL,
+  direction VARCHAR(10) NOT NULL,        -- spike, drop
+  severity VARCHAR(10) NOT NULL,         -- low, medium, high
+  drivers JSONB DEFAULT '[]'::JSONB,     -- namespace/controller cost changes (root-cause hints)
+  status VARCHAR(20) NOT NULL DEFAULT 'open',
+  acknowledged_by VARCHAR(255),
+  acknowledged_at timestamptz,
+  resolved_by VARCHAR(255),
+  resolved_at timestamptz,
+  note TEXT,
+  created_at timestamptz DEFAULT now(),
+  updated_at timestamptz DEFAULT now(),
+  UNIQUE(tenant_id, granularity, dimension, name, window_start),
+  CONSTRAINT cost_anomalies_status_check CHECK (status IN ('open', 'acknowledged', 'resolved'))
+);
+
+CREATE INDEX IF NOT EXISTS idx_cost_anomalies_tenant ON cost_anomalies(tenant_id, status, window_start DESC);
+
+-- Periods already evaluated, so each period is evaluated once across API server replicas
+CREATE TABLE IF NOT EXISTS anomaly_detection_runs (
+  id BIGSERIAL PRIMARY KEY,
+  tenant_id BIGINT NOT NULL REFERENCES tenants(id) ON DELETE CASCADE,
+  granularity VARCHAR(10) NOT NULL,
+  window_start timestamptz NOT NULL,
+  anomalies INT NOT NULL DEFAULT 0,
+  created_at timestamptz DEFAULT now(),
+  UNIQUE(tenant_id, granularity, window_start)
+);
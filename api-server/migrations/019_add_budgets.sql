@@ -0,0 +1,40 @@
+-- Migration: Add budgets and budget alerts
+
+-- Monthly or quarterly spend limits on the allocations matching the filters
+CREATE TABLE IF NOT EXISTS budgets (
+  id BIGSERIAL PRIMARY KEY,
+  tenant_id BIGINT NOT NULL REFERENCES tenants(id) ON DELETE CASCADE,
+  name VARCHAR(100) NOT NULL,
+  period VARCHAR(20) NOT NULL,                         -- monthly, quarterly
+  amount DECIMAL(14,2) NOT NULL,
+  currency CHAR(3) NOT NULL DEFAULT 'USD',
+  filters TEXT[] DEFAULT ARRAY[]::TEXT[],              -- allocation filters; empty = whole tenant
+  thresholds FLOAT8[] DEFAULT ARRAY[50, 80, 100]::FLOAT8[],  -- percent of the amount
+  alert_on_forecast BOOLEAN NOT NULL DEFAULT TRUE,
+  last_spent DECIMAL(14,2) DEFAULT 0,
+  last_forecast DECIMAL(14,2) DEFAULT 0,
+  last_evaluated_at timestamptz,
+  created_at timestamptz DEFAULT now(),
+  updated_at timestamptz DEFAULT now(),
+  UNIQUE(tenant_id, name),
+  CONSTRAINT budgets_period_check CHECK (period IN ('monthly', 'quarterly')),
+  CONSTRAINT budgets_amount_check CHECK (amount > 0)
+);
+
+-- Thresholds crossed by a budget, once per threshold and period
+CREATE TABLE IF NOT EXISTS budget_alerts (
+  id BIGSERIAL PRIMARY KEY,
+  tenant_id BIGINT NOT NULL REFERENCES tenants(id) ON DELETE CASCADE,
+  budget_id BIGINT NOT NULL REFERENCES budgets(id) ON DELETE CASCADE,
+  period_start timestamptz NOT NULL,
+  type VARCHAR(20) NOT NULL,                           -- actual, forecast
+  threshold DECIMAL(6,2) NOT NULL,
+  amount DECIMAL(14,2) NOT NULL,
+  spent DECIMAL(14,2) NOT NULL,
+  forecast DECIMAL(14,2) NOT NULL,
+  currency CHAR(3) NOT NULL,
+  created_at timestamptz DEFAULT now(),
+  UNIQUE(budget_id, period_start, type, threshold)
+);
+
+CREATE INDEX IF NOT EXISTS idx_budget_alerts_tenant ON budget_alerts(tenant_id, created_at DESC);
@@ -0,0 +1,32 @@
+-- Migration: Add notification channels and delivery log
+
+-- Destinations for alert notifications (Slack, Teams, signed webhook, SMTP email)
+CREATE TABLE IF NOT EXISTS notification_channels (
+  id BIGSERIAL PRIMARY KEY,
+  tenant_id BIGINT NOT NULL REFERENCES tenants(id) ON DELETE CASCADE,
+  name VARCHAR(100) NOT NULL,
+  type VARCHAR(20) NOT NULL,                           -- slack, teams, webhook, email
+  config JSONB NOT NULL DEFAULT '{}'::JSONB,
+  events TEXT[] DEFAULT ARRAY[]::TEXT[],               -- anomaly, budget, recommendation; empty = all
+  enabled BOOLEAN NOT NULL DEFAULT TRUE,
+  created_at timestamptz DEFAULT now(),
+  updated_at timestamptz DEFAULT now(),
+  UNIQUE(tenant_id, name),
+  CONSTRAINT notification_channels_type_check CHECK (type IN ('slack', 'teams', 'webhook', 'email'))
+);
+
+-- Delivery log: one row per notification sent to a channel
+CREATE TABLE IF NOT EXISTS notification_deliveries (
+  id BIGSERIAL PRIMARY KEY,
+  tenant_id BIGINT NOT NULL REFERENCES tenants(id) ON DELETE CASCADE,
+  channel_id BIGINT NOT NULL REFERENCES notification_channels(id) ON DELETE CASCADE,
+  event VARCHAR(50) NOT NULL,
+  title VARCHAR(500) NOT NULL,
+  status VARCHAR(20) NOT NULL,                         -- sent, failed
+  attempts INT NOT NULL DEFAULT 0,
+  error TEXT,
+  created_at timestamptz DEFAULT now()
+);
+
+CREATE INDEX IF NOT EXISTS idx_notification_deliveries_tenant ON notification_deliveries(tenant_id, created_at DESC);
+CREATE INDEX IF NOT EXISTS idx_notification_deliveries_channel ON notification_deliveries(channel_id, created_at DESC);
@@ -0,0 +1,44 @@
+-- Migration: Add scheduled reports and their run history
+
+-- Saved allocation queries rendered on a cron schedule and delivered through notification channels
+CREATE TABLE IF NOT EXISTS reports (
+  id BIGSERIAL PRIMARY KEY,
+  tenant_id BIGINT NOT NULL REFERENCES tenants(id) ON DELETE CASCADE,
+  name VARCHAR(100) NOT NULL,
+  query JSONB NOT NULL DEFAULT '{}'::JSONB,            -- window, aggregate, filters, ...
+  format VARCHAR(10) NOT NULL,                         -- csv, html
+  schedule VARCHAR(100) NOT NULL,                      -- cron expression
+  timezone VARCHAR(64) NOT NULL DEFAULT 'UTC',
+  channel_ids BIGINT[] DEFAULT ARRAY[]::BIGINT[],      -- notification_channels
+  recipients TEXT[] DEFAULT ARRAY[]::TEXT[],           -- override email channel recipients
+  enabled BOOLEAN NOT NULL DEFAULT TRUE,
+  next_run_at timestamptz,
+  last_run_at timestamptz,
+  created_at timestamptz DEFAULT now(),
+  updated_at timestamptz DEFAULT now(),
+  UNIQUE(tenant_id, name),
+  CONSTRAINT reports_format_check CHECK (format IN ('csv', 'html'))
+);
+
+CREATE INDEX IF NOT EXISTS idx_reports_next_run ON reports(next_run_at) WHERE enabled;
+
+-- Run history; scheduled runs are unique per report and time so only one replica runs them
+CREATE TABLE IF NOT EXISTS report_runs (
+  id BIGSERIAL PRIMARY KEY,
+  tenant_id BIGINT NOT NULL REFERENCES tenants(id) ON DELETE CASCADE,
+  report_id BIGINT NOT NULL REFERENCES reports(id) ON DELETE CASCADE,
+  scheduled_for timestamptz NOT NULL,
+  manual BOOLEAN NOT NULL DEFAULT FALSE,
+  status VARCHAR(20) NOT NULL,                         -- running, succeeded, failed
+  format VARCHAR(10) NOT NULL,
+  rows INT NOT NULL DEFAULT 0,
+  size INT NOT NULL DEFAULT 0,
+  deliveries INT NOT NULL DEFAULT 0,
+  error TEXT,
+  output BYTEA,
+  started_at timestamptz NOT NULL DEFAULT now(),
+  finished_at timestamptz
+);
+
+CREATE UNIQUE INDEX IF NOT EXISTS idx_report_runs_scheduled ON report_runs(report_id, scheduled_for) WHERE NOT manual;
+CREATE INDEX IF NOT EXISTS idx_report_runs_report ON report_runs(report_id, started_at DESC);
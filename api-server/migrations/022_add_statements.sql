@@ -0,0 +1,107 @@
+-- Migration: Add cost centers and chargeback statements
+
+-- Chargeback units pods are mapped to by namespace or pod labels
+CREATE TABLE IF NOT EXISTS cost_centers (
+  id BIGSERIAL PRIMARY KEY,
+  tenant_id BIGINT NOT NULL REFERENCES tenants(id) ON DELETE CASCADE,
+  name VARCHAR(100) NOT NULL,
+  description TEXT,
+  namespaces TEXT[] DEFAULT ARRAY[]::TEXT[],
+  labels TEXT[] DEFAULT ARRAY[]::TEXT[],               -- pod label selectors: key=value or key
+  priority INT NOT NULL DEFAULT 0,                     -- lowest matching priority wins
+  created_at timestamptz DEFAULT now(),
+  updated_at timestamptz DEFAULT now(),
+  UNIQUE(tenant_id, name)
+);
+
+-- Monthly chargeback statements: allocation results frozen per cost center
+CREATE TABLE IF NOT EXISTS statements (
+  id BIGSERIAL PRIMARY KEY,
+  tenant_id BIGINT NOT NULL REFERENCES tenants(id) ON DELETE CASCADE,
+  period VARCHAR(7) NOT NULL,                          -- YYYY-MM
+  period_start timestamptz NOT NULL,
+  period_end timestamptz NOT NULL,
+  currency VARCHAR(3) NOT NULL,
+  share_idle VARCHAR(20) NOT NULL,
+  status VARCHAR(20) NOT NULL DEFAULT 'draft',
+  allocated_cost DECIMAL(14,2) NOT NULL DEFAULT 0,
+  adjustments_total DECIMAL(14,2) NOT NULL DEFAULT 0,
+  total DECIMAL(14,2) NOT NULL DEFAULT 0,
+  generated_at timestamptz NOT NULL DEFAULT now(),
+  created_by VARCHAR(255),
+  reviewed_by VARCHAR(255),
+  reviewed_at timestamptz,
+  finalized_by VARCHAR(255),
+  finalized_at timestamptz,
+  created_at timestamptz DEFAULT now(),
+  updated_at timestamptz DEFAULT now(),
+  UNIQUE(tenant_id, period),
+  CONSTRAINT statements_status_check CHECK (status IN ('draft', 'reviewed', 'finalized'))
+);
+
+CREATE TABLE IF NOT EXISTS statement_lines (
+  id BIGSERIAL PRIMARY KEY,
+  statement_id BIGINT NOT NULL REFERENCES statements(id) ON DELETE CASCADE,
+  cost_center_id BIGINT REFERENCES cost_centers(id) ON DELETE SET NULL,
+  cost_center VARCHAR(100) NOT NULL,                   -- name when generated, __unallocated__ or __idle__
+  namespaces TEXT[] DEFAULT ARRAY[]::TEXT[],
+  pod_count INT NOT NULL DEFAULT 0,
+  cpu_cost DECIMAL(14,4) NOT NULL DEFAULT 0,
+  ram_cost DECIMAL(14,4) NOT NULL DEFAULT 0,
+  shared_idle_cost DECIMAL(14,4) NOT NULL DEFAULT 0,
+  shared_cost DECIMAL(14,4) NOT NULL DEFAULT 0,
+  external_cost DECIMAL(14,4) NOT NULL DEFAULT 0,
+  allocated_cost DECIMAL(14,4) NOT NULL DEFAULT 0,
+  adjustments DECIMAL(14,4) NOT NULL DEFAULT 0,
+  total DECIMAL(14,4) NOT NULL DEFAULT 0,
+  UNIQUE(statement_id, cost_center)
+);
+
+CREATE TABLE IF NOT EXISTS statement_adjustments (
+  id BIGSERIAL PRIMARY KEY,
+  statement_id BIGINT NOT NULL REFERENCES statements(id) ON DELETE CASCADE,
+  cost_center VARCHAR(100) NOT NULL,
+  kind VARCHAR(20) NOT NULL,                           -- adjustment, credit
+  amount DECIMAL(14,4) NOT NULL,
+  comment TEXT NOT NULL,
+  created_by VARCHAR(255),
+  created_at timestamptz DEFAULT now(),
+  CONSTRAINT statement_adjustments_kind_check CHECK (kind IN ('adjustment', 'credit'))
+);
+
+CREATE INDEX IF NOT EXISTS idx_statement_adjustments_statement ON statement_adjustments(statement_id);
+
+-- Finalized statements are immutable: they cannot be updated and their lines and
+-- adjustments cannot be added to or changed. Deletes are left to the application so that
+-- deleting a tenant still cascades.
+CREATE OR REPLACE FUNCTION protect_finalized_statement()
+RETURNS TRIGGER AS $$
+BEGIN
+  IF TG_TABLE_NAME = 'statements' THEN
+    IF OLD.status = 'finalized' THEN
+      RAISE EXCEPTION 'statement % is finalized', OLD.id;
+    END IF;
+  ELSIF EXISTS (SELECT 1 FROM statements WHERE id = NEW.statement_id AND status = 'finalized') THEN
+    RAISE EXCEPTION 'statement % is finalized', NEW.statement_id;
+  END IF;
+  RETURN NEW;
+END;
+$$ LANGUAGE plpgsql;
+
+DROP TRIGGER IF EXISTS protect_finalized_statement ON statements;
+CREATE TRIGGER protect_finalized_statement
+  BEFORE UPDATE ON statements
+  FOR EACH ROW
+  EXECUTE FUNCTION protect_finalized_statement();
+
+DROP TRIGGER IF EXISTS protect_finalized_statement_lines ON statement_lines;
+CREATE TRIGGER protect_finalized_statement_lines
+  BEFORE INSERT OR UPDATE ON statement_lines
+  FOR EACH ROW
+  EXECUTE FUNCTION protect_finalized_statement();
+
+DROP TRIGGER IF EXISTS protect_finalized_statement_adjustments ON statement_adjustments;
+CREATE TRIGGER protect_finalized_statement_adjustments
+  BEFORE INSERT OR UPDATE ON statement_adjustments
+  FOR EACH ROW
+  EXECUTE FUNCTION protect_finalized_statement();
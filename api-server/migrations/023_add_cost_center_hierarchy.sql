@@ -0,0 +1,9 @@
+-- Migration: Add the cost center hierarchy and cluster mapping rules
+
+-- Cost centers form a tree (org -> department -> team -> service); level names the
+-- node's tier and clusters restrict the node's namespace and label rules
+ALTER TABLE cost_centers ADD COLUMN IF NOT EXISTS parent_id BIGINT REFERENCES cost_centers(id);
+ALTER TABLE cost_centers ADD COLUMN IF NOT EXISTS level VARCHAR(50);
+ALTER TABLE cost_centers ADD COLUMN IF NOT EXISTS clusters TEXT[] DEFAULT ARRAY[]::TEXT[];
+
+CREATE INDEX IF NOT EXISTS idx_cost_centers_parent ON cost_centers(parent_id);
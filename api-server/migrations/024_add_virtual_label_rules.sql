@@ -0,0 +1,21 @@
+-- Migration: Add virtual label rules
+
+-- Virtual label rules: derive a pod label at query time from other labels so that
+-- allocation by label is consistent without relabelling workloads
+CREATE TABLE IF NOT EXISTS virtual_label_rules (
+  id BIGSERIAL PRIMARY KEY,
+  tenant_id BIGINT NOT NULL REFERENCES tenants(id) ON DELETE CASCADE,
+  key VARCHAR(100) NOT NULL,                           -- virtual label key
+  sources TEXT[] DEFAULT ARRAY[]::TEXT[],              -- label keys coalesced in order
+  lowercase BOOLEAN NOT NULL DEFAULT false,
+  rewrites JSONB NOT NULL DEFAULT '[]'::JSONB,         -- [{pattern, replacement}], applied in order
+  lookup JSONB NOT NULL DEFAULT '{}'::JSONB,           -- value -> value
+  namespace_defaults JSONB NOT NULL DEFAULT '{}'::JSONB, -- namespace -> value
+  default_value VARCHAR(255),
+  enabled BOOLEAN NOT NULL DEFAULT true,
+  created_at timestamptz DEFAULT now(),
+  updated_at timestamptz DEFAULT now(),
+  UNIQUE(tenant_id, key)
+);
+
+CREATE INDEX IF NOT EXISTS idx_virtual_label_rules_tenant ON virtual_label_rules(tenant_id) WHERE enabled = true;
@@ -53,6 +53,8 @@ type NodeMetric struct {
 	MemoryCapacity    int64
 	CPUAllocatable    int64
 	MemoryAllocatable int64
+	ProviderID        string            `json:"provider_id,omitempty"` // Cloud instance ID, e.g. aws:///us-east-1a/i-0abc123
+	Labels            map[string]string `json:"labels,omitempty"`      // Node labels, used to select nodes for pricing overrides
 }
 
 type Collector struct {
@@ -239,6 +241,8 @@ func (c *Collector) CollectNodeMetrics(ctx context.Context) ([]NodeMetric, error
 			ClusterName:  c.ClusterName,
 			NodeName:     n.Name,
 			InstanceType: n.Labels["node.kubernetes.io/instance-type"],
+			ProviderID:   n.Spec.ProviderID,
+			Labels:       n.Labels,
 		}
 		if cpuCap != nil {
 			nm.CPUCapacity = cpuCap.MilliValue()